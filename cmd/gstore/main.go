@@ -5,14 +5,20 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
+	"regexp"
 	"syscall"
+	"time"
 
 	"github.com/go-chi/chi"
 	"github.com/jessevdk/go-flags"
 	"github.com/sirupsen/logrus"
 	"github.com/vliubezny/gstore/internal/auth"
+	"github.com/vliubezny/gstore/internal/mail"
+	"github.com/vliubezny/gstore/internal/media"
+	"github.com/vliubezny/gstore/internal/payment"
 	"github.com/vliubezny/gstore/internal/server"
 	"github.com/vliubezny/gstore/internal/service"
 	"github.com/vliubezny/gstore/internal/storage"
@@ -28,12 +34,36 @@ var opts = struct {
 
 	LogLevel string `long:"log.level" env:"LOG_LEVEL" default:"debug" description:"Log level" choice:"debug" choice:"info" choice:"warning" choice:"error"`
 
-	SignKey string `long:"auth.signkey" env:"AUTH_SIGN_KEY" default:"changeme" description:"sign key for JWT"`
+	SignKey            string        `long:"auth.signkey" env:"AUTH_SIGN_KEY" description:"sign key for JWT (HS256), required if auth.keys are not set"`
+	Keys               []string      `long:"auth.keys" env:"AUTH_KEYS" env-delim:"," description:"PEM files or directories of RSA/Ed25519 private keys for JWT, the last key by file name signs tokens while others only verify them"`
+	KeysReload         time.Duration `long:"auth.keys_reload" env:"AUTH_KEYS_RELOAD" default:"1m" description:"interval of reloading auth.keys to pick up rotated keys, 0 disables reloading"`
+	VerificationPolicy string        `long:"auth.verification_policy" env:"AUTH_VERIFICATION_POLICY" default:"none" description:"what users with unverified email are not allowed to do" choice:"none" choice:"login" choice:"write"`
+	MFAPolicy          string        `long:"auth.mfa_policy" env:"AUTH_MFA_POLICY" default:"optional" description:"who must use second factor to get granted roles" choice:"optional" choice:"admin"`
+	RevocationInterval time.Duration `long:"auth.revocation_interval" env:"AUTH_REVOCATION_INTERVAL" default:"10s" description:"interval of refreshing revoked access tokens, bounded to [1s, 1m]"`
+
+	AppURL string `long:"app.url" env:"APP_URL" default:"http://localhost:8080" description:"base URL of web application used in links sent in emails"`
 
 	PostgresDSN                string `long:"postgres" env:"POSTGRES_DSN" default:"host=localhost port=5432 user=postgres password=root dbname=postgres sslmode=disable" description:"postgres dsn"`
 	PostgresMaxOpenConnections int    `long:"postgres.max_open_connections" env:"POSTGRES_MAX_OPEN_CONNECTIONS" default:"0" description:"postgres maximal open connections count, 0 means unlimited"`
 	PostgresMaxIdleConnections int    `long:"postgres.max_idle_connections" env:"POSTGRES_MAX_IDLE_CONNECTIONS" default:"5" description:"postgres maximal idle connections count"`
 	PostgresMigrations         string `long:"postgres.migrations" env:"POSTGRES_MIGRATIONS" default:"scripts/migrations/postgres" description:"postgres migrations directory"`
+
+	MediaDir string `long:"media.dir" env:"MEDIA_DIR" default:"media" description:"directory to store uploaded media files, served under /media/ path"`
+	MediaURL string `long:"media.url" env:"MEDIA_URL" default:"/media" description:"base URL of media files"`
+
+	RatesFile string `long:"rates.file" env:"RATES_FILE" description:"file with <currency>,<rate> lines of exchange rates imported on start"`
+
+	PaymentFake        bool          `long:"payment.fake" env:"PAYMENT_FAKE" description:"simulate payments with the fake provider, for development only"`
+	PaymentSecret      string        `long:"payment.secret" env:"PAYMENT_SECRET" description:"secret to sign payment webhooks of the fake provider"`
+	PaymentCallbackURL string        `long:"payment.callback_url" env:"PAYMENT_CALLBACK_URL" default:"http://localhost:8080/v1/payments/webhook" description:"URL the fake payment provider sends webhooks to"`
+	PaymentDelay       time.Duration `long:"payment.delay" env:"PAYMENT_DELAY" default:"2s" description:"delay of asynchronous payment confirmation by the fake payment provider"`
+
+	SMTPAddr     string        `long:"smtp.addr" env:"SMTP_ADDR" description:"SMTP server address (host:port), emails are written to mail.file if empty"`
+	SMTPUsername string        `long:"smtp.username" env:"SMTP_USERNAME" description:"SMTP username, empty disables authentication"`
+	SMTPPassword string        `long:"smtp.password" env:"SMTP_PASSWORD" description:"SMTP password"`
+	MailFrom     string        `long:"mail.from" env:"MAIL_FROM" default:"noreply@gstore.local" description:"sender address of emails"`
+	MailFile     string        `long:"mail.file" env:"MAIL_FILE" description:"file to write emails to when SMTP is not configured, stdout if empty"`
+	MailInterval time.Duration `long:"mail.interval" env:"MAIL_INTERVAL" default:"10s" description:"interval of email outbox polling"`
 }{}
 
 func main() {
@@ -56,24 +86,60 @@ func main() {
 	logrus.SetLevel(lvl)
 
 	logrus.Info("starting service")
-	logrus.Infof("%+v", opts) // can print secrets!
+	logOptions()
 
 	db := postgres.MustSetupDB(opts.PostgresDSN, opts.PostgresMaxOpenConnections,
 		opts.PostgresMaxIdleConnections, opts.PostgresMigrations)
 	strg := postgres.New(db)
-	authSvc := auth.New(strg.(storage.UserStorage), opts.SignKey)
+	verificationPolicy := auth.VerificationPolicy(opts.VerificationPolicy)
+	keys := setupKeys()
+	authSvc := auth.New(strg.(storage.UserStorage), keys, opts.AppURL, verificationPolicy, auth.MFAPolicy(opts.MFAPolicy))
+	revocations := auth.NewRevocations(strg.(storage.UserStorage), opts.RevocationInterval)
+	mediaStorage := media.NewLocalStorage(opts.MediaDir, opts.MediaURL)
+	svc := service.New(strg, mediaStorage)
+	cartSvc := service.NewCartService(strg.(storage.CartStorage))
+	orderSvc := service.NewOrderService(strg.(storage.OrderStorage), setupPaymentProvider())
+
+	if opts.RatesFile != "" {
+		importExchangeRates(svc, opts.RatesFile)
+	}
+
 	r := chi.NewMux()
 
-	server.SetupRouter(service.New(strg), authSvc, r, authSvc.ValidateAccessToken)
+	server.SetupRouter(svc, authSvc, cartSvc, orderSvc, r, authSvc.ValidateAccessToken, revocations.IsRevoked,
+		authSvc.ValidateAPIKey, verificationPolicy)
+
+	mux := http.NewServeMux()
+	mux.Handle("/media/", http.StripPrefix("/media/", http.FileServer(media.NewFileSystem(opts.MediaDir))))
+	mux.Handle("/", r)
 
 	srv := http.Server{
 		Addr:    fmt.Sprintf("%s:%d", opts.Host, opts.Port),
-		Handler: r,
+		Handler: mux,
 	}
 
-	gr, _ := errgroup.WithContext(context.Background())
+	mailWorker := mail.NewWorker(strg.(storage.OutboxStorage), setupMailer(), opts.MailInterval)
+
+	gr, gctx := errgroup.WithContext(context.Background())
 	gr.Go(srv.ListenAndServe)
 
+	gr.Go(func() error {
+		mailWorker.Run(gctx)
+		return nil
+	})
+
+	gr.Go(func() error {
+		revocations.Run(gctx)
+		return nil
+	})
+
+	if len(opts.Keys) > 0 && opts.KeysReload > 0 {
+		gr.Go(func() error {
+			keys.Watch(gctx, opts.KeysReload)
+			return nil
+		})
+	}
+
 	gr.Go(func() error {
 		sigs := make(chan os.Signal, 1)
 		signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
@@ -94,3 +160,112 @@ func main() {
 		logrus.WithError(err).Fatal("service unexpectedly stopped")
 	}
 }
+
+// logOptions logs options the service is started with, secrets are redacted.
+func logOptions() {
+	logrus.Infof("%+v", redactedOpts())
+}
+
+// redactedOpts returns copy of options with secrets hidden to be logged,
+// every option that holds secret must be redacted here.
+func redactedOpts() interface{} {
+	o := opts
+	o.SignKey = redact(o.SignKey)
+	o.PostgresDSN = redactDSN(o.PostgresDSN)
+	o.PaymentSecret = redact(o.PaymentSecret)
+	o.SMTPPassword = redact(o.SMTPPassword)
+	return o
+}
+
+func redact(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	return "<redacted>"
+}
+
+// dsnPassword matches password of key=value connection string, the value may be quoted.
+var dsnPassword = regexp.MustCompile(`(password\s*=\s*)('(?:[^'\\]|\\.)*'|\S*)`)
+
+// redactDSN hides password of postgres connection string in URL or key=value form.
+func redactDSN(dsn string) string {
+	if u, err := url.Parse(dsn); err == nil && u.Scheme != "" {
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), "redacted")
+		}
+		// password may be passed as query parameter as well
+		q := u.Query()
+		if q.Get("password") != "" {
+			q.Set("password", "redacted")
+			u.RawQuery = q.Encode()
+		}
+		return u.String()
+	}
+
+	return dsnPassword.ReplaceAllString(dsn, "${1}<redacted>")
+}
+
+func setupKeys() *auth.KeySet {
+	if len(opts.Keys) == 0 {
+		// anyone could forge tokens signed with well-known key
+		if opts.SignKey == "" || opts.SignKey == "changeme" {
+			logrus.Fatal("auth.signkey must be set to non-default value if auth.keys are not set")
+		}
+
+		logrus.Warn("JWT are signed with shared secret, set auth.keys to let other services verify tokens")
+		return auth.NewHMACKeySet(opts.SignKey)
+	}
+
+	keys, err := auth.LoadKeySet(opts.Keys...)
+	if err != nil {
+		logrus.WithError(err).Fatal("failed to load auth keys")
+	}
+
+	return keys
+}
+
+func setupPaymentProvider() payment.Provider {
+	if !opts.PaymentFake {
+		logrus.Warn("payments are disabled, set payment.fake to simulate them in development")
+		return payment.NewDisabledProvider()
+	}
+
+	if opts.PaymentSecret == "" || opts.PaymentSecret == "changeme" {
+		logrus.Fatal("payment.secret must be set to non-default value")
+	}
+
+	logrus.Warn("payments are simulated by the fake provider")
+	return payment.NewFakeProvider(opts.PaymentSecret, opts.PaymentCallbackURL, opts.PaymentDelay)
+}
+
+func setupMailer() mail.Mailer {
+	if opts.SMTPAddr != "" {
+		return mail.NewSMTPMailer(opts.SMTPAddr, opts.SMTPUsername, opts.SMTPPassword, opts.MailFrom)
+	}
+
+	if opts.MailFile == "" {
+		return mail.NewLogMailer(os.Stdout)
+	}
+
+	f, err := os.OpenFile(opts.MailFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		logrus.WithError(err).Fatal("failed to open mail file")
+	}
+
+	return mail.NewLogMailer(f)
+}
+
+func importExchangeRates(svc service.Service, path string) {
+	f, err := os.Open(path)
+	if err != nil {
+		logrus.WithError(err).Fatal("failed to open exchange rates file")
+	}
+	defer f.Close()
+
+	n, err := svc.ImportExchangeRates(context.Background(), f)
+	if err != nil {
+		logrus.WithError(err).Fatal("failed to import exchange rates")
+	}
+
+	logrus.Infof("imported %d exchange rates from %s", n, path)
+}
@@ -0,0 +1,171 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/google/uuid"
+	"github.com/vliubezny/gstore/internal/model"
+	"github.com/vliubezny/gstore/internal/storage"
+)
+
+const (
+	typeAPIKey = "api_key"
+
+	// apiKeyPrefix marks API keys, so leaked keys are easy to find by secret scanners.
+	apiKeyPrefix = "gsk_"
+	// apiKeyDisplayLen is a length of the key beginning shown to tell keys apart.
+	apiKeyDisplayLen = len(apiKeyPrefix) + 8
+)
+
+// APIKeyValidator authenticates user by API key.
+type APIKeyValidator func(ctx context.Context, key string) (AccessTokenClaims, error)
+
+func (s *authService) CreateAPIKey(ctx context.Context, userID int64, name string, scopes []model.Permission,
+	expiresAt time.Time) (string, model.APIKey, error) {
+	scopes, err := knownScopes(scopes)
+	if err != nil {
+		return "", model.APIKey{}, err
+	}
+
+	key, err := newAPIKey()
+	if err != nil {
+		return "", model.APIKey{}, fmt.Errorf("failed to generate api key: %w", err)
+	}
+
+	k, err := s.s.CreateAPIKey(ctx, model.APIKey{
+		ID:        uuid.NewString(),
+		UserID:    userID,
+		Name:      name,
+		Prefix:    key[:apiKeyDisplayLen],
+		KeyHash:   hashToken(key),
+		Scopes:    scopes,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		if errors.Is(err, storage.ErrUnknownUser) {
+			return "", model.APIKey{}, ErrNotFound
+		}
+		return "", model.APIKey{}, fmt.Errorf("failed to create api key: %w", err)
+	}
+
+	return key, k, nil
+}
+
+func (s *authService) GetAPIKeys(ctx context.Context, userID int64) ([]model.APIKey, error) {
+	keys, err := s.s.GetAPIKeys(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get api keys: %w", err)
+	}
+	return keys, nil
+}
+
+func (s *authService) DeleteAPIKey(ctx context.Context, userID int64, keyID string) error {
+	if err := s.s.DeleteAPIKey(ctx, userID, keyID); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return ErrNotFound
+		}
+		return fmt.Errorf("failed to delete api key: %w", err)
+	}
+	return nil
+}
+
+// ValidateAPIKey authenticates user by API key with permissions limited by scopes of the key.
+func (s *authService) ValidateAPIKey(ctx context.Context, key string) (AccessTokenClaims, error) {
+	if !strings.HasPrefix(key, apiKeyPrefix) {
+		return AccessTokenClaims{}, fmt.Errorf("%w: malformed api key", ErrInvalidToken)
+	}
+
+	k, err := s.s.UseAPIKey(ctx, hashToken(key))
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return AccessTokenClaims{}, fmt.Errorf("%w: unknown or expired api key", ErrInvalidToken)
+		}
+		return AccessTokenClaims{}, fmt.Errorf("failed to use api key: %w", err)
+	}
+
+	u, err := s.s.GetUserByID(ctx, k.UserID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return AccessTokenClaims{}, fmt.Errorf("%w: unknown user", ErrInvalidToken)
+		}
+		return AccessTokenClaims{}, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	if s.policy == VerificationBeforeLogin && !u.EmailVerified {
+		return AccessTokenClaims{}, fmt.Errorf("%w: email is not verified", ErrInvalidToken)
+	}
+
+	return newAPIKeyClaims(s.grantedUser(u), k), nil
+}
+
+// newAPIKeyClaims builds claims of the user authenticated by API key,
+// permissions are evaluated on every request, so role changes apply immediately.
+func newAPIKeyClaims(user model.User, key model.APIKey) AccessTokenClaims {
+	var expiresAt int64
+	if !key.ExpiresAt.IsZero() {
+		expiresAt = key.ExpiresAt.Unix()
+	}
+
+	return AccessTokenClaims{
+		TokenType:     typeAPIKey,
+		UserID:        user.ID,
+		EmailVerified: user.EmailVerified,
+		Roles:         user.Roles,
+		Permissions:   limitPermissions(user.Permissions, key.Scopes),
+		APIKeyID:      key.ID,
+		Scopes:        key.Scopes,
+		StandardClaims: jwt.StandardClaims{
+			Id:        key.ID,
+			Issuer:    issuer,
+			IssuedAt:  time.Now().Unix(),
+			ExpiresAt: expiresAt,
+		},
+	}
+}
+
+func newAPIKey() (string, error) {
+	secret, err := newSecretToken()
+	if err != nil {
+		return "", err
+	}
+	return apiKeyPrefix + secret, nil
+}
+
+// limitPermissions returns permissions within the scopes.
+func limitPermissions(permissions, scopes []model.Permission) []model.Permission {
+	limited := make([]model.Permission, 0, len(scopes))
+	for _, p := range permissions {
+		if hasPermission(scopes, p) {
+			limited = append(limited, p)
+		}
+	}
+	return limited
+}
+
+// knownScopes checks that scopes are known permissions and removes duplicates.
+func knownScopes(scopes []model.Permission) ([]model.Permission, error) {
+	unique := make([]model.Permission, 0, len(scopes))
+	for _, sc := range scopes {
+		if !hasPermission(model.AllScopes, sc) {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownScope, sc)
+		}
+		if !hasPermission(unique, sc) {
+			unique = append(unique, sc)
+		}
+	}
+	return unique, nil
+}
+
+func hasPermission(permissions []model.Permission, p model.Permission) bool {
+	for _, cp := range permissions {
+		if cp == p {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,339 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vliubezny/gstore/internal/model"
+	"github.com/vliubezny/gstore/internal/storage"
+)
+
+const testAPIKeyID = "8ab3c8a2-f698-11e6-8dd4-cb9ced3df976"
+
+func TestService_CreateAPIKey(t *testing.T) {
+	expiresAt := time.Now().Add(time.Hour)
+
+	testCases := []struct {
+		desc   string
+		scopes []model.Permission
+		rErr   error
+		err    error
+	}{
+		{
+			desc:   "success",
+			scopes: []model.Permission{model.PermissionPositionWrite, model.PermissionPositionWrite},
+			rErr:   nil,
+			err:    nil,
+		},
+		{
+			desc:   "ErrUnknownScope",
+			scopes: []model.Permission{model.PermissionPositionWrite, "test:write"},
+			rErr:   errSkip,
+			err:    ErrUnknownScope,
+		},
+		{
+			desc:   "ErrNotFound",
+			scopes: []model.Permission{model.PermissionPositionWrite},
+			rErr:   storage.ErrUnknownUser,
+			err:    ErrNotFound,
+		},
+		{
+			desc:   "create api key - error",
+			scopes: []model.Permission{model.PermissionPositionWrite},
+			rErr:   assert.AnError,
+			err:    assert.AnError,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			st := storage.NewMockUserStorage(ctrl)
+
+			var stored model.APIKey
+			if tC.rErr != errSkip {
+				st.EXPECT().CreateAPIKey(ctx, gomock.Any()).DoAndReturn(
+					func(_ context.Context, k model.APIKey) (model.APIKey, error) {
+						stored = k
+						return k, tC.rErr
+					})
+			}
+
+			s := New(st, NewHMACKeySet(signKey), testAppURL, VerificationOptional, MFAOptional)
+
+			key, k, err := s.CreateAPIKey(ctx, 1, "import", tC.scopes, expiresAt)
+
+			assert.True(t, errors.Is(err, tC.err), fmt.Sprintf("wanted %s got %s", tC.err, err))
+			if tC.err != nil {
+				assert.Empty(t, key)
+				return
+			}
+
+			assert.True(t, strings.HasPrefix(key, apiKeyPrefix))
+			assert.Equal(t, hashToken(key), stored.KeyHash, "only hash of the key must be stored")
+			assert.Equal(t, key[:apiKeyDisplayLen], k.Prefix)
+			assert.Equal(t, int64(1), k.UserID)
+			assert.Equal(t, "import", k.Name)
+			assert.Equal(t, []model.Permission{model.PermissionPositionWrite}, k.Scopes)
+			assert.Equal(t, expiresAt, k.ExpiresAt)
+		})
+	}
+}
+
+func TestService_GetAPIKeys(t *testing.T) {
+	keys := []model.APIKey{{ID: testAPIKeyID, UserID: 1, Name: "import"}}
+
+	testCases := []struct {
+		desc  string
+		rKeys []model.APIKey
+		rErr  error
+		err   error
+	}{
+		{
+			desc:  "success",
+			rKeys: keys,
+			rErr:  nil,
+			err:   nil,
+		},
+		{
+			desc:  "get api keys - error",
+			rKeys: nil,
+			rErr:  assert.AnError,
+			err:   assert.AnError,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			st := storage.NewMockUserStorage(ctrl)
+			st.EXPECT().GetAPIKeys(ctx, int64(1)).Return(tC.rKeys, tC.rErr)
+
+			s := New(st, NewHMACKeySet(signKey), testAppURL, VerificationOptional, MFAOptional)
+
+			res, err := s.GetAPIKeys(ctx, 1)
+
+			assert.True(t, errors.Is(err, tC.err), fmt.Sprintf("wanted %s got %s", tC.err, err))
+			assert.Equal(t, tC.rKeys, res)
+		})
+	}
+}
+
+func TestService_DeleteAPIKey(t *testing.T) {
+	testCases := []struct {
+		desc string
+		rErr error
+		err  error
+	}{
+		{
+			desc: "success",
+			rErr: nil,
+			err:  nil,
+		},
+		{
+			desc: "ErrNotFound",
+			rErr: storage.ErrNotFound,
+			err:  ErrNotFound,
+		},
+		{
+			desc: "delete api key - error",
+			rErr: assert.AnError,
+			err:  assert.AnError,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			st := storage.NewMockUserStorage(ctrl)
+			st.EXPECT().DeleteAPIKey(ctx, int64(1), testAPIKeyID).Return(tC.rErr)
+
+			s := New(st, NewHMACKeySet(signKey), testAppURL, VerificationOptional, MFAOptional)
+
+			err := s.DeleteAPIKey(ctx, 1, testAPIKeyID)
+
+			assert.True(t, errors.Is(err, tC.err), fmt.Sprintf("wanted %s got %s", tC.err, err))
+		})
+	}
+}
+
+func TestService_ValidateAPIKey(t *testing.T) {
+	const testKey = apiKeyPrefix + "secret"
+
+	testKeyModel := model.APIKey{
+		ID:     testAPIKeyID,
+		UserID: 1,
+		Scopes: []model.Permission{model.PermissionProductWrite, model.PermissionPositionWrite},
+	}
+	testUser := model.User{
+		ID:            1,
+		Email:         "admin@test.com",
+		EmailVerified: true,
+		Roles:         testRoles,
+		Permissions:   testPermissions,
+	}
+
+	testCases := []struct {
+		desc      string
+		key       string
+		policy    VerificationPolicy
+		mfaPolicy MFAPolicy
+		rUseErr   error
+		rUser     model.User
+		rUserErr  error
+		claims    AccessTokenClaims
+		err       error
+	}{
+		{
+			desc:      "success",
+			key:       testKey,
+			policy:    VerificationOptional,
+			mfaPolicy: MFAOptional,
+			rUseErr:   nil,
+			rUser:     testUser,
+			rUserErr:  nil,
+			claims: AccessTokenClaims{
+				TokenType:     typeAPIKey,
+				UserID:        1,
+				EmailVerified: true,
+				Roles:         testRoles,
+				Permissions:   []model.Permission{model.PermissionProductWrite},
+				APIKeyID:      testAPIKeyID,
+				Scopes:        testKeyModel.Scopes,
+			},
+			err: nil,
+		},
+		{
+			desc:      "roles are not granted without MFA",
+			key:       testKey,
+			policy:    VerificationOptional,
+			mfaPolicy: MFARequiredForAdmins,
+			rUseErr:   nil,
+			rUser:     testUser,
+			rUserErr:  nil,
+			claims: AccessTokenClaims{
+				TokenType:     typeAPIKey,
+				UserID:        1,
+				EmailVerified: true,
+				Permissions:   []model.Permission{},
+				APIKeyID:      testAPIKeyID,
+				Scopes:        testKeyModel.Scopes,
+			},
+			err: nil,
+		},
+		{
+			desc:      "malformed key - ErrInvalidToken",
+			key:       "secret",
+			policy:    VerificationOptional,
+			mfaPolicy: MFAOptional,
+			rUseErr:   errSkip,
+			rUserErr:  errSkip,
+			err:       ErrInvalidToken,
+		},
+		{
+			desc:      "unknown key - ErrInvalidToken",
+			key:       testKey,
+			policy:    VerificationOptional,
+			mfaPolicy: MFAOptional,
+			rUseErr:   storage.ErrNotFound,
+			rUserErr:  errSkip,
+			err:       ErrInvalidToken,
+		},
+		{
+			desc:      "use api key - error",
+			key:       testKey,
+			policy:    VerificationOptional,
+			mfaPolicy: MFAOptional,
+			rUseErr:   assert.AnError,
+			rUserErr:  errSkip,
+			err:       assert.AnError,
+		},
+		{
+			desc:      "unknown user - ErrInvalidToken",
+			key:       testKey,
+			policy:    VerificationOptional,
+			mfaPolicy: MFAOptional,
+			rUseErr:   nil,
+			rUserErr:  storage.ErrNotFound,
+			err:       ErrInvalidToken,
+		},
+		{
+			desc:      "get user - error",
+			key:       testKey,
+			policy:    VerificationOptional,
+			mfaPolicy: MFAOptional,
+			rUseErr:   nil,
+			rUserErr:  assert.AnError,
+			err:       assert.AnError,
+		},
+		{
+			desc:      "unverified email - ErrInvalidToken",
+			key:       testKey,
+			policy:    VerificationBeforeLogin,
+			mfaPolicy: MFAOptional,
+			rUseErr:   nil,
+			rUser:     model.User{ID: 1, Email: "admin@test.com"},
+			rUserErr:  nil,
+			err:       ErrInvalidToken,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			st := storage.NewMockUserStorage(ctrl)
+
+			if tC.rUseErr != errSkip {
+				st.EXPECT().UseAPIKey(ctx, hashToken(tC.key)).Return(testKeyModel, tC.rUseErr)
+			}
+
+			if tC.rUserErr != errSkip {
+				st.EXPECT().GetUserByID(ctx, int64(1)).Return(tC.rUser, tC.rUserErr)
+			}
+
+			s := New(st, NewHMACKeySet(signKey), testAppURL, tC.policy, tC.mfaPolicy)
+
+			claims, err := s.ValidateAPIKey(ctx, tC.key)
+
+			assert.True(t, errors.Is(err, tC.err), fmt.Sprintf("wanted %s got %s", tC.err, err))
+			if tC.err != nil {
+				return
+			}
+
+			assert.WithinDuration(t, time.Now(), time.Unix(claims.IssuedAt, 0), time.Minute)
+			assert.Equal(t, testAPIKeyID, claims.Id)
+			claims.StandardClaims = tC.claims.StandardClaims
+			assert.Equal(t, tC.claims, claims)
+		})
+	}
+}
+
+func TestAccessTokenClaims_InScope(t *testing.T) {
+	c := AccessTokenClaims{Permissions: testPermissions}
+	assert.True(t, c.InScope(model.PermissionPositionWrite), "access token must not be limited")
+
+	c.APIKeyID = testAPIKeyID
+	c.Scopes = []model.Permission{model.PermissionPositionWrite}
+	assert.True(t, c.InScope(model.PermissionPositionWrite))
+	assert.False(t, c.InScope(model.PermissionStoreWrite))
+}
+
+func Test_knownScopes(t *testing.T) {
+	scopes, err := knownScopes([]model.Permission{model.ScopeCart, model.PermissionOrderRead, model.ScopeCart})
+	require.NoError(t, err)
+	assert.Equal(t, []model.Permission{model.ScopeCart, model.PermissionOrderRead}, scopes)
+
+	_, err = knownScopes([]model.Permission{"cart:write"})
+	assert.True(t, errors.Is(err, ErrUnknownScope))
+}
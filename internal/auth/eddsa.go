@@ -0,0 +1,54 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"errors"
+
+	jwt "github.com/dgrijalva/jwt-go"
+)
+
+// signingMethodEdDSA implements EdDSA signing method with Ed25519 keys (RFC 8037),
+// jwt-go v3 supports RSA, ECDSA and HMAC only.
+type signingMethodEdDSA struct{}
+
+// SigningMethodEdDSA signs tokens with ed25519.PrivateKey and verifies with ed25519.PublicKey.
+var SigningMethodEdDSA jwt.SigningMethod = signingMethodEdDSA{}
+
+var errEdDSAVerification = errors.New("ed25519: verification error")
+
+func init() {
+	jwt.RegisterSigningMethod(SigningMethodEdDSA.Alg(), func() jwt.SigningMethod {
+		return SigningMethodEdDSA
+	})
+}
+
+func (m signingMethodEdDSA) Alg() string {
+	return "EdDSA"
+}
+
+func (m signingMethodEdDSA) Verify(signingString, signature string, key interface{}) error {
+	pub, ok := key.(ed25519.PublicKey)
+	if !ok || len(pub) != ed25519.PublicKeySize {
+		return jwt.ErrInvalidKeyType
+	}
+
+	sig, err := jwt.DecodeSegment(signature)
+	if err != nil {
+		return err
+	}
+
+	if !ed25519.Verify(pub, []byte(signingString), sig) {
+		return errEdDSAVerification
+	}
+
+	return nil
+}
+
+func (m signingMethodEdDSA) Sign(signingString string, key interface{}) (string, error) {
+	priv, ok := key.(ed25519.PrivateKey)
+	if !ok || len(priv) != ed25519.PrivateKeySize {
+		return "", jwt.ErrInvalidKeyType
+	}
+
+	return jwt.EncodeSegment(ed25519.Sign(priv, []byte(signingString))), nil
+}
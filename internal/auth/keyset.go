@@ -0,0 +1,279 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/sirupsen/logrus"
+)
+
+const minRSAKeyBits = 2048
+
+// PublicKey is a public part of a key that signs tokens.
+type PublicKey struct {
+	// ID is set in kid header of tokens signed by the key.
+	ID string
+	// Algorithm is a JWS algorithm of the key: RS256 or EdDSA.
+	Algorithm string
+	// Key is *rsa.PublicKey or ed25519.PublicKey.
+	Key crypto.PublicKey
+}
+
+// signingKey is a key to sign and verify tokens.
+type signingKey struct {
+	id      string
+	method  jwt.SigningMethod
+	private interface{}
+	public  interface{}
+}
+
+// KeySet holds keys to sign and verify tokens.
+// Only the last loaded key signs new tokens, while the others keep verifying tokens signed before rotation.
+type KeySet struct {
+	paths []string
+
+	mu     sync.RWMutex
+	signer signingKey
+	keys   map[string]signingKey
+}
+
+// NewHMACKeySet creates key set that signs and verifies tokens with shared secret (HS256).
+func NewHMACKeySet(secret string) *KeySet {
+	k := signingKey{
+		method:  jwt.SigningMethodHS256,
+		private: []byte(secret),
+		public:  []byte(secret),
+	}
+
+	return &KeySet{
+		signer: k,
+		keys:   map[string]signingKey{k.id: k},
+	}
+}
+
+// LoadKeySet loads RSA and Ed25519 private keys from PEM files.
+// Paths are files or directories of *.pem files, keys of directory are loaded in order of file names,
+// so naming files by creation date (e.g. 2021-03-01.pem) makes the newest key sign tokens.
+func LoadKeySet(paths ...string) (*KeySet, error) {
+	ks := &KeySet{paths: paths}
+	if err := ks.Reload(); err != nil {
+		return nil, err
+	}
+	return ks, nil
+}
+
+// Reload loads keys again, the key set is not changed on failure.
+func (ks *KeySet) Reload() error {
+	files, err := keyFiles(ks.paths)
+	if err != nil {
+		return err
+	}
+
+	if len(files) == 0 {
+		return errors.New("no keys found")
+	}
+
+	keys := make(map[string]signingKey, len(files))
+	var signer signingKey
+	for _, f := range files {
+		k, err := readKeyFile(f)
+		if err != nil {
+			return fmt.Errorf("failed to read key %s: %w", f, err)
+		}
+		keys[k.id] = k
+		signer = k
+	}
+
+	ks.mu.Lock()
+	changed := ks.signer.id != signer.id
+	ks.signer, ks.keys = signer, keys
+	ks.mu.Unlock()
+
+	if changed {
+		logrus.WithFields(logrus.Fields{
+			"kid":  signer.id,
+			"alg":  signer.method.Alg(),
+			"keys": len(keys),
+		}).Info("signing key changed")
+	}
+
+	return nil
+}
+
+// Watch reloads keys with the interval until the context is done.
+func (ks *KeySet) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := ks.Reload(); err != nil {
+				logrus.WithError(err).Error("failed to reload keys")
+			}
+		}
+	}
+}
+
+// PublicKeys returns public keys to verify tokens, keys of shared secret are not published.
+func (ks *KeySet) PublicKeys() []PublicKey {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	keys := make([]PublicKey, 0, len(ks.keys))
+	for _, k := range ks.keys {
+		if k.id == "" {
+			continue
+		}
+		keys = append(keys, PublicKey{ID: k.id, Algorithm: k.method.Alg(), Key: k.public})
+	}
+
+	sort.Slice(keys, func(i, j int) bool { return keys[i].ID < keys[j].ID })
+
+	return keys
+}
+
+// sign signs claims with the current signing key.
+func (ks *KeySet) sign(claims jwt.Claims) (string, error) {
+	ks.mu.RLock()
+	k := ks.signer
+	ks.mu.RUnlock()
+
+	t := jwt.NewWithClaims(k.method, claims)
+	if k.id != "" {
+		t.Header["kid"] = k.id
+	}
+
+	return t.SignedString(k.private)
+}
+
+// keyFunc looks up a key to verify the token by its kid header.
+func (ks *KeySet) keyFunc(t *jwt.Token) (interface{}, error) {
+	kid, _ := t.Header["kid"].(string)
+
+	ks.mu.RLock()
+	k, ok := ks.keys[kid]
+	ks.mu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown key %q", kid)
+	}
+
+	if t.Method.Alg() != k.method.Alg() {
+		return nil, fmt.Errorf("token must be signed with %s alg", k.method.Alg())
+	}
+
+	return k.public, nil
+}
+
+func keyFiles(paths []string) ([]string, error) {
+	var files []string
+	for _, p := range paths {
+		info, err := os.Stat(p)
+		if err != nil {
+			return nil, err
+		}
+
+		if !info.IsDir() {
+			files = append(files, p)
+			continue
+		}
+
+		matches, err := filepath.Glob(filepath.Join(p, "*.pem"))
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(matches)
+		files = append(files, matches...)
+	}
+	return files, nil
+}
+
+func readKeyFile(path string) (signingKey, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return signingKey{}, err
+	}
+	return parseKey(data)
+}
+
+// parseKey parses PEM encoded PKCS #1 RSA or PKCS #8 RSA/Ed25519 private key.
+func parseKey(data []byte) (signingKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return signingKey{}, errors.New("no PEM data")
+	}
+
+	var (
+		priv interface{}
+		err  error
+	)
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		priv, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		priv, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		return signingKey{}, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return signingKey{}, err
+	}
+
+	switch k := priv.(type) {
+	case *rsa.PrivateKey:
+		if k.N.BitLen() < minRSAKeyBits {
+			return signingKey{}, fmt.Errorf("RSA key must be at least %d bits", minRSAKeyBits)
+		}
+		return signingKey{
+			id:      thumbprint(&k.PublicKey),
+			method:  jwt.SigningMethodRS256,
+			private: k,
+			public:  &k.PublicKey,
+		}, nil
+	case ed25519.PrivateKey:
+		pub := k.Public().(ed25519.PublicKey)
+		return signingKey{
+			id:      thumbprint(pub),
+			method:  SigningMethodEdDSA,
+			private: k,
+			public:  pub,
+		}, nil
+	}
+
+	return signingKey{}, fmt.Errorf("unsupported key type %T", priv)
+}
+
+// thumbprint returns JWK thumbprint of the key (RFC 7638), so every instance derives the same key ID.
+func thumbprint(pub crypto.PublicKey) string {
+	var jwk string
+	switch k := pub.(type) {
+	case *rsa.PublicKey:
+		jwk = fmt.Sprintf(`{"e":"%s","kty":"RSA","n":"%s"}`,
+			base64.RawURLEncoding.EncodeToString(big.NewInt(int64(k.E)).Bytes()),
+			base64.RawURLEncoding.EncodeToString(k.N.Bytes()))
+	case ed25519.PublicKey:
+		jwk = fmt.Sprintf(`{"crv":"Ed25519","kty":"OKP","x":"%s"}`, base64.RawURLEncoding.EncodeToString(k))
+	}
+
+	sum := sha256.Sum256([]byte(jwk))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,193 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vliubezny/gstore/internal/model"
+)
+
+func writeKey(t *testing.T, path string, key interface{}) {
+	t.Helper()
+
+	var block *pem.Block
+	if k, ok := key.(*rsa.PrivateKey); ok {
+		block = &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(k)}
+	} else {
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		require.NoError(t, err)
+		block = &pem.Block{Type: "PRIVATE KEY", Bytes: der}
+	}
+
+	require.NoError(t, ioutil.WriteFile(path, pem.EncodeToMemory(block), 0600))
+}
+
+func TestKeySet_rotation(t *testing.T) {
+	dir := t.TempDir()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	writeKey(t, filepath.Join(dir, "2021-01-01.pem"), rsaKey)
+
+	ks, err := LoadKeySet(dir)
+	require.NoError(t, err)
+
+	s := New(nil, ks, testAppURL, VerificationOptional, MFAOptional)
+	u := model.User{ID: 1, Email: "admin@test.com"}
+
+	oldToken, err := ks.sign(newAccessClaims(u))
+	require.NoError(t, err)
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	writeKey(t, filepath.Join(dir, "2021-02-01.pem"), edKey)
+
+	require.NoError(t, ks.Reload())
+
+	newToken, err := ks.sign(newAccessClaims(u))
+	require.NoError(t, err)
+
+	parsed, _, err := new(jwt.Parser).ParseUnverified(newToken, &AccessTokenClaims{})
+	require.NoError(t, err)
+	assert.Equal(t, "EdDSA", parsed.Method.Alg())
+	assert.Equal(t, thumbprint(edKey.Public()), parsed.Header["kid"])
+
+	_, err = s.ValidateAccessToken(newToken)
+	assert.NoError(t, err)
+
+	_, err = s.ValidateAccessToken(oldToken)
+	assert.NoError(t, err, "old key must verify tokens until removed")
+
+	keys := s.PublicKeys()
+	assert.Len(t, keys, 2)
+
+	require.NoError(t, os.Remove(filepath.Join(dir, "2021-01-01.pem")))
+	require.NoError(t, ks.Reload())
+
+	_, err = s.ValidateAccessToken(oldToken)
+	assert.Error(t, err, "removed key must not verify tokens")
+
+	_, err = s.ValidateAccessToken(newToken)
+	assert.NoError(t, err)
+}
+
+func TestKeySet_Reload_keepsKeysOnError(t *testing.T) {
+	dir := t.TempDir()
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	writeKey(t, filepath.Join(dir, "key.pem"), edKey)
+
+	ks, err := LoadKeySet(dir)
+	require.NoError(t, err)
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "broken.pem"), []byte("test"), 0600))
+	assert.Error(t, ks.Reload())
+
+	assert.Len(t, ks.PublicKeys(), 1)
+}
+
+func TestKeySet_keyFunc(t *testing.T) {
+	hmac := NewHMACKeySet(signKey)
+	assert.Empty(t, hmac.PublicKeys(), "shared secret must not be published")
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "key.pem")
+	writeKey(t, path, edKey)
+
+	ed, err := LoadKeySet(path)
+	require.NoError(t, err)
+
+	c := newAccessClaims(model.User{ID: 1})
+
+	hmacToken, err := hmac.sign(c)
+	require.NoError(t, err)
+
+	_, err = jwt.ParseWithClaims(hmacToken, &AccessTokenClaims{}, ed.keyFunc)
+	assert.Error(t, err, "token without kid must be rejected")
+
+	// token signed with shared secret but pointing to asymmetric key
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, c)
+	forged.Header["kid"] = thumbprint(edKey.Public())
+	forgedToken, err := forged.SignedString([]byte(edKey.Public().(ed25519.PublicKey)))
+	require.NoError(t, err)
+
+	_, err = jwt.ParseWithClaims(forgedToken, &AccessTokenClaims{}, ed.keyFunc)
+	assert.Error(t, err, "algorithm must match the key")
+}
+
+func Test_parseKey(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	ecDER, err := x509.MarshalPKCS8PrivateKey(ecKey)
+	require.NoError(t, err)
+
+	smallKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		desc string
+		data []byte
+	}{
+		{
+			desc: "not PEM",
+			data: []byte("test"),
+		},
+		{
+			desc: "public key",
+			data: pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: []byte("test")}),
+		},
+		{
+			desc: "ECDSA key",
+			data: pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: ecDER}),
+		},
+		{
+			desc: "small RSA key",
+			data: pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(smallKey)}),
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			_, err := parseKey(tC.data)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func Test_thumbprint(t *testing.T) {
+	// RFC 8037 appendix A.3
+	x, err := base64.RawURLEncoding.DecodeString("11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo")
+	require.NoError(t, err)
+
+	assert.Equal(t, "kPrK_qmxVWaYVA9wwBF6Iuo3vVzz7TxHCTwXBygrS4k", thumbprint(ed25519.PublicKey(x)))
+}
+
+func TestSigningMethodEdDSA(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	sig, err := SigningMethodEdDSA.Sign("test", priv)
+	require.NoError(t, err)
+
+	assert.NoError(t, SigningMethodEdDSA.Verify("test", sig, pub))
+	assert.Error(t, SigningMethodEdDSA.Verify("test2", sig, pub))
+	assert.Equal(t, jwt.ErrInvalidKeyType, SigningMethodEdDSA.Verify("test", sig, []byte(signKey)))
+
+	_, err = SigningMethodEdDSA.Sign("test", []byte(signKey))
+	assert.Equal(t, jwt.ErrInvalidKeyType, err)
+}
@@ -0,0 +1,242 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/google/uuid"
+
+	"github.com/vliubezny/gstore/internal/model"
+	"github.com/vliubezny/gstore/internal/storage"
+)
+
+const (
+	mfaTokenTTL = 5 * time.Minute
+
+	recoveryCodeCount = 10
+)
+
+// mfaClaims specifies the claims for MFA challenge token issued after password check.
+type mfaClaims struct {
+	TokenType string `json:"type,omitempty"`
+	UserID    int64  `json:"userId,omitempty"`
+	jwt.StandardClaims
+}
+
+func (s *authService) VerifyMFA(ctx context.Context, mfaToken, code string, client Client) (TokenPair, error) {
+	claims, err := validateMFAToken(mfaToken, s.keys)
+	if err != nil {
+		return TokenPair{}, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	u, err := s.s.GetUserByID(ctx, claims.UserID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return TokenPair{}, fmt.Errorf("%w: missing user", ErrInvalidToken)
+		}
+		return TokenPair{}, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	if !u.MFAEnabled {
+		return TokenPair{}, fmt.Errorf("%w: mfa has been disabled", ErrInvalidToken)
+	}
+
+	account, ipSubject := accountSubject(u.Email), clientSubject(client.IP)
+
+	if err := s.checkLoginFailures(ctx, account, ipSubject); err != nil {
+		return TokenPair{}, err
+	}
+
+	if err := s.checkMFACode(ctx, u, code); err != nil {
+		if errors.Is(err, ErrInvalidMFACode) {
+			return TokenPair{}, s.loginFailed(ctx, account, ipSubject, err)
+		}
+		return TokenPair{}, err
+	}
+
+	if err = s.s.DeleteLoginFailures(ctx, account); err != nil {
+		return TokenPair{}, fmt.Errorf("failed to delete login failures: %w", err)
+	}
+
+	return s.issueTokens(ctx, u, delegation{}, client)
+}
+
+func (s *authService) EnrollMFA(ctx context.Context, userID int64) (MFAEnrollment, error) {
+	u, err := s.getUser(ctx, userID)
+	if err != nil {
+		return MFAEnrollment{}, err
+	}
+
+	if u.MFAEnabled {
+		return MFAEnrollment{}, ErrMFAEnabled
+	}
+
+	secret, err := newTOTPSecret()
+	if err != nil {
+		return MFAEnrollment{}, fmt.Errorf("failed to generate totp secret: %w", err)
+	}
+
+	if err = s.s.SetMFASecret(ctx, u.ID, secret); err != nil {
+		return MFAEnrollment{}, fmt.Errorf("failed to set mfa secret: %w", err)
+	}
+
+	return MFAEnrollment{Secret: secret, URI: totpURI(u.Email, secret)}, nil
+}
+
+func (s *authService) ConfirmMFA(ctx context.Context, userID int64, code string) ([]string, error) {
+	u, err := s.getUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case u.MFAEnabled:
+		return nil, ErrMFAEnabled
+	case u.MFASecret == "":
+		return nil, ErrMFANotEnrolled
+	}
+
+	step, ok := validateTOTP(u.MFASecret, code, time.Now())
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		if codes[i], err = newRecoveryCode(); err != nil {
+			return nil, fmt.Errorf("failed to generate recovery code: %w", err)
+		}
+		hashes[i] = hashRecoveryCode(codes[i])
+	}
+
+	if err = s.s.InTx(ctx, func(us storage.UserStorage) error {
+		if err := us.EnableMFA(ctx, u.ID, hashes); err != nil {
+			return fmt.Errorf("failed to enable mfa: %w", err)
+		}
+
+		if err := us.UseMFAStep(ctx, u.ID, step); err != nil {
+			return fmt.Errorf("failed to use mfa step: %w", err)
+		}
+
+		// sessions started with password only must pass second factor
+		if err := us.DeleteUserTokens(ctx, u.ID); err != nil {
+			return fmt.Errorf("failed to delete user tokens: %w", err)
+		}
+
+		if err := us.RevokeUserTokens(ctx, u.ID); err != nil {
+			return fmt.Errorf("failed to revoke user tokens: %w", err)
+		}
+
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+func (s *authService) DisableMFA(ctx context.Context, userID int64, code, ip string) error {
+	u, err := s.getUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if !u.MFAEnabled {
+		return ErrMFANotEnrolled
+	}
+
+	account, client := accountSubject(u.Email), clientSubject(ip)
+
+	if err := s.checkLoginFailures(ctx, account, client); err != nil {
+		return err
+	}
+
+	if err := s.checkMFACode(ctx, u, code); err != nil {
+		if errors.Is(err, ErrInvalidMFACode) {
+			return s.loginFailed(ctx, account, client, err)
+		}
+		return err
+	}
+
+	if err = s.s.DisableMFA(ctx, u.ID); err != nil {
+		return fmt.Errorf("failed to disable mfa: %w", err)
+	}
+
+	return nil
+}
+
+func (s *authService) getUser(ctx context.Context, userID int64) (model.User, error) {
+	u, err := s.s.GetUserByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return model.User{}, ErrNotFound
+		}
+		return model.User{}, fmt.Errorf("failed to get user: %w", err)
+	}
+	return u, nil
+}
+
+// checkMFACode accepts TOTP code or unused recovery code of the user.
+func (s *authService) checkMFACode(ctx context.Context, u model.User, code string) error {
+	if step, ok := validateTOTP(u.MFASecret, code, time.Now()); ok {
+		if err := s.s.UseMFAStep(ctx, u.ID, step); err != nil {
+			if errors.Is(err, storage.ErrNotFound) { // reject replayed code
+				return fmt.Errorf("%w: code has been used", ErrInvalidMFACode)
+			}
+			return fmt.Errorf("failed to use mfa step: %w", err)
+		}
+		return nil
+	}
+
+	if err := s.s.UseRecoveryCode(ctx, u.ID, hashRecoveryCode(code)); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return ErrInvalidMFACode
+		}
+		return fmt.Errorf("failed to use recovery code: %w", err)
+	}
+
+	return nil
+}
+
+// grantedUser strips roles of the user if MFA policy requires second factor to use them.
+func (s *authService) grantedUser(u model.User) model.User {
+	if s.mfaPolicy == MFARequiredForAdmins && !u.MFAEnabled && len(u.Roles) > 0 {
+		u.Roles = nil
+		u.Permissions = nil
+	}
+	return u
+}
+
+func newMFAClaims(user model.User) mfaClaims {
+	return mfaClaims{
+		TokenType: typeMFA,
+		UserID:    user.ID,
+		StandardClaims: jwt.StandardClaims{
+			Id:        uuid.NewString(),
+			Issuer:    issuer,
+			IssuedAt:  time.Now().Unix(),
+			ExpiresAt: time.Now().Add(mfaTokenTTL).Unix(),
+		},
+	}
+}
+
+func validateMFAToken(token string, keys *KeySet) (mfaClaims, error) {
+	mt, err := jwt.ParseWithClaims(token, &mfaClaims{}, keys.keyFunc)
+
+	if err != nil {
+		return mfaClaims{}, fmt.Errorf("unable to parse claims: %w", err)
+	}
+
+	claims, ok := mt.Claims.(*mfaClaims)
+	if !ok || !mt.Valid {
+		return mfaClaims{}, errors.New("invalid token")
+	}
+	if claims.TokenType != typeMFA {
+		return mfaClaims{}, fmt.Errorf("invalid mfa token: type %s", claims.TokenType)
+	}
+	return *claims, nil
+}
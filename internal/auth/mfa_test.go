@@ -0,0 +1,481 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vliubezny/gstore/internal/model"
+	"github.com/vliubezny/gstore/internal/storage"
+)
+
+var testMFAUser = model.User{
+	ID: 1, Email: "admin@test.com", PasswordHash: testHash, MFASecret: rfcSecret, MFAEnabled: true,
+	Roles: testRoles, Permissions: testPermissions,
+}
+
+func mustCreateMFAToken(u model.User) string {
+	c := newMFAClaims(u)
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, c).SignedString([]byte(signKey))
+	if err != nil {
+		panic(err)
+	}
+	return token
+}
+
+func currentTOTPCode() string {
+	return totpCode([]byte("12345678901234567890"), time.Now().Unix()/totpPeriod)
+}
+
+func TestService_Login_mfaChallenge(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	st := storage.NewMockUserStorage(ctrl)
+	st.EXPECT().GetLoginFailures(ctx, gomock.Any()).Return(nil, nil)
+	st.EXPECT().GetUserByEmail(ctx, testMFAUser.Email).Return(testMFAUser, nil)
+	st.EXPECT().DeleteLoginFailures(ctx, gomock.Any()).Return(nil)
+
+	s := New(st, NewHMACKeySet(signKey), testAppURL, VerificationOptional, MFAOptional)
+
+	pair, err := s.Login(ctx, testMFAUser.Email, testPass, testClient)
+	require.NoError(t, err)
+
+	assert.Empty(t, pair.AccessToken)
+	assert.Empty(t, pair.RefreshToken)
+
+	claims, err := validateMFAToken(pair.MFAToken, NewHMACKeySet(signKey))
+	require.NoError(t, err)
+	assert.Equal(t, testMFAUser.ID, claims.UserID)
+}
+
+func TestService_VerifyMFA(t *testing.T) {
+	testCases := []struct {
+		desc      string
+		token     string
+		code      string
+		rUser     model.User
+		rUserErr  error
+		rStepErr  error
+		rCodeErr  error
+		rTokenErr error
+		err       error
+	}{
+		{
+			desc:      "success - totp",
+			token:     mustCreateMFAToken(testMFAUser),
+			code:      currentTOTPCode(),
+			rUser:     testMFAUser,
+			rUserErr:  nil,
+			rStepErr:  nil,
+			rCodeErr:  errSkip,
+			rTokenErr: nil,
+			err:       nil,
+		},
+		{
+			desc:      "success - recovery code",
+			token:     mustCreateMFAToken(testMFAUser),
+			code:      "abcde-fghij",
+			rUser:     testMFAUser,
+			rUserErr:  nil,
+			rStepErr:  errSkip,
+			rCodeErr:  nil,
+			rTokenErr: nil,
+			err:       nil,
+		},
+		{
+			desc:      "malformed token - ErrInvalidToken",
+			token:     "test",
+			code:      currentTOTPCode(),
+			rUserErr:  errSkip,
+			rStepErr:  errSkip,
+			rCodeErr:  errSkip,
+			rTokenErr: errSkip,
+			err:       ErrInvalidToken,
+		},
+		{
+			desc:      "refresh token - ErrInvalidToken",
+			token:     mustCreateRefreshToken(testMFAUser),
+			code:      currentTOTPCode(),
+			rUserErr:  errSkip,
+			rStepErr:  errSkip,
+			rCodeErr:  errSkip,
+			rTokenErr: errSkip,
+			err:       ErrInvalidToken,
+		},
+		{
+			desc:      "mfa disabled - ErrInvalidToken",
+			token:     mustCreateMFAToken(testMFAUser),
+			code:      currentTOTPCode(),
+			rUser:     model.User{ID: 1, Email: "admin@test.com"},
+			rUserErr:  nil,
+			rStepErr:  errSkip,
+			rCodeErr:  errSkip,
+			rTokenErr: errSkip,
+			err:       ErrInvalidToken,
+		},
+		{
+			desc:      "get user - error",
+			token:     mustCreateMFAToken(testMFAUser),
+			code:      currentTOTPCode(),
+			rUserErr:  assert.AnError,
+			rStepErr:  errSkip,
+			rCodeErr:  errSkip,
+			rTokenErr: errSkip,
+			err:       assert.AnError,
+		},
+		{
+			desc:      "replayed code - ErrInvalidMFACode",
+			token:     mustCreateMFAToken(testMFAUser),
+			code:      currentTOTPCode(),
+			rUser:     testMFAUser,
+			rUserErr:  nil,
+			rStepErr:  storage.ErrNotFound,
+			rCodeErr:  errSkip,
+			rTokenErr: errSkip,
+			err:       ErrInvalidMFACode,
+		},
+		{
+			desc:      "unknown recovery code - ErrInvalidMFACode",
+			token:     mustCreateMFAToken(testMFAUser),
+			code:      "abcde-fghij",
+			rUser:     testMFAUser,
+			rUserErr:  nil,
+			rStepErr:  errSkip,
+			rCodeErr:  storage.ErrNotFound,
+			rTokenErr: errSkip,
+			err:       ErrInvalidMFACode,
+		},
+		{
+			desc:      "save token - error",
+			token:     mustCreateMFAToken(testMFAUser),
+			code:      currentTOTPCode(),
+			rUser:     testMFAUser,
+			rUserErr:  nil,
+			rStepErr:  nil,
+			rCodeErr:  errSkip,
+			rTokenErr: assert.AnError,
+			err:       assert.AnError,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			st := storage.NewMockUserStorage(ctrl)
+
+			if tC.rUserErr != errSkip {
+				st.EXPECT().GetUserByID(ctx, testMFAUser.ID).Return(tC.rUser, tC.rUserErr)
+			}
+
+			if tC.rStepErr != errSkip || tC.rCodeErr != errSkip {
+				st.EXPECT().GetLoginFailures(ctx, []string{"account:admin@test.com", "ip:" + testIP}).Return(nil, nil)
+			}
+
+			if tC.rStepErr != errSkip {
+				st.EXPECT().UseMFAStep(ctx, testMFAUser.ID, time.Now().Unix()/totpPeriod).Return(tC.rStepErr)
+			}
+
+			if tC.rCodeErr != errSkip {
+				st.EXPECT().UseRecoveryCode(ctx, testMFAUser.ID, hashRecoveryCode(tC.code)).Return(tC.rCodeErr)
+			}
+
+			if tC.err == ErrInvalidMFACode {
+				st.EXPECT().AddLoginFailure(ctx, gomock.Any(), gomock.Any()).Return(model.LoginFailures{}, nil).Times(2)
+			}
+
+			if tC.rTokenErr != errSkip {
+				st.EXPECT().DeleteLoginFailures(ctx, "account:admin@test.com").Return(nil)
+				st.EXPECT().SaveToken(ctx, gomock.Any()).Return(tC.rTokenErr)
+			}
+
+			s := New(st, NewHMACKeySet(signKey), testAppURL, VerificationOptional, MFAOptional)
+
+			pair, err := s.VerifyMFA(ctx, tC.token, tC.code, testClient)
+
+			assert.True(t, errors.Is(err, tC.err), fmt.Sprintf("wanted %s got %s", tC.err, err))
+			if err == nil {
+				assert.NotEmpty(t, pair.AccessToken)
+				assert.NotEmpty(t, pair.RefreshToken)
+			}
+		})
+	}
+}
+
+func TestService_EnrollMFA(t *testing.T) {
+	testCases := []struct {
+		desc       string
+		rUser      model.User
+		rUserErr   error
+		rSecretErr error
+		err        error
+	}{
+		{
+			desc:       "success",
+			rUser:      model.User{ID: 1, Email: "admin@test.com"},
+			rUserErr:   nil,
+			rSecretErr: nil,
+			err:        nil,
+		},
+		{
+			desc:       "ErrNotFound",
+			rUserErr:   storage.ErrNotFound,
+			rSecretErr: errSkip,
+			err:        ErrNotFound,
+		},
+		{
+			desc:       "ErrMFAEnabled",
+			rUser:      testMFAUser,
+			rUserErr:   nil,
+			rSecretErr: errSkip,
+			err:        ErrMFAEnabled,
+		},
+		{
+			desc:       "set secret - error",
+			rUser:      model.User{ID: 1, Email: "admin@test.com"},
+			rUserErr:   nil,
+			rSecretErr: assert.AnError,
+			err:        assert.AnError,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			st := storage.NewMockUserStorage(ctrl)
+			st.EXPECT().GetUserByID(ctx, int64(1)).Return(tC.rUser, tC.rUserErr)
+
+			var secret string
+			if tC.rSecretErr != errSkip {
+				st.EXPECT().SetMFASecret(ctx, int64(1), gomock.Any()).
+					DoAndReturn(func(_ context.Context, _ int64, s string) error {
+						secret = s
+						return tC.rSecretErr
+					})
+			}
+
+			s := New(st, NewHMACKeySet(signKey), testAppURL, VerificationOptional, MFAOptional)
+
+			e, err := s.EnrollMFA(ctx, 1)
+
+			assert.True(t, errors.Is(err, tC.err), fmt.Sprintf("wanted %s got %s", tC.err, err))
+			if err == nil {
+				assert.Equal(t, secret, e.Secret)
+				assert.Equal(t, totpURI("admin@test.com", secret), e.URI)
+			}
+		})
+	}
+}
+
+func TestService_ConfirmMFA(t *testing.T) {
+	enrolled := model.User{ID: 1, Email: "admin@test.com", MFASecret: rfcSecret}
+	testCases := []struct {
+		desc        string
+		rUser       model.User
+		code        string
+		rEnableErr  error
+		rStepErr    error
+		rDeleteErr  error
+		rRevokeErr  error
+		err         error
+		recoveryLen int
+	}{
+		{
+			desc:        "success",
+			rUser:       enrolled,
+			code:        currentTOTPCode(),
+			rEnableErr:  nil,
+			rStepErr:    nil,
+			rDeleteErr:  nil,
+			rRevokeErr:  nil,
+			err:         nil,
+			recoveryLen: recoveryCodeCount,
+		},
+		{
+			desc:       "ErrMFAEnabled",
+			rUser:      testMFAUser,
+			code:       currentTOTPCode(),
+			rEnableErr: errSkip,
+			rStepErr:   errSkip,
+			rDeleteErr: errSkip,
+			rRevokeErr: errSkip,
+			err:        ErrMFAEnabled,
+		},
+		{
+			desc:       "ErrMFANotEnrolled",
+			rUser:      model.User{ID: 1, Email: "admin@test.com"},
+			code:       currentTOTPCode(),
+			rEnableErr: errSkip,
+			rStepErr:   errSkip,
+			rDeleteErr: errSkip,
+			rRevokeErr: errSkip,
+			err:        ErrMFANotEnrolled,
+		},
+		{
+			desc:       "ErrInvalidMFACode",
+			rUser:      enrolled,
+			code:       "abcdef",
+			rEnableErr: errSkip,
+			rStepErr:   errSkip,
+			rDeleteErr: errSkip,
+			rRevokeErr: errSkip,
+			err:        ErrInvalidMFACode,
+		},
+		{
+			desc:       "enable - error",
+			rUser:      enrolled,
+			code:       currentTOTPCode(),
+			rEnableErr: assert.AnError,
+			rStepErr:   errSkip,
+			rDeleteErr: errSkip,
+			rRevokeErr: errSkip,
+			err:        assert.AnError,
+		},
+		{
+			desc:       "delete tokens - error",
+			rUser:      enrolled,
+			code:       currentTOTPCode(),
+			rEnableErr: nil,
+			rStepErr:   nil,
+			rDeleteErr: assert.AnError,
+			rRevokeErr: errSkip,
+			err:        assert.AnError,
+		},
+		{
+			desc:       "revoke tokens - error",
+			rUser:      enrolled,
+			code:       currentTOTPCode(),
+			rEnableErr: nil,
+			rStepErr:   nil,
+			rDeleteErr: nil,
+			rRevokeErr: assert.AnError,
+			err:        assert.AnError,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			st := storage.NewMockUserStorage(ctrl)
+			tx := storage.NewMockUserStorage(ctrl)
+
+			st.EXPECT().GetUserByID(ctx, int64(1)).Return(tC.rUser, nil)
+
+			if tC.rEnableErr != errSkip {
+				st.EXPECT().InTx(ctx, gomock.Any()).DoAndReturn(
+					func(_ context.Context, action func(s storage.UserStorage) error) error {
+						return action(tx)
+					})
+				tx.EXPECT().EnableMFA(ctx, int64(1), gomock.Any()).Return(tC.rEnableErr)
+			}
+
+			if tC.rStepErr != errSkip {
+				tx.EXPECT().UseMFAStep(ctx, int64(1), gomock.Any()).Return(tC.rStepErr)
+			}
+
+			if tC.rDeleteErr != errSkip {
+				tx.EXPECT().DeleteUserTokens(ctx, int64(1)).Return(tC.rDeleteErr)
+			}
+
+			if tC.rRevokeErr != errSkip {
+				tx.EXPECT().RevokeUserTokens(ctx, int64(1)).Return(tC.rRevokeErr)
+			}
+
+			s := New(st, NewHMACKeySet(signKey), testAppURL, VerificationOptional, MFAOptional)
+
+			codes, err := s.ConfirmMFA(ctx, 1, tC.code)
+
+			assert.True(t, errors.Is(err, tC.err), fmt.Sprintf("wanted %s got %s", tC.err, err))
+			assert.Len(t, codes, tC.recoveryLen)
+		})
+	}
+}
+
+func TestService_DisableMFA(t *testing.T) {
+	testCases := []struct {
+		desc        string
+		rUser       model.User
+		rStepErr    error
+		rDisableErr error
+		err         error
+	}{
+		{
+			desc:        "success",
+			rUser:       testMFAUser,
+			rStepErr:    nil,
+			rDisableErr: nil,
+			err:         nil,
+		},
+		{
+			desc:        "ErrMFANotEnrolled",
+			rUser:       model.User{ID: 1, Email: "admin@test.com"},
+			rStepErr:    errSkip,
+			rDisableErr: errSkip,
+			err:         ErrMFANotEnrolled,
+		},
+		{
+			desc:        "ErrInvalidMFACode",
+			rUser:       testMFAUser,
+			rStepErr:    storage.ErrNotFound,
+			rDisableErr: errSkip,
+			err:         ErrInvalidMFACode,
+		},
+		{
+			desc:        "disable - error",
+			rUser:       testMFAUser,
+			rStepErr:    nil,
+			rDisableErr: assert.AnError,
+			err:         assert.AnError,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			st := storage.NewMockUserStorage(ctrl)
+			st.EXPECT().GetUserByID(ctx, int64(1)).Return(tC.rUser, nil)
+
+			if tC.rStepErr != errSkip {
+				st.EXPECT().GetLoginFailures(ctx, gomock.Any()).Return(nil, nil)
+				st.EXPECT().UseMFAStep(ctx, int64(1), gomock.Any()).Return(tC.rStepErr)
+			}
+
+			if tC.err == ErrInvalidMFACode {
+				st.EXPECT().AddLoginFailure(ctx, gomock.Any(), gomock.Any()).Return(model.LoginFailures{}, nil).Times(2)
+			}
+
+			if tC.rDisableErr != errSkip {
+				st.EXPECT().DisableMFA(ctx, int64(1)).Return(tC.rDisableErr)
+			}
+
+			s := New(st, NewHMACKeySet(signKey), testAppURL, VerificationOptional, MFAOptional)
+
+			err := s.DisableMFA(ctx, 1, currentTOTPCode(), testIP)
+
+			assert.True(t, errors.Is(err, tC.err), fmt.Sprintf("wanted %s got %s", tC.err, err))
+		})
+	}
+}
+
+func TestService_grantedUser(t *testing.T) {
+	admin := model.User{ID: 1, Roles: testRoles, Permissions: testPermissions}
+
+	optional := &authService{mfaPolicy: MFAOptional}
+	assert.Equal(t, admin, optional.grantedUser(admin))
+
+	required := &authService{mfaPolicy: MFARequiredForAdmins}
+	assert.Equal(t, model.User{ID: 1}, required.grantedUser(admin))
+
+	admin.MFAEnabled = true
+	assert.Equal(t, admin, required.grantedUser(admin))
+}
@@ -0,0 +1,366 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vliubezny/gstore/internal/model"
+	"github.com/vliubezny/gstore/internal/storage"
+)
+
+// OAuth 2.0 grant types.
+const (
+	GrantClientCredentials = "client_credentials"
+	GrantAuthorizationCode = "authorization_code"
+	GrantRefreshToken      = "refresh_token"
+)
+
+const (
+	authorizationCodeTTL = time.Minute
+
+	// code verifier length limits (RFC 7636 section 4.1)
+	minCodeVerifierLen = 43
+	maxCodeVerifierLen = 128
+)
+
+// AuthorizationRequest is a request of OAuth client to act on behalf of the user approved by the user.
+type AuthorizationRequest struct {
+	ClientID    string
+	RedirectURI string
+	// Scopes default to all scopes of the client if empty.
+	Scopes []model.Permission
+	// CodeChallenge is S256 PKCE challenge (RFC 7636), plain challenges are not supported.
+	CodeChallenge string
+}
+
+// OAuthTokenRequest is a request of OAuth client to token endpoint (RFC 6749).
+type OAuthTokenRequest struct {
+	GrantType    string
+	ClientID     string
+	ClientSecret string
+	// Code, RedirectURI and CodeVerifier are set for authorization code grant.
+	Code         string
+	RedirectURI  string
+	CodeVerifier string
+	// RefreshToken is set for refresh token grant.
+	RefreshToken string
+	// Scopes narrow down scopes of client credentials and refresh token grants if set.
+	Scopes []model.Permission
+}
+
+// OAuthToken is a response of token endpoint.
+type OAuthToken struct {
+	AccessToken string
+	// RefreshToken is not issued for client credentials grant.
+	RefreshToken string
+	ExpiresIn    time.Duration
+	Scopes       []model.Permission
+}
+
+// delegation limits tokens issued to OAuth client, zero value issues own tokens of the user.
+type delegation struct {
+	clientID string
+	scopes   []model.Permission
+}
+
+// limit restricts permissions of the claims to the scopes of delegation.
+func (d delegation) limit(c AccessTokenClaims) AccessTokenClaims {
+	if d.clientID == "" {
+		return c
+	}
+
+	c.ClientID = d.clientID
+	c.Scopes = d.scopes
+	c.Permissions = limitPermissions(c.Permissions, d.scopes)
+	return c
+}
+
+func (s *authService) RegisterOAuthClient(ctx context.Context, client model.OAuthClient, confidential bool) (string, model.OAuthClient, error) {
+	scopes, err := knownScopes(client.Scopes)
+	if err != nil {
+		return "", model.OAuthClient{}, err
+	}
+
+	client.ID = uuid.NewString()
+	client.Scopes = scopes
+
+	var secret string
+	if confidential {
+		if secret, err = newSecretToken(); err != nil {
+			return "", model.OAuthClient{}, fmt.Errorf("failed to generate client secret: %w", err)
+		}
+		client.SecretHash = hashToken(secret)
+	}
+
+	c, err := s.s.CreateOAuthClient(ctx, client)
+	if err != nil {
+		if errors.Is(err, storage.ErrUnknownUser) {
+			return "", model.OAuthClient{}, ErrNotFound
+		}
+		return "", model.OAuthClient{}, fmt.Errorf("failed to create oauth client: %w", err)
+	}
+
+	return secret, c, nil
+}
+
+func (s *authService) GetOAuthClients(ctx context.Context, userID int64) ([]model.OAuthClient, error) {
+	clients, err := s.s.GetOAuthClients(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get oauth clients: %w", err)
+	}
+	return clients, nil
+}
+
+func (s *authService) DeleteOAuthClient(ctx context.Context, userID int64, clientID string) error {
+	if err := s.s.DeleteOAuthClient(ctx, userID, clientID); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return ErrNotFound
+		}
+		return fmt.Errorf("failed to delete oauth client: %w", err)
+	}
+	return nil
+}
+
+// AuthorizeOAuthClient issues authorization code to the client approved by the user (RFC 6749 section 4.1.2).
+func (s *authService) AuthorizeOAuthClient(ctx context.Context, userID int64, req AuthorizationRequest) (string, error) {
+	c, err := s.getOAuthClient(ctx, req.ClientID)
+	if err != nil {
+		return "", err
+	}
+
+	registered := false
+	for _, uri := range c.RedirectURIs {
+		if uri == req.RedirectURI {
+			registered = true
+			break
+		}
+	}
+	if !registered {
+		return "", ErrInvalidRedirectURI
+	}
+
+	scopes, err := clientScopes(c.Scopes, req.Scopes)
+	if err != nil {
+		return "", err
+	}
+
+	code, err := newSecretToken()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate authorization code: %w", err)
+	}
+
+	if err = s.s.SaveAuthorizationCode(ctx, model.AuthorizationCode{
+		CodeHash:      hashToken(code),
+		ClientID:      c.ID,
+		UserID:        userID,
+		RedirectURI:   req.RedirectURI,
+		Scopes:        scopes,
+		CodeChallenge: req.CodeChallenge,
+		ExpiresAt:     time.Now().Add(authorizationCodeTTL),
+	}); err != nil {
+		return "", fmt.Errorf("failed to save authorization code: %w", err)
+	}
+
+	return code, nil
+}
+
+// AuthenticateOAuthClient authenticates confidential client with its secret.
+func (s *authService) AuthenticateOAuthClient(ctx context.Context, clientID, secret string) (model.OAuthClient, error) {
+	c, err := s.authenticateOAuthClient(ctx, clientID, secret)
+	if err != nil {
+		return model.OAuthClient{}, err
+	}
+
+	if c.SecretHash == "" {
+		return model.OAuthClient{}, fmt.Errorf("%w: public client", ErrInvalidClient)
+	}
+
+	return c, nil
+}
+
+// IssueOAuthToken handles request to token endpoint of authenticated client.
+func (s *authService) IssueOAuthToken(ctx context.Context, req OAuthTokenRequest, client Client) (OAuthToken, error) {
+	c, err := s.authenticateOAuthClient(ctx, req.ClientID, req.ClientSecret)
+	if err != nil {
+		return OAuthToken{}, err
+	}
+
+	switch req.GrantType {
+	case GrantClientCredentials:
+		return s.clientCredentials(ctx, c, req.Scopes)
+	case GrantAuthorizationCode:
+		return s.exchangeAuthorizationCode(ctx, c, req, client)
+	case GrantRefreshToken:
+		return s.refreshOAuthToken(ctx, c, req, client)
+	}
+
+	return OAuthToken{}, fmt.Errorf("%w: %s", ErrUnsupportedGrantType, req.GrantType)
+}
+
+// clientCredentials issues access token of the client owner (RFC 6749 section 4.4).
+func (s *authService) clientCredentials(ctx context.Context, c model.OAuthClient, requested []model.Permission) (OAuthToken, error) {
+	if c.SecretHash == "" {
+		return OAuthToken{}, fmt.Errorf("%w: public client can't use client credentials", ErrUnauthorizedClient)
+	}
+
+	scopes, err := clientScopes(c.Scopes, requested)
+	if err != nil {
+		return OAuthToken{}, err
+	}
+
+	u, err := s.s.GetUserByID(ctx, c.UserID)
+	if err != nil {
+		return OAuthToken{}, fmt.Errorf("failed to get client owner: %w", err)
+	}
+
+	d := delegation{clientID: c.ID, scopes: scopes}
+	at, err := s.signToken(d.limit(newAccessClaims(s.grantedUser(u))))
+	if err != nil {
+		return OAuthToken{}, fmt.Errorf("failed to sign access token: %w", err)
+	}
+
+	return OAuthToken{AccessToken: at, ExpiresIn: accessTokenTTL, Scopes: scopes}, nil
+}
+
+// exchangeAuthorizationCode starts session of the user who authorized the client (RFC 6749 section 4.1.3).
+func (s *authService) exchangeAuthorizationCode(ctx context.Context, c model.OAuthClient, req OAuthTokenRequest,
+	client Client) (OAuthToken, error) {
+	code, err := s.s.UseAuthorizationCode(ctx, hashToken(req.Code))
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return OAuthToken{}, fmt.Errorf("%w: unknown or expired code", ErrInvalidGrant)
+		}
+		return OAuthToken{}, fmt.Errorf("failed to use authorization code: %w", err)
+	}
+
+	switch {
+	case code.ClientID != c.ID:
+		return OAuthToken{}, fmt.Errorf("%w: code is issued to another client", ErrInvalidGrant)
+	case code.RedirectURI != req.RedirectURI:
+		return OAuthToken{}, fmt.Errorf("%w: redirect uri mismatch", ErrInvalidGrant)
+	case !verifyCodeChallenge(req.CodeVerifier, code.CodeChallenge):
+		return OAuthToken{}, fmt.Errorf("%w: invalid code verifier", ErrInvalidGrant)
+	}
+
+	u, err := s.s.GetUserByID(ctx, code.UserID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return OAuthToken{}, fmt.Errorf("%w: missing user", ErrInvalidGrant)
+		}
+		return OAuthToken{}, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	pair, err := s.issueTokens(ctx, u, delegation{clientID: c.ID, scopes: code.Scopes}, client)
+	if err != nil {
+		return OAuthToken{}, err
+	}
+
+	return OAuthToken{
+		AccessToken:  pair.AccessToken,
+		RefreshToken: pair.RefreshToken,
+		ExpiresIn:    accessTokenTTL,
+		Scopes:       code.Scopes,
+	}, nil
+}
+
+// refreshOAuthToken rotates refresh token issued to the client (RFC 6749 section 6).
+func (s *authService) refreshOAuthToken(ctx context.Context, c model.OAuthClient, req OAuthTokenRequest,
+	client Client) (OAuthToken, error) {
+	claims, err := validateRefreshToken(req.RefreshToken, s.keys)
+	if err != nil {
+		return OAuthToken{}, fmt.Errorf("%w: %v", ErrInvalidGrant, err)
+	}
+
+	if claims.ClientID != c.ID {
+		return OAuthToken{}, fmt.Errorf("%w: token is issued to another client", ErrInvalidGrant)
+	}
+
+	scopes, err := clientScopes(claims.Scopes, req.Scopes)
+	if err != nil {
+		return OAuthToken{}, err
+	}
+
+	pair, err := s.rotate(ctx, claims, delegation{clientID: c.ID, scopes: scopes}, client)
+	if err != nil {
+		if errors.Is(err, ErrInvalidToken) {
+			return OAuthToken{}, fmt.Errorf("%w: %v", ErrInvalidGrant, err)
+		}
+		return OAuthToken{}, err
+	}
+
+	return OAuthToken{
+		AccessToken:  pair.AccessToken,
+		RefreshToken: pair.RefreshToken,
+		ExpiresIn:    accessTokenTTL,
+		Scopes:       scopes,
+	}, nil
+}
+
+// authenticateOAuthClient authenticates client with its secret, public clients are identified by ID only.
+func (s *authService) authenticateOAuthClient(ctx context.Context, clientID, secret string) (model.OAuthClient, error) {
+	c, err := s.getOAuthClient(ctx, clientID)
+	if err != nil {
+		return model.OAuthClient{}, err
+	}
+
+	if c.SecretHash == "" && secret == "" {
+		return c, nil
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hashToken(secret)), []byte(c.SecretHash)) != 1 {
+		return model.OAuthClient{}, fmt.Errorf("%w: invalid secret", ErrInvalidClient)
+	}
+
+	return c, nil
+}
+
+func (s *authService) getOAuthClient(ctx context.Context, clientID string) (model.OAuthClient, error) {
+	if _, err := uuid.Parse(clientID); err != nil {
+		return model.OAuthClient{}, fmt.Errorf("%w: malformed client ID", ErrInvalidClient)
+	}
+
+	c, err := s.s.GetOAuthClient(ctx, clientID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return model.OAuthClient{}, fmt.Errorf("%w: unknown client", ErrInvalidClient)
+		}
+		return model.OAuthClient{}, fmt.Errorf("failed to get oauth client: %w", err)
+	}
+
+	return c, nil
+}
+
+// clientScopes checks that requested scopes are granted, all granted scopes are returned if none requested.
+func clientScopes(granted, requested []model.Permission) ([]model.Permission, error) {
+	if len(requested) == 0 {
+		return granted, nil
+	}
+
+	scopes := make([]model.Permission, 0, len(requested))
+	for _, sc := range requested {
+		if !hasPermission(granted, sc) {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidScope, sc)
+		}
+		if !hasPermission(scopes, sc) {
+			scopes = append(scopes, sc)
+		}
+	}
+
+	return scopes, nil
+}
+
+// verifyCodeChallenge checks PKCE code verifier against S256 challenge (RFC 7636 section 4.6).
+func verifyCodeChallenge(verifier, challenge string) bool {
+	if len(verifier) < minCodeVerifierLen || len(verifier) > maxCodeVerifierLen {
+		return false
+	}
+
+	sum := sha256.Sum256([]byte(verifier))
+	return subtle.ConstantTimeCompare([]byte(base64.RawURLEncoding.EncodeToString(sum[:])), []byte(challenge)) == 1
+}
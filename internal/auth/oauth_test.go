@@ -0,0 +1,762 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vliubezny/gstore/internal/model"
+	"github.com/vliubezny/gstore/internal/storage"
+)
+
+const (
+	testClientID     = "a37f2f5e-f698-11e6-8dd4-cb9ced3df976"
+	testClientSecret = "secret"
+	testRedirectURI  = "https://app.test.com/callback"
+	testCode         = "code"
+
+	// RFC 7636 appendix B
+	testCodeVerifier  = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	testCodeChallenge = "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+)
+
+var testScopes = []model.Permission{model.PermissionProductWrite, model.PermissionPositionWrite}
+
+func mustCreateClientRefreshToken(u model.User, clientID string, scopes []model.Permission) string {
+	c := newRefreshClaims(u, testFamily)
+	c.ClientID, c.Scopes = clientID, scopes
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, c).SignedString([]byte(signKey))
+	if err != nil {
+		panic(err)
+	}
+	return token
+}
+
+func TestService_RegisterOAuthClient(t *testing.T) {
+	testCases := []struct {
+		desc         string
+		scopes       []model.Permission
+		confidential bool
+		rErr         error
+		err          error
+	}{
+		{
+			desc:         "success - confidential",
+			scopes:       testScopes,
+			confidential: true,
+			rErr:         nil,
+			err:          nil,
+		},
+		{
+			desc:         "success - public",
+			scopes:       testScopes,
+			confidential: false,
+			rErr:         nil,
+			err:          nil,
+		},
+		{
+			desc:         "ErrUnknownScope",
+			scopes:       []model.Permission{"test:write"},
+			confidential: true,
+			rErr:         errSkip,
+			err:          ErrUnknownScope,
+		},
+		{
+			desc:         "ErrNotFound",
+			scopes:       testScopes,
+			confidential: true,
+			rErr:         storage.ErrUnknownUser,
+			err:          ErrNotFound,
+		},
+		{
+			desc:         "create oauth client - error",
+			scopes:       testScopes,
+			confidential: true,
+			rErr:         assert.AnError,
+			err:          assert.AnError,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			st := storage.NewMockUserStorage(ctrl)
+
+			if tC.rErr != errSkip {
+				st.EXPECT().CreateOAuthClient(ctx, gomock.Any()).DoAndReturn(
+					func(_ context.Context, c model.OAuthClient) (model.OAuthClient, error) {
+						return c, tC.rErr
+					})
+			}
+
+			s := New(st, NewHMACKeySet(signKey), testAppURL, VerificationOptional, MFAOptional)
+
+			secret, c, err := s.RegisterOAuthClient(ctx, model.OAuthClient{
+				UserID:       1,
+				Name:         "app",
+				RedirectURIs: []string{testRedirectURI},
+				Scopes:       tC.scopes,
+			}, tC.confidential)
+
+			assert.True(t, errors.Is(err, tC.err), fmt.Sprintf("wanted %s got %s", tC.err, err))
+			if tC.err != nil {
+				return
+			}
+
+			assert.NotEmpty(t, c.ID)
+			assert.Equal(t, testScopes, c.Scopes)
+			if tC.confidential {
+				assert.NotEmpty(t, secret)
+				assert.Equal(t, hashToken(secret), c.SecretHash, "only hash of the secret must be stored")
+			} else {
+				assert.Empty(t, secret)
+				assert.Empty(t, c.SecretHash)
+			}
+		})
+	}
+}
+
+func TestService_GetOAuthClients(t *testing.T) {
+	clients := []model.OAuthClient{{ID: testClientID, UserID: 1, Name: "app"}}
+
+	testCases := []struct {
+		desc     string
+		rClients []model.OAuthClient
+		rErr     error
+		err      error
+	}{
+		{
+			desc:     "success",
+			rClients: clients,
+			rErr:     nil,
+			err:      nil,
+		},
+		{
+			desc:     "get oauth clients - error",
+			rClients: nil,
+			rErr:     assert.AnError,
+			err:      assert.AnError,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			st := storage.NewMockUserStorage(ctrl)
+			st.EXPECT().GetOAuthClients(ctx, int64(1)).Return(tC.rClients, tC.rErr)
+
+			s := New(st, NewHMACKeySet(signKey), testAppURL, VerificationOptional, MFAOptional)
+
+			res, err := s.GetOAuthClients(ctx, 1)
+
+			assert.True(t, errors.Is(err, tC.err), fmt.Sprintf("wanted %s got %s", tC.err, err))
+			assert.Equal(t, tC.rClients, res)
+		})
+	}
+}
+
+func TestService_DeleteOAuthClient(t *testing.T) {
+	testCases := []struct {
+		desc string
+		rErr error
+		err  error
+	}{
+		{
+			desc: "success",
+			rErr: nil,
+			err:  nil,
+		},
+		{
+			desc: "ErrNotFound",
+			rErr: storage.ErrNotFound,
+			err:  ErrNotFound,
+		},
+		{
+			desc: "delete oauth client - error",
+			rErr: assert.AnError,
+			err:  assert.AnError,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			st := storage.NewMockUserStorage(ctrl)
+			st.EXPECT().DeleteOAuthClient(ctx, int64(1), testClientID).Return(tC.rErr)
+
+			s := New(st, NewHMACKeySet(signKey), testAppURL, VerificationOptional, MFAOptional)
+
+			err := s.DeleteOAuthClient(ctx, 1, testClientID)
+
+			assert.True(t, errors.Is(err, tC.err), fmt.Sprintf("wanted %s got %s", tC.err, err))
+		})
+	}
+}
+
+func TestService_AuthorizeOAuthClient(t *testing.T) {
+	client := model.OAuthClient{ID: testClientID, UserID: 2, RedirectURIs: []string{testRedirectURI}, Scopes: testScopes}
+
+	testCases := []struct {
+		desc       string
+		req        AuthorizationRequest
+		rClientErr error
+		rSaveErr   error
+		scopes     []model.Permission
+		err        error
+	}{
+		{
+			desc:       "success",
+			req:        AuthorizationRequest{ClientID: testClientID, RedirectURI: testRedirectURI, CodeChallenge: testCodeChallenge},
+			rClientErr: nil,
+			rSaveErr:   nil,
+			scopes:     testScopes,
+			err:        nil,
+		},
+		{
+			desc: "success - narrowed scopes",
+			req: AuthorizationRequest{ClientID: testClientID, RedirectURI: testRedirectURI, CodeChallenge: testCodeChallenge,
+				Scopes: []model.Permission{model.PermissionPositionWrite}},
+			rClientErr: nil,
+			rSaveErr:   nil,
+			scopes:     []model.Permission{model.PermissionPositionWrite},
+			err:        nil,
+		},
+		{
+			desc:       "malformed client ID - ErrInvalidClient",
+			req:        AuthorizationRequest{ClientID: "test", RedirectURI: testRedirectURI},
+			rClientErr: errSkip,
+			rSaveErr:   errSkip,
+			err:        ErrInvalidClient,
+		},
+		{
+			desc:       "unknown client - ErrInvalidClient",
+			req:        AuthorizationRequest{ClientID: testClientID, RedirectURI: testRedirectURI},
+			rClientErr: storage.ErrNotFound,
+			rSaveErr:   errSkip,
+			err:        ErrInvalidClient,
+		},
+		{
+			desc:       "get oauth client - error",
+			req:        AuthorizationRequest{ClientID: testClientID, RedirectURI: testRedirectURI},
+			rClientErr: assert.AnError,
+			rSaveErr:   errSkip,
+			err:        assert.AnError,
+		},
+		{
+			desc:       "ErrInvalidRedirectURI",
+			req:        AuthorizationRequest{ClientID: testClientID, RedirectURI: "https://evil.com/callback"},
+			rClientErr: nil,
+			rSaveErr:   errSkip,
+			err:        ErrInvalidRedirectURI,
+		},
+		{
+			desc: "ErrInvalidScope",
+			req: AuthorizationRequest{ClientID: testClientID, RedirectURI: testRedirectURI,
+				Scopes: []model.Permission{model.PermissionStoreWrite}},
+			rClientErr: nil,
+			rSaveErr:   errSkip,
+			err:        ErrInvalidScope,
+		},
+		{
+			desc:       "save authorization code - error",
+			req:        AuthorizationRequest{ClientID: testClientID, RedirectURI: testRedirectURI},
+			rClientErr: nil,
+			rSaveErr:   assert.AnError,
+			err:        assert.AnError,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			st := storage.NewMockUserStorage(ctrl)
+
+			if tC.rClientErr != errSkip {
+				st.EXPECT().GetOAuthClient(ctx, testClientID).Return(client, tC.rClientErr)
+			}
+
+			var saved model.AuthorizationCode
+			if tC.rSaveErr != errSkip {
+				st.EXPECT().SaveAuthorizationCode(ctx, gomock.Any()).DoAndReturn(
+					func(_ context.Context, c model.AuthorizationCode) error {
+						saved = c
+						return tC.rSaveErr
+					})
+			}
+
+			s := New(st, NewHMACKeySet(signKey), testAppURL, VerificationOptional, MFAOptional)
+
+			code, err := s.AuthorizeOAuthClient(ctx, 1, tC.req)
+
+			assert.True(t, errors.Is(err, tC.err), fmt.Sprintf("wanted %s got %s", tC.err, err))
+			if tC.err != nil {
+				assert.Empty(t, code)
+				return
+			}
+
+			assert.Equal(t, hashToken(code), saved.CodeHash, "only hash of the code must be stored")
+			assert.Equal(t, int64(1), saved.UserID, "code must be issued for approving user")
+			assert.Equal(t, testClientID, saved.ClientID)
+			assert.Equal(t, testRedirectURI, saved.RedirectURI)
+			assert.Equal(t, tC.scopes, saved.Scopes)
+			assert.Equal(t, testCodeChallenge, saved.CodeChallenge)
+			assert.WithinDuration(t, time.Now().Add(authorizationCodeTTL), saved.ExpiresAt, time.Second)
+		})
+	}
+}
+
+func TestService_AuthenticateOAuthClient(t *testing.T) {
+	confidential := model.OAuthClient{ID: testClientID, SecretHash: hashToken(testClientSecret)}
+	public := model.OAuthClient{ID: testClientID}
+
+	testCases := []struct {
+		desc    string
+		secret  string
+		rClient model.OAuthClient
+		err     error
+	}{
+		{
+			desc:    "success",
+			secret:  testClientSecret,
+			rClient: confidential,
+			err:     nil,
+		},
+		{
+			desc:    "invalid secret - ErrInvalidClient",
+			secret:  "test",
+			rClient: confidential,
+			err:     ErrInvalidClient,
+		},
+		{
+			desc:    "missing secret - ErrInvalidClient",
+			secret:  "",
+			rClient: confidential,
+			err:     ErrInvalidClient,
+		},
+		{
+			desc:    "public client - ErrInvalidClient",
+			secret:  "",
+			rClient: public,
+			err:     ErrInvalidClient,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			st := storage.NewMockUserStorage(ctrl)
+			st.EXPECT().GetOAuthClient(ctx, testClientID).Return(tC.rClient, nil)
+
+			s := New(st, NewHMACKeySet(signKey), testAppURL, VerificationOptional, MFAOptional)
+
+			c, err := s.AuthenticateOAuthClient(ctx, testClientID, tC.secret)
+
+			assert.True(t, errors.Is(err, tC.err), fmt.Sprintf("wanted %s got %s", tC.err, err))
+			if tC.err == nil {
+				assert.Equal(t, tC.rClient, c)
+			}
+		})
+	}
+}
+
+func TestService_IssueOAuthToken_clientCredentials(t *testing.T) {
+	owner := model.User{ID: 2, Email: "owner@test.com", Roles: testRoles, Permissions: testPermissions}
+	confidential := model.OAuthClient{ID: testClientID, UserID: 2, SecretHash: hashToken(testClientSecret), Scopes: testScopes}
+	public := model.OAuthClient{ID: testClientID, UserID: 2, Scopes: testScopes}
+
+	testCases := []struct {
+		desc     string
+		req      OAuthTokenRequest
+		rClient  model.OAuthClient
+		rUserErr error
+		scopes   []model.Permission
+		err      error
+	}{
+		{
+			desc:     "success",
+			req:      OAuthTokenRequest{GrantType: GrantClientCredentials, ClientID: testClientID, ClientSecret: testClientSecret},
+			rClient:  confidential,
+			rUserErr: nil,
+			scopes:   testScopes,
+			err:      nil,
+		},
+		{
+			desc: "success - narrowed scopes",
+			req: OAuthTokenRequest{GrantType: GrantClientCredentials, ClientID: testClientID, ClientSecret: testClientSecret,
+				Scopes: []model.Permission{model.PermissionPositionWrite}},
+			rClient:  confidential,
+			rUserErr: nil,
+			scopes:   []model.Permission{model.PermissionPositionWrite},
+			err:      nil,
+		},
+		{
+			desc:     "invalid secret - ErrInvalidClient",
+			req:      OAuthTokenRequest{GrantType: GrantClientCredentials, ClientID: testClientID, ClientSecret: "test"},
+			rClient:  confidential,
+			rUserErr: errSkip,
+			err:      ErrInvalidClient,
+		},
+		{
+			desc:     "public client - ErrUnauthorizedClient",
+			req:      OAuthTokenRequest{GrantType: GrantClientCredentials, ClientID: testClientID},
+			rClient:  public,
+			rUserErr: errSkip,
+			err:      ErrUnauthorizedClient,
+		},
+		{
+			desc: "ErrInvalidScope",
+			req: OAuthTokenRequest{GrantType: GrantClientCredentials, ClientID: testClientID, ClientSecret: testClientSecret,
+				Scopes: []model.Permission{model.PermissionStoreWrite}},
+			rClient:  confidential,
+			rUserErr: errSkip,
+			err:      ErrInvalidScope,
+		},
+		{
+			desc:     "get user - error",
+			req:      OAuthTokenRequest{GrantType: GrantClientCredentials, ClientID: testClientID, ClientSecret: testClientSecret},
+			rClient:  confidential,
+			rUserErr: assert.AnError,
+			err:      assert.AnError,
+		},
+		{
+			desc:     "ErrUnsupportedGrantType",
+			req:      OAuthTokenRequest{GrantType: "password", ClientID: testClientID, ClientSecret: testClientSecret},
+			rClient:  confidential,
+			rUserErr: errSkip,
+			err:      ErrUnsupportedGrantType,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			st := storage.NewMockUserStorage(ctrl)
+			st.EXPECT().GetOAuthClient(ctx, testClientID).Return(tC.rClient, nil)
+
+			if tC.rUserErr != errSkip {
+				st.EXPECT().GetUserByID(ctx, owner.ID).Return(owner, tC.rUserErr)
+			}
+
+			s := New(st, NewHMACKeySet(signKey), testAppURL, VerificationOptional, MFAOptional)
+
+			token, err := s.IssueOAuthToken(ctx, tC.req, testClient)
+
+			assert.True(t, errors.Is(err, tC.err), fmt.Sprintf("wanted %s got %s", tC.err, err))
+			if tC.err != nil {
+				return
+			}
+
+			assert.Empty(t, token.RefreshToken, "refresh token must not be issued for client credentials")
+			assert.Equal(t, accessTokenTTL, token.ExpiresIn)
+			assert.Equal(t, tC.scopes, token.Scopes)
+
+			claims, err := s.ValidateAccessToken(token.AccessToken)
+			require.NoError(t, err)
+			assert.Equal(t, owner.ID, claims.UserID)
+			assert.Equal(t, testClientID, claims.ClientID)
+			assert.Equal(t, tC.scopes, claims.Scopes)
+			assert.ElementsMatch(t, limitPermissions(testPermissions, tC.scopes), claims.Permissions)
+		})
+	}
+}
+
+func TestService_IssueOAuthToken_authorizationCode(t *testing.T) {
+	user := model.User{ID: 1, Email: "admin@test.com", Roles: testRoles, Permissions: testPermissions}
+	client := model.OAuthClient{ID: testClientID, UserID: 2, RedirectURIs: []string{testRedirectURI}, Scopes: testScopes}
+	code := model.AuthorizationCode{
+		CodeHash:      hashToken(testCode),
+		ClientID:      testClientID,
+		UserID:        1,
+		RedirectURI:   testRedirectURI,
+		Scopes:        []model.Permission{model.PermissionProductWrite},
+		CodeChallenge: testCodeChallenge,
+	}
+	req := OAuthTokenRequest{
+		GrantType:    GrantAuthorizationCode,
+		ClientID:     testClientID,
+		Code:         testCode,
+		RedirectURI:  testRedirectURI,
+		CodeVerifier: testCodeVerifier,
+	}
+
+	otherClientCode := code
+	otherClientCode.ClientID = testAPIKeyID
+
+	wrongVerifierReq := req
+	wrongVerifierReq.CodeVerifier = testCodeVerifier[1:] + "a"
+
+	wrongRedirectReq := req
+	wrongRedirectReq.RedirectURI = "https://app.test.com/other"
+
+	testCases := []struct {
+		desc          string
+		req           OAuthTokenRequest
+		rCode         model.AuthorizationCode
+		rCodeErr      error
+		rUserErr      error
+		rSaveTokenErr error
+		err           error
+	}{
+		{
+			desc:          "success",
+			req:           req,
+			rCode:         code,
+			rCodeErr:      nil,
+			rUserErr:      nil,
+			rSaveTokenErr: nil,
+			err:           nil,
+		},
+		{
+			desc:          "unknown code - ErrInvalidGrant",
+			req:           req,
+			rCode:         model.AuthorizationCode{},
+			rCodeErr:      storage.ErrNotFound,
+			rUserErr:      errSkip,
+			rSaveTokenErr: errSkip,
+			err:           ErrInvalidGrant,
+		},
+		{
+			desc:          "use code - error",
+			req:           req,
+			rCode:         model.AuthorizationCode{},
+			rCodeErr:      assert.AnError,
+			rUserErr:      errSkip,
+			rSaveTokenErr: errSkip,
+			err:           assert.AnError,
+		},
+		{
+			desc:          "code of other client - ErrInvalidGrant",
+			req:           req,
+			rCode:         otherClientCode,
+			rCodeErr:      nil,
+			rUserErr:      errSkip,
+			rSaveTokenErr: errSkip,
+			err:           ErrInvalidGrant,
+		},
+		{
+			desc:          "redirect uri mismatch - ErrInvalidGrant",
+			req:           wrongRedirectReq,
+			rCode:         code,
+			rCodeErr:      nil,
+			rUserErr:      errSkip,
+			rSaveTokenErr: errSkip,
+			err:           ErrInvalidGrant,
+		},
+		{
+			desc:          "invalid code verifier - ErrInvalidGrant",
+			req:           wrongVerifierReq,
+			rCode:         code,
+			rCodeErr:      nil,
+			rUserErr:      errSkip,
+			rSaveTokenErr: errSkip,
+			err:           ErrInvalidGrant,
+		},
+		{
+			desc:          "missing user - ErrInvalidGrant",
+			req:           req,
+			rCode:         code,
+			rCodeErr:      nil,
+			rUserErr:      storage.ErrNotFound,
+			rSaveTokenErr: errSkip,
+			err:           ErrInvalidGrant,
+		},
+		{
+			desc:          "save token - error",
+			req:           req,
+			rCode:         code,
+			rCodeErr:      nil,
+			rUserErr:      nil,
+			rSaveTokenErr: assert.AnError,
+			err:           assert.AnError,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			st := storage.NewMockUserStorage(ctrl)
+			st.EXPECT().GetOAuthClient(ctx, testClientID).Return(client, nil)
+			st.EXPECT().UseAuthorizationCode(ctx, hashToken(testCode)).Return(tC.rCode, tC.rCodeErr)
+
+			if tC.rUserErr != errSkip {
+				st.EXPECT().GetUserByID(ctx, user.ID).Return(user, tC.rUserErr)
+			}
+
+			if tC.rSaveTokenErr != errSkip {
+				st.EXPECT().SaveToken(ctx, gomock.AssignableToTypeOf(model.RefreshToken{})).Return(tC.rSaveTokenErr)
+			}
+
+			s := New(st, NewHMACKeySet(signKey), testAppURL, VerificationOptional, MFAOptional)
+
+			token, err := s.IssueOAuthToken(ctx, tC.req, testClient)
+
+			assert.True(t, errors.Is(err, tC.err), fmt.Sprintf("wanted %s got %s", tC.err, err))
+			if tC.err != nil {
+				return
+			}
+
+			assert.NotEmpty(t, token.RefreshToken)
+			assert.Equal(t, code.Scopes, token.Scopes)
+
+			claims, err := s.ValidateAccessToken(token.AccessToken)
+			require.NoError(t, err)
+			assert.Equal(t, user.ID, claims.UserID, "token must be issued on behalf of approving user")
+			assert.Equal(t, testClientID, claims.ClientID)
+			assert.Equal(t, []model.Permission{model.PermissionProductWrite}, claims.Permissions)
+
+			rc, err := validateRefreshToken(token.RefreshToken, s.(*authService).keys)
+			require.NoError(t, err)
+			assert.Equal(t, testClientID, rc.ClientID)
+			assert.Equal(t, code.Scopes, rc.Scopes)
+		})
+	}
+}
+
+func TestService_IssueOAuthToken_refreshToken(t *testing.T) {
+	user := model.User{ID: 1, Email: "admin@test.com", Roles: testRoles, Permissions: testPermissions}
+	client := model.OAuthClient{ID: testClientID, UserID: 2, Scopes: testScopes}
+
+	testCases := []struct {
+		desc         string
+		req          OAuthTokenRequest
+		rUseTokenErr error
+		scopes       []model.Permission
+		err          error
+	}{
+		{
+			desc: "success",
+			req: OAuthTokenRequest{GrantType: GrantRefreshToken, ClientID: testClientID,
+				RefreshToken: mustCreateClientRefreshToken(user, testClientID, testScopes)},
+			rUseTokenErr: nil,
+			scopes:       testScopes,
+			err:          nil,
+		},
+		{
+			desc: "success - narrowed scopes",
+			req: OAuthTokenRequest{GrantType: GrantRefreshToken, ClientID: testClientID,
+				RefreshToken: mustCreateClientRefreshToken(user, testClientID, testScopes),
+				Scopes:       []model.Permission{model.PermissionPositionWrite}},
+			rUseTokenErr: nil,
+			scopes:       []model.Permission{model.PermissionPositionWrite},
+			err:          nil,
+		},
+		{
+			desc:         "malformed token - ErrInvalidGrant",
+			req:          OAuthTokenRequest{GrantType: GrantRefreshToken, ClientID: testClientID, RefreshToken: "test"},
+			rUseTokenErr: errSkip,
+			err:          ErrInvalidGrant,
+		},
+		{
+			desc: "token of user - ErrInvalidGrant",
+			req: OAuthTokenRequest{GrantType: GrantRefreshToken, ClientID: testClientID,
+				RefreshToken: mustCreateRefreshToken(user)},
+			rUseTokenErr: errSkip,
+			err:          ErrInvalidGrant,
+		},
+		{
+			desc: "token of other client - ErrInvalidGrant",
+			req: OAuthTokenRequest{GrantType: GrantRefreshToken, ClientID: testClientID,
+				RefreshToken: mustCreateClientRefreshToken(user, testAPIKeyID, testScopes)},
+			rUseTokenErr: errSkip,
+			err:          ErrInvalidGrant,
+		},
+		{
+			desc: "widened scopes - ErrInvalidScope",
+			req: OAuthTokenRequest{GrantType: GrantRefreshToken, ClientID: testClientID,
+				RefreshToken: mustCreateClientRefreshToken(user, testClientID, []model.Permission{model.PermissionProductWrite}),
+				Scopes:       testScopes},
+			rUseTokenErr: errSkip,
+			err:          ErrInvalidScope,
+		},
+		{
+			desc: "token revoked - ErrInvalidGrant",
+			req: OAuthTokenRequest{GrantType: GrantRefreshToken, ClientID: testClientID,
+				RefreshToken: mustCreateClientRefreshToken(user, testClientID, testScopes)},
+			rUseTokenErr: storage.ErrNotFound,
+			err:          ErrInvalidGrant,
+		},
+		{
+			desc: "use token - error",
+			req: OAuthTokenRequest{GrantType: GrantRefreshToken, ClientID: testClientID,
+				RefreshToken: mustCreateClientRefreshToken(user, testClientID, testScopes)},
+			rUseTokenErr: assert.AnError,
+			err:          assert.AnError,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			st := storage.NewMockUserStorage(ctrl)
+			tx := storage.NewMockUserStorage(ctrl)
+
+			st.EXPECT().GetOAuthClient(ctx, testClientID).Return(client, nil)
+
+			if tC.rUseTokenErr != errSkip {
+				st.EXPECT().GetUserByID(ctx, user.ID).Return(user, nil)
+				st.EXPECT().InTx(ctx, gomock.Any()).DoAndReturn(
+					func(_ context.Context, action func(s storage.UserStorage) error) error {
+						return action(tx)
+					})
+				tx.EXPECT().UseToken(ctx, gomock.Any()).Return(tC.rUseTokenErr)
+
+				if tC.rUseTokenErr == nil {
+					tx.EXPECT().SaveToken(ctx, gomock.AssignableToTypeOf(model.RefreshToken{})).Return(nil)
+				}
+			}
+
+			s := New(st, NewHMACKeySet(signKey), testAppURL, VerificationOptional, MFAOptional)
+
+			token, err := s.IssueOAuthToken(ctx, tC.req, testClient)
+
+			assert.True(t, errors.Is(err, tC.err), fmt.Sprintf("wanted %s got %s", tC.err, err))
+			if tC.err != nil {
+				return
+			}
+
+			assert.NotEmpty(t, token.RefreshToken)
+			assert.Equal(t, tC.scopes, token.Scopes)
+
+			claims, err := s.ValidateAccessToken(token.AccessToken)
+			require.NoError(t, err)
+			assert.Equal(t, testClientID, claims.ClientID)
+			assert.Equal(t, testFamily, claims.SessionID)
+			assert.Equal(t, tC.scopes, claims.Scopes)
+		})
+	}
+}
+
+func Test_clientScopes(t *testing.T) {
+	scopes, err := clientScopes(testScopes, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, testScopes, scopes, "all granted scopes must be returned by default")
+
+	scopes, err = clientScopes(testScopes, []model.Permission{model.PermissionPositionWrite, model.PermissionPositionWrite})
+	assert.NoError(t, err)
+	assert.Equal(t, []model.Permission{model.PermissionPositionWrite}, scopes)
+
+	_, err = clientScopes(testScopes, []model.Permission{model.PermissionStoreWrite})
+	assert.True(t, errors.Is(err, ErrInvalidScope))
+}
+
+func Test_verifyCodeChallenge(t *testing.T) {
+	assert.True(t, verifyCodeChallenge(testCodeVerifier, testCodeChallenge))
+	assert.False(t, verifyCodeChallenge(testCodeVerifier+"a", testCodeChallenge))
+	assert.False(t, verifyCodeChallenge(testCodeChallenge, testCodeChallenge))
+	assert.False(t, verifyCodeChallenge("short", "short"), "verifier must be at least 43 chars")
+	assert.False(t, verifyCodeChallenge(testCodeVerifier, ""), "code must have challenge")
+}
@@ -0,0 +1,100 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/vliubezny/gstore/internal/model"
+	"github.com/vliubezny/gstore/internal/storage"
+)
+
+const resetTokenTTL = time.Hour
+
+func (s *authService) ForgotPassword(ctx context.Context, email string) error {
+	u, err := s.s.GetUserByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) { // don't reveal whether email is registered
+			return nil
+		}
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	token, err := newSecretToken()
+	if err != nil {
+		return fmt.Errorf("failed to generate reset token: %w", err)
+	}
+
+	link := s.appURL + "/password/reset?token=" + url.QueryEscape(token)
+
+	return s.s.InTx(ctx, func(us storage.UserStorage) error {
+		if err := us.SaveResetToken(ctx, hashToken(token), u.ID, time.Now().Add(resetTokenTTL)); err != nil {
+			return fmt.Errorf("failed to save reset token: %w", err)
+		}
+
+		if err := us.EnqueueEmail(ctx, model.Email{
+			To:      u.Email,
+			Subject: "Password reset",
+			Body: "Follow the link to set a new password:\n" + link + "\n\n" +
+				"The link expires in 1 hour. If you didn't request password reset, ignore this email.",
+		}); err != nil {
+			return fmt.Errorf("failed to enqueue email: %w", err)
+		}
+
+		return nil
+	})
+}
+
+func (s *authService) ResetPassword(ctx context.Context, token, password string) error {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	return s.s.InTx(ctx, func(us storage.UserStorage) error {
+		userID, err := us.UseResetToken(ctx, hashToken(token))
+		if err != nil {
+			if errors.Is(err, storage.ErrNotFound) {
+				return fmt.Errorf("%w: unknown or expired reset token", ErrInvalidToken)
+			}
+			return fmt.Errorf("failed to use reset token: %w", err)
+		}
+
+		if err := us.UpdateUserPassword(ctx, userID, string(hash)); err != nil {
+			return fmt.Errorf("failed to update password: %w", err)
+		}
+
+		// sign out everywhere
+		if err := us.DeleteUserTokens(ctx, userID); err != nil {
+			return fmt.Errorf("failed to delete user tokens: %w", err)
+		}
+
+		if err := us.RevokeUserTokens(ctx, userID); err != nil {
+			return fmt.Errorf("failed to revoke user tokens: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// newSecretToken returns random token of 256 bits, e.g. password reset token.
+func newSecretToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// hashToken returns hash of the secret token to store, so leaked storage doesn't expose valid tokens.
+func hashToken(token string) string {
+	h := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(h[:])
+}
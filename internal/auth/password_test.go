@@ -0,0 +1,200 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/vliubezny/gstore/internal/model"
+	"github.com/vliubezny/gstore/internal/storage"
+)
+
+func TestService_ForgotPassword(t *testing.T) {
+	user := model.User{ID: 1, Email: "admin@test.com", PasswordHash: testHash}
+	testCases := []struct {
+		desc      string
+		rUserErr  error
+		rSaveErr  error
+		rEmailErr error
+		err       error
+	}{
+		{
+			desc:      "success",
+			rUserErr:  nil,
+			rSaveErr:  nil,
+			rEmailErr: nil,
+			err:       nil,
+		},
+		{
+			desc:      "unknown email - silent success",
+			rUserErr:  storage.ErrNotFound,
+			rSaveErr:  errSkip,
+			rEmailErr: errSkip,
+			err:       nil,
+		},
+		{
+			desc:      "get user - error",
+			rUserErr:  assert.AnError,
+			rSaveErr:  errSkip,
+			rEmailErr: errSkip,
+			err:       assert.AnError,
+		},
+		{
+			desc:      "save token - error",
+			rUserErr:  nil,
+			rSaveErr:  assert.AnError,
+			rEmailErr: errSkip,
+			err:       assert.AnError,
+		},
+		{
+			desc:      "enqueue email - error",
+			rUserErr:  nil,
+			rSaveErr:  nil,
+			rEmailErr: assert.AnError,
+			err:       assert.AnError,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			st := storage.NewMockUserStorage(ctrl)
+			tx := storage.NewMockUserStorage(ctrl)
+
+			st.EXPECT().GetUserByEmail(ctx, user.Email).Return(user, tC.rUserErr)
+
+			var tokenHash string
+			if tC.rSaveErr != errSkip {
+				st.EXPECT().InTx(ctx, gomock.Any()).DoAndReturn(
+					func(_ context.Context, action func(s storage.UserStorage) error) error {
+						return action(tx)
+					})
+				tx.EXPECT().SaveResetToken(ctx, gomock.Any(), user.ID, gomock.AssignableToTypeOf(time.Time{})).
+					DoAndReturn(func(_ context.Context, hash string, _ int64, _ time.Time) error {
+						tokenHash = hash
+						return tC.rSaveErr
+					})
+			}
+
+			if tC.rEmailErr != errSkip {
+				tx.EXPECT().EnqueueEmail(ctx, gomock.AssignableToTypeOf(model.Email{})).
+					DoAndReturn(func(_ context.Context, e model.Email) error {
+						assert.Equal(t, user.Email, e.To)
+
+						link := testAppURL + "/password/reset?token="
+						i := strings.Index(e.Body, link)
+						if assert.True(t, i >= 0, "body must contain reset link") {
+							token := strings.Fields(e.Body[i+len(link):])[0]
+							assert.Equal(t, tokenHash, hashToken(token), "stored hash must match token")
+						}
+						return tC.rEmailErr
+					})
+			}
+
+			s := New(st, NewHMACKeySet(signKey), testAppURL, VerificationOptional, MFAOptional)
+
+			err := s.ForgotPassword(ctx, user.Email)
+
+			assert.True(t, errors.Is(err, tC.err), fmt.Sprintf("wanted %s got %s", tC.err, err))
+		})
+	}
+}
+
+func TestService_ResetPassword(t *testing.T) {
+	testCases := []struct {
+		desc       string
+		rUseErr    error
+		rUpdateErr error
+		rDeleteErr error
+		rRevokeErr error
+		err        error
+	}{
+		{
+			desc:       "success",
+			rUseErr:    nil,
+			rUpdateErr: nil,
+			rDeleteErr: nil,
+			rRevokeErr: nil,
+			err:        nil,
+		},
+		{
+			desc:       "unknown token - ErrInvalidToken",
+			rUseErr:    storage.ErrNotFound,
+			rUpdateErr: errSkip,
+			rDeleteErr: errSkip,
+			rRevokeErr: errSkip,
+			err:        ErrInvalidToken,
+		},
+		{
+			desc:       "use token - error",
+			rUseErr:    assert.AnError,
+			rUpdateErr: errSkip,
+			rDeleteErr: errSkip,
+			rRevokeErr: errSkip,
+			err:        assert.AnError,
+		},
+		{
+			desc:       "update password - error",
+			rUseErr:    nil,
+			rUpdateErr: assert.AnError,
+			rDeleteErr: errSkip,
+			rRevokeErr: errSkip,
+			err:        assert.AnError,
+		},
+		{
+			desc:       "delete tokens - error",
+			rUseErr:    nil,
+			rUpdateErr: nil,
+			rDeleteErr: assert.AnError,
+			rRevokeErr: errSkip,
+			err:        assert.AnError,
+		},
+		{
+			desc:       "revoke tokens - error",
+			rUseErr:    nil,
+			rUpdateErr: nil,
+			rDeleteErr: nil,
+			rRevokeErr: assert.AnError,
+			err:        assert.AnError,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			st := storage.NewMockUserStorage(ctrl)
+			tx := storage.NewMockUserStorage(ctrl)
+
+			st.EXPECT().InTx(ctx, gomock.Any()).DoAndReturn(
+				func(_ context.Context, action func(s storage.UserStorage) error) error {
+					return action(tx)
+				})
+			tx.EXPECT().UseResetToken(ctx, hashToken("token")).Return(int64(1), tC.rUseErr)
+
+			if tC.rUpdateErr != errSkip {
+				tx.EXPECT().UpdateUserPassword(ctx, int64(1), gomock.Any()).Return(tC.rUpdateErr)
+			}
+
+			if tC.rDeleteErr != errSkip {
+				tx.EXPECT().DeleteUserTokens(ctx, int64(1)).Return(tC.rDeleteErr)
+			}
+
+			if tC.rRevokeErr != errSkip {
+				tx.EXPECT().RevokeUserTokens(ctx, int64(1)).Return(tC.rRevokeErr)
+			}
+
+			s := New(st, NewHMACKeySet(signKey), testAppURL, VerificationOptional, MFAOptional)
+
+			err := s.ResetPassword(ctx, "token", testPass)
+
+			assert.True(t, errors.Is(err, tC.err), fmt.Sprintf("wanted %s got %s", tC.err, err))
+		})
+	}
+}
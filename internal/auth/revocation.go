@@ -0,0 +1,109 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/vliubezny/gstore/internal/storage"
+)
+
+const (
+	minRevocationInterval = time.Second
+	maxRevocationInterval = time.Minute
+)
+
+// RevocationChecker reports whether access token was revoked.
+type RevocationChecker func(claims AccessTokenClaims) bool
+
+// Revocations caches per-user watermarks of revoked access tokens along with revoked sessions.
+// Tokens issued before the watermark of the user or within revoked session are revoked,
+// so revocation takes effect on other instances within the refresh interval.
+type Revocations struct {
+	s        storage.UserStorage
+	interval time.Duration
+
+	mu         sync.RWMutex
+	watermarks map[int64]int64
+	sessions   map[string]struct{}
+}
+
+// NewRevocations creates revocation cache refreshed with the interval bounded to [1s, 1m].
+func NewRevocations(s storage.UserStorage, interval time.Duration) *Revocations {
+	if interval < minRevocationInterval {
+		interval = minRevocationInterval
+	}
+	if interval > maxRevocationInterval {
+		interval = maxRevocationInterval
+	}
+
+	return &Revocations{
+		s:          s,
+		interval:   interval,
+		watermarks: make(map[int64]int64),
+		sessions:   make(map[string]struct{}),
+	}
+}
+
+// Refresh loads revocations that may affect unexpired access tokens.
+func (r *Revocations) Refresh(ctx context.Context) error {
+	// extra minute covers clock skew between instances and database
+	since := time.Now().Add(-accessTokenTTL - time.Minute)
+
+	revocations, err := r.s.GetTokenRevocations(ctx, since)
+	if err != nil {
+		return fmt.Errorf("failed to get token revocations: %w", err)
+	}
+
+	watermarks := make(map[int64]int64, len(revocations))
+	sessions := make(map[string]struct{})
+	for _, rv := range revocations {
+		if rv.SessionID != "" {
+			sessions[rv.SessionID] = struct{}{}
+			continue
+		}
+		// iat has seconds precision, so tokens issued within the second of revocation survive
+		// rather than tokens issued right after it are rejected
+		watermarks[rv.UserID] = rv.IssuedBefore.Unix()
+	}
+
+	r.mu.Lock()
+	r.watermarks = watermarks
+	r.sessions = sessions
+	r.mu.Unlock()
+
+	return nil
+}
+
+// Run refreshes revocations with the interval until the context is done.
+func (r *Revocations) Run(ctx context.Context) {
+	if err := r.Refresh(ctx); err != nil {
+		logrus.WithError(err).Error("failed to refresh token revocations")
+	}
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.Refresh(ctx); err != nil {
+				logrus.WithError(err).Error("failed to refresh token revocations")
+			}
+		}
+	}
+}
+
+// IsRevoked reports whether the token was issued before the watermark of its user or within revoked session.
+func (r *Revocations) IsRevoked(claims AccessTokenClaims) bool {
+	r.mu.RLock()
+	watermark, ok := r.watermarks[claims.UserID]
+	_, revoked := r.sessions[claims.SessionID]
+	r.mu.RUnlock()
+
+	return (ok && claims.IssuedAt < watermark) || (claims.SessionID != "" && revoked)
+}
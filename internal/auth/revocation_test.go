@@ -0,0 +1,101 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vliubezny/gstore/internal/model"
+	"github.com/vliubezny/gstore/internal/storage"
+)
+
+func TestRevocations_IsRevoked(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	now := time.Now()
+
+	st := storage.NewMockUserStorage(ctrl)
+	st.EXPECT().GetTokenRevocations(ctx, gomock.Any()).Return([]model.TokenRevocation{
+		{UserID: 1, IssuedBefore: now},
+	}, nil)
+
+	r := NewRevocations(st, time.Second)
+	require.NoError(t, r.Refresh(ctx))
+
+	old := newAccessClaims(model.User{ID: 1})
+	old.IssuedAt = now.Add(-time.Minute).Unix()
+	assert.True(t, r.IsRevoked(old))
+
+	fresh := newAccessClaims(model.User{ID: 1})
+	fresh.IssuedAt = now.Unix()
+	assert.False(t, r.IsRevoked(fresh), "token issued within the second of revocation must be valid")
+
+	other := newAccessClaims(model.User{ID: 2})
+	other.IssuedAt = now.Add(-time.Minute).Unix()
+	assert.False(t, r.IsRevoked(other))
+}
+
+func TestRevocations_IsRevoked_Session(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	now := time.Now()
+
+	st := storage.NewMockUserStorage(ctrl)
+	st.EXPECT().GetTokenRevocations(ctx, gomock.Any()).Return([]model.TokenRevocation{
+		{UserID: 1, SessionID: testFamily, IssuedBefore: now},
+	}, nil)
+
+	r := NewRevocations(st, time.Second)
+	require.NoError(t, r.Refresh(ctx))
+
+	revoked := newAccessClaims(model.User{ID: 1})
+	revoked.SessionID = testFamily
+	revoked.IssuedAt = now.Unix()
+	assert.True(t, r.IsRevoked(revoked), "tokens of revoked session must be revoked whenever issued")
+
+	other := newAccessClaims(model.User{ID: 1})
+	other.SessionID = "another"
+	other.IssuedAt = now.Add(-time.Minute).Unix()
+	assert.False(t, r.IsRevoked(other), "tokens of other sessions of the user must be valid")
+
+	noSession := newAccessClaims(model.User{ID: 1})
+	noSession.IssuedAt = now.Add(-time.Minute).Unix()
+	assert.False(t, r.IsRevoked(noSession))
+}
+
+func TestRevocations_Refresh(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	st := storage.NewMockUserStorage(ctrl)
+	gomock.InOrder(
+		st.EXPECT().GetTokenRevocations(ctx, gomock.Any()).DoAndReturn(
+			func(_ context.Context, since time.Time) ([]model.TokenRevocation, error) {
+				assert.WithinDuration(t, time.Now().Add(-accessTokenTTL), since, 2*time.Minute,
+					"revocations of unexpired tokens must be loaded")
+				return []model.TokenRevocation{{UserID: 1, IssuedBefore: time.Now()}}, nil
+			}),
+		st.EXPECT().GetTokenRevocations(ctx, gomock.Any()).Return(nil, assert.AnError),
+		st.EXPECT().GetTokenRevocations(ctx, gomock.Any()).Return(nil, nil),
+	)
+
+	r := NewRevocations(st, time.Hour)
+	assert.Equal(t, maxRevocationInterval, r.interval)
+
+	c := newAccessClaims(model.User{ID: 1})
+	c.IssuedAt = time.Now().Add(-time.Minute).Unix()
+
+	require.NoError(t, r.Refresh(ctx))
+	assert.True(t, r.IsRevoked(c))
+
+	assert.Error(t, r.Refresh(ctx))
+	assert.True(t, r.IsRevoked(c), "cache must be kept on error")
+
+	require.NoError(t, r.Refresh(ctx))
+	assert.False(t, r.IsRevoked(c), "expired revocations must be dropped")
+}
@@ -17,10 +17,14 @@ import (
 //go:generate mockgen -destination=./service_mock.go -package=auth -source=service.go
 
 const (
-	typeAccess  = "access"
-	typeRefresh = "refresh"
+	typeAccess       = "access"
+	typeRefresh      = "refresh"
+	typeVerification = "verification"
+	typeMFA          = "mfa"
 
 	issuer = "gstore.auth"
+
+	accessTokenTTL = 10 * time.Minute
 )
 
 var (
@@ -35,20 +39,121 @@ var (
 
 	// ErrNotFound states that object(s) was not found.
 	ErrNotFound = errors.New("not found")
+
+	// ErrUnknownRole states that role is unknown.
+	ErrUnknownRole = errors.New("role is unknown")
+
+	// ErrEmailNotVerified states that user has not verified email address.
+	ErrEmailNotVerified = errors.New("email is not verified")
+
+	// ErrRateLimited states that action is repeated too often.
+	ErrRateLimited = errors.New("rate limited")
+
+	// ErrTooManyAttempts states that login is throttled after failed attempts.
+	ErrTooManyAttempts = errors.New("too many login attempts")
+
+	// ErrAccountLocked states that login is locked after too many failed attempts.
+	ErrAccountLocked = errors.New("account is locked")
+
+	// ErrInvalidMFACode states that TOTP or recovery code is invalid.
+	ErrInvalidMFACode = errors.New("invalid mfa code")
+
+	// ErrMFAEnabled states that MFA is already enabled.
+	ErrMFAEnabled = errors.New("mfa is enabled")
+
+	// ErrMFANotEnrolled states that user has not enrolled MFA.
+	ErrMFANotEnrolled = errors.New("mfa is not enrolled")
+
+	// ErrUnknownScope states that scope of API key or OAuth client is unknown.
+	ErrUnknownScope = errors.New("scope is unknown")
+
+	// ErrInvalidClient states that OAuth client is unknown or failed to authenticate.
+	ErrInvalidClient = errors.New("invalid client")
+
+	// ErrUnauthorizedClient states that OAuth client is not allowed to use the grant type.
+	ErrUnauthorizedClient = errors.New("unauthorized client")
+
+	// ErrInvalidGrant states that authorization code or refresh token is invalid or issued to another client.
+	ErrInvalidGrant = errors.New("invalid grant")
+
+	// ErrUnsupportedGrantType states that grant type is not supported.
+	ErrUnsupportedGrantType = errors.New("unsupported grant type")
+
+	// ErrInvalidScope states that requested scope is not granted to OAuth client.
+	ErrInvalidScope = errors.New("invalid scope")
+
+	// ErrInvalidRedirectURI states that redirect URI is not registered for OAuth client.
+	ErrInvalidRedirectURI = errors.New("invalid redirect uri")
+)
+
+// VerificationPolicy defines what unverified users are not allowed to do.
+type VerificationPolicy string
+
+// Verification policies.
+const (
+	// VerificationOptional allows unverified users to do everything.
+	VerificationOptional VerificationPolicy = "none"
+	// VerificationBeforeLogin denies login to unverified users.
+	VerificationBeforeLogin VerificationPolicy = "login"
+	// VerificationBeforeWrite allows unverified users only to read data.
+	VerificationBeforeWrite VerificationPolicy = "write"
+)
+
+// MFAPolicy defines who must use multi-factor authentication.
+type MFAPolicy string
+
+// MFA policies.
+const (
+	// MFAOptional lets users decide whether to enable MFA.
+	MFAOptional MFAPolicy = "optional"
+	// MFARequiredForAdmins grants roles only to users with enabled MFA,
+	// users with roles but without MFA are authenticated as regular users to be able to enroll.
+	MFARequiredForAdmins MFAPolicy = "admin"
 )
 
 // AccessTokenClaims specifies the claims for access token.
 type AccessTokenClaims struct {
-	TokenType string `json:"type,omitempty"`
-	UserID    int64  `json:"userId,omitempty"`
-	IsAdmin   bool   `json:"admin,omitempty"`
+	TokenType     string `json:"type,omitempty"`
+	UserID        int64  `json:"userId,omitempty"`
+	EmailVerified bool   `json:"emailVerified,omitempty"`
+	// Roles are informational, access is checked with permissions granted by them.
+	Roles       []string           `json:"roles,omitempty"`
+	Permissions []model.Permission `json:"permissions,omitempty"`
+	// SessionID is ID of the refresh token family the token is issued with.
+	SessionID string `json:"sid,omitempty"`
+	// APIKeyID is set if user is authenticated by API key instead of access token.
+	APIKeyID string `json:"-"`
+	// ClientID is set if the token is issued to OAuth client.
+	ClientID string `json:"cid,omitempty"`
+	// Scopes of API key or OAuth client limit permissions including ones user has as store member.
+	Scopes []model.Permission `json:"scp,omitempty"`
 	jwt.StandardClaims
 }
 
+// HasPermission checks whether the token grants the permission.
+func (c AccessTokenClaims) HasPermission(p model.Permission) bool {
+	return hasPermission(c.Permissions, p)
+}
+
+// Delegated reports whether API key or OAuth client acts on behalf of the user.
+func (c AccessTokenClaims) Delegated() bool {
+	return c.APIKeyID != "" || c.ClientID != ""
+}
+
+// InScope checks whether the permission is within scopes of delegated access, user's own tokens are not limited.
+func (c AccessTokenClaims) InScope(p model.Permission) bool {
+	return !c.Delegated() || hasPermission(c.Scopes, p)
+}
+
 // RefreshTokenClaims specifies the claims for access token.
 type RefreshTokenClaims struct {
 	TokenType string `json:"type,omitempty"`
 	UserID    int64  `json:"userId,omitempty"`
+	// FamilyID groups tokens rotated from one login.
+	FamilyID string `json:"fid,omitempty"`
+	// ClientID and Scopes are set if the token is issued to OAuth client.
+	ClientID string             `json:"cid,omitempty"`
+	Scopes   []model.Permission `json:"scp,omitempty"`
 	jwt.StandardClaims
 }
 
@@ -56,6 +161,21 @@ type RefreshTokenClaims struct {
 type TokenPair struct {
 	AccessToken  string
 	RefreshToken string
+	// MFAToken is issued by login instead of access and refresh tokens if user has to pass second factor.
+	MFAToken string
+}
+
+// Client describes the device user authenticates from.
+type Client struct {
+	IP        string
+	UserAgent string
+}
+
+// MFAEnrollment contains TOTP secret to be added to authenticator app.
+type MFAEnrollment struct {
+	Secret string
+	// URI is a provisioning URI to be rendered as QR code.
+	URI string
 }
 
 // AccessTokenValidator parses and validates access token.
@@ -64,23 +184,54 @@ type AccessTokenValidator func(token string) (AccessTokenClaims, error)
 // Service provides methods for user authentication.
 type Service interface {
 	Register(ctx context.Context, user model.User, password string) (model.User, error)
-	Login(ctx context.Context, email, password string) (TokenPair, error)
-	Refresh(ctx context.Context, refreshToken string) (TokenPair, error)
+	Login(ctx context.Context, email, password string, client Client) (TokenPair, error)
+	Refresh(ctx context.Context, refreshToken string, client Client) (TokenPair, error)
 	Revoke(ctx context.Context, refreshToken string) error
+	GetSessions(ctx context.Context, userID int64) ([]model.Session, error)
+	RevokeSession(ctx context.Context, userID int64, sessionID string) error
+	RevokeSessions(ctx context.Context, userID int64) error
 	ValidateAccessToken(token string) (AccessTokenClaims, error)
-	UpdateUserPermissions(ctx context.Context, user model.User) error
+	CreateAPIKey(ctx context.Context, userID int64, name string, scopes []model.Permission, expiresAt time.Time) (string, model.APIKey, error)
+	GetAPIKeys(ctx context.Context, userID int64) ([]model.APIKey, error)
+	DeleteAPIKey(ctx context.Context, userID int64, keyID string) error
+	ValidateAPIKey(ctx context.Context, key string) (AccessTokenClaims, error)
+	RegisterOAuthClient(ctx context.Context, client model.OAuthClient, confidential bool) (string, model.OAuthClient, error)
+	GetOAuthClients(ctx context.Context, userID int64) ([]model.OAuthClient, error)
+	DeleteOAuthClient(ctx context.Context, userID int64, clientID string) error
+	AuthorizeOAuthClient(ctx context.Context, userID int64, req AuthorizationRequest) (string, error)
+	AuthenticateOAuthClient(ctx context.Context, clientID, secret string) (model.OAuthClient, error)
+	IssueOAuthToken(ctx context.Context, req OAuthTokenRequest, client Client) (OAuthToken, error)
+	PublicKeys() []PublicKey
+	GetRoles(ctx context.Context) ([]model.Role, error)
+	SetUserRoles(ctx context.Context, userID int64, roles []string) error
+	UnlockUser(ctx context.Context, userID int64) error
+	VerifyMFA(ctx context.Context, mfaToken, code string, client Client) (TokenPair, error)
+	EnrollMFA(ctx context.Context, userID int64) (MFAEnrollment, error)
+	ConfirmMFA(ctx context.Context, userID int64, code string) ([]string, error)
+	DisableMFA(ctx context.Context, userID int64, code, ip string) error
+	ForgotPassword(ctx context.Context, email string) error
+	ResetPassword(ctx context.Context, token, password string) error
+	VerifyEmail(ctx context.Context, token string) error
+	ResendVerification(ctx context.Context, email string) error
 }
 
 type authService struct {
-	s       storage.UserStorage
-	signKey []byte
+	s         storage.UserStorage
+	keys      *KeySet
+	appURL    string
+	policy    VerificationPolicy
+	mfaPolicy MFAPolicy
 }
 
 // New creates instance of auth service.
-func New(s storage.UserStorage, signKey string) Service {
+// keys sign and verify tokens, appURL is a base URL of web application used in links sent to users.
+func New(s storage.UserStorage, keys *KeySet, appURL string, policy VerificationPolicy, mfaPolicy MFAPolicy) Service {
 	return &authService{
-		s:       s,
-		signKey: []byte(signKey),
+		s:         s,
+		keys:      keys,
+		appURL:    appURL,
+		policy:    policy,
+		mfaPolicy: mfaPolicy,
 	}
 }
 
@@ -91,57 +242,123 @@ func (s *authService) Register(ctx context.Context, user model.User, password st
 	}
 
 	user.PasswordHash = string(hash)
+	user.EmailVerified = false
 
-	user, err = s.s.CreateUser(ctx, user)
-	if err != nil {
-		if errors.Is(err, storage.ErrEmailIsTaken) {
-			return model.User{}, ErrEmailIsTaken
+	if err = s.s.InTx(ctx, func(us storage.UserStorage) error {
+		user, err = us.CreateUser(ctx, user)
+		if err != nil {
+			if errors.Is(err, storage.ErrEmailIsTaken) {
+				return ErrEmailIsTaken
+			}
+
+			return fmt.Errorf("failed to register user: %w", err)
 		}
 
-		return model.User{}, fmt.Errorf("failed to register user: %w", err)
+		return s.enqueueVerification(ctx, us, user)
+	}); err != nil {
+		return model.User{}, err
 	}
 
 	return user, nil
 }
 
-func (s *authService) Login(ctx context.Context, email, password string) (TokenPair, error) {
+func (s *authService) Login(ctx context.Context, email, password string, client Client) (TokenPair, error) {
+	account, ipSubject := accountSubject(email), clientSubject(client.IP)
+
+	if err := s.checkLoginFailures(ctx, account, ipSubject); err != nil {
+		return TokenPair{}, err
+	}
+
 	u, err := s.s.GetUserByEmail(ctx, email)
 	if err != nil {
 		if errors.Is(err, storage.ErrNotFound) {
-			return TokenPair{}, ErrInvalidCredentials
+			// don't reveal whether email is registered by response time
+			_ = bcrypt.CompareHashAndPassword([]byte(dummyHash), []byte(password))
+			return TokenPair{}, s.loginFailed(ctx, account, ipSubject, ErrInvalidCredentials)
 		}
 		return TokenPair{}, fmt.Errorf("failed to get user: %w", err)
 	}
 
 	if err = bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(password)); err != nil {
-		return TokenPair{}, ErrInvalidCredentials
+		return TokenPair{}, s.loginFailed(ctx, account, ipSubject, ErrInvalidCredentials)
 	}
 
-	at, err := s.signToken(newAccessClaims(u))
-	if err != nil {
-		return TokenPair{}, fmt.Errorf("failed to sign access token: %w", err)
+	if err = s.s.DeleteLoginFailures(ctx, account); err != nil {
+		return TokenPair{}, fmt.Errorf("failed to delete login failures: %w", err)
+	}
+
+	if s.policy == VerificationBeforeLogin && !u.EmailVerified {
+		return TokenPair{}, ErrEmailNotVerified
+	}
+
+	if u.MFAEnabled {
+		mt, err := s.signToken(newMFAClaims(u))
+		if err != nil {
+			return TokenPair{}, fmt.Errorf("failed to sign mfa token: %w", err)
+		}
+		return TokenPair{MFAToken: mt}, nil
 	}
 
-	rc := newRefreshClaims(u)
+	return s.issueTokens(ctx, u, delegation{}, client)
+}
+
+// issueTokens starts new session of authenticated user.
+func (s *authService) issueTokens(ctx context.Context, u model.User, d delegation, client Client) (TokenPair, error) {
+	pair, rt, err := s.newTokens(u, uuid.NewString(), d, client)
+	if err != nil {
+		return TokenPair{}, err
+	}
 
-	if err = s.s.SaveToken(ctx, rc.Id, u.ID, time.Unix(rc.ExpiresAt, 0)); err != nil {
+	if err = s.s.SaveToken(ctx, rt); err != nil {
 		return TokenPair{}, fmt.Errorf("failed to save refresh token: %w", err)
 	}
 
+	return pair, nil
+}
+
+// newTokens signs access and refresh tokens of the session and returns reference of refresh token to be saved.
+func (s *authService) newTokens(u model.User, familyID string, d delegation, client Client) (TokenPair, model.RefreshToken, error) {
+	ac := d.limit(newAccessClaims(s.grantedUser(u)))
+	ac.SessionID = familyID
+
+	at, err := s.signToken(ac)
+	if err != nil {
+		return TokenPair{}, model.RefreshToken{}, fmt.Errorf("failed to sign access token: %w", err)
+	}
+
+	rc := newRefreshClaims(u, familyID)
+	rc.ClientID, rc.Scopes = d.clientID, d.scopes
 	rt, err := s.signToken(rc)
 	if err != nil {
-		return TokenPair{}, fmt.Errorf("failed to sign refresh token: %w", err)
+		return TokenPair{}, model.RefreshToken{}, fmt.Errorf("failed to sign refresh token: %w", err)
 	}
 
-	return TokenPair{AccessToken: at, RefreshToken: rt}, nil
+	return TokenPair{AccessToken: at, RefreshToken: rt}, model.RefreshToken{
+		ID:        rc.Id,
+		FamilyID:  familyID,
+		UserID:    u.ID,
+		ExpiresAt: time.Unix(rc.ExpiresAt, 0),
+		IP:        client.IP,
+		UserAgent: truncate(client.UserAgent, maxUserAgentLen),
+	}, nil
 }
 
-func (s *authService) Refresh(ctx context.Context, refreshToken string) (TokenPair, error) {
-	claims, err := validateRefreshToken(refreshToken, s.signKey)
+func (s *authService) Refresh(ctx context.Context, refreshToken string, client Client) (TokenPair, error) {
+	claims, err := validateRefreshToken(refreshToken, s.keys)
 	if err != nil {
 		return TokenPair{}, fmt.Errorf("%w: %v", ErrInvalidToken, err)
 	}
 
+	// OAuth client must authenticate to refresh its tokens
+	if claims.ClientID != "" {
+		return TokenPair{}, fmt.Errorf("%w: token is issued to oauth client", ErrInvalidToken)
+	}
+
+	return s.rotate(ctx, claims, delegation{}, client)
+}
+
+// rotate exchanges refresh token for new tokens of the same session.
+func (s *authService) rotate(ctx context.Context, claims RefreshTokenClaims, d delegation, client Client) (TokenPair, error) {
 	u, err := s.s.GetUserByID(ctx, claims.UserID)
 	if err != nil {
 		if errors.Is(err, storage.ErrNotFound) {
@@ -150,75 +367,72 @@ func (s *authService) Refresh(ctx context.Context, refreshToken string) (TokenPa
 		return TokenPair{}, fmt.Errorf("failed to get user: %w", err)
 	}
 
-	at, err := s.signToken(newAccessClaims(u))
-	if err != nil {
-		return TokenPair{}, fmt.Errorf("failed to sign access token: %w", err)
-	}
-
-	rc := newRefreshClaims(u)
-	rt, err := s.signToken(rc)
+	pair, rt, err := s.newTokens(u, claims.family(), d, client)
 	if err != nil {
-		return TokenPair{}, fmt.Errorf("failed to sign refresh token: %w", err)
+		return TokenPair{}, err
 	}
 
 	if err = s.s.InTx(ctx, func(us storage.UserStorage) error {
-		if err = us.DeleteToken(ctx, claims.Id); err != nil {
-			if errors.Is(err, storage.ErrNotFound) { // reject refresh request if token was not found
-				return fmt.Errorf("%w: token has been used", ErrInvalidToken)
+		if err := us.UseToken(ctx, claims.Id); err != nil {
+			switch {
+			case errors.Is(err, storage.ErrNotFound): // reject refresh request if token was revoked
+				return fmt.Errorf("%w: token has been revoked", ErrInvalidToken)
+			case errors.Is(err, storage.ErrTokenReused):
+				return err
 			}
-			return fmt.Errorf("failed to delete token: %w", err)
+			return fmt.Errorf("failed to use token: %w", err)
 		}
 
-		if err = us.SaveToken(ctx, rc.Id, u.ID, time.Unix(rc.ExpiresAt, 0)); err != nil {
+		if err := us.SaveToken(ctx, rt); err != nil {
 			return fmt.Errorf("failed to save refresh token: %w", err)
 		}
 
 		return nil
 	}); err != nil {
+		if errors.Is(err, storage.ErrTokenReused) {
+			return TokenPair{}, s.tokenReused(ctx, claims, client)
+		}
 		return TokenPair{}, err
 	}
 
-	return TokenPair{AccessToken: at, RefreshToken: rt}, nil
+	return pair, nil
 }
 
 func (s *authService) Revoke(ctx context.Context, refreshToken string) error {
-	claims, err := validateRefreshToken(refreshToken, s.signKey)
+	claims, err := validateRefreshToken(refreshToken, s.keys)
 	if err != nil {
 		return fmt.Errorf("%w: %v", ErrInvalidToken, err)
 	}
 
-	if err = s.s.DeleteToken(ctx, claims.Id); err != nil && !errors.Is(err, storage.ErrNotFound) {
-		return fmt.Errorf("failed to delete token: %w", err)
-	}
-
-	return nil
+	return s.revokeFamily(ctx, claims.UserID, claims.family())
 }
 
 func (s *authService) signToken(claims jwt.Claims) (string, error) {
-	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.signKey)
+	return s.keys.sign(claims)
+}
+
+func (s *authService) PublicKeys() []PublicKey {
+	return s.keys.PublicKeys()
 }
 
 func newAccessClaims(user model.User) AccessTokenClaims {
 	return AccessTokenClaims{
-		TokenType: typeAccess,
-		UserID:    user.ID,
-		IsAdmin:   user.IsAdmin,
+		TokenType:     typeAccess,
+		UserID:        user.ID,
+		EmailVerified: user.EmailVerified,
+		Roles:         user.Roles,
+		Permissions:   user.Permissions,
 		StandardClaims: jwt.StandardClaims{
 			Id:        uuid.NewString(),
 			Issuer:    issuer,
 			IssuedAt:  time.Now().Unix(),
-			ExpiresAt: time.Now().Add(10 * time.Minute).Unix(),
+			ExpiresAt: time.Now().Add(accessTokenTTL).Unix(),
 		},
 	}
 }
 
 func (s *authService) ValidateAccessToken(token string) (AccessTokenClaims, error) {
-	at, err := jwt.ParseWithClaims(token, &AccessTokenClaims{}, func(t *jwt.Token) (interface{}, error) {
-		if t.Method.Alg() != jwt.SigningMethodHS256.Alg() {
-			return nil, errors.New("token must be signed with HS256 alg")
-		}
-		return s.signKey, nil
-	})
+	at, err := jwt.ParseWithClaims(token, &AccessTokenClaims{}, s.keys.keyFunc)
 
 	if err != nil {
 		return AccessTokenClaims{}, fmt.Errorf("unable to parse claims: %w", err)
@@ -234,10 +448,11 @@ func (s *authService) ValidateAccessToken(token string) (AccessTokenClaims, erro
 	return *claims, nil
 }
 
-func newRefreshClaims(user model.User) RefreshTokenClaims {
+func newRefreshClaims(user model.User, familyID string) RefreshTokenClaims {
 	return RefreshTokenClaims{
 		TokenType: typeRefresh,
 		UserID:    user.ID,
+		FamilyID:  familyID,
 		StandardClaims: jwt.StandardClaims{
 			Id:        uuid.NewString(),
 			Issuer:    issuer,
@@ -247,13 +462,8 @@ func newRefreshClaims(user model.User) RefreshTokenClaims {
 	}
 }
 
-func validateRefreshToken(token string, signKey []byte) (RefreshTokenClaims, error) {
-	at, err := jwt.ParseWithClaims(token, &RefreshTokenClaims{}, func(t *jwt.Token) (interface{}, error) {
-		if t.Method.Alg() != jwt.SigningMethodHS256.Alg() {
-			return nil, errors.New("token must be signed with HS256 alg")
-		}
-		return signKey, nil
-	})
+func validateRefreshToken(token string, keys *KeySet) (RefreshTokenClaims, error) {
+	at, err := jwt.ParseWithClaims(token, &RefreshTokenClaims{}, keys.keyFunc)
 
 	if err != nil {
 		return RefreshTokenClaims{}, fmt.Errorf("unable to parse claims: %w", err)
@@ -269,13 +479,38 @@ func validateRefreshToken(token string, signKey []byte) (RefreshTokenClaims, err
 	return *claims, nil
 }
 
-func (s *authService) UpdateUserPermissions(ctx context.Context, user model.User) error {
-	if err := s.s.UpdateUserPermissions(ctx, user); err != nil {
-		if errors.Is(storage.ErrNotFound, err) {
+func (s *authService) GetRoles(ctx context.Context) ([]model.Role, error) {
+	roles, err := s.s.GetRoles(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get roles: %w", err)
+	}
+	return roles, nil
+}
+
+func (s *authService) SetUserRoles(ctx context.Context, userID int64, roles []string) error {
+	unique := make([]string, 0, len(roles))
+	seen := make(map[string]bool, len(roles))
+	for _, r := range roles {
+		if !seen[r] {
+			seen[r] = true
+			unique = append(unique, r)
+		}
+	}
+
+	if err := s.s.SetUserRoles(ctx, userID, unique); err != nil {
+		switch {
+		case errors.Is(err, storage.ErrNotFound):
 			return ErrNotFound
+		case errors.Is(err, storage.ErrUnknownRole):
+			return ErrUnknownRole
 		}
 
-		return fmt.Errorf("failed to update user permissions: %w", err)
+		return fmt.Errorf("failed to set user roles: %w", err)
+	}
+
+	// access tokens carry roles and permissions
+	if err := s.s.RevokeUserTokens(ctx, userID); err != nil {
+		return fmt.Errorf("failed to revoke user tokens: %w", err)
 	}
 	return nil
 }
@@ -9,6 +9,7 @@ import (
 	gomock "github.com/golang/mock/gomock"
 	model "github.com/vliubezny/gstore/internal/model"
 	reflect "reflect"
+	time "time"
 )
 
 // MockService is a mock of Service interface
@@ -50,33 +51,33 @@ func (mr *MockServiceMockRecorder) Register(ctx, user, password interface{}) *go
 }
 
 // Login mocks base method
-func (m *MockService) Login(ctx context.Context, email, password string) (TokenPair, error) {
+func (m *MockService) Login(ctx context.Context, email, password string, client Client) (TokenPair, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Login", ctx, email, password)
+	ret := m.ctrl.Call(m, "Login", ctx, email, password, client)
 	ret0, _ := ret[0].(TokenPair)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Login indicates an expected call of Login
-func (mr *MockServiceMockRecorder) Login(ctx, email, password interface{}) *gomock.Call {
+func (mr *MockServiceMockRecorder) Login(ctx, email, password, client interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Login", reflect.TypeOf((*MockService)(nil).Login), ctx, email, password)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Login", reflect.TypeOf((*MockService)(nil).Login), ctx, email, password, client)
 }
 
 // Refresh mocks base method
-func (m *MockService) Refresh(ctx context.Context, refreshToken string) (TokenPair, error) {
+func (m *MockService) Refresh(ctx context.Context, refreshToken string, client Client) (TokenPair, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Refresh", ctx, refreshToken)
+	ret := m.ctrl.Call(m, "Refresh", ctx, refreshToken, client)
 	ret0, _ := ret[0].(TokenPair)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Refresh indicates an expected call of Refresh
-func (mr *MockServiceMockRecorder) Refresh(ctx, refreshToken interface{}) *gomock.Call {
+func (mr *MockServiceMockRecorder) Refresh(ctx, refreshToken, client interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Refresh", reflect.TypeOf((*MockService)(nil).Refresh), ctx, refreshToken)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Refresh", reflect.TypeOf((*MockService)(nil).Refresh), ctx, refreshToken, client)
 }
 
 // Revoke mocks base method
@@ -93,6 +94,49 @@ func (mr *MockServiceMockRecorder) Revoke(ctx, refreshToken interface{}) *gomock
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Revoke", reflect.TypeOf((*MockService)(nil).Revoke), ctx, refreshToken)
 }
 
+// GetSessions mocks base method
+func (m *MockService) GetSessions(ctx context.Context, userID int64) ([]model.Session, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSessions", ctx, userID)
+	ret0, _ := ret[0].([]model.Session)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSessions indicates an expected call of GetSessions
+func (mr *MockServiceMockRecorder) GetSessions(ctx, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSessions", reflect.TypeOf((*MockService)(nil).GetSessions), ctx, userID)
+}
+
+// RevokeSession mocks base method
+func (m *MockService) RevokeSession(ctx context.Context, userID int64, sessionID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeSession", ctx, userID, sessionID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeSession indicates an expected call of RevokeSession
+func (mr *MockServiceMockRecorder) RevokeSession(ctx, userID, sessionID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeSession", reflect.TypeOf((*MockService)(nil).RevokeSession), ctx, userID, sessionID)
+}
+
+// RevokeSessions mocks base method
+func (m *MockService) RevokeSessions(ctx context.Context, userID int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeSessions", ctx, userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeSessions indicates an expected call of RevokeSessions
+func (mr *MockServiceMockRecorder) RevokeSessions(ctx, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeSessions", reflect.TypeOf((*MockService)(nil).RevokeSessions), ctx, userID)
+}
+
 // ValidateAccessToken mocks base method
 func (m *MockService) ValidateAccessToken(token string) (AccessTokenClaims, error) {
 	m.ctrl.T.Helper()
@@ -108,16 +152,324 @@ func (mr *MockServiceMockRecorder) ValidateAccessToken(token interface{}) *gomoc
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ValidateAccessToken", reflect.TypeOf((*MockService)(nil).ValidateAccessToken), token)
 }
 
-// UpdateUserPermissions mocks base method
-func (m *MockService) UpdateUserPermissions(ctx context.Context, user model.User) error {
+// CreateAPIKey mocks base method
+func (m *MockService) CreateAPIKey(ctx context.Context, userID int64, name string, scopes []model.Permission, expiresAt time.Time) (string, model.APIKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateAPIKey", ctx, userID, name, scopes, expiresAt)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(model.APIKey)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// CreateAPIKey indicates an expected call of CreateAPIKey
+func (mr *MockServiceMockRecorder) CreateAPIKey(ctx, userID, name, scopes, expiresAt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAPIKey", reflect.TypeOf((*MockService)(nil).CreateAPIKey), ctx, userID, name, scopes, expiresAt)
+}
+
+// GetAPIKeys mocks base method
+func (m *MockService) GetAPIKeys(ctx context.Context, userID int64) ([]model.APIKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAPIKeys", ctx, userID)
+	ret0, _ := ret[0].([]model.APIKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAPIKeys indicates an expected call of GetAPIKeys
+func (mr *MockServiceMockRecorder) GetAPIKeys(ctx, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAPIKeys", reflect.TypeOf((*MockService)(nil).GetAPIKeys), ctx, userID)
+}
+
+// DeleteAPIKey mocks base method
+func (m *MockService) DeleteAPIKey(ctx context.Context, userID int64, keyID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteAPIKey", ctx, userID, keyID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteAPIKey indicates an expected call of DeleteAPIKey
+func (mr *MockServiceMockRecorder) DeleteAPIKey(ctx, userID, keyID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAPIKey", reflect.TypeOf((*MockService)(nil).DeleteAPIKey), ctx, userID, keyID)
+}
+
+// ValidateAPIKey mocks base method
+func (m *MockService) ValidateAPIKey(ctx context.Context, key string) (AccessTokenClaims, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ValidateAPIKey", ctx, key)
+	ret0, _ := ret[0].(AccessTokenClaims)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ValidateAPIKey indicates an expected call of ValidateAPIKey
+func (mr *MockServiceMockRecorder) ValidateAPIKey(ctx, key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ValidateAPIKey", reflect.TypeOf((*MockService)(nil).ValidateAPIKey), ctx, key)
+}
+
+// RegisterOAuthClient mocks base method
+func (m *MockService) RegisterOAuthClient(ctx context.Context, client model.OAuthClient, confidential bool) (string, model.OAuthClient, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RegisterOAuthClient", ctx, client, confidential)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(model.OAuthClient)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// RegisterOAuthClient indicates an expected call of RegisterOAuthClient
+func (mr *MockServiceMockRecorder) RegisterOAuthClient(ctx, client, confidential interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RegisterOAuthClient", reflect.TypeOf((*MockService)(nil).RegisterOAuthClient), ctx, client, confidential)
+}
+
+// GetOAuthClients mocks base method
+func (m *MockService) GetOAuthClients(ctx context.Context, userID int64) ([]model.OAuthClient, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOAuthClients", ctx, userID)
+	ret0, _ := ret[0].([]model.OAuthClient)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOAuthClients indicates an expected call of GetOAuthClients
+func (mr *MockServiceMockRecorder) GetOAuthClients(ctx, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOAuthClients", reflect.TypeOf((*MockService)(nil).GetOAuthClients), ctx, userID)
+}
+
+// DeleteOAuthClient mocks base method
+func (m *MockService) DeleteOAuthClient(ctx context.Context, userID int64, clientID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteOAuthClient", ctx, userID, clientID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteOAuthClient indicates an expected call of DeleteOAuthClient
+func (mr *MockServiceMockRecorder) DeleteOAuthClient(ctx, userID, clientID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteOAuthClient", reflect.TypeOf((*MockService)(nil).DeleteOAuthClient), ctx, userID, clientID)
+}
+
+// AuthorizeOAuthClient mocks base method
+func (m *MockService) AuthorizeOAuthClient(ctx context.Context, userID int64, req AuthorizationRequest) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AuthorizeOAuthClient", ctx, userID, req)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AuthorizeOAuthClient indicates an expected call of AuthorizeOAuthClient
+func (mr *MockServiceMockRecorder) AuthorizeOAuthClient(ctx, userID, req interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AuthorizeOAuthClient", reflect.TypeOf((*MockService)(nil).AuthorizeOAuthClient), ctx, userID, req)
+}
+
+// AuthenticateOAuthClient mocks base method
+func (m *MockService) AuthenticateOAuthClient(ctx context.Context, clientID, secret string) (model.OAuthClient, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AuthenticateOAuthClient", ctx, clientID, secret)
+	ret0, _ := ret[0].(model.OAuthClient)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AuthenticateOAuthClient indicates an expected call of AuthenticateOAuthClient
+func (mr *MockServiceMockRecorder) AuthenticateOAuthClient(ctx, clientID, secret interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AuthenticateOAuthClient", reflect.TypeOf((*MockService)(nil).AuthenticateOAuthClient), ctx, clientID, secret)
+}
+
+// IssueOAuthToken mocks base method
+func (m *MockService) IssueOAuthToken(ctx context.Context, req OAuthTokenRequest, client Client) (OAuthToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IssueOAuthToken", ctx, req, client)
+	ret0, _ := ret[0].(OAuthToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IssueOAuthToken indicates an expected call of IssueOAuthToken
+func (mr *MockServiceMockRecorder) IssueOAuthToken(ctx, req, client interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IssueOAuthToken", reflect.TypeOf((*MockService)(nil).IssueOAuthToken), ctx, req, client)
+}
+
+// PublicKeys mocks base method
+func (m *MockService) PublicKeys() []PublicKey {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PublicKeys")
+	ret0, _ := ret[0].([]PublicKey)
+	return ret0
+}
+
+// PublicKeys indicates an expected call of PublicKeys
+func (mr *MockServiceMockRecorder) PublicKeys() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PublicKeys", reflect.TypeOf((*MockService)(nil).PublicKeys))
+}
+
+// GetRoles mocks base method
+func (m *MockService) GetRoles(ctx context.Context) ([]model.Role, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRoles", ctx)
+	ret0, _ := ret[0].([]model.Role)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRoles indicates an expected call of GetRoles
+func (mr *MockServiceMockRecorder) GetRoles(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRoles", reflect.TypeOf((*MockService)(nil).GetRoles), ctx)
+}
+
+// SetUserRoles mocks base method
+func (m *MockService) SetUserRoles(ctx context.Context, userID int64, roles []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetUserRoles", ctx, userID, roles)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetUserRoles indicates an expected call of SetUserRoles
+func (mr *MockServiceMockRecorder) SetUserRoles(ctx, userID, roles interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetUserRoles", reflect.TypeOf((*MockService)(nil).SetUserRoles), ctx, userID, roles)
+}
+
+// UnlockUser mocks base method
+func (m *MockService) UnlockUser(ctx context.Context, userID int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UnlockUser", ctx, userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UnlockUser indicates an expected call of UnlockUser
+func (mr *MockServiceMockRecorder) UnlockUser(ctx, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UnlockUser", reflect.TypeOf((*MockService)(nil).UnlockUser), ctx, userID)
+}
+
+// VerifyMFA mocks base method
+func (m *MockService) VerifyMFA(ctx context.Context, mfaToken, code string, client Client) (TokenPair, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VerifyMFA", ctx, mfaToken, code, client)
+	ret0, _ := ret[0].(TokenPair)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// VerifyMFA indicates an expected call of VerifyMFA
+func (mr *MockServiceMockRecorder) VerifyMFA(ctx, mfaToken, code, client interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyMFA", reflect.TypeOf((*MockService)(nil).VerifyMFA), ctx, mfaToken, code, client)
+}
+
+// EnrollMFA mocks base method
+func (m *MockService) EnrollMFA(ctx context.Context, userID int64) (MFAEnrollment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnrollMFA", ctx, userID)
+	ret0, _ := ret[0].(MFAEnrollment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// EnrollMFA indicates an expected call of EnrollMFA
+func (mr *MockServiceMockRecorder) EnrollMFA(ctx, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnrollMFA", reflect.TypeOf((*MockService)(nil).EnrollMFA), ctx, userID)
+}
+
+// ConfirmMFA mocks base method
+func (m *MockService) ConfirmMFA(ctx context.Context, userID int64, code string) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConfirmMFA", ctx, userID, code)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ConfirmMFA indicates an expected call of ConfirmMFA
+func (mr *MockServiceMockRecorder) ConfirmMFA(ctx, userID, code interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConfirmMFA", reflect.TypeOf((*MockService)(nil).ConfirmMFA), ctx, userID, code)
+}
+
+// DisableMFA mocks base method
+func (m *MockService) DisableMFA(ctx context.Context, userID int64, code, ip string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DisableMFA", ctx, userID, code, ip)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DisableMFA indicates an expected call of DisableMFA
+func (mr *MockServiceMockRecorder) DisableMFA(ctx, userID, code, ip interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DisableMFA", reflect.TypeOf((*MockService)(nil).DisableMFA), ctx, userID, code, ip)
+}
+
+// ForgotPassword mocks base method
+func (m *MockService) ForgotPassword(ctx context.Context, email string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ForgotPassword", ctx, email)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ForgotPassword indicates an expected call of ForgotPassword
+func (mr *MockServiceMockRecorder) ForgotPassword(ctx, email interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ForgotPassword", reflect.TypeOf((*MockService)(nil).ForgotPassword), ctx, email)
+}
+
+// ResetPassword mocks base method
+func (m *MockService) ResetPassword(ctx context.Context, token, password string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResetPassword", ctx, token, password)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ResetPassword indicates an expected call of ResetPassword
+func (mr *MockServiceMockRecorder) ResetPassword(ctx, token, password interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResetPassword", reflect.TypeOf((*MockService)(nil).ResetPassword), ctx, token, password)
+}
+
+// VerifyEmail mocks base method
+func (m *MockService) VerifyEmail(ctx context.Context, token string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VerifyEmail", ctx, token)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// VerifyEmail indicates an expected call of VerifyEmail
+func (mr *MockServiceMockRecorder) VerifyEmail(ctx, token interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyEmail", reflect.TypeOf((*MockService)(nil).VerifyEmail), ctx, token)
+}
+
+// ResendVerification mocks base method
+func (m *MockService) ResendVerification(ctx context.Context, email string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "UpdateUserPermissions", ctx, user)
+	ret := m.ctrl.Call(m, "ResendVerification", ctx, email)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// UpdateUserPermissions indicates an expected call of UpdateUserPermissions
-func (mr *MockServiceMockRecorder) UpdateUserPermissions(ctx, user interface{}) *gomock.Call {
+// ResendVerification indicates an expected call of ResendVerification
+func (mr *MockServiceMockRecorder) ResendVerification(ctx, email interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUserPermissions", reflect.TypeOf((*MockService)(nil).UpdateUserPermissions), ctx, user)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResendVerification", reflect.TypeOf((*MockService)(nil).ResendVerification), ctx, email)
 }
@@ -17,44 +17,64 @@ import (
 )
 
 const (
-	signKey  = "secret"
-	testPass = "test123"
-	testHash = "$2a$10$Ej1ANHun0jp1O5ozBhTbGODKprti6Z2FheUyHdyuvcJ6/feFo9s/K"
+	signKey    = "secret"
+	testAppURL = "http://localhost"
+	testIP     = "127.0.0.1"
+	testFamily = "0e37df36-f698-11e6-8dd4-cb9ced3df976"
+	testPass   = "test123"
+	testHash   = "$2a$10$Ej1ANHun0jp1O5ozBhTbGODKprti6Z2FheUyHdyuvcJ6/feFo9s/K"
 )
 
 var (
 	ctx     = context.Background()
 	errSkip = errors.New("skip")
+
+	testClient = Client{IP: testIP, UserAgent: "test"}
+
+	testRoles       = []string{model.RoleCatalogEditor}
+	testPermissions = []model.Permission{model.PermissionCategoryWrite, model.PermissionProductWrite}
 )
 
 func TestService_Register(t *testing.T) {
 	testCases := []struct {
-		desc     string
-		rErr     error
-		user     model.User
-		password string
-		err      error
+		desc      string
+		rErr      error
+		rEmailErr error
+		user      model.User
+		password  string
+		err       error
 	}{
 		{
-			desc:     "success",
-			rErr:     nil,
-			user:     model.User{Email: "admin@test.com", IsAdmin: true},
-			password: testPass,
-			err:      nil,
+			desc:      "success",
+			rErr:      nil,
+			rEmailErr: nil,
+			user:      model.User{Email: "admin@test.com"},
+			password:  testPass,
+			err:       nil,
+		},
+		{
+			desc:      "ErrEmailIsTaken",
+			rErr:      storage.ErrEmailIsTaken,
+			rEmailErr: errSkip,
+			user:      model.User{Email: "admin@test.com"},
+			password:  testPass,
+			err:       ErrEmailIsTaken,
 		},
 		{
-			desc:     "ErrEmailIsTaken",
-			rErr:     storage.ErrEmailIsTaken,
-			user:     model.User{Email: "admin@test.com", IsAdmin: true},
-			password: testPass,
-			err:      ErrEmailIsTaken,
+			desc:      "unexpected error",
+			rErr:      assert.AnError,
+			rEmailErr: errSkip,
+			user:      model.User{Email: "admin@test.com"},
+			password:  testPass,
+			err:       assert.AnError,
 		},
 		{
-			desc:     "unexpected error",
-			rErr:     assert.AnError,
-			user:     model.User{Email: "admin@test.com", IsAdmin: true},
-			password: testPass,
-			err:      assert.AnError,
+			desc:      "enqueue email - error",
+			rErr:      nil,
+			rEmailErr: assert.AnError,
+			user:      model.User{Email: "admin@test.com"},
+			password:  testPass,
+			err:       assert.AnError,
 		},
 	}
 	for _, tC := range testCases {
@@ -63,14 +83,24 @@ func TestService_Register(t *testing.T) {
 			defer ctrl.Finish()
 
 			st := storage.NewMockUserStorage(ctrl)
+			tx := storage.NewMockUserStorage(ctrl)
 
-			st.EXPECT().CreateUser(ctx, gomock.AssignableToTypeOf(model.User{})).
+			st.EXPECT().InTx(ctx, gomock.Any()).DoAndReturn(
+				func(_ context.Context, action func(s storage.UserStorage) error) error {
+					return action(tx)
+				})
+
+			tx.EXPECT().CreateUser(ctx, gomock.AssignableToTypeOf(model.User{})).
 				DoAndReturn(func(_ context.Context, u model.User) (model.User, error) {
 					u.ID = 1
 					return u, tC.rErr
 				})
 
-			s := New(st, signKey)
+			if tC.rEmailErr != errSkip {
+				tx.EXPECT().EnqueueEmail(ctx, gomock.AssignableToTypeOf(model.Email{})).Return(tC.rEmailErr)
+			}
+
+			s := New(st, NewHMACKeySet(signKey), testAppURL, VerificationOptional, MFAOptional)
 
 			u, err := s.Register(ctx, tC.user, tC.password)
 
@@ -78,7 +108,7 @@ func TestService_Register(t *testing.T) {
 			if err == nil {
 				assert.Equal(t, int64(1), u.ID)
 				assert.Equal(t, tC.user.Email, u.Email)
-				assert.Equal(t, tC.user.IsAdmin, u.IsAdmin)
+				assert.False(t, u.EmailVerified)
 				assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(tC.password)), "incorrect password hash")
 			}
 		})
@@ -97,7 +127,7 @@ func TestService_Login(t *testing.T) {
 	}{
 		{
 			desc:      "success",
-			rUser:     model.User{ID: 1, Email: "admin@test.com", PasswordHash: testHash, IsAdmin: true},
+			rUser:     model.User{ID: 1, Email: "admin@test.com", PasswordHash: testHash, Roles: testRoles, Permissions: testPermissions},
 			rUserErr:  nil,
 			rTokenErr: nil,
 			email:     "admin@test.com",
@@ -106,7 +136,7 @@ func TestService_Login(t *testing.T) {
 		},
 		{
 			desc:      "invalid email",
-			rUser:     model.User{ID: 1, Email: "admin@test.com", PasswordHash: testHash, IsAdmin: true},
+			rUser:     model.User{ID: 1, Email: "admin@test.com", PasswordHash: testHash, Roles: testRoles, Permissions: testPermissions},
 			rUserErr:  storage.ErrNotFound,
 			rTokenErr: errSkip,
 			email:     "admin@test.com",
@@ -115,7 +145,7 @@ func TestService_Login(t *testing.T) {
 		},
 		{
 			desc:      "invalid password",
-			rUser:     model.User{ID: 1, Email: "admin@test.com", PasswordHash: testHash, IsAdmin: true},
+			rUser:     model.User{ID: 1, Email: "admin@test.com", PasswordHash: testHash, Roles: testRoles, Permissions: testPermissions},
 			rUserErr:  nil,
 			rTokenErr: errSkip,
 			email:     "admin@test.com",
@@ -124,7 +154,7 @@ func TestService_Login(t *testing.T) {
 		},
 		{
 			desc:      "error getting user",
-			rUser:     model.User{ID: 1, Email: "admin@test.com", PasswordHash: testHash, IsAdmin: true},
+			rUser:     model.User{ID: 1, Email: "admin@test.com", PasswordHash: testHash, Roles: testRoles, Permissions: testPermissions},
 			rUserErr:  assert.AnError,
 			rTokenErr: errSkip,
 			email:     "admin@test.com",
@@ -133,7 +163,7 @@ func TestService_Login(t *testing.T) {
 		},
 		{
 			desc:      "error saving token",
-			rUser:     model.User{ID: 1, Email: "admin@test.com", PasswordHash: testHash, IsAdmin: true},
+			rUser:     model.User{ID: 1, Email: "admin@test.com", PasswordHash: testHash, Roles: testRoles, Permissions: testPermissions},
 			rUserErr:  nil,
 			rTokenErr: assert.AnError,
 			email:     "admin@test.com",
@@ -148,16 +178,32 @@ func TestService_Login(t *testing.T) {
 
 			st := storage.NewMockUserStorage(ctrl)
 
+			st.EXPECT().GetLoginFailures(ctx, []string{"account:" + tC.email, "ip:" + testIP}).Return(nil, nil)
 			st.EXPECT().GetUserByEmail(ctx, tC.email).Return(tC.rUser, tC.rUserErr)
 
+			if tC.err == ErrInvalidCredentials {
+				st.EXPECT().AddLoginFailure(ctx, "account:"+tC.email, accountPolicy.window).
+					Return(model.LoginFailures{Count: 1}, nil)
+				st.EXPECT().AddLoginFailure(ctx, "ip:"+testIP, clientPolicy.window).
+					Return(model.LoginFailures{Count: 1}, nil)
+			}
+
 			if tC.rTokenErr != errSkip {
-				st.EXPECT().SaveToken(ctx, gomock.AssignableToTypeOf(""), tC.rUser.ID, gomock.AssignableToTypeOf(time.Time{})).
-					Return(tC.rTokenErr)
+				st.EXPECT().DeleteLoginFailures(ctx, "account:"+tC.email).Return(nil)
+				st.EXPECT().SaveToken(ctx, gomock.AssignableToTypeOf(model.RefreshToken{})).
+					DoAndReturn(func(_ context.Context, rt model.RefreshToken) error {
+						assert.Equal(t, tC.rUser.ID, rt.UserID)
+						assert.NotEmpty(t, rt.ID)
+						assert.NotEmpty(t, rt.FamilyID)
+						assert.Equal(t, testIP, rt.IP)
+						assert.Equal(t, "test", rt.UserAgent)
+						return tC.rTokenErr
+					})
 			}
 
-			s := New(st, signKey)
+			s := New(st, NewHMACKeySet(signKey), testAppURL, VerificationOptional, MFAOptional)
 
-			pair, err := s.Login(ctx, tC.email, tC.password)
+			pair, err := s.Login(ctx, tC.email, tC.password, testClient)
 
 			assert.True(t, errors.Is(err, tC.err), fmt.Sprintf("wanted %s got %s", tC.err, err))
 			if err == nil {
@@ -178,7 +224,7 @@ func mustCreateAccessToken(u model.User) string {
 }
 
 func mustCreateRefreshToken(u model.User) string {
-	c := newRefreshClaims(u)
+	c := newRefreshClaims(u, testFamily)
 	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, c).SignedString([]byte(signKey))
 	if err != nil {
 		panic(err)
@@ -187,13 +233,14 @@ func mustCreateRefreshToken(u model.User) string {
 }
 
 func TestService_Refresh(t *testing.T) {
-	user := model.User{ID: 1, Email: "admin@test.com", PasswordHash: testHash, IsAdmin: true}
+	user := model.User{ID: 1, Email: "admin@test.com", PasswordHash: testHash, Roles: testRoles, Permissions: testPermissions}
 	testCases := []struct {
 		desc            string
 		rUser           model.User
 		rUserErr        error
-		rDeleteTokenErr error
+		rUseTokenErr    error
 		rSaveTokenErr   error
+		rDeleteTokenErr error
 		token           string
 		err             error
 	}{
@@ -201,8 +248,9 @@ func TestService_Refresh(t *testing.T) {
 			desc:            "success",
 			rUser:           user,
 			rUserErr:        nil,
-			rDeleteTokenErr: nil,
+			rUseTokenErr:    nil,
 			rSaveTokenErr:   nil,
+			rDeleteTokenErr: errSkip,
 			token:           mustCreateRefreshToken(user),
 			err:             nil,
 		},
@@ -210,17 +258,29 @@ func TestService_Refresh(t *testing.T) {
 			desc:            "malformed token - ErrInvalidToken",
 			rUser:           model.User{},
 			rUserErr:        errSkip,
-			rDeleteTokenErr: errSkip,
+			rUseTokenErr:    errSkip,
 			rSaveTokenErr:   errSkip,
+			rDeleteTokenErr: errSkip,
 			token:           "test",
 			err:             ErrInvalidToken,
 		},
+		{
+			desc:            "token of oauth client - ErrInvalidToken",
+			rUser:           model.User{},
+			rUserErr:        errSkip,
+			rUseTokenErr:    errSkip,
+			rSaveTokenErr:   errSkip,
+			rDeleteTokenErr: errSkip,
+			token:           mustCreateClientRefreshToken(user, testClientID, testScopes),
+			err:             ErrInvalidToken,
+		},
 		{
 			desc:            "missing user - ErrInvalidToken",
 			rUser:           user,
 			rUserErr:        storage.ErrNotFound,
-			rDeleteTokenErr: errSkip,
+			rUseTokenErr:    errSkip,
 			rSaveTokenErr:   errSkip,
+			rDeleteTokenErr: errSkip,
 			token:           mustCreateRefreshToken(user),
 			err:             ErrInvalidToken,
 		},
@@ -228,26 +288,49 @@ func TestService_Refresh(t *testing.T) {
 			desc:            "get user - error",
 			rUser:           user,
 			rUserErr:        assert.AnError,
-			rDeleteTokenErr: errSkip,
+			rUseTokenErr:    errSkip,
 			rSaveTokenErr:   errSkip,
+			rDeleteTokenErr: errSkip,
 			token:           mustCreateRefreshToken(user),
 			err:             assert.AnError,
 		},
 		{
-			desc:            "token used - ErrInvalidToken",
+			desc:            "token revoked - ErrInvalidToken",
+			rUser:           user,
+			rUserErr:        nil,
+			rUseTokenErr:    storage.ErrNotFound,
+			rSaveTokenErr:   errSkip,
+			rDeleteTokenErr: errSkip,
+			token:           mustCreateRefreshToken(user),
+			err:             ErrInvalidToken,
+		},
+		{
+			desc:            "token reused - ErrInvalidToken",
 			rUser:           user,
 			rUserErr:        nil,
-			rDeleteTokenErr: storage.ErrNotFound,
+			rUseTokenErr:    storage.ErrTokenReused,
 			rSaveTokenErr:   errSkip,
+			rDeleteTokenErr: nil,
 			token:           mustCreateRefreshToken(user),
 			err:             ErrInvalidToken,
 		},
 		{
-			desc:            "delete token - error",
+			desc:            "token reused - delete family error",
 			rUser:           user,
 			rUserErr:        nil,
+			rUseTokenErr:    storage.ErrTokenReused,
+			rSaveTokenErr:   errSkip,
 			rDeleteTokenErr: assert.AnError,
+			token:           mustCreateRefreshToken(user),
+			err:             assert.AnError,
+		},
+		{
+			desc:            "use token - error",
+			rUser:           user,
+			rUserErr:        nil,
+			rUseTokenErr:    assert.AnError,
 			rSaveTokenErr:   errSkip,
+			rDeleteTokenErr: errSkip,
 			token:           mustCreateRefreshToken(user),
 			err:             assert.AnError,
 		},
@@ -255,8 +338,9 @@ func TestService_Refresh(t *testing.T) {
 			desc:            "save token - error",
 			rUser:           user,
 			rUserErr:        nil,
-			rDeleteTokenErr: nil,
+			rUseTokenErr:    nil,
 			rSaveTokenErr:   assert.AnError,
+			rDeleteTokenErr: errSkip,
 			token:           mustCreateRefreshToken(user),
 			err:             assert.AnError,
 		},
@@ -273,63 +357,95 @@ func TestService_Refresh(t *testing.T) {
 				st.EXPECT().GetUserByID(ctx, tC.rUser.ID).Return(tC.rUser, tC.rUserErr)
 			}
 
-			if tC.rDeleteTokenErr != errSkip {
+			if tC.rUseTokenErr != errSkip {
 				st.EXPECT().InTx(ctx, gomock.Any()).DoAndReturn(
 					func(_ context.Context, action func(s storage.UserStorage) error) error {
 						return action(tx)
 					})
-				tx.EXPECT().DeleteToken(ctx, gomock.Any()).Return(tC.rDeleteTokenErr)
+				tx.EXPECT().UseToken(ctx, gomock.Any()).Return(tC.rUseTokenErr)
 			}
 
 			if tC.rSaveTokenErr != errSkip {
-				tx.EXPECT().SaveToken(ctx, gomock.Any(), tC.rUser.ID, gomock.AssignableToTypeOf(time.Time{})).
-					Return(tC.rSaveTokenErr)
+				tx.EXPECT().SaveToken(ctx, gomock.AssignableToTypeOf(model.RefreshToken{})).
+					DoAndReturn(func(_ context.Context, rt model.RefreshToken) error {
+						assert.Equal(t, testFamily, rt.FamilyID, "rotated token must stay in the family")
+						assert.Equal(t, testIP, rt.IP)
+						return tC.rSaveTokenErr
+					})
+			}
+
+			if tC.rDeleteTokenErr != errSkip {
+				st.EXPECT().InTx(ctx, gomock.Any()).DoAndReturn(
+					func(_ context.Context, action func(s storage.UserStorage) error) error {
+						return action(tx)
+					})
+				tx.EXPECT().DeleteTokenFamily(ctx, tC.rUser.ID, testFamily).Return(tC.rDeleteTokenErr)
+
+				if tC.rDeleteTokenErr == nil {
+					tx.EXPECT().RevokeSessionTokens(ctx, tC.rUser.ID, testFamily).Return(nil)
+				}
 			}
 
-			s := New(st, signKey)
+			s := New(st, NewHMACKeySet(signKey), testAppURL, VerificationOptional, MFAOptional)
 
-			pair, err := s.Refresh(ctx, tC.token)
+			pair, err := s.Refresh(ctx, tC.token, testClient)
 
 			assert.True(t, errors.Is(err, tC.err), fmt.Sprintf("wanted %s got %s", tC.err, err))
 			if err == nil {
 				assert.NotEmpty(t, pair.AccessToken)
 				assert.NotEmpty(t, pair.RefreshToken)
+
+				claims, err := s.ValidateAccessToken(pair.AccessToken)
+				require.NoError(t, err)
+				assert.Equal(t, testFamily, claims.SessionID)
 			}
 		})
 	}
 }
 
 func TestService_Revoke(t *testing.T) {
-	user := model.User{ID: 1, Email: "admin@test.com", PasswordHash: testHash, IsAdmin: true}
+	user := model.User{ID: 1, Email: "admin@test.com", PasswordHash: testHash, Roles: testRoles, Permissions: testPermissions}
 	testCases := []struct {
-		desc  string
-		rErr  error
-		token string
-		err   error
+		desc       string
+		rErr       error
+		rRevokeErr error
+		token      string
+		err        error
 	}{
 		{
-			desc:  "success",
-			rErr:  nil,
-			token: mustCreateRefreshToken(user),
-			err:   nil,
+			desc:       "success",
+			rErr:       nil,
+			rRevokeErr: nil,
+			token:      mustCreateRefreshToken(user),
+			err:        nil,
 		},
 		{
-			desc:  "token used - success",
-			rErr:  storage.ErrNotFound,
-			token: mustCreateRefreshToken(user),
-			err:   nil,
+			desc:       "session revoked - success",
+			rErr:       storage.ErrNotFound,
+			rRevokeErr: nil,
+			token:      mustCreateRefreshToken(user),
+			err:        nil,
+		},
+		{
+			desc:       "malformed token - ErrInvalidToken",
+			rErr:       errSkip,
+			rRevokeErr: errSkip,
+			token:      "test",
+			err:        ErrInvalidToken,
 		},
 		{
-			desc:  "malformed token - ErrInvalidToken",
-			rErr:  errSkip,
-			token: "test",
-			err:   ErrInvalidToken,
+			desc:       "delete token family - error",
+			rErr:       assert.AnError,
+			rRevokeErr: errSkip,
+			token:      mustCreateRefreshToken(user),
+			err:        assert.AnError,
 		},
 		{
-			desc:  "delete token - error",
-			rErr:  assert.AnError,
-			token: mustCreateRefreshToken(user),
-			err:   assert.AnError,
+			desc:       "revoke session tokens - error",
+			rErr:       nil,
+			rRevokeErr: assert.AnError,
+			token:      mustCreateRefreshToken(user),
+			err:        assert.AnError,
 		},
 	}
 	for _, tC := range testCases {
@@ -338,12 +454,21 @@ func TestService_Revoke(t *testing.T) {
 			defer ctrl.Finish()
 
 			st := storage.NewMockUserStorage(ctrl)
+			tx := storage.NewMockUserStorage(ctrl)
 
 			if tC.rErr != errSkip {
-				st.EXPECT().DeleteToken(ctx, gomock.AssignableToTypeOf("")).Return(tC.rErr)
+				st.EXPECT().InTx(ctx, gomock.Any()).DoAndReturn(
+					func(_ context.Context, action func(s storage.UserStorage) error) error {
+						return action(tx)
+					})
+				tx.EXPECT().DeleteTokenFamily(ctx, user.ID, testFamily).Return(tC.rErr)
 			}
 
-			s := New(st, signKey)
+			if tC.rRevokeErr != errSkip {
+				tx.EXPECT().RevokeSessionTokens(ctx, user.ID, testFamily).Return(tC.rRevokeErr)
+			}
+
+			s := New(st, NewHMACKeySet(signKey), testAppURL, VerificationOptional, MFAOptional)
 
 			err := s.Revoke(ctx, tC.token)
 
@@ -354,51 +479,56 @@ func TestService_Revoke(t *testing.T) {
 
 func TestService_newAccessClaims(t *testing.T) {
 	u := model.User{
-		ID:      1,
-		Email:   "admin@test.com",
-		IsAdmin: true,
+		ID:          1,
+		Email:       "admin@test.com",
+		Roles:       testRoles,
+		Permissions: testPermissions,
 	}
 
 	ac := newAccessClaims(u)
 
 	assert.Equal(t, typeAccess, ac.TokenType)
 	assert.Equal(t, u.ID, ac.UserID)
-	assert.Equal(t, u.IsAdmin, ac.IsAdmin)
+	assert.Equal(t, u.Roles, ac.Roles)
+	assert.Equal(t, u.Permissions, ac.Permissions)
 	assert.NotEmpty(t, ac.Id)
 	assert.InDelta(t, time.Now().Add(10*time.Minute).Unix(), ac.ExpiresAt, 1)
 }
 
 func TestService_newRefreshClaims(t *testing.T) {
 	u := model.User{
-		ID:      1,
-		Email:   "admin@test.com",
-		IsAdmin: true,
+		ID:          1,
+		Email:       "admin@test.com",
+		Roles:       testRoles,
+		Permissions: testPermissions,
 	}
 
-	ac := newRefreshClaims(u)
+	ac := newRefreshClaims(u, testFamily)
 
 	assert.Equal(t, typeRefresh, ac.TokenType)
 	assert.Equal(t, u.ID, ac.UserID)
+	assert.Equal(t, testFamily, ac.FamilyID)
 	assert.NotEmpty(t, ac.Id)
 	assert.InDelta(t, time.Now().Add(30*24*time.Hour).Unix(), ac.ExpiresAt, 1)
 }
 
 func TestService_validateRefreshToken(t *testing.T) {
 	s := &authService{
-		signKey: []byte(signKey),
+		keys: NewHMACKeySet(signKey),
 	}
 
 	u := model.User{
-		ID:      1,
-		Email:   "admin@test.com",
-		IsAdmin: true,
+		ID:          1,
+		Email:       "admin@test.com",
+		Roles:       testRoles,
+		Permissions: testPermissions,
 	}
 
-	ac := newRefreshClaims(u)
+	ac := newRefreshClaims(u, testFamily)
 	token, err := s.signToken(ac)
 	require.NoError(t, err)
 
-	ac, err = validateRefreshToken(token, []byte(signKey))
+	ac, err = validateRefreshToken(token, NewHMACKeySet(signKey))
 	require.NoError(t, err)
 
 	assert.Equal(t, typeRefresh, ac.TokenType)
@@ -408,12 +538,13 @@ func TestService_validateRefreshToken(t *testing.T) {
 }
 
 func TestService_ValidateAccessToken(t *testing.T) {
-	s := New(nil, signKey)
+	s := New(nil, NewHMACKeySet(signKey), testAppURL, VerificationOptional, MFAOptional)
 
 	u := model.User{
-		ID:      1,
-		Email:   "admin@test.com",
-		IsAdmin: true,
+		ID:          1,
+		Email:       "admin@test.com",
+		Roles:       testRoles,
+		Permissions: testPermissions,
 	}
 
 	token := mustCreateAccessToken(u)
@@ -426,29 +557,24 @@ func TestService_ValidateAccessToken(t *testing.T) {
 	assert.NotEmpty(t, claims.Id)
 }
 
-func TestService_UpdateUserPermissions(t *testing.T) {
+func TestService_GetRoles(t *testing.T) {
+	roles := []model.Role{{Name: model.RoleSuperadmin, Permissions: testPermissions}}
+
 	testCases := []struct {
-		desc string
-		user model.User
-		rErr error
-		err  error
+		desc  string
+		roles []model.Role
+		rErr  error
+		err   error
 	}{
 		{
-			desc: "success",
-			user: model.User{ID: 1, IsAdmin: true},
-			rErr: nil,
-			err:  nil,
-		},
-		{
-			desc: "ErrNotFound",
-			rErr: storage.ErrNotFound,
-			user: model.User{ID: 1, IsAdmin: true},
-			err:  ErrNotFound,
+			desc:  "success",
+			roles: roles,
+			rErr:  nil,
+			err:   nil,
 		},
 		{
 			desc: "unexpected error",
 			rErr: assert.AnError,
-			user: model.User{ID: 1, IsAdmin: true},
 			err:  assert.AnError,
 		},
 	}
@@ -459,13 +585,87 @@ func TestService_UpdateUserPermissions(t *testing.T) {
 
 			st := storage.NewMockUserStorage(ctrl)
 
-			st.EXPECT().UpdateUserPermissions(ctx, tC.user).Return(tC.rErr)
+			st.EXPECT().GetRoles(ctx).Return(tC.roles, tC.rErr)
+
+			s := New(st, NewHMACKeySet(signKey), testAppURL, VerificationOptional, MFAOptional)
+
+			r, err := s.GetRoles(ctx)
+
+			assert.True(t, errors.Is(err, tC.err), fmt.Sprintf("wanted %s got %s", tC.err, err))
+			assert.Equal(t, tC.roles, r)
+		})
+	}
+}
+
+func TestService_SetUserRoles(t *testing.T) {
+	testCases := []struct {
+		desc       string
+		roles      []string
+		rErr       error
+		rRevokeErr error
+		err        error
+	}{
+		{
+			desc:       "success",
+			roles:      []string{model.RoleSupport, model.RoleCatalogEditor, model.RoleSupport},
+			rErr:       nil,
+			rRevokeErr: nil,
+			err:        nil,
+		},
+		{
+			desc:       "ErrNotFound",
+			roles:      []string{model.RoleSupport, model.RoleCatalogEditor},
+			rErr:       storage.ErrNotFound,
+			rRevokeErr: errSkip,
+			err:        ErrNotFound,
+		},
+		{
+			desc:       "ErrUnknownRole",
+			roles:      []string{model.RoleSupport, model.RoleCatalogEditor},
+			rErr:       storage.ErrUnknownRole,
+			rRevokeErr: errSkip,
+			err:        ErrUnknownRole,
+		},
+		{
+			desc:       "unexpected error",
+			roles:      []string{model.RoleSupport, model.RoleCatalogEditor},
+			rErr:       assert.AnError,
+			rRevokeErr: errSkip,
+			err:        assert.AnError,
+		},
+		{
+			desc:       "revoke tokens - error",
+			roles:      []string{model.RoleSupport, model.RoleCatalogEditor},
+			rErr:       nil,
+			rRevokeErr: assert.AnError,
+			err:        assert.AnError,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			st := storage.NewMockUserStorage(ctrl)
+
+			st.EXPECT().SetUserRoles(ctx, int64(1), []string{model.RoleSupport, model.RoleCatalogEditor}).Return(tC.rErr)
+
+			if tC.rRevokeErr != errSkip {
+				st.EXPECT().RevokeUserTokens(ctx, int64(1)).Return(tC.rRevokeErr)
+			}
 
-			s := New(st, signKey)
+			s := New(st, NewHMACKeySet(signKey), testAppURL, VerificationOptional, MFAOptional)
 
-			err := s.UpdateUserPermissions(ctx, tC.user)
+			err := s.SetUserRoles(ctx, 1, tC.roles)
 
 			assert.True(t, errors.Is(err, tC.err), fmt.Sprintf("wanted %s got %s", tC.err, err))
 		})
 	}
 }
+
+func TestAccessTokenClaims_HasPermission(t *testing.T) {
+	c := AccessTokenClaims{Permissions: testPermissions}
+
+	assert.True(t, c.HasPermission(model.PermissionCategoryWrite))
+	assert.False(t, c.HasPermission(model.PermissionUserWrite))
+}
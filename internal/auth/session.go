@@ -0,0 +1,105 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/vliubezny/gstore/internal/model"
+	"github.com/vliubezny/gstore/internal/storage"
+)
+
+const maxUserAgentLen = 256
+
+func (s *authService) GetSessions(ctx context.Context, userID int64) ([]model.Session, error) {
+	sessions, err := s.s.GetSessions(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sessions: %w", err)
+	}
+	return sessions, nil
+}
+
+// RevokeSession deletes refresh tokens of the session and revokes its access tokens,
+// so the session can't be used anymore on any instance once revocations are refreshed.
+func (s *authService) RevokeSession(ctx context.Context, userID int64, sessionID string) error {
+	return s.s.InTx(ctx, func(us storage.UserStorage) error {
+		if err := us.DeleteTokenFamily(ctx, userID, sessionID); err != nil {
+			if errors.Is(err, storage.ErrNotFound) {
+				return ErrNotFound
+			}
+			return fmt.Errorf("failed to delete token family: %w", err)
+		}
+
+		if err := us.RevokeSessionTokens(ctx, userID, sessionID); err != nil {
+			return fmt.Errorf("failed to revoke session tokens: %w", err)
+		}
+
+		return nil
+	})
+}
+
+func (s *authService) RevokeSessions(ctx context.Context, userID int64) error {
+	return s.s.InTx(ctx, func(us storage.UserStorage) error {
+		if err := us.DeleteUserTokens(ctx, userID); err != nil {
+			return fmt.Errorf("failed to delete user tokens: %w", err)
+		}
+
+		if err := us.RevokeUserTokens(ctx, userID); err != nil {
+			return fmt.Errorf("failed to revoke user tokens: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// tokenReused revokes the family of rotated out refresh token presented again,
+// since it's unknown whether legitimate client or attacker holds the stolen token (OAuth 2.0 Security BCP).
+func (s *authService) tokenReused(ctx context.Context, claims RefreshTokenClaims, client Client) error {
+	if err := s.revokeFamily(ctx, claims.UserID, claims.family()); err != nil {
+		return err
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"userID":    claims.UserID,
+		"sessionID": claims.family(),
+		"tokenID":   claims.Id,
+		"ip":        client.IP,
+		"userAgent": client.UserAgent,
+	}).Warn("refresh token reuse detected, session revoked")
+
+	return fmt.Errorf("%w: token has been used", ErrInvalidToken)
+}
+
+// revokeFamily deletes refresh tokens of the family and revokes access tokens issued within it,
+// the family may have no refresh tokens left if it has been revoked or expired already.
+func (s *authService) revokeFamily(ctx context.Context, userID int64, familyID string) error {
+	return s.s.InTx(ctx, func(us storage.UserStorage) error {
+		if err := us.DeleteTokenFamily(ctx, userID, familyID); err != nil && !errors.Is(err, storage.ErrNotFound) {
+			return fmt.Errorf("failed to delete token family: %w", err)
+		}
+
+		if err := us.RevokeSessionTokens(ctx, userID, familyID); err != nil && !errors.Is(err, storage.ErrNotFound) {
+			return fmt.Errorf("failed to revoke session tokens: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// family returns ID of the token family, tokens issued before families were introduced form own ones.
+func (c RefreshTokenClaims) family() string {
+	if c.FamilyID == "" {
+		return c.Id
+	}
+	return c.FamilyID
+}
+
+// truncate cuts the string to n runes.
+func truncate(s string, n int) string {
+	if r := []rune(s); len(r) > n {
+		return string(r[:n])
+	}
+	return s
+}
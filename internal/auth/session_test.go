@@ -0,0 +1,259 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vliubezny/gstore/internal/model"
+	"github.com/vliubezny/gstore/internal/storage"
+)
+
+func TestService_GetSessions(t *testing.T) {
+	sessions := []model.Session{
+		{ID: testFamily, UserID: 1, IP: testIP, UserAgent: "test", CreatedAt: time.Now(), LastUsedAt: time.Now()},
+	}
+
+	testCases := []struct {
+		desc      string
+		rSessions []model.Session
+		rErr      error
+		err       error
+	}{
+		{
+			desc:      "success",
+			rSessions: sessions,
+			rErr:      nil,
+			err:       nil,
+		},
+		{
+			desc:      "get sessions - error",
+			rSessions: nil,
+			rErr:      assert.AnError,
+			err:       assert.AnError,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			st := storage.NewMockUserStorage(ctrl)
+			st.EXPECT().GetSessions(ctx, int64(1)).Return(tC.rSessions, tC.rErr)
+
+			s := New(st, NewHMACKeySet(signKey), testAppURL, VerificationOptional, MFAOptional)
+
+			res, err := s.GetSessions(ctx, 1)
+
+			assert.True(t, errors.Is(err, tC.err), fmt.Sprintf("wanted %s got %s", tC.err, err))
+			assert.Equal(t, tC.rSessions, res)
+		})
+	}
+}
+
+func TestService_RevokeSession(t *testing.T) {
+	testCases := []struct {
+		desc       string
+		rDeleteErr error
+		rRevokeErr error
+		err        error
+	}{
+		{
+			desc:       "success",
+			rDeleteErr: nil,
+			rRevokeErr: nil,
+			err:        nil,
+		},
+		{
+			desc:       "ErrNotFound",
+			rDeleteErr: storage.ErrNotFound,
+			rRevokeErr: errSkip,
+			err:        ErrNotFound,
+		},
+		{
+			desc:       "delete token family - error",
+			rDeleteErr: assert.AnError,
+			rRevokeErr: errSkip,
+			err:        assert.AnError,
+		},
+		{
+			desc:       "revoke session tokens - error",
+			rDeleteErr: nil,
+			rRevokeErr: assert.AnError,
+			err:        assert.AnError,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			st := storage.NewMockUserStorage(ctrl)
+			tx := storage.NewMockUserStorage(ctrl)
+
+			st.EXPECT().InTx(ctx, gomock.Any()).DoAndReturn(
+				func(_ context.Context, action func(s storage.UserStorage) error) error {
+					return action(tx)
+				})
+			tx.EXPECT().DeleteTokenFamily(ctx, int64(1), testFamily).Return(tC.rDeleteErr)
+
+			if tC.rRevokeErr != errSkip {
+				tx.EXPECT().RevokeSessionTokens(ctx, int64(1), testFamily).Return(tC.rRevokeErr)
+			}
+
+			s := New(st, NewHMACKeySet(signKey), testAppURL, VerificationOptional, MFAOptional)
+
+			err := s.RevokeSession(ctx, 1, testFamily)
+
+			assert.True(t, errors.Is(err, tC.err), fmt.Sprintf("wanted %s got %s", tC.err, err))
+		})
+	}
+}
+
+func TestService_RevokeSessions(t *testing.T) {
+	testCases := []struct {
+		desc       string
+		rDeleteErr error
+		rRevokeErr error
+		err        error
+	}{
+		{
+			desc:       "success",
+			rDeleteErr: nil,
+			rRevokeErr: nil,
+			err:        nil,
+		},
+		{
+			desc:       "delete tokens - error",
+			rDeleteErr: assert.AnError,
+			rRevokeErr: errSkip,
+			err:        assert.AnError,
+		},
+		{
+			desc:       "revoke tokens - error",
+			rDeleteErr: nil,
+			rRevokeErr: assert.AnError,
+			err:        assert.AnError,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			st := storage.NewMockUserStorage(ctrl)
+			tx := storage.NewMockUserStorage(ctrl)
+
+			st.EXPECT().InTx(ctx, gomock.Any()).DoAndReturn(
+				func(_ context.Context, action func(s storage.UserStorage) error) error {
+					return action(tx)
+				})
+			tx.EXPECT().DeleteUserTokens(ctx, int64(1)).Return(tC.rDeleteErr)
+
+			if tC.rRevokeErr != errSkip {
+				tx.EXPECT().RevokeUserTokens(ctx, int64(1)).Return(tC.rRevokeErr)
+			}
+
+			s := New(st, NewHMACKeySet(signKey), testAppURL, VerificationOptional, MFAOptional)
+
+			err := s.RevokeSessions(ctx, 1)
+
+			assert.True(t, errors.Is(err, tC.err), fmt.Sprintf("wanted %s got %s", tC.err, err))
+		})
+	}
+}
+
+func TestRefreshTokenClaims_family(t *testing.T) {
+	c := newRefreshClaims(model.User{ID: 1}, testFamily)
+	assert.Equal(t, testFamily, c.family())
+
+	c.FamilyID = ""
+	assert.Equal(t, c.Id, c.family(), "token issued before families must form own family")
+}
+
+func Test_truncate(t *testing.T) {
+	assert.Equal(t, "test", truncate("test", 10))
+	assert.Equal(t, "te", truncate("test", 2))
+	assert.Equal(t, "ёж", truncate("ёжик", 2))
+	assert.Len(t, []rune(truncate(strings.Repeat("a", 300), maxUserAgentLen)), maxUserAgentLen)
+}
+
+func TestService_revokedFamilyAccessTokens(t *testing.T) {
+	user := model.User{ID: 1, Email: "admin@test.com", PasswordHash: testHash, Roles: testRoles, Permissions: testPermissions}
+
+	testCases := []struct {
+		desc   string
+		expect func(st, tx *storage.MockUserStorage)
+		revoke func(s Service) error
+	}{
+		{
+			desc:   "revoke",
+			expect: func(st, tx *storage.MockUserStorage) {},
+			revoke: func(s Service) error {
+				return s.Revoke(ctx, mustCreateRefreshToken(user))
+			},
+		},
+		{
+			desc: "refresh token reuse",
+			expect: func(st, tx *storage.MockUserStorage) {
+				st.EXPECT().GetUserByID(ctx, user.ID).Return(user, nil)
+				st.EXPECT().InTx(ctx, gomock.Any()).DoAndReturn(
+					func(_ context.Context, action func(s storage.UserStorage) error) error {
+						return action(tx)
+					})
+				tx.EXPECT().UseToken(ctx, gomock.Any()).Return(storage.ErrTokenReused)
+			},
+			revoke: func(s Service) error {
+				_, err := s.Refresh(ctx, mustCreateRefreshToken(user), testClient)
+				if errors.Is(err, ErrInvalidToken) {
+					return nil
+				}
+				return err
+			},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			st := storage.NewMockUserStorage(ctrl)
+			tx := storage.NewMockUserStorage(ctrl)
+
+			tC.expect(st, tx)
+
+			var revocations []model.TokenRevocation
+			st.EXPECT().InTx(ctx, gomock.Any()).DoAndReturn(
+				func(_ context.Context, action func(s storage.UserStorage) error) error {
+					return action(tx)
+				})
+			tx.EXPECT().DeleteTokenFamily(ctx, user.ID, testFamily).Return(nil)
+			tx.EXPECT().RevokeSessionTokens(ctx, user.ID, testFamily).DoAndReturn(
+				func(_ context.Context, userID int64, sessionID string) error {
+					revocations = append(revocations, model.TokenRevocation{UserID: userID, SessionID: sessionID, IssuedBefore: time.Now()})
+					return nil
+				})
+			st.EXPECT().GetTokenRevocations(ctx, gomock.Any()).DoAndReturn(
+				func(_ context.Context, _ time.Time) ([]model.TokenRevocation, error) {
+					return revocations, nil
+				})
+
+			s := New(st, NewHMACKeySet(signKey), testAppURL, VerificationOptional, MFAOptional)
+
+			old := newAccessClaims(user)
+			old.SessionID = testFamily
+
+			require.NoError(t, tC.revoke(s))
+
+			r := NewRevocations(st, time.Second)
+			require.NoError(t, r.Refresh(ctx))
+			assert.True(t, r.IsRevoked(old), "access token of revoked family must be rejected")
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/vliubezny/gstore/internal/model"
+)
+
+// dummyHash is compared with password of unknown users,
+// so login of unknown and registered users takes the same time.
+const dummyHash = "$2a$10$6ukXfT7kC6bnUun/2uTXnuVcTF4CQeGkoDCMjL5L.m.A4IOWwt9Hy"
+
+// throttlePolicy defines how failed login attempts slow down next attempts.
+type throttlePolicy struct {
+	// free is a number of failures allowed without delay.
+	free int
+	// base is a delay after the first non-free failure, it's doubled with each next failure up to maxDelay.
+	base     time.Duration
+	maxDelay time.Duration
+	// lockout is a number of failures that locks login for lockDuration.
+	lockout      int
+	lockDuration time.Duration
+	// window is a period after the last failure when failures are forgotten.
+	window time.Duration
+}
+
+var (
+	accountPolicy = throttlePolicy{
+		free:         3,
+		base:         time.Second,
+		maxDelay:     time.Minute,
+		lockout:      10,
+		lockDuration: time.Hour,
+		window:       24 * time.Hour,
+	}
+
+	// clientPolicy is looser than account one since many users may share IP address.
+	clientPolicy = throttlePolicy{
+		free:         20,
+		base:         time.Second,
+		maxDelay:     time.Minute,
+		lockout:      100,
+		lockDuration: time.Hour,
+		window:       time.Hour,
+	}
+)
+
+// blockedUntil returns time until next login attempt is not allowed.
+func (p throttlePolicy) blockedUntil(f model.LoginFailures) time.Time {
+	switch {
+	case f.Count < p.free:
+		return time.Time{}
+	case f.Count >= p.lockout:
+		return f.LastAt.Add(p.lockDuration)
+	}
+
+	delay := p.maxDelay
+	if n := f.Count - p.free; n < 32 {
+		if d := p.base << n; d > 0 && d < delay {
+			delay = d
+		}
+	}
+
+	return f.LastAt.Add(delay)
+}
+
+func accountSubject(email string) string {
+	return "account:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func clientSubject(ip string) string {
+	return "ip:" + ip
+}
+
+// checkLoginFailures rejects login attempt if account or client is throttled.
+func (s *authService) checkLoginFailures(ctx context.Context, account, client string) error {
+	failures, err := s.s.GetLoginFailures(ctx, []string{account, client})
+	if err != nil {
+		return fmt.Errorf("failed to get login failures: %w", err)
+	}
+
+	now := time.Now()
+	for _, f := range failures {
+		p := clientPolicy
+		if f.Subject == account {
+			p = accountPolicy
+		}
+
+		if now.Before(p.blockedUntil(f)) {
+			if f.Subject == account && f.Count >= p.lockout {
+				return ErrAccountLocked
+			}
+			return ErrTooManyAttempts
+		}
+	}
+
+	return nil
+}
+
+// loginFailed records failed login attempt and returns its cause.
+func (s *authService) loginFailed(ctx context.Context, account, client string, cause error) error {
+	af, err := s.s.AddLoginFailure(ctx, account, accountPolicy.window)
+	if err != nil {
+		return fmt.Errorf("failed to add login failure: %w", err)
+	}
+
+	cf, err := s.s.AddLoginFailure(ctx, client, clientPolicy.window)
+	if err != nil {
+		return fmt.Errorf("failed to add login failure: %w", err)
+	}
+
+	if af.Count == accountPolicy.lockout {
+		logrus.WithFields(logrus.Fields{
+			"subject":     account,
+			"failures":    af.Count,
+			"lockedUntil": accountPolicy.blockedUntil(af),
+		}).Warn("account locked")
+	}
+
+	if cf.Count == clientPolicy.lockout {
+		logrus.WithFields(logrus.Fields{
+			"subject":     client,
+			"failures":    cf.Count,
+			"lockedUntil": clientPolicy.blockedUntil(cf),
+		}).Warn("client locked")
+	}
+
+	return cause
+}
+
+func (s *authService) UnlockUser(ctx context.Context, userID int64) error {
+	u, err := s.getUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if err = s.s.DeleteLoginFailures(ctx, accountSubject(u.Email)); err != nil {
+		return fmt.Errorf("failed to delete login failures: %w", err)
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"subject": accountSubject(u.Email),
+		"userID":  u.ID,
+	}).Info("account unlocked")
+
+	return nil
+}
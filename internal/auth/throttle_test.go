@@ -0,0 +1,199 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/vliubezny/gstore/internal/model"
+	"github.com/vliubezny/gstore/internal/storage"
+)
+
+func Test_throttlePolicy_blockedUntil(t *testing.T) {
+	last := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	p := throttlePolicy{free: 3, base: time.Second, maxDelay: time.Minute, lockout: 10, lockDuration: time.Hour}
+
+	testCases := []struct {
+		desc  string
+		count int
+		until time.Time
+	}{
+		{
+			desc:  "free failure",
+			count: 2,
+			until: time.Time{},
+		},
+		{
+			desc:  "first delay",
+			count: 3,
+			until: last.Add(time.Second),
+		},
+		{
+			desc:  "doubled delay",
+			count: 5,
+			until: last.Add(4 * time.Second),
+		},
+		{
+			desc:  "max delay",
+			count: 9,
+			until: last.Add(time.Minute),
+		},
+		{
+			desc:  "lockout",
+			count: 10,
+			until: last.Add(time.Hour),
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			assert.Equal(t, tC.until, p.blockedUntil(model.LoginFailures{Count: tC.count, LastAt: last}))
+		})
+	}
+}
+
+func TestService_Login_throttled(t *testing.T) {
+	account, client := "account:admin@test.com", "ip:"+testIP
+	testCases := []struct {
+		desc     string
+		failures []model.LoginFailures
+		err      error
+	}{
+		{
+			desc:     "account delay",
+			failures: []model.LoginFailures{{Subject: account, Count: accountPolicy.free, LastAt: time.Now()}},
+			err:      ErrTooManyAttempts,
+		},
+		{
+			desc:     "account lockout",
+			failures: []model.LoginFailures{{Subject: account, Count: accountPolicy.lockout, LastAt: time.Now()}},
+			err:      ErrAccountLocked,
+		},
+		{
+			desc:     "client lockout",
+			failures: []model.LoginFailures{{Subject: client, Count: clientPolicy.lockout, LastAt: time.Now()}},
+			err:      ErrTooManyAttempts,
+		},
+		{
+			desc:     "get failures - error",
+			failures: nil,
+			err:      assert.AnError,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			var rErr error
+			if tC.failures == nil {
+				rErr = tC.err
+			}
+
+			st := storage.NewMockUserStorage(ctrl)
+			st.EXPECT().GetLoginFailures(ctx, []string{account, client}).Return(tC.failures, rErr)
+
+			s := New(st, NewHMACKeySet(signKey), testAppURL, VerificationOptional, MFAOptional)
+
+			_, err := s.Login(ctx, " Admin@test.com", testPass, testClient)
+
+			assert.True(t, errors.Is(err, tC.err), fmt.Sprintf("wanted %s got %s", tC.err, err))
+		})
+	}
+}
+
+func TestService_Login_expiredFailures(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	user := model.User{ID: 1, Email: "admin@test.com", PasswordHash: testHash}
+	failures := []model.LoginFailures{
+		{Subject: "account:admin@test.com", Count: accountPolicy.lockout, LastAt: time.Now().Add(-accountPolicy.lockDuration)},
+	}
+
+	st := storage.NewMockUserStorage(ctrl)
+	st.EXPECT().GetLoginFailures(ctx, gomock.Any()).Return(failures, nil)
+	st.EXPECT().GetUserByEmail(ctx, user.Email).Return(user, nil)
+	st.EXPECT().DeleteLoginFailures(ctx, "account:admin@test.com").Return(nil)
+	st.EXPECT().SaveToken(ctx, gomock.Any()).Return(nil)
+
+	s := New(st, NewHMACKeySet(signKey), testAppURL, VerificationOptional, MFAOptional)
+
+	_, err := s.Login(ctx, user.Email, testPass, testClient)
+
+	assert.NoError(t, err)
+}
+
+func TestService_Login_unknownEmail(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	st := storage.NewMockUserStorage(ctrl)
+	st.EXPECT().GetLoginFailures(ctx, gomock.Any()).Return(nil, nil)
+	st.EXPECT().GetUserByEmail(ctx, "unknown@test.com").Return(model.User{}, storage.ErrNotFound)
+	st.EXPECT().AddLoginFailure(ctx, "account:unknown@test.com", accountPolicy.window).
+		Return(model.LoginFailures{Count: accountPolicy.lockout}, nil)
+	st.EXPECT().AddLoginFailure(ctx, "ip:"+testIP, clientPolicy.window).
+		Return(model.LoginFailures{}, assert.AnError)
+
+	s := New(st, NewHMACKeySet(signKey), testAppURL, VerificationOptional, MFAOptional)
+
+	_, err := s.Login(ctx, "unknown@test.com", testPass, testClient)
+
+	assert.True(t, errors.Is(err, assert.AnError), fmt.Sprintf("wanted %s got %s", assert.AnError, err))
+}
+
+func TestService_UnlockUser(t *testing.T) {
+	testCases := []struct {
+		desc       string
+		rUserErr   error
+		rDeleteErr error
+		err        error
+	}{
+		{
+			desc:       "success",
+			rUserErr:   nil,
+			rDeleteErr: nil,
+			err:        nil,
+		},
+		{
+			desc:       "ErrNotFound",
+			rUserErr:   storage.ErrNotFound,
+			rDeleteErr: errSkip,
+			err:        ErrNotFound,
+		},
+		{
+			desc:       "get user - error",
+			rUserErr:   assert.AnError,
+			rDeleteErr: errSkip,
+			err:        assert.AnError,
+		},
+		{
+			desc:       "delete failures - error",
+			rUserErr:   nil,
+			rDeleteErr: assert.AnError,
+			err:        assert.AnError,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			st := storage.NewMockUserStorage(ctrl)
+			st.EXPECT().GetUserByID(ctx, int64(1)).Return(model.User{ID: 1, Email: "Admin@test.com"}, tC.rUserErr)
+
+			if tC.rDeleteErr != errSkip {
+				st.EXPECT().DeleteLoginFailures(ctx, "account:admin@test.com").Return(tC.rDeleteErr)
+			}
+
+			s := New(st, NewHMACKeySet(signKey), testAppURL, VerificationOptional, MFAOptional)
+
+			err := s.UnlockUser(ctx, 1)
+
+			assert.True(t, errors.Is(err, tC.err), fmt.Sprintf("wanted %s got %s", tC.err, err))
+		})
+	}
+}
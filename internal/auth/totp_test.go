@@ -0,0 +1,102 @@
+package auth
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rfcSecret is a secret of RFC 6238 test vectors.
+var rfcSecret = totpEncoding.EncodeToString([]byte("12345678901234567890"))
+
+func Test_totpCode(t *testing.T) {
+	testCases := []struct {
+		time int64
+		code string
+	}{
+		{time: 59, code: "287082"},
+		{time: 1111111109, code: "081804"},
+		{time: 1234567890, code: "005924"},
+		{time: 2000000000, code: "279037"},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.code, func(t *testing.T) {
+			assert.Equal(t, tC.code, totpCode([]byte("12345678901234567890"), tC.time/totpPeriod))
+		})
+	}
+}
+
+func Test_validateTOTP(t *testing.T) {
+	now := time.Unix(1111111109, 0)
+	step := now.Unix() / totpPeriod
+
+	testCases := []struct {
+		desc string
+		time time.Time
+		code string
+		step int64
+		ok   bool
+	}{
+		{
+			desc: "current step",
+			time: now,
+			code: "081804",
+			step: step,
+			ok:   true,
+		},
+		{
+			desc: "previous step",
+			time: now.Add(totpPeriod * time.Second),
+			code: "081804",
+			step: step,
+			ok:   true,
+		},
+		{
+			desc: "expired",
+			time: now.Add(2 * totpPeriod * time.Second),
+			code: "081804",
+			ok:   false,
+		},
+		{
+			desc: "invalid code",
+			time: now,
+			code: "123456",
+			ok:   false,
+		},
+		{
+			desc: "malformed code",
+			time: now,
+			code: "0818",
+			ok:   false,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			s, ok := validateTOTP(rfcSecret, tC.code, tC.time)
+			assert.Equal(t, tC.ok, ok)
+			assert.Equal(t, tC.step, s)
+		})
+	}
+}
+
+func Test_totpURI(t *testing.T) {
+	u, err := url.Parse(totpURI("admin@test.com", "SECRET"))
+	require.NoError(t, err)
+
+	assert.Equal(t, "otpauth", u.Scheme)
+	assert.Equal(t, "totp", u.Host)
+	assert.Equal(t, "/gstore:admin@test.com", u.Path)
+	assert.Equal(t, "SECRET", u.Query().Get("secret"))
+	assert.Equal(t, "gstore", u.Query().Get("issuer"))
+}
+
+func Test_hashRecoveryCode(t *testing.T) {
+	code, err := newRecoveryCode()
+	require.NoError(t, err)
+	assert.Len(t, code, 11)
+
+	assert.Equal(t, hashRecoveryCode(code), hashRecoveryCode(" "+code[:5]+code[6:]+" "))
+}
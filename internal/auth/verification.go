@@ -0,0 +1,122 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/google/uuid"
+
+	"github.com/vliubezny/gstore/internal/model"
+	"github.com/vliubezny/gstore/internal/storage"
+)
+
+const (
+	verificationTokenTTL = 24 * time.Hour
+	// resendInterval is a minimal interval between verification emails.
+	resendInterval = time.Minute
+)
+
+// verificationClaims specifies the claims for email verification token.
+type verificationClaims struct {
+	TokenType string `json:"type,omitempty"`
+	UserID    int64  `json:"userId,omitempty"`
+	// Email binds token to the address it was sent to.
+	Email string `json:"email,omitempty"`
+	jwt.StandardClaims
+}
+
+func (s *authService) VerifyEmail(ctx context.Context, token string) error {
+	claims, err := validateVerificationToken(token, s.keys)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	if err = s.s.VerifyUserEmail(ctx, claims.UserID, claims.Email); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return fmt.Errorf("%w: email has been changed", ErrInvalidToken)
+		}
+		return fmt.Errorf("failed to verify email: %w", err)
+	}
+
+	return nil
+}
+
+func (s *authService) ResendVerification(ctx context.Context, email string) error {
+	u, err := s.s.GetUserByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) { // don't reveal whether email is registered
+			return nil
+		}
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	if u.EmailVerified {
+		return nil
+	}
+
+	return s.s.InTx(ctx, func(us storage.UserStorage) error {
+		if err := us.MarkVerificationSent(ctx, u.ID, resendInterval); err != nil {
+			if errors.Is(err, storage.ErrRateLimited) {
+				return ErrRateLimited
+			}
+			return fmt.Errorf("failed to mark verification sent: %w", err)
+		}
+
+		return s.enqueueVerification(ctx, us, u)
+	})
+}
+
+// enqueueVerification enqueues email with verification link for the user.
+func (s *authService) enqueueVerification(ctx context.Context, us storage.UserStorage, u model.User) error {
+	token, err := s.signToken(newVerificationClaims(u))
+	if err != nil {
+		return fmt.Errorf("failed to sign verification token: %w", err)
+	}
+
+	if err := us.EnqueueEmail(ctx, model.Email{
+		To:      u.Email,
+		Subject: "Verify your email address",
+		Body: "Follow the link to verify your email address:\n" +
+			s.appURL + "/verify-email?token=" + url.QueryEscape(token) + "\n\n" +
+			"The link expires in 24 hours.",
+	}); err != nil {
+		return fmt.Errorf("failed to enqueue email: %w", err)
+	}
+
+	return nil
+}
+
+func newVerificationClaims(user model.User) verificationClaims {
+	return verificationClaims{
+		TokenType: typeVerification,
+		UserID:    user.ID,
+		Email:     user.Email,
+		StandardClaims: jwt.StandardClaims{
+			Id:        uuid.NewString(),
+			Issuer:    issuer,
+			IssuedAt:  time.Now().Unix(),
+			ExpiresAt: time.Now().Add(verificationTokenTTL).Unix(),
+		},
+	}
+}
+
+func validateVerificationToken(token string, keys *KeySet) (verificationClaims, error) {
+	vt, err := jwt.ParseWithClaims(token, &verificationClaims{}, keys.keyFunc)
+
+	if err != nil {
+		return verificationClaims{}, fmt.Errorf("unable to parse claims: %w", err)
+	}
+
+	claims, ok := vt.Claims.(*verificationClaims)
+	if !ok || !vt.Valid {
+		return verificationClaims{}, errors.New("invalid token")
+	}
+	if claims.TokenType != typeVerification {
+		return verificationClaims{}, fmt.Errorf("invalid verification token: type %s", claims.TokenType)
+	}
+	return *claims, nil
+}
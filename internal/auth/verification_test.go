@@ -0,0 +1,259 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vliubezny/gstore/internal/model"
+	"github.com/vliubezny/gstore/internal/storage"
+)
+
+func mustCreateVerificationToken(u model.User) string {
+	c := newVerificationClaims(u)
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, c).SignedString([]byte(signKey))
+	if err != nil {
+		panic(err)
+	}
+	return token
+}
+
+func TestService_VerifyEmail(t *testing.T) {
+	user := model.User{ID: 1, Email: "admin@test.com"}
+	testCases := []struct {
+		desc  string
+		token string
+		rErr  error
+		err   error
+	}{
+		{
+			desc:  "success",
+			token: mustCreateVerificationToken(user),
+			rErr:  nil,
+			err:   nil,
+		},
+		{
+			desc:  "malformed token - ErrInvalidToken",
+			token: "test",
+			rErr:  errSkip,
+			err:   ErrInvalidToken,
+		},
+		{
+			desc:  "refresh token - ErrInvalidToken",
+			token: mustCreateRefreshToken(user),
+			rErr:  errSkip,
+			err:   ErrInvalidToken,
+		},
+		{
+			desc:  "email changed - ErrInvalidToken",
+			token: mustCreateVerificationToken(user),
+			rErr:  storage.ErrNotFound,
+			err:   ErrInvalidToken,
+		},
+		{
+			desc:  "unexpected error",
+			token: mustCreateVerificationToken(user),
+			rErr:  assert.AnError,
+			err:   assert.AnError,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			st := storage.NewMockUserStorage(ctrl)
+			if tC.rErr != errSkip {
+				st.EXPECT().VerifyUserEmail(ctx, user.ID, user.Email).Return(tC.rErr)
+			}
+
+			s := New(st, NewHMACKeySet(signKey), testAppURL, VerificationOptional, MFAOptional)
+
+			err := s.VerifyEmail(ctx, tC.token)
+
+			assert.True(t, errors.Is(err, tC.err), fmt.Sprintf("wanted %s got %s", tC.err, err))
+		})
+	}
+}
+
+func TestService_ResendVerification(t *testing.T) {
+	user := model.User{ID: 1, Email: "admin@test.com"}
+	testCases := []struct {
+		desc      string
+		rUser     model.User
+		rUserErr  error
+		rMarkErr  error
+		rEmailErr error
+		err       error
+	}{
+		{
+			desc:      "success",
+			rUser:     user,
+			rUserErr:  nil,
+			rMarkErr:  nil,
+			rEmailErr: nil,
+			err:       nil,
+		},
+		{
+			desc:      "unknown email - silent success",
+			rUser:     model.User{},
+			rUserErr:  storage.ErrNotFound,
+			rMarkErr:  errSkip,
+			rEmailErr: errSkip,
+			err:       nil,
+		},
+		{
+			desc:      "already verified - silent success",
+			rUser:     model.User{ID: 1, Email: "admin@test.com", EmailVerified: true},
+			rUserErr:  nil,
+			rMarkErr:  errSkip,
+			rEmailErr: errSkip,
+			err:       nil,
+		},
+		{
+			desc:      "get user - error",
+			rUser:     model.User{},
+			rUserErr:  assert.AnError,
+			rMarkErr:  errSkip,
+			rEmailErr: errSkip,
+			err:       assert.AnError,
+		},
+		{
+			desc:      "ErrRateLimited",
+			rUser:     user,
+			rUserErr:  nil,
+			rMarkErr:  storage.ErrRateLimited,
+			rEmailErr: errSkip,
+			err:       ErrRateLimited,
+		},
+		{
+			desc:      "mark sent - error",
+			rUser:     user,
+			rUserErr:  nil,
+			rMarkErr:  assert.AnError,
+			rEmailErr: errSkip,
+			err:       assert.AnError,
+		},
+		{
+			desc:      "enqueue email - error",
+			rUser:     user,
+			rUserErr:  nil,
+			rMarkErr:  nil,
+			rEmailErr: assert.AnError,
+			err:       assert.AnError,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			st := storage.NewMockUserStorage(ctrl)
+			tx := storage.NewMockUserStorage(ctrl)
+
+			st.EXPECT().GetUserByEmail(ctx, user.Email).Return(tC.rUser, tC.rUserErr)
+
+			if tC.rMarkErr != errSkip {
+				st.EXPECT().InTx(ctx, gomock.Any()).DoAndReturn(
+					func(_ context.Context, action func(s storage.UserStorage) error) error {
+						return action(tx)
+					})
+				tx.EXPECT().MarkVerificationSent(ctx, user.ID, resendInterval).Return(tC.rMarkErr)
+			}
+
+			if tC.rEmailErr != errSkip {
+				tx.EXPECT().EnqueueEmail(ctx, gomock.AssignableToTypeOf(model.Email{})).
+					DoAndReturn(func(_ context.Context, e model.Email) error {
+						assert.Equal(t, user.Email, e.To)
+
+						link := testAppURL + "/verify-email?token="
+						i := strings.Index(e.Body, link)
+						if assert.True(t, i >= 0, "body must contain verification link") {
+							token, err := url.QueryUnescape(strings.Fields(e.Body[i+len(link):])[0])
+							require.NoError(t, err)
+
+							claims, err := validateVerificationToken(token, NewHMACKeySet(signKey))
+							require.NoError(t, err)
+							assert.Equal(t, user.ID, claims.UserID)
+							assert.Equal(t, user.Email, claims.Email)
+						}
+						return tC.rEmailErr
+					})
+			}
+
+			s := New(st, NewHMACKeySet(signKey), testAppURL, VerificationOptional, MFAOptional)
+
+			err := s.ResendVerification(ctx, user.Email)
+
+			assert.True(t, errors.Is(err, tC.err), fmt.Sprintf("wanted %s got %s", tC.err, err))
+		})
+	}
+}
+
+func TestService_Login_verificationPolicy(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		policy   VerificationPolicy
+		verified bool
+		err      error
+	}{
+		{
+			desc:     "optional - unverified",
+			policy:   VerificationOptional,
+			verified: false,
+			err:      nil,
+		},
+		{
+			desc:     "before write - unverified",
+			policy:   VerificationBeforeWrite,
+			verified: false,
+			err:      nil,
+		},
+		{
+			desc:     "before login - verified",
+			policy:   VerificationBeforeLogin,
+			verified: true,
+			err:      nil,
+		},
+		{
+			desc:     "before login - unverified",
+			policy:   VerificationBeforeLogin,
+			verified: false,
+			err:      ErrEmailNotVerified,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			user := model.User{ID: 1, Email: "admin@test.com", PasswordHash: testHash, EmailVerified: tC.verified}
+
+			st := storage.NewMockUserStorage(ctrl)
+			st.EXPECT().GetLoginFailures(ctx, gomock.Any()).Return(nil, nil)
+			st.EXPECT().GetUserByEmail(ctx, user.Email).Return(user, nil)
+			st.EXPECT().DeleteLoginFailures(ctx, gomock.Any()).Return(nil)
+			if tC.err == nil {
+				st.EXPECT().SaveToken(ctx, gomock.Any()).Return(nil)
+			}
+
+			s := New(st, NewHMACKeySet(signKey), testAppURL, tC.policy, MFAOptional)
+
+			pair, err := s.Login(ctx, user.Email, testPass, testClient)
+
+			assert.True(t, errors.Is(err, tC.err), fmt.Sprintf("wanted %s got %s", tC.err, err))
+			if err == nil {
+				claims, err := s.ValidateAccessToken(pair.AccessToken)
+				require.NoError(t, err)
+				assert.Equal(t, tC.verified, claims.EmailVerified)
+			}
+		})
+	}
+}
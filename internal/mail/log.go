@@ -0,0 +1,28 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+)
+
+type logMailer struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewLogMailer creates mailer that writes emails to w instead of sending them, intended for local runs.
+func NewLogMailer(w io.Writer) Mailer {
+	return &logMailer{w: w}
+}
+
+func (m *logMailer) Send(ctx context.Context, msg Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, err := fmt.Fprintf(m.w, "To: %s\nSubject: %s\n\n%s\n\n", msg.To, msg.Subject, msg.Body); err != nil {
+		return fmt.Errorf("failed to write email: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,20 @@
+package mail
+
+import (
+	"context"
+)
+
+//go:generate mockgen -destination=./mail_mock.go -package=mail -source=mail.go
+
+// Message represents plain text email.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer provides methods to deliver emails.
+type Mailer interface {
+	// Send delivers message to its recipient.
+	Send(ctx context.Context, msg Message) error
+}
@@ -0,0 +1,33 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testMessage = Message{To: "user@test.com", Subject: "Password reset", Body: "line1\nline2"}
+
+func Test_formatMessage(t *testing.T) {
+	msg := formatMessage("noreply@test.com", testMessage)
+
+	assert.Equal(t, "From: noreply@test.com\r\n"+
+		"To: user@test.com\r\n"+
+		"Subject: Password reset\r\n"+
+		"MIME-Version: 1.0\r\n"+
+		"Content-Type: text/plain; charset=utf-8\r\n"+
+		"\r\n"+
+		"line1\r\nline2", string(msg))
+}
+
+func TestLogMailer_Send(t *testing.T) {
+	var b bytes.Buffer
+	m := NewLogMailer(&b)
+
+	require.NoError(t, m.Send(context.Background(), testMessage))
+
+	assert.Equal(t, "To: user@test.com\nSubject: Password reset\n\nline1\nline2\n\n", b.String())
+}
@@ -0,0 +1,89 @@
+package mail
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/vliubezny/gstore/internal/storage"
+)
+
+const (
+	batchSize = 10
+	// lease is a time given to deliver claimed emails before other workers may pick them up.
+	lease       = 5 * time.Minute
+	maxAttempts = 5
+)
+
+// Worker delivers emails from outbox.
+type Worker struct {
+	s        storage.OutboxStorage
+	m        Mailer
+	interval time.Duration
+}
+
+// NewWorker creates outbox worker which checks for due emails every interval.
+func NewWorker(s storage.OutboxStorage, m Mailer, interval time.Duration) *Worker {
+	return &Worker{
+		s:        s,
+		m:        m,
+		interval: interval,
+	}
+}
+
+// Run delivers emails until context is cancelled.
+func (w *Worker) Run(ctx context.Context) {
+	t := time.NewTicker(w.interval)
+	defer t.Stop()
+
+	for {
+		for w.deliver(ctx) == batchSize {
+			// keep going while outbox is full
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// deliver sends a batch of due emails and returns number of claimed ones.
+func (w *Worker) deliver(ctx context.Context) int {
+	emails, err := w.s.ClaimEmails(ctx, batchSize, lease)
+	if err != nil {
+		logrus.WithError(err).Error("failed to claim emails")
+		return 0
+	}
+
+	for _, e := range emails {
+		l := logrus.WithField("emailID", e.ID)
+
+		err := w.m.Send(ctx, Message{To: e.To, Subject: e.Subject, Body: e.Body})
+		if err == nil {
+			if err := w.s.MarkEmailSent(ctx, e.ID); err != nil {
+				l.WithError(err).Error("failed to mark email sent")
+			}
+			continue
+		}
+
+		l.WithError(err).Warn("failed to send email")
+
+		var retryAt time.Time
+		if e.Attempts+1 < maxAttempts {
+			retryAt = time.Now().Add(backoff(e.Attempts + 1))
+		}
+
+		if err := w.s.MarkEmailFailed(ctx, e.ID, err.Error(), retryAt); err != nil {
+			l.WithError(err).Error("failed to mark email failed")
+		}
+	}
+
+	return len(emails)
+}
+
+// backoff returns delay before the next delivery attempt.
+func backoff(attempts int) time.Duration {
+	return time.Duration(attempts*attempts) * time.Minute
+}
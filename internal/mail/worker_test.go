@@ -0,0 +1,81 @@
+package mail
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/vliubezny/gstore/internal/model"
+	"github.com/vliubezny/gstore/internal/storage"
+)
+
+var ctx = context.Background()
+
+func TestWorker_deliver(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		attempts int
+		sErr     error
+		retry    bool
+	}{
+		{
+			desc:     "sent",
+			attempts: 0,
+			sErr:     nil,
+		},
+		{
+			desc:     "failed - retry",
+			attempts: 0,
+			sErr:     assert.AnError,
+			retry:    true,
+		},
+		{
+			desc:     "failed - give up",
+			attempts: maxAttempts - 1,
+			sErr:     assert.AnError,
+			retry:    false,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			email := model.Email{ID: 1, To: "user@test.com", Subject: "subject", Body: "body", Attempts: tC.attempts}
+
+			st := storage.NewMockOutboxStorage(ctrl)
+			st.EXPECT().ClaimEmails(ctx, batchSize, lease).Return([]model.Email{email}, nil)
+
+			m := NewMockMailer(ctrl)
+			m.EXPECT().Send(ctx, Message{To: "user@test.com", Subject: "subject", Body: "body"}).Return(tC.sErr)
+
+			if tC.sErr == nil {
+				st.EXPECT().MarkEmailSent(ctx, int64(1)).Return(nil)
+			} else {
+				st.EXPECT().MarkEmailFailed(ctx, int64(1), tC.sErr.Error(), gomock.AssignableToTypeOf(time.Time{})).
+					DoAndReturn(func(_ context.Context, _ int64, _ string, retryAt time.Time) error {
+						assert.Equal(t, tC.retry, !retryAt.IsZero())
+						return nil
+					})
+			}
+
+			w := NewWorker(st, m, time.Second)
+
+			assert.Equal(t, 1, w.deliver(ctx))
+		})
+	}
+}
+
+func TestWorker_deliver_claimError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	st := storage.NewMockOutboxStorage(ctrl)
+	st.EXPECT().ClaimEmails(ctx, batchSize, lease).Return(nil, assert.AnError)
+
+	w := NewWorker(st, NewMockMailer(ctrl), time.Second)
+
+	assert.Equal(t, 0, w.deliver(ctx))
+}
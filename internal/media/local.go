@@ -0,0 +1,119 @@
+package media
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+type localStorage struct {
+	dir     string
+	baseURL string
+}
+
+// NewLocalStorage creates storage that keeps files in the local directory,
+// files are expected to be served under baseURL.
+func NewLocalStorage(dir, baseURL string) Storage {
+	return localStorage{
+		dir:     dir,
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+	}
+}
+
+func (s localStorage) Save(ctx context.Context, key, contentType string, r io.Reader) error {
+	name, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	// write to temporary file first so readers never see partial content
+	f, err := ioutil.TempFile(filepath.Dir(name), ".upload-*")
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+
+	if err := os.Chmod(f.Name(), 0644); err != nil {
+		return fmt.Errorf("failed to change file mode: %w", err)
+	}
+
+	if err := os.Rename(f.Name(), name); err != nil {
+		return fmt.Errorf("failed to save file: %w", err)
+	}
+
+	return nil
+}
+
+func (s localStorage) Delete(ctx context.Context, key string) error {
+	name, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(name); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete file: %w", err)
+	}
+
+	return nil
+}
+
+func (s localStorage) URL(key string) string {
+	return s.baseURL + "/" + key
+}
+
+// path converts key into file path inside storage directory.
+func (s localStorage) path(key string) (string, error) {
+	if key == "" || path.Clean("/"+key) != "/"+key {
+		return "", fmt.Errorf("%w: %q", ErrInvalidKey, key)
+	}
+	return filepath.Join(s.dir, filepath.FromSlash(key)), nil
+}
+
+// NewFileSystem creates file system to serve files of the local storage directory,
+// directories are reported as not existing, so their listings are never served.
+func NewFileSystem(dir string) http.FileSystem {
+	return filesOnly{fs: http.Dir(dir)}
+}
+
+type filesOnly struct {
+	fs http.FileSystem
+}
+
+func (f filesOnly) Open(name string) (http.File, error) {
+	file, err := f.fs.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	if info.IsDir() {
+		file.Close()
+		return nil, os.ErrNotExist
+	}
+
+	return file, nil
+}
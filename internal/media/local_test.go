@@ -0,0 +1,84 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalStorage(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	s := NewLocalStorage(dir, "http://localhost/media/")
+
+	err := s.Save(ctx, "products/1/image.png", "image/png", strings.NewReader("content"))
+	require.NoError(t, err)
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, "products", "1", "image.png"))
+	require.NoError(t, err)
+	assert.Equal(t, "content", string(data))
+
+	assert.Equal(t, "http://localhost/media/products/1/image.png", s.URL("products/1/image.png"))
+
+	err = s.Delete(ctx, "products/1/image.png")
+	require.NoError(t, err)
+
+	_, err = os.Stat(filepath.Join(dir, "products", "1", "image.png"))
+	assert.True(t, os.IsNotExist(err))
+
+	err = s.Delete(ctx, "products/1/image.png")
+	assert.NoError(t, err)
+}
+
+func TestLocalStorage_ErrInvalidKey(t *testing.T) {
+	ctx := context.Background()
+	s := NewLocalStorage(t.TempDir(), "/media")
+
+	for _, key := range []string{"", "../image.png", "/image.png", "products/../../image.png", "products//image.png"} {
+		err := s.Save(ctx, key, "image/png", strings.NewReader("content"))
+		assert.True(t, errors.Is(err, ErrInvalidKey), "key %q: got %v", key, err)
+
+		err = s.Delete(ctx, key)
+		assert.True(t, errors.Is(err, ErrInvalidKey), "key %q: got %v", key, err)
+	}
+}
+
+func TestFileSystem(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "products", "1"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "products", "1", "image.png"), []byte("content"), 0644))
+
+	h := http.FileServer(NewFileSystem(dir))
+
+	testCases := []struct {
+		desc  string
+		path  string
+		rcode int
+	}{
+		{desc: "file", path: "/products/1/image.png", rcode: http.StatusOK},
+		{desc: "root", path: "/", rcode: http.StatusNotFound},
+		{desc: "directory", path: "/products/", rcode: http.StatusNotFound},
+		{desc: "directory without slash", path: "/products/1", rcode: http.StatusNotFound},
+		{desc: "missing file", path: "/products/2/image.png", rcode: http.StatusNotFound},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tC.path, nil))
+
+			assert.Equal(t, tC.rcode, rec.Code)
+			if tC.rcode == http.StatusOK {
+				assert.Equal(t, "content", rec.Body.String())
+			}
+		})
+	}
+}
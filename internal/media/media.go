@@ -0,0 +1,24 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+//go:generate mockgen -destination=./media_mock.go -package=media -source=media.go
+
+// ErrInvalidKey states that file key is malformed.
+var ErrInvalidKey = errors.New("invalid key")
+
+// Storage provides methods to store public media files.
+type Storage interface {
+	// Save saves file content under the key, existing file is overwritten.
+	Save(ctx context.Context, key, contentType string, r io.Reader) error
+
+	// Delete deletes file by key, missing file is not an error.
+	Delete(ctx context.Context, key string) error
+
+	// URL returns public URL of the file.
+	URL(key string) string
+}
@@ -0,0 +1,68 @@
+package media
+
+import (
+	"image"
+	"image/color"
+)
+
+// ThumbnailSize specifies thumbnail bounding box.
+type ThumbnailSize struct {
+	Name string
+	// MaxSide is a maximal width and height of thumbnail in pixels.
+	MaxSide int
+}
+
+// ThumbnailSizes is a list of generated image thumbnails.
+var ThumbnailSizes = []ThumbnailSize{
+	{Name: "small", MaxSide: 160},
+	{Name: "medium", MaxSide: 480},
+	{Name: "large", MaxSide: 1200},
+}
+
+// Thumbnail scales image down to fit into maxSide x maxSide box preserving aspect ratio,
+// image that already fits is returned as is.
+func Thumbnail(img image.Image, maxSide int) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w <= maxSide && h <= maxSide {
+		return img
+	}
+
+	tw, th := maxSide, h*maxSide/w
+	if h > w {
+		tw, th = w*maxSide/h, maxSide
+	}
+	if tw < 1 {
+		tw = 1
+	}
+	if th < 1 {
+		th = 1
+	}
+
+	// every thumbnail pixel is an average of the source pixels it covers
+	dst := image.NewRGBA64(image.Rect(0, 0, tw, th))
+	for y := 0; y < th; y++ {
+		sy0, sy1 := b.Min.Y+y*h/th, b.Min.Y+(y+1)*h/th
+		for x := 0; x < tw; x++ {
+			sx0, sx1 := b.Min.X+x*w/tw, b.Min.X+(x+1)*w/tw
+
+			var r, g, bl, a, n uint64
+			for sy := sy0; sy < sy1; sy++ {
+				for sx := sx0; sx < sx1; sx++ {
+					cr, cg, cb, ca := img.At(sx, sy).RGBA()
+					r, g, bl, a = r+uint64(cr), g+uint64(cg), bl+uint64(cb), a+uint64(ca)
+					n++
+				}
+			}
+
+			dst.SetRGBA64(x, y, color.RGBA64{
+				R: uint16(r / n),
+				G: uint16(g / n),
+				B: uint16(bl / n),
+				A: uint16(a / n),
+			})
+		}
+	}
+
+	return dst
+}
@@ -0,0 +1,43 @@
+package media
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestThumbnail(t *testing.T) {
+	testCases := []struct {
+		desc   string
+		width  int
+		height int
+		side   int
+		rw     int
+		rh     int
+	}{
+		{desc: "landscape", width: 400, height: 200, side: 100, rw: 100, rh: 50},
+		{desc: "portrait", width: 200, height: 400, side: 100, rw: 50, rh: 100},
+		{desc: "square", width: 300, height: 300, side: 100, rw: 100, rh: 100},
+		{desc: "thin", width: 1000, height: 2, side: 100, rw: 100, rh: 1},
+		{desc: "small", width: 80, height: 40, side: 100, rw: 80, rh: 40},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			img := image.NewRGBA(image.Rect(0, 0, tC.width, tC.height))
+			for y := 0; y < tC.height; y++ {
+				for x := 0; x < tC.width; x++ {
+					img.Set(x, y, color.RGBA{R: 200, G: 100, B: 50, A: 255})
+				}
+			}
+
+			th := Thumbnail(img, tC.side)
+
+			assert.Equal(t, image.Rect(0, 0, tC.rw, tC.rh), th.Bounds())
+
+			r, g, b, a := th.At(0, 0).RGBA()
+			assert.Equal(t, []uint32{200, 100, 50, 255}, []uint32{r >> 8, g >> 8, b >> 8, a >> 8})
+		})
+	}
+}
@@ -1,11 +1,75 @@
 package model
 
+import "time"
+
+// Permission grants access to a group of operations.
+type Permission string
+
+// Permissions.
+const (
+	PermissionUserWrite         Permission = "user:write"
+	PermissionCategoryWrite     Permission = "category:write"
+	PermissionProductWrite      Permission = "product:write"
+	PermissionStoreWrite        Permission = "store:write"
+	PermissionPositionWrite     Permission = "position:write"
+	PermissionPriceHistoryRead  Permission = "price_history:read"
+	PermissionExchangeRateWrite Permission = "exchange_rate:write"
+	// PermissionOrderRead allows to see orders of all users.
+	PermissionOrderRead  Permission = "order:read"
+	PermissionOrderWrite Permission = "order:write"
+)
+
+// AllPermissions lists known permissions.
+var AllPermissions = []Permission{
+	PermissionUserWrite,
+	PermissionCategoryWrite,
+	PermissionProductWrite,
+	PermissionStoreWrite,
+	PermissionPositionWrite,
+	PermissionPriceHistoryRead,
+	PermissionExchangeRateWrite,
+	PermissionOrderRead,
+	PermissionOrderWrite,
+}
+
+// Scopes of delegated access to own cart and orders of the user,
+// they aren't granted by roles since every user manages own cart and orders.
+const (
+	ScopeCart     Permission = "cart"
+	ScopeOrders   Permission = "orders"
+	ScopePayments Permission = "payments"
+)
+
+// AllScopes lists known scopes of API keys and OAuth clients, permissions are scopes as well.
+var AllScopes = append(append([]Permission{}, AllPermissions...), ScopeCart, ScopeOrders, ScopePayments)
+
+// Built-in roles.
+const (
+	RoleSuperadmin    = "superadmin"
+	RoleCatalogEditor = "catalog-editor"
+	RoleStoreManager  = "store-manager"
+	RoleSupport       = "support"
+)
+
+// Role represents named set of permissions.
+type Role struct {
+	Name        string
+	Permissions []Permission
+}
+
 // User represents authenticated person.
 type User struct {
 	ID           int64
 	Email        string
 	PasswordHash string
-	IsAdmin      bool
+	// EmailVerified states that user confirmed ownership of email address.
+	EmailVerified bool
+	// MFASecret is a TOTP secret, it's set on enrollment and becomes effective when MFAEnabled.
+	MFASecret  string
+	MFAEnabled bool
+	Roles      []string
+	// Permissions are granted by user roles.
+	Permissions []Permission
 }
 
 // TokenPair groups access and refresh tokens.
@@ -13,3 +77,96 @@ type TokenPair struct {
 	AccessToken  string
 	RefreshToken string
 }
+
+// RefreshToken is a reference to issued refresh token.
+type RefreshToken struct {
+	ID string
+	// FamilyID groups tokens rotated from one login, it identifies user session.
+	FamilyID  string
+	UserID    int64
+	ExpiresAt time.Time
+	// IP and UserAgent describe the client the token is issued to.
+	IP        string
+	UserAgent string
+}
+
+// Session represents login of a user on a client, it lasts while its refresh tokens are rotated.
+type Session struct {
+	ID        string
+	UserID    int64
+	IP        string
+	UserAgent string
+	CreatedAt time.Time
+	// LastUsedAt is a time of the last refresh.
+	LastUsedAt time.Time
+	ExpiresAt  time.Time
+}
+
+// TokenRevocation invalidates access tokens of the user issued before the time,
+// only tokens of the session are invalidated if SessionID is set.
+type TokenRevocation struct {
+	UserID       int64
+	SessionID    string
+	IssuedBefore time.Time
+}
+
+// APIKey is a personal key of machine client acting on behalf of the user.
+type APIKey struct {
+	ID     string
+	UserID int64
+	Name   string
+	// Prefix is a beginning of the key to tell keys apart, the key itself is known only to its owner.
+	Prefix  string
+	KeyHash string
+	// Scopes limit permissions of the user available with the key.
+	Scopes    []Permission
+	CreatedAt time.Time
+	// ExpiresAt is zero if the key never expires.
+	ExpiresAt time.Time
+	// LastUsedAt is zero if the key has never been used.
+	LastUsedAt time.Time
+}
+
+// OAuthClient is a third-party application registered by the user to access gstore with OAuth 2.0.
+type OAuthClient struct {
+	ID string
+	// UserID is an owner of the client, client credentials grant acts on behalf of the owner.
+	UserID int64
+	Name   string
+	// SecretHash is empty for public clients that can't keep secret.
+	SecretHash   string
+	RedirectURIs []string
+	// Scopes are permissions the client may request.
+	Scopes    []Permission
+	CreatedAt time.Time
+}
+
+// AuthorizationCode is issued to OAuth client authorized by the user to be exchanged for tokens.
+type AuthorizationCode struct {
+	CodeHash    string
+	ClientID    string
+	UserID      int64
+	RedirectURI string
+	Scopes      []Permission
+	// CodeChallenge is S256 PKCE challenge (RFC 7636) the code verifier is checked against.
+	CodeChallenge string
+	ExpiresAt     time.Time
+}
+
+// Email represents email queued for delivery.
+type Email struct {
+	ID      int64
+	To      string
+	Subject string
+	Body    string
+	// Attempts is a number of failed delivery attempts.
+	Attempts int
+}
+
+// LoginFailures represents recent failed login attempts of account or client.
+type LoginFailures struct {
+	// Subject identifies account or client.
+	Subject string
+	Count   int
+	LastAt  time.Time
+}
@@ -1,11 +1,48 @@
 package model
 
-import "github.com/shopspring/decimal"
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
 
 // Category represents product cetegory.
 type Category struct {
 	ID   int64
 	Name string
+	// ParentID is an ID of parent category, 0 means top-level category.
+	ParentID int64
+	// Schema defines attributes of products in the category.
+	Schema []Attribute
+}
+
+// AttributeType specifies type of product attribute value.
+type AttributeType string
+
+// Product attribute types.
+const (
+	AttributeString  AttributeType = "string"
+	AttributeNumber  AttributeType = "number"
+	AttributeEnum    AttributeType = "enum"
+	AttributeBoolean AttributeType = "boolean"
+)
+
+// Attribute describes product attribute in category schema.
+type Attribute struct {
+	Name string
+	Type AttributeType
+	// Unit is a measurement unit of number attribute, e.g. "GB".
+	Unit string
+	// Values is a list of allowed values of enum attribute.
+	Values []string
+	// Required states that every product in category must have the attribute.
+	Required bool
+}
+
+// CategoryNode represents category with its subcategories.
+type CategoryNode struct {
+	Category
+	Children []CategoryNode
 }
 
 // Store represents product store.
@@ -14,17 +51,362 @@ type Store struct {
 	Name string
 }
 
+// StoreRole specifies role of a member in store.
+type StoreRole string
+
+// Store roles.
+const (
+	// StoreOwner manages store, its positions and members.
+	StoreOwner StoreRole = "owner"
+	// StoreEditor manages store positions.
+	StoreEditor StoreRole = "editor"
+)
+
+// StoreMember represents user who manages store.
+type StoreMember struct {
+	StoreID int64
+	UserID  int64
+	Email   string
+	Role    StoreRole
+}
+
 // Product represents product item.
 type Product struct {
 	ID          int64
 	CategoryID  int64
 	Name        string
 	Description string
+	// Attributes contains product specifications keyed by attribute name,
+	// values are strings, float64 numbers or booleans according to category schema.
+	Attributes map[string]interface{}
+	// Images contains product images ordered by position.
+	Images []Image
+	// Variants contains product SKUs ordered by ID.
+	Variants []Variant
+	// Offers contains price comparison of product positions across stores.
+	Offers PriceSummary
+}
+
+// PriceSummary represents comparison of product prices across stores,
+// discontinued positions are not counted as offers.
+type PriceSummary struct {
+	ProductID int64
+	// OfferCount is a number of positions, prices are zero if there are no offers.
+	OfferCount int64
+	// Currency is an ISO 4217 code of prices currency.
+	Currency    string
+	MinPrice    decimal.Decimal
+	MaxPrice    decimal.Decimal
+	MedianPrice decimal.Decimal
+	// CheapestStoreID is an ID of the store offering the lowest price,
+	// the lowest store ID wins on ties.
+	CheapestStoreID int64
+}
+
+// Variant represents product SKU, e.g. particular color or capacity of a product.
+type Variant struct {
+	ID        int64
+	ProductID int64
+	// SKU is a unique stock keeping unit code.
+	SKU string
+	// GTIN is an optional global trade item number (EAN, UPC), empty value means no GTIN.
+	GTIN string
+	// Attributes contains variant-specific attributes defined in category schema.
+	Attributes map[string]interface{}
+}
+
+// VariantOption represents dimension of product variant matrix.
+type VariantOption struct {
+	// Name is a name of variant attribute.
+	Name string
+	// Values contains distinct attribute values in ascending order.
+	Values []interface{}
+}
+
+// VariantMatrix represents product variants along with their dimensions.
+type VariantMatrix struct {
+	// Options contains variant attributes ordered by name.
+	Options  []VariantOption
+	Variants []Variant
+}
+
+// Image represents product image.
+type Image struct {
+	ID        int64
+	ProductID int64
+	// Key is a media storage key of the original image.
+	Key         string
+	ContentType string
+	Width       int
+	Height      int
+	// Position defines order of product images.
+	Position int
+	// Primary states that the image goes first among product images.
+	Primary bool
+	// URL is a public URL of the original image.
+	URL string
+	// Thumbnails contains public URLs of image thumbnails keyed by size name.
+	Thumbnails map[string]string
 }
 
 // Position represents store prosition.
 type Position struct {
 	ProductID int64
+	VariantID int64
+	StoreID   int64
+	Price     decimal.Decimal
+	// Currency is an ISO 4217 code of price currency.
+	Currency string
+	// Quantity is a number of items in stock.
+	Quantity     int64
+	Availability Availability
+	// RestockDate is an expected date of stock replenishment, zero value means unknown.
+	RestockDate time.Time
+}
+
+// Availability specifies availability status of store position.
+type Availability string
+
+// Position availability statuses.
+const (
+	AvailabilityInStock      Availability = "in_stock"
+	AvailabilityBackorder    Availability = "backorder"
+	AvailabilityDiscontinued Availability = "discontinued"
+)
+
+// BaseCurrency is a currency exchange rates are relative to.
+const BaseCurrency = "USD"
+
+// ExchangeRate represents amount of currency units per one unit of base currency.
+type ExchangeRate struct {
+	// Currency is an ISO 4217 currency code.
+	Currency  string
+	Rate      decimal.Decimal
+	UpdatedAt time.Time
+}
+
+// CartOwner identifies shopping cart of authenticated user or anonymous visitor.
+type CartOwner struct {
+	// UserID is an ID of cart owner, 0 means anonymous cart.
+	UserID int64
+	// Token is an opaque identifier of anonymous cart.
+	Token string
+}
+
+// Cart represents shopping cart.
+type Cart struct {
+	// Items contains cart items in order they were added.
+	Items []CartItem
+	// Currency is an ISO 4217 code of cart total currency.
+	Currency string
+	// Total is a cost of available items at current prices.
+	Total decimal.Decimal
+}
+
+// CartItem represents store position put into shopping cart.
+type CartItem struct {
+	ProductID int64
+	VariantID int64
+	StoreID   int64
+	Quantity  int64
+	// Price is a unit price of the position at the moment the item was put into cart.
+	Price    decimal.Decimal
+	Currency string
+	// Position is a current state of the store position, zero value means the position no longer exists.
+	Position Position
+	// PriceChanged states that current position price differs from the item price.
+	PriceChanged bool
+	// Available states that the position can be ordered in the item quantity.
+	Available bool
+}
+
+// OrderStatus specifies order lifecycle status.
+type OrderStatus string
+
+// Order statuses.
+const (
+	OrderPending   OrderStatus = "pending"
+	OrderPaid      OrderStatus = "paid"
+	OrderShipped   OrderStatus = "shipped"
+	OrderDelivered OrderStatus = "delivered"
+	OrderCancelled OrderStatus = "cancelled"
+	OrderRefunded  OrderStatus = "refunded"
+)
+
+// Order represents placed order.
+type Order struct {
+	ID     int64
+	UserID int64
+	Status OrderStatus
+	// Currency is an ISO 4217 code of order total currency.
+	Currency string
+	Total    decimal.Decimal
+	Items    []OrderItem
+	// Transitions contains status changes in chronological order.
+	Transitions []OrderTransition
+	CreatedAt   time.Time
+}
+
+// OrderItem represents store position snapshotted at checkout.
+type OrderItem struct {
+	ProductID int64
+	VariantID int64
+	StoreID   int64
+	// Name is a product name at the moment of checkout.
+	Name     string
+	SKU      string
+	Quantity int64
+	// Price is a unit price of the position at the moment of checkout.
+	Price    decimal.Decimal
+	Currency string
+	// Reserved states that item quantity is taken from position stock.
+	Reserved bool
+}
+
+// OrderTransition represents change of order status.
+type OrderTransition struct {
+	// From is a previous status, empty value means order placement.
+	From OrderStatus
+	To   OrderStatus
+	// ActorID is an ID of user who changed the status, 0 means unknown user.
+	ActorID   int64
+	CreatedAt time.Time
+}
+
+// OrderFilter specifies order list filtering.
+type OrderFilter struct {
+	// UserID limits orders to ones placed by the user, 0 means any user.
+	UserID int64
+	// Status limits orders to ones with listed statuses, empty value means any status.
+	Status []OrderStatus
+}
+
+// PaymentStatus specifies status of order payment.
+type PaymentStatus string
+
+// Payment statuses.
+const (
+	// PaymentPending means that payment awaits asynchronous confirmation from provider.
+	PaymentPending    PaymentStatus = "pending"
+	PaymentAuthorized PaymentStatus = "authorized"
+	PaymentCaptured   PaymentStatus = "captured"
+	PaymentDeclined   PaymentStatus = "declined"
+	PaymentRefunded   PaymentStatus = "refunded"
+)
+
+// Payment represents order payment at payment provider.
+type Payment struct {
+	// ID is a payment identifier assigned by provider.
+	ID       string
+	OrderID  int64
+	Status   PaymentStatus
+	Amount   decimal.Decimal
+	Currency string
+	// CreatedAt is a time of payment authorization.
+	CreatedAt time.Time
+}
+
+// PriceChange represents price of store position recorded on change.
+type PriceChange struct {
+	ProductID int64
+	VariantID int64
 	StoreID   int64
 	Price     decimal.Decimal
+	Currency  string
+	// UserID is an ID of user who changed the price, 0 means unknown user.
+	UserID    int64
+	ChangedAt time.Time
+}
+
+// PriceBucket represents statistics of prices recorded during a day.
+type PriceBucket struct {
+	// Date is a start of the day in UTC.
+	Date time.Time
+	Min  decimal.Decimal
+	Avg  decimal.Decimal
+	Max  decimal.Decimal
+}
+
+// PriceHistoryFilter specifies price history range.
+type PriceHistoryFilter struct {
+	// StoreID limits history to the store, 0 means any store.
+	StoreID int64
+	// From is an inclusive start of the range.
+	From time.Time
+	// To is an exclusive end of the range.
+	To time.Time
+}
+
+// PositionFilter specifies position list filtering.
+type PositionFilter struct {
+	// Availability limits positions to ones with listed statuses, empty value means any status.
+	Availability []Availability
+}
+
+// Page specifies requested slice of a list.
+type Page struct {
+	// Limit is a maximal number of items in a page.
+	Limit int
+	// Cursor is an opaque position in a list, empty value means first page.
+	Cursor string
+}
+
+// ProductSort specifies product ordering key.
+type ProductSort string
+
+// Product ordering keys.
+const (
+	SortByID    ProductSort = "id"
+	SortByName  ProductSort = "name"
+	SortByPrice ProductSort = "price"
+)
+
+// ProductFilter specifies product list filtering and ordering.
+type ProductFilter struct {
+	// Name is a case-insensitive substring of product name.
+	Name string
+	// MinPrice is a lower bound of the best product price across stores in base currency.
+	MinPrice decimal.NullDecimal
+	// MaxPrice is an upper bound of the best product price across stores in base currency.
+	MaxPrice decimal.NullDecimal
+	// IncludeSubcategories extends category filter to all its descendant categories.
+	IncludeSubcategories bool
+	// StoreID limits products to ones available in the store, 0 means any store.
+	StoreID int64
+	// Attributes limits products to ones with matching attributes.
+	Attributes []AttributeFilter
+	// Sort is an ordering key, empty value means ordering by ID.
+	Sort ProductSort
+	// Desc reverses ordering.
+	Desc bool
+}
+
+// AttributeFilter specifies condition on product attribute.
+type AttributeFilter struct {
+	Name string
+	// Value is an exact attribute value, nil means any value.
+	Value interface{}
+	// Min is a lower bound of number attribute.
+	Min decimal.NullDecimal
+	// Max is an upper bound of number attribute.
+	Max decimal.NullDecimal
+}
+
+// ProductSearch specifies full-text product search.
+type ProductSearch struct {
+	// Query is a text to search, the last word is matched as a prefix.
+	Query string
+	// CategoryID limits search to the category, 0 means any category.
+	CategoryID int64
+}
+
+// ProductSearchResult represents product matched by search query.
+type ProductSearchResult struct {
+	Product Product
+	// Rank is a relevance of the product to the query.
+	Rank float64
+	// NameHighlight is a product name with matched words highlighted.
+	NameHighlight string
+	// DescriptionHighlight is a fragment of product description with matched words highlighted.
+	DescriptionHighlight string
 }
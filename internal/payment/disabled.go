@@ -0,0 +1,30 @@
+package payment
+
+import (
+	"context"
+	"net/http"
+)
+
+type disabledProvider struct{}
+
+// NewDisabledProvider creates provider that rejects all payments and webhooks,
+// it is used when no real provider is configured.
+func NewDisabledProvider() Provider {
+	return disabledProvider{}
+}
+
+func (disabledProvider) Authorize(ctx context.Context, req AuthorizeRequest) (Payment, error) {
+	return Payment{}, ErrUnavailable
+}
+
+func (disabledProvider) Capture(ctx context.Context, paymentID string) (Payment, error) {
+	return Payment{}, ErrUnavailable
+}
+
+func (disabledProvider) Refund(ctx context.Context, paymentID string) (Payment, error) {
+	return Payment{}, ErrUnavailable
+}
+
+func (disabledProvider) ParseWebhook(header http.Header, body []byte) (Event, error) {
+	return Event{}, ErrInvalidSignature
+}
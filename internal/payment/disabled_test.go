@@ -0,0 +1,26 @@
+package payment
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDisabledProvider(t *testing.T) {
+	ctx := context.Background()
+	p := NewDisabledProvider()
+
+	_, err := p.Authorize(ctx, testRequest)
+	assert.Equal(t, ErrUnavailable, err)
+
+	_, err = p.Capture(ctx, "p1")
+	assert.Equal(t, ErrUnavailable, err)
+
+	_, err = p.Refund(ctx, "p1")
+	assert.Equal(t, ErrUnavailable, err)
+
+	_, err = p.ParseWebhook(http.Header{}, []byte(`{"id":"e1","paymentId":"p1","status":"captured"}`))
+	assert.Equal(t, ErrInvalidSignature, err)
+}
@@ -0,0 +1,184 @@
+package payment
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// Payment methods recognized by the fake provider.
+const (
+	// FakeMethodSuccess is authorized immediately.
+	FakeMethodSuccess = "fake_success"
+	// FakeMethodDecline is declined immediately.
+	FakeMethodDecline = "fake_decline"
+	// FakeMethodAsync is authorized later with webhook.
+	FakeMethodAsync = "fake_async"
+	// FakeMethodAsyncDecline is declined later with webhook.
+	FakeMethodAsyncDecline = "fake_async_decline"
+)
+
+// FakeSignatureHeader is a header of webhook request with hex encoded HMAC-SHA256 of the body.
+const FakeSignatureHeader = "X-Fake-Signature"
+
+type fakeEvent struct {
+	ID        string `json:"id"`
+	PaymentID string `json:"paymentId"`
+	Status    Status `json:"status"`
+}
+
+type fakeProvider struct {
+	secret      []byte
+	callbackURL string
+	delay       time.Duration
+	client      *http.Client
+
+	mu       sync.Mutex
+	payments map[string]Payment
+}
+
+// NewFakeProvider creates in-memory provider that simulates payments without external calls.
+// Webhooks signed with secret are sent to callbackURL after delay to confirm asynchronous payments,
+// empty callbackURL disables sending.
+func NewFakeProvider(secret, callbackURL string, delay time.Duration) Provider {
+	return &fakeProvider{
+		secret:      []byte(secret),
+		callbackURL: callbackURL,
+		delay:       delay,
+		client:      &http.Client{Timeout: 10 * time.Second},
+		payments:    make(map[string]Payment),
+	}
+}
+
+func (p *fakeProvider) Authorize(ctx context.Context, req AuthorizeRequest) (Payment, error) {
+	pay := Payment{
+		ID:       "fake_" + uuid.New().String(),
+		Amount:   req.Amount,
+		Currency: req.Currency,
+	}
+
+	var result Status
+	switch req.Method {
+	case FakeMethodSuccess:
+		pay.Status = StatusAuthorized
+	case FakeMethodDecline:
+		return Payment{}, ErrDeclined
+	case FakeMethodAsync:
+		pay.Status = StatusPending
+		result = StatusAuthorized
+	case FakeMethodAsyncDecline:
+		pay.Status = StatusPending
+		result = StatusDeclined
+	default:
+		return Payment{}, fmt.Errorf("%w: unknown payment method", ErrDeclined)
+	}
+
+	p.mu.Lock()
+	p.payments[pay.ID] = pay
+	p.mu.Unlock()
+
+	if result != "" {
+		time.AfterFunc(p.delay, func() {
+			p.confirm(pay.ID, result)
+		})
+	}
+
+	return pay, nil
+}
+
+func (p *fakeProvider) Capture(ctx context.Context, paymentID string) (Payment, error) {
+	return p.transition(paymentID, StatusAuthorized, StatusCaptured)
+}
+
+func (p *fakeProvider) Refund(ctx context.Context, paymentID string) (Payment, error) {
+	return p.transition(paymentID, StatusCaptured, StatusRefunded)
+}
+
+func (p *fakeProvider) ParseWebhook(header http.Header, body []byte) (Event, error) {
+	sig, err := hex.DecodeString(header.Get(FakeSignatureHeader))
+	if err != nil || !hmac.Equal(sig, p.sign(body)) {
+		return Event{}, ErrInvalidSignature
+	}
+
+	var e fakeEvent
+	if err := json.Unmarshal(body, &e); err != nil {
+		return Event{}, fmt.Errorf("failed to parse event: %w", err)
+	}
+
+	return Event{
+		ID:        e.ID,
+		PaymentID: e.PaymentID,
+		Status:    e.Status,
+	}, nil
+}
+
+func (p *fakeProvider) transition(paymentID string, from, to Status) (Payment, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	pay, ok := p.payments[paymentID]
+	if !ok {
+		return Payment{}, ErrNotFound
+	}
+
+	if pay.Status != from {
+		return Payment{}, fmt.Errorf("%w: payment is %s", ErrInvalidState, pay.Status)
+	}
+
+	pay.Status = to
+	p.payments[paymentID] = pay
+
+	return pay, nil
+}
+
+func (p *fakeProvider) confirm(paymentID string, status Status) {
+	if _, err := p.transition(paymentID, StatusPending, status); err != nil {
+		logrus.WithError(err).Errorf("fake provider failed to confirm payment %s", paymentID)
+		return
+	}
+
+	if p.callbackURL == "" {
+		return
+	}
+
+	body, _ := json.Marshal(fakeEvent{
+		ID:        uuid.New().String(),
+		PaymentID: paymentID,
+		Status:    status,
+	})
+
+	req, err := http.NewRequest(http.MethodPost, p.callbackURL, bytes.NewReader(body))
+	if err != nil {
+		logrus.WithError(err).Error("fake provider failed to create webhook request")
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(FakeSignatureHeader, hex.EncodeToString(p.sign(body)))
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		logrus.WithError(err).Errorf("fake provider failed to send webhook for payment %s", paymentID)
+		return
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		logrus.Errorf("fake provider webhook for payment %s rejected with status %d", paymentID, resp.StatusCode)
+	}
+}
+
+func (p *fakeProvider) sign(body []byte) []byte {
+	mac := hmac.New(sha256.New, p.secret)
+	mac.Write(body)
+	return mac.Sum(nil)
+}
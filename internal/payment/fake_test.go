@@ -0,0 +1,131 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testRequest = AuthorizeRequest{
+	Reference: "1",
+	Amount:    decimal.RequireFromString("19.99"),
+	Currency:  "USD",
+	Method:    FakeMethodSuccess,
+}
+
+func TestFakeProvider(t *testing.T) {
+	ctx := context.Background()
+	p := NewFakeProvider("secret", "", 0)
+
+	pay, err := p.Authorize(ctx, testRequest)
+	require.NoError(t, err)
+	assert.Equal(t, StatusAuthorized, pay.Status)
+	assert.Equal(t, "19.99", pay.Amount.String())
+	assert.Equal(t, "USD", pay.Currency)
+
+	_, err = p.Refund(ctx, pay.ID)
+	assert.True(t, errors.Is(err, ErrInvalidState))
+
+	pay, err = p.Capture(ctx, pay.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusCaptured, pay.Status)
+
+	pay, err = p.Refund(ctx, pay.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusRefunded, pay.Status)
+
+	_, err = p.Capture(ctx, "unknown")
+	assert.Equal(t, ErrNotFound, err)
+}
+
+func TestFakeProvider_Decline(t *testing.T) {
+	p := NewFakeProvider("secret", "", 0)
+
+	req := testRequest
+	req.Method = FakeMethodDecline
+	_, err := p.Authorize(context.Background(), req)
+	assert.Equal(t, ErrDeclined, err)
+
+	req.Method = "unknown"
+	_, err = p.Authorize(context.Background(), req)
+	assert.True(t, errors.Is(err, ErrDeclined))
+}
+
+func TestFakeProvider_Async(t *testing.T) {
+	testCases := []struct {
+		desc   string
+		method string
+		status Status
+	}{
+		{
+			desc:   "authorized",
+			method: FakeMethodAsync,
+			status: StatusAuthorized,
+		},
+		{
+			desc:   "declined",
+			method: FakeMethodAsyncDecline,
+			status: StatusDeclined,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			events := make(chan Event, 1)
+			var p Provider
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := ioutil.ReadAll(r.Body)
+				e, err := p.ParseWebhook(r.Header, body)
+				if err != nil {
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+				events <- e
+			}))
+			defer srv.Close()
+
+			p = NewFakeProvider("secret", srv.URL, time.Millisecond)
+
+			req := testRequest
+			req.Method = tC.method
+			pay, err := p.Authorize(context.Background(), req)
+			require.NoError(t, err)
+			assert.Equal(t, StatusPending, pay.Status)
+
+			select {
+			case e := <-events:
+				assert.NotEmpty(t, e.ID)
+				assert.Equal(t, pay.ID, e.PaymentID)
+				assert.Equal(t, tC.status, e.Status)
+			case <-time.After(5 * time.Second):
+				t.Fatal("webhook is not received")
+			}
+		})
+	}
+}
+
+func TestFakeProvider_ParseWebhook(t *testing.T) {
+	p := NewFakeProvider("secret", "", 0)
+	body := []byte(`{"id":"e1","paymentId":"p1","status":"captured"}`)
+
+	h := http.Header{}
+	h.Set(FakeSignatureHeader, "f92eea7a1c5cd1a1e4fb8bcf5a12f4a1d0e0a6c3fd30d3b0a0e0e0a9e6a0a0a0")
+	_, err := p.ParseWebhook(h, body)
+	assert.Equal(t, ErrInvalidSignature, err)
+
+	h.Set(FakeSignatureHeader, "not hex")
+	_, err = p.ParseWebhook(h, body)
+	assert.Equal(t, ErrInvalidSignature, err)
+
+	h.Set(FakeSignatureHeader, "e5bde9b430a534ce56d1d9a671285b6d6a3781ebabfbb38eb06db1cde900bc41")
+	e, err := p.ParseWebhook(h, body)
+	require.NoError(t, err)
+	assert.Equal(t, Event{ID: "e1", PaymentID: "p1", Status: StatusCaptured}, e)
+}
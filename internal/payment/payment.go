@@ -0,0 +1,83 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/shopspring/decimal"
+)
+
+//go:generate mockgen -destination=./payment_mock.go -package=payment -source=payment.go
+
+var (
+	// ErrDeclined states that payment was declined by provider.
+	ErrDeclined = errors.New("payment declined")
+
+	// ErrNotFound states that payment is unknown to provider.
+	ErrNotFound = errors.New("payment not found")
+
+	// ErrInvalidState states that operation is not allowed in current payment status.
+	ErrInvalidState = errors.New("invalid payment state")
+
+	// ErrInvalidSignature states that webhook signature doesn't match its payload.
+	ErrInvalidSignature = errors.New("invalid signature")
+
+	// ErrUnavailable states that payments are not configured.
+	ErrUnavailable = errors.New("payments are unavailable")
+)
+
+// Status specifies payment status at provider.
+type Status string
+
+// Payment statuses.
+const (
+	// StatusPending means that authorization result will be delivered by webhook.
+	StatusPending    Status = "pending"
+	StatusAuthorized Status = "authorized"
+	StatusCaptured   Status = "captured"
+	StatusDeclined   Status = "declined"
+	StatusRefunded   Status = "refunded"
+)
+
+// Payment represents payment at provider.
+type Payment struct {
+	ID       string
+	Status   Status
+	Amount   decimal.Decimal
+	Currency string
+}
+
+// AuthorizeRequest specifies payment to authorize.
+type AuthorizeRequest struct {
+	// Reference is a merchant identifier of the payment, e.g. order ID.
+	Reference string
+	Amount    decimal.Decimal
+	Currency  string
+	// Method is a provider token of customer payment method.
+	Method string
+}
+
+// Event represents provider notification about payment status change.
+type Event struct {
+	// ID is a unique identifier of the event, repeated deliveries of the event share it.
+	ID        string
+	PaymentID string
+	Status    Status
+}
+
+// Provider provides methods to charge customers.
+type Provider interface {
+	// Authorize reserves amount on customer payment method,
+	// pending payments are authorized or declined later with webhook event.
+	Authorize(ctx context.Context, req AuthorizeRequest) (Payment, error)
+
+	// Capture charges the whole authorized amount.
+	Capture(ctx context.Context, paymentID string) (Payment, error)
+
+	// Refund returns the whole captured amount to customer.
+	Refund(ctx context.Context, paymentID string) (Payment, error)
+
+	// ParseWebhook verifies signature of webhook request and returns its event.
+	ParseWebhook(header http.Header, body []byte) (Event, error)
+}
@@ -0,0 +1,95 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: payment.go
+
+// Package payment is a generated GoMock package.
+package payment
+
+import (
+	context "context"
+	gomock "github.com/golang/mock/gomock"
+	http "net/http"
+	reflect "reflect"
+)
+
+// MockProvider is a mock of Provider interface
+type MockProvider struct {
+	ctrl     *gomock.Controller
+	recorder *MockProviderMockRecorder
+}
+
+// MockProviderMockRecorder is the mock recorder for MockProvider
+type MockProviderMockRecorder struct {
+	mock *MockProvider
+}
+
+// NewMockProvider creates a new mock instance
+func NewMockProvider(ctrl *gomock.Controller) *MockProvider {
+	mock := &MockProvider{ctrl: ctrl}
+	mock.recorder = &MockProviderMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockProvider) EXPECT() *MockProviderMockRecorder {
+	return m.recorder
+}
+
+// Authorize mocks base method
+func (m *MockProvider) Authorize(ctx context.Context, req AuthorizeRequest) (Payment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Authorize", ctx, req)
+	ret0, _ := ret[0].(Payment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Authorize indicates an expected call of Authorize
+func (mr *MockProviderMockRecorder) Authorize(ctx, req interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Authorize", reflect.TypeOf((*MockProvider)(nil).Authorize), ctx, req)
+}
+
+// Capture mocks base method
+func (m *MockProvider) Capture(ctx context.Context, paymentID string) (Payment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Capture", ctx, paymentID)
+	ret0, _ := ret[0].(Payment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Capture indicates an expected call of Capture
+func (mr *MockProviderMockRecorder) Capture(ctx, paymentID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Capture", reflect.TypeOf((*MockProvider)(nil).Capture), ctx, paymentID)
+}
+
+// Refund mocks base method
+func (m *MockProvider) Refund(ctx context.Context, paymentID string) (Payment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Refund", ctx, paymentID)
+	ret0, _ := ret[0].(Payment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Refund indicates an expected call of Refund
+func (mr *MockProviderMockRecorder) Refund(ctx, paymentID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Refund", reflect.TypeOf((*MockProvider)(nil).Refund), ctx, paymentID)
+}
+
+// ParseWebhook mocks base method
+func (m *MockProvider) ParseWebhook(header http.Header, body []byte) (Event, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ParseWebhook", header, body)
+	ret0, _ := ret[0].(Event)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ParseWebhook indicates an expected call of ParseWebhook
+func (mr *MockProviderMockRecorder) ParseWebhook(header, body interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ParseWebhook", reflect.TypeOf((*MockProvider)(nil).ParseWebhook), header, body)
+}
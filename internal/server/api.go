@@ -1,33 +1,116 @@
 package server
 
 import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"math/big"
+	"time"
+
 	"github.com/shopspring/decimal"
 	"github.com/vliubezny/gstore/internal/auth"
 	"github.com/vliubezny/gstore/internal/model"
 )
 
+const dateLayout = "2006-01-02"
+
 // errorResponse represents error response
 type errorResponse struct {
 	Error string `json:"error"`
 }
 
+// page represents a page of list response.
+type page struct {
+	Items      interface{} `json:"items"`
+	NextCursor string      `json:"nextCursor,omitempty"`
+}
+
 // category represents category object.
 type category struct {
-	ID   int64  `json:"id"`
-	Name string `json:"name" validate:"required,gte=2,lte=80"`
+	ID       int64       `json:"id"`
+	Name     string      `json:"name" validate:"required,gte=2,lte=80"`
+	ParentID int64       `json:"parentId,omitempty" validate:"gte=0"`
+	Schema   []attribute `json:"schema,omitempty" validate:"dive"`
 }
 
 func fromCategoryModel(c model.Category) category {
+	var schema []attribute
+	if len(c.Schema) > 0 {
+		schema = make([]attribute, len(c.Schema))
+		for i, a := range c.Schema {
+			schema[i] = fromAttributeModel(a)
+		}
+	}
+
 	return category{
-		ID:   c.ID,
-		Name: c.Name,
+		ID:       c.ID,
+		Name:     c.Name,
+		ParentID: c.ParentID,
+		Schema:   schema,
 	}
 }
 
 func (c category) toModel() model.Category {
+	var schema []model.Attribute
+	if len(c.Schema) > 0 {
+		schema = make([]model.Attribute, len(c.Schema))
+		for i, a := range c.Schema {
+			schema[i] = a.toModel()
+		}
+	}
+
 	return model.Category{
-		ID:   c.ID,
-		Name: c.Name,
+		ID:       c.ID,
+		Name:     c.Name,
+		ParentID: c.ParentID,
+		Schema:   schema,
+	}
+}
+
+// attribute represents product attribute in category schema.
+type attribute struct {
+	Name     string   `json:"name" validate:"required"`
+	Type     string   `json:"type" validate:"required"`
+	Unit     string   `json:"unit,omitempty" validate:"lte=20"`
+	Values   []string `json:"values,omitempty"`
+	Required bool     `json:"required,omitempty"`
+}
+
+func fromAttributeModel(a model.Attribute) attribute {
+	return attribute{
+		Name:     a.Name,
+		Type:     string(a.Type),
+		Unit:     a.Unit,
+		Values:   a.Values,
+		Required: a.Required,
+	}
+}
+
+func (a attribute) toModel() model.Attribute {
+	return model.Attribute{
+		Name:     a.Name,
+		Type:     model.AttributeType(a.Type),
+		Unit:     a.Unit,
+		Values:   a.Values,
+		Required: a.Required,
+	}
+}
+
+// categoryNode represents category with its subcategories.
+type categoryNode struct {
+	category
+	Children []categoryNode `json:"children"`
+}
+
+func fromCategoryNodeModel(n model.CategoryNode) categoryNode {
+	children := make([]categoryNode, len(n.Children))
+	for i, c := range n.Children {
+		children[i] = fromCategoryNodeModel(c)
+	}
+
+	return categoryNode{
+		category: fromCategoryModel(n.Category),
+		Children: children,
 	}
 }
 
@@ -50,19 +133,68 @@ func (s store) toModel() model.Store {
 	}
 }
 
+type storeMember struct {
+	UserID int64  `json:"userId"`
+	Email  string `json:"email"`
+	Role   string `json:"role"`
+}
+
+func fromStoreMemberModel(m model.StoreMember) storeMember {
+	return storeMember{
+		UserID: m.UserID,
+		Email:  m.Email,
+		Role:   string(m.Role),
+	}
+}
+
+type storeMemberInvite struct {
+	Email string `json:"email" validate:"required,email"`
+	Role  string `json:"role" validate:"oneof=owner editor"`
+}
+
 type product struct {
-	ID          int64  `json:"id"`
-	CategoryID  int64  `json:"categoryId" validate:"required"`
-	Name        string `json:"name" validate:"required,gte=3,lte=160"`
-	Description string `json:"description" validate:"required"`
+	ID          int64                  `json:"id"`
+	CategoryID  int64                  `json:"categoryId" validate:"required"`
+	Name        string                 `json:"name" validate:"required,gte=3,lte=160"`
+	Description string                 `json:"description" validate:"required"`
+	Attributes  map[string]interface{} `json:"attributes,omitempty"`
+	Images      []image                `json:"images,omitempty"`
+	Variants    []variant              `json:"variants,omitempty"`
+	Offers      *offerSummary          `json:"offers,omitempty"`
 }
 
 func fromProductModel(p model.Product) product {
+	var images []image
+	if len(p.Images) > 0 {
+		images = make([]image, len(p.Images))
+		for i, img := range p.Images {
+			images[i] = fromImageModel(img)
+		}
+	}
+
+	var variants []variant
+	if len(p.Variants) > 0 {
+		variants = make([]variant, len(p.Variants))
+		for i, v := range p.Variants {
+			variants[i] = fromVariantModel(v)
+		}
+	}
+
+	var offers *offerSummary
+	if p.Offers.OfferCount > 0 {
+		o := fromPriceSummaryModel(p.Offers)
+		offers = &o
+	}
+
 	return product{
 		ID:          p.ID,
 		CategoryID:  p.CategoryID,
 		Name:        p.Name,
 		Description: p.Description,
+		Attributes:  p.Attributes,
+		Images:      images,
+		Variants:    variants,
+		Offers:      offers,
 	}
 }
 
@@ -72,47 +204,452 @@ func (p product) toModel() model.Product {
 		CategoryID:  p.CategoryID,
 		Name:        p.Name,
 		Description: p.Description,
+		Attributes:  p.Attributes,
+	}
+}
+
+// image represents product image.
+type image struct {
+	ID         int64             `json:"id"`
+	URL        string            `json:"url"`
+	Thumbnails map[string]string `json:"thumbnails"`
+	Width      int               `json:"width"`
+	Height     int               `json:"height"`
+	Primary    bool              `json:"primary"`
+}
+
+func fromImageModel(i model.Image) image {
+	return image{
+		ID:         i.ID,
+		URL:        i.URL,
+		Thumbnails: i.Thumbnails,
+		Width:      i.Width,
+		Height:     i.Height,
+		Primary:    i.Primary,
+	}
+}
+
+// imageOrder represents desired order of product images.
+type imageOrder struct {
+	ImageIDs []int64 `json:"imageIds" validate:"required,unique"`
+}
+
+type productHighlight struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type productSearchResult struct {
+	product
+	Rank      float64          `json:"rank"`
+	Highlight productHighlight `json:"highlight"`
+}
+
+func fromProductSearchResultModel(r model.ProductSearchResult) productSearchResult {
+	return productSearchResult{
+		product: fromProductModel(r.Product),
+		Rank:    r.Rank,
+		Highlight: productHighlight{
+			Name:        r.NameHighlight,
+			Description: r.DescriptionHighlight,
+		},
+	}
+}
+
+// variant represents product SKU.
+type variant struct {
+	ID         int64                  `json:"id"`
+	ProductID  int64                  `json:"productId"`
+	SKU        string                 `json:"sku" validate:"required,lte=64,printascii"`
+	GTIN       string                 `json:"gtin,omitempty" validate:"omitempty,numeric"`
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+}
+
+func fromVariantModel(v model.Variant) variant {
+	return variant{
+		ID:         v.ID,
+		ProductID:  v.ProductID,
+		SKU:        v.SKU,
+		GTIN:       v.GTIN,
+		Attributes: v.Attributes,
 	}
 }
 
+func (v variant) toModel() model.Variant {
+	return model.Variant{
+		ID:         v.ID,
+		ProductID:  v.ProductID,
+		SKU:        v.SKU,
+		GTIN:       v.GTIN,
+		Attributes: v.Attributes,
+	}
+}
+
+// variantOption represents dimension of variant matrix.
+type variantOption struct {
+	Name   string        `json:"name"`
+	Values []interface{} `json:"values"`
+}
+
+// variantMatrix represents product variants along with their dimensions.
+type variantMatrix struct {
+	Options  []variantOption `json:"options"`
+	Variants []variant       `json:"variants"`
+}
+
+func fromVariantMatrixModel(m model.VariantMatrix) variantMatrix {
+	options := make([]variantOption, len(m.Options))
+	for i, o := range m.Options {
+		options[i] = variantOption{Name: o.Name, Values: o.Values}
+	}
+
+	variants := make([]variant, len(m.Variants))
+	for i, v := range m.Variants {
+		variants[i] = fromVariantModel(v)
+	}
+
+	return variantMatrix{
+		Options:  options,
+		Variants: variants,
+	}
+}
+
+// position is a store position, quantity is initial stock of new position,
+// stock of existing one is changed with stock endpoints. Availability and restock date
+// of existing position are kept unless availability is set.
 type position struct {
-	ProductID int64           `json:"productId"`
-	StoreID   int64           `json:"storeId"`
-	Price     decimal.Decimal `json:"price" validate:"gt=0"`
+	ProductID    int64           `json:"productId"`
+	VariantID    int64           `json:"variantId"`
+	StoreID      int64           `json:"storeId"`
+	Price        decimal.Decimal `json:"price" validate:"gt=0"`
+	Currency     string          `json:"currency" validate:"omitempty,len=3,alpha,uppercase"`
+	Quantity     int64           `json:"quantity" validate:"gte=0"`
+	Availability string          `json:"availability" validate:"omitempty,oneof=in_stock backorder discontinued"`
+	RestockDate  string          `json:"restockDate,omitempty" validate:"omitempty,datetime=2006-01-02"`
 }
 
 func fromPositionModel(p model.Position) position {
+	var restockDate string
+	if !p.RestockDate.IsZero() {
+		restockDate = p.RestockDate.Format(dateLayout)
+	}
+
 	return position{
-		ProductID: p.ProductID,
-		StoreID:   p.StoreID,
-		Price:     p.Price,
+		ProductID:    p.ProductID,
+		VariantID:    p.VariantID,
+		StoreID:      p.StoreID,
+		Price:        p.Price,
+		Currency:     p.Currency,
+		Quantity:     p.Quantity,
+		Availability: string(p.Availability),
+		RestockDate:  restockDate,
 	}
 }
 
 func (p position) toModel() model.Position {
+	// date format is checked by validation
+	restockDate, _ := time.Parse(dateLayout, p.RestockDate)
+
 	return model.Position{
-		ProductID: p.ProductID,
-		StoreID:   p.StoreID,
-		Price:     p.Price,
+		ProductID:    p.ProductID,
+		VariantID:    p.VariantID,
+		StoreID:      p.StoreID,
+		Price:        p.Price,
+		Currency:     p.Currency,
+		Quantity:     p.Quantity,
+		Availability: model.Availability(p.Availability),
+		RestockDate:  restockDate,
+	}
+}
+
+// offerSummary represents comparison of product prices across stores,
+// prices are null if there are no offers.
+type offerSummary struct {
+	ProductID       int64               `json:"productId"`
+	OfferCount      int64               `json:"offerCount"`
+	Currency        string              `json:"currency"`
+	MinPrice        decimal.NullDecimal `json:"minPrice"`
+	MaxPrice        decimal.NullDecimal `json:"maxPrice"`
+	MedianPrice     decimal.NullDecimal `json:"medianPrice"`
+	CheapestStoreID int64               `json:"cheapestStoreId,omitempty"`
+}
+
+func fromPriceSummaryModel(s model.PriceSummary) offerSummary {
+	hasOffers := s.OfferCount > 0
+	return offerSummary{
+		ProductID:       s.ProductID,
+		OfferCount:      s.OfferCount,
+		Currency:        s.Currency,
+		MinPrice:        decimal.NullDecimal{Decimal: s.MinPrice, Valid: hasOffers},
+		MaxPrice:        decimal.NullDecimal{Decimal: s.MaxPrice, Valid: hasOffers},
+		MedianPrice:     decimal.NullDecimal{Decimal: s.MedianPrice, Valid: hasOffers},
+		CheapestStoreID: s.CheapestStoreID,
+	}
+}
+
+// exchangeRate represents amount of currency units per one unit of base currency.
+type exchangeRate struct {
+	Currency  string          `json:"currency" validate:"len=3,alpha,uppercase"`
+	Rate      decimal.Decimal `json:"rate" validate:"gt=0"`
+	UpdatedAt *time.Time      `json:"updatedAt,omitempty"`
+}
+
+func fromExchangeRateModel(r model.ExchangeRate) exchangeRate {
+	updatedAt := r.UpdatedAt
+	return exchangeRate{
+		Currency:  r.Currency,
+		Rate:      r.Rate,
+		UpdatedAt: &updatedAt,
+	}
+}
+
+func (r exchangeRate) toModel() model.ExchangeRate {
+	return model.ExchangeRate{
+		Currency: r.Currency,
+		Rate:     r.Rate,
+	}
+}
+
+type exchangeRatesImport struct {
+	Imported int `json:"imported"`
+}
+
+type cart struct {
+	Items    []cartItem      `json:"items"`
+	Currency string          `json:"currency"`
+	Total    decimal.Decimal `json:"total"`
+}
+
+func fromCartModel(c model.Cart) cart {
+	items := make([]cartItem, len(c.Items))
+	for i, item := range c.Items {
+		items[i] = fromCartItemModel(item)
+	}
+
+	return cart{
+		Items:    items,
+		Currency: c.Currency,
+		Total:    c.Total,
+	}
+}
+
+// cartItem represents position in cart, current price is null if the position no longer exists.
+type cartItem struct {
+	ProductID     int64               `json:"productId"`
+	VariantID     int64               `json:"variantId"`
+	StoreID       int64               `json:"storeId"`
+	Quantity      int64               `json:"quantity"`
+	Price         decimal.NullDecimal `json:"price"`
+	Currency      string              `json:"currency,omitempty"`
+	AddedPrice    decimal.Decimal     `json:"addedPrice"`
+	AddedCurrency string              `json:"addedCurrency"`
+	PriceChanged  bool                `json:"priceChanged"`
+	Available     bool                `json:"available"`
+}
+
+func fromCartItemModel(i model.CartItem) cartItem {
+	return cartItem{
+		ProductID:     i.ProductID,
+		VariantID:     i.VariantID,
+		StoreID:       i.StoreID,
+		Quantity:      i.Quantity,
+		Price:         decimal.NullDecimal{Decimal: i.Position.Price, Valid: i.Position.Currency != ""},
+		Currency:      i.Position.Currency,
+		AddedPrice:    i.Price,
+		AddedCurrency: i.Currency,
+		PriceChanged:  i.PriceChanged,
+		Available:     i.Available,
 	}
 }
 
+type cartItemQuantity struct {
+	Quantity int64 `json:"quantity" validate:"gt=0"`
+}
+
+type order struct {
+	ID          int64             `json:"id"`
+	UserID      int64             `json:"userId,omitempty"`
+	Status      string            `json:"status"`
+	Currency    string            `json:"currency"`
+	Total       decimal.Decimal   `json:"total"`
+	Items       []orderItem       `json:"items"`
+	Transitions []orderTransition `json:"transitions,omitempty"`
+	CreatedAt   time.Time         `json:"createdAt"`
+}
+
+func fromOrderModel(o model.Order) order {
+	items := make([]orderItem, len(o.Items))
+	for i, item := range o.Items {
+		items[i] = fromOrderItemModel(item)
+	}
+
+	var transitions []orderTransition
+	if len(o.Transitions) > 0 {
+		transitions = make([]orderTransition, len(o.Transitions))
+		for i, t := range o.Transitions {
+			transitions[i] = fromOrderTransitionModel(t)
+		}
+	}
+
+	return order{
+		ID:          o.ID,
+		UserID:      o.UserID,
+		Status:      string(o.Status),
+		Currency:    o.Currency,
+		Total:       o.Total,
+		Items:       items,
+		Transitions: transitions,
+		CreatedAt:   o.CreatedAt,
+	}
+}
+
+type orderItem struct {
+	ProductID int64           `json:"productId"`
+	VariantID int64           `json:"variantId"`
+	StoreID   int64           `json:"storeId"`
+	Name      string          `json:"name"`
+	SKU       string          `json:"sku"`
+	Quantity  int64           `json:"quantity"`
+	Price     decimal.Decimal `json:"price"`
+	Currency  string          `json:"currency"`
+	Reserved  bool            `json:"reserved"`
+}
+
+func fromOrderItemModel(i model.OrderItem) orderItem {
+	return orderItem{
+		ProductID: i.ProductID,
+		VariantID: i.VariantID,
+		StoreID:   i.StoreID,
+		Name:      i.Name,
+		SKU:       i.SKU,
+		Quantity:  i.Quantity,
+		Price:     i.Price,
+		Currency:  i.Currency,
+		Reserved:  i.Reserved,
+	}
+}
+
+type orderTransition struct {
+	From      string    `json:"from,omitempty"`
+	To        string    `json:"to"`
+	ActorID   int64     `json:"actorId,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func fromOrderTransitionModel(t model.OrderTransition) orderTransition {
+	return orderTransition{
+		From:      string(t.From),
+		To:        string(t.To),
+		ActorID:   t.ActorID,
+		CreatedAt: t.CreatedAt,
+	}
+}
+
+type orderStatusChange struct {
+	Status string `json:"status" validate:"oneof=pending paid shipped delivered cancelled refunded"`
+}
+
+type orderPayment struct {
+	Method string `json:"method" validate:"required,max=64"`
+}
+
+type payment struct {
+	ID        string          `json:"id"`
+	OrderID   int64           `json:"orderId"`
+	Status    string          `json:"status"`
+	Amount    decimal.Decimal `json:"amount"`
+	Currency  string          `json:"currency"`
+	CreatedAt time.Time       `json:"createdAt"`
+}
+
+func fromPaymentModel(p model.Payment) payment {
+	return payment{
+		ID:        p.ID,
+		OrderID:   p.OrderID,
+		Status:    string(p.Status),
+		Amount:    p.Amount,
+		Currency:  p.Currency,
+		CreatedAt: p.CreatedAt,
+	}
+}
+
+type priceChange struct {
+	VariantID int64           `json:"variantId"`
+	StoreID   int64           `json:"storeId"`
+	Price     decimal.Decimal `json:"price"`
+	Currency  string          `json:"currency"`
+	UserID    int64           `json:"userId,omitempty"`
+	ChangedAt time.Time       `json:"changedAt"`
+}
+
+func fromPriceChangeModel(c model.PriceChange) priceChange {
+	return priceChange{
+		VariantID: c.VariantID,
+		StoreID:   c.StoreID,
+		Price:     c.Price,
+		Currency:  c.Currency,
+		UserID:    c.UserID,
+		ChangedAt: c.ChangedAt,
+	}
+}
+
+type priceBucket struct {
+	Date string          `json:"date"`
+	Min  decimal.Decimal `json:"min"`
+	Avg  decimal.Decimal `json:"avg"`
+	Max  decimal.Decimal `json:"max"`
+}
+
+func fromPriceBucketModel(b model.PriceBucket) priceBucket {
+	return priceBucket{
+		Date: b.Date.Format(dateLayout),
+		Min:  b.Min,
+		Avg:  b.Avg,
+		Max:  b.Max,
+	}
+}
+
+// stockChange represents increment or decrement of position quantity.
+type stockChange struct {
+	Quantity int64 `json:"quantity" validate:"gt=0"`
+}
+
 type credentials struct {
 	Email    string `json:"email" validate:"required,email"`
 	Password string `json:"password" validate:"required,gte=8,lte=160"`
 }
 
+type userEmail struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type resetPassword struct {
+	Token    string `json:"token" validate:"required,max=128"`
+	Password string `json:"password" validate:"required,gte=8,lte=160"`
+}
+
+type verifyEmail struct {
+	Token string `json:"token" validate:"required,max=1024"`
+}
+
 type user struct {
-	ID      int64  `json:"id"`
-	Email   string `json:"email"`
-	IsAdmin bool   `json:"isAdmin"`
+	ID            int64    `json:"id"`
+	Email         string   `json:"email"`
+	EmailVerified bool     `json:"emailVerified"`
+	Roles         []string `json:"roles"`
 }
 
 func fromUserModel(u model.User) user {
+	roles := u.Roles
+	if roles == nil {
+		roles = []string{}
+	}
+
 	return user{
-		ID:      u.ID,
-		Email:   u.Email,
-		IsAdmin: u.IsAdmin,
+		ID:            u.ID,
+		Email:         u.Email,
+		EmailVerified: u.EmailVerified,
+		Roles:         roles,
 	}
 }
 
@@ -128,12 +665,239 @@ func fromTokenPairModel(tp auth.TokenPair) tokenPair {
 	}
 }
 
-type userPermissions struct {
-	IsAdmin bool `json:"isAdmin" validate:"required"`
+type mfaChallenge struct {
+	MFARequired bool   `json:"mfaRequired"`
+	MFAToken    string `json:"mfaToken"`
+}
+
+type mfaLogin struct {
+	MFAToken string `json:"mfaToken" validate:"required,max=1024"`
+	Code     string `json:"code" validate:"required,max=32"`
+}
+
+type mfaCode struct {
+	Code string `json:"code" validate:"required,max=32"`
+}
+
+type mfaEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+func fromMFAEnrollmentModel(e auth.MFAEnrollment) mfaEnrollment {
+	return mfaEnrollment{
+		Secret: e.Secret,
+		URI:    e.URI,
+	}
+}
+
+type recoveryCodes struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+// jwk represents public key in JSON Web Key format (RFC 7517).
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+func fromPublicKeyModel(pk auth.PublicKey) jwk {
+	k := jwk{
+		Kid: pk.ID,
+		Use: "sig",
+		Alg: pk.Algorithm,
+	}
+
+	switch key := pk.Key.(type) {
+	case *rsa.PublicKey:
+		k.Kty = "RSA"
+		k.N = base64.RawURLEncoding.EncodeToString(key.N.Bytes())
+		k.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes())
+	case ed25519.PublicKey:
+		k.Kty = "OKP"
+		k.Crv = "Ed25519"
+		k.X = base64.RawURLEncoding.EncodeToString(key)
+	}
+
+	return k
+}
+
+type jwks struct {
+	Keys []jwk `json:"keys"`
+}
+
+type session struct {
+	ID         string    `json:"id"`
+	IP         string    `json:"ip"`
+	UserAgent  string    `json:"userAgent"`
+	CreatedAt  time.Time `json:"createdAt"`
+	LastUsedAt time.Time `json:"lastUsedAt"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+	// Current states that the request is authenticated with the session.
+	Current bool `json:"current"`
+}
+
+func fromSessionModel(s model.Session, currentID string) session {
+	return session{
+		ID:         s.ID,
+		IP:         s.IP,
+		UserAgent:  s.UserAgent,
+		CreatedAt:  s.CreatedAt,
+		LastUsedAt: s.LastUsedAt,
+		ExpiresAt:  s.ExpiresAt,
+		Current:    s.ID == currentID,
+	}
+}
+
+type apiKeyRequest struct {
+	Name      string     `json:"name" validate:"required,max=64"`
+	Scopes    []string   `json:"scopes" validate:"dive,required,max=64"`
+	ExpiresAt *time.Time `json:"expiresAt" validate:"omitempty,gt"`
+}
+
+type apiKey struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	ExpiresAt  *time.Time `json:"expiresAt,omitempty"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`
+}
+
+func fromAPIKeyModel(k model.APIKey) apiKey {
+	scopes := make([]string, len(k.Scopes))
+	for i, s := range k.Scopes {
+		scopes[i] = string(s)
+	}
+
+	key := apiKey{
+		ID:        k.ID,
+		Name:      k.Name,
+		Prefix:    k.Prefix,
+		Scopes:    scopes,
+		CreatedAt: k.CreatedAt,
+	}
+
+	if !k.ExpiresAt.IsZero() {
+		key.ExpiresAt = &k.ExpiresAt
+	}
+	if !k.LastUsedAt.IsZero() {
+		key.LastUsedAt = &k.LastUsedAt
+	}
+
+	return key
+}
+
+// createdAPIKey contains the key shown only once on creation.
+type createdAPIKey struct {
+	apiKey
+	Key string `json:"key"`
+}
+
+type oauthClientRequest struct {
+	Name         string   `json:"name" validate:"required,max=64"`
+	RedirectURIs []string `json:"redirectUris" validate:"max=10,dive,required,url,max=256"`
+	Scopes       []string `json:"scopes" validate:"required,dive,required,max=64"`
+	// Confidential clients get secret, public clients (e.g. SPA) can use only authorization code with PKCE.
+	Confidential bool `json:"confidential"`
+}
+
+type oauthClient struct {
+	ID           string    `json:"id"`
+	Name         string    `json:"name"`
+	RedirectURIs []string  `json:"redirectUris"`
+	Scopes       []string  `json:"scopes"`
+	Confidential bool      `json:"confidential"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+func fromOAuthClientModel(c model.OAuthClient) oauthClient {
+	scopes := make([]string, len(c.Scopes))
+	for i, s := range c.Scopes {
+		scopes[i] = string(s)
+	}
+
+	return oauthClient{
+		ID:           c.ID,
+		Name:         c.Name,
+		RedirectURIs: c.RedirectURIs,
+		Scopes:       scopes,
+		Confidential: c.SecretHash != "",
+		CreatedAt:    c.CreatedAt,
+	}
+}
+
+// registeredOAuthClient contains the secret shown only once on registration.
+type registeredOAuthClient struct {
+	oauthClient
+	Secret string `json:"secret,omitempty"`
+}
+
+type authorizationRequest struct {
+	ClientID    string `json:"clientId" validate:"required"`
+	RedirectURI string `json:"redirectUri" validate:"required,url"`
+	// Scope is space-delimited list of scopes (RFC 6749 section 3.3).
+	Scope               string `json:"scope"`
+	State               string `json:"state" validate:"max=256"`
+	CodeChallenge       string `json:"codeChallenge" validate:"required,len=43"`
+	CodeChallengeMethod string `json:"codeChallengeMethod" validate:"required,oneof=S256"`
 }
 
-func (p userPermissions) toModel() model.User {
-	return model.User{
-		IsAdmin: p.IsAdmin,
+type authorizationResponse struct {
+	// RedirectURI is the URI to redirect user agent to with code and state parameters.
+	RedirectURI string `json:"redirectUri"`
+}
+
+// oauthToken is a response of token endpoint (RFC 6749 section 5.1).
+type oauthToken struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	Scope        string `json:"scope,omitempty"`
+}
+
+// oauthError is an error response of token endpoint (RFC 6749 section 5.2).
+type oauthError struct {
+	Error       string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+}
+
+// introspection is a response of introspection endpoint (RFC 7662 section 2.2).
+type introspection struct {
+	Active    bool   `json:"active"`
+	Scope     string `json:"scope,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	Subject   string `json:"sub,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	ExpiresAt int64  `json:"exp,omitempty"`
+	IssuedAt  int64  `json:"iat,omitempty"`
+}
+
+type userRoles struct {
+	Roles []string `json:"roles" validate:"required,dive,required,max=32"`
+}
+
+type role struct {
+	Name        string   `json:"name"`
+	Permissions []string `json:"permissions"`
+}
+
+func fromRoleModel(r model.Role) role {
+	permissions := make([]string, len(r.Permissions))
+	for i, p := range r.Permissions {
+		permissions[i] = string(p)
+	}
+
+	return role{
+		Name:        r.Name,
+		Permissions: permissions,
 	}
 }
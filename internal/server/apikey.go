@@ -0,0 +1,97 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+	"github.com/vliubezny/gstore/internal/auth"
+	"github.com/vliubezny/gstore/internal/model"
+)
+
+func (s *server) createAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	l := getLogger(r)
+
+	var req apiKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(l.WithError(err), w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := validate(&req); err != nil {
+		writeError(l.WithError(err), w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	scopes := make([]model.Permission, len(req.Scopes))
+	for i, sc := range req.Scopes {
+		scopes[i] = model.Permission(sc)
+	}
+
+	var expiresAt time.Time
+	if req.ExpiresAt != nil {
+		expiresAt = *req.ExpiresAt
+	}
+
+	key, k, err := s.a.CreateAPIKey(r.Context(), getClaims(r).UserID, req.Name, scopes, expiresAt)
+	if err != nil {
+		if errors.Is(err, auth.ErrUnknownScope) {
+			writeError(l.WithError(err), w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		writeInternalError(l.WithError(err), w, "fail to create api key")
+		return
+	}
+
+	l.WithField("apiKeyID", k.ID).Info("api key created")
+
+	writeOK(l, w, createdAPIKey{
+		apiKey: fromAPIKeyModel(k),
+		Key:    key,
+	})
+}
+
+func (s *server) getAPIKeysHandler(w http.ResponseWriter, r *http.Request) {
+	l := getLogger(r)
+
+	keys, err := s.a.GetAPIKeys(r.Context(), getClaims(r).UserID)
+	if err != nil {
+		writeInternalError(l.WithError(err), w, "fail to get api keys")
+		return
+	}
+
+	resp := make([]apiKey, len(keys))
+	for i, k := range keys {
+		resp[i] = fromAPIKeyModel(k)
+	}
+
+	writeOK(l, w, resp)
+}
+
+func (s *server) deleteAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	l := getLogger(r)
+
+	id := chi.URLParam(r, "id")
+	if _, err := uuid.Parse(id); err != nil {
+		writeError(l.WithError(err), w, http.StatusBadRequest, "invalid api key ID")
+		return
+	}
+
+	if err := s.a.DeleteAPIKey(r.Context(), getClaims(r).UserID, id); err != nil {
+		if errors.Is(err, auth.ErrNotFound) {
+			writeError(l.WithError(err), w, http.StatusNotFound, "api key not found")
+			return
+		}
+
+		writeInternalError(l.WithError(err), w, "fail to delete api key")
+		return
+	}
+
+	l.WithField("apiKeyID", id).Info("api key deleted")
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,325 @@
+package server
+
+import (
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/vliubezny/gstore/internal/auth"
+	"github.com/vliubezny/gstore/internal/model"
+	"github.com/vliubezny/gstore/internal/service"
+)
+
+const testAPIKeyID = "8ab3c8a2-f698-11e6-8dd4-cb9ced3df976"
+
+func Test_createAPIKeyHandler(t *testing.T) {
+	ts := time.Date(2021, 3, 1, 10, 0, 0, 0, time.UTC)
+	expiresAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	key := model.APIKey{
+		ID:        testAPIKeyID,
+		UserID:    1,
+		Name:      "import",
+		Prefix:    "gsk_12345678",
+		Scopes:    []model.Permission{model.PermissionPositionWrite},
+		CreatedAt: ts,
+	}
+
+	testCases := []struct {
+		desc      string
+		input     string
+		expiresAt time.Time
+		rErr      error
+		rcode     int
+		rdata     string
+	}{
+		{
+			desc:      "success",
+			input:     `{"name":"import", "scopes":["position:write"]}`,
+			expiresAt: time.Time{},
+			rErr:      nil,
+			rcode:     http.StatusOK,
+			rdata: `{"id":"8ab3c8a2-f698-11e6-8dd4-cb9ced3df976", "name":"import", "prefix":"gsk_12345678",
+				"scopes":["position:write"], "createdAt":"2021-03-01T10:00:00Z", "key":"gsk_12345678secret"}`,
+		},
+		{
+			desc:      "success with expiry",
+			input:     `{"name":"import", "scopes":["position:write"], "expiresAt":"` + expiresAt.Format(time.RFC3339) + `"}`,
+			expiresAt: expiresAt,
+			rErr:      nil,
+			rcode:     http.StatusOK,
+			rdata: `{"id":"8ab3c8a2-f698-11e6-8dd4-cb9ced3df976", "name":"import", "prefix":"gsk_12345678",
+				"scopes":["position:write"], "createdAt":"2021-03-01T10:00:00Z", "key":"gsk_12345678secret"}`,
+		},
+		{
+			desc:  "invalid JSON",
+			input: `{"name":`,
+			rErr:  errSkip,
+			rcode: http.StatusBadRequest,
+			rdata: `{"error":"unexpected EOF"}`,
+		},
+		{
+			desc:  "missing name",
+			input: `{"scopes":["position:write"]}`,
+			rErr:  errSkip,
+			rcode: http.StatusBadRequest,
+			rdata: `{"error":"name is a required field"}`,
+		},
+		{
+			desc:  "expired",
+			input: `{"name":"import", "expiresAt":"2021-03-01T10:00:00Z"}`,
+			rErr:  errSkip,
+			rcode: http.StatusBadRequest,
+			rdata: `{"error":"expiresAt must be greater than the current Date & Time"}`,
+		},
+		{
+			desc:  "unknown scope",
+			input: `{"name":"import", "scopes":["position:write"]}`,
+			rErr:  auth.ErrUnknownScope,
+			rcode: http.StatusBadRequest,
+			rdata: `{"error":"scope is unknown"}`,
+		},
+		{
+			desc:  "internal error",
+			input: `{"name":"import", "scopes":["position:write"]}`,
+			rErr:  assert.AnError,
+			rcode: http.StatusInternalServerError,
+			rdata: `{"error":"internal error"}`,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			svc := auth.NewMockService(ctrl)
+			if tC.rErr != errSkip {
+				svc.EXPECT().CreateAPIKey(gomock.Any(), testSuperadminClaims.UserID, "import",
+					[]model.Permission{model.PermissionPositionWrite}, tC.expiresAt).
+					Return("gsk_12345678secret", key, tC.rErr)
+			}
+
+			router := setupTestRouterWithAuth(nil, svc)
+			rec, r := newTestParameters(http.MethodPost, "/v1/api-keys", tC.input)
+
+			router.ServeHTTP(rec, r)
+
+			body, _ := ioutil.ReadAll(rec.Result().Body)
+
+			assert.Equal(t, tC.rcode, rec.Result().StatusCode)
+			assert.JSONEq(t, tC.rdata, string(body))
+		})
+	}
+}
+
+func Test_getAPIKeysHandler(t *testing.T) {
+	ts := time.Date(2021, 3, 1, 10, 0, 0, 0, time.UTC)
+	keys := []model.APIKey{
+		{ID: testAPIKeyID, UserID: 1, Name: "import", Prefix: "gsk_12345678", Scopes: []model.Permission{model.PermissionPositionWrite},
+			CreatedAt: ts, ExpiresAt: ts, LastUsedAt: ts},
+		{ID: "1e37df36-f698-11e6-8dd4-cb9ced3df976", UserID: 1, Name: "orders", Prefix: "gsk_87654321", CreatedAt: ts},
+	}
+
+	testCases := []struct {
+		desc  string
+		rKeys []model.APIKey
+		rErr  error
+		rcode int
+		rdata string
+	}{
+		{
+			desc:  "success",
+			rKeys: keys,
+			rErr:  nil,
+			rcode: http.StatusOK,
+			rdata: `[
+				{"id":"8ab3c8a2-f698-11e6-8dd4-cb9ced3df976", "name":"import", "prefix":"gsk_12345678", "scopes":["position:write"],
+				 "createdAt":"2021-03-01T10:00:00Z", "expiresAt":"2021-03-01T10:00:00Z", "lastUsedAt":"2021-03-01T10:00:00Z"},
+				{"id":"1e37df36-f698-11e6-8dd4-cb9ced3df976", "name":"orders", "prefix":"gsk_87654321", "scopes":[],
+				 "createdAt":"2021-03-01T10:00:00Z"}
+			]`,
+		},
+		{
+			desc:  "no keys",
+			rKeys: []model.APIKey{},
+			rErr:  nil,
+			rcode: http.StatusOK,
+			rdata: `[]`,
+		},
+		{
+			desc:  "internal error",
+			rKeys: nil,
+			rErr:  assert.AnError,
+			rcode: http.StatusInternalServerError,
+			rdata: `{"error":"internal error"}`,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			svc := auth.NewMockService(ctrl)
+			svc.EXPECT().GetAPIKeys(gomock.Any(), testSuperadminClaims.UserID).Return(tC.rKeys, tC.rErr)
+
+			router := setupTestRouterWithAuth(nil, svc)
+			rec, r := newTestParameters(http.MethodGet, "/v1/api-keys", "")
+
+			router.ServeHTTP(rec, r)
+
+			body, _ := ioutil.ReadAll(rec.Result().Body)
+
+			assert.Equal(t, tC.rcode, rec.Result().StatusCode)
+			assert.JSONEq(t, tC.rdata, string(body))
+		})
+	}
+}
+
+func Test_deleteAPIKeyHandler(t *testing.T) {
+	testCases := []struct {
+		desc  string
+		id    string
+		err   error
+		rcode int
+		rdata string
+	}{
+		{
+			desc:  "success",
+			id:    testAPIKeyID,
+			err:   nil,
+			rcode: http.StatusNoContent,
+			rdata: "",
+		},
+		{
+			desc:  "invalid api key ID",
+			id:    "test",
+			err:   errSkip,
+			rcode: http.StatusBadRequest,
+			rdata: `{"error":"invalid api key ID"}`,
+		},
+		{
+			desc:  "api key not found",
+			id:    testAPIKeyID,
+			err:   auth.ErrNotFound,
+			rcode: http.StatusNotFound,
+			rdata: `{"error":"api key not found"}`,
+		},
+		{
+			desc:  "internal error",
+			id:    testAPIKeyID,
+			err:   assert.AnError,
+			rcode: http.StatusInternalServerError,
+			rdata: `{"error":"internal error"}`,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			svc := auth.NewMockService(ctrl)
+			if tC.err != errSkip {
+				svc.EXPECT().DeleteAPIKey(gomock.Any(), testSuperadminClaims.UserID, testAPIKeyID).Return(tC.err)
+			}
+
+			router := setupTestRouterWithAuth(nil, svc)
+			rec, r := newTestParameters(http.MethodDelete, "/v1/api-keys/"+tC.id, "")
+
+			router.ServeHTTP(rec, r)
+
+			body, _ := ioutil.ReadAll(rec.Result().Body)
+
+			assert.Equal(t, tC.rcode, rec.Result().StatusCode)
+			if tC.rdata == "" {
+				assert.Empty(t, string(body))
+			} else {
+				assert.JSONEq(t, tC.rdata, string(body))
+			}
+		})
+	}
+}
+
+func Test_apiKeyManagement_deniedWithAPIKey(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	claims := auth.AccessTokenClaims{UserID: 1, APIKeyID: testAPIKeyID}
+	router := setupTestRouterWithOrders(nil, auth.NewMockService(ctrl), nil, nil, claims)
+	rec, r := newTestParameters(http.MethodPost, "/v1/api-keys", `{"name":"import"}`)
+
+	router.ServeHTTP(rec, r)
+
+	body, _ := ioutil.ReadAll(rec.Result().Body)
+
+	assert.Equal(t, http.StatusForbidden, rec.Result().StatusCode)
+	assert.JSONEq(t, `{"error":"not allowed with api key"}`, string(body))
+}
+
+func Test_cartAndOrders_deniedWithAPIKeyOutOfScope(t *testing.T) {
+	testCases := []struct {
+		desc   string
+		method string
+		uri    string
+		input  string
+		scopes []model.Permission
+	}{
+		{
+			desc:   "get cart",
+			method: http.MethodGet,
+			uri:    "/v1/cart",
+			scopes: []model.Permission{model.ScopeOrders, model.ScopePayments},
+		},
+		{
+			desc:   "set cart item",
+			method: http.MethodPut,
+			uri:    "/v1/cart/items/1/1",
+			input:  `{"quantity":1}`,
+			scopes: []model.Permission{model.ScopeOrders, model.ScopePayments},
+		},
+		{
+			desc:   "checkout",
+			method: http.MethodPost,
+			uri:    "/v1/orders",
+			input:  `{}`,
+			scopes: []model.Permission{model.ScopeCart, model.ScopePayments, model.PermissionOrderRead},
+		},
+		{
+			desc:   "get orders",
+			method: http.MethodGet,
+			uri:    "/v1/orders",
+			scopes: []model.Permission{model.ScopeCart, model.ScopePayments},
+		},
+		{
+			desc:   "get order",
+			method: http.MethodGet,
+			uri:    "/v1/orders/1",
+			scopes: []model.Permission{model.ScopeCart, model.ScopePayments},
+		},
+		{
+			desc:   "pay order",
+			method: http.MethodPost,
+			uri:    "/v1/orders/1/payments",
+			input:  `{"token":"tok"}`,
+			scopes: []model.Permission{model.ScopeCart, model.ScopeOrders, model.PermissionOrderWrite},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			claims := auth.AccessTokenClaims{UserID: 1, APIKeyID: testAPIKeyID, Scopes: tC.scopes}
+			router := setupTestRouterWithOrders(nil, nil, service.NewMockCartService(ctrl), service.NewMockOrderService(ctrl), claims)
+			rec, r := newTestParameters(tC.method, tC.uri, tC.input)
+
+			router.ServeHTTP(rec, r)
+
+			body, _ := ioutil.ReadAll(rec.Result().Body)
+
+			assert.Equal(t, http.StatusForbidden, rec.Result().StatusCode)
+			assert.JSONEq(t, `{"error":"access not allowed"}`, string(body))
+		})
+	}
+}
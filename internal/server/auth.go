@@ -5,6 +5,7 @@ import (
 	"errors"
 	"net/http"
 
+	"github.com/tomasen/realip"
 	"github.com/vliubezny/gstore/internal/auth"
 	"github.com/vliubezny/gstore/internal/model"
 )
@@ -57,22 +58,178 @@ func (s *server) loginHandler(w http.ResponseWriter, r *http.Request) {
 
 	l = l.WithField("email", req.Email)
 
-	tokens, err := s.a.Login(r.Context(), req.Email, req.Password)
+	tokens, err := s.a.Login(r.Context(), req.Email, req.Password, getClient(r))
 	if err != nil {
-		if errors.Is(err, auth.ErrInvalidCredentials) {
+		switch {
+		case errors.Is(err, auth.ErrInvalidCredentials):
 			writeError(l.WithError(err), w, http.StatusUnauthorized, "invalid username or password")
 			return
+		case errors.Is(err, auth.ErrEmailNotVerified):
+			writeError(l.WithError(err), w, http.StatusForbidden, "email address is not verified")
+			return
+		case errors.Is(err, auth.ErrAccountLocked):
+			writeError(l.WithError(err), w, http.StatusTooManyRequests, "account is temporarily locked")
+			return
+		case errors.Is(err, auth.ErrTooManyAttempts):
+			writeError(l.WithError(err), w, http.StatusTooManyRequests, "too many login attempts, try again later")
+			return
 		}
 
 		writeInternalError(l.WithError(err), w, "fail to login user")
 		return
 	}
 
+	if tokens.MFAToken != "" {
+		l.Info("second factor required")
+		writeOK(l, w, mfaChallenge{MFARequired: true, MFAToken: tokens.MFAToken})
+		return
+	}
+
 	l.Info("logged in successfully")
 
+	if token := getCartToken(r); token != "" {
+		s.mergeCart(l, w, r, token, tokens.AccessToken)
+	}
+
 	writeOK(l, w, fromTokenPairModel(tokens))
 }
 
+func (s *server) loginMFAHandler(w http.ResponseWriter, r *http.Request) {
+	l := getLogger(r)
+
+	var req mfaLogin
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(l.WithError(err), w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := validate(&req); err != nil {
+		writeError(l.WithError(err), w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	tokens, err := s.a.VerifyMFA(r.Context(), req.MFAToken, req.Code, getClient(r))
+	if err != nil {
+		switch {
+		case errors.Is(err, auth.ErrInvalidToken):
+			writeError(l.WithError(err), w, http.StatusUnauthorized, "invalid mfa token")
+			return
+		case errors.Is(err, auth.ErrInvalidMFACode):
+			writeError(l.WithError(err), w, http.StatusUnauthorized, "invalid mfa code")
+			return
+		case errors.Is(err, auth.ErrAccountLocked):
+			writeError(l.WithError(err), w, http.StatusTooManyRequests, "account is temporarily locked")
+			return
+		case errors.Is(err, auth.ErrTooManyAttempts):
+			writeError(l.WithError(err), w, http.StatusTooManyRequests, "too many login attempts, try again later")
+			return
+		}
+
+		writeInternalError(l.WithError(err), w, "fail to verify mfa code")
+		return
+	}
+
+	l.Info("logged in successfully")
+
+	if token := getCartToken(r); token != "" {
+		s.mergeCart(l, w, r, token, tokens.AccessToken)
+	}
+
+	writeOK(l, w, fromTokenPairModel(tokens))
+}
+
+func (s *server) enrollMFAHandler(w http.ResponseWriter, r *http.Request) {
+	l := getLogger(r)
+
+	e, err := s.a.EnrollMFA(r.Context(), getClaims(r).UserID)
+	if err != nil {
+		switch {
+		case errors.Is(err, auth.ErrNotFound):
+			writeError(l.WithError(err), w, http.StatusNotFound, "user not found")
+		case errors.Is(err, auth.ErrMFAEnabled):
+			writeError(l.WithError(err), w, http.StatusConflict, "mfa is already enabled")
+		default:
+			writeInternalError(l.WithError(err), w, "fail to enroll mfa")
+		}
+		return
+	}
+
+	writeOK(l, w, fromMFAEnrollmentModel(e))
+}
+
+func (s *server) confirmMFAHandler(w http.ResponseWriter, r *http.Request) {
+	l := getLogger(r)
+
+	var req mfaCode
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(l.WithError(err), w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := validate(&req); err != nil {
+		writeError(l.WithError(err), w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	codes, err := s.a.ConfirmMFA(r.Context(), getClaims(r).UserID, req.Code)
+	if err != nil {
+		switch {
+		case errors.Is(err, auth.ErrNotFound):
+			writeError(l.WithError(err), w, http.StatusNotFound, "user not found")
+		case errors.Is(err, auth.ErrInvalidMFACode):
+			writeError(l.WithError(err), w, http.StatusBadRequest, "invalid mfa code")
+		case errors.Is(err, auth.ErrMFAEnabled):
+			writeError(l.WithError(err), w, http.StatusConflict, "mfa is already enabled")
+		case errors.Is(err, auth.ErrMFANotEnrolled):
+			writeError(l.WithError(err), w, http.StatusConflict, "mfa enrollment is not started")
+		default:
+			writeInternalError(l.WithError(err), w, "fail to confirm mfa")
+		}
+		return
+	}
+
+	l.Info("mfa enabled")
+
+	writeOK(l, w, recoveryCodes{RecoveryCodes: codes})
+}
+
+func (s *server) disableMFAHandler(w http.ResponseWriter, r *http.Request) {
+	l := getLogger(r)
+
+	var req mfaCode
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(l.WithError(err), w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := validate(&req); err != nil {
+		writeError(l.WithError(err), w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := s.a.DisableMFA(r.Context(), getClaims(r).UserID, req.Code, realip.FromRequest(r)); err != nil {
+		switch {
+		case errors.Is(err, auth.ErrNotFound):
+			writeError(l.WithError(err), w, http.StatusNotFound, "user not found")
+		case errors.Is(err, auth.ErrInvalidMFACode):
+			writeError(l.WithError(err), w, http.StatusBadRequest, "invalid mfa code")
+		case errors.Is(err, auth.ErrMFANotEnrolled):
+			writeError(l.WithError(err), w, http.StatusConflict, "mfa is not enabled")
+		case errors.Is(err, auth.ErrAccountLocked):
+			writeError(l.WithError(err), w, http.StatusTooManyRequests, "account is temporarily locked")
+		case errors.Is(err, auth.ErrTooManyAttempts):
+			writeError(l.WithError(err), w, http.StatusTooManyRequests, "too many attempts, try again later")
+		default:
+			writeInternalError(l.WithError(err), w, "fail to disable mfa")
+		}
+		return
+	}
+
+	l.Info("mfa disabled")
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
 func (s *server) refreshHandler(w http.ResponseWriter, r *http.Request) {
 	l := getLogger(r)
 
@@ -82,7 +239,7 @@ func (s *server) refreshHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	tokens, err := s.a.Refresh(r.Context(), token)
+	tokens, err := s.a.Refresh(r.Context(), token, getClient(r))
 	if err != nil {
 		if errors.Is(err, auth.ErrInvalidToken) {
 			writeError(l.WithError(err), w, http.StatusUnauthorized, "invalid refresh token")
@@ -121,7 +278,129 @@ func (s *server) revokeHandler(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
-func (s *server) updateUserPermissionsHandler(w http.ResponseWriter, r *http.Request) {
+func (s *server) forgotPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	l := getLogger(r)
+
+	var req userEmail
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(l.WithError(err), w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := validate(&req); err != nil {
+		writeError(l.WithError(err), w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := s.a.ForgotPassword(r.Context(), req.Email); err != nil {
+		writeInternalError(l.WithError(err), w, "fail to request password reset")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *server) resetPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	l := getLogger(r)
+
+	var req resetPassword
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(l.WithError(err), w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := validate(&req); err != nil {
+		writeError(l.WithError(err), w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := s.a.ResetPassword(r.Context(), req.Token, req.Password); err != nil {
+		if errors.Is(err, auth.ErrInvalidToken) {
+			writeError(l.WithError(err), w, http.StatusBadRequest, "invalid or expired reset token")
+			return
+		}
+
+		writeInternalError(l.WithError(err), w, "fail to reset password")
+		return
+	}
+
+	l.Info("password has been reset")
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *server) verifyEmailHandler(w http.ResponseWriter, r *http.Request) {
+	l := getLogger(r)
+
+	var req verifyEmail
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(l.WithError(err), w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := validate(&req); err != nil {
+		writeError(l.WithError(err), w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := s.a.VerifyEmail(r.Context(), req.Token); err != nil {
+		if errors.Is(err, auth.ErrInvalidToken) {
+			writeError(l.WithError(err), w, http.StatusBadRequest, "invalid or expired verification token")
+			return
+		}
+
+		writeInternalError(l.WithError(err), w, "fail to verify email")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *server) resendVerificationHandler(w http.ResponseWriter, r *http.Request) {
+	l := getLogger(r)
+
+	var req userEmail
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(l.WithError(err), w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := validate(&req); err != nil {
+		writeError(l.WithError(err), w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := s.a.ResendVerification(r.Context(), req.Email); err != nil {
+		if errors.Is(err, auth.ErrRateLimited) {
+			writeError(l.WithError(err), w, http.StatusTooManyRequests, "verification email has been sent recently")
+			return
+		}
+
+		writeInternalError(l.WithError(err), w, "fail to resend verification email")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *server) getRolesHandler(w http.ResponseWriter, r *http.Request) {
+	l := getLogger(r)
+
+	roles, err := s.a.GetRoles(r.Context())
+	if err != nil {
+		writeInternalError(l.WithError(err), w, "fail to get roles")
+		return
+	}
+
+	resp := make([]role, len(roles))
+	for i, r := range roles {
+		resp[i] = fromRoleModel(r)
+	}
+
+	writeOK(l, w, resp)
+}
+
+func (s *server) setUserRolesHandler(w http.ResponseWriter, r *http.Request) {
 	l := getLogger(r)
 
 	id, err := getIDFromURL(r, "id")
@@ -130,7 +409,7 @@ func (s *server) updateUserPermissionsHandler(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	var req userPermissions
+	var req userRoles
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		writeError(l.WithError(err), w, http.StatusBadRequest, err.Error())
 		return
@@ -141,18 +420,55 @@ func (s *server) updateUserPermissionsHandler(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	user := req.toModel()
-	user.ID = id
-
-	if err := s.a.UpdateUserPermissions(r.Context(), user); err != nil {
+	if err := s.a.SetUserRoles(r.Context(), id, req.Roles); err != nil {
 		switch {
 		case errors.Is(err, auth.ErrNotFound):
 			writeError(l.WithError(err), w, http.StatusNotFound, "user not found")
+		case errors.Is(err, auth.ErrUnknownRole):
+			writeError(l.WithError(err), w, http.StatusBadRequest, "unknown role")
 		default:
-			writeInternalError(l.WithError(err), w, "fail to update user permissions")
+			writeInternalError(l.WithError(err), w, "fail to set user roles")
 		}
 		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+func (s *server) unlockUserHandler(w http.ResponseWriter, r *http.Request) {
+	l := getLogger(r)
+
+	userID, err := getIDFromURL(r, "id")
+	if err != nil {
+		writeError(l.WithError(err), w, http.StatusBadRequest, "invalid user ID")
+		return
+	}
+
+	if err := s.a.UnlockUser(r.Context(), userID); err != nil {
+		if errors.Is(err, auth.ErrNotFound) {
+			writeError(l.WithError(err), w, http.StatusNotFound, "user not found")
+			return
+		}
+
+		writeInternalError(l.WithError(err), w, "fail to unlock user")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *server) getJWKSHandler(w http.ResponseWriter, r *http.Request) {
+	l := getLogger(r)
+
+	keys := s.a.PublicKeys()
+
+	resp := jwks{Keys: make([]jwk, len(keys))}
+	for i, k := range keys {
+		resp.Keys[i] = fromPublicKeyModel(k)
+	}
+
+	// let verifiers cache keys but pick up rotated ones soon
+	w.Header().Set("Cache-Control", "public, max-age=300")
+
+	writeOK(l, w, resp)
+}
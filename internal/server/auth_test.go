@@ -2,8 +2,11 @@ package server
 
 import (
 	"context"
+	"crypto/ed25519"
+	"crypto/rsa"
 	"fmt"
 	"io/ioutil"
+	"math/big"
 	"net/http"
 	"testing"
 
@@ -11,6 +14,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/vliubezny/gstore/internal/auth"
 	"github.com/vliubezny/gstore/internal/model"
+	"github.com/vliubezny/gstore/internal/service"
 )
 
 func Test_registerHandler(t *testing.T) {
@@ -30,7 +34,7 @@ func Test_registerHandler(t *testing.T) {
 			err:      nil,
 			input:    `{"email":"admin@test.com", "password":"testP@ss"}`,
 			rcode:    http.StatusOK,
-			rdata:    `{"id":1, "email":"admin@test.com", "isAdmin":false}`,
+			rdata:    `{"id":1, "email":"admin@test.com", "emailVerified":false, "roles":[]}`,
 		},
 		{
 			desc:     "invalid: missing email",
@@ -108,6 +112,16 @@ func Test_loginHandler(t *testing.T) {
 			rcode:    http.StatusOK,
 			rdata:    `{"accessToken":"testAccess", "refreshToken":"testRefresh"}`,
 		},
+		{
+			desc:     "mfa required",
+			email:    "admin@test.com",
+			password: "testP@ss",
+			tokens:   auth.TokenPair{MFAToken: "testMFA"},
+			err:      nil,
+			input:    `{"email":"admin@test.com", "password":"testP@ss"}`,
+			rcode:    http.StatusOK,
+			rdata:    `{"mfaRequired":true, "mfaToken":"testMFA"}`,
+		},
 		{
 			desc:     "invalid credentials",
 			email:    "admin@test.com",
@@ -118,6 +132,36 @@ func Test_loginHandler(t *testing.T) {
 			rcode:    http.StatusUnauthorized,
 			rdata:    `{"error":"invalid username or password"}`,
 		},
+		{
+			desc:     "email not verified",
+			email:    "admin@test.com",
+			password: "testP@ss",
+			tokens:   auth.TokenPair{},
+			err:      auth.ErrEmailNotVerified,
+			input:    `{"email":"admin@test.com", "password":"testP@ss"}`,
+			rcode:    http.StatusForbidden,
+			rdata:    `{"error":"email address is not verified"}`,
+		},
+		{
+			desc:     "account locked",
+			email:    "admin@test.com",
+			password: "testP@ss",
+			tokens:   auth.TokenPair{},
+			err:      auth.ErrAccountLocked,
+			input:    `{"email":"admin@test.com", "password":"testP@ss"}`,
+			rcode:    http.StatusTooManyRequests,
+			rdata:    `{"error":"account is temporarily locked"}`,
+		},
+		{
+			desc:     "too many attempts",
+			email:    "admin@test.com",
+			password: "testP@ss",
+			tokens:   auth.TokenPair{},
+			err:      auth.ErrTooManyAttempts,
+			input:    `{"email":"admin@test.com", "password":"testP@ss"}`,
+			rcode:    http.StatusTooManyRequests,
+			rdata:    `{"error":"too many login attempts, try again later"}`,
+		},
 		{
 			desc:     "internal error",
 			email:    "admin@test.com",
@@ -136,7 +180,7 @@ func Test_loginHandler(t *testing.T) {
 
 			svc := auth.NewMockService(ctrl)
 			if tC.err != errSkip {
-				svc.EXPECT().Login(gomock.Any(), tC.email, tC.password).Return(tC.tokens, tC.err)
+				svc.EXPECT().Login(gomock.Any(), tC.email, tC.password, gomock.Any()).Return(tC.tokens, tC.err)
 			}
 
 			router := setupTestRouterWithAuth(nil, svc)
@@ -152,6 +196,72 @@ func Test_loginHandler(t *testing.T) {
 	}
 }
 
+func Test_loginHandler_mergeCart(t *testing.T) {
+	testCases := []struct {
+		desc   string
+		vErr   error
+		mErr   error
+		merged bool
+	}{
+		{
+			desc:   "success",
+			vErr:   nil,
+			mErr:   nil,
+			merged: true,
+		},
+		{
+			desc:   "invalid access token",
+			vErr:   auth.ErrInvalidToken,
+			mErr:   errSkip,
+			merged: false,
+		},
+		{
+			desc:   "merge error",
+			vErr:   nil,
+			mErr:   assert.AnError,
+			merged: false,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			tokens := auth.TokenPair{AccessToken: "testAccess", RefreshToken: "testRefresh"}
+
+			svc := auth.NewMockService(ctrl)
+			svc.EXPECT().Login(gomock.Any(), "admin@test.com", "testP@ss", gomock.Any()).Return(tokens, nil)
+			svc.EXPECT().ValidateAccessToken("testAccess").Return(auth.AccessTokenClaims{UserID: 2}, tC.vErr)
+
+			cartSvc := service.NewMockCartService(ctrl)
+			if tC.mErr != errSkip {
+				cartSvc.EXPECT().MergeCart(gomock.Any(), testCartToken, int64(2)).Return(tC.mErr)
+			}
+
+			router := setupTestRouterWithCart(nil, svc, cartSvc)
+			rec, r := newTestParameters(http.MethodPost, "/v1/login", `{"email":"admin@test.com", "password":"testP@ss"}`)
+			r.AddCookie(&http.Cookie{Name: cartTokenCookie, Value: testCartToken})
+
+			router.ServeHTTP(rec, r)
+
+			body, _ := ioutil.ReadAll(rec.Result().Body)
+
+			assert.Equal(t, http.StatusOK, rec.Result().StatusCode)
+			assert.JSONEq(t, `{"accessToken":"testAccess", "refreshToken":"testRefresh"}`, string(body))
+
+			cookies := rec.Result().Cookies()
+			if tC.merged {
+				if assert.Len(t, cookies, 1) {
+					assert.Equal(t, cartTokenCookie, cookies[0].Name)
+					assert.True(t, cookies[0].MaxAge < 0)
+				}
+			} else {
+				assert.Empty(t, cookies)
+			}
+		})
+	}
+}
+
 func Test_refreshHandler(t *testing.T) {
 	testCases := []struct {
 		desc   string
@@ -201,7 +311,7 @@ func Test_refreshHandler(t *testing.T) {
 
 			svc := auth.NewMockService(ctrl)
 			if tC.err != errSkip {
-				svc.EXPECT().Refresh(gomock.Any(), tC.token).Return(tC.tokens, tC.err)
+				svc.EXPECT().Refresh(gomock.Any(), tC.token, gomock.Any()).Return(tC.tokens, tC.err)
 			}
 
 			router := setupTestRouterWithAuth(nil, svc)
@@ -291,58 +401,224 @@ func Test_revokeHandler(t *testing.T) {
 	}
 }
 
-func Test_updateUserPermissionsHandler(t *testing.T) {
+func Test_forgotPasswordHandler(t *testing.T) {
 	testCases := []struct {
 		desc  string
-		id    string
-		user  model.User
+		input string
 		err   error
+		rcode int
+		rdata string
+	}{
+		{
+			desc:  "success",
+			input: `{"email":"admin@test.com"}`,
+			err:   nil,
+			rcode: http.StatusNoContent,
+			rdata: "",
+		},
+		{
+			desc:  "invalid email",
+			input: `{"email":"admin"}`,
+			err:   errSkip,
+			rcode: http.StatusBadRequest,
+			rdata: `{"error":"email must be a valid email address"}`,
+		},
+		{
+			desc:  "internal error",
+			input: `{"email":"admin@test.com"}`,
+			err:   assert.AnError,
+			rcode: http.StatusInternalServerError,
+			rdata: `{"error":"internal error"}`,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			svc := auth.NewMockService(ctrl)
+			if tC.err != errSkip {
+				svc.EXPECT().ForgotPassword(gomock.Any(), "admin@test.com").Return(tC.err)
+			}
+
+			router := setupTestRouterWithAuth(nil, svc)
+			rec, r := newTestParameters(http.MethodPost, "/v1/password/forgot", tC.input)
+
+			router.ServeHTTP(rec, r)
+
+			body, _ := ioutil.ReadAll(rec.Result().Body)
+
+			assert.Equal(t, tC.rcode, rec.Result().StatusCode)
+			if tC.rdata == "" {
+				assert.Empty(t, string(body))
+			} else {
+				assert.JSONEq(t, tC.rdata, string(body))
+			}
+		})
+	}
+}
+
+func Test_resetPasswordHandler(t *testing.T) {
+	testCases := []struct {
+		desc  string
 		input string
+		err   error
 		rcode int
 		rdata string
 	}{
 		{
 			desc:  "success",
-			id:    "1",
-			user:  model.User{ID: 1, IsAdmin: true},
+			input: `{"token":"testToken", "password":"testP@ss"}`,
 			err:   nil,
-			input: `{"isAdmin":true}`,
 			rcode: http.StatusNoContent,
-			rdata: ``,
+			rdata: "",
 		},
 		{
-			desc:  "invalid id",
-			id:    "test",
-			user:  model.User{ID: 1, IsAdmin: true},
+			desc:  "short password",
+			input: `{"token":"testToken", "password":"test"}`,
 			err:   errSkip,
-			input: `{"isAdmin":true}`,
 			rcode: http.StatusBadRequest,
-			rdata: `{"error":"invalid user ID"}`,
+			rdata: `{"error":"password must be at least 8 characters in length"}`,
 		},
 		{
-			desc:  "invalid payload",
-			id:    "1",
-			user:  model.User{ID: 1, IsAdmin: true},
+			desc:  "invalid token",
+			input: `{"token":"testToken", "password":"testP@ss"}`,
+			err:   auth.ErrInvalidToken,
+			rcode: http.StatusBadRequest,
+			rdata: `{"error":"invalid or expired reset token"}`,
+		},
+		{
+			desc:  "internal error",
+			input: `{"token":"testToken", "password":"testP@ss"}`,
+			err:   assert.AnError,
+			rcode: http.StatusInternalServerError,
+			rdata: `{"error":"internal error"}`,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			svc := auth.NewMockService(ctrl)
+			if tC.err != errSkip {
+				svc.EXPECT().ResetPassword(gomock.Any(), "testToken", "testP@ss").Return(tC.err)
+			}
+
+			router := setupTestRouterWithAuth(nil, svc)
+			rec, r := newTestParameters(http.MethodPost, "/v1/password/reset", tC.input)
+
+			router.ServeHTTP(rec, r)
+
+			body, _ := ioutil.ReadAll(rec.Result().Body)
+
+			assert.Equal(t, tC.rcode, rec.Result().StatusCode)
+			if tC.rdata == "" {
+				assert.Empty(t, string(body))
+			} else {
+				assert.JSONEq(t, tC.rdata, string(body))
+			}
+		})
+	}
+}
+
+func Test_verifyEmailHandler(t *testing.T) {
+	testCases := []struct {
+		desc  string
+		input string
+		err   error
+		rcode int
+		rdata string
+	}{
+		{
+			desc:  "success",
+			input: `{"token":"testToken"}`,
+			err:   nil,
+			rcode: http.StatusNoContent,
+			rdata: "",
+		},
+		{
+			desc:  "missing token",
+			input: `{}`,
 			err:   errSkip,
-			input: `{"admin":true}`,
 			rcode: http.StatusBadRequest,
-			rdata: `{"error":"isAdmin is a required field"}`,
+			rdata: `{"error":"token is a required field"}`,
 		},
 		{
-			desc:  "user not found",
-			id:    "1",
-			user:  model.User{ID: 1, IsAdmin: true},
-			err:   auth.ErrNotFound,
-			input: `{"isAdmin":true}`,
-			rcode: http.StatusNotFound,
-			rdata: `{"error":"user not found"}`,
+			desc:  "invalid token",
+			input: `{"token":"testToken"}`,
+			err:   auth.ErrInvalidToken,
+			rcode: http.StatusBadRequest,
+			rdata: `{"error":"invalid or expired verification token"}`,
 		},
 		{
 			desc:  "internal error",
-			id:    "1",
-			user:  model.User{ID: 1, IsAdmin: true},
+			input: `{"token":"testToken"}`,
+			err:   assert.AnError,
+			rcode: http.StatusInternalServerError,
+			rdata: `{"error":"internal error"}`,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			svc := auth.NewMockService(ctrl)
+			if tC.err != errSkip {
+				svc.EXPECT().VerifyEmail(gomock.Any(), "testToken").Return(tC.err)
+			}
+
+			router := setupTestRouterWithAuth(nil, svc)
+			rec, r := newTestParameters(http.MethodPost, "/v1/verify-email", tC.input)
+
+			router.ServeHTTP(rec, r)
+
+			body, _ := ioutil.ReadAll(rec.Result().Body)
+
+			assert.Equal(t, tC.rcode, rec.Result().StatusCode)
+			if tC.rdata == "" {
+				assert.Empty(t, string(body))
+			} else {
+				assert.JSONEq(t, tC.rdata, string(body))
+			}
+		})
+	}
+}
+
+func Test_resendVerificationHandler(t *testing.T) {
+	testCases := []struct {
+		desc  string
+		input string
+		err   error
+		rcode int
+		rdata string
+	}{
+		{
+			desc:  "success",
+			input: `{"email":"admin@test.com"}`,
+			err:   nil,
+			rcode: http.StatusNoContent,
+			rdata: "",
+		},
+		{
+			desc:  "invalid email",
+			input: `{"email":"admin"}`,
+			err:   errSkip,
+			rcode: http.StatusBadRequest,
+			rdata: `{"error":"email must be a valid email address"}`,
+		},
+		{
+			desc:  "rate limited",
+			input: `{"email":"admin@test.com"}`,
+			err:   auth.ErrRateLimited,
+			rcode: http.StatusTooManyRequests,
+			rdata: `{"error":"verification email has been sent recently"}`,
+		},
+		{
+			desc:  "internal error",
+			input: `{"email":"admin@test.com"}`,
 			err:   assert.AnError,
-			input: `{"isAdmin":true}`,
 			rcode: http.StatusInternalServerError,
 			rdata: `{"error":"internal error"}`,
 		},
@@ -354,11 +630,11 @@ func Test_updateUserPermissionsHandler(t *testing.T) {
 
 			svc := auth.NewMockService(ctrl)
 			if tC.err != errSkip {
-				svc.EXPECT().UpdateUserPermissions(gomock.Any(), tC.user).Return(tC.err)
+				svc.EXPECT().ResendVerification(gomock.Any(), "admin@test.com").Return(tC.err)
 			}
 
 			router := setupTestRouterWithAuth(nil, svc)
-			rec, r := newTestParameters(http.MethodPut, fmt.Sprintf("/v1/users/%s/permissions", tC.id), tC.input)
+			rec, r := newTestParameters(http.MethodPost, "/v1/verify-email/resend", tC.input)
 
 			router.ServeHTTP(rec, r)
 
@@ -373,3 +649,522 @@ func Test_updateUserPermissionsHandler(t *testing.T) {
 		})
 	}
 }
+
+func Test_getRolesHandler(t *testing.T) {
+	testCases := []struct {
+		desc  string
+		roles []model.Role
+		err   error
+		rcode int
+		rdata string
+	}{
+		{
+			desc: "success",
+			roles: []model.Role{
+				{Name: model.RoleSupport, Permissions: []model.Permission{model.PermissionOrderRead, model.PermissionOrderWrite}},
+			},
+			err:   nil,
+			rcode: http.StatusOK,
+			rdata: `[{"name":"support", "permissions":["order:read", "order:write"]}]`,
+		},
+		{
+			desc:  "internal error",
+			err:   assert.AnError,
+			rcode: http.StatusInternalServerError,
+			rdata: `{"error":"internal error"}`,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			svc := auth.NewMockService(ctrl)
+			svc.EXPECT().GetRoles(gomock.Any()).Return(tC.roles, tC.err)
+
+			router := setupTestRouterWithAuth(nil, svc)
+			rec, r := newTestParameters(http.MethodGet, "/v1/roles", "")
+
+			router.ServeHTTP(rec, r)
+
+			body, _ := ioutil.ReadAll(rec.Result().Body)
+
+			assert.Equal(t, tC.rcode, rec.Result().StatusCode)
+			assert.JSONEq(t, tC.rdata, string(body))
+		})
+	}
+}
+
+func Test_setUserRolesHandler(t *testing.T) {
+	roles := []string{model.RoleSupport}
+
+	testCases := []struct {
+		desc  string
+		id    string
+		err   error
+		input string
+		rcode int
+		rdata string
+	}{
+		{
+			desc:  "success",
+			id:    "1",
+			err:   nil,
+			input: `{"roles":["support"]}`,
+			rcode: http.StatusNoContent,
+			rdata: ``,
+		},
+		{
+			desc:  "invalid id",
+			id:    "test",
+			err:   errSkip,
+			input: `{"roles":["support"]}`,
+			rcode: http.StatusBadRequest,
+			rdata: `{"error":"invalid user ID"}`,
+		},
+		{
+			desc:  "invalid payload",
+			id:    "1",
+			err:   errSkip,
+			input: `{"isAdmin":true}`,
+			rcode: http.StatusBadRequest,
+			rdata: `{"error":"roles is a required field"}`,
+		},
+		{
+			desc:  "user not found",
+			id:    "1",
+			err:   auth.ErrNotFound,
+			input: `{"roles":["support"]}`,
+			rcode: http.StatusNotFound,
+			rdata: `{"error":"user not found"}`,
+		},
+		{
+			desc:  "unknown role",
+			id:    "1",
+			err:   auth.ErrUnknownRole,
+			input: `{"roles":["support"]}`,
+			rcode: http.StatusBadRequest,
+			rdata: `{"error":"unknown role"}`,
+		},
+		{
+			desc:  "internal error",
+			id:    "1",
+			err:   assert.AnError,
+			input: `{"roles":["support"]}`,
+			rcode: http.StatusInternalServerError,
+			rdata: `{"error":"internal error"}`,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			svc := auth.NewMockService(ctrl)
+			if tC.err != errSkip {
+				svc.EXPECT().SetUserRoles(gomock.Any(), int64(1), roles).Return(tC.err)
+			}
+
+			router := setupTestRouterWithAuth(nil, svc)
+			rec, r := newTestParameters(http.MethodPut, fmt.Sprintf("/v1/users/%s/roles", tC.id), tC.input)
+
+			router.ServeHTTP(rec, r)
+
+			body, _ := ioutil.ReadAll(rec.Result().Body)
+
+			assert.Equal(t, tC.rcode, rec.Result().StatusCode)
+			if tC.rdata == "" {
+				assert.Empty(t, string(body))
+			} else {
+				assert.JSONEq(t, tC.rdata, string(body))
+			}
+		})
+	}
+}
+
+func Test_unlockUserHandler(t *testing.T) {
+	testCases := []struct {
+		desc  string
+		id    string
+		err   error
+		rcode int
+		rdata string
+	}{
+		{
+			desc:  "success",
+			id:    "1",
+			err:   nil,
+			rcode: http.StatusNoContent,
+			rdata: "",
+		},
+		{
+			desc:  "invalid user ID",
+			id:    "test",
+			err:   errSkip,
+			rcode: http.StatusBadRequest,
+			rdata: `{"error":"invalid user ID"}`,
+		},
+		{
+			desc:  "user not found",
+			id:    "1",
+			err:   auth.ErrNotFound,
+			rcode: http.StatusNotFound,
+			rdata: `{"error":"user not found"}`,
+		},
+		{
+			desc:  "internal error",
+			id:    "1",
+			err:   assert.AnError,
+			rcode: http.StatusInternalServerError,
+			rdata: `{"error":"internal error"}`,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			svc := auth.NewMockService(ctrl)
+			if tC.err != errSkip {
+				svc.EXPECT().UnlockUser(gomock.Any(), int64(1)).Return(tC.err)
+			}
+
+			router := setupTestRouterWithAuth(nil, svc)
+			rec, r := newTestParameters(http.MethodDelete, fmt.Sprintf("/v1/users/%s/lockout", tC.id), "")
+
+			router.ServeHTTP(rec, r)
+
+			body, _ := ioutil.ReadAll(rec.Result().Body)
+
+			assert.Equal(t, tC.rcode, rec.Result().StatusCode)
+			if tC.rdata == "" {
+				assert.Empty(t, string(body))
+			} else {
+				assert.JSONEq(t, tC.rdata, string(body))
+			}
+		})
+	}
+}
+
+func Test_loginMFAHandler(t *testing.T) {
+	testCases := []struct {
+		desc   string
+		tokens auth.TokenPair
+		err    error
+		input  string
+		rcode  int
+		rdata  string
+	}{
+		{
+			desc:   "success",
+			tokens: auth.TokenPair{AccessToken: "testAccess", RefreshToken: "testRefresh"},
+			err:    nil,
+			input:  `{"mfaToken":"testMFA", "code":"123456"}`,
+			rcode:  http.StatusOK,
+			rdata:  `{"accessToken":"testAccess", "refreshToken":"testRefresh"}`,
+		},
+		{
+			desc:   "missing code",
+			tokens: auth.TokenPair{},
+			err:    errSkip,
+			input:  `{"mfaToken":"testMFA"}`,
+			rcode:  http.StatusBadRequest,
+			rdata:  `{"error":"code is a required field"}`,
+		},
+		{
+			desc:   "invalid token",
+			tokens: auth.TokenPair{},
+			err:    auth.ErrInvalidToken,
+			input:  `{"mfaToken":"testMFA", "code":"123456"}`,
+			rcode:  http.StatusUnauthorized,
+			rdata:  `{"error":"invalid mfa token"}`,
+		},
+		{
+			desc:   "invalid code",
+			tokens: auth.TokenPair{},
+			err:    auth.ErrInvalidMFACode,
+			input:  `{"mfaToken":"testMFA", "code":"123456"}`,
+			rcode:  http.StatusUnauthorized,
+			rdata:  `{"error":"invalid mfa code"}`,
+		},
+		{
+			desc:   "account locked",
+			tokens: auth.TokenPair{},
+			err:    auth.ErrAccountLocked,
+			input:  `{"mfaToken":"testMFA", "code":"123456"}`,
+			rcode:  http.StatusTooManyRequests,
+			rdata:  `{"error":"account is temporarily locked"}`,
+		},
+		{
+			desc:   "too many attempts",
+			tokens: auth.TokenPair{},
+			err:    auth.ErrTooManyAttempts,
+			input:  `{"mfaToken":"testMFA", "code":"123456"}`,
+			rcode:  http.StatusTooManyRequests,
+			rdata:  `{"error":"too many login attempts, try again later"}`,
+		},
+		{
+			desc:   "internal error",
+			tokens: auth.TokenPair{},
+			err:    assert.AnError,
+			input:  `{"mfaToken":"testMFA", "code":"123456"}`,
+			rcode:  http.StatusInternalServerError,
+			rdata:  `{"error":"internal error"}`,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			svc := auth.NewMockService(ctrl)
+			if tC.err != errSkip {
+				svc.EXPECT().VerifyMFA(gomock.Any(), "testMFA", "123456", gomock.Any()).Return(tC.tokens, tC.err)
+			}
+
+			router := setupTestRouterWithAuth(nil, svc)
+			rec, r := newTestParameters(http.MethodPost, "/v1/login/mfa", tC.input)
+
+			router.ServeHTTP(rec, r)
+
+			body, _ := ioutil.ReadAll(rec.Result().Body)
+
+			assert.Equal(t, tC.rcode, rec.Result().StatusCode)
+			assert.JSONEq(t, tC.rdata, string(body))
+		})
+	}
+}
+
+func Test_enrollMFAHandler(t *testing.T) {
+	testCases := []struct {
+		desc       string
+		enrollment auth.MFAEnrollment
+		err        error
+		rcode      int
+		rdata      string
+	}{
+		{
+			desc:       "success",
+			enrollment: auth.MFAEnrollment{Secret: "SECRET", URI: "otpauth://totp/test"},
+			err:        nil,
+			rcode:      http.StatusOK,
+			rdata:      `{"secret":"SECRET", "uri":"otpauth://totp/test"}`,
+		},
+		{
+			desc:  "user not found",
+			err:   auth.ErrNotFound,
+			rcode: http.StatusNotFound,
+			rdata: `{"error":"user not found"}`,
+		},
+		{
+			desc:  "mfa enabled",
+			err:   auth.ErrMFAEnabled,
+			rcode: http.StatusConflict,
+			rdata: `{"error":"mfa is already enabled"}`,
+		},
+		{
+			desc:  "internal error",
+			err:   assert.AnError,
+			rcode: http.StatusInternalServerError,
+			rdata: `{"error":"internal error"}`,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			svc := auth.NewMockService(ctrl)
+			svc.EXPECT().EnrollMFA(gomock.Any(), testSuperadminClaims.UserID).Return(tC.enrollment, tC.err)
+
+			router := setupTestRouterWithAuth(nil, svc)
+			rec, r := newTestParameters(http.MethodPost, "/v1/mfa/enroll", "")
+
+			router.ServeHTTP(rec, r)
+
+			body, _ := ioutil.ReadAll(rec.Result().Body)
+
+			assert.Equal(t, tC.rcode, rec.Result().StatusCode)
+			assert.JSONEq(t, tC.rdata, string(body))
+		})
+	}
+}
+
+func Test_confirmMFAHandler(t *testing.T) {
+	testCases := []struct {
+		desc  string
+		codes []string
+		err   error
+		input string
+		rcode int
+		rdata string
+	}{
+		{
+			desc:  "success",
+			codes: []string{"aaaaa-aaaaa", "bbbbb-bbbbb"},
+			err:   nil,
+			input: `{"code":"123456"}`,
+			rcode: http.StatusOK,
+			rdata: `{"recoveryCodes":["aaaaa-aaaaa", "bbbbb-bbbbb"]}`,
+		},
+		{
+			desc:  "missing code",
+			err:   errSkip,
+			input: `{}`,
+			rcode: http.StatusBadRequest,
+			rdata: `{"error":"code is a required field"}`,
+		},
+		{
+			desc:  "invalid code",
+			err:   auth.ErrInvalidMFACode,
+			input: `{"code":"123456"}`,
+			rcode: http.StatusBadRequest,
+			rdata: `{"error":"invalid mfa code"}`,
+		},
+		{
+			desc:  "mfa enabled",
+			err:   auth.ErrMFAEnabled,
+			input: `{"code":"123456"}`,
+			rcode: http.StatusConflict,
+			rdata: `{"error":"mfa is already enabled"}`,
+		},
+		{
+			desc:  "mfa not enrolled",
+			err:   auth.ErrMFANotEnrolled,
+			input: `{"code":"123456"}`,
+			rcode: http.StatusConflict,
+			rdata: `{"error":"mfa enrollment is not started"}`,
+		},
+		{
+			desc:  "internal error",
+			err:   assert.AnError,
+			input: `{"code":"123456"}`,
+			rcode: http.StatusInternalServerError,
+			rdata: `{"error":"internal error"}`,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			svc := auth.NewMockService(ctrl)
+			if tC.err != errSkip {
+				svc.EXPECT().ConfirmMFA(gomock.Any(), testSuperadminClaims.UserID, "123456").Return(tC.codes, tC.err)
+			}
+
+			router := setupTestRouterWithAuth(nil, svc)
+			rec, r := newTestParameters(http.MethodPost, "/v1/mfa/confirm", tC.input)
+
+			router.ServeHTTP(rec, r)
+
+			body, _ := ioutil.ReadAll(rec.Result().Body)
+
+			assert.Equal(t, tC.rcode, rec.Result().StatusCode)
+			assert.JSONEq(t, tC.rdata, string(body))
+		})
+	}
+}
+
+func Test_disableMFAHandler(t *testing.T) {
+	testCases := []struct {
+		desc  string
+		err   error
+		input string
+		rcode int
+		rdata string
+	}{
+		{
+			desc:  "success",
+			err:   nil,
+			input: `{"code":"123456"}`,
+			rcode: http.StatusNoContent,
+			rdata: "",
+		},
+		{
+			desc:  "missing code",
+			err:   errSkip,
+			input: `{}`,
+			rcode: http.StatusBadRequest,
+			rdata: `{"error":"code is a required field"}`,
+		},
+		{
+			desc:  "invalid code",
+			err:   auth.ErrInvalidMFACode,
+			input: `{"code":"123456"}`,
+			rcode: http.StatusBadRequest,
+			rdata: `{"error":"invalid mfa code"}`,
+		},
+		{
+			desc:  "mfa not enabled",
+			err:   auth.ErrMFANotEnrolled,
+			input: `{"code":"123456"}`,
+			rcode: http.StatusConflict,
+			rdata: `{"error":"mfa is not enabled"}`,
+		},
+		{
+			desc:  "too many attempts",
+			err:   auth.ErrTooManyAttempts,
+			input: `{"code":"123456"}`,
+			rcode: http.StatusTooManyRequests,
+			rdata: `{"error":"too many attempts, try again later"}`,
+		},
+		{
+			desc:  "internal error",
+			err:   assert.AnError,
+			input: `{"code":"123456"}`,
+			rcode: http.StatusInternalServerError,
+			rdata: `{"error":"internal error"}`,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			svc := auth.NewMockService(ctrl)
+			if tC.err != errSkip {
+				svc.EXPECT().DisableMFA(gomock.Any(), testSuperadminClaims.UserID, "123456", gomock.Any()).Return(tC.err)
+			}
+
+			router := setupTestRouterWithAuth(nil, svc)
+			rec, r := newTestParameters(http.MethodPost, "/v1/mfa/disable", tC.input)
+
+			router.ServeHTTP(rec, r)
+
+			body, _ := ioutil.ReadAll(rec.Result().Body)
+
+			assert.Equal(t, tC.rcode, rec.Result().StatusCode)
+			if tC.rdata == "" {
+				assert.Empty(t, string(body))
+			} else {
+				assert.JSONEq(t, tC.rdata, string(body))
+			}
+		})
+	}
+}
+
+func Test_getJWKSHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	svc := auth.NewMockService(ctrl)
+	svc.EXPECT().PublicKeys().Return([]auth.PublicKey{
+		{ID: "key1", Algorithm: "RS256", Key: &rsa.PublicKey{N: big.NewInt(0xabcdef), E: 65537}},
+		{ID: "key2", Algorithm: "EdDSA", Key: ed25519.PublicKey{0xde, 0xad, 0xbe, 0xef}},
+	})
+
+	router := setupTestRouterWithAuth(nil, svc)
+	rec, r := newTestParameters(http.MethodGet, "/.well-known/jwks.json", "")
+
+	router.ServeHTTP(rec, r)
+
+	body, _ := ioutil.ReadAll(rec.Result().Body)
+
+	assert.Equal(t, http.StatusOK, rec.Result().StatusCode)
+	assert.Equal(t, "public, max-age=300", rec.Result().Header.Get("Cache-Control"))
+	assert.JSONEq(t, `{"keys":[
+		{"kty":"RSA", "kid":"key1", "use":"sig", "alg":"RS256", "n":"q83v", "e":"AQAB"},
+		{"kty":"OKP", "kid":"key2", "use":"sig", "alg":"EdDSA", "crv":"Ed25519", "x":"3q2-7w"}
+	]}`, string(body))
+}
@@ -0,0 +1,203 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/vliubezny/gstore/internal/model"
+	"github.com/vliubezny/gstore/internal/service"
+)
+
+const (
+	// cartTokenCookie holds identifier of anonymous cart.
+	cartTokenCookie = "cart_token"
+	cartTokenMaxAge = 30 * 24 * 60 * 60
+)
+
+func (s *server) getCartHandler(w http.ResponseWriter, r *http.Request) {
+	l := getLogger(r)
+
+	currency, err := getCurrencyFromURL(r)
+	if err != nil {
+		writeError(l.WithError(err), w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	owner, _ := getCartOwner(r)
+
+	c, err := s.c.GetCart(r.Context(), owner, currency)
+	if err != nil {
+		if errors.Is(err, service.ErrUnknownCurrency) {
+			writeError(l.WithError(err), w, http.StatusBadRequest, "unknown currency")
+			return
+		}
+
+		writeInternalError(l.WithError(err), w, "fail to get cart")
+		return
+	}
+
+	writeOK(l, w, fromCartModel(c))
+}
+
+func (s *server) setCartItemHandler(w http.ResponseWriter, r *http.Request) {
+	l := getLogger(r)
+
+	storeID, err := getIDFromURL(r, "storeId")
+	if err != nil {
+		writeError(l.WithError(err), w, http.StatusBadRequest, "invalid store ID")
+		return
+	}
+
+	variantID, err := getIDFromURL(r, "variantId")
+	if err != nil {
+		writeError(l.WithError(err), w, http.StatusBadRequest, "invalid variant ID")
+		return
+	}
+
+	var req cartItemQuantity
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(l.WithError(err), w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := validate(&req); err != nil {
+		writeError(l.WithError(err), w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	owner, ok := getCartOwner(r)
+	if !ok {
+		owner = model.CartOwner{Token: uuid.New().String()}
+		setCartToken(w, owner.Token, cartTokenMaxAge)
+	}
+
+	if err := s.c.SetCartItem(r.Context(), owner, variantID, storeID, req.Quantity); err != nil {
+		switch {
+		case errors.Is(err, service.ErrUnknownPosition):
+			writeError(l.WithError(err), w, http.StatusNotFound, "position not found")
+		case errors.Is(err, service.ErrInsufficientStock):
+			writeError(l.WithError(err), w, http.StatusConflict, "insufficient stock")
+		case errors.Is(err, service.ErrInvalidCartItem):
+			writeError(l.WithError(err), w, http.StatusBadRequest, err.Error())
+		default:
+			writeInternalError(l.WithError(err), w, "fail to set cart item")
+		}
+		return
+	}
+
+	c, err := s.c.GetCart(r.Context(), owner, "")
+	if err != nil {
+		writeInternalError(l.WithError(err), w, "fail to get cart")
+		return
+	}
+
+	writeOK(l, w, fromCartModel(c))
+}
+
+func (s *server) deleteCartItemHandler(w http.ResponseWriter, r *http.Request) {
+	l := getLogger(r)
+
+	storeID, err := getIDFromURL(r, "storeId")
+	if err != nil {
+		writeError(l.WithError(err), w, http.StatusBadRequest, "invalid store ID")
+		return
+	}
+
+	variantID, err := getIDFromURL(r, "variantId")
+	if err != nil {
+		writeError(l.WithError(err), w, http.StatusBadRequest, "invalid variant ID")
+		return
+	}
+
+	owner, ok := getCartOwner(r)
+	if !ok {
+		writeError(l, w, http.StatusNotFound, "cart item not found")
+		return
+	}
+
+	if err := s.c.RemoveCartItem(r.Context(), owner, variantID, storeID); err != nil {
+		if errors.Is(err, service.ErrNotFound) {
+			writeError(l.WithError(err), w, http.StatusNotFound, "cart item not found")
+			return
+		}
+
+		writeInternalError(l.WithError(err), w, "fail to remove cart item")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *server) clearCartHandler(w http.ResponseWriter, r *http.Request) {
+	l := getLogger(r)
+
+	owner, ok := getCartOwner(r)
+	if ok {
+		if err := s.c.ClearCart(r.Context(), owner); err != nil {
+			writeInternalError(l.WithError(err), w, "fail to clear cart")
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// mergeCart moves anonymous cart into the cart of just logged in user,
+// failure is only logged since it must not prevent login.
+func (s *server) mergeCart(l logrus.FieldLogger, w http.ResponseWriter, r *http.Request, token, accessToken string) {
+	claims, err := s.a.ValidateAccessToken(accessToken)
+	if err != nil {
+		l.WithError(err).Error("fail to validate issued access token")
+		return
+	}
+
+	if err := s.c.MergeCart(r.Context(), token, claims.UserID); err != nil {
+		l.WithError(err).Error("fail to merge cart")
+		return
+	}
+
+	setCartToken(w, "", -1)
+}
+
+// getCartOwner returns owner of the cart, authenticated users own carts by ID
+// while anonymous carts are identified by cookie token.
+func getCartOwner(r *http.Request) (model.CartOwner, bool) {
+	if userID := getClaims(r).UserID; userID != 0 {
+		return model.CartOwner{UserID: userID}, true
+	}
+
+	if token := getCartToken(r); token != "" {
+		return model.CartOwner{Token: token}, true
+	}
+
+	return model.CartOwner{}, false
+}
+
+// getCartToken returns token of anonymous cart, malformed tokens are ignored.
+func getCartToken(r *http.Request) string {
+	c, err := r.Cookie(cartTokenCookie)
+	if err != nil {
+		return ""
+	}
+
+	token, err := uuid.Parse(c.Value)
+	if err != nil {
+		return ""
+	}
+
+	return token.String()
+}
+
+func setCartToken(w http.ResponseWriter, token string, maxAge int) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     cartTokenCookie,
+		Value:    token,
+		Path:     "/",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
@@ -0,0 +1,440 @@
+package server
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vliubezny/gstore/internal/model"
+	"github.com/vliubezny/gstore/internal/service"
+)
+
+const testCartToken = "8a7b6f2e-3c1d-4e5f-9a0b-1c2d3e4f5a6b"
+
+var testCart = model.Cart{
+	Items: []model.CartItem{
+		{
+			ProductID: 1, VariantID: 2, StoreID: 3, Quantity: 2,
+			Price: decimal.NewFromInt(90), Currency: "USD",
+			Position: model.Position{
+				ProductID: 1, VariantID: 2, StoreID: 3, Price: decimal.NewFromInt(100), Currency: "USD",
+				Quantity: 5, Availability: model.AvailabilityInStock,
+			},
+			PriceChanged: true,
+			Available:    true,
+		},
+		{
+			ProductID: 4, VariantID: 5, StoreID: 3, Quantity: 1,
+			Price: decimal.NewFromInt(10), Currency: "EUR",
+		},
+	},
+	Currency: "USD",
+	Total:    decimal.NewFromInt(200),
+}
+
+const testCartJSON = `{"items":[
+	{"productId":1, "variantId":2, "storeId":3, "quantity":2, "price":100, "currency":"USD",
+		"addedPrice":90, "addedCurrency":"USD", "priceChanged":true, "available":true},
+	{"productId":4, "variantId":5, "storeId":3, "quantity":1, "price":null,
+		"addedPrice":10, "addedCurrency":"EUR", "priceChanged":false, "available":false}
+], "currency":"USD", "total":200}`
+
+func Test_getCartHandler(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		anon     bool
+		cookie   string
+		query    string
+		owner    model.CartOwner
+		currency string
+		cart     model.Cart
+		err      error
+		rcode    int
+		rdata    string
+	}{
+		{
+			desc:     "success",
+			query:    "?currency=eur",
+			owner:    model.CartOwner{UserID: 1},
+			currency: "EUR",
+			cart:     testCart,
+			err:      nil,
+			rcode:    http.StatusOK,
+			rdata:    testCartJSON,
+		},
+		{
+			desc:   "success anonymous",
+			anon:   true,
+			cookie: testCartToken,
+			owner:  model.CartOwner{Token: testCartToken},
+			cart:   testCart,
+			err:    nil,
+			rcode:  http.StatusOK,
+			rdata:  testCartJSON,
+		},
+		{
+			desc:   "success anonymous without cart",
+			anon:   true,
+			cookie: "malformed",
+			owner:  model.CartOwner{},
+			cart:   model.Cart{Currency: "USD", Total: decimal.Zero},
+			err:    nil,
+			rcode:  http.StatusOK,
+			rdata:  `{"items":[], "currency":"USD", "total":0}`,
+		},
+		{
+			desc:  "invalid currency",
+			query: "?currency=dollar",
+			err:   errSkip,
+			rcode: http.StatusBadRequest,
+			rdata: `{"error":"currency must be an ISO 4217 code"}`,
+		},
+		{
+			desc:     "unknown currency",
+			query:    "?currency=GBP",
+			owner:    model.CartOwner{UserID: 1},
+			currency: "GBP",
+			err:      service.ErrUnknownCurrency,
+			rcode:    http.StatusBadRequest,
+			rdata:    `{"error":"unknown currency"}`,
+		},
+		{
+			desc:  "internal error",
+			owner: model.CartOwner{UserID: 1},
+			err:   errTest,
+			rcode: http.StatusInternalServerError,
+			rdata: `{"error":"internal error"}`,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			svc := service.NewMockCartService(ctrl)
+			if tC.err != errSkip {
+				svc.EXPECT().GetCart(gomock.Any(), tC.owner, tC.currency).Return(tC.cart, tC.err)
+			}
+
+			router := setupTestRouterWithCart(nil, nil, svc)
+			rec, r := newTestParameters(http.MethodGet, "/v1/cart"+tC.query, "")
+			if tC.anon {
+				r.Header.Del("Authorization")
+			}
+			if tC.cookie != "" {
+				r.AddCookie(&http.Cookie{Name: cartTokenCookie, Value: tC.cookie})
+			}
+
+			router.ServeHTTP(rec, r)
+
+			body, _ := ioutil.ReadAll(rec.Result().Body)
+
+			assert.Equal(t, tC.rcode, rec.Result().StatusCode)
+			assert.JSONEq(t, tC.rdata, string(body))
+		})
+	}
+}
+
+func Test_setCartItemHandler(t *testing.T) {
+	testCases := []struct {
+		desc      string
+		anon      bool
+		storeID   string
+		variantID string
+		input     string
+		quantity  int64
+		err       error
+		rcode     int
+		rdata     string
+	}{
+		{
+			desc:      "success",
+			storeID:   "3",
+			variantID: "2",
+			input:     `{"quantity":2}`,
+			quantity:  2,
+			err:       nil,
+			rcode:     http.StatusOK,
+			rdata:     testCartJSON,
+		},
+		{
+			desc:      "success anonymous",
+			anon:      true,
+			storeID:   "3",
+			variantID: "2",
+			input:     `{"quantity":2}`,
+			quantity:  2,
+			err:       nil,
+			rcode:     http.StatusOK,
+			rdata:     testCartJSON,
+		},
+		{
+			desc:      "invalid store ID",
+			storeID:   "test",
+			variantID: "2",
+			input:     `{"quantity":2}`,
+			err:       errSkip,
+			rcode:     http.StatusBadRequest,
+			rdata:     `{"error":"invalid store ID"}`,
+		},
+		{
+			desc:      "invalid variant ID",
+			storeID:   "3",
+			variantID: "test",
+			input:     `{"quantity":2}`,
+			err:       errSkip,
+			rcode:     http.StatusBadRequest,
+			rdata:     `{"error":"invalid variant ID"}`,
+		},
+		{
+			desc:      "invalid input",
+			storeID:   "3",
+			variantID: "2",
+			input:     `{"quantity":"2"}`,
+			err:       errSkip,
+			rcode:     http.StatusBadRequest,
+			rdata:     `{"error":"json: cannot unmarshal string into Go struct field cartItemQuantity.quantity of type int64"}`,
+		},
+		{
+			desc:      "invalid quantity",
+			storeID:   "3",
+			variantID: "2",
+			input:     `{"quantity":0}`,
+			err:       errSkip,
+			rcode:     http.StatusBadRequest,
+			rdata:     `{"error":"quantity must be greater than 0"}`,
+		},
+		{
+			desc:      "ErrUnknownPosition",
+			storeID:   "3",
+			variantID: "2",
+			input:     `{"quantity":2}`,
+			quantity:  2,
+			err:       service.ErrUnknownPosition,
+			rcode:     http.StatusNotFound,
+			rdata:     `{"error":"position not found"}`,
+		},
+		{
+			desc:      "ErrInsufficientStock",
+			storeID:   "3",
+			variantID: "2",
+			input:     `{"quantity":2}`,
+			quantity:  2,
+			err:       service.ErrInsufficientStock,
+			rcode:     http.StatusConflict,
+			rdata:     `{"error":"insufficient stock"}`,
+		},
+		{
+			desc:      "ErrInvalidCartItem",
+			storeID:   "3",
+			variantID: "2",
+			input:     `{"quantity":100}`,
+			quantity:  100,
+			err:       fmt.Errorf("%w: quantity must be between 1 and 99", service.ErrInvalidCartItem),
+			rcode:     http.StatusBadRequest,
+			rdata:     `{"error":"invalid cart item: quantity must be between 1 and 99"}`,
+		},
+		{
+			desc:      "internal error",
+			storeID:   "3",
+			variantID: "2",
+			input:     `{"quantity":2}`,
+			quantity:  2,
+			err:       errTest,
+			rcode:     http.StatusInternalServerError,
+			rdata:     `{"error":"internal error"}`,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			var owner interface{} = model.CartOwner{UserID: 1}
+			if tC.anon {
+				owner = gomock.Any()
+			}
+
+			svc := service.NewMockCartService(ctrl)
+			if tC.err != errSkip {
+				svc.EXPECT().SetCartItem(gomock.Any(), owner, int64(2), int64(3), tC.quantity).Return(tC.err)
+			}
+			if tC.err == nil {
+				svc.EXPECT().GetCart(gomock.Any(), owner, "").Return(testCart, nil)
+			}
+
+			router := setupTestRouterWithCart(nil, nil, svc)
+			rec, r := newTestParameters(http.MethodPut, fmt.Sprintf("/v1/cart/items/%s/%s", tC.storeID, tC.variantID), tC.input)
+			if tC.anon {
+				r.Header.Del("Authorization")
+			}
+
+			router.ServeHTTP(rec, r)
+
+			body, _ := ioutil.ReadAll(rec.Result().Body)
+
+			assert.Equal(t, tC.rcode, rec.Result().StatusCode)
+			assert.JSONEq(t, tC.rdata, string(body))
+
+			if tC.anon {
+				cookies := rec.Result().Cookies()
+				require.Len(t, cookies, 1)
+				assert.Equal(t, cartTokenCookie, cookies[0].Name)
+				assert.NotEmpty(t, cookies[0].Value)
+			}
+		})
+	}
+}
+
+func Test_deleteCartItemHandler(t *testing.T) {
+	testCases := []struct {
+		desc      string
+		anon      bool
+		storeID   string
+		variantID string
+		err       error
+		rcode     int
+		rdata     string
+	}{
+		{
+			desc:      "success",
+			storeID:   "3",
+			variantID: "2",
+			err:       nil,
+			rcode:     http.StatusNoContent,
+			rdata:     "",
+		},
+		{
+			desc:      "invalid store ID",
+			storeID:   "test",
+			variantID: "2",
+			err:       errSkip,
+			rcode:     http.StatusBadRequest,
+			rdata:     `{"error":"invalid store ID"}`,
+		},
+		{
+			desc:      "invalid variant ID",
+			storeID:   "3",
+			variantID: "test",
+			err:       errSkip,
+			rcode:     http.StatusBadRequest,
+			rdata:     `{"error":"invalid variant ID"}`,
+		},
+		{
+			desc:      "anonymous without cart",
+			anon:      true,
+			storeID:   "3",
+			variantID: "2",
+			err:       errSkip,
+			rcode:     http.StatusNotFound,
+			rdata:     `{"error":"cart item not found"}`,
+		},
+		{
+			desc:      "not found",
+			storeID:   "3",
+			variantID: "2",
+			err:       service.ErrNotFound,
+			rcode:     http.StatusNotFound,
+			rdata:     `{"error":"cart item not found"}`,
+		},
+		{
+			desc:      "internal error",
+			storeID:   "3",
+			variantID: "2",
+			err:       errTest,
+			rcode:     http.StatusInternalServerError,
+			rdata:     `{"error":"internal error"}`,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			svc := service.NewMockCartService(ctrl)
+			if tC.err != errSkip {
+				svc.EXPECT().RemoveCartItem(gomock.Any(), model.CartOwner{UserID: 1}, int64(2), int64(3)).Return(tC.err)
+			}
+
+			router := setupTestRouterWithCart(nil, nil, svc)
+			rec, r := newTestParameters(http.MethodDelete, fmt.Sprintf("/v1/cart/items/%s/%s", tC.storeID, tC.variantID), "")
+			if tC.anon {
+				r.Header.Del("Authorization")
+			}
+
+			router.ServeHTTP(rec, r)
+
+			body, _ := ioutil.ReadAll(rec.Result().Body)
+
+			assert.Equal(t, tC.rcode, rec.Result().StatusCode)
+			if tC.rdata == "" {
+				assert.Empty(t, string(body))
+			} else {
+				assert.JSONEq(t, tC.rdata, string(body))
+			}
+		})
+	}
+}
+
+func Test_clearCartHandler(t *testing.T) {
+	testCases := []struct {
+		desc  string
+		anon  bool
+		err   error
+		rcode int
+		rdata string
+	}{
+		{
+			desc:  "success",
+			err:   nil,
+			rcode: http.StatusNoContent,
+			rdata: "",
+		},
+		{
+			desc:  "anonymous without cart",
+			anon:  true,
+			err:   errSkip,
+			rcode: http.StatusNoContent,
+			rdata: "",
+		},
+		{
+			desc:  "internal error",
+			err:   errTest,
+			rcode: http.StatusInternalServerError,
+			rdata: `{"error":"internal error"}`,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			svc := service.NewMockCartService(ctrl)
+			if tC.err != errSkip {
+				svc.EXPECT().ClearCart(gomock.Any(), model.CartOwner{UserID: 1}).Return(tC.err)
+			}
+
+			router := setupTestRouterWithCart(nil, nil, svc)
+			rec, r := newTestParameters(http.MethodDelete, "/v1/cart", "")
+			if tC.anon {
+				r.Header.Del("Authorization")
+			}
+
+			router.ServeHTTP(rec, r)
+
+			body, _ := ioutil.ReadAll(rec.Result().Body)
+
+			assert.Equal(t, tC.rcode, rec.Result().StatusCode)
+			if tC.rdata == "" {
+				assert.Empty(t, string(body))
+			} else {
+				assert.JSONEq(t, tC.rdata, string(body))
+			}
+		})
+	}
+}
@@ -1,18 +1,37 @@
 package server
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
 	"net/http"
+	"time"
 
+	"github.com/go-chi/chi"
+	"github.com/sirupsen/logrus"
+	"github.com/vliubezny/gstore/internal/model"
 	"github.com/vliubezny/gstore/internal/service"
 )
 
 func (s *server) getCategoriesHandler(w http.ResponseWriter, r *http.Request) {
 	l := getLogger(r)
 
-	categories, err := s.s.GetCategories(r.Context())
+	pg, err := getPageFromURL(r)
 	if err != nil {
+		writeError(l.WithError(err), w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	categories, next, err := s.s.GetCategories(r.Context(), pg)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidCursor) {
+			writeError(l.WithError(err), w, http.StatusBadRequest, "invalid cursor")
+			return
+		}
+
 		writeInternalError(l.WithError(err), w, "fail to get categories")
 		return
 	}
@@ -23,7 +42,7 @@ func (s *server) getCategoriesHandler(w http.ResponseWriter, r *http.Request) {
 		resp[i] = fromCategoryModel(c)
 	}
 
-	writeOK(l, w, resp)
+	writeOK(l, w, page{Items: resp, NextCursor: next})
 }
 
 func (s *server) getCategoryHandler(w http.ResponseWriter, r *http.Request) {
@@ -49,6 +68,47 @@ func (s *server) getCategoryHandler(w http.ResponseWriter, r *http.Request) {
 	writeOK(l, w, fromCategoryModel(c))
 }
 
+func (s *server) getCategoryTreeHandler(w http.ResponseWriter, r *http.Request) {
+	l := getLogger(r)
+
+	nodes, err := s.s.GetCategoryTree(r.Context())
+	if err != nil {
+		writeInternalError(l.WithError(err), w, "fail to get category tree")
+		return
+	}
+
+	resp := make([]categoryNode, len(nodes))
+
+	for i, n := range nodes {
+		resp[i] = fromCategoryNodeModel(n)
+	}
+
+	writeOK(l, w, resp)
+}
+
+func (s *server) getCategorySubtreeHandler(w http.ResponseWriter, r *http.Request) {
+	l := getLogger(r)
+
+	categoryID, err := getIDFromURL(r, "id")
+	if err != nil {
+		writeError(l.WithError(err), w, http.StatusBadRequest, "invalid category ID")
+		return
+	}
+
+	node, err := s.s.GetCategorySubtree(r.Context(), categoryID)
+	if err != nil {
+		if errors.Is(err, service.ErrNotFound) {
+			writeError(l.WithError(err), w, http.StatusNotFound, "category not found")
+			return
+		}
+
+		writeInternalError(l.WithError(err), w, "fail to get category subtree")
+		return
+	}
+
+	writeOK(l, w, fromCategoryNodeModel(node))
+}
+
 func (s *server) createCategoryHandler(w http.ResponseWriter, r *http.Request) {
 	l := getLogger(r)
 
@@ -65,7 +125,14 @@ func (s *server) createCategoryHandler(w http.ResponseWriter, r *http.Request) {
 
 	c, err := s.s.CreateCategory(r.Context(), req.toModel())
 	if err != nil {
-		writeInternalError(l.WithError(err), w, "fail to create category")
+		switch {
+		case errors.Is(err, service.ErrUnknownCategory):
+			writeError(l.WithError(err), w, http.StatusBadRequest, "unknown parent category")
+		case errors.Is(err, service.ErrInvalidSchema):
+			writeError(l.WithError(err), w, http.StatusBadRequest, err.Error())
+		default:
+			writeInternalError(l.WithError(err), w, "fail to create category")
+		}
 		return
 	}
 
@@ -96,12 +163,18 @@ func (s *server) updateCategoryHandler(w http.ResponseWriter, r *http.Request) {
 	c.ID = categoryID
 
 	if err := s.s.UpdateCategory(r.Context(), c); err != nil {
-		if errors.Is(err, service.ErrNotFound) {
+		switch {
+		case errors.Is(err, service.ErrNotFound):
 			writeError(l.WithError(err), w, http.StatusNotFound, "category not found")
-			return
+		case errors.Is(err, service.ErrUnknownCategory):
+			writeError(l.WithError(err), w, http.StatusBadRequest, "unknown parent category")
+		case errors.Is(err, service.ErrCategoryCycle):
+			writeError(l.WithError(err), w, http.StatusBadRequest, "category cannot be moved into its own subtree")
+		case errors.Is(err, service.ErrInvalidSchema):
+			writeError(l.WithError(err), w, http.StatusBadRequest, err.Error())
+		default:
+			writeInternalError(l.WithError(err), w, "fail to update category")
 		}
-
-		writeInternalError(l.WithError(err), w, "fail to update category")
 		return
 	}
 
@@ -134,8 +207,19 @@ func (s *server) deleteCategoryHandler(w http.ResponseWriter, r *http.Request) {
 func (s *server) getStoresHandler(w http.ResponseWriter, r *http.Request) {
 	l := getLogger(r)
 
-	stores, err := s.s.GetStores(r.Context())
+	pg, err := getPageFromURL(r)
+	if err != nil {
+		writeError(l.WithError(err), w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	stores, next, err := s.s.GetStores(r.Context(), pg)
 	if err != nil {
+		if errors.Is(err, service.ErrInvalidCursor) {
+			writeError(l.WithError(err), w, http.StatusBadRequest, "invalid cursor")
+			return
+		}
+
 		writeInternalError(l.WithError(err), w, "fail to get stores")
 		return
 	}
@@ -146,7 +230,7 @@ func (s *server) getStoresHandler(w http.ResponseWriter, r *http.Request) {
 		resp[i] = fromStoreModel(str)
 	}
 
-	writeOK(l, w, resp)
+	writeOK(l, w, page{Items: resp, NextCursor: next})
 }
 
 func (s *server) getStoreHandler(w http.ResponseWriter, r *http.Request) {
@@ -263,8 +347,19 @@ func (s *server) getStorePositionsHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	positions, err := s.s.GetStorePositions(r.Context(), storeID)
+	pg, err := getPageFromURL(r)
 	if err != nil {
+		writeError(l.WithError(err), w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	positions, next, err := s.s.GetStorePositions(r.Context(), storeID, pg)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidCursor) {
+			writeError(l.WithError(err), w, http.StatusBadRequest, "invalid cursor")
+			return
+		}
+
 		writeInternalError(l.WithError(err), w, "fail to get store positions")
 		return
 	}
@@ -275,7 +370,7 @@ func (s *server) getStorePositionsHandler(w http.ResponseWriter, r *http.Request
 		resp[i] = fromPositionModel(p)
 	}
 
-	writeOK(l, w, resp)
+	writeOK(l, w, page{Items: resp, NextCursor: next})
 }
 
 func (s *server) setPositionHandler(w http.ResponseWriter, r *http.Request) {
@@ -287,9 +382,9 @@ func (s *server) setPositionHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	productID, err := getIDFromURL(r, "productId")
+	variantID, err := getIDFromURL(r, "variantId")
 	if err != nil {
-		writeError(l.WithError(err), w, http.StatusBadRequest, "invalid product ID")
+		writeError(l.WithError(err), w, http.StatusBadRequest, "invalid variant ID")
 		return
 	}
 
@@ -304,16 +399,24 @@ func (s *server) setPositionHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if req.RestockDate != "" && req.Availability == "" {
+		writeError(l, w, http.StatusBadRequest, "restockDate requires availability")
+		return
+	}
+
 	p := req.toModel()
-	p.ProductID = productID
+	p.VariantID = variantID
 	p.StoreID = storeID
 
-	if err := s.s.SetPosition(r.Context(), p); err != nil {
+	p, err = s.s.SetPosition(r.Context(), p, getClaims(r).UserID)
+	if err != nil {
 		switch {
-		case errors.Is(err, service.ErrUnknownProduct):
-			writeError(l.WithError(err), w, http.StatusNotFound, "product not found")
+		case errors.Is(err, service.ErrUnknownVariant):
+			writeError(l.WithError(err), w, http.StatusNotFound, "variant not found")
 		case errors.Is(err, service.ErrUnknownStore):
 			writeError(l.WithError(err), w, http.StatusNotFound, "store not found")
+		case errors.Is(err, service.ErrUnknownCurrency):
+			writeError(l.WithError(err), w, http.StatusBadRequest, "unknown currency")
 		default:
 			writeInternalError(l.WithError(err), w, "fail to set position")
 		}
@@ -323,6 +426,57 @@ func (s *server) setPositionHandler(w http.ResponseWriter, r *http.Request) {
 	writeOK(l, w, fromPositionModel(p))
 }
 
+func (s *server) increaseStockHandler(w http.ResponseWriter, r *http.Request) {
+	s.adjustStock(w, r, 1)
+}
+
+func (s *server) decreaseStockHandler(w http.ResponseWriter, r *http.Request) {
+	s.adjustStock(w, r, -1)
+}
+
+// adjustStock changes position quantity by requested amount in direction of sign.
+func (s *server) adjustStock(w http.ResponseWriter, r *http.Request, sign int64) {
+	l := getLogger(r)
+
+	storeID, err := getIDFromURL(r, "id")
+	if err != nil {
+		writeError(l.WithError(err), w, http.StatusBadRequest, "invalid store ID")
+		return
+	}
+
+	variantID, err := getIDFromURL(r, "variantId")
+	if err != nil {
+		writeError(l.WithError(err), w, http.StatusBadRequest, "invalid variant ID")
+		return
+	}
+
+	var req stockChange
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(l.WithError(err), w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := validate(&req); err != nil {
+		writeError(l.WithError(err), w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	p, err := s.s.AdjustStock(r.Context(), variantID, storeID, sign*req.Quantity)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrNotFound):
+			writeError(l.WithError(err), w, http.StatusNotFound, "position not found")
+		case errors.Is(err, service.ErrInsufficientStock):
+			writeError(l.WithError(err), w, http.StatusConflict, "insufficient stock")
+		default:
+			writeInternalError(l.WithError(err), w, "fail to adjust stock")
+		}
+		return
+	}
+
+	writeOK(l, w, fromPositionModel(p))
+}
+
 func (s *server) deletePositionHandler(w http.ResponseWriter, r *http.Request) {
 	l := getLogger(r)
 
@@ -332,20 +486,20 @@ func (s *server) deletePositionHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	productID, err := getIDFromURL(r, "productId")
+	variantID, err := getIDFromURL(r, "variantId")
 	if err != nil {
-		writeError(l.WithError(err), w, http.StatusBadRequest, "invalid product ID")
+		writeError(l.WithError(err), w, http.StatusBadRequest, "invalid variant ID")
 		return
 	}
 
-	err = s.s.DeletePosition(r.Context(), productID, storeID)
+	err = s.s.DeletePosition(r.Context(), variantID, storeID)
 	if err != nil {
 		if errors.Is(err, service.ErrNotFound) {
-			writeError(l.WithError(err), w, http.StatusNotFound, "product not found")
+			writeError(l.WithError(err), w, http.StatusNotFound, "position not found")
 			return
 		}
 
-		writeInternalError(l.WithError(err), w, "fail to delete product")
+		writeInternalError(l.WithError(err), w, "fail to delete position")
 		return
 	}
 
@@ -361,9 +515,30 @@ func (s *server) getCategoryProductsHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	products, err := s.s.GetProducts(r.Context(), categoryID)
+	filter, err := getProductFilterFromURL(r)
 	if err != nil {
-		writeInternalError(l.WithError(err), w, "fail to get products")
+		writeError(l.WithError(err), w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	pg, err := getPageFromURL(r)
+	if err != nil {
+		writeError(l.WithError(err), w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	products, next, err := s.s.GetProducts(r.Context(), categoryID, filter, pg)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidCursor):
+			writeError(l.WithError(err), w, http.StatusBadRequest, "invalid cursor")
+		case errors.Is(err, service.ErrInvalidAttributes):
+			writeError(l.WithError(err), w, http.StatusBadRequest, err.Error())
+		case errors.Is(err, service.ErrNotFound):
+			writeError(l.WithError(err), w, http.StatusNotFound, "category not found")
+		default:
+			writeInternalError(l.WithError(err), w, "fail to get products")
+		}
 		return
 	}
 
@@ -373,6 +548,76 @@ func (s *server) getCategoryProductsHandler(w http.ResponseWriter, r *http.Reque
 		resp[i] = fromProductModel(p)
 	}
 
+	writeOK(l, w, page{Items: resp, NextCursor: next})
+}
+
+func (s *server) searchProductsHandler(w http.ResponseWriter, r *http.Request) {
+	l := getLogger(r)
+
+	search, err := getProductSearchFromURL(r)
+	if err != nil {
+		writeError(l.WithError(err), w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	pg, err := getPageFromURL(r)
+	if err != nil {
+		writeError(l.WithError(err), w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	results, next, err := s.s.SearchProducts(r.Context(), search, pg)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidCursor) {
+			writeError(l.WithError(err), w, http.StatusBadRequest, "invalid cursor")
+			return
+		}
+
+		writeInternalError(l.WithError(err), w, "fail to search products")
+		return
+	}
+
+	resp := make([]productSearchResult, len(results))
+
+	for i, res := range results {
+		resp[i] = fromProductSearchResultModel(res)
+	}
+
+	writeOK(l, w, page{Items: resp, NextCursor: next})
+}
+
+func (s *server) compareProductPricesHandler(w http.ResponseWriter, r *http.Request) {
+	l := getLogger(r)
+
+	ids, err := getProductIDsFromURL(r)
+	if err != nil {
+		writeError(l.WithError(err), w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	currency, err := getCurrencyFromURL(r)
+	if err != nil {
+		writeError(l.WithError(err), w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	summaries, err := s.s.ComparePrices(r.Context(), ids, currency)
+	if err != nil {
+		if errors.Is(err, service.ErrUnknownCurrency) {
+			writeError(l.WithError(err), w, http.StatusBadRequest, "unknown currency")
+			return
+		}
+
+		writeInternalError(l.WithError(err), w, "fail to compare prices")
+		return
+	}
+
+	resp := make([]offerSummary, len(summaries))
+
+	for i, s := range summaries {
+		resp[i] = fromPriceSummaryModel(s)
+	}
+
 	writeOK(l, w, resp)
 }
 
@@ -415,9 +660,12 @@ func (s *server) createProductHandler(w http.ResponseWriter, r *http.Request) {
 
 	p, err := s.s.CreateProduct(r.Context(), req.toModel())
 	if err != nil {
-		if errors.Is(err, service.ErrUnknownCategory) {
+		switch {
+		case errors.Is(err, service.ErrUnknownCategory):
 			writeError(l.WithError(err), w, http.StatusBadRequest, "unknown category")
-		} else {
+		case errors.Is(err, service.ErrInvalidAttributes):
+			writeError(l.WithError(err), w, http.StatusBadRequest, err.Error())
+		default:
 			writeInternalError(l.WithError(err), w, "fail to create product")
 		}
 		return
@@ -455,6 +703,8 @@ func (s *server) updateProductHandler(w http.ResponseWriter, r *http.Request) {
 			writeError(l.WithError(err), w, http.StatusNotFound, "product not found")
 		case errors.Is(err, service.ErrUnknownCategory):
 			writeError(l.WithError(err), w, http.StatusBadRequest, "unknown category")
+		case errors.Is(err, service.ErrInvalidAttributes):
+			writeError(l.WithError(err), w, http.StatusBadRequest, err.Error())
 		default:
 			writeInternalError(l.WithError(err), w, "fail to update product")
 		}
@@ -496,8 +746,35 @@ func (s *server) getProductOffersHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	positions, err := s.s.GetProductPositions(r.Context(), productID)
+	pg, err := getPageFromURL(r)
+	if err != nil {
+		writeError(l.WithError(err), w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	f, err := getPositionFilterFromURL(r)
+	if err != nil {
+		writeError(l.WithError(err), w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	currency, err := getCurrencyFromURL(r)
+	if err != nil {
+		writeError(l.WithError(err), w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	positions, next, err := s.s.GetProductPositions(r.Context(), productID, f, currency, pg)
 	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidCursor):
+			writeError(l.WithError(err), w, http.StatusBadRequest, "invalid cursor")
+			return
+		case errors.Is(err, service.ErrUnknownCurrency):
+			writeError(l.WithError(err), w, http.StatusBadRequest, "unknown currency")
+			return
+		}
+
 		writeInternalError(l.WithError(err), w, "fail to get product offers")
 		return
 	}
@@ -508,5 +785,439 @@ func (s *server) getProductOffersHandler(w http.ResponseWriter, r *http.Request)
 		resp[i] = fromPositionModel(p)
 	}
 
+	writeOK(l, w, page{Items: resp, NextCursor: next})
+}
+
+func (s *server) getPriceHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	l := getLogger(r)
+
+	productID, err := getIDFromURL(r, "id")
+	if err != nil {
+		writeError(l.WithError(err), w, http.StatusBadRequest, "invalid product ID")
+		return
+	}
+
+	f, err := getPriceHistoryFilterFromURL(r, time.Now().UTC())
+	if err != nil {
+		writeError(l.WithError(err), w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var resp interface{}
+	switch r.URL.Query().Get("interval") {
+	case "":
+		var changes []model.PriceChange
+		if changes, err = s.s.GetPriceHistory(r.Context(), productID, f); err == nil {
+			items := make([]priceChange, len(changes))
+			for i, c := range changes {
+				items[i] = fromPriceChangeModel(c)
+			}
+			resp = items
+		}
+	case "day":
+		var buckets []model.PriceBucket
+		if buckets, err = s.s.GetDailyPriceHistory(r.Context(), productID, f); err == nil {
+			items := make([]priceBucket, len(buckets))
+			for i, b := range buckets {
+				items[i] = fromPriceBucketModel(b)
+			}
+			resp = items
+		}
+	default:
+		writeError(l, w, http.StatusBadRequest, "interval must be one of [day]")
+		return
+	}
+
+	if err != nil {
+		if errors.Is(err, service.ErrNotFound) {
+			writeError(l.WithError(err), w, http.StatusNotFound, "product not found")
+			return
+		}
+
+		writeInternalError(l.WithError(err), w, "fail to get price history")
+		return
+	}
+
+	writeOK(l, w, resp)
+}
+
+func (s *server) addProductImageHandler(w http.ResponseWriter, r *http.Request) {
+	l := getLogger(r)
+
+	productID, err := getIDFromURL(r, "id")
+	if err != nil {
+		writeError(l.WithError(err), w, http.StatusBadRequest, "invalid product ID")
+		return
+	}
+
+	if r.ContentLength > maxImageRequestSize {
+		writeError(l, w, http.StatusRequestEntityTooLarge, fmt.Sprintf("image must be at maximum %d MB", maxImageSize>>20))
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxImageRequestSize)
+
+	file, _, err := r.FormFile(imageFormField)
+	if err != nil {
+		switch {
+		case errors.Is(err, http.ErrNotMultipart):
+			writeError(l.WithError(err), w, http.StatusBadRequest, "request must be multipart/form-data")
+		case errors.Is(err, http.ErrMissingFile):
+			writeError(l.WithError(err), w, http.StatusBadRequest, imageFormField+" file is required")
+		default:
+			writeError(l.WithError(err), w, http.StatusBadRequest, "malformed multipart form")
+		}
+		return
+	}
+	defer file.Close()
+
+	data, err := ioutil.ReadAll(io.LimitReader(file, maxImageSize+1))
+	if err != nil {
+		writeError(l.WithError(err), w, http.StatusBadRequest, "malformed multipart form")
+		return
+	}
+
+	if len(data) > maxImageSize {
+		writeError(l, w, http.StatusRequestEntityTooLarge, fmt.Sprintf("image must be at maximum %d MB", maxImageSize>>20))
+		return
+	}
+
+	img, err := s.s.AddProductImage(r.Context(), productID, data)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrUnknownProduct):
+			writeError(l.WithError(err), w, http.StatusNotFound, "product not found")
+		case errors.Is(err, service.ErrUnsupportedImage):
+			writeError(l.WithError(err), w, http.StatusUnsupportedMediaType, err.Error())
+		default:
+			writeInternalError(l.WithError(err), w, "fail to add product image")
+		}
+		return
+	}
+
+	writeOK(l, w, fromImageModel(img))
+}
+
+func (s *server) setProductImageOrderHandler(w http.ResponseWriter, r *http.Request) {
+	l := getLogger(r)
+
+	productID, err := getIDFromURL(r, "id")
+	if err != nil {
+		writeError(l.WithError(err), w, http.StatusBadRequest, "invalid product ID")
+		return
+	}
+
+	var req imageOrder
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(l.WithError(err), w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := validate(&req); err != nil {
+		writeError(l.WithError(err), w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := s.s.SetProductImageOrder(r.Context(), productID, req.ImageIDs); err != nil {
+		if errors.Is(err, service.ErrInvalidImageOrder) {
+			writeError(l.WithError(err), w, http.StatusBadRequest, err.Error())
+		} else {
+			writeInternalError(l.WithError(err), w, "fail to set product image order")
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *server) setPrimaryProductImageHandler(w http.ResponseWriter, r *http.Request) {
+	l := getLogger(r)
+
+	productID, err := getIDFromURL(r, "id")
+	if err != nil {
+		writeError(l.WithError(err), w, http.StatusBadRequest, "invalid product ID")
+		return
+	}
+
+	imageID, err := getIDFromURL(r, "imageId")
+	if err != nil {
+		writeError(l.WithError(err), w, http.StatusBadRequest, "invalid image ID")
+		return
+	}
+
+	if err := s.s.SetPrimaryProductImage(r.Context(), productID, imageID); err != nil {
+		switch {
+		case errors.Is(err, service.ErrNotFound):
+			writeError(l.WithError(err), w, http.StatusNotFound, "image not found")
+		case errors.Is(err, service.ErrInvalidImageOrder):
+			writeError(l.WithError(err), w, http.StatusConflict, err.Error())
+		default:
+			writeInternalError(l.WithError(err), w, "fail to set primary product image")
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *server) deleteProductImageHandler(w http.ResponseWriter, r *http.Request) {
+	l := getLogger(r)
+
+	productID, err := getIDFromURL(r, "id")
+	if err != nil {
+		writeError(l.WithError(err), w, http.StatusBadRequest, "invalid product ID")
+		return
+	}
+
+	imageID, err := getIDFromURL(r, "imageId")
+	if err != nil {
+		writeError(l.WithError(err), w, http.StatusBadRequest, "invalid image ID")
+		return
+	}
+
+	if err := s.s.DeleteProductImage(r.Context(), productID, imageID); err != nil {
+		if errors.Is(err, service.ErrNotFound) {
+			writeError(l.WithError(err), w, http.StatusNotFound, "image not found")
+			return
+		}
+
+		writeInternalError(l.WithError(err), w, "fail to delete product image")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *server) getProductVariantsHandler(w http.ResponseWriter, r *http.Request) {
+	l := getLogger(r)
+
+	productID, err := getIDFromURL(r, "id")
+	if err != nil {
+		writeError(l.WithError(err), w, http.StatusBadRequest, "invalid product ID")
+		return
+	}
+
+	m, err := s.s.GetProductVariants(r.Context(), productID)
+	if err != nil {
+		if errors.Is(err, service.ErrNotFound) {
+			writeError(l.WithError(err), w, http.StatusNotFound, "product not found")
+			return
+		}
+
+		writeInternalError(l.WithError(err), w, "fail to get product variants")
+		return
+	}
+
+	writeOK(l, w, fromVariantMatrixModel(m))
+}
+
+func (s *server) createVariantHandler(w http.ResponseWriter, r *http.Request) {
+	l := getLogger(r)
+
+	productID, err := getIDFromURL(r, "id")
+	if err != nil {
+		writeError(l.WithError(err), w, http.StatusBadRequest, "invalid product ID")
+		return
+	}
+
+	var req variant
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(l.WithError(err), w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := validate(&req); err != nil {
+		writeError(l.WithError(err), w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	v := req.toModel()
+	v.ID = 0
+	v.ProductID = productID
+
+	v, err = s.s.CreateVariant(r.Context(), v)
+	if err != nil {
+		writeVariantError(l.WithError(err), w, err, "fail to create variant")
+		return
+	}
+
+	writeOK(l, w, fromVariantModel(v))
+}
+
+func (s *server) updateVariantHandler(w http.ResponseWriter, r *http.Request) {
+	l := getLogger(r)
+
+	productID, err := getIDFromURL(r, "id")
+	if err != nil {
+		writeError(l.WithError(err), w, http.StatusBadRequest, "invalid product ID")
+		return
+	}
+
+	variantID, err := getIDFromURL(r, "variantId")
+	if err != nil {
+		writeError(l.WithError(err), w, http.StatusBadRequest, "invalid variant ID")
+		return
+	}
+
+	var req variant
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(l.WithError(err), w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := validate(&req); err != nil {
+		writeError(l.WithError(err), w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	v := req.toModel()
+	v.ID = variantID
+	v.ProductID = productID
+
+	if err := s.s.UpdateVariant(r.Context(), v); err != nil {
+		writeVariantError(l.WithError(err), w, err, "fail to update variant")
+		return
+	}
+
+	writeOK(l, w, fromVariantModel(v))
+}
+
+// writeVariantError writes response of failed variant modification.
+func (s *server) getExchangeRatesHandler(w http.ResponseWriter, r *http.Request) {
+	l := getLogger(r)
+
+	rates, err := s.s.GetExchangeRates(r.Context())
+	if err != nil {
+		writeInternalError(l.WithError(err), w, "fail to get exchange rates")
+		return
+	}
+
+	resp := make([]exchangeRate, len(rates))
+
+	for i, rate := range rates {
+		resp[i] = fromExchangeRateModel(rate)
+	}
+
 	writeOK(l, w, resp)
 }
+
+func (s *server) setExchangeRateHandler(w http.ResponseWriter, r *http.Request) {
+	l := getLogger(r)
+
+	var req exchangeRate
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(l.WithError(err), w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	req.Currency = chi.URLParam(r, "currency")
+
+	if err := validate(&req); err != nil {
+		writeError(l.WithError(err), w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := s.s.SetExchangeRate(r.Context(), req.toModel()); err != nil {
+		if errors.Is(err, service.ErrInvalidExchangeRate) {
+			writeError(l.WithError(err), w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		writeInternalError(l.WithError(err), w, "fail to set exchange rate")
+		return
+	}
+
+	writeOK(l, w, req)
+}
+
+func (s *server) deleteExchangeRateHandler(w http.ResponseWriter, r *http.Request) {
+	l := getLogger(r)
+
+	if err := s.s.DeleteExchangeRate(r.Context(), chi.URLParam(r, "currency")); err != nil {
+		switch {
+		case errors.Is(err, service.ErrNotFound):
+			writeError(l.WithError(err), w, http.StatusNotFound, "exchange rate not found")
+		case errors.Is(err, service.ErrInvalidExchangeRate):
+			writeError(l.WithError(err), w, http.StatusBadRequest, err.Error())
+		case errors.Is(err, service.ErrCurrencyInUse):
+			writeError(l.WithError(err), w, http.StatusConflict, "currency is used by positions")
+		default:
+			writeInternalError(l.WithError(err), w, "fail to delete exchange rate")
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *server) importExchangeRatesHandler(w http.ResponseWriter, r *http.Request) {
+	l := getLogger(r)
+
+	data, err := ioutil.ReadAll(io.LimitReader(r.Body, maxExchangeRatesSize+1))
+	if err != nil {
+		writeError(l.WithError(err), w, http.StatusBadRequest, "failed to read exchange rates")
+		return
+	}
+
+	if len(data) > maxExchangeRatesSize {
+		writeError(l, w, http.StatusRequestEntityTooLarge, fmt.Sprintf("exchange rates must be at maximum %d MB", maxExchangeRatesSize>>20))
+		return
+	}
+
+	n, err := s.s.ImportExchangeRates(r.Context(), bytes.NewReader(data))
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidExchangeRate) {
+			writeError(l.WithError(err), w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		writeInternalError(l.WithError(err), w, "fail to import exchange rates")
+		return
+	}
+
+	writeOK(l, w, exchangeRatesImport{Imported: n})
+}
+
+func writeVariantError(l logrus.FieldLogger, w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, service.ErrUnknownProduct):
+		writeError(l, w, http.StatusNotFound, "product not found")
+	case errors.Is(err, service.ErrNotFound):
+		writeError(l, w, http.StatusNotFound, "variant not found")
+	case errors.Is(err, service.ErrSKUIsTaken):
+		writeError(l, w, http.StatusBadRequest, "SKU has been already taken")
+	case errors.Is(err, service.ErrGTINIsTaken):
+		writeError(l, w, http.StatusBadRequest, "GTIN has been already taken")
+	case errors.Is(err, service.ErrInvalidVariant), errors.Is(err, service.ErrInvalidAttributes):
+		writeError(l, w, http.StatusBadRequest, err.Error())
+	default:
+		writeInternalError(l, w, message)
+	}
+}
+
+func (s *server) deleteVariantHandler(w http.ResponseWriter, r *http.Request) {
+	l := getLogger(r)
+
+	productID, err := getIDFromURL(r, "id")
+	if err != nil {
+		writeError(l.WithError(err), w, http.StatusBadRequest, "invalid product ID")
+		return
+	}
+
+	variantID, err := getIDFromURL(r, "variantId")
+	if err != nil {
+		writeError(l.WithError(err), w, http.StatusBadRequest, "invalid variant ID")
+		return
+	}
+
+	if err := s.s.DeleteVariant(r.Context(), productID, variantID); err != nil {
+		if errors.Is(err, service.ErrNotFound) {
+			writeError(l.WithError(err), w, http.StatusNotFound, "variant not found")
+			return
+		}
+
+		writeInternalError(l.WithError(err), w, "fail to delete variant")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
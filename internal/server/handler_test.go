@@ -1,12 +1,17 @@
 package server
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"mime/multipart"
 	"net/http"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/shopspring/decimal"
@@ -23,23 +28,58 @@ var (
 func Test_getCategoriesHandler(t *testing.T) {
 	testCases := []struct {
 		desc       string
+		query      string
+		page       model.Page
 		categories []model.Category
+		next       string
 		err        error
 		rcode      int
 		rdata      string
 	}{
 		{
-			desc: "success",
+			desc:  "success",
+			query: "",
+			page:  model.Page{Limit: defaultPageLimit},
 			categories: []model.Category{
 				{ID: 1, Name: "Test1"},
 				{ID: 2, Name: "Test2"},
 			},
+			next:  "next",
 			err:   nil,
 			rcode: http.StatusOK,
-			rdata: `[{"id":1, "name":"Test1"}, {"id":2, "name":"Test2"}]`,
+			rdata: `{"items":[{"id":1, "name":"Test1"}, {"id":2, "name":"Test2"}], "nextCursor":"next"}`,
+		},
+		{
+			desc:       "success: last page",
+			query:      "?limit=2&cursor=abc",
+			page:       model.Page{Limit: 2, Cursor: "abc"},
+			categories: []model.Category{{ID: 3, Name: "Test3"}},
+			next:       "",
+			err:        nil,
+			rcode:      http.StatusOK,
+			rdata:      `{"items":[{"id":3, "name":"Test3"}]}`,
+		},
+		{
+			desc:       "invalid limit",
+			query:      "?limit=101",
+			categories: nil,
+			err:        errSkip,
+			rcode:      http.StatusBadRequest,
+			rdata:      `{"error":"limit must be between 1 and 100"}`,
+		},
+		{
+			desc:       "invalid cursor",
+			query:      "?cursor=abc",
+			page:       model.Page{Limit: defaultPageLimit, Cursor: "abc"},
+			categories: nil,
+			err:        service.ErrInvalidCursor,
+			rcode:      http.StatusBadRequest,
+			rdata:      `{"error":"invalid cursor"}`,
 		},
 		{
 			desc:       "internal error",
+			query:      "",
+			page:       model.Page{Limit: defaultPageLimit},
 			categories: nil,
 			err:        errTest,
 			rcode:      http.StatusInternalServerError,
@@ -52,10 +92,12 @@ func Test_getCategoriesHandler(t *testing.T) {
 			defer ctrl.Finish()
 
 			svc := service.NewMockService(ctrl)
-			svc.EXPECT().GetCategories(gomock.Any()).Return(tC.categories, tC.err)
+			if tC.err != errSkip {
+				svc.EXPECT().GetCategories(gomock.Any(), tC.page).Return(tC.categories, tC.next, tC.err)
+			}
 
 			router := setupTestRouter(svc)
-			rec, r := newTestParameters(http.MethodGet, "/v1/categories", "")
+			rec, r := newTestParameters(http.MethodGet, "/v1/categories"+tC.query, "")
 
 			router.ServeHTTP(rec, r)
 
@@ -132,6 +174,124 @@ func Test_getCategoryHandler(t *testing.T) {
 	}
 }
 
+func Test_getCategoryTreeHandler(t *testing.T) {
+	testCases := []struct {
+		desc  string
+		nodes []model.CategoryNode
+		err   error
+		rcode int
+		rdata string
+	}{
+		{
+			desc: "success",
+			nodes: []model.CategoryNode{
+				{
+					Category: model.Category{ID: 1, Name: "Test1"},
+					Children: []model.CategoryNode{
+						{Category: model.Category{ID: 2, Name: "Test2", ParentID: 1}},
+					},
+				},
+			},
+			err:   nil,
+			rcode: http.StatusOK,
+			rdata: `[{"id":1, "name":"Test1", "children":[{"id":2, "name":"Test2", "parentId":1, "children":[]}]}]`,
+		},
+		{
+			desc:  "internal error",
+			nodes: nil,
+			err:   errTest,
+			rcode: http.StatusInternalServerError,
+			rdata: `{"error":"internal error"}`,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			svc := service.NewMockService(ctrl)
+			svc.EXPECT().GetCategoryTree(gomock.Any()).Return(tC.nodes, tC.err)
+
+			router := setupTestRouter(svc)
+			rec, r := newTestParameters(http.MethodGet, "/v1/categories/tree", "")
+
+			router.ServeHTTP(rec, r)
+
+			body, _ := ioutil.ReadAll(rec.Result().Body)
+
+			assert.Equal(t, tC.rcode, rec.Result().StatusCode)
+			assert.JSONEq(t, tC.rdata, string(body))
+		})
+	}
+}
+
+func Test_getCategorySubtreeHandler(t *testing.T) {
+	testCases := []struct {
+		desc  string
+		id    string
+		node  model.CategoryNode
+		err   error
+		rcode int
+		rdata string
+	}{
+		{
+			desc: "success",
+			id:   "1",
+			node: model.CategoryNode{
+				Category: model.Category{ID: 1, Name: "Test1"},
+				Children: []model.CategoryNode{
+					{Category: model.Category{ID: 2, Name: "Test2", ParentID: 1}},
+				},
+			},
+			err:   nil,
+			rcode: http.StatusOK,
+			rdata: `{"id":1, "name":"Test1", "children":[{"id":2, "name":"Test2", "parentId":1, "children":[]}]}`,
+		},
+		{
+			desc:  "invalid category ID",
+			id:    "test",
+			err:   errSkip,
+			rcode: http.StatusBadRequest,
+			rdata: `{"error":"invalid category ID"}`,
+		},
+		{
+			desc:  "not found",
+			id:    "1",
+			err:   service.ErrNotFound,
+			rcode: http.StatusNotFound,
+			rdata: `{"error":"category not found"}`,
+		},
+		{
+			desc:  "internal error",
+			id:    "1",
+			err:   errTest,
+			rcode: http.StatusInternalServerError,
+			rdata: `{"error":"internal error"}`,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			svc := service.NewMockService(ctrl)
+			if tC.err != errSkip {
+				svc.EXPECT().GetCategorySubtree(gomock.Any(), int64(1)).Return(tC.node, tC.err)
+			}
+
+			router := setupTestRouter(svc)
+			rec, r := newTestParameters(http.MethodGet, fmt.Sprintf("/v1/categories/%s/tree", tC.id), "")
+
+			router.ServeHTTP(rec, r)
+
+			body, _ := ioutil.ReadAll(rec.Result().Body)
+
+			assert.Equal(t, tC.rcode, rec.Result().StatusCode)
+			assert.JSONEq(t, tC.rdata, string(body))
+		})
+	}
+}
+
 func Test_createCategoryHandler(t *testing.T) {
 	testCases := []struct {
 		desc     string
@@ -157,6 +317,53 @@ func Test_createCategoryHandler(t *testing.T) {
 			rcode:    http.StatusBadRequest,
 			rdata:    `{"error":"name is a required field"}`,
 		},
+		{
+			desc:     "success with parent",
+			category: model.Category{Name: "Test1", ParentID: 2},
+			err:      nil,
+			input:    `{"name": "Test1", "parentId": 2}`,
+			rcode:    http.StatusOK,
+			rdata:    `{"id":1, "name":"Test1", "parentId":2}`,
+		},
+		{
+			desc:     "unknown parent",
+			category: model.Category{Name: "Test1", ParentID: 100},
+			err:      service.ErrUnknownCategory,
+			input:    `{"name": "Test1", "parentId": 100}`,
+			rcode:    http.StatusBadRequest,
+			rdata:    `{"error":"unknown parent category"}`,
+		},
+		{
+			desc: "success with schema",
+			category: model.Category{Name: "Test1", Schema: []model.Attribute{
+				{Name: "ram", Type: model.AttributeNumber, Unit: "GB", Required: true},
+				{Name: "color", Type: model.AttributeEnum, Values: []string{"black", "silver"}},
+			}},
+			err: nil,
+			input: `{"name": "Test1", "schema": [
+				{"name":"ram", "type":"number", "unit":"GB", "required":true},
+				{"name":"color", "type":"enum", "values":["black", "silver"]}]}`,
+			rcode: http.StatusOK,
+			rdata: `{"id":1, "name":"Test1", "schema": [
+				{"name":"ram", "type":"number", "unit":"GB", "required":true},
+				{"name":"color", "type":"enum", "values":["black", "silver"]}]}`,
+		},
+		{
+			desc:     "invalid: missing attribute type",
+			category: model.Category{},
+			input:    `{"name": "Test1", "schema": [{"name":"ram"}]}`,
+			err:      errSkip,
+			rcode:    http.StatusBadRequest,
+			rdata:    `{"error":"type is a required field"}`,
+		},
+		{
+			desc:     "invalid schema",
+			category: model.Category{Name: "Test1", Schema: []model.Attribute{{Name: "ram", Type: "size"}}},
+			err:      fmt.Errorf("%w: attribute \"ram\" has unknown type \"size\"", service.ErrInvalidSchema),
+			input:    `{"name": "Test1", "schema": [{"name":"ram", "type":"size"}]}`,
+			rcode:    http.StatusBadRequest,
+			rdata:    `{"error":"invalid attribute schema: attribute \"ram\" has unknown type \"size\""}`,
+		},
 		{
 			desc:     "internal error",
 			category: model.Category{Name: "Test1"},
@@ -238,6 +445,24 @@ func Test_updateCategoryHandler(t *testing.T) {
 			rcode:    http.StatusNotFound,
 			rdata:    `{"error":"category not found"}`,
 		},
+		{
+			desc:     "unknown parent",
+			category: model.Category{ID: 1, Name: "Test1", ParentID: 100},
+			id:       "1",
+			input:    `{"name": "Test1", "parentId": 100}`,
+			err:      service.ErrUnknownCategory,
+			rcode:    http.StatusBadRequest,
+			rdata:    `{"error":"unknown parent category"}`,
+		},
+		{
+			desc:     "cycle",
+			category: model.Category{ID: 1, Name: "Test1", ParentID: 2},
+			id:       "1",
+			input:    `{"name": "Test1", "parentId": 2}`,
+			err:      service.ErrCategoryCycle,
+			rcode:    http.StatusBadRequest,
+			rdata:    `{"error":"category cannot be moved into its own subtree"}`,
+		},
 		{
 			desc:     "internal error",
 			category: model.Category{ID: 1, Name: "Test1"},
@@ -351,7 +576,14 @@ func Test_getStoresHandler(t *testing.T) {
 			},
 			err:   nil,
 			rcode: http.StatusOK,
-			rdata: `[{"id":1, "name":"Test1"}, {"id":2, "name":"Test2"}]`,
+			rdata: `{"items":[{"id":1, "name":"Test1"}, {"id":2, "name":"Test2"}], "nextCursor":"next"}`,
+		},
+		{
+			desc:   "invalid cursor",
+			stores: nil,
+			err:    service.ErrInvalidCursor,
+			rcode:  http.StatusBadRequest,
+			rdata:  `{"error":"invalid cursor"}`,
 		},
 		{
 			desc:   "internal error",
@@ -367,7 +599,7 @@ func Test_getStoresHandler(t *testing.T) {
 			defer ctrl.Finish()
 
 			svc := service.NewMockService(ctrl)
-			svc.EXPECT().GetStores(gomock.Any()).Return(tC.stores, tC.err)
+			svc.EXPECT().GetStores(gomock.Any(), model.Page{Limit: defaultPageLimit}).Return(tC.stores, "next", tC.err)
 
 			router := setupTestRouter(svc)
 			rec, r := newTestParameters(http.MethodGet, "/v1/stores", "")
@@ -663,13 +895,23 @@ func Test_getStorePositionsHandler(t *testing.T) {
 			desc:    "success",
 			storeID: "1",
 			positions: []model.Position{
-				{ProductID: 1, StoreID: 1, Price: decimal.NewFromInt(100)},
-				{ProductID: 2, StoreID: 1, Price: decimal.NewFromInt(200)},
+				{ProductID: 1, VariantID: 1, StoreID: 1, Price: decimal.NewFromInt(100), Currency: "USD", Quantity: 5, Availability: model.AvailabilityInStock},
+				{ProductID: 2, VariantID: 3, StoreID: 1, Price: decimal.NewFromInt(200), Currency: "USD", Availability: model.AvailabilityBackorder,
+					RestockDate: time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)},
 			},
 			err:   nil,
 			rcode: http.StatusOK,
-			rdata: `[{"productId":1, "storeId":1, "price":100},
-				{"productId":2, "storeId":1, "price":200}]`,
+			rdata: `{"items":[{"productId":1, "variantId":1, "storeId":1, "price":100, "currency":"USD", "quantity":5, "availability":"in_stock"},
+				{"productId":2, "variantId":3, "storeId":1, "price":200, "currency":"USD", "quantity":0, "availability":"backorder", "restockDate":"2021-03-01"}],
+				"nextCursor":"next"}`,
+		},
+		{
+			desc:      "invalid cursor",
+			storeID:   "1",
+			positions: nil,
+			err:       service.ErrInvalidCursor,
+			rcode:     http.StatusBadRequest,
+			rdata:     `{"error":"invalid cursor"}`,
 		},
 		{
 			desc:      "internal error",
@@ -703,7 +945,7 @@ func Test_getStorePositionsHandler(t *testing.T) {
 
 			svc := service.NewMockService(ctrl)
 			if tC.err != errSkip {
-				svc.EXPECT().GetStorePositions(gomock.Any(), int64(1)).Return(tC.positions, tC.err)
+				svc.EXPECT().GetStorePositions(gomock.Any(), int64(1), model.Page{Limit: defaultPageLimit}).Return(tC.positions, "next", tC.err)
 			}
 
 			router := setupTestRouter(svc)
@@ -723,28 +965,98 @@ func Test_setPositionHandler(t *testing.T) {
 	testCases := []struct {
 		desc      string
 		position  model.Position
+		rPosition model.Position
 		err       error
 		storeID   string
-		productID string
+		variantID string
 		input     string
 		rcode     int
 		rdata     string
 	}{
 		{
 			desc:      "success",
-			position:  model.Position{StoreID: 1, ProductID: 2, Price: decimal.NewFromInt(100)},
+			position:  model.Position{StoreID: 1, VariantID: 2, Price: decimal.NewFromInt(100)},
+			rPosition: model.Position{ProductID: 3, StoreID: 1, VariantID: 2, Price: decimal.NewFromInt(100), Currency: "USD"},
 			err:       nil,
 			storeID:   "1",
-			productID: "2",
+			variantID: "2",
 			input:     `{"price": 100}`,
 			rcode:     http.StatusOK,
-			rdata:     `{"productId":2, "storeId":1, "price":100}`,
+			rdata:     `{"productId":3, "variantId":2, "storeId":1, "price":100, "currency":"USD", "quantity":0, "availability":""}`,
+		},
+		{
+			desc: "success with stock",
+			position: model.Position{StoreID: 1, VariantID: 2, Price: decimal.NewFromInt(100), Currency: "USD", Quantity: 0,
+				Availability: model.AvailabilityBackorder, RestockDate: time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)},
+			rPosition: model.Position{ProductID: 3, StoreID: 1, VariantID: 2, Price: decimal.NewFromInt(100), Currency: "USD", Quantity: 0,
+				Availability: model.AvailabilityBackorder, RestockDate: time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)},
+			err:       nil,
+			storeID:   "1",
+			variantID: "2",
+			input:     `{"price": 100, "currency": "USD", "quantity": 0, "availability": "backorder", "restockDate": "2021-03-01"}`,
+			rcode:     http.StatusOK,
+			rdata:     `{"productId":3, "variantId":2, "storeId":1, "price":100, "currency":"USD", "quantity":0, "availability":"backorder", "restockDate":"2021-03-01"}`,
+		},
+		{
+			desc:      "invalid: malformed currency",
+			storeID:   "1",
+			variantID: "2",
+			input:     `{"price": 100, "currency": "usd"}`,
+			err:       errSkip,
+			rcode:     http.StatusBadRequest,
+			rdata:     `{"error":"currency must be an uppercase string"}`,
+		},
+		{
+			desc:      "unknown currency",
+			position:  model.Position{StoreID: 1, VariantID: 2, Price: decimal.NewFromInt(100), Currency: "XYZ"},
+			storeID:   "1",
+			variantID: "2",
+			input:     `{"price": 100, "currency": "XYZ"}`,
+			err:       service.ErrUnknownCurrency,
+			rcode:     http.StatusBadRequest,
+			rdata:     `{"error":"unknown currency"}`,
+		},
+		{
+			desc:      "invalid: negative quantity",
+			storeID:   "1",
+			variantID: "2",
+			input:     `{"price": 100, "quantity": -1}`,
+			err:       errSkip,
+			rcode:     http.StatusBadRequest,
+			rdata:     `{"error":"quantity must be 0 or greater"}`,
+		},
+		{
+			desc:      "invalid: unknown availability",
+			storeID:   "1",
+			variantID: "2",
+			input:     `{"price": 100, "availability": "sold_out"}`,
+			err:       errSkip,
+			rcode:     http.StatusBadRequest,
+			rdata:     `{"error":"availability must be one of [in_stock backorder discontinued]"}`,
+		},
+		{
+			desc:      "invalid: malformed restock date",
+			storeID:   "1",
+			variantID: "2",
+			input:     `{"price": 100, "availability": "backorder", "restockDate": "01.03.2021"}`,
+			err:       errSkip,
+			rcode:     http.StatusBadRequest,
+			rdata:     `{"error":"restockDate does not match the 2006-01-02 format"}`,
+		},
+		{
+			desc:      "invalid: restock date without availability",
+			storeID:   "1",
+			variantID: "2",
+			input:     `{"price": 100, "restockDate": "2021-03-01"}`,
+			err:       errSkip,
+			rcode:     http.StatusBadRequest,
+			rdata:     `{"error":"restockDate requires availability"}`,
 		},
 		{
 			desc:      "invalid: missing name",
 			position:  model.Position{},
 			storeID:   "1",
-			productID: "2",
+			variantID: "2",
 			input:     `{"price": 0}`,
 			err:       errSkip,
 			rcode:     http.StatusBadRequest,
@@ -754,48 +1066,48 @@ func Test_setPositionHandler(t *testing.T) {
 			desc:      "invalid store ID",
 			position:  model.Position{},
 			storeID:   "test",
-			productID: "2",
+			variantID: "2",
 			input:     `{"price": 100}`,
 			err:       errSkip,
 			rcode:     http.StatusBadRequest,
 			rdata:     `{"error":"invalid store ID"}`,
 		},
 		{
-			desc:      "invalid product ID",
+			desc:      "invalid variant ID",
 			position:  model.Position{},
 			storeID:   "1",
-			productID: "test",
+			variantID: "test",
 			input:     `{"price": 100}`,
 			err:       errSkip,
 			rcode:     http.StatusBadRequest,
-			rdata:     `{"error":"invalid product ID"}`,
+			rdata:     `{"error":"invalid variant ID"}`,
 		},
 		{
 			desc:      "store not found",
-			position:  model.Position{StoreID: 1, ProductID: 2, Price: decimal.NewFromInt(100)},
+			position:  model.Position{StoreID: 1, VariantID: 2, Price: decimal.NewFromInt(100)},
 			storeID:   "1",
-			productID: "2",
+			variantID: "2",
 			input:     `{"price": 100}`,
 			err:       service.ErrUnknownStore,
 			rcode:     http.StatusNotFound,
 			rdata:     `{"error":"store not found"}`,
 		},
 		{
-			desc:      "product not found",
-			position:  model.Position{StoreID: 1, ProductID: 2, Price: decimal.NewFromInt(100)},
+			desc:      "variant not found",
+			position:  model.Position{StoreID: 1, VariantID: 2, Price: decimal.NewFromInt(100)},
 			storeID:   "1",
-			productID: "2",
+			variantID: "2",
 			input:     `{"price": 100}`,
-			err:       service.ErrUnknownProduct,
+			err:       service.ErrUnknownVariant,
 			rcode:     http.StatusNotFound,
-			rdata:     `{"error":"product not found"}`,
+			rdata:     `{"error":"variant not found"}`,
 		},
 		{
 			desc:      "internal error",
-			position:  model.Position{StoreID: 1, ProductID: 2, Price: decimal.NewFromInt(100)},
+			position:  model.Position{StoreID: 1, VariantID: 2, Price: decimal.NewFromInt(100)},
 			err:       errTest,
 			storeID:   "1",
-			productID: "2",
+			variantID: "2",
 			input:     `{"price": 100}`,
 			rcode:     http.StatusInternalServerError,
 			rdata:     `{"error":"internal error"}`,
@@ -808,11 +1120,11 @@ func Test_setPositionHandler(t *testing.T) {
 
 			svc := service.NewMockService(ctrl)
 			if tC.err != errSkip {
-				svc.EXPECT().SetPosition(gomock.Any(), tC.position).Return(tC.err)
+				svc.EXPECT().SetPosition(gomock.Any(), tC.position, int64(1)).Return(tC.rPosition, tC.err)
 			}
 
 			router := setupTestRouter(svc)
-			rec, r := newTestParameters(http.MethodPut, fmt.Sprintf("/v1/stores/%s/positions/%s", tC.storeID, tC.productID), tC.input)
+			rec, r := newTestParameters(http.MethodPut, fmt.Sprintf("/v1/stores/%s/positions/%s", tC.storeID, tC.variantID), tC.input)
 
 			router.ServeHTTP(rec, r)
 
@@ -828,7 +1140,7 @@ func Test_deletePositionHandler(t *testing.T) {
 	testCases := []struct {
 		desc      string
 		storeID   string
-		productID string
+		variantID string
 		err       error
 		rcode     int
 		rdata     string
@@ -836,7 +1148,7 @@ func Test_deletePositionHandler(t *testing.T) {
 		{
 			desc:      "success",
 			storeID:   "1",
-			productID: "2",
+			variantID: "2",
 			err:       nil,
 			rcode:     http.StatusNoContent,
 			rdata:     "",
@@ -844,31 +1156,31 @@ func Test_deletePositionHandler(t *testing.T) {
 		{
 			desc:      "invalid store ID",
 			storeID:   "test",
-			productID: "2",
+			variantID: "2",
 			err:       errSkip,
 			rcode:     http.StatusBadRequest,
 			rdata:     `{"error":"invalid store ID"}`,
 		},
 		{
-			desc:      "invalid product ID",
+			desc:      "invalid variant ID",
 			storeID:   "1",
-			productID: "test",
+			variantID: "test",
 			err:       errSkip,
 			rcode:     http.StatusBadRequest,
-			rdata:     `{"error":"invalid product ID"}`,
+			rdata:     `{"error":"invalid variant ID"}`,
 		},
 		{
 			desc:      "not found",
 			storeID:   "1",
-			productID: "2",
+			variantID: "2",
 			err:       service.ErrNotFound,
 			rcode:     http.StatusNotFound,
-			rdata:     `{"error":"product not found"}`,
+			rdata:     `{"error":"position not found"}`,
 		},
 		{
 			desc:      "internal error",
 			storeID:   "1",
-			productID: "2",
+			variantID: "2",
 			err:       errTest,
 			rcode:     http.StatusInternalServerError,
 			rdata:     `{"error":"internal error"}`,
@@ -885,7 +1197,7 @@ func Test_deletePositionHandler(t *testing.T) {
 			}
 
 			router := setupTestRouter(svc)
-			rec, r := newTestParameters(http.MethodDelete, fmt.Sprintf("/v1/stores/%s/positions/%s", tC.storeID, tC.productID), "")
+			rec, r := newTestParameters(http.MethodDelete, fmt.Sprintf("/v1/stores/%s/positions/%s", tC.storeID, tC.variantID), "")
 
 			router.ServeHTTP(rec, r)
 
@@ -905,6 +1217,8 @@ func Test_getCategoryProductsHandler(t *testing.T) {
 	testCases := []struct {
 		desc       string
 		categoryID string
+		query      string
+		filter     model.ProductFilter
 		products   []model.Product
 		err        error
 		rcode      int
@@ -913,18 +1227,97 @@ func Test_getCategoryProductsHandler(t *testing.T) {
 		{
 			desc:       "success",
 			categoryID: "1",
+			query:      "",
+			filter:     model.ProductFilter{},
 			products: []model.Product{
 				{ID: 1, CategoryID: 1, Name: "Test1", Description: "Desc 1"},
 				{ID: 2, CategoryID: 1, Name: "Test2", Description: "Desc 2"},
 			},
 			err:   nil,
 			rcode: http.StatusOK,
-			rdata: `[{"id":1, "categoryId":1, "name":"Test1", "description":"Desc 1"},
-				{"id":2, "categoryId":1, "name":"Test2", "description":"Desc 2"}]`,
+			rdata: `{"items":[{"id":1, "categoryId":1, "name":"Test1", "description":"Desc 1"},
+				{"id":2, "categoryId":1, "name":"Test2", "description":"Desc 2"}], "nextCursor":"next"}`,
+		},
+		{
+			desc:       "success with filter",
+			categoryID: "1",
+			query:      "?name=Test&minPrice=10&maxPrice=20.5&storeId=3&sort=-price",
+			filter: model.ProductFilter{
+				Name:     "Test",
+				MinPrice: decimal.NullDecimal{Decimal: decimal.NewFromInt(10), Valid: true},
+				MaxPrice: decimal.NullDecimal{Decimal: decimal.RequireFromString("20.5"), Valid: true},
+				StoreID:  3,
+				Sort:     model.SortByPrice,
+				Desc:     true,
+			},
+			products: []model.Product{
+				{ID: 1, CategoryID: 1, Name: "Test1", Description: "Desc 1"},
+			},
+			err:   nil,
+			rcode: http.StatusOK,
+			rdata: `{"items":[{"id":1, "categoryId":1, "name":"Test1", "description":"Desc 1"}], "nextCursor":"next"}`,
+		},
+		{
+			desc:       "success with attribute filter",
+			categoryID: "1",
+			query:      "?attr.ram.min=8&attr.color=black",
+			filter: model.ProductFilter{
+				Attributes: []model.AttributeFilter{
+					{Name: "color", Value: "black"},
+					{Name: "ram", Min: decimal.NullDecimal{Decimal: decimal.NewFromInt(8), Valid: true}},
+				},
+			},
+			products: []model.Product{
+				{ID: 1, CategoryID: 1, Name: "Test1", Description: "Desc 1", Attributes: map[string]interface{}{"ram": 16.0, "color": "black"}},
+			},
+			err:   nil,
+			rcode: http.StatusOK,
+			rdata: `{"items":[{"id":1, "categoryId":1, "name":"Test1", "description":"Desc 1", "attributes":{"ram":16, "color":"black"}}], "nextCursor":"next"}`,
+		},
+		{
+			desc:       "invalid attribute filter",
+			categoryID: "1",
+			query:      "?attr.cpu=i7",
+			filter:     model.ProductFilter{Attributes: []model.AttributeFilter{{Name: "cpu", Value: "i7"}}},
+			products:   nil,
+			err:        fmt.Errorf("%w: unknown attribute \"cpu\"", service.ErrInvalidAttributes),
+			rcode:      http.StatusBadRequest,
+			rdata:      `{"error":"invalid attributes: unknown attribute \"cpu\""}`,
+		},
+		{
+			desc:       "category not found",
+			categoryID: "1",
+			query:      "?attr.cpu=i7",
+			filter:     model.ProductFilter{Attributes: []model.AttributeFilter{{Name: "cpu", Value: "i7"}}},
+			products:   nil,
+			err:        service.ErrNotFound,
+			rcode:      http.StatusNotFound,
+			rdata:      `{"error":"category not found"}`,
+		},
+		{
+			desc:       "invalid cursor",
+			categoryID: "1",
+			query:      "",
+			filter:     model.ProductFilter{},
+			products:   nil,
+			err:        service.ErrInvalidCursor,
+			rcode:      http.StatusBadRequest,
+			rdata:      `{"error":"invalid cursor"}`,
+		},
+		{
+			desc:       "invalid filter",
+			categoryID: "1",
+			query:      "?sort=rating",
+			products:   nil,
+			err:        errSkip,
+			rcode:      http.StatusBadRequest,
+			rdata:      `{"error":"sort must be one of [id name price] optionally prefixed with '-'"}`,
 		},
 		{
 			desc:       "internal error",
 			categoryID: "1",
+			query:      "",
+			filter:     model.ProductFilter{},
 			products:   nil,
 			err:        errTest,
 			rcode:      http.StatusInternalServerError,
@@ -954,11 +1347,11 @@ func Test_getCategoryProductsHandler(t *testing.T) {
 
 			svc := service.NewMockService(ctrl)
 			if tC.err != errSkip {
-				svc.EXPECT().GetProducts(gomock.Any(), int64(1)).Return(tC.products, tC.err)
+				svc.EXPECT().GetProducts(gomock.Any(), int64(1), tC.filter, model.Page{Limit: defaultPageLimit}).Return(tC.products, "next", tC.err)
 			}
 
 			router := setupTestRouter(svc)
-			rec, r := newTestParameters(http.MethodGet, fmt.Sprintf("/v1/categories/%s/products", tC.categoryID), "")
+			rec, r := newTestParameters(http.MethodGet, fmt.Sprintf("/v1/categories/%s/products%s", tC.categoryID, tC.query), "")
 
 			router.ServeHTTP(rec, r)
 
@@ -970,43 +1363,55 @@ func Test_getCategoryProductsHandler(t *testing.T) {
 	}
 }
 
-func Test_getProductHandler(t *testing.T) {
+func Test_searchProductsHandler(t *testing.T) {
 	testCases := []struct {
 		desc    string
-		product model.Product
-		id      string
+		query   string
+		search  model.ProductSearch
+		results []model.ProductSearchResult
 		err     error
 		rcode   int
 		rdata   string
 	}{
 		{
-			desc:    "success",
-			product: model.Product{ID: 1, CategoryID: 2, Name: "Test1", Description: "ABC"},
-			id:      "1",
-			err:     nil,
-			rcode:   http.StatusOK,
-			rdata:   `{"id":1, "categoryId":2, "name":"Test1", "description":"ABC"}`,
+			desc:   "success",
+			query:  "?q=iph&categoryId=1",
+			search: model.ProductSearch{Query: "iph", CategoryID: 1},
+			results: []model.ProductSearchResult{
+				{
+					Product:              model.Product{ID: 1, CategoryID: 1, Name: "iPhone", Description: "Phone"},
+					Rank:                 0.5,
+					NameHighlight:        "<b>iPhone</b>",
+					DescriptionHighlight: "Phone",
+				},
+			},
+			err:   nil,
+			rcode: http.StatusOK,
+			rdata: `{"items":[{"id":1, "categoryId":1, "name":"iPhone", "description":"Phone", "rank":0.5,
+				"highlight":{"name":"\u003cb\u003eiPhone\u003c/b\u003e", "description":"Phone"}}], "nextCursor":"next"}`,
 		},
 		{
-			desc:    "invalid product ID",
-			id:      "test",
-			product: model.Product{},
+			desc:    "missing query",
+			query:   "?categoryId=1",
+			results: nil,
 			err:     errSkip,
 			rcode:   http.StatusBadRequest,
-			rdata:   `{"error":"invalid product ID"}`,
+			rdata:   `{"error":"q is a required parameter"}`,
 		},
 		{
-			desc:    "not found",
-			id:      "1",
-			product: model.Product{},
-			err:     service.ErrNotFound,
-			rcode:   http.StatusNotFound,
-			rdata:   `{"error":"product not found"}`,
+			desc:    "invalid cursor",
+			query:   "?q=iph",
+			search:  model.ProductSearch{Query: "iph"},
+			results: nil,
+			err:     service.ErrInvalidCursor,
+			rcode:   http.StatusBadRequest,
+			rdata:   `{"error":"invalid cursor"}`,
 		},
 		{
 			desc:    "internal error",
-			product: model.Product{},
-			id:      "1",
+			query:   "?q=iph",
+			search:  model.ProductSearch{Query: "iph"},
+			results: nil,
 			err:     errTest,
 			rcode:   http.StatusInternalServerError,
 			rdata:   `{"error":"internal error"}`,
@@ -1019,11 +1424,11 @@ func Test_getProductHandler(t *testing.T) {
 
 			svc := service.NewMockService(ctrl)
 			if tC.err != errSkip {
-				svc.EXPECT().GetProduct(gomock.Any(), int64(1)).Return(tC.product, tC.err)
+				svc.EXPECT().SearchProducts(gomock.Any(), tC.search, model.Page{Limit: defaultPageLimit}).Return(tC.results, "next", tC.err)
 			}
 
 			router := setupTestRouter(svc)
-			rec, r := newTestParameters(http.MethodGet, fmt.Sprintf("/v1/products/%s", tC.id), "")
+			rec, r := newTestParameters(http.MethodGet, "/v1/products/search"+tC.query, "")
 
 			router.ServeHTTP(rec, r)
 
@@ -1035,25 +1440,133 @@ func Test_getProductHandler(t *testing.T) {
 	}
 }
 
-func Test_createProductHandler(t *testing.T) {
+func Test_getProductHandler(t *testing.T) {
 	testCases := []struct {
 		desc    string
 		product model.Product
+		id      string
 		err     error
-		input   string
 		rcode   int
 		rdata   string
 	}{
 		{
 			desc:    "success",
-			product: model.Product{CategoryID: 2, Name: "Test1", Description: "ABC"},
+			product: model.Product{ID: 1, CategoryID: 2, Name: "Test1", Description: "ABC"},
+			id:      "1",
 			err:     nil,
-			input:   `{"categoryId":2, "name":"Test1", "description":"ABC"}`,
 			rcode:   http.StatusOK,
 			rdata:   `{"id":1, "categoryId":2, "name":"Test1", "description":"ABC"}`,
 		},
 		{
-			desc:    "invalid: missing name",
+			desc: "success with images",
+			product: model.Product{ID: 1, CategoryID: 2, Name: "Test1", Description: "ABC", Images: []model.Image{
+				{
+					ID: 3, ProductID: 1, Key: "products/1/a.png", Width: 300, Height: 200, Primary: true,
+					URL:        "/media/products/1/a.png",
+					Thumbnails: map[string]string{"small": "/media/products/1/a_small.png"},
+				},
+			}},
+			id:    "1",
+			err:   nil,
+			rcode: http.StatusOK,
+			rdata: `{"id":1, "categoryId":2, "name":"Test1", "description":"ABC", "images":[{"id":3, "url":"/media/products/1/a.png",
+				"thumbnails":{"small":"/media/products/1/a_small.png"}, "width":300, "height":200, "primary":true}]}`,
+		},
+		{
+			desc: "success with offers",
+			product: model.Product{ID: 1, CategoryID: 2, Name: "Test1", Description: "ABC", Offers: model.PriceSummary{
+				ProductID: 1, OfferCount: 2, Currency: "USD", MinPrice: decimal.NewFromInt(90), MaxPrice: decimal.NewFromInt(110),
+				MedianPrice: decimal.NewFromInt(100), CheapestStoreID: 3,
+			}},
+			id:    "1",
+			err:   nil,
+			rcode: http.StatusOK,
+			rdata: `{"id":1, "categoryId":2, "name":"Test1", "description":"ABC", "offers":{"productId":1, "offerCount":2,
+				"currency":"USD", "minPrice":90, "maxPrice":110, "medianPrice":100, "cheapestStoreId":3}}`,
+		},
+		{
+			desc:    "invalid product ID",
+			id:      "test",
+			product: model.Product{},
+			err:     errSkip,
+			rcode:   http.StatusBadRequest,
+			rdata:   `{"error":"invalid product ID"}`,
+		},
+		{
+			desc:    "not found",
+			id:      "1",
+			product: model.Product{},
+			err:     service.ErrNotFound,
+			rcode:   http.StatusNotFound,
+			rdata:   `{"error":"product not found"}`,
+		},
+		{
+			desc:    "internal error",
+			product: model.Product{},
+			id:      "1",
+			err:     errTest,
+			rcode:   http.StatusInternalServerError,
+			rdata:   `{"error":"internal error"}`,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			svc := service.NewMockService(ctrl)
+			if tC.err != errSkip {
+				svc.EXPECT().GetProduct(gomock.Any(), int64(1)).Return(tC.product, tC.err)
+			}
+
+			router := setupTestRouter(svc)
+			rec, r := newTestParameters(http.MethodGet, fmt.Sprintf("/v1/products/%s", tC.id), "")
+
+			router.ServeHTTP(rec, r)
+
+			body, _ := ioutil.ReadAll(rec.Result().Body)
+
+			assert.Equal(t, tC.rcode, rec.Result().StatusCode)
+			assert.JSONEq(t, tC.rdata, string(body))
+		})
+	}
+}
+
+func Test_createProductHandler(t *testing.T) {
+	testCases := []struct {
+		desc    string
+		product model.Product
+		err     error
+		input   string
+		rcode   int
+		rdata   string
+	}{
+		{
+			desc:    "success",
+			product: model.Product{CategoryID: 2, Name: "Test1", Description: "ABC"},
+			err:     nil,
+			input:   `{"categoryId":2, "name":"Test1", "description":"ABC"}`,
+			rcode:   http.StatusOK,
+			rdata:   `{"id":1, "categoryId":2, "name":"Test1", "description":"ABC"}`,
+		},
+		{
+			desc:    "success with attributes",
+			product: model.Product{CategoryID: 2, Name: "Test1", Description: "ABC", Attributes: map[string]interface{}{"ram": 16.0, "color": "black"}},
+			err:     nil,
+			input:   `{"categoryId":2, "name":"Test1", "description":"ABC", "attributes":{"ram":16, "color":"black"}}`,
+			rcode:   http.StatusOK,
+			rdata:   `{"id":1, "categoryId":2, "name":"Test1", "description":"ABC", "attributes":{"ram":16, "color":"black"}}`,
+		},
+		{
+			desc:    "invalid attributes",
+			product: model.Product{CategoryID: 2, Name: "Test1", Description: "ABC", Attributes: map[string]interface{}{"ram": "16"}},
+			err:     fmt.Errorf("%w: attribute \"ram\" must be a number", service.ErrInvalidAttributes),
+			input:   `{"categoryId":2, "name":"Test1", "description":"ABC", "attributes":{"ram":"16"}}`,
+			rcode:   http.StatusBadRequest,
+			rdata:   `{"error":"invalid attributes: attribute \"ram\" must be a number"}`,
+		},
+		{
+			desc:    "invalid: missing name",
 			product: model.Product{},
 			input:   `{"categoryId":2, "description":"ABC"}`,
 			err:     errSkip,
@@ -1259,6 +1772,9 @@ func Test_getProductOffersHandler(t *testing.T) {
 	testCases := []struct {
 		desc      string
 		productID string
+		query     string
+		filter    model.PositionFilter
+		currency  string
 		positions []model.Position
 		err       error
 		rcode     int
@@ -1268,13 +1784,72 @@ func Test_getProductOffersHandler(t *testing.T) {
 			desc:      "success",
 			productID: "1",
 			positions: []model.Position{
-				{ProductID: 1, StoreID: 1, Price: decimal.NewFromInt(100)},
-				{ProductID: 1, StoreID: 2, Price: decimal.NewFromInt(200)},
+				{ProductID: 1, VariantID: 1, StoreID: 1, Price: decimal.NewFromInt(100), Currency: "USD", Quantity: 5, Availability: model.AvailabilityInStock},
+				{ProductID: 1, VariantID: 2, StoreID: 1, Price: decimal.NewFromInt(150), Currency: "USD", Quantity: 1, Availability: model.AvailabilityInStock},
+				{ProductID: 1, VariantID: 1, StoreID: 2, Price: decimal.NewFromInt(200), Currency: "USD", Availability: model.AvailabilityDiscontinued},
+			},
+			err:   nil,
+			rcode: http.StatusOK,
+			rdata: `{"items":[{"productId":1, "variantId":1, "storeId":1, "price":100, "currency":"USD", "quantity":5, "availability":"in_stock"},
+				{"productId":1, "variantId":2, "storeId":1, "price":150, "currency":"USD", "quantity":1, "availability":"in_stock"},
+				{"productId":1, "variantId":1, "storeId":2, "price":200, "currency":"USD", "quantity":0, "availability":"discontinued"}], "nextCursor":"next"}`,
+		},
+		{
+			desc:      "success with availability filter",
+			productID: "1",
+			query:     "?availability=in_stock,%20backorder",
+			filter:    model.PositionFilter{Availability: []model.Availability{model.AvailabilityInStock, model.AvailabilityBackorder}},
+			positions: []model.Position{
+				{ProductID: 1, VariantID: 1, StoreID: 1, Price: decimal.NewFromInt(100), Currency: "USD", Quantity: 5, Availability: model.AvailabilityInStock},
+			},
+			err:   nil,
+			rcode: http.StatusOK,
+			rdata: `{"items":[{"productId":1, "variantId":1, "storeId":1, "price":100, "currency":"USD", "quantity":5, "availability":"in_stock"}], "nextCursor":"next"}`,
+		},
+		{
+			desc:      "success with currency",
+			productID: "1",
+			query:     "?currency=eur",
+			currency:  "EUR",
+			positions: []model.Position{
+				{ProductID: 1, VariantID: 1, StoreID: 1, Price: decimal.RequireFromString("84.5"), Currency: "EUR", Quantity: 5, Availability: model.AvailabilityInStock},
 			},
 			err:   nil,
 			rcode: http.StatusOK,
-			rdata: `[{"productId":1, "storeId":1, "price":100},
-				{"productId":1, "storeId":2, "price":200}]`,
+			rdata: `{"items":[{"productId":1, "variantId":1, "storeId":1, "price":84.5, "currency":"EUR", "quantity":5, "availability":"in_stock"}], "nextCursor":"next"}`,
+		},
+		{
+			desc:      "invalid currency",
+			productID: "1",
+			query:     "?currency=euro",
+			err:       errSkip,
+			rcode:     http.StatusBadRequest,
+			rdata:     `{"error":"currency must be an ISO 4217 code"}`,
+		},
+		{
+			desc:      "unknown currency",
+			productID: "1",
+			query:     "?currency=XYZ",
+			currency:  "XYZ",
+			err:       service.ErrUnknownCurrency,
+			rcode:     http.StatusBadRequest,
+			rdata:     `{"error":"unknown currency"}`,
+		},
+		{
+			desc:      "invalid availability",
+			productID: "1",
+			query:     "?availability=in_stock,sold_out",
+			err:       errSkip,
+			rcode:     http.StatusBadRequest,
+			rdata:     `{"error":"availability must be a comma-separated list of [in_stock backorder discontinued]"}`,
+		},
+		{
+			desc:      "invalid cursor",
+			productID: "1",
+			positions: nil,
+			err:       service.ErrInvalidCursor,
+			rcode:     http.StatusBadRequest,
+			rdata:     `{"error":"invalid cursor"}`,
 		},
 		{
 			desc:      "internal error",
@@ -1308,11 +1883,1338 @@ func Test_getProductOffersHandler(t *testing.T) {
 
 			svc := service.NewMockService(ctrl)
 			if tC.err != errSkip {
-				svc.EXPECT().GetProductPositions(gomock.Any(), int64(1)).Return(tC.positions, tC.err)
+				svc.EXPECT().GetProductPositions(gomock.Any(), int64(1), tC.filter, tC.currency, model.Page{Limit: defaultPageLimit}).Return(tC.positions, "next", tC.err)
+			}
+
+			router := setupTestRouter(svc)
+			rec, r := newTestParameters(http.MethodGet, fmt.Sprintf("/v1/products/%s/offers%s", tC.productID, tC.query), "")
+
+			router.ServeHTTP(rec, r)
+
+			body, _ := ioutil.ReadAll(rec.Result().Body)
+
+			assert.Equal(t, tC.rcode, rec.Result().StatusCode)
+			assert.JSONEq(t, tC.rdata, string(body))
+		})
+	}
+}
+
+func newMultipartBody(t *testing.T, field string, data []byte) (string, string) {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+
+	fw, err := mw.CreateFormFile(field, "image.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fw.Write(data)
+	mw.Close()
+
+	return buf.String(), mw.FormDataContentType()
+}
+
+func Test_addProductImageHandler(t *testing.T) {
+	data := []byte("image")
+
+	testCases := []struct {
+		desc  string
+		id    string
+		field string
+		data  []byte
+		json  bool
+		image model.Image
+		err   error
+		rcode int
+		rdata string
+	}{
+		{
+			desc:  "success",
+			id:    "1",
+			field: "image",
+			data:  data,
+			image: model.Image{
+				ID: 1, ProductID: 1, Width: 300, Height: 200, Primary: true,
+				URL:        "/media/products/1/a.png",
+				Thumbnails: map[string]string{"small": "/media/products/1/a_small.png"},
+			},
+			err:   nil,
+			rcode: http.StatusOK,
+			rdata: `{"id":1, "url":"/media/products/1/a.png", "thumbnails":{"small":"/media/products/1/a_small.png"},
+				"width":300, "height":200, "primary":true}`,
+		},
+		{
+			desc:  "invalid product ID",
+			id:    "test",
+			field: "image",
+			data:  data,
+			err:   errSkip,
+			rcode: http.StatusBadRequest,
+			rdata: `{"error":"invalid product ID"}`,
+		},
+		{
+			desc:  "not multipart",
+			id:    "1",
+			json:  true,
+			err:   errSkip,
+			rcode: http.StatusBadRequest,
+			rdata: `{"error":"request must be multipart/form-data"}`,
+		},
+		{
+			desc:  "missing file",
+			id:    "1",
+			field: "file",
+			data:  data,
+			err:   errSkip,
+			rcode: http.StatusBadRequest,
+			rdata: `{"error":"image file is required"}`,
+		},
+		{
+			desc:  "too large",
+			id:    "1",
+			field: "image",
+			data:  make([]byte, maxImageSize+1),
+			err:   errSkip,
+			rcode: http.StatusRequestEntityTooLarge,
+			rdata: `{"error":"image must be at maximum 10 MB"}`,
+		},
+		{
+			desc:  "unsupported image",
+			id:    "1",
+			field: "image",
+			data:  data,
+			err:   fmt.Errorf("%w: image must be one of [image/gif image/jpeg image/png]", service.ErrUnsupportedImage),
+			rcode: http.StatusUnsupportedMediaType,
+			rdata: `{"error":"unsupported image: image must be one of [image/gif image/jpeg image/png]"}`,
+		},
+		{
+			desc:  "product not found",
+			id:    "1",
+			field: "image",
+			data:  data,
+			err:   service.ErrUnknownProduct,
+			rcode: http.StatusNotFound,
+			rdata: `{"error":"product not found"}`,
+		},
+		{
+			desc:  "internal error",
+			id:    "1",
+			field: "image",
+			data:  data,
+			err:   errTest,
+			rcode: http.StatusInternalServerError,
+			rdata: `{"error":"internal error"}`,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			svc := service.NewMockService(ctrl)
+			if tC.err != errSkip {
+				svc.EXPECT().AddProductImage(gomock.Any(), int64(1), tC.data).Return(tC.image, tC.err)
+			}
+
+			router := setupTestRouter(svc)
+
+			body, contentType := `{}`, contentTypeJSON
+			if !tC.json {
+				body, contentType = newMultipartBody(t, tC.field, tC.data)
+			}
+
+			rec, r := newTestParameters(http.MethodPost, fmt.Sprintf("/v1/products/%s/images", tC.id), body)
+			r.Header.Set(headerContentType, contentType)
+
+			router.ServeHTTP(rec, r)
+
+			rbody, _ := ioutil.ReadAll(rec.Result().Body)
+
+			assert.Equal(t, tC.rcode, rec.Result().StatusCode)
+			assert.JSONEq(t, tC.rdata, string(rbody))
+		})
+	}
+}
+
+func Test_setProductImageOrderHandler(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		id       string
+		input    string
+		imageIDs []int64
+		err      error
+		rcode    int
+		rdata    string
+	}{
+		{
+			desc:     "success",
+			id:       "1",
+			input:    `{"imageIds":[3, 1, 2]}`,
+			imageIDs: []int64{3, 1, 2},
+			err:      nil,
+			rcode:    http.StatusNoContent,
+			rdata:    ``,
+		},
+		{
+			desc:  "invalid product ID",
+			id:    "test",
+			input: `{"imageIds":[3, 1, 2]}`,
+			err:   errSkip,
+			rcode: http.StatusBadRequest,
+			rdata: `{"error":"invalid product ID"}`,
+		},
+		{
+			desc:  "invalid: missing imageIds",
+			id:    "1",
+			input: `{}`,
+			err:   errSkip,
+			rcode: http.StatusBadRequest,
+			rdata: `{"error":"imageIds is a required field"}`,
+		},
+		{
+			desc:  "invalid: duplicate imageIds",
+			id:    "1",
+			input: `{"imageIds":[1, 1]}`,
+			err:   errSkip,
+			rcode: http.StatusBadRequest,
+			rdata: `{"error":"imageIds must contain unique values"}`,
+		},
+		{
+			desc:     "invalid image order",
+			id:       "1",
+			input:    `{"imageIds":[3, 1]}`,
+			imageIDs: []int64{3, 1},
+			err:      fmt.Errorf("%w: all 3 product images must be listed", service.ErrInvalidImageOrder),
+			rcode:    http.StatusBadRequest,
+			rdata:    `{"error":"invalid image order: all 3 product images must be listed"}`,
+		},
+		{
+			desc:     "internal error",
+			id:       "1",
+			input:    `{"imageIds":[3, 1, 2]}`,
+			imageIDs: []int64{3, 1, 2},
+			err:      errTest,
+			rcode:    http.StatusInternalServerError,
+			rdata:    `{"error":"internal error"}`,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			svc := service.NewMockService(ctrl)
+			if tC.err != errSkip {
+				svc.EXPECT().SetProductImageOrder(gomock.Any(), int64(1), tC.imageIDs).Return(tC.err)
+			}
+
+			router := setupTestRouter(svc)
+			rec, r := newTestParameters(http.MethodPut, fmt.Sprintf("/v1/products/%s/images/order", tC.id), tC.input)
+
+			router.ServeHTTP(rec, r)
+
+			body, _ := ioutil.ReadAll(rec.Result().Body)
+
+			assert.Equal(t, tC.rcode, rec.Result().StatusCode)
+			if tC.rdata == "" {
+				assert.Empty(t, body)
+			} else {
+				assert.JSONEq(t, tC.rdata, string(body))
+			}
+		})
+	}
+}
+
+func Test_setPrimaryProductImageHandler(t *testing.T) {
+	testCases := []struct {
+		desc    string
+		id      string
+		imageID string
+		err     error
+		rcode   int
+		rdata   string
+	}{
+		{
+			desc:    "success",
+			id:      "1",
+			imageID: "2",
+			err:     nil,
+			rcode:   http.StatusNoContent,
+			rdata:   ``,
+		},
+		{
+			desc:    "invalid product ID",
+			id:      "test",
+			imageID: "2",
+			err:     errSkip,
+			rcode:   http.StatusBadRequest,
+			rdata:   `{"error":"invalid product ID"}`,
+		},
+		{
+			desc:    "invalid image ID",
+			id:      "1",
+			imageID: "test",
+			err:     errSkip,
+			rcode:   http.StatusBadRequest,
+			rdata:   `{"error":"invalid image ID"}`,
+		},
+		{
+			desc:    "not found",
+			id:      "1",
+			imageID: "2",
+			err:     service.ErrNotFound,
+			rcode:   http.StatusNotFound,
+			rdata:   `{"error":"image not found"}`,
+		},
+		{
+			desc:    "concurrent change",
+			id:      "1",
+			imageID: "2",
+			err:     fmt.Errorf("%w: product images were changed concurrently", service.ErrInvalidImageOrder),
+			rcode:   http.StatusConflict,
+			rdata:   `{"error":"invalid image order: product images were changed concurrently"}`,
+		},
+		{
+			desc:    "internal error",
+			id:      "1",
+			imageID: "2",
+			err:     errTest,
+			rcode:   http.StatusInternalServerError,
+			rdata:   `{"error":"internal error"}`,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			svc := service.NewMockService(ctrl)
+			if tC.err != errSkip {
+				svc.EXPECT().SetPrimaryProductImage(gomock.Any(), int64(1), int64(2)).Return(tC.err)
+			}
+
+			router := setupTestRouter(svc)
+			rec, r := newTestParameters(http.MethodPut, fmt.Sprintf("/v1/products/%s/images/%s/primary", tC.id, tC.imageID), "")
+
+			router.ServeHTTP(rec, r)
+
+			body, _ := ioutil.ReadAll(rec.Result().Body)
+
+			assert.Equal(t, tC.rcode, rec.Result().StatusCode)
+			if tC.rdata == "" {
+				assert.Empty(t, body)
+			} else {
+				assert.JSONEq(t, tC.rdata, string(body))
+			}
+		})
+	}
+}
+
+func Test_deleteProductImageHandler(t *testing.T) {
+	testCases := []struct {
+		desc    string
+		id      string
+		imageID string
+		err     error
+		rcode   int
+		rdata   string
+	}{
+		{
+			desc:    "success",
+			id:      "1",
+			imageID: "2",
+			err:     nil,
+			rcode:   http.StatusNoContent,
+			rdata:   ``,
+		},
+		{
+			desc:    "invalid product ID",
+			id:      "test",
+			imageID: "2",
+			err:     errSkip,
+			rcode:   http.StatusBadRequest,
+			rdata:   `{"error":"invalid product ID"}`,
+		},
+		{
+			desc:    "invalid image ID",
+			id:      "1",
+			imageID: "test",
+			err:     errSkip,
+			rcode:   http.StatusBadRequest,
+			rdata:   `{"error":"invalid image ID"}`,
+		},
+		{
+			desc:    "not found",
+			id:      "1",
+			imageID: "2",
+			err:     service.ErrNotFound,
+			rcode:   http.StatusNotFound,
+			rdata:   `{"error":"image not found"}`,
+		},
+		{
+			desc:    "internal error",
+			id:      "1",
+			imageID: "2",
+			err:     errTest,
+			rcode:   http.StatusInternalServerError,
+			rdata:   `{"error":"internal error"}`,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			svc := service.NewMockService(ctrl)
+			if tC.err != errSkip {
+				svc.EXPECT().DeleteProductImage(gomock.Any(), int64(1), int64(2)).Return(tC.err)
+			}
+
+			router := setupTestRouter(svc)
+			rec, r := newTestParameters(http.MethodDelete, fmt.Sprintf("/v1/products/%s/images/%s", tC.id, tC.imageID), "")
+
+			router.ServeHTTP(rec, r)
+
+			body, _ := ioutil.ReadAll(rec.Result().Body)
+
+			assert.Equal(t, tC.rcode, rec.Result().StatusCode)
+			if tC.rdata == "" {
+				assert.Empty(t, body)
+			} else {
+				assert.JSONEq(t, tC.rdata, string(body))
+			}
+		})
+	}
+}
+
+func Test_getProductVariantsHandler(t *testing.T) {
+	testCases := []struct {
+		desc   string
+		id     string
+		matrix model.VariantMatrix
+		err    error
+		rcode  int
+		rdata  string
+	}{
+		{
+			desc: "success",
+			id:   "1",
+			matrix: model.VariantMatrix{
+				Options: []model.VariantOption{
+					{Name: "capacity", Values: []interface{}{64.0, 128.0}},
+					{Name: "color", Values: []interface{}{"black"}},
+				},
+				Variants: []model.Variant{
+					{ID: 1, ProductID: 1, SKU: "IP-64-B", GTIN: "4006381333931", Attributes: map[string]interface{}{"capacity": 64.0, "color": "black"}},
+					{ID: 2, ProductID: 1, SKU: "IP-128-B", Attributes: map[string]interface{}{"capacity": 128.0, "color": "black"}},
+				},
+			},
+			err:   nil,
+			rcode: http.StatusOK,
+			rdata: `{"options":[{"name":"capacity","values":[64,128]},{"name":"color","values":["black"]}],
+				"variants":[
+					{"id":1,"productId":1,"sku":"IP-64-B","gtin":"4006381333931","attributes":{"capacity":64,"color":"black"}},
+					{"id":2,"productId":1,"sku":"IP-128-B","attributes":{"capacity":128,"color":"black"}}
+				]}`,
+		},
+		{
+			desc:   "success without variants",
+			id:     "1",
+			matrix: model.VariantMatrix{},
+			err:    nil,
+			rcode:  http.StatusOK,
+			rdata:  `{"options":[],"variants":[]}`,
+		},
+		{
+			desc:  "invalid product ID",
+			id:    "test",
+			err:   errSkip,
+			rcode: http.StatusBadRequest,
+			rdata: `{"error":"invalid product ID"}`,
+		},
+		{
+			desc:  "not found",
+			id:    "1",
+			err:   service.ErrNotFound,
+			rcode: http.StatusNotFound,
+			rdata: `{"error":"product not found"}`,
+		},
+		{
+			desc:  "internal error",
+			id:    "1",
+			err:   errTest,
+			rcode: http.StatusInternalServerError,
+			rdata: `{"error":"internal error"}`,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			svc := service.NewMockService(ctrl)
+			if tC.err != errSkip {
+				svc.EXPECT().GetProductVariants(gomock.Any(), int64(1)).Return(tC.matrix, tC.err)
+			}
+
+			router := setupTestRouter(svc)
+			rec, r := newTestParameters(http.MethodGet, fmt.Sprintf("/v1/products/%s/variants", tC.id), "")
+
+			router.ServeHTTP(rec, r)
+
+			body, _ := ioutil.ReadAll(rec.Result().Body)
+
+			assert.Equal(t, tC.rcode, rec.Result().StatusCode)
+			assert.JSONEq(t, tC.rdata, string(body))
+		})
+	}
+}
+
+func Test_createVariantHandler(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		id       string
+		input    string
+		variant  model.Variant
+		rVariant model.Variant
+		err      error
+		rcode    int
+		rdata    string
+	}{
+		{
+			desc:     "success",
+			id:       "1",
+			input:    `{"sku":"IP-64-B","gtin":"4006381333931","attributes":{"capacity":64}}`,
+			variant:  model.Variant{ProductID: 1, SKU: "IP-64-B", GTIN: "4006381333931", Attributes: map[string]interface{}{"capacity": 64.0}},
+			rVariant: model.Variant{ID: 3, ProductID: 1, SKU: "IP-64-B", GTIN: "4006381333931", Attributes: map[string]interface{}{"capacity": 64.0}},
+			err:      nil,
+			rcode:    http.StatusOK,
+			rdata:    `{"id":3,"productId":1,"sku":"IP-64-B","gtin":"4006381333931","attributes":{"capacity":64}}`,
+		},
+		{
+			desc:  "invalid product ID",
+			id:    "test",
+			input: `{"sku":"IP-64-B"}`,
+			err:   errSkip,
+			rcode: http.StatusBadRequest,
+			rdata: `{"error":"invalid product ID"}`,
+		},
+		{
+			desc:  "invalid: missing SKU",
+			id:    "1",
+			input: `{"gtin":"4006381333931"}`,
+			err:   errSkip,
+			rcode: http.StatusBadRequest,
+			rdata: `{"error":"sku is a required field"}`,
+		},
+		{
+			desc:  "invalid: GTIN is not numeric",
+			id:    "1",
+			input: `{"sku":"IP-64-B","gtin":"40063813339X"}`,
+			err:   errSkip,
+			rcode: http.StatusBadRequest,
+			rdata: `{"error":"gtin must be a valid numeric value"}`,
+		},
+		{
+			desc:    "product not found",
+			id:      "1",
+			input:   `{"sku":"IP-64-B"}`,
+			variant: model.Variant{ProductID: 1, SKU: "IP-64-B"},
+			err:     service.ErrUnknownProduct,
+			rcode:   http.StatusNotFound,
+			rdata:   `{"error":"product not found"}`,
+		},
+		{
+			desc:    "SKU is taken",
+			id:      "1",
+			input:   `{"sku":"IP-64-B"}`,
+			variant: model.Variant{ProductID: 1, SKU: "IP-64-B"},
+			err:     service.ErrSKUIsTaken,
+			rcode:   http.StatusBadRequest,
+			rdata:   `{"error":"SKU has been already taken"}`,
+		},
+		{
+			desc:    "GTIN is taken",
+			id:      "1",
+			input:   `{"sku":"IP-64-B"}`,
+			variant: model.Variant{ProductID: 1, SKU: "IP-64-B"},
+			err:     service.ErrGTINIsTaken,
+			rcode:   http.StatusBadRequest,
+			rdata:   `{"error":"GTIN has been already taken"}`,
+		},
+		{
+			desc:    "invalid variant",
+			id:      "1",
+			input:   `{"sku":"IP-64-B"}`,
+			variant: model.Variant{ProductID: 1, SKU: "IP-64-B"},
+			err:     fmt.Errorf("%w: variant IP-64-W has the same attributes", service.ErrInvalidVariant),
+			rcode:   http.StatusBadRequest,
+			rdata:   `{"error":"invalid variant: variant IP-64-W has the same attributes"}`,
+		},
+		{
+			desc:    "invalid attributes",
+			id:      "1",
+			input:   `{"sku":"IP-64-B"}`,
+			variant: model.Variant{ProductID: 1, SKU: "IP-64-B"},
+			err:     fmt.Errorf("%w: unknown attribute \"weight\"", service.ErrInvalidAttributes),
+			rcode:   http.StatusBadRequest,
+			rdata:   `{"error":"invalid attributes: unknown attribute \"weight\""}`,
+		},
+		{
+			desc:    "internal error",
+			id:      "1",
+			input:   `{"sku":"IP-64-B"}`,
+			variant: model.Variant{ProductID: 1, SKU: "IP-64-B"},
+			err:     errTest,
+			rcode:   http.StatusInternalServerError,
+			rdata:   `{"error":"internal error"}`,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			svc := service.NewMockService(ctrl)
+			if tC.err != errSkip {
+				svc.EXPECT().CreateVariant(gomock.Any(), tC.variant).Return(tC.rVariant, tC.err)
+			}
+
+			router := setupTestRouter(svc)
+			rec, r := newTestParameters(http.MethodPost, fmt.Sprintf("/v1/products/%s/variants", tC.id), tC.input)
+
+			router.ServeHTTP(rec, r)
+
+			body, _ := ioutil.ReadAll(rec.Result().Body)
+
+			assert.Equal(t, tC.rcode, rec.Result().StatusCode)
+			assert.JSONEq(t, tC.rdata, string(body))
+		})
+	}
+}
+
+func Test_updateVariantHandler(t *testing.T) {
+	testCases := []struct {
+		desc      string
+		id        string
+		variantID string
+		input     string
+		variant   model.Variant
+		err       error
+		rcode     int
+		rdata     string
+	}{
+		{
+			desc:      "success",
+			id:        "1",
+			variantID: "2",
+			input:     `{"sku":"IP-64-W","attributes":{"color":"white"}}`,
+			variant:   model.Variant{ID: 2, ProductID: 1, SKU: "IP-64-W", Attributes: map[string]interface{}{"color": "white"}},
+			err:       nil,
+			rcode:     http.StatusOK,
+			rdata:     `{"id":2,"productId":1,"sku":"IP-64-W","attributes":{"color":"white"}}`,
+		},
+		{
+			desc:      "invalid product ID",
+			id:        "test",
+			variantID: "2",
+			input:     `{"sku":"IP-64-W"}`,
+			err:       errSkip,
+			rcode:     http.StatusBadRequest,
+			rdata:     `{"error":"invalid product ID"}`,
+		},
+		{
+			desc:      "invalid variant ID",
+			id:        "1",
+			variantID: "test",
+			input:     `{"sku":"IP-64-W"}`,
+			err:       errSkip,
+			rcode:     http.StatusBadRequest,
+			rdata:     `{"error":"invalid variant ID"}`,
+		},
+		{
+			desc:      "invalid: missing SKU",
+			id:        "1",
+			variantID: "2",
+			input:     `{}`,
+			err:       errSkip,
+			rcode:     http.StatusBadRequest,
+			rdata:     `{"error":"sku is a required field"}`,
+		},
+		{
+			desc:      "not found",
+			id:        "1",
+			variantID: "2",
+			input:     `{"sku":"IP-64-W"}`,
+			variant:   model.Variant{ID: 2, ProductID: 1, SKU: "IP-64-W"},
+			err:       service.ErrNotFound,
+			rcode:     http.StatusNotFound,
+			rdata:     `{"error":"variant not found"}`,
+		},
+		{
+			desc:      "internal error",
+			id:        "1",
+			variantID: "2",
+			input:     `{"sku":"IP-64-W"}`,
+			variant:   model.Variant{ID: 2, ProductID: 1, SKU: "IP-64-W"},
+			err:       errTest,
+			rcode:     http.StatusInternalServerError,
+			rdata:     `{"error":"internal error"}`,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			svc := service.NewMockService(ctrl)
+			if tC.err != errSkip {
+				svc.EXPECT().UpdateVariant(gomock.Any(), tC.variant).Return(tC.err)
+			}
+
+			router := setupTestRouter(svc)
+			rec, r := newTestParameters(http.MethodPut, fmt.Sprintf("/v1/products/%s/variants/%s", tC.id, tC.variantID), tC.input)
+
+			router.ServeHTTP(rec, r)
+
+			body, _ := ioutil.ReadAll(rec.Result().Body)
+
+			assert.Equal(t, tC.rcode, rec.Result().StatusCode)
+			assert.JSONEq(t, tC.rdata, string(body))
+		})
+	}
+}
+
+func Test_deleteVariantHandler(t *testing.T) {
+	testCases := []struct {
+		desc      string
+		id        string
+		variantID string
+		err       error
+		rcode     int
+		rdata     string
+	}{
+		{
+			desc:      "success",
+			id:        "1",
+			variantID: "2",
+			err:       nil,
+			rcode:     http.StatusNoContent,
+			rdata:     ``,
+		},
+		{
+			desc:      "invalid product ID",
+			id:        "test",
+			variantID: "2",
+			err:       errSkip,
+			rcode:     http.StatusBadRequest,
+			rdata:     `{"error":"invalid product ID"}`,
+		},
+		{
+			desc:      "invalid variant ID",
+			id:        "1",
+			variantID: "test",
+			err:       errSkip,
+			rcode:     http.StatusBadRequest,
+			rdata:     `{"error":"invalid variant ID"}`,
+		},
+		{
+			desc:      "not found",
+			id:        "1",
+			variantID: "2",
+			err:       service.ErrNotFound,
+			rcode:     http.StatusNotFound,
+			rdata:     `{"error":"variant not found"}`,
+		},
+		{
+			desc:      "internal error",
+			id:        "1",
+			variantID: "2",
+			err:       errTest,
+			rcode:     http.StatusInternalServerError,
+			rdata:     `{"error":"internal error"}`,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			svc := service.NewMockService(ctrl)
+			if tC.err != errSkip {
+				svc.EXPECT().DeleteVariant(gomock.Any(), int64(1), int64(2)).Return(tC.err)
+			}
+
+			router := setupTestRouter(svc)
+			rec, r := newTestParameters(http.MethodDelete, fmt.Sprintf("/v1/products/%s/variants/%s", tC.id, tC.variantID), "")
+
+			router.ServeHTTP(rec, r)
+
+			body, _ := ioutil.ReadAll(rec.Result().Body)
+
+			assert.Equal(t, tC.rcode, rec.Result().StatusCode)
+			if tC.rdata == "" {
+				assert.Empty(t, body)
+			} else {
+				assert.JSONEq(t, tC.rdata, string(body))
+			}
+		})
+	}
+}
+
+func Test_adjustStockHandler(t *testing.T) {
+	testCases := []struct {
+		desc      string
+		action    string
+		storeID   string
+		variantID string
+		input     string
+		delta     int64
+		position  model.Position
+		err       error
+		rcode     int
+		rdata     string
+	}{
+		{
+			desc:      "increment",
+			action:    "increment",
+			storeID:   "1",
+			variantID: "2",
+			input:     `{"quantity": 3}`,
+			delta:     3,
+			position:  model.Position{ProductID: 3, VariantID: 2, StoreID: 1, Price: decimal.NewFromInt(100), Currency: "USD", Quantity: 8, Availability: model.AvailabilityInStock},
+			err:       nil,
+			rcode:     http.StatusOK,
+			rdata:     `{"productId":3, "variantId":2, "storeId":1, "price":100, "currency":"USD", "quantity":8, "availability":"in_stock"}`,
+		},
+		{
+			desc:      "decrement",
+			action:    "decrement",
+			storeID:   "1",
+			variantID: "2",
+			input:     `{"quantity": 3}`,
+			delta:     -3,
+			position:  model.Position{ProductID: 3, VariantID: 2, StoreID: 1, Price: decimal.NewFromInt(100), Currency: "USD", Quantity: 2, Availability: model.AvailabilityInStock},
+			err:       nil,
+			rcode:     http.StatusOK,
+			rdata:     `{"productId":3, "variantId":2, "storeId":1, "price":100, "currency":"USD", "quantity":2, "availability":"in_stock"}`,
+		},
+		{
+			desc:      "invalid store ID",
+			action:    "increment",
+			storeID:   "test",
+			variantID: "2",
+			input:     `{"quantity": 3}`,
+			err:       errSkip,
+			rcode:     http.StatusBadRequest,
+			rdata:     `{"error":"invalid store ID"}`,
+		},
+		{
+			desc:      "invalid variant ID",
+			action:    "increment",
+			storeID:   "1",
+			variantID: "test",
+			input:     `{"quantity": 3}`,
+			err:       errSkip,
+			rcode:     http.StatusBadRequest,
+			rdata:     `{"error":"invalid variant ID"}`,
+		},
+		{
+			desc:      "invalid: zero quantity",
+			action:    "decrement",
+			storeID:   "1",
+			variantID: "2",
+			input:     `{"quantity": 0}`,
+			err:       errSkip,
+			rcode:     http.StatusBadRequest,
+			rdata:     `{"error":"quantity must be greater than 0"}`,
+		},
+		{
+			desc:      "not found",
+			action:    "increment",
+			storeID:   "1",
+			variantID: "2",
+			input:     `{"quantity": 3}`,
+			delta:     3,
+			err:       service.ErrNotFound,
+			rcode:     http.StatusNotFound,
+			rdata:     `{"error":"position not found"}`,
+		},
+		{
+			desc:      "insufficient stock",
+			action:    "decrement",
+			storeID:   "1",
+			variantID: "2",
+			input:     `{"quantity": 3}`,
+			delta:     -3,
+			err:       service.ErrInsufficientStock,
+			rcode:     http.StatusConflict,
+			rdata:     `{"error":"insufficient stock"}`,
+		},
+		{
+			desc:      "internal error",
+			action:    "decrement",
+			storeID:   "1",
+			variantID: "2",
+			input:     `{"quantity": 3}`,
+			delta:     -3,
+			err:       errTest,
+			rcode:     http.StatusInternalServerError,
+			rdata:     `{"error":"internal error"}`,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			svc := service.NewMockService(ctrl)
+			if tC.err != errSkip {
+				svc.EXPECT().AdjustStock(gomock.Any(), int64(2), int64(1), tC.delta).Return(tC.position, tC.err)
+			}
+
+			router := setupTestRouter(svc)
+			rec, r := newTestParameters(http.MethodPost,
+				fmt.Sprintf("/v1/stores/%s/positions/%s/stock/%s", tC.storeID, tC.variantID, tC.action), tC.input)
+
+			router.ServeHTTP(rec, r)
+
+			body, _ := ioutil.ReadAll(rec.Result().Body)
+
+			assert.Equal(t, tC.rcode, rec.Result().StatusCode)
+			assert.JSONEq(t, tC.rdata, string(body))
+		})
+	}
+}
+
+func Test_getPriceHistoryHandler(t *testing.T) {
+	filter := model.PriceHistoryFilter{
+		StoreID: 2,
+		From:    time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+		To:      time.Date(2021, 1, 3, 0, 0, 0, 0, time.UTC),
+	}
+
+	testCases := []struct {
+		desc     string
+		id       string
+		query    string
+		changes  []model.PriceChange
+		buckets  []model.PriceBucket
+		err      error
+		rcode    int
+		rdata    string
+		bucketed bool
+	}{
+		{
+			desc:  "success",
+			id:    "1",
+			query: "?storeId=2&from=2021-01-01&to=2021-01-02",
+			changes: []model.PriceChange{
+				{ProductID: 1, VariantID: 1, StoreID: 2, Price: decimal.NewFromInt(100), Currency: "USD", ChangedAt: time.Date(2021, 1, 1, 10, 0, 0, 0, time.UTC)},
+				{ProductID: 1, VariantID: 1, StoreID: 2, Price: decimal.NewFromInt(90), Currency: "EUR", UserID: 3, ChangedAt: time.Date(2021, 1, 2, 10, 0, 0, 0, time.UTC)},
+			},
+			err:   nil,
+			rcode: http.StatusOK,
+			rdata: `[
+				{"variantId":1,"storeId":2,"price":100,"currency":"USD","changedAt":"2021-01-01T10:00:00Z"},
+				{"variantId":1,"storeId":2,"price":90,"currency":"EUR","userId":3,"changedAt":"2021-01-02T10:00:00Z"}
+			]`,
+		},
+		{
+			desc:    "success without changes",
+			id:      "1",
+			query:   "?storeId=2&from=2021-01-01&to=2021-01-02",
+			changes: nil,
+			err:     nil,
+			rcode:   http.StatusOK,
+			rdata:   `[]`,
+		},
+		{
+			desc:  "success daily",
+			id:    "1",
+			query: "?storeId=2&from=2021-01-01&to=2021-01-02&interval=day",
+			buckets: []model.PriceBucket{
+				{Date: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC), Min: decimal.NewFromInt(90), Avg: decimal.NewFromInt(95), Max: decimal.NewFromInt(100)},
+			},
+			bucketed: true,
+			err:      nil,
+			rcode:    http.StatusOK,
+			rdata:    `[{"date":"2021-01-01","min":90,"avg":95,"max":100}]`,
+		},
+		{
+			desc:  "invalid product ID",
+			id:    "test",
+			err:   errSkip,
+			rcode: http.StatusBadRequest,
+			rdata: `{"error":"invalid product ID"}`,
+		},
+		{
+			desc:  "invalid range",
+			id:    "1",
+			query: "?from=2021-01-02&to=2021-01-01",
+			err:   errSkip,
+			rcode: http.StatusBadRequest,
+			rdata: `{"error":"from must be before to"}`,
+		},
+		{
+			desc:  "invalid interval",
+			id:    "1",
+			query: "?storeId=2&from=2021-01-01&to=2021-01-02&interval=hour",
+			err:   errSkip,
+			rcode: http.StatusBadRequest,
+			rdata: `{"error":"interval must be one of [day]"}`,
+		},
+		{
+			desc:  "not found",
+			id:    "1",
+			query: "?storeId=2&from=2021-01-01&to=2021-01-02",
+			err:   service.ErrNotFound,
+			rcode: http.StatusNotFound,
+			rdata: `{"error":"product not found"}`,
+		},
+		{
+			desc:     "internal error",
+			id:       "1",
+			query:    "?storeId=2&from=2021-01-01&to=2021-01-02&interval=day",
+			bucketed: true,
+			err:      errTest,
+			rcode:    http.StatusInternalServerError,
+			rdata:    `{"error":"internal error"}`,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			svc := service.NewMockService(ctrl)
+			if tC.err != errSkip {
+				if tC.bucketed {
+					svc.EXPECT().GetDailyPriceHistory(gomock.Any(), int64(1), filter).Return(tC.buckets, tC.err)
+				} else {
+					svc.EXPECT().GetPriceHistory(gomock.Any(), int64(1), filter).Return(tC.changes, tC.err)
+				}
+			}
+
+			router := setupTestRouter(svc)
+			rec, r := newTestParameters(http.MethodGet, fmt.Sprintf("/v1/products/%s/price-history%s", tC.id, tC.query), "")
+
+			router.ServeHTTP(rec, r)
+
+			body, _ := ioutil.ReadAll(rec.Result().Body)
+
+			assert.Equal(t, tC.rcode, rec.Result().StatusCode)
+			assert.JSONEq(t, tC.rdata, string(body))
+		})
+	}
+}
+
+func Test_compareProductPricesHandler(t *testing.T) {
+	testCases := []struct {
+		desc      string
+		query     string
+		currency  string
+		summaries []model.PriceSummary
+		err       error
+		rcode     int
+		rdata     string
+	}{
+		{
+			desc:     "success",
+			query:    "?ids=1,2&currency=JPY",
+			currency: "JPY",
+			summaries: []model.PriceSummary{
+				{ProductID: 1, OfferCount: 3, Currency: "JPY", MinPrice: decimal.NewFromInt(90), MaxPrice: decimal.NewFromInt(110), MedianPrice: decimal.NewFromInt(100), CheapestStoreID: 2},
+				{ProductID: 2, Currency: "JPY"},
+			},
+			err:   nil,
+			rcode: http.StatusOK,
+			rdata: `[
+				{"productId":1, "offerCount":3, "currency":"JPY", "minPrice":90, "maxPrice":110, "medianPrice":100, "cheapestStoreId":2},
+				{"productId":2, "offerCount":0, "currency":"JPY", "minPrice":null, "maxPrice":null, "medianPrice":null}
+			]`,
+		},
+		{
+			desc:     "unknown currency",
+			query:    "?ids=1,2&currency=XYZ",
+			currency: "XYZ",
+			err:      service.ErrUnknownCurrency,
+			rcode:    http.StatusBadRequest,
+			rdata:    `{"error":"unknown currency"}`,
+		},
+		{
+			desc:  "invalid ids",
+			query: "?ids=1,x",
+			err:   errSkip,
+			rcode: http.StatusBadRequest,
+			rdata: `{"error":"ids must be a comma-separated list of positive integers"}`,
+		},
+		{
+			desc:  "internal error",
+			query: "?ids=1,2",
+			err:   errTest,
+			rcode: http.StatusInternalServerError,
+			rdata: `{"error":"internal error"}`,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			svc := service.NewMockService(ctrl)
+			if tC.err != errSkip {
+				svc.EXPECT().ComparePrices(gomock.Any(), []int64{1, 2}, tC.currency).Return(tC.summaries, tC.err)
+			}
+
+			router := setupTestRouter(svc)
+			rec, r := newTestParameters(http.MethodGet, "/v1/products/compare"+tC.query, "")
+
+			router.ServeHTTP(rec, r)
+
+			body, _ := ioutil.ReadAll(rec.Result().Body)
+
+			assert.Equal(t, tC.rcode, rec.Result().StatusCode)
+			assert.JSONEq(t, tC.rdata, string(body))
+		})
+	}
+}
+
+func Test_getExchangeRatesHandler(t *testing.T) {
+	testCases := []struct {
+		desc  string
+		rates []model.ExchangeRate
+		err   error
+		rcode int
+		rdata string
+	}{
+		{
+			desc: "success",
+			rates: []model.ExchangeRate{
+				{Currency: "EUR", Rate: decimal.RequireFromString("0.845"), UpdatedAt: time.Date(2021, 1, 1, 10, 0, 0, 0, time.UTC)},
+				{Currency: "USD", Rate: decimal.NewFromInt(1), UpdatedAt: time.Date(2021, 1, 1, 9, 0, 0, 0, time.UTC)},
+			},
+			err:   nil,
+			rcode: http.StatusOK,
+			rdata: `[{"currency":"EUR", "rate":0.845, "updatedAt":"2021-01-01T10:00:00Z"},
+				{"currency":"USD", "rate":1, "updatedAt":"2021-01-01T09:00:00Z"}]`,
+		},
+		{
+			desc:  "internal error",
+			err:   errTest,
+			rcode: http.StatusInternalServerError,
+			rdata: `{"error":"internal error"}`,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			svc := service.NewMockService(ctrl)
+			svc.EXPECT().GetExchangeRates(gomock.Any()).Return(tC.rates, tC.err)
+
+			router := setupTestRouter(svc)
+			rec, r := newTestParameters(http.MethodGet, "/v1/exchange-rates", "")
+
+			router.ServeHTTP(rec, r)
+
+			body, _ := ioutil.ReadAll(rec.Result().Body)
+
+			assert.Equal(t, tC.rcode, rec.Result().StatusCode)
+			assert.JSONEq(t, tC.rdata, string(body))
+		})
+	}
+}
+
+func Test_setExchangeRateHandler(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		currency string
+		input    string
+		rate     model.ExchangeRate
+		err      error
+		rcode    int
+		rdata    string
+	}{
+		{
+			desc:     "success",
+			currency: "EUR",
+			input:    `{"rate": 0.845}`,
+			rate:     model.ExchangeRate{Currency: "EUR", Rate: decimal.RequireFromString("0.845")},
+			err:      nil,
+			rcode:    http.StatusOK,
+			rdata:    `{"currency":"EUR", "rate":0.845}`,
+		},
+		{
+			desc:     "invalid currency",
+			currency: "euro",
+			input:    `{"rate": 0.845}`,
+			err:      errSkip,
+			rcode:    http.StatusBadRequest,
+			rdata:    `{"error":"currency must be 3 characters in length"}`,
+		},
+		{
+			desc:     "invalid rate",
+			currency: "EUR",
+			input:    `{"rate": 0}`,
+			err:      errSkip,
+			rcode:    http.StatusBadRequest,
+			rdata:    `{"error":"rate must be greater than 0"}`,
+		},
+		{
+			desc:     "invalid base currency rate",
+			currency: "USD",
+			input:    `{"rate": 2}`,
+			rate:     model.ExchangeRate{Currency: "USD", Rate: decimal.NewFromInt(2)},
+			err:      fmt.Errorf("%w: rate of base currency USD must be 1", service.ErrInvalidExchangeRate),
+			rcode:    http.StatusBadRequest,
+			rdata:    `{"error":"invalid exchange rate: rate of base currency USD must be 1"}`,
+		},
+		{
+			desc:     "internal error",
+			currency: "EUR",
+			input:    `{"rate": 0.845}`,
+			rate:     model.ExchangeRate{Currency: "EUR", Rate: decimal.RequireFromString("0.845")},
+			err:      errTest,
+			rcode:    http.StatusInternalServerError,
+			rdata:    `{"error":"internal error"}`,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			svc := service.NewMockService(ctrl)
+			if tC.err != errSkip {
+				svc.EXPECT().SetExchangeRate(gomock.Any(), tC.rate).Return(tC.err)
+			}
+
+			router := setupTestRouter(svc)
+			rec, r := newTestParameters(http.MethodPut, "/v1/exchange-rates/"+tC.currency, tC.input)
+
+			router.ServeHTTP(rec, r)
+
+			body, _ := ioutil.ReadAll(rec.Result().Body)
+
+			assert.Equal(t, tC.rcode, rec.Result().StatusCode)
+			assert.JSONEq(t, tC.rdata, string(body))
+		})
+	}
+}
+
+func Test_deleteExchangeRateHandler(t *testing.T) {
+	testCases := []struct {
+		desc  string
+		err   error
+		rcode int
+		rdata string
+	}{
+		{
+			desc:  "success",
+			err:   nil,
+			rcode: http.StatusNoContent,
+			rdata: "",
+		},
+		{
+			desc:  "not found",
+			err:   service.ErrNotFound,
+			rcode: http.StatusNotFound,
+			rdata: `{"error":"exchange rate not found"}`,
+		},
+		{
+			desc:  "in use",
+			err:   service.ErrCurrencyInUse,
+			rcode: http.StatusConflict,
+			rdata: `{"error":"currency is used by positions"}`,
+		},
+		{
+			desc:  "internal error",
+			err:   errTest,
+			rcode: http.StatusInternalServerError,
+			rdata: `{"error":"internal error"}`,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			svc := service.NewMockService(ctrl)
+			svc.EXPECT().DeleteExchangeRate(gomock.Any(), "EUR").Return(tC.err)
+
+			router := setupTestRouter(svc)
+			rec, r := newTestParameters(http.MethodDelete, "/v1/exchange-rates/EUR", "")
+
+			router.ServeHTTP(rec, r)
+
+			body, _ := ioutil.ReadAll(rec.Result().Body)
+
+			assert.Equal(t, tC.rcode, rec.Result().StatusCode)
+			if tC.rdata == "" {
+				assert.Empty(t, body)
+			} else {
+				assert.JSONEq(t, tC.rdata, string(body))
+			}
+		})
+	}
+}
+
+func Test_importExchangeRatesHandler(t *testing.T) {
+	testCases := []struct {
+		desc  string
+		input string
+		n     int
+		err   error
+		rcode int
+		rdata string
+	}{
+		{
+			desc:  "success",
+			input: "EUR,0.845\nJPY,104.55\n",
+			n:     2,
+			err:   nil,
+			rcode: http.StatusOK,
+			rdata: `{"imported":2}`,
+		},
+		{
+			desc:  "too large",
+			input: strings.Repeat("#", maxExchangeRatesSize+1),
+			err:   errSkip,
+			rcode: http.StatusRequestEntityTooLarge,
+			rdata: `{"error":"exchange rates must be at maximum 1 MB"}`,
+		},
+		{
+			desc:  "invalid rates",
+			input: "EUR;0.845",
+			err:   fmt.Errorf("%w: line 1: expected <currency>,<rate>", service.ErrInvalidExchangeRate),
+			rcode: http.StatusBadRequest,
+			rdata: `{"error":"invalid exchange rate: line 1: expected <currency>,<rate>"}`,
+		},
+		{
+			desc:  "internal error",
+			input: "EUR,0.845",
+			err:   errTest,
+			rcode: http.StatusInternalServerError,
+			rdata: `{"error":"internal error"}`,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			svc := service.NewMockService(ctrl)
+			if tC.err != errSkip {
+				svc.EXPECT().ImportExchangeRates(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, r io.Reader) (int, error) {
+					data, _ := ioutil.ReadAll(r)
+					assert.Equal(t, tC.input, string(data))
+					return tC.n, tC.err
+				})
 			}
 
 			router := setupTestRouter(svc)
-			rec, r := newTestParameters(http.MethodGet, fmt.Sprintf("/v1/products/%s/offers", tC.productID), "")
+			rec, r := newTestParameters(http.MethodPost, "/v1/exchange-rates/import", tC.input)
 
 			router.ServeHTTP(rec, r)
 
@@ -0,0 +1,103 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/vliubezny/gstore/internal/model"
+	"github.com/vliubezny/gstore/internal/service"
+)
+
+func (s *server) getStoreMembersHandler(w http.ResponseWriter, r *http.Request) {
+	l := getLogger(r)
+
+	storeID, err := getIDFromURL(r, "id")
+	if err != nil {
+		writeError(l.WithError(err), w, http.StatusBadRequest, "invalid store ID")
+		return
+	}
+
+	members, err := s.s.GetStoreMembers(r.Context(), storeID)
+	if err != nil {
+		writeInternalError(l.WithError(err), w, "fail to get store members")
+		return
+	}
+
+	resp := make([]storeMember, len(members))
+	for i, m := range members {
+		resp[i] = fromStoreMemberModel(m)
+	}
+
+	writeOK(l, w, resp)
+}
+
+func (s *server) setStoreMemberHandler(w http.ResponseWriter, r *http.Request) {
+	l := getLogger(r)
+
+	storeID, err := getIDFromURL(r, "id")
+	if err != nil {
+		writeError(l.WithError(err), w, http.StatusBadRequest, "invalid store ID")
+		return
+	}
+
+	var req storeMemberInvite
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(l.WithError(err), w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := validate(&req); err != nil {
+		writeError(l.WithError(err), w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	m, err := s.s.SetStoreMember(r.Context(), storeID, req.Email, model.StoreRole(req.Role))
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrUnknownStore):
+			writeError(l.WithError(err), w, http.StatusNotFound, "store not found")
+		case errors.Is(err, service.ErrUnknownUser):
+			writeError(l.WithError(err), w, http.StatusBadRequest, "user is not registered")
+		case errors.Is(err, service.ErrLastOwner):
+			writeError(l.WithError(err), w, http.StatusConflict, "store must have an owner")
+		default:
+			writeInternalError(l.WithError(err), w, "fail to set store member")
+		}
+		return
+	}
+
+	l.WithField("storeID", storeID).Infof("user %d became store %s", m.UserID, m.Role)
+
+	writeOK(l, w, fromStoreMemberModel(m))
+}
+
+func (s *server) deleteStoreMemberHandler(w http.ResponseWriter, r *http.Request) {
+	l := getLogger(r)
+
+	storeID, err := getIDFromURL(r, "id")
+	if err != nil {
+		writeError(l.WithError(err), w, http.StatusBadRequest, "invalid store ID")
+		return
+	}
+
+	userID, err := getIDFromURL(r, "userId")
+	if err != nil {
+		writeError(l.WithError(err), w, http.StatusBadRequest, "invalid user ID")
+		return
+	}
+
+	if err := s.s.RemoveStoreMember(r.Context(), storeID, userID); err != nil {
+		switch {
+		case errors.Is(err, service.ErrNotFound):
+			writeError(l.WithError(err), w, http.StatusNotFound, "member not found")
+		case errors.Is(err, service.ErrLastOwner):
+			writeError(l.WithError(err), w, http.StatusConflict, "store must have an owner")
+		default:
+			writeInternalError(l.WithError(err), w, "fail to remove store member")
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,365 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/golang/mock/gomock"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/vliubezny/gstore/internal/auth"
+	"github.com/vliubezny/gstore/internal/model"
+	"github.com/vliubezny/gstore/internal/service"
+)
+
+var testMember = model.StoreMember{StoreID: 1, UserID: 3, Email: "editor@test.com", Role: model.StoreEditor}
+
+const testMemberJSON = `{"userId":3, "email":"editor@test.com", "role":"editor"}`
+
+func Test_getStoreMembersHandler(t *testing.T) {
+	testCases := []struct {
+		desc    string
+		id      string
+		members []model.StoreMember
+		err     error
+		rcode   int
+		rdata   string
+	}{
+		{
+			desc:    "success",
+			id:      "1",
+			members: []model.StoreMember{testMember},
+			err:     nil,
+			rcode:   http.StatusOK,
+			rdata:   "[" + testMemberJSON + "]",
+		},
+		{
+			desc:  "invalid store ID",
+			id:    "test",
+			err:   errSkip,
+			rcode: http.StatusBadRequest,
+			rdata: `{"error":"invalid store ID"}`,
+		},
+		{
+			desc:  "internal error",
+			id:    "1",
+			err:   errTest,
+			rcode: http.StatusInternalServerError,
+			rdata: `{"error":"internal error"}`,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			svc := service.NewMockService(ctrl)
+			if tC.err != errSkip {
+				svc.EXPECT().GetStoreMembers(gomock.Any(), int64(1)).Return(tC.members, tC.err)
+			}
+
+			router := setupTestRouter(svc)
+			rec, r := newTestParameters(http.MethodGet, fmt.Sprintf("/v1/stores/%s/members", tC.id), "")
+
+			router.ServeHTTP(rec, r)
+
+			body, _ := ioutil.ReadAll(rec.Result().Body)
+
+			assert.Equal(t, tC.rcode, rec.Result().StatusCode)
+			assert.JSONEq(t, tC.rdata, string(body))
+		})
+	}
+}
+
+func Test_setStoreMemberHandler(t *testing.T) {
+	testCases := []struct {
+		desc  string
+		id    string
+		input string
+		err   error
+		rcode int
+		rdata string
+	}{
+		{
+			desc:  "success",
+			id:    "1",
+			input: `{"email":"editor@test.com", "role":"editor"}`,
+			err:   nil,
+			rcode: http.StatusOK,
+			rdata: testMemberJSON,
+		},
+		{
+			desc:  "invalid store ID",
+			id:    "test",
+			input: `{"email":"editor@test.com", "role":"editor"}`,
+			err:   errSkip,
+			rcode: http.StatusBadRequest,
+			rdata: `{"error":"invalid store ID"}`,
+		},
+		{
+			desc:  "invalid role",
+			id:    "1",
+			input: `{"email":"editor@test.com", "role":"admin"}`,
+			err:   errSkip,
+			rcode: http.StatusBadRequest,
+			rdata: `{"error":"role must be one of [owner editor]"}`,
+		},
+		{
+			desc:  "unknown store",
+			id:    "1",
+			input: `{"email":"editor@test.com", "role":"editor"}`,
+			err:   service.ErrUnknownStore,
+			rcode: http.StatusNotFound,
+			rdata: `{"error":"store not found"}`,
+		},
+		{
+			desc:  "unknown user",
+			id:    "1",
+			input: `{"email":"editor@test.com", "role":"editor"}`,
+			err:   service.ErrUnknownUser,
+			rcode: http.StatusBadRequest,
+			rdata: `{"error":"user is not registered"}`,
+		},
+		{
+			desc:  "last owner",
+			id:    "1",
+			input: `{"email":"editor@test.com", "role":"editor"}`,
+			err:   service.ErrLastOwner,
+			rcode: http.StatusConflict,
+			rdata: `{"error":"store must have an owner"}`,
+		},
+		{
+			desc:  "internal error",
+			id:    "1",
+			input: `{"email":"editor@test.com", "role":"editor"}`,
+			err:   errTest,
+			rcode: http.StatusInternalServerError,
+			rdata: `{"error":"internal error"}`,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			svc := service.NewMockService(ctrl)
+			if tC.err != errSkip {
+				svc.EXPECT().SetStoreMember(gomock.Any(), int64(1), "editor@test.com", model.StoreEditor).Return(testMember, tC.err)
+			}
+
+			router := setupTestRouter(svc)
+			rec, r := newTestParameters(http.MethodPut, fmt.Sprintf("/v1/stores/%s/members", tC.id), tC.input)
+
+			router.ServeHTTP(rec, r)
+
+			body, _ := ioutil.ReadAll(rec.Result().Body)
+
+			assert.Equal(t, tC.rcode, rec.Result().StatusCode)
+			assert.JSONEq(t, tC.rdata, string(body))
+		})
+	}
+}
+
+func Test_deleteStoreMemberHandler(t *testing.T) {
+	testCases := []struct {
+		desc   string
+		id     string
+		userID string
+		err    error
+		rcode  int
+		rdata  string
+	}{
+		{
+			desc:   "success",
+			id:     "1",
+			userID: "3",
+			err:    nil,
+			rcode:  http.StatusNoContent,
+			rdata:  "",
+		},
+		{
+			desc:   "invalid user ID",
+			id:     "1",
+			userID: "test",
+			err:    errSkip,
+			rcode:  http.StatusBadRequest,
+			rdata:  `{"error":"invalid user ID"}`,
+		},
+		{
+			desc:   "not found",
+			id:     "1",
+			userID: "3",
+			err:    service.ErrNotFound,
+			rcode:  http.StatusNotFound,
+			rdata:  `{"error":"member not found"}`,
+		},
+		{
+			desc:   "last owner",
+			id:     "1",
+			userID: "3",
+			err:    service.ErrLastOwner,
+			rcode:  http.StatusConflict,
+			rdata:  `{"error":"store must have an owner"}`,
+		},
+		{
+			desc:   "internal error",
+			id:     "1",
+			userID: "3",
+			err:    errTest,
+			rcode:  http.StatusInternalServerError,
+			rdata:  `{"error":"internal error"}`,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			svc := service.NewMockService(ctrl)
+			if tC.err != errSkip {
+				svc.EXPECT().RemoveStoreMember(gomock.Any(), int64(1), int64(3)).Return(tC.err)
+			}
+
+			router := setupTestRouter(svc)
+			rec, r := newTestParameters(http.MethodDelete, fmt.Sprintf("/v1/stores/%s/members/%s", tC.id, tC.userID), "")
+
+			router.ServeHTTP(rec, r)
+
+			body, _ := ioutil.ReadAll(rec.Result().Body)
+
+			assert.Equal(t, tC.rcode, rec.Result().StatusCode)
+			if tC.rdata == "" {
+				assert.Empty(t, string(body))
+				return
+			}
+			assert.JSONEq(t, tC.rdata, string(body))
+		})
+	}
+}
+
+func Test_allowStoreMemberMiddleware(t *testing.T) {
+	testCases := []struct {
+		desc   string
+		id     string
+		claims *auth.AccessTokenClaims
+		member model.StoreMember
+		err    error
+		rcode  int
+		rdata  string
+	}{
+		{
+			desc:   "allow granted",
+			id:     "1",
+			claims: &auth.AccessTokenClaims{UserID: 3, Permissions: []model.Permission{model.PermissionPositionWrite}},
+			err:    errSkip,
+			rcode:  http.StatusOK,
+			rdata:  `{"result":"OK"}`,
+		},
+		{
+			desc:   "allow member",
+			id:     "1",
+			claims: &auth.AccessTokenClaims{UserID: 3},
+			member: testMember,
+			err:    nil,
+			rcode:  http.StatusOK,
+			rdata:  `{"result":"OK"}`,
+		},
+		{
+			desc:   "allow member with api key in scope",
+			id:     "1",
+			claims: &auth.AccessTokenClaims{UserID: 3, APIKeyID: "1", Scopes: []model.Permission{model.PermissionPositionWrite}},
+			member: testMember,
+			err:    nil,
+			rcode:  http.StatusOK,
+			rdata:  `{"result":"OK"}`,
+		},
+		{
+			desc:   "block member with api key out of scope",
+			id:     "1",
+			claims: &auth.AccessTokenClaims{UserID: 3, APIKeyID: "1", Scopes: []model.Permission{model.PermissionStoreWrite}},
+			err:    errSkip,
+			rcode:  http.StatusForbidden,
+			rdata:  `{"error":"access not allowed"}`,
+		},
+		{
+			desc:   "block anonymous",
+			id:     "1",
+			claims: nil,
+			err:    errSkip,
+			rcode:  http.StatusUnauthorized,
+			rdata:  `{"error":"authentication required"}`,
+		},
+		{
+			desc:   "block member with another role",
+			id:     "1",
+			claims: &auth.AccessTokenClaims{UserID: 3},
+			member: model.StoreMember{StoreID: 1, UserID: 3, Role: model.StoreOwner},
+			err:    nil,
+			rcode:  http.StatusForbidden,
+			rdata:  `{"error":"access not allowed"}`,
+		},
+		{
+			desc:   "block non member",
+			id:     "1",
+			claims: &auth.AccessTokenClaims{UserID: 3},
+			err:    service.ErrNotFound,
+			rcode:  http.StatusForbidden,
+			rdata:  `{"error":"access not allowed"}`,
+		},
+		{
+			desc:   "invalid store ID",
+			id:     "test",
+			claims: &auth.AccessTokenClaims{UserID: 3},
+			err:    errSkip,
+			rcode:  http.StatusBadRequest,
+			rdata:  `{"error":"invalid store ID"}`,
+		},
+		{
+			desc:   "internal error",
+			id:     "1",
+			claims: &auth.AccessTokenClaims{UserID: 3},
+			err:    errTest,
+			rcode:  http.StatusInternalServerError,
+			rdata:  `{"error":"internal error"}`,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			svc := service.NewMockService(ctrl)
+			if tC.err != errSkip {
+				svc.EXPECT().GetStoreMember(gomock.Any(), int64(1), int64(3)).Return(tC.member, tC.err)
+			}
+
+			logger, _ := test.NewNullLogger()
+			ctx := context.WithValue(context.Background(), loggerKey{}, logger)
+			if tC.claims != nil {
+				ctx = context.WithValue(ctx, claimsKey{}, *tC.claims)
+			}
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/stores/%s", tC.id), nil).WithContext(ctx)
+
+			srv := &server{s: svc}
+			r := chi.NewRouter()
+			r.With(srv.allowStoreMemberMiddleware(model.PermissionPositionWrite, model.StoreEditor)).
+				Post("/stores/{id}", func(w http.ResponseWriter, r *http.Request) {
+					w.WriteHeader(http.StatusOK)
+					w.Write([]byte(`{"result":"OK"}`))
+				})
+
+			r.ServeHTTP(rec, req)
+
+			body, _ := ioutil.ReadAll(rec.Result().Body)
+
+			assert.Equal(t, tC.rcode, rec.Result().StatusCode)
+			assert.JSONEq(t, tC.rdata, string(body))
+		})
+	}
+}
@@ -10,6 +10,8 @@ import (
 	"github.com/sirupsen/logrus"
 	"github.com/tomasen/realip"
 	"github.com/vliubezny/gstore/internal/auth"
+	"github.com/vliubezny/gstore/internal/model"
+	"github.com/vliubezny/gstore/internal/service"
 )
 
 const (
@@ -58,12 +60,30 @@ func recoveryMiddleware(next http.Handler) http.Handler {
 	})
 }
 
-// jwtAuthMiddleware authenticates user with JWT.
-func jwtAuthMiddleware(accessTokenValidator auth.AccessTokenValidator) func(http.Handler) http.Handler {
+// jwtAuthMiddleware authenticates user with JWT, revoked tokens are rejected.
+// Machine clients may authenticate with API key instead, it results in the same claims limited by key scopes.
+func jwtAuthMiddleware(accessTokenValidator auth.AccessTokenValidator, isRevoked auth.RevocationChecker,
+	apiKeyValidator auth.APIKeyValidator) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			l := getLogger(r)
 
+			if key := extractAPIKey(r); key != "" {
+				claims, err := apiKeyValidator(r.Context(), key)
+				if err != nil {
+					if errors.Is(err, auth.ErrInvalidToken) {
+						writeError(l.WithError(err), w, http.StatusUnauthorized, "invalid api key")
+						return
+					}
+
+					writeInternalError(l.WithError(err), w, "failed to validate api key")
+					return
+				}
+
+				next.ServeHTTP(w, withClaims(r, l.WithField("apiKeyID", claims.APIKeyID), claims))
+				return
+			}
+
 			token := extractBearer(r)
 			if token == "" {
 				writeError(l, w, http.StatusUnauthorized, "missing token")
@@ -81,30 +101,183 @@ func jwtAuthMiddleware(accessTokenValidator auth.AccessTokenValidator) func(http
 				return
 			}
 
-			ctx := context.WithValue(r.Context(), claimsKey{}, claims)
-			ctx = context.WithValue(ctx, loggerKey{}, l.WithField("userID", claims.UserID))
+			if isRevoked(claims) {
+				writeError(l.WithField("userID", claims.UserID), w, http.StatusUnauthorized, "access token has been revoked")
+				return
+			}
 
-			next.ServeHTTP(w, r.WithContext(ctx))
+			next.ServeHTTP(w, withClaims(r, l, claims))
 		})
 	}
 }
 
-// allowAdminMiddleware authorizes admin to access resource.
-func allowAdminMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		l := getLogger(r)
+// withClaims populates request context with claims of authenticated user.
+func withClaims(r *http.Request, l logrus.FieldLogger, claims auth.AccessTokenClaims) *http.Request {
+	ctx := context.WithValue(r.Context(), claimsKey{}, claims)
+	ctx = context.WithValue(ctx, loggerKey{}, l.WithField("userID", claims.UserID))
+	return r.WithContext(ctx)
+}
 
-		claims, ok := r.Context().Value(claimsKey{}).(auth.AccessTokenClaims)
-		if !ok {
-			writeError(l, w, http.StatusUnauthorized, "authentication required")
-			return
+// optionalJWTAuthMiddleware authenticates user with JWT or API key if provided.
+func optionalJWTAuthMiddleware(accessTokenValidator auth.AccessTokenValidator, isRevoked auth.RevocationChecker,
+	apiKeyValidator auth.APIKeyValidator) func(http.Handler) http.Handler {
+	authenticate := jwtAuthMiddleware(accessTokenValidator, isRevoked, apiKeyValidator)
+	return func(next http.Handler) http.Handler {
+		authenticated := authenticate(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if extractBearer(r) == "" && extractAPIKey(r) == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			authenticated.ServeHTTP(w, r)
+		})
+	}
+}
+
+// verifiedEmailMiddleware denies modifying requests of users with unverified email
+// if verification policy requires it.
+func verifiedEmailMiddleware(policy auth.VerificationPolicy) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if policy != auth.VerificationBeforeWrite {
+			return next
 		}
 
-		if !claims.IsAdmin {
-			writeError(l, w, http.StatusForbidden, "access not allowed")
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions:
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			l := getLogger(r)
+
+			claims, ok := r.Context().Value(claimsKey{}).(auth.AccessTokenClaims)
+			if !ok {
+				writeError(l, w, http.StatusUnauthorized, "authentication required")
+				return
+			}
+
+			if !claims.EmailVerified {
+				writeError(l, w, http.StatusForbidden, "email address is not verified")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// denyDelegatedMiddleware denies requests authenticated with API key or token of OAuth client,
+// so leaked key or compromised client can't be used to manage credentials of the user.
+func denyDelegatedMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch claims := getClaims(r); {
+		case claims.APIKeyID != "":
+			writeError(getLogger(r), w, http.StatusForbidden, "not allowed with api key")
+			return
+		case claims.ClientID != "":
+			writeError(getLogger(r), w, http.StatusForbidden, "not allowed with oauth token")
 			return
 		}
 
 		next.ServeHTTP(w, r)
 	})
 }
+
+// allowScopeMiddleware authorizes delegated access within one of the scopes,
+// anonymous requests and user's own tokens are not limited.
+func allowScopeMiddleware(scopes ...model.Permission) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, ok := r.Context().Value(claimsKey{}).(auth.AccessTokenClaims)
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			for _, sc := range scopes {
+				if claims.InScope(sc) {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+
+			writeError(getLogger(r).WithField("scopes", scopes), w, http.StatusForbidden, "access not allowed")
+		})
+	}
+}
+
+// allowPermissionMiddleware authorizes user granted the permission to access resource.
+func allowPermissionMiddleware(p model.Permission) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			l := getLogger(r)
+
+			claims, ok := r.Context().Value(claimsKey{}).(auth.AccessTokenClaims)
+			if !ok {
+				writeError(l, w, http.StatusUnauthorized, "authentication required")
+				return
+			}
+
+			if !claims.HasPermission(p) {
+				writeError(l.WithField("permission", p), w, http.StatusForbidden, "access not allowed")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// allowStoreMemberMiddleware authorizes user granted the permission or member of the store from URL
+// with one of the roles to access resource.
+func (s *server) allowStoreMemberMiddleware(p model.Permission, roles ...model.StoreRole) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			l := getLogger(r)
+
+			claims, ok := r.Context().Value(claimsKey{}).(auth.AccessTokenClaims)
+			if !ok {
+				writeError(l, w, http.StatusUnauthorized, "authentication required")
+				return
+			}
+
+			if claims.HasPermission(p) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if !claims.InScope(p) {
+				writeError(l.WithField("permission", p), w, http.StatusForbidden, "access not allowed")
+				return
+			}
+
+			storeID, err := getIDFromURL(r, "id")
+			if err != nil {
+				writeError(l.WithError(err), w, http.StatusBadRequest, "invalid store ID")
+				return
+			}
+
+			m, err := s.s.GetStoreMember(r.Context(), storeID, claims.UserID)
+			if err != nil {
+				if errors.Is(err, service.ErrNotFound) {
+					writeError(l.WithField("permission", p), w, http.StatusForbidden, "access not allowed")
+					return
+				}
+
+				writeInternalError(l.WithError(err), w, "failed to get store member")
+				return
+			}
+
+			for _, role := range roles {
+				if m.Role == role {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+
+			writeError(l.WithField("storeRole", m.Role), w, http.StatusForbidden, "access not allowed")
+		})
+	}
+}
@@ -14,6 +14,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/vliubezny/gstore/internal/auth"
+	"github.com/vliubezny/gstore/internal/model"
 )
 
 func Test_setContentTypeMiddleware(t *testing.T) {
@@ -81,25 +82,144 @@ func Test_recoveryMiddleware(t *testing.T) {
 func Test_jwtAuthMiddleware(t *testing.T) {
 	testClaims := auth.AccessTokenClaims{UserID: 1}
 	testCases := []struct {
-		desc  string
-		token string
-		err   error
-		rcode int
-		rdata string
+		desc    string
+		header  string
+		token   string
+		err     error
+		key     string
+		keyErr  error
+		revoked bool
+		rcode   int
+		rdata   string
 	}{
 		{
-			desc:  "allow valid token",
-			token: "testtoken",
-			err:   nil,
-			rcode: http.StatusOK,
-			rdata: `{"result":"OK"}`,
+			desc:   "allow valid token",
+			header: "Bearer testtoken",
+			token:  "testtoken",
+			err:    nil,
+			rcode:  http.StatusOK,
+			rdata:  `{"result":"OK"}`,
 		},
 		{
-			desc:  "missing token",
-			token: "",
-			err:   nil,
-			rcode: http.StatusUnauthorized,
-			rdata: `{"error":"missing token"}`,
+			desc:   "missing token",
+			header: "",
+			err:    nil,
+			rcode:  http.StatusUnauthorized,
+			rdata:  `{"error":"missing token"}`,
+		},
+		{
+			desc:   "invalid token",
+			header: "Bearer testtoken",
+			token:  "testtoken",
+			err:    auth.ErrInvalidToken,
+			rcode:  http.StatusUnauthorized,
+			rdata:  `{"error":"invalid access token"}`,
+		},
+		{
+			desc:    "revoked token",
+			header:  "Bearer testtoken",
+			token:   "testtoken",
+			err:     nil,
+			revoked: true,
+			rcode:   http.StatusUnauthorized,
+			rdata:   `{"error":"access token has been revoked"}`,
+		},
+		{
+			desc:   "internal error",
+			header: "Bearer testtoken",
+			token:  "testtoken",
+			err:    assert.AnError,
+			rcode:  http.StatusInternalServerError,
+			rdata:  `{"error":"internal error"}`,
+		},
+		{
+			desc:   "allow valid api key",
+			header: "ApiKey testkey",
+			key:    "testkey",
+			keyErr: nil,
+			rcode:  http.StatusOK,
+			rdata:  `{"result":"OK"}`,
+		},
+		{
+			desc:   "invalid api key",
+			header: "ApiKey testkey",
+			key:    "testkey",
+			keyErr: auth.ErrInvalidToken,
+			rcode:  http.StatusUnauthorized,
+			rdata:  `{"error":"invalid api key"}`,
+		},
+		{
+			desc:   "api key internal error",
+			header: "ApiKey testkey",
+			key:    "testkey",
+			keyErr: assert.AnError,
+			rcode:  http.StatusInternalServerError,
+			rdata:  `{"error":"internal error"}`,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			logger, _ := test.NewNullLogger()
+			ctx := context.WithValue(context.Background(), loggerKey{}, logger)
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/", nil).WithContext(ctx)
+
+			if tC.header != "" {
+				req.Header.Set("Authorization", tC.header)
+			}
+
+			h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				c := r.Context().Value(claimsKey{})
+				assert.Equal(t, testClaims, c)
+
+				w.WriteHeader(http.StatusOK)
+				w.Write([]byte(`{"result":"OK"}`))
+			})
+
+			jwtAuthMiddleware(func(token string) (auth.AccessTokenClaims, error) {
+				assert.Equal(t, tC.token, token)
+				return testClaims, tC.err
+			}, func(c auth.AccessTokenClaims) bool {
+				assert.Equal(t, testClaims, c)
+				return tC.revoked
+			}, func(_ context.Context, key string) (auth.AccessTokenClaims, error) {
+				assert.Equal(t, tC.key, key)
+				return testClaims, tC.keyErr
+			})(h).ServeHTTP(rec, req)
+
+			body, _ := ioutil.ReadAll(rec.Result().Body)
+
+			assert.Equal(t, tC.rcode, rec.Result().StatusCode)
+			assert.JSONEq(t, tC.rdata, string(body))
+		})
+	}
+}
+
+func Test_optionalJWTAuthMiddleware(t *testing.T) {
+	testClaims := auth.AccessTokenClaims{UserID: 1}
+	testCases := []struct {
+		desc   string
+		token  string
+		err    error
+		claims interface{}
+		rcode  int
+		rdata  string
+	}{
+		{
+			desc:   "allow valid token",
+			token:  "testtoken",
+			err:    nil,
+			claims: testClaims,
+			rcode:  http.StatusOK,
+			rdata:  `{"result":"OK"}`,
+		},
+		{
+			desc:   "allow missing token",
+			token:  "",
+			err:    nil,
+			claims: nil,
+			rcode:  http.StatusOK,
+			rdata:  `{"result":"OK"}`,
 		},
 		{
 			desc:  "invalid token",
@@ -108,13 +228,6 @@ func Test_jwtAuthMiddleware(t *testing.T) {
 			rcode: http.StatusUnauthorized,
 			rdata: `{"error":"invalid access token"}`,
 		},
-		{
-			desc:  "internal error",
-			token: "testtoken",
-			err:   assert.AnError,
-			rcode: http.StatusInternalServerError,
-			rdata: `{"error":"internal error"}`,
-		},
 	}
 	for _, tC := range testCases {
 		t.Run(tC.desc, func(t *testing.T) {
@@ -129,15 +242,19 @@ func Test_jwtAuthMiddleware(t *testing.T) {
 
 			h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 				c := r.Context().Value(claimsKey{})
-				assert.Equal(t, testClaims, c)
+				assert.Equal(t, tC.claims, c)
 
 				w.WriteHeader(http.StatusOK)
 				w.Write([]byte(`{"result":"OK"}`))
 			})
 
-			jwtAuthMiddleware(func(token string) (auth.AccessTokenClaims, error) {
+			optionalJWTAuthMiddleware(func(token string) (auth.AccessTokenClaims, error) {
 				assert.Equal(t, tC.token, token)
 				return testClaims, tC.err
+			}, func(_ auth.AccessTokenClaims) bool {
+				return false
+			}, func(_ context.Context, _ string) (auth.AccessTokenClaims, error) {
+				return testClaims, tC.err
 			})(h).ServeHTTP(rec, req)
 
 			body, _ := ioutil.ReadAll(rec.Result().Body)
@@ -148,7 +265,212 @@ func Test_jwtAuthMiddleware(t *testing.T) {
 	}
 }
 
-func Test_allowAdminMiddleware(t *testing.T) {
+func Test_verifiedEmailMiddleware(t *testing.T) {
+	testCases := []struct {
+		desc   string
+		policy auth.VerificationPolicy
+		method string
+		claims *auth.AccessTokenClaims
+		rcode  int
+		rdata  string
+	}{
+		{
+			desc:   "allow verified",
+			policy: auth.VerificationBeforeWrite,
+			method: http.MethodPost,
+			claims: &auth.AccessTokenClaims{UserID: 1, EmailVerified: true},
+			rcode:  http.StatusOK,
+			rdata:  `{"result":"OK"}`,
+		},
+		{
+			desc:   "allow unverified to read",
+			policy: auth.VerificationBeforeWrite,
+			method: http.MethodGet,
+			claims: &auth.AccessTokenClaims{UserID: 1},
+			rcode:  http.StatusOK,
+			rdata:  `{"result":"OK"}`,
+		},
+		{
+			desc:   "allow unverified if verification is optional",
+			policy: auth.VerificationOptional,
+			method: http.MethodPost,
+			claims: &auth.AccessTokenClaims{UserID: 1},
+			rcode:  http.StatusOK,
+			rdata:  `{"result":"OK"}`,
+		},
+		{
+			desc:   "block unverified to write",
+			policy: auth.VerificationBeforeWrite,
+			method: http.MethodPost,
+			claims: &auth.AccessTokenClaims{UserID: 1},
+			rcode:  http.StatusForbidden,
+			rdata:  `{"error":"email address is not verified"}`,
+		},
+		{
+			desc:   "block anonymous",
+			policy: auth.VerificationBeforeWrite,
+			method: http.MethodPost,
+			claims: nil,
+			rcode:  http.StatusUnauthorized,
+			rdata:  `{"error":"authentication required"}`,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			logger, _ := test.NewNullLogger()
+			ctx := context.WithValue(context.Background(), loggerKey{}, logger)
+			if tC.claims != nil {
+				ctx = context.WithValue(ctx, claimsKey{}, *tC.claims)
+			}
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(tC.method, "/", nil).WithContext(ctx)
+
+			h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+				w.Write([]byte(`{"result":"OK"}`))
+			})
+
+			verifiedEmailMiddleware(tC.policy)(h).ServeHTTP(rec, req)
+
+			body, _ := ioutil.ReadAll(rec.Result().Body)
+
+			assert.Equal(t, tC.rcode, rec.Result().StatusCode)
+			assert.JSONEq(t, tC.rdata, string(body))
+		})
+	}
+}
+
+func Test_denyDelegatedMiddleware(t *testing.T) {
+	testCases := []struct {
+		desc   string
+		claims auth.AccessTokenClaims
+		rcode  int
+		rdata  string
+	}{
+		{
+			desc:   "allow access token",
+			claims: auth.AccessTokenClaims{UserID: 1},
+			rcode:  http.StatusOK,
+			rdata:  `{"result":"OK"}`,
+		},
+		{
+			desc:   "block api key",
+			claims: auth.AccessTokenClaims{UserID: 1, APIKeyID: "1"},
+			rcode:  http.StatusForbidden,
+			rdata:  `{"error":"not allowed with api key"}`,
+		},
+		{
+			desc:   "block oauth token",
+			claims: auth.AccessTokenClaims{UserID: 1, ClientID: "1"},
+			rcode:  http.StatusForbidden,
+			rdata:  `{"error":"not allowed with oauth token"}`,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			logger, _ := test.NewNullLogger()
+			ctx := context.WithValue(context.Background(), loggerKey{}, logger)
+			ctx = context.WithValue(ctx, claimsKey{}, tC.claims)
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/", nil).WithContext(ctx)
+
+			h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+				w.Write([]byte(`{"result":"OK"}`))
+			})
+
+			denyDelegatedMiddleware(h).ServeHTTP(rec, req)
+
+			body, _ := ioutil.ReadAll(rec.Result().Body)
+
+			assert.Equal(t, tC.rcode, rec.Result().StatusCode)
+			assert.JSONEq(t, tC.rdata, string(body))
+		})
+	}
+}
+
+func Test_allowScopeMiddleware(t *testing.T) {
+	testCases := []struct {
+		desc   string
+		claims *auth.AccessTokenClaims
+		rcode  int
+		rdata  string
+	}{
+		{
+			desc:   "allow anonymous",
+			claims: nil,
+			rcode:  http.StatusOK,
+			rdata:  `{"result":"OK"}`,
+		},
+		{
+			desc:   "allow access token",
+			claims: &auth.AccessTokenClaims{UserID: 1},
+			rcode:  http.StatusOK,
+			rdata:  `{"result":"OK"}`,
+		},
+		{
+			desc:   "allow api key in scope",
+			claims: &auth.AccessTokenClaims{UserID: 1, APIKeyID: "1", Scopes: []model.Permission{model.ScopeOrders}},
+			rcode:  http.StatusOK,
+			rdata:  `{"result":"OK"}`,
+		},
+		{
+			desc:   "allow api key in any of scopes",
+			claims: &auth.AccessTokenClaims{UserID: 1, APIKeyID: "1", Scopes: []model.Permission{model.PermissionOrderRead}},
+			rcode:  http.StatusOK,
+			rdata:  `{"result":"OK"}`,
+		},
+		{
+			desc:   "block api key out of scope",
+			claims: &auth.AccessTokenClaims{UserID: 1, APIKeyID: "1", Scopes: []model.Permission{model.PermissionPositionWrite}},
+			rcode:  http.StatusForbidden,
+			rdata:  `{"error":"access not allowed"}`,
+		},
+		{
+			desc:   "allow oauth token in scope",
+			claims: &auth.AccessTokenClaims{UserID: 1, ClientID: "1", Scopes: []model.Permission{model.ScopeOrders}},
+			rcode:  http.StatusOK,
+			rdata:  `{"result":"OK"}`,
+		},
+		{
+			desc:   "block oauth token out of scope",
+			claims: &auth.AccessTokenClaims{UserID: 1, ClientID: "1", Scopes: []model.Permission{model.ScopeCart}},
+			rcode:  http.StatusForbidden,
+			rdata:  `{"error":"access not allowed"}`,
+		},
+		{
+			desc:   "block oauth token without scopes",
+			claims: &auth.AccessTokenClaims{UserID: 1, ClientID: "1"},
+			rcode:  http.StatusForbidden,
+			rdata:  `{"error":"access not allowed"}`,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			logger, _ := test.NewNullLogger()
+			ctx := context.WithValue(context.Background(), loggerKey{}, logger)
+			if tC.claims != nil {
+				ctx = context.WithValue(ctx, claimsKey{}, *tC.claims)
+			}
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/", nil).WithContext(ctx)
+
+			h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+				w.Write([]byte(`{"result":"OK"}`))
+			})
+
+			allowScopeMiddleware(model.ScopeOrders, model.PermissionOrderRead)(h).ServeHTTP(rec, req)
+
+			body, _ := ioutil.ReadAll(rec.Result().Body)
+
+			assert.Equal(t, tC.rcode, rec.Result().StatusCode)
+			assert.JSONEq(t, tC.rdata, string(body))
+		})
+	}
+}
+
+func Test_allowPermissionMiddleware(t *testing.T) {
 	testCases := []struct {
 		desc   string
 		claims *auth.AccessTokenClaims
@@ -156,8 +478,8 @@ func Test_allowAdminMiddleware(t *testing.T) {
 		rdata  string
 	}{
 		{
-			desc:   "allow admin",
-			claims: &auth.AccessTokenClaims{UserID: 1, IsAdmin: true},
+			desc:   "allow granted",
+			claims: &auth.AccessTokenClaims{UserID: 1, Permissions: []model.Permission{model.PermissionStoreWrite, model.PermissionOrderRead}},
 			rcode:  http.StatusOK,
 			rdata:  `{"result":"OK"}`,
 		},
@@ -168,8 +490,8 @@ func Test_allowAdminMiddleware(t *testing.T) {
 			rdata:  `{"error":"authentication required"}`,
 		},
 		{
-			desc:   "block non admin",
-			claims: &auth.AccessTokenClaims{UserID: 1, IsAdmin: false},
+			desc:   "block not granted",
+			claims: &auth.AccessTokenClaims{UserID: 1, Permissions: []model.Permission{model.PermissionStoreWrite}},
 			rcode:  http.StatusForbidden,
 			rdata:  `{"error":"access not allowed"}`,
 		},
@@ -189,7 +511,7 @@ func Test_allowAdminMiddleware(t *testing.T) {
 				w.Write([]byte(`{"result":"OK"}`))
 			})
 
-			allowAdminMiddleware(h).ServeHTTP(rec, req)
+			allowPermissionMiddleware(model.PermissionOrderRead)(h).ServeHTTP(rec, req)
 
 			body, _ := ioutil.ReadAll(rec.Result().Body)
 
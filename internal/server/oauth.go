@@ -0,0 +1,342 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/vliubezny/gstore/internal/auth"
+	"github.com/vliubezny/gstore/internal/model"
+)
+
+// OAuth 2.0 error codes (RFC 6749 section 5.2).
+const (
+	oauthErrInvalidRequest       = "invalid_request"
+	oauthErrInvalidClient        = "invalid_client"
+	oauthErrInvalidGrant         = "invalid_grant"
+	oauthErrUnauthorizedClient   = "unauthorized_client"
+	oauthErrUnsupportedGrantType = "unsupported_grant_type"
+	oauthErrInvalidScope         = "invalid_scope"
+)
+
+const tokenTypeBearer = "Bearer"
+
+func (s *server) registerOAuthClientHandler(w http.ResponseWriter, r *http.Request) {
+	l := getLogger(r)
+
+	var req oauthClientRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(l.WithError(err), w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := validate(&req); err != nil {
+		writeError(l.WithError(err), w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if !req.Confidential && len(req.RedirectURIs) == 0 {
+		writeError(l, w, http.StatusBadRequest, "public client must have redirect uris")
+		return
+	}
+
+	scopes := make([]model.Permission, len(req.Scopes))
+	for i, sc := range req.Scopes {
+		scopes[i] = model.Permission(sc)
+	}
+
+	secret, c, err := s.a.RegisterOAuthClient(r.Context(), model.OAuthClient{
+		UserID:       getClaims(r).UserID,
+		Name:         req.Name,
+		RedirectURIs: req.RedirectURIs,
+		Scopes:       scopes,
+	}, req.Confidential)
+	if err != nil {
+		if errors.Is(err, auth.ErrUnknownScope) {
+			writeError(l.WithError(err), w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		writeInternalError(l.WithError(err), w, "fail to register oauth client")
+		return
+	}
+
+	l.WithField("clientID", c.ID).Info("oauth client registered")
+
+	writeOK(l, w, registeredOAuthClient{
+		oauthClient: fromOAuthClientModel(c),
+		Secret:      secret,
+	})
+}
+
+func (s *server) getOAuthClientsHandler(w http.ResponseWriter, r *http.Request) {
+	l := getLogger(r)
+
+	clients, err := s.a.GetOAuthClients(r.Context(), getClaims(r).UserID)
+	if err != nil {
+		writeInternalError(l.WithError(err), w, "fail to get oauth clients")
+		return
+	}
+
+	resp := make([]oauthClient, len(clients))
+	for i, c := range clients {
+		resp[i] = fromOAuthClientModel(c)
+	}
+
+	writeOK(l, w, resp)
+}
+
+func (s *server) deleteOAuthClientHandler(w http.ResponseWriter, r *http.Request) {
+	l := getLogger(r)
+
+	id := chi.URLParam(r, "id")
+	if _, err := uuid.Parse(id); err != nil {
+		writeError(l.WithError(err), w, http.StatusBadRequest, "invalid client ID")
+		return
+	}
+
+	if err := s.a.DeleteOAuthClient(r.Context(), getClaims(r).UserID, id); err != nil {
+		if errors.Is(err, auth.ErrNotFound) {
+			writeError(l.WithError(err), w, http.StatusNotFound, "oauth client not found")
+			return
+		}
+
+		writeInternalError(l.WithError(err), w, "fail to delete oauth client")
+		return
+	}
+
+	l.WithField("clientID", id).Info("oauth client deleted")
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// authorizeHandler issues authorization code once the user approved access of the client,
+// consent page of web application redirects user agent to the returned URI.
+func (s *server) authorizeHandler(w http.ResponseWriter, r *http.Request) {
+	l := getLogger(r)
+
+	var req authorizationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(l.WithError(err), w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := validate(&req); err != nil {
+		writeError(l.WithError(err), w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	redirectURI, err := url.Parse(req.RedirectURI)
+	if err != nil {
+		writeError(l.WithError(err), w, http.StatusBadRequest, "invalid redirect uri")
+		return
+	}
+
+	code, err := s.a.AuthorizeOAuthClient(r.Context(), getClaims(r).UserID, auth.AuthorizationRequest{
+		ClientID:      req.ClientID,
+		RedirectURI:   req.RedirectURI,
+		Scopes:        parseScope(req.Scope),
+		CodeChallenge: req.CodeChallenge,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, auth.ErrInvalidClient),
+			errors.Is(err, auth.ErrInvalidRedirectURI),
+			errors.Is(err, auth.ErrInvalidScope):
+			writeError(l.WithError(err), w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		writeInternalError(l.WithError(err), w, "fail to authorize oauth client")
+		return
+	}
+
+	q := redirectURI.Query()
+	q.Set("code", code)
+	if req.State != "" {
+		q.Set("state", req.State)
+	}
+	redirectURI.RawQuery = q.Encode()
+
+	l.WithField("clientID", req.ClientID).Info("oauth client authorized")
+
+	writeOK(l, w, authorizationResponse{RedirectURI: redirectURI.String()})
+}
+
+// oauthTokenHandler implements token endpoint (RFC 6749 section 3.2).
+func (s *server) oauthTokenHandler(w http.ResponseWriter, r *http.Request) {
+	l := getLogger(r)
+
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+
+	if err := r.ParseForm(); err != nil {
+		writeOAuthError(l.WithError(err), w, http.StatusBadRequest, oauthErrInvalidRequest, "malformed form")
+		return
+	}
+
+	req := auth.OAuthTokenRequest{
+		GrantType:    r.PostForm.Get("grant_type"),
+		Code:         r.PostForm.Get("code"),
+		RedirectURI:  r.PostForm.Get("redirect_uri"),
+		CodeVerifier: r.PostForm.Get("code_verifier"),
+		RefreshToken: r.PostForm.Get("refresh_token"),
+		Scopes:       parseScope(r.PostForm.Get("scope")),
+	}
+
+	if req.GrantType == "" {
+		writeOAuthError(l, w, http.StatusBadRequest, oauthErrInvalidRequest, "grant_type is required")
+		return
+	}
+
+	var ok bool
+	if req.ClientID, req.ClientSecret, ok = extractClientCredentials(r); !ok {
+		writeOAuthError(l, w, http.StatusBadRequest, oauthErrInvalidRequest, "malformed client credentials")
+		return
+	}
+
+	token, err := s.a.IssueOAuthToken(r.Context(), req, getClient(r))
+	if err != nil {
+		l = l.WithError(err).WithField("clientID", req.ClientID)
+		switch {
+		case errors.Is(err, auth.ErrInvalidClient):
+			w.Header().Set("WWW-Authenticate", `Basic realm="gstore"`)
+			writeOAuthError(l, w, http.StatusUnauthorized, oauthErrInvalidClient, "client authentication failed")
+		case errors.Is(err, auth.ErrInvalidGrant):
+			writeOAuthError(l, w, http.StatusBadRequest, oauthErrInvalidGrant, err.Error())
+		case errors.Is(err, auth.ErrUnauthorizedClient):
+			writeOAuthError(l, w, http.StatusBadRequest, oauthErrUnauthorizedClient, err.Error())
+		case errors.Is(err, auth.ErrUnsupportedGrantType):
+			writeOAuthError(l, w, http.StatusBadRequest, oauthErrUnsupportedGrantType, err.Error())
+		case errors.Is(err, auth.ErrInvalidScope):
+			writeOAuthError(l, w, http.StatusBadRequest, oauthErrInvalidScope, err.Error())
+		default:
+			writeInternalError(l, w, "fail to issue oauth token")
+		}
+		return
+	}
+
+	writeOK(l, w, oauthToken{
+		AccessToken:  token.AccessToken,
+		TokenType:    tokenTypeBearer,
+		ExpiresIn:    int64(token.ExpiresIn.Seconds()),
+		RefreshToken: token.RefreshToken,
+		Scope:        formatScope(token.Scopes),
+	})
+}
+
+// introspectHandler implements token introspection for confidential clients (RFC 7662),
+// client can introspect only tokens issued to itself, so it can't learn about other tokens of the user.
+func (s *server) introspectHandler(w http.ResponseWriter, r *http.Request) {
+	l := getLogger(r)
+
+	w.Header().Set("Cache-Control", "no-store")
+
+	if err := r.ParseForm(); err != nil {
+		writeOAuthError(l.WithError(err), w, http.StatusBadRequest, oauthErrInvalidRequest, "malformed form")
+		return
+	}
+
+	token := r.PostForm.Get("token")
+	if token == "" {
+		writeOAuthError(l, w, http.StatusBadRequest, oauthErrInvalidRequest, "token is required")
+		return
+	}
+
+	clientID, secret, ok := extractClientCredentials(r)
+	if !ok {
+		writeOAuthError(l, w, http.StatusBadRequest, oauthErrInvalidRequest, "malformed client credentials")
+		return
+	}
+
+	c, err := s.a.AuthenticateOAuthClient(r.Context(), clientID, secret)
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidClient) {
+			w.Header().Set("WWW-Authenticate", `Basic realm="gstore"`)
+			writeOAuthError(l.WithError(err).WithField("clientID", clientID), w, http.StatusUnauthorized,
+				oauthErrInvalidClient, "client authentication failed")
+			return
+		}
+
+		writeInternalError(l.WithError(err), w, "fail to authenticate oauth client")
+		return
+	}
+
+	// only access tokens of the client are introspected, any other token is reported inactive
+	claims, err := s.a.ValidateAccessToken(token)
+	if err != nil || claims.ClientID != c.ID || s.isRevoked(claims) {
+		writeOK(l, w, introspection{Active: false})
+		return
+	}
+
+	writeOK(l, w, introspection{
+		Active:    true,
+		Scope:     formatScope(claims.Scopes),
+		ClientID:  claims.ClientID,
+		Subject:   strconv.FormatInt(claims.UserID, 10),
+		TokenType: tokenTypeBearer,
+		ExpiresAt: claims.ExpiresAt,
+		IssuedAt:  claims.IssuedAt,
+	})
+}
+
+// extractClientCredentials returns client credentials from Basic authorization header or form.
+func extractClientCredentials(r *http.Request) (string, string, bool) {
+	id, secret, ok := r.BasicAuth()
+	if !ok {
+		return r.PostForm.Get("client_id"), r.PostForm.Get("client_secret"), true
+	}
+
+	// credentials are form-urlencoded before encoding to Basic (RFC 6749 section 2.3.1)
+	id, err := url.QueryUnescape(id)
+	if err != nil {
+		return "", "", false
+	}
+
+	secret, err = url.QueryUnescape(secret)
+	if err != nil {
+		return "", "", false
+	}
+
+	return id, secret, true
+}
+
+// parseScope parses space-delimited list of scopes.
+func parseScope(scope string) []model.Permission {
+	fields := strings.Fields(scope)
+	if len(fields) == 0 {
+		return nil
+	}
+
+	scopes := make([]model.Permission, len(fields))
+	for i, f := range fields {
+		scopes[i] = model.Permission(f)
+	}
+	return scopes
+}
+
+func formatScope(scopes []model.Permission) string {
+	fields := make([]string, len(scopes))
+	for i, s := range scopes {
+		fields[i] = string(s)
+	}
+	return strings.Join(fields, " ")
+}
+
+func writeOAuthError(l logrus.FieldLogger, w http.ResponseWriter, code int, oauthCode, description string) {
+	l.WithField("oauthError", oauthCode).Error(description)
+
+	body, _ := json.Marshal(oauthError{
+		Error:       oauthCode,
+		Description: description,
+	})
+
+	w.WriteHeader(code)
+	w.Write(body)
+}
@@ -0,0 +1,677 @@
+package server
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/vliubezny/gstore/internal/auth"
+	"github.com/vliubezny/gstore/internal/model"
+	"github.com/vliubezny/gstore/internal/service"
+)
+
+const (
+	testClientID      = "a37f2f5e-f698-11e6-8dd4-cb9ced3df976"
+	testCodeChallenge = "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+)
+
+func newFormTestParameters(uri string, form url.Values) (*httptest.ResponseRecorder, *http.Request) {
+	test.NewGlobal()
+	rec := httptest.NewRecorder()
+
+	r := httptest.NewRequest(http.MethodPost, uri, strings.NewReader(form.Encode()))
+	r.Header.Set(headerContentType, "application/x-www-form-urlencoded")
+
+	return rec, r
+}
+
+func Test_registerOAuthClientHandler(t *testing.T) {
+	ts := time.Date(2021, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		desc         string
+		input        string
+		confidential bool
+		rSecret      string
+		rErr         error
+		rcode        int
+		rdata        string
+	}{
+		{
+			desc:         "success - confidential",
+			input:        `{"name":"app", "scopes":["position:write"], "confidential":true}`,
+			confidential: true,
+			rSecret:      "secret",
+			rErr:         nil,
+			rcode:        http.StatusOK,
+			rdata: `{"id":"a37f2f5e-f698-11e6-8dd4-cb9ced3df976", "name":"app", "redirectUris":["https://app.test.com/cb"],
+				"scopes":["position:write"], "confidential":true, "createdAt":"2021-03-01T10:00:00Z", "secret":"secret"}`,
+		},
+		{
+			desc:         "success - public",
+			input:        `{"name":"app", "redirectUris":["https://app.test.com/cb"], "scopes":["position:write"]}`,
+			confidential: false,
+			rSecret:      "",
+			rErr:         nil,
+			rcode:        http.StatusOK,
+			rdata: `{"id":"a37f2f5e-f698-11e6-8dd4-cb9ced3df976", "name":"app", "redirectUris":["https://app.test.com/cb"],
+				"scopes":["position:write"], "confidential":false, "createdAt":"2021-03-01T10:00:00Z"}`,
+		},
+		{
+			desc:  "invalid JSON",
+			input: `{"name":`,
+			rErr:  errSkip,
+			rcode: http.StatusBadRequest,
+			rdata: `{"error":"unexpected EOF"}`,
+		},
+		{
+			desc:  "missing name",
+			input: `{"scopes":["position:write"], "confidential":true}`,
+			rErr:  errSkip,
+			rcode: http.StatusBadRequest,
+			rdata: `{"error":"name is a required field"}`,
+		},
+		{
+			desc:  "invalid redirect uri",
+			input: `{"name":"app", "redirectUris":["test"], "scopes":["position:write"]}`,
+			rErr:  errSkip,
+			rcode: http.StatusBadRequest,
+			rdata: `{"error":"redirectUris[0] must be a valid URL"}`,
+		},
+		{
+			desc:  "public client without redirect uris",
+			input: `{"name":"app", "scopes":["position:write"]}`,
+			rErr:  errSkip,
+			rcode: http.StatusBadRequest,
+			rdata: `{"error":"public client must have redirect uris"}`,
+		},
+		{
+			desc:         "unknown scope",
+			input:        `{"name":"app", "scopes":["position:write"], "confidential":true}`,
+			confidential: true,
+			rErr:         auth.ErrUnknownScope,
+			rcode:        http.StatusBadRequest,
+			rdata:        `{"error":"scope is unknown"}`,
+		},
+		{
+			desc:         "internal error",
+			input:        `{"name":"app", "scopes":["position:write"], "confidential":true}`,
+			confidential: true,
+			rErr:         assert.AnError,
+			rcode:        http.StatusInternalServerError,
+			rdata:        `{"error":"internal error"}`,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			svc := auth.NewMockService(ctrl)
+			if tC.rErr != errSkip {
+				c := model.OAuthClient{
+					ID:           testClientID,
+					UserID:       1,
+					Name:         "app",
+					RedirectURIs: []string{"https://app.test.com/cb"},
+					Scopes:       []model.Permission{model.PermissionPositionWrite},
+					CreatedAt:    ts,
+				}
+				if tC.confidential {
+					c.SecretHash = "hash"
+				}
+
+				svc.EXPECT().RegisterOAuthClient(gomock.Any(), gomock.Any(), tC.confidential).DoAndReturn(
+					func(_ interface{}, req model.OAuthClient, _ bool) (string, model.OAuthClient, error) {
+						assert.Equal(t, testSuperadminClaims.UserID, req.UserID)
+						assert.Equal(t, []model.Permission{model.PermissionPositionWrite}, req.Scopes)
+						return tC.rSecret, c, tC.rErr
+					})
+			}
+
+			router := setupTestRouterWithAuth(nil, svc)
+			rec, r := newTestParameters(http.MethodPost, "/v1/oauth/clients", tC.input)
+
+			router.ServeHTTP(rec, r)
+
+			body, _ := ioutil.ReadAll(rec.Result().Body)
+
+			assert.Equal(t, tC.rcode, rec.Result().StatusCode)
+			assert.JSONEq(t, tC.rdata, string(body))
+		})
+	}
+}
+
+func Test_getOAuthClientsHandler(t *testing.T) {
+	ts := time.Date(2021, 3, 1, 10, 0, 0, 0, time.UTC)
+	clients := []model.OAuthClient{
+		{ID: testClientID, UserID: 1, Name: "app", SecretHash: "hash", RedirectURIs: []string{},
+			Scopes: []model.Permission{model.PermissionPositionWrite}, CreatedAt: ts},
+	}
+
+	testCases := []struct {
+		desc     string
+		rClients []model.OAuthClient
+		rErr     error
+		rcode    int
+		rdata    string
+	}{
+		{
+			desc:     "success",
+			rClients: clients,
+			rErr:     nil,
+			rcode:    http.StatusOK,
+			rdata: `[{"id":"a37f2f5e-f698-11e6-8dd4-cb9ced3df976", "name":"app", "redirectUris":[],
+				"scopes":["position:write"], "confidential":true, "createdAt":"2021-03-01T10:00:00Z"}]`,
+		},
+		{
+			desc:     "internal error",
+			rClients: nil,
+			rErr:     assert.AnError,
+			rcode:    http.StatusInternalServerError,
+			rdata:    `{"error":"internal error"}`,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			svc := auth.NewMockService(ctrl)
+			svc.EXPECT().GetOAuthClients(gomock.Any(), testSuperadminClaims.UserID).Return(tC.rClients, tC.rErr)
+
+			router := setupTestRouterWithAuth(nil, svc)
+			rec, r := newTestParameters(http.MethodGet, "/v1/oauth/clients", "")
+
+			router.ServeHTTP(rec, r)
+
+			body, _ := ioutil.ReadAll(rec.Result().Body)
+
+			assert.Equal(t, tC.rcode, rec.Result().StatusCode)
+			assert.JSONEq(t, tC.rdata, string(body))
+		})
+	}
+}
+
+func Test_deleteOAuthClientHandler(t *testing.T) {
+	testCases := []struct {
+		desc  string
+		id    string
+		err   error
+		rcode int
+		rdata string
+	}{
+		{
+			desc:  "success",
+			id:    testClientID,
+			err:   nil,
+			rcode: http.StatusNoContent,
+			rdata: "",
+		},
+		{
+			desc:  "invalid client ID",
+			id:    "test",
+			err:   errSkip,
+			rcode: http.StatusBadRequest,
+			rdata: `{"error":"invalid client ID"}`,
+		},
+		{
+			desc:  "oauth client not found",
+			id:    testClientID,
+			err:   auth.ErrNotFound,
+			rcode: http.StatusNotFound,
+			rdata: `{"error":"oauth client not found"}`,
+		},
+		{
+			desc:  "internal error",
+			id:    testClientID,
+			err:   assert.AnError,
+			rcode: http.StatusInternalServerError,
+			rdata: `{"error":"internal error"}`,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			svc := auth.NewMockService(ctrl)
+			if tC.err != errSkip {
+				svc.EXPECT().DeleteOAuthClient(gomock.Any(), testSuperadminClaims.UserID, testClientID).Return(tC.err)
+			}
+
+			router := setupTestRouterWithAuth(nil, svc)
+			rec, r := newTestParameters(http.MethodDelete, "/v1/oauth/clients/"+tC.id, "")
+
+			router.ServeHTTP(rec, r)
+
+			body, _ := ioutil.ReadAll(rec.Result().Body)
+
+			assert.Equal(t, tC.rcode, rec.Result().StatusCode)
+			if tC.rdata == "" {
+				assert.Empty(t, string(body))
+			} else {
+				assert.JSONEq(t, tC.rdata, string(body))
+			}
+		})
+	}
+}
+
+func Test_authorizeHandler(t *testing.T) {
+	req := auth.AuthorizationRequest{
+		ClientID:      testClientID,
+		RedirectURI:   "https://app.test.com/cb?tab=1",
+		Scopes:        []model.Permission{model.PermissionPositionWrite, model.PermissionOrderRead},
+		CodeChallenge: testCodeChallenge,
+	}
+
+	testCases := []struct {
+		desc  string
+		input string
+		rErr  error
+		rcode int
+		rdata string
+	}{
+		{
+			desc: "success",
+			input: `{"clientId":"` + testClientID + `", "redirectUri":"https://app.test.com/cb?tab=1",
+				"scope":"position:write order:read", "state":"xyz",
+				"codeChallenge":"` + testCodeChallenge + `", "codeChallengeMethod":"S256"}`,
+			rErr:  nil,
+			rcode: http.StatusOK,
+			rdata: `{"redirectUri":"https://app.test.com/cb?code=code&state=xyz&tab=1"}`,
+		},
+		{
+			desc:  "invalid JSON",
+			input: `{"clientId":`,
+			rErr:  errSkip,
+			rcode: http.StatusBadRequest,
+			rdata: `{"error":"unexpected EOF"}`,
+		},
+		{
+			desc: "plain code challenge",
+			input: `{"clientId":"` + testClientID + `", "redirectUri":"https://app.test.com/cb?tab=1",
+				"codeChallenge":"` + testCodeChallenge + `", "codeChallengeMethod":"plain"}`,
+			rErr:  errSkip,
+			rcode: http.StatusBadRequest,
+			rdata: `{"error":"codeChallengeMethod must be one of [S256]"}`,
+		},
+		{
+			desc: "missing code challenge",
+			input: `{"clientId":"` + testClientID + `", "redirectUri":"https://app.test.com/cb?tab=1",
+				"codeChallengeMethod":"S256"}`,
+			rErr:  errSkip,
+			rcode: http.StatusBadRequest,
+			rdata: `{"error":"codeChallenge is a required field"}`,
+		},
+		{
+			desc: "invalid redirect uri",
+			input: `{"clientId":"` + testClientID + `", "redirectUri":"https://app.test.com/cb?tab=1",
+				"scope":"position:write order:read", "codeChallenge":"` + testCodeChallenge + `", "codeChallengeMethod":"S256"}`,
+			rErr:  auth.ErrInvalidRedirectURI,
+			rcode: http.StatusBadRequest,
+			rdata: `{"error":"invalid redirect uri"}`,
+		},
+		{
+			desc: "invalid client",
+			input: `{"clientId":"` + testClientID + `", "redirectUri":"https://app.test.com/cb?tab=1",
+				"scope":"position:write order:read", "codeChallenge":"` + testCodeChallenge + `", "codeChallengeMethod":"S256"}`,
+			rErr:  auth.ErrInvalidClient,
+			rcode: http.StatusBadRequest,
+			rdata: `{"error":"invalid client"}`,
+		},
+		{
+			desc: "internal error",
+			input: `{"clientId":"` + testClientID + `", "redirectUri":"https://app.test.com/cb?tab=1",
+				"scope":"position:write order:read", "codeChallenge":"` + testCodeChallenge + `", "codeChallengeMethod":"S256"}`,
+			rErr:  assert.AnError,
+			rcode: http.StatusInternalServerError,
+			rdata: `{"error":"internal error"}`,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			svc := auth.NewMockService(ctrl)
+			if tC.rErr != errSkip {
+				svc.EXPECT().AuthorizeOAuthClient(gomock.Any(), testSuperadminClaims.UserID, req).Return("code", tC.rErr)
+			}
+
+			router := setupTestRouterWithAuth(nil, svc)
+			rec, r := newTestParameters(http.MethodPost, "/v1/oauth/authorize", tC.input)
+
+			router.ServeHTTP(rec, r)
+
+			body, _ := ioutil.ReadAll(rec.Result().Body)
+
+			assert.Equal(t, tC.rcode, rec.Result().StatusCode)
+			assert.JSONEq(t, tC.rdata, string(body))
+		})
+	}
+}
+
+func Test_oauthClientManagement_deniedWithOAuthToken(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	claims := auth.AccessTokenClaims{UserID: 1, ClientID: testClientID}
+	router := setupTestRouterWithOrders(nil, auth.NewMockService(ctrl), nil, nil, claims)
+	rec, r := newTestParameters(http.MethodPost, "/v1/oauth/clients", `{"name":"app"}`)
+
+	router.ServeHTTP(rec, r)
+
+	body, _ := ioutil.ReadAll(rec.Result().Body)
+
+	assert.Equal(t, http.StatusForbidden, rec.Result().StatusCode)
+	assert.JSONEq(t, `{"error":"not allowed with oauth token"}`, string(body))
+}
+
+func Test_cartAndOrders_deniedWithOAuthTokenOutOfScope(t *testing.T) {
+	testCases := []struct {
+		desc   string
+		method string
+		uri    string
+		input  string
+		scopes []model.Permission
+	}{
+		{
+			desc:   "get cart",
+			method: http.MethodGet,
+			uri:    "/v1/cart",
+			scopes: []model.Permission{model.ScopeOrders, model.ScopePayments},
+		},
+		{
+			desc:   "checkout",
+			method: http.MethodPost,
+			uri:    "/v1/orders",
+			input:  `{}`,
+			scopes: []model.Permission{model.ScopeCart, model.ScopePayments},
+		},
+		{
+			desc:   "get orders",
+			method: http.MethodGet,
+			uri:    "/v1/orders",
+			scopes: []model.Permission{model.PermissionPositionWrite},
+		},
+		{
+			desc:   "pay order",
+			method: http.MethodPost,
+			uri:    "/v1/orders/1/payments",
+			input:  `{"token":"tok"}`,
+			scopes: []model.Permission{model.ScopeCart, model.ScopeOrders},
+		},
+		{
+			desc:   "pay order without scopes",
+			method: http.MethodPost,
+			uri:    "/v1/orders/1/payments",
+			input:  `{"token":"tok"}`,
+			scopes: nil,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			claims := auth.AccessTokenClaims{UserID: 1, ClientID: testClientID, Scopes: tC.scopes}
+			router := setupTestRouterWithOrders(nil, nil, service.NewMockCartService(ctrl), service.NewMockOrderService(ctrl), claims)
+			rec, r := newTestParameters(tC.method, tC.uri, tC.input)
+
+			router.ServeHTTP(rec, r)
+
+			body, _ := ioutil.ReadAll(rec.Result().Body)
+
+			assert.Equal(t, http.StatusForbidden, rec.Result().StatusCode)
+			assert.JSONEq(t, `{"error":"access not allowed"}`, string(body))
+		})
+	}
+}
+
+func Test_oauthTokenHandler(t *testing.T) {
+	token := auth.OAuthToken{
+		AccessToken:  "access",
+		RefreshToken: "refresh",
+		ExpiresIn:    10 * time.Minute,
+		Scopes:       []model.Permission{model.PermissionPositionWrite, model.PermissionOrderRead},
+	}
+
+	testCases := []struct {
+		desc   string
+		form   url.Values
+		basic  []string
+		req    auth.OAuthTokenRequest
+		rErr   error
+		rcode  int
+		rdata  string
+		header string
+	}{
+		{
+			desc:  "authorization code - success",
+			form:  url.Values{"grant_type": {"authorization_code"}, "client_id": {testClientID}, "code": {"code"}, "redirect_uri": {"https://app.test.com/cb"}, "code_verifier": {"verifier"}},
+			basic: nil,
+			req: auth.OAuthTokenRequest{GrantType: auth.GrantAuthorizationCode, ClientID: testClientID, Code: "code",
+				RedirectURI: "https://app.test.com/cb", CodeVerifier: "verifier"},
+			rErr:  nil,
+			rcode: http.StatusOK,
+			rdata: `{"access_token":"access", "token_type":"Bearer", "expires_in":600, "refresh_token":"refresh",
+				"scope":"position:write order:read"}`,
+		},
+		{
+			desc:  "client credentials with basic auth - success",
+			form:  url.Values{"grant_type": {"client_credentials"}, "scope": {"position:write  order:read"}},
+			basic: []string{testClientID, "s%2Becret"},
+			req: auth.OAuthTokenRequest{GrantType: auth.GrantClientCredentials, ClientID: testClientID, ClientSecret: "s+ecret",
+				Scopes: []model.Permission{model.PermissionPositionWrite, model.PermissionOrderRead}},
+			rErr:  nil,
+			rcode: http.StatusOK,
+			rdata: `{"access_token":"access", "token_type":"Bearer", "expires_in":600, "refresh_token":"refresh",
+				"scope":"position:write order:read"}`,
+		},
+		{
+			desc:  "missing grant type",
+			form:  url.Values{"client_id": {testClientID}},
+			rErr:  errSkip,
+			rcode: http.StatusBadRequest,
+			rdata: `{"error":"invalid_request", "error_description":"grant_type is required"}`,
+		},
+		{
+			desc:  "malformed basic auth",
+			form:  url.Values{"grant_type": {"client_credentials"}},
+			basic: []string{testClientID, "%zz"},
+			rErr:  errSkip,
+			rcode: http.StatusBadRequest,
+			rdata: `{"error":"invalid_request", "error_description":"malformed client credentials"}`,
+		},
+		{
+			desc:   "invalid client",
+			form:   url.Values{"grant_type": {"client_credentials"}, "client_id": {testClientID}, "client_secret": {"secret"}},
+			req:    auth.OAuthTokenRequest{GrantType: auth.GrantClientCredentials, ClientID: testClientID, ClientSecret: "secret"},
+			rErr:   auth.ErrInvalidClient,
+			rcode:  http.StatusUnauthorized,
+			rdata:  `{"error":"invalid_client", "error_description":"client authentication failed"}`,
+			header: `Basic realm="gstore"`,
+		},
+		{
+			desc:  "invalid grant",
+			form:  url.Values{"grant_type": {"refresh_token"}, "client_id": {testClientID}, "refresh_token": {"token"}},
+			req:   auth.OAuthTokenRequest{GrantType: auth.GrantRefreshToken, ClientID: testClientID, RefreshToken: "token"},
+			rErr:  auth.ErrInvalidGrant,
+			rcode: http.StatusBadRequest,
+			rdata: `{"error":"invalid_grant", "error_description":"invalid grant"}`,
+		},
+		{
+			desc:  "unauthorized client",
+			form:  url.Values{"grant_type": {"client_credentials"}, "client_id": {testClientID}},
+			req:   auth.OAuthTokenRequest{GrantType: auth.GrantClientCredentials, ClientID: testClientID},
+			rErr:  auth.ErrUnauthorizedClient,
+			rcode: http.StatusBadRequest,
+			rdata: `{"error":"unauthorized_client", "error_description":"unauthorized client"}`,
+		},
+		{
+			desc:  "unsupported grant type",
+			form:  url.Values{"grant_type": {"password"}, "client_id": {testClientID}},
+			req:   auth.OAuthTokenRequest{GrantType: "password", ClientID: testClientID},
+			rErr:  auth.ErrUnsupportedGrantType,
+			rcode: http.StatusBadRequest,
+			rdata: `{"error":"unsupported_grant_type", "error_description":"unsupported grant type"}`,
+		},
+		{
+			desc:  "invalid scope",
+			form:  url.Values{"grant_type": {"client_credentials"}, "client_id": {testClientID}},
+			req:   auth.OAuthTokenRequest{GrantType: auth.GrantClientCredentials, ClientID: testClientID},
+			rErr:  auth.ErrInvalidScope,
+			rcode: http.StatusBadRequest,
+			rdata: `{"error":"invalid_scope", "error_description":"invalid scope"}`,
+		},
+		{
+			desc:  "internal error",
+			form:  url.Values{"grant_type": {"client_credentials"}, "client_id": {testClientID}},
+			req:   auth.OAuthTokenRequest{GrantType: auth.GrantClientCredentials, ClientID: testClientID},
+			rErr:  assert.AnError,
+			rcode: http.StatusInternalServerError,
+			rdata: `{"error":"internal error"}`,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			svc := auth.NewMockService(ctrl)
+			if tC.rErr != errSkip {
+				svc.EXPECT().IssueOAuthToken(gomock.Any(), tC.req, gomock.Any()).Return(token, tC.rErr)
+			}
+
+			router := setupTestRouterWithAuth(nil, svc)
+			rec, r := newFormTestParameters("/v1/oauth/token", tC.form)
+			if tC.basic != nil {
+				r.SetBasicAuth(tC.basic[0], tC.basic[1])
+			}
+
+			router.ServeHTTP(rec, r)
+
+			body, _ := ioutil.ReadAll(rec.Result().Body)
+
+			assert.Equal(t, tC.rcode, rec.Result().StatusCode)
+			assert.JSONEq(t, tC.rdata, string(body))
+			assert.Equal(t, "no-store", rec.Result().Header.Get("Cache-Control"))
+			assert.Equal(t, tC.header, rec.Result().Header.Get("WWW-Authenticate"))
+		})
+	}
+}
+
+func Test_introspectHandler(t *testing.T) {
+	claims := auth.AccessTokenClaims{
+		UserID:   1,
+		ClientID: testClientID,
+		Scopes:   []model.Permission{model.PermissionPositionWrite},
+	}
+	claims.IssuedAt = 1614592800
+	claims.ExpiresAt = 1614593400
+
+	testCases := []struct {
+		desc       string
+		form       url.Values
+		rClientErr error
+		rClaims    auth.AccessTokenClaims
+		rTokenErr  error
+		rcode      int
+		rdata      string
+	}{
+		{
+			desc:       "active",
+			form:       url.Values{"token": {"token"}, "client_id": {testClientID}, "client_secret": {"secret"}},
+			rClientErr: nil,
+			rClaims:    claims,
+			rTokenErr:  nil,
+			rcode:      http.StatusOK,
+			rdata: `{"active":true, "scope":"position:write", "client_id":"a37f2f5e-f698-11e6-8dd4-cb9ced3df976",
+				"sub":"1", "token_type":"Bearer", "exp":1614593400, "iat":1614592800}`,
+		},
+		{
+			desc:       "inactive",
+			form:       url.Values{"token": {"token"}, "client_id": {testClientID}, "client_secret": {"secret"}},
+			rClientErr: nil,
+			rClaims:    auth.AccessTokenClaims{},
+			rTokenErr:  assert.AnError,
+			rcode:      http.StatusOK,
+			rdata:      `{"active":false}`,
+		},
+		{
+			desc:       "token of user",
+			form:       url.Values{"token": {"token"}, "client_id": {testClientID}, "client_secret": {"secret"}},
+			rClientErr: nil,
+			rClaims:    testSuperadminClaims,
+			rTokenErr:  nil,
+			rcode:      http.StatusOK,
+			rdata:      `{"active":false}`,
+		},
+		{
+			desc:       "token of another client",
+			form:       url.Values{"token": {"token"}, "client_id": {testClientID}, "client_secret": {"secret"}},
+			rClientErr: nil,
+			rClaims:    auth.AccessTokenClaims{UserID: 1, ClientID: testAPIKeyID},
+			rTokenErr:  nil,
+			rcode:      http.StatusOK,
+			rdata:      `{"active":false}`,
+		},
+		{
+			desc:       "missing token",
+			form:       url.Values{"client_id": {testClientID}, "client_secret": {"secret"}},
+			rClientErr: errSkip,
+			rTokenErr:  errSkip,
+			rcode:      http.StatusBadRequest,
+			rdata:      `{"error":"invalid_request", "error_description":"token is required"}`,
+		},
+		{
+			desc:       "invalid client",
+			form:       url.Values{"token": {"token"}, "client_id": {testClientID}, "client_secret": {"secret"}},
+			rClientErr: auth.ErrInvalidClient,
+			rTokenErr:  errSkip,
+			rcode:      http.StatusUnauthorized,
+			rdata:      `{"error":"invalid_client", "error_description":"client authentication failed"}`,
+		},
+		{
+			desc:       "internal error",
+			form:       url.Values{"token": {"token"}, "client_id": {testClientID}, "client_secret": {"secret"}},
+			rClientErr: assert.AnError,
+			rTokenErr:  errSkip,
+			rcode:      http.StatusInternalServerError,
+			rdata:      `{"error":"internal error"}`,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			svc := auth.NewMockService(ctrl)
+			if tC.rClientErr != errSkip {
+				svc.EXPECT().AuthenticateOAuthClient(gomock.Any(), testClientID, "secret").
+					Return(model.OAuthClient{ID: testClientID}, tC.rClientErr)
+			}
+			if tC.rTokenErr != errSkip {
+				svc.EXPECT().ValidateAccessToken("token").Return(tC.rClaims, tC.rTokenErr)
+			}
+
+			router := setupTestRouterWithAuth(nil, svc)
+			rec, r := newFormTestParameters("/v1/oauth/introspect", tC.form)
+
+			router.ServeHTTP(rec, r)
+
+			body, _ := ioutil.ReadAll(rec.Result().Body)
+
+			assert.Equal(t, tC.rcode, rec.Result().StatusCode)
+			assert.JSONEq(t, tC.rdata, string(body))
+		})
+	}
+}
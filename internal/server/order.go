@@ -0,0 +1,148 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/vliubezny/gstore/internal/model"
+	"github.com/vliubezny/gstore/internal/service"
+)
+
+func (s *server) checkoutHandler(w http.ResponseWriter, r *http.Request) {
+	l := getLogger(r)
+
+	currency, err := getCurrencyFromURL(r)
+	if err != nil {
+		writeError(l.WithError(err), w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	o, err := s.o.Checkout(r.Context(), getClaims(r).UserID, currency)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrUnknownCurrency):
+			writeError(l.WithError(err), w, http.StatusBadRequest, "unknown currency")
+		case errors.Is(err, service.ErrEmptyCart):
+			writeError(l.WithError(err), w, http.StatusBadRequest, "cart is empty")
+		case errors.Is(err, service.ErrCartChanged):
+			writeError(l.WithError(err), w, http.StatusConflict, err.Error())
+		case errors.Is(err, service.ErrInsufficientStock):
+			writeError(l.WithError(err), w, http.StatusConflict, "insufficient stock")
+		default:
+			writeInternalError(l.WithError(err), w, "fail to checkout")
+		}
+		return
+	}
+
+	l.WithField("orderID", o.ID).Info("order placed")
+
+	writeOK(l, w, fromOrderModel(o))
+}
+
+func (s *server) getOrdersHandler(w http.ResponseWriter, r *http.Request) {
+	l := getLogger(r)
+
+	pg, err := getPageFromURL(r)
+	if err != nil {
+		writeError(l.WithError(err), w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	f, err := getOrderFilterFromURL(r)
+	if err != nil {
+		writeError(l.WithError(err), w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// users see only their own orders
+	if claims := getClaims(r); !claims.HasPermission(model.PermissionOrderRead) {
+		f.UserID = claims.UserID
+	}
+
+	orders, next, err := s.o.GetOrders(r.Context(), f, pg)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidCursor) {
+			writeError(l.WithError(err), w, http.StatusBadRequest, "invalid cursor")
+			return
+		}
+
+		writeInternalError(l.WithError(err), w, "fail to get orders")
+		return
+	}
+
+	resp := make([]order, len(orders))
+
+	for i, o := range orders {
+		resp[i] = fromOrderModel(o)
+	}
+
+	writeOK(l, w, page{Items: resp, NextCursor: next})
+}
+
+func (s *server) getOrderHandler(w http.ResponseWriter, r *http.Request) {
+	l := getLogger(r)
+
+	orderID, err := getIDFromURL(r, "id")
+	if err != nil {
+		writeError(l.WithError(err), w, http.StatusBadRequest, "invalid order ID")
+		return
+	}
+
+	o, err := s.o.GetOrder(r.Context(), orderID)
+	if err != nil {
+		if errors.Is(err, service.ErrNotFound) {
+			writeError(l.WithError(err), w, http.StatusNotFound, "order not found")
+			return
+		}
+
+		writeInternalError(l.WithError(err), w, "fail to get order")
+		return
+	}
+
+	// orders of other users are hidden rather than forbidden to not disclose their existence
+	if claims := getClaims(r); !claims.HasPermission(model.PermissionOrderRead) && o.UserID != claims.UserID {
+		writeError(l, w, http.StatusNotFound, "order not found")
+		return
+	}
+
+	writeOK(l, w, fromOrderModel(o))
+}
+
+func (s *server) transitionOrderHandler(w http.ResponseWriter, r *http.Request) {
+	l := getLogger(r)
+
+	orderID, err := getIDFromURL(r, "id")
+	if err != nil {
+		writeError(l.WithError(err), w, http.StatusBadRequest, "invalid order ID")
+		return
+	}
+
+	var req orderStatusChange
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(l.WithError(err), w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := validate(&req); err != nil {
+		writeError(l.WithError(err), w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	o, err := s.o.TransitionOrder(r.Context(), orderID, model.OrderStatus(req.Status), getClaims(r).UserID)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrNotFound):
+			writeError(l.WithError(err), w, http.StatusNotFound, "order not found")
+		case errors.Is(err, service.ErrInvalidTransition):
+			writeError(l.WithError(err), w, http.StatusConflict, err.Error())
+		default:
+			writeInternalError(l.WithError(err), w, "fail to change order status")
+		}
+		return
+	}
+
+	l.WithField("orderID", o.ID).Infof("order became %s", o.Status)
+
+	writeOK(l, w, fromOrderModel(o))
+}
@@ -0,0 +1,377 @@
+package server
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/vliubezny/gstore/internal/auth"
+	"github.com/vliubezny/gstore/internal/model"
+	"github.com/vliubezny/gstore/internal/service"
+)
+
+var (
+	testUserClaims  = auth.AccessTokenClaims{UserID: 1}
+	testAdminClaims = auth.AccessTokenClaims{UserID: 2, Roles: []string{model.RoleSupport},
+		Permissions: []model.Permission{model.PermissionOrderRead, model.PermissionOrderWrite}}
+
+	testOrder = model.Order{
+		ID:       10,
+		UserID:   1,
+		Status:   model.OrderPending,
+		Currency: "USD",
+		Total:    decimal.NewFromInt(200),
+		Items: []model.OrderItem{
+			{ProductID: 1, VariantID: 2, StoreID: 3, Name: "iPhone", SKU: "IP12", Quantity: 2, Price: decimal.NewFromInt(100), Currency: "USD", Reserved: true},
+		},
+		Transitions: []model.OrderTransition{
+			{To: model.OrderPending, ActorID: 1, CreatedAt: time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC)},
+		},
+		CreatedAt: time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+)
+
+const testOrderJSON = `{"id":10, "userId":1, "status":"pending", "currency":"USD", "total":200,
+	"items":[{"productId":1, "variantId":2, "storeId":3, "name":"iPhone", "sku":"IP12", "quantity":2,
+		"price":100, "currency":"USD", "reserved":true}],
+	"transitions":[{"to":"pending", "actorId":1, "createdAt":"2021-01-02T03:04:05Z"}],
+	"createdAt":"2021-01-02T03:04:05Z"}`
+
+func Test_checkoutHandler(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		query    string
+		currency string
+		err      error
+		rcode    int
+		rdata    string
+	}{
+		{
+			desc:     "success",
+			query:    "?currency=usd",
+			currency: "USD",
+			err:      nil,
+			rcode:    http.StatusOK,
+			rdata:    testOrderJSON,
+		},
+		{
+			desc:  "invalid currency",
+			query: "?currency=dollar",
+			err:   errSkip,
+			rcode: http.StatusBadRequest,
+			rdata: `{"error":"currency must be an ISO 4217 code"}`,
+		},
+		{
+			desc:  "unknown currency",
+			err:   service.ErrUnknownCurrency,
+			rcode: http.StatusBadRequest,
+			rdata: `{"error":"unknown currency"}`,
+		},
+		{
+			desc:  "empty cart",
+			err:   service.ErrEmptyCart,
+			rcode: http.StatusBadRequest,
+			rdata: `{"error":"cart is empty"}`,
+		},
+		{
+			desc:  "cart changed",
+			err:   fmt.Errorf("%w: price of variant 2 in store 3 has changed", service.ErrCartChanged),
+			rcode: http.StatusConflict,
+			rdata: `{"error":"cart has changed: price of variant 2 in store 3 has changed"}`,
+		},
+		{
+			desc:  "insufficient stock",
+			err:   service.ErrInsufficientStock,
+			rcode: http.StatusConflict,
+			rdata: `{"error":"insufficient stock"}`,
+		},
+		{
+			desc:  "internal error",
+			err:   errTest,
+			rcode: http.StatusInternalServerError,
+			rdata: `{"error":"internal error"}`,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			svc := service.NewMockOrderService(ctrl)
+			if tC.err != errSkip {
+				svc.EXPECT().Checkout(gomock.Any(), int64(1), tC.currency).Return(testOrder, tC.err)
+			}
+
+			router := setupTestRouterWithOrders(nil, nil, nil, svc, testUserClaims)
+			rec, r := newTestParameters(http.MethodPost, "/v1/orders"+tC.query, "")
+
+			router.ServeHTTP(rec, r)
+
+			body, _ := ioutil.ReadAll(rec.Result().Body)
+
+			assert.Equal(t, tC.rcode, rec.Result().StatusCode)
+			assert.JSONEq(t, tC.rdata, string(body))
+		})
+	}
+}
+
+func Test_getOrdersHandler(t *testing.T) {
+	list := testOrder
+	list.Transitions = nil
+
+	testCases := []struct {
+		desc   string
+		claims auth.AccessTokenClaims
+		query  string
+		filter model.OrderFilter
+		err    error
+		rcode  int
+		rdata  string
+	}{
+		{
+			desc:   "success user",
+			claims: testUserClaims,
+			query:  "?userId=5&status=pending",
+			filter: model.OrderFilter{UserID: 1, Status: []model.OrderStatus{model.OrderPending}},
+			err:    nil,
+			rcode:  http.StatusOK,
+			rdata: `{"items":[{"id":10, "userId":1, "status":"pending", "currency":"USD", "total":200,
+				"items":[{"productId":1, "variantId":2, "storeId":3, "name":"iPhone", "sku":"IP12", "quantity":2,
+					"price":100, "currency":"USD", "reserved":true}],
+				"createdAt":"2021-01-02T03:04:05Z"}], "nextCursor":"next"}`,
+		},
+		{
+			desc:   "success admin",
+			claims: testAdminClaims,
+			query:  "",
+			filter: model.OrderFilter{},
+			err:    nil,
+			rcode:  http.StatusOK,
+			rdata: `{"items":[{"id":10, "userId":1, "status":"pending", "currency":"USD", "total":200,
+				"items":[{"productId":1, "variantId":2, "storeId":3, "name":"iPhone", "sku":"IP12", "quantity":2,
+					"price":100, "currency":"USD", "reserved":true}],
+				"createdAt":"2021-01-02T03:04:05Z"}], "nextCursor":"next"}`,
+		},
+		{
+			desc:   "invalid filter",
+			claims: testAdminClaims,
+			query:  "?status=lost",
+			err:    errSkip,
+			rcode:  http.StatusBadRequest,
+			rdata:  `{"error":"status must be a comma-separated list of [pending paid shipped delivered cancelled refunded]"}`,
+		},
+		{
+			desc:   "invalid cursor",
+			claims: testAdminClaims,
+			filter: model.OrderFilter{},
+			err:    service.ErrInvalidCursor,
+			rcode:  http.StatusBadRequest,
+			rdata:  `{"error":"invalid cursor"}`,
+		},
+		{
+			desc:   "internal error",
+			claims: testAdminClaims,
+			filter: model.OrderFilter{},
+			err:    errTest,
+			rcode:  http.StatusInternalServerError,
+			rdata:  `{"error":"internal error"}`,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			svc := service.NewMockOrderService(ctrl)
+			if tC.err != errSkip {
+				svc.EXPECT().GetOrders(gomock.Any(), tC.filter, model.Page{Limit: defaultPageLimit}).
+					Return([]model.Order{list}, "next", tC.err)
+			}
+
+			router := setupTestRouterWithOrders(nil, nil, nil, svc, tC.claims)
+			rec, r := newTestParameters(http.MethodGet, "/v1/orders"+tC.query, "")
+
+			router.ServeHTTP(rec, r)
+
+			body, _ := ioutil.ReadAll(rec.Result().Body)
+
+			assert.Equal(t, tC.rcode, rec.Result().StatusCode)
+			assert.JSONEq(t, tC.rdata, string(body))
+		})
+	}
+}
+
+func Test_getOrderHandler(t *testing.T) {
+	testCases := []struct {
+		desc    string
+		claims  auth.AccessTokenClaims
+		orderID string
+		err     error
+		rcode   int
+		rdata   string
+	}{
+		{
+			desc:    "success owner",
+			claims:  testUserClaims,
+			orderID: "10",
+			err:     nil,
+			rcode:   http.StatusOK,
+			rdata:   testOrderJSON,
+		},
+		{
+			desc:    "success admin",
+			claims:  testAdminClaims,
+			orderID: "10",
+			err:     nil,
+			rcode:   http.StatusOK,
+			rdata:   testOrderJSON,
+		},
+		{
+			desc:    "order of another user",
+			claims:  auth.AccessTokenClaims{UserID: 3},
+			orderID: "10",
+			err:     nil,
+			rcode:   http.StatusNotFound,
+			rdata:   `{"error":"order not found"}`,
+		},
+		{
+			desc:    "invalid order ID",
+			claims:  testUserClaims,
+			orderID: "test",
+			err:     errSkip,
+			rcode:   http.StatusBadRequest,
+			rdata:   `{"error":"invalid order ID"}`,
+		},
+		{
+			desc:    "not found",
+			claims:  testUserClaims,
+			orderID: "10",
+			err:     service.ErrNotFound,
+			rcode:   http.StatusNotFound,
+			rdata:   `{"error":"order not found"}`,
+		},
+		{
+			desc:    "internal error",
+			claims:  testUserClaims,
+			orderID: "10",
+			err:     errTest,
+			rcode:   http.StatusInternalServerError,
+			rdata:   `{"error":"internal error"}`,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			svc := service.NewMockOrderService(ctrl)
+			if tC.err != errSkip {
+				svc.EXPECT().GetOrder(gomock.Any(), int64(10)).Return(testOrder, tC.err)
+			}
+
+			router := setupTestRouterWithOrders(nil, nil, nil, svc, tC.claims)
+			rec, r := newTestParameters(http.MethodGet, "/v1/orders/"+tC.orderID, "")
+
+			router.ServeHTTP(rec, r)
+
+			body, _ := ioutil.ReadAll(rec.Result().Body)
+
+			assert.Equal(t, tC.rcode, rec.Result().StatusCode)
+			assert.JSONEq(t, tC.rdata, string(body))
+		})
+	}
+}
+
+func Test_transitionOrderHandler(t *testing.T) {
+	testCases := []struct {
+		desc    string
+		orderID string
+		input   string
+		err     error
+		rcode   int
+		rdata   string
+	}{
+		{
+			desc:    "success",
+			orderID: "10",
+			input:   `{"status":"paid"}`,
+			err:     nil,
+			rcode:   http.StatusOK,
+			rdata:   testOrderJSON,
+		},
+		{
+			desc:    "invalid order ID",
+			orderID: "test",
+			input:   `{"status":"paid"}`,
+			err:     errSkip,
+			rcode:   http.StatusBadRequest,
+			rdata:   `{"error":"invalid order ID"}`,
+		},
+		{
+			desc:    "invalid input",
+			orderID: "10",
+			input:   `{"status":1}`,
+			err:     errSkip,
+			rcode:   http.StatusBadRequest,
+			rdata:   `{"error":"json: cannot unmarshal number into Go struct field orderStatusChange.status of type string"}`,
+		},
+		{
+			desc:    "unknown status",
+			orderID: "10",
+			input:   `{"status":"lost"}`,
+			err:     errSkip,
+			rcode:   http.StatusBadRequest,
+			rdata:   `{"error":"status must be one of [pending paid shipped delivered cancelled refunded]"}`,
+		},
+		{
+			desc:    "not found",
+			orderID: "10",
+			input:   `{"status":"paid"}`,
+			err:     service.ErrNotFound,
+			rcode:   http.StatusNotFound,
+			rdata:   `{"error":"order not found"}`,
+		},
+		{
+			desc:    "invalid transition",
+			orderID: "10",
+			input:   `{"status":"paid"}`,
+			err:     fmt.Errorf("%w: cancelled order cannot become paid", service.ErrInvalidTransition),
+			rcode:   http.StatusConflict,
+			rdata:   `{"error":"invalid order status transition: cancelled order cannot become paid"}`,
+		},
+		{
+			desc:    "internal error",
+			orderID: "10",
+			input:   `{"status":"paid"}`,
+			err:     errTest,
+			rcode:   http.StatusInternalServerError,
+			rdata:   `{"error":"internal error"}`,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			svc := service.NewMockOrderService(ctrl)
+			if tC.err != errSkip {
+				svc.EXPECT().TransitionOrder(gomock.Any(), int64(10), model.OrderPaid, int64(2)).Return(testOrder, tC.err)
+			}
+
+			router := setupTestRouterWithOrders(nil, nil, nil, svc, testAdminClaims)
+			rec, r := newTestParameters(http.MethodPost, fmt.Sprintf("/v1/orders/%s/transitions", tC.orderID), tC.input)
+
+			router.ServeHTTP(rec, r)
+
+			body, _ := ioutil.ReadAll(rec.Result().Body)
+
+			assert.Equal(t, tC.rcode, rec.Result().StatusCode)
+			assert.JSONEq(t, tC.rdata, string(body))
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/vliubezny/gstore/internal/service"
+)
+
+func (s *server) payOrderHandler(w http.ResponseWriter, r *http.Request) {
+	l := getLogger(r)
+
+	orderID, err := getIDFromURL(r, "id")
+	if err != nil {
+		writeError(l.WithError(err), w, http.StatusBadRequest, "invalid order ID")
+		return
+	}
+
+	var req orderPayment
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(l.WithError(err), w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := validate(&req); err != nil {
+		writeError(l.WithError(err), w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	p, err := s.o.PayOrder(r.Context(), orderID, getClaims(r).UserID, req.Method)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrNotFound):
+			writeError(l.WithError(err), w, http.StatusNotFound, "order not found")
+		case errors.Is(err, service.ErrInvalidTransition):
+			writeError(l.WithError(err), w, http.StatusConflict, err.Error())
+		case errors.Is(err, service.ErrPaymentDeclined):
+			writeError(l.WithError(err), w, http.StatusPaymentRequired, "payment declined")
+		case errors.Is(err, service.ErrPaymentUnavailable):
+			writeError(l.WithError(err), w, http.StatusServiceUnavailable, "payments are unavailable")
+		default:
+			writeInternalError(l.WithError(err), w, "fail to pay order")
+		}
+		return
+	}
+
+	l.WithField("orderID", orderID).Infof("payment %s is %s", p.ID, p.Status)
+
+	writeOK(l, w, fromPaymentModel(p))
+}
+
+func (s *server) paymentWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	l := getLogger(r)
+
+	data, err := ioutil.ReadAll(io.LimitReader(r.Body, maxPaymentWebhookSize+1))
+	if err != nil {
+		writeError(l.WithError(err), w, http.StatusBadRequest, "failed to read event")
+		return
+	}
+
+	if len(data) > maxPaymentWebhookSize {
+		writeError(l, w, http.StatusRequestEntityTooLarge, "event is too large")
+		return
+	}
+
+	if err := s.o.HandlePaymentWebhook(r.Context(), r.Header, data); err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidSignature):
+			writeError(l.WithError(err), w, http.StatusUnauthorized, "invalid signature")
+		case errors.Is(err, service.ErrNotFound):
+			writeError(l.WithError(err), w, http.StatusNotFound, "payment not found")
+		default:
+			writeInternalError(l.WithError(err), w, "fail to handle payment event")
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,197 @@
+package server
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/vliubezny/gstore/internal/model"
+	"github.com/vliubezny/gstore/internal/service"
+)
+
+var testPayment = model.Payment{
+	ID:        "pay1",
+	OrderID:   10,
+	Status:    model.PaymentCaptured,
+	Amount:    decimal.NewFromInt(200),
+	Currency:  "USD",
+	CreatedAt: time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC),
+}
+
+const testPaymentJSON = `{"id":"pay1", "orderId":10, "status":"captured", "amount":200, "currency":"USD",
+	"createdAt":"2021-01-02T03:04:05Z"}`
+
+func Test_payOrderHandler(t *testing.T) {
+	testCases := []struct {
+		desc    string
+		orderID string
+		input   string
+		err     error
+		rcode   int
+		rdata   string
+	}{
+		{
+			desc:    "success",
+			orderID: "10",
+			input:   `{"method":"card"}`,
+			err:     nil,
+			rcode:   http.StatusOK,
+			rdata:   testPaymentJSON,
+		},
+		{
+			desc:    "invalid order ID",
+			orderID: "test",
+			input:   `{"method":"card"}`,
+			err:     errSkip,
+			rcode:   http.StatusBadRequest,
+			rdata:   `{"error":"invalid order ID"}`,
+		},
+		{
+			desc:    "missing method",
+			orderID: "10",
+			input:   `{}`,
+			err:     errSkip,
+			rcode:   http.StatusBadRequest,
+			rdata:   `{"error":"method is a required field"}`,
+		},
+		{
+			desc:    "not found",
+			orderID: "10",
+			input:   `{"method":"card"}`,
+			err:     service.ErrNotFound,
+			rcode:   http.StatusNotFound,
+			rdata:   `{"error":"order not found"}`,
+		},
+		{
+			desc:    "not pending",
+			orderID: "10",
+			input:   `{"method":"card"}`,
+			err:     fmt.Errorf("%w: paid order cannot be paid", service.ErrInvalidTransition),
+			rcode:   http.StatusConflict,
+			rdata:   `{"error":"invalid order status transition: paid order cannot be paid"}`,
+		},
+		{
+			desc:    "declined",
+			orderID: "10",
+			input:   `{"method":"card"}`,
+			err:     service.ErrPaymentDeclined,
+			rcode:   http.StatusPaymentRequired,
+			rdata:   `{"error":"payment declined"}`,
+		},
+		{
+			desc:    "payments unavailable",
+			orderID: "10",
+			input:   `{"method":"card"}`,
+			err:     service.ErrPaymentUnavailable,
+			rcode:   http.StatusServiceUnavailable,
+			rdata:   `{"error":"payments are unavailable"}`,
+		},
+		{
+			desc:    "internal error",
+			orderID: "10",
+			input:   `{"method":"card"}`,
+			err:     errTest,
+			rcode:   http.StatusInternalServerError,
+			rdata:   `{"error":"internal error"}`,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			svc := service.NewMockOrderService(ctrl)
+			if tC.err != errSkip {
+				svc.EXPECT().PayOrder(gomock.Any(), int64(10), int64(1), "card").Return(testPayment, tC.err)
+			}
+
+			router := setupTestRouterWithOrders(nil, nil, nil, svc, testUserClaims)
+			rec, r := newTestParameters(http.MethodPost, fmt.Sprintf("/v1/orders/%s/payments", tC.orderID), tC.input)
+
+			router.ServeHTTP(rec, r)
+
+			body, _ := ioutil.ReadAll(rec.Result().Body)
+
+			assert.Equal(t, tC.rcode, rec.Result().StatusCode)
+			assert.JSONEq(t, tC.rdata, string(body))
+		})
+	}
+}
+
+func Test_paymentWebhookHandler(t *testing.T) {
+	testCases := []struct {
+		desc  string
+		input string
+		err   error
+		rcode int
+		rdata string
+	}{
+		{
+			desc:  "success",
+			input: `{"id":"e1"}`,
+			err:   nil,
+			rcode: http.StatusNoContent,
+			rdata: "",
+		},
+		{
+			desc:  "too large",
+			input: strings.Repeat("#", maxPaymentWebhookSize+1),
+			err:   errSkip,
+			rcode: http.StatusRequestEntityTooLarge,
+			rdata: `{"error":"event is too large"}`,
+		},
+		{
+			desc:  "invalid signature",
+			input: `{"id":"e1"}`,
+			err:   service.ErrInvalidSignature,
+			rcode: http.StatusUnauthorized,
+			rdata: `{"error":"invalid signature"}`,
+		},
+		{
+			desc:  "unknown payment",
+			input: `{"id":"e1"}`,
+			err:   service.ErrNotFound,
+			rcode: http.StatusNotFound,
+			rdata: `{"error":"payment not found"}`,
+		},
+		{
+			desc:  "internal error",
+			input: `{"id":"e1"}`,
+			err:   errTest,
+			rcode: http.StatusInternalServerError,
+			rdata: `{"error":"internal error"}`,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			svc := service.NewMockOrderService(ctrl)
+			if tC.err != errSkip {
+				svc.EXPECT().HandlePaymentWebhook(gomock.Any(), gomock.Any(), []byte(tC.input)).Return(tC.err)
+			}
+
+			router := setupTestRouterWithOrders(nil, nil, nil, svc, testUserClaims)
+			rec, r := newTestParameters(http.MethodPost, "/v1/payments/webhook", tC.input)
+			r.Header.Del("Authorization")
+
+			router.ServeHTTP(rec, r)
+
+			body, _ := ioutil.ReadAll(rec.Result().Body)
+
+			assert.Equal(t, tC.rcode, rec.Result().StatusCode)
+			if tC.rdata == "" {
+				assert.Empty(t, body)
+				return
+			}
+			assert.JSONEq(t, tC.rdata, string(body))
+		})
+	}
+}
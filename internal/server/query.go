@@ -0,0 +1,373 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/shopspring/decimal"
+	"github.com/vliubezny/gstore/internal/model"
+)
+
+const (
+	maxNameFilterLength  = 160
+	maxSearchQueryLength = 200
+	maxComparedProducts  = 100
+
+	attributeFilterPrefix = "attr."
+
+	defaultPriceHistoryRange = 30 * 24 * time.Hour
+	maxPriceHistoryRange     = 366 * 24 * time.Hour
+)
+
+var (
+	productSorts   = []model.ProductSort{model.SortByID, model.SortByName, model.SortByPrice}
+	availabilities = []model.Availability{model.AvailabilityInStock, model.AvailabilityBackorder, model.AvailabilityDiscontinued}
+	orderStatuses  = []model.OrderStatus{
+		model.OrderPending, model.OrderPaid, model.OrderShipped,
+		model.OrderDelivered, model.OrderCancelled, model.OrderRefunded,
+	}
+)
+
+// getProductFilterFromURL parses and validates product filter query parameters.
+func getProductFilterFromURL(r *http.Request) (model.ProductFilter, error) {
+	q := r.URL.Query()
+	var f model.ProductFilter
+
+	f.Name = strings.TrimSpace(q.Get("name"))
+	if utf8.RuneCountInString(f.Name) > maxNameFilterLength {
+		return model.ProductFilter{}, fmt.Errorf("name must be at maximum %d characters in length", maxNameFilterLength)
+	}
+
+	var err error
+	if f.MinPrice, err = parsePrice(q.Get("minPrice")); err != nil {
+		return model.ProductFilter{}, fmt.Errorf("minPrice %w", err)
+	}
+
+	if f.MaxPrice, err = parsePrice(q.Get("maxPrice")); err != nil {
+		return model.ProductFilter{}, fmt.Errorf("maxPrice %w", err)
+	}
+
+	if f.MinPrice.Valid && f.MaxPrice.Valid && f.MinPrice.Decimal.GreaterThan(f.MaxPrice.Decimal) {
+		return model.ProductFilter{}, errors.New("minPrice must be less than or equal to maxPrice")
+	}
+
+	if s := q.Get("includeSubcategories"); s != "" {
+		if f.IncludeSubcategories, err = strconv.ParseBool(s); err != nil {
+			return model.ProductFilter{}, errors.New("includeSubcategories must be a boolean")
+		}
+	}
+
+	if s := q.Get("storeId"); s != "" {
+		if f.StoreID, err = strconv.ParseInt(s, 10, 64); err != nil || f.StoreID < 1 {
+			return model.ProductFilter{}, errors.New("storeId must be a positive integer")
+		}
+	}
+
+	if f.Attributes, err = getAttributeFilters(q); err != nil {
+		return model.ProductFilter{}, err
+	}
+
+	if s := q.Get("sort"); s != "" {
+		if strings.HasPrefix(s, "-") {
+			f.Desc = true
+			s = s[1:]
+		}
+
+		f.Sort = model.ProductSort(s)
+		if !isValidProductSort(f.Sort) {
+			return model.ProductFilter{}, errors.New("sort must be one of [id name price] optionally prefixed with '-'")
+		}
+	}
+
+	return f, nil
+}
+
+// getProductSearchFromURL parses and validates product search query parameters.
+func getProductSearchFromURL(r *http.Request) (model.ProductSearch, error) {
+	q := r.URL.Query()
+	var s model.ProductSearch
+
+	s.Query = strings.TrimSpace(q.Get("q"))
+	if s.Query == "" {
+		return model.ProductSearch{}, errors.New("q is a required parameter")
+	}
+
+	if utf8.RuneCountInString(s.Query) > maxSearchQueryLength {
+		return model.ProductSearch{}, fmt.Errorf("q must be at maximum %d characters in length", maxSearchQueryLength)
+	}
+
+	if c := q.Get("categoryId"); c != "" {
+		var err error
+		if s.CategoryID, err = strconv.ParseInt(c, 10, 64); err != nil || s.CategoryID < 1 {
+			return model.ProductSearch{}, errors.New("categoryId must be a positive integer")
+		}
+	}
+
+	return s, nil
+}
+
+// getPositionFilterFromURL parses position filter query parameters,
+// availability is a comma-separated list of statuses.
+func getPositionFilterFromURL(r *http.Request) (model.PositionFilter, error) {
+	var f model.PositionFilter
+
+	for _, s := range strings.Split(r.URL.Query().Get("availability"), ",") {
+		a := model.Availability(strings.TrimSpace(s))
+		if a == "" {
+			continue
+		}
+
+		if !isValidAvailability(a) {
+			return model.PositionFilter{}, errors.New("availability must be a comma-separated list of [in_stock backorder discontinued]")
+		}
+		f.Availability = append(f.Availability, a)
+	}
+
+	return f, nil
+}
+
+// getOrderFilterFromURL parses order list query parameters.
+func getOrderFilterFromURL(r *http.Request) (model.OrderFilter, error) {
+	q := r.URL.Query()
+	var f model.OrderFilter
+
+	if s := q.Get("userId"); s != "" {
+		var err error
+		if f.UserID, err = strconv.ParseInt(s, 10, 64); err != nil || f.UserID < 1 {
+			return model.OrderFilter{}, errors.New("userId must be a positive integer")
+		}
+	}
+
+	for _, s := range strings.Split(q.Get("status"), ",") {
+		status := model.OrderStatus(strings.TrimSpace(s))
+		if status == "" {
+			continue
+		}
+
+		if !isValidOrderStatus(status) {
+			return model.OrderFilter{}, errors.New("status must be a comma-separated list of [pending paid shipped delivered cancelled refunded]")
+		}
+		f.Status = append(f.Status, status)
+	}
+
+	return f, nil
+}
+
+// getPriceHistoryFilterFromURL parses price history range query parameters,
+// from and to accept RFC 3339 timestamps or dates, date in to includes the whole day.
+// Range defaults to 30 days before now.
+func getPriceHistoryFilterFromURL(r *http.Request, now time.Time) (model.PriceHistoryFilter, error) {
+	q := r.URL.Query()
+	var f model.PriceHistoryFilter
+
+	if s := q.Get("storeId"); s != "" {
+		var err error
+		if f.StoreID, err = strconv.ParseInt(s, 10, 64); err != nil || f.StoreID < 1 {
+			return model.PriceHistoryFilter{}, errors.New("storeId must be a positive integer")
+		}
+	}
+
+	f.To = now
+	if s := q.Get("to"); s != "" {
+		t, isDate, err := parseTime(s)
+		if err != nil {
+			return model.PriceHistoryFilter{}, fmt.Errorf("to %w", err)
+		}
+
+		if isDate {
+			t = t.AddDate(0, 0, 1)
+		}
+		f.To = t
+	}
+
+	f.From = f.To.Add(-defaultPriceHistoryRange)
+	if s := q.Get("from"); s != "" {
+		t, _, err := parseTime(s)
+		if err != nil {
+			return model.PriceHistoryFilter{}, fmt.Errorf("from %w", err)
+		}
+		f.From = t
+	}
+
+	if !f.From.Before(f.To) {
+		return model.PriceHistoryFilter{}, errors.New("from must be before to")
+	}
+
+	if f.To.Sub(f.From) > maxPriceHistoryRange {
+		return model.PriceHistoryFilter{}, errors.New("range between from and to must be at maximum 366 days")
+	}
+
+	return f, nil
+}
+
+// getProductIDsFromURL parses comma-separated list of product IDs.
+func getProductIDsFromURL(r *http.Request) ([]int64, error) {
+	var ids []int64
+
+	for _, s := range strings.Split(r.URL.Query().Get("ids"), ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+
+		id, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || id < 1 {
+			return nil, errors.New("ids must be a comma-separated list of positive integers")
+		}
+		ids = append(ids, id)
+	}
+
+	if len(ids) == 0 {
+		return nil, errors.New("ids is a required parameter")
+	}
+
+	if len(ids) > maxComparedProducts {
+		return nil, fmt.Errorf("ids must contain at maximum %d items", maxComparedProducts)
+	}
+
+	return ids, nil
+}
+
+// getCurrencyFromURL parses optional ISO 4217 currency code.
+func getCurrencyFromURL(r *http.Request) (string, error) {
+	c := strings.ToUpper(strings.TrimSpace(r.URL.Query().Get("currency")))
+	if c == "" {
+		return "", nil
+	}
+
+	if len(c) != 3 || strings.Trim(c, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") != "" {
+		return "", errors.New("currency must be an ISO 4217 code")
+	}
+
+	return c, nil
+}
+
+// getAttributeFilters parses attribute filters specified as attr.<name>=<value>
+// for exact match and attr.<name>.min=<number>, attr.<name>.max=<number> for range,
+// filters are ordered by attribute name.
+func getAttributeFilters(q url.Values) ([]model.AttributeFilter, error) {
+	filters := make(map[string]*model.AttributeFilter)
+	var names []string
+
+	for key := range q {
+		if !strings.HasPrefix(key, attributeFilterPrefix) {
+			continue
+		}
+
+		value := strings.TrimSpace(q.Get(key))
+		if value == "" {
+			continue
+		}
+
+		name := strings.TrimPrefix(key, attributeFilterPrefix)
+		bound := ""
+		if i := strings.LastIndex(name, "."); i >= 0 {
+			name, bound = name[:i], name[i+1:]
+		}
+
+		if name == "" || (bound != "" && bound != "min" && bound != "max") {
+			return nil, fmt.Errorf("%s must be in form of attr.<name>, attr.<name>.min or attr.<name>.max", key)
+		}
+
+		f, ok := filters[name]
+		if !ok {
+			f = &model.AttributeFilter{Name: name}
+			filters[name] = f
+			names = append(names, name)
+		}
+
+		if bound == "" {
+			f.Value = value
+			continue
+		}
+
+		d, err := decimal.NewFromString(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be a number", key)
+		}
+
+		if bound == "min" {
+			f.Min = decimal.NullDecimal{Decimal: d, Valid: true}
+		} else {
+			f.Max = decimal.NullDecimal{Decimal: d, Valid: true}
+		}
+	}
+
+	sort.Strings(names)
+
+	var res []model.AttributeFilter
+	for _, name := range names {
+		f := filters[name]
+		if f.Min.Valid && f.Max.Valid && f.Min.Decimal.GreaterThan(f.Max.Decimal) {
+			return nil, fmt.Errorf("%s%s.min must be less than or equal to %s%s.max", attributeFilterPrefix, name, attributeFilterPrefix, name)
+		}
+		res = append(res, *f)
+	}
+
+	return res, nil
+}
+
+func parsePrice(s string) (decimal.NullDecimal, error) {
+	if s == "" {
+		return decimal.NullDecimal{}, nil
+	}
+
+	d, err := decimal.NewFromString(s)
+	if err != nil {
+		return decimal.NullDecimal{}, errors.New("must be a number")
+	}
+
+	if d.IsNegative() {
+		return decimal.NullDecimal{}, errors.New("must be 0 or greater")
+	}
+
+	return decimal.NullDecimal{Decimal: d, Valid: true}, nil
+}
+
+// parseTime parses RFC 3339 timestamp or date and reports whether value is a date.
+func parseTime(s string) (time.Time, bool, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t.UTC(), false, nil
+	}
+
+	t, err := time.Parse(dateLayout, s)
+	if err != nil {
+		return time.Time{}, false, errors.New("must be a date or RFC 3339 timestamp")
+	}
+
+	return t, true, nil
+}
+
+func isValidProductSort(s model.ProductSort) bool {
+	for _, ps := range productSorts {
+		if s == ps {
+			return true
+		}
+	}
+	return false
+}
+
+func isValidAvailability(a model.Availability) bool {
+	for _, v := range availabilities {
+		if a == v {
+			return true
+		}
+	}
+	return false
+}
+
+func isValidOrderStatus(s model.OrderStatus) bool {
+	for _, v := range orderStatuses {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
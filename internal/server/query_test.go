@@ -0,0 +1,400 @@
+package server
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/vliubezny/gstore/internal/model"
+)
+
+func Test_getProductFilterFromURL(t *testing.T) {
+	testCases := []struct {
+		desc   string
+		query  string
+		filter model.ProductFilter
+		err    string
+	}{
+		{
+			desc:   "empty",
+			query:  "",
+			filter: model.ProductFilter{},
+		},
+		{
+			desc:  "all filters",
+			query: "?name=%20phone%20&minPrice=1.5&maxPrice=10&storeId=2&sort=name&includeSubcategories=true",
+			filter: model.ProductFilter{
+				Name:                 "phone",
+				MinPrice:             decimal.NullDecimal{Decimal: decimal.RequireFromString("1.5"), Valid: true},
+				MaxPrice:             decimal.NullDecimal{Decimal: decimal.NewFromInt(10), Valid: true},
+				IncludeSubcategories: true,
+				StoreID:              2,
+				Sort:                 model.SortByName,
+			},
+		},
+		{
+			desc:  "attribute filters",
+			query: "?attr.ram.min=8&attr.ram.max=32&attr.color=%20black%20&attr.os=",
+			filter: model.ProductFilter{
+				Attributes: []model.AttributeFilter{
+					{Name: "color", Value: "black"},
+					{
+						Name: "ram",
+						Min:  decimal.NullDecimal{Decimal: decimal.NewFromInt(8), Valid: true},
+						Max:  decimal.NullDecimal{Decimal: decimal.NewFromInt(32), Valid: true},
+					},
+				},
+			},
+		},
+		{
+			desc:  "malformed attribute filter bound",
+			query: "?attr.ram.avg=8",
+			err:   "attr.ram.avg must be in form of attr.<name>, attr.<name>.min or attr.<name>.max",
+		},
+		{
+			desc:  "malformed attribute filter name",
+			query: "?attr..min=8",
+			err:   "attr..min must be in form of attr.<name>, attr.<name>.min or attr.<name>.max",
+		},
+		{
+			desc:  "malformed attribute filter range",
+			query: "?attr.ram.min=lots",
+			err:   "attr.ram.min must be a number",
+		},
+		{
+			desc:  "attribute filter range min greater than max",
+			query: "?attr.ram.min=32&attr.ram.max=8",
+			err:   "attr.ram.min must be less than or equal to attr.ram.max",
+		},
+		{
+			desc:  "malformed includeSubcategories",
+			query: "?includeSubcategories=yes",
+			err:   "includeSubcategories must be a boolean",
+		},
+		{
+			desc:   "descending sort",
+			query:  "?sort=-id",
+			filter: model.ProductFilter{Sort: model.SortByID, Desc: true},
+		},
+		{
+			desc:  "long name",
+			query: "?name=" + strings.Repeat("x", 161),
+			err:   "name must be at maximum 160 characters in length",
+		},
+		{
+			desc:  "malformed minPrice",
+			query: "?minPrice=abc",
+			err:   "minPrice must be a number",
+		},
+		{
+			desc:  "negative maxPrice",
+			query: "?maxPrice=-1",
+			err:   "maxPrice must be 0 or greater",
+		},
+		{
+			desc:  "minPrice greater than maxPrice",
+			query: "?minPrice=10&maxPrice=5",
+			err:   "minPrice must be less than or equal to maxPrice",
+		},
+		{
+			desc:  "malformed storeId",
+			query: "?storeId=abc",
+			err:   "storeId must be a positive integer",
+		},
+		{
+			desc:  "zero storeId",
+			query: "?storeId=0",
+			err:   "storeId must be a positive integer",
+		},
+		{
+			desc:  "unknown sort",
+			query: "?sort=rating",
+			err:   "sort must be one of [id name price] optionally prefixed with '-'",
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			r := httptest.NewRequest("", "/"+tC.query, nil)
+
+			f, err := getProductFilterFromURL(r)
+
+			if tC.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tC.err)
+			}
+			assert.Equal(t, tC.filter, f)
+		})
+	}
+}
+
+func Test_getProductSearchFromURL(t *testing.T) {
+	testCases := []struct {
+		desc   string
+		query  string
+		search model.ProductSearch
+		err    string
+	}{
+		{
+			desc:   "query",
+			query:  "?q=%20iphone%2012%20",
+			search: model.ProductSearch{Query: "iphone 12"},
+		},
+		{
+			desc:   "query and category",
+			query:  "?q=iphone&categoryId=3",
+			search: model.ProductSearch{Query: "iphone", CategoryID: 3},
+		},
+		{
+			desc:  "missing query",
+			query: "?q=%20",
+			err:   "q is a required parameter",
+		},
+		{
+			desc:  "long query",
+			query: "?q=" + strings.Repeat("x", 201),
+			err:   "q must be at maximum 200 characters in length",
+		},
+		{
+			desc:  "malformed category",
+			query: "?q=iphone&categoryId=abc",
+			err:   "categoryId must be a positive integer",
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			r := httptest.NewRequest("", "/"+tC.query, nil)
+
+			s, err := getProductSearchFromURL(r)
+
+			if tC.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tC.err)
+			}
+			assert.Equal(t, tC.search, s)
+		})
+	}
+}
+
+func Test_getPriceHistoryFilterFromURL(t *testing.T) {
+	now := time.Date(2021, 3, 15, 12, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		desc   string
+		query  string
+		filter model.PriceHistoryFilter
+		err    string
+	}{
+		{
+			desc:  "defaults",
+			query: "",
+			filter: model.PriceHistoryFilter{
+				From: time.Date(2021, 2, 13, 12, 0, 0, 0, time.UTC),
+				To:   now,
+			},
+		},
+		{
+			desc:  "dates",
+			query: "?storeId=2&from=2021-01-01&to=2021-01-31",
+			filter: model.PriceHistoryFilter{
+				StoreID: 2,
+				From:    time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+				To:      time.Date(2021, 2, 1, 0, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			desc:  "timestamps",
+			query: "?from=2021-01-01T10:00:00%2B02:00&to=2021-01-02T10:00:00Z",
+			filter: model.PriceHistoryFilter{
+				From: time.Date(2021, 1, 1, 8, 0, 0, 0, time.UTC),
+				To:   time.Date(2021, 1, 2, 10, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			desc:  "default from",
+			query: "?to=2021-01-31",
+			filter: model.PriceHistoryFilter{
+				From: time.Date(2021, 1, 2, 0, 0, 0, 0, time.UTC),
+				To:   time.Date(2021, 2, 1, 0, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			desc:  "malformed storeId",
+			query: "?storeId=0",
+			err:   "storeId must be a positive integer",
+		},
+		{
+			desc:  "malformed from",
+			query: "?from=yesterday",
+			err:   "from must be a date or RFC 3339 timestamp",
+		},
+		{
+			desc:  "malformed to",
+			query: "?to=2021-13-01",
+			err:   "to must be a date or RFC 3339 timestamp",
+		},
+		{
+			desc:  "from after to",
+			query: "?from=2021-02-01&to=2021-01-31",
+			err:   "from must be before to",
+		},
+		{
+			desc:  "long range",
+			query: "?from=2020-01-01&to=2021-01-01",
+			err:   "range between from and to must be at maximum 366 days",
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			r := httptest.NewRequest("", "/"+tC.query, nil)
+
+			f, err := getPriceHistoryFilterFromURL(r, now)
+
+			if tC.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tC.err)
+			}
+			assert.Equal(t, tC.filter, f)
+		})
+	}
+}
+
+func Test_getOrderFilterFromURL(t *testing.T) {
+	testCases := []struct {
+		desc   string
+		query  string
+		filter model.OrderFilter
+		err    string
+	}{
+		{
+			desc:   "empty",
+			query:  "",
+			filter: model.OrderFilter{},
+		},
+		{
+			desc:  "success",
+			query: "?userId=2&status=paid,%20shipped,",
+			filter: model.OrderFilter{
+				UserID: 2,
+				Status: []model.OrderStatus{model.OrderPaid, model.OrderShipped},
+			},
+		},
+		{
+			desc:  "malformed userId",
+			query: "?userId=a",
+			err:   "userId must be a positive integer",
+		},
+		{
+			desc:  "malformed status",
+			query: "?status=paid,lost",
+			err:   "status must be a comma-separated list of [pending paid shipped delivered cancelled refunded]",
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			r := httptest.NewRequest("", "/"+tC.query, nil)
+
+			f, err := getOrderFilterFromURL(r)
+
+			if tC.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tC.err)
+			}
+			assert.Equal(t, tC.filter, f)
+		})
+	}
+}
+
+func Test_getProductIDsFromURL(t *testing.T) {
+	testCases := []struct {
+		desc  string
+		query string
+		ids   []int64
+		err   string
+	}{
+		{
+			desc:  "success",
+			query: "?ids=3,%201,2,",
+			ids:   []int64{3, 1, 2},
+		},
+		{
+			desc:  "missing ids",
+			query: "?ids=",
+			err:   "ids is a required parameter",
+		},
+		{
+			desc:  "malformed ids",
+			query: "?ids=1,a",
+			err:   "ids must be a comma-separated list of positive integers",
+		},
+		{
+			desc:  "too many ids",
+			query: "?ids=" + strings.Repeat("1,", 101),
+			err:   "ids must contain at maximum 100 items",
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			r := httptest.NewRequest("", "/"+tC.query, nil)
+
+			ids, err := getProductIDsFromURL(r)
+
+			if tC.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tC.err)
+			}
+			assert.Equal(t, tC.ids, ids)
+		})
+	}
+}
+
+func Test_getCurrencyFromURL(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		query    string
+		currency string
+		err      string
+	}{
+		{
+			desc:     "empty",
+			query:    "",
+			currency: "",
+		},
+		{
+			desc:     "lowercase",
+			query:    "?currency=%20eur%20",
+			currency: "EUR",
+		},
+		{
+			desc:  "too long",
+			query: "?currency=EURO",
+			err:   "currency must be an ISO 4217 code",
+		},
+		{
+			desc:  "not letters",
+			query: "?currency=E1R",
+			err:   "currency must be an ISO 4217 code",
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			r := httptest.NewRequest("", "/"+tC.query, nil)
+
+			c, err := getCurrencyFromURL(r)
+
+			if tC.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tC.err)
+			}
+			assert.Equal(t, tC.currency, c)
+		})
+	}
+}
@@ -2,6 +2,7 @@ package server
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"runtime/debug"
 	"strconv"
@@ -10,20 +11,46 @@ import (
 	"github.com/go-chi/chi"
 	"github.com/shopspring/decimal"
 	"github.com/sirupsen/logrus"
+	"github.com/tomasen/realip"
 	"github.com/vliubezny/gstore/internal/auth"
+	"github.com/vliubezny/gstore/internal/model"
 	"github.com/vliubezny/gstore/internal/service"
 )
 
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+
+	imageFormField = "image"
+	maxImageSize   = 10 << 20
+	// maxImageRequestSize leaves room for multipart form overhead.
+	maxImageRequestSize = maxImageSize + 1<<20
+
+	maxExchangeRatesSize = 1 << 20
+
+	maxPaymentWebhookSize = 64 << 10
+)
+
 type server struct {
 	s service.Service
 	a auth.Service
+	c service.CartService
+	o service.OrderService
+
+	isRevoked auth.RevocationChecker
 }
 
 // SetupRouter setups routes and handlers.
-func SetupRouter(s service.Service, a auth.Service, r chi.Router, accessTokenValidator auth.AccessTokenValidator) {
+func SetupRouter(s service.Service, a auth.Service, c service.CartService, o service.OrderService,
+	r chi.Router, accessTokenValidator auth.AccessTokenValidator, revocationChecker auth.RevocationChecker,
+	apiKeyValidator auth.APIKeyValidator, verificationPolicy auth.VerificationPolicy) {
 	srv := &server{
 		s: s,
 		a: a,
+		c: c,
+		o: o,
+
+		isRevoked: revocationChecker,
 	}
 
 	r.Use(
@@ -34,42 +61,162 @@ func SetupRouter(s service.Service, a auth.Service, r chi.Router, accessTokenVal
 
 	r.Post("/v1/register", srv.registerHandler)
 	r.Post("/v1/login", srv.loginHandler)
+	r.Post("/v1/login/mfa", srv.loginMFAHandler)
 	r.Post("/v1/refresh", srv.refreshHandler)
 	r.Post("/v1/revoke", srv.revokeHandler)
+	r.Post("/v1/password/forgot", srv.forgotPasswordHandler)
+	r.Post("/v1/password/reset", srv.resetPasswordHandler)
+	r.Post("/v1/verify-email", srv.verifyEmailHandler)
+	r.Post("/v1/verify-email/resend", srv.resendVerificationHandler)
+
+	r.Get("/.well-known/jwks.json", srv.getJWKSHandler)
+
+	// OAuth clients authenticate with own credentials
+	r.Post("/v1/oauth/token", srv.oauthTokenHandler)
+	r.Post("/v1/oauth/introspect", srv.introspectHandler)
 
 	r.Get("/v1/categories", srv.getCategoriesHandler)
+	r.Get("/v1/categories/tree", srv.getCategoryTreeHandler)
 	r.Get("/v1/categories/{id}", srv.getCategoryHandler)
+	r.Get("/v1/categories/{id}/tree", srv.getCategorySubtreeHandler)
 	r.Get("/v1/categories/{id}/products", srv.getCategoryProductsHandler)
 
 	r.Get("/v1/stores", srv.getStoresHandler)
 	r.Get("/v1/stores/{id}", srv.getStoreHandler)
 	r.Get("/v1/stores/{id}/positions", srv.getStorePositionsHandler)
 
+	r.Get("/v1/products/search", srv.searchProductsHandler)
+	r.Get("/v1/products/compare", srv.compareProductPricesHandler)
 	r.Get("/v1/products/{id}", srv.getProductHandler)
 	r.Get("/v1/products/{id}/offers", srv.getProductOffersHandler)
+	r.Get("/v1/products/{id}/variants", srv.getProductVariantsHandler)
+
+	r.Get("/v1/exchange-rates", srv.getExchangeRatesHandler)
+
+	// webhook requests are authenticated with provider signature
+	r.Post("/v1/payments/webhook", srv.paymentWebhookHandler)
+
+	r.Group(func(r chi.Router) {
+		r.Use(
+			optionalJWTAuthMiddleware(accessTokenValidator, revocationChecker, apiKeyValidator),
+			allowScopeMiddleware(model.ScopeCart),
+		)
+
+		r.Get("/v1/cart", srv.getCartHandler)
+		r.Delete("/v1/cart", srv.clearCartHandler)
+		r.Put("/v1/cart/items/{storeId}/{variantId}", srv.setCartItemHandler)
+		r.Delete("/v1/cart/items/{storeId}/{variantId}", srv.deleteCartItemHandler)
+	})
 
 	r.Group(func(r chi.Router) {
 		r.Use(
-			jwtAuthMiddleware(accessTokenValidator),
-			allowAdminMiddleware,
+			jwtAuthMiddleware(accessTokenValidator, revocationChecker, apiKeyValidator),
+			verifiedEmailMiddleware(verificationPolicy),
 		)
 
-		r.Put("/v1/users/{id}/permissions", srv.updateUserPermissionsHandler)
+		r.With(allowScopeMiddleware(model.ScopeOrders)).Post("/v1/orders", srv.checkoutHandler)
+		r.With(allowScopeMiddleware(model.ScopePayments)).Post("/v1/orders/{id}/payments", srv.payOrderHandler)
+
+		r.Group(func(r chi.Router) {
+			r.Use(allowScopeMiddleware(model.ScopeOrders, model.PermissionOrderRead))
+
+			r.Get("/v1/orders", srv.getOrdersHandler)
+			r.Get("/v1/orders/{id}", srv.getOrderHandler)
+		})
+
+		r.Group(func(r chi.Router) {
+			r.Use(denyDelegatedMiddleware)
+
+			r.Post("/v1/mfa/enroll", srv.enrollMFAHandler)
+			r.Post("/v1/mfa/confirm", srv.confirmMFAHandler)
+			r.Post("/v1/mfa/disable", srv.disableMFAHandler)
+
+			r.Get("/v1/sessions", srv.getSessionsHandler)
+			r.Delete("/v1/sessions", srv.deleteSessionsHandler)
+			r.Delete("/v1/sessions/{id}", srv.deleteSessionHandler)
+
+			r.Post("/v1/api-keys", srv.createAPIKeyHandler)
+			r.Get("/v1/api-keys", srv.getAPIKeysHandler)
+			r.Delete("/v1/api-keys/{id}", srv.deleteAPIKeyHandler)
+
+			r.Post("/v1/oauth/clients", srv.registerOAuthClientHandler)
+			r.Get("/v1/oauth/clients", srv.getOAuthClientsHandler)
+			r.Delete("/v1/oauth/clients/{id}", srv.deleteOAuthClientHandler)
+			r.Post("/v1/oauth/authorize", srv.authorizeHandler)
+		})
+
+		r.Group(func(r chi.Router) {
+			r.Use(allowPermissionMiddleware(model.PermissionUserWrite))
+
+			r.Get("/v1/roles", srv.getRolesHandler)
+			r.Put("/v1/users/{id}/roles", srv.setUserRolesHandler)
+			r.Delete("/v1/users/{id}/lockout", srv.unlockUserHandler)
+		})
+
+		r.Group(func(r chi.Router) {
+			r.Use(allowPermissionMiddleware(model.PermissionCategoryWrite))
+
+			r.Post("/v1/categories", srv.createCategoryHandler)
+			r.Put("/v1/categories/{id}", srv.updateCategoryHandler)
+			r.Delete("/v1/categories/{id}", srv.deleteCategoryHandler)
+		})
 
-		r.Post("/v1/categories", srv.createCategoryHandler)
-		r.Put("/v1/categories/{id}", srv.updateCategoryHandler)
-		r.Delete("/v1/categories/{id}", srv.deleteCategoryHandler)
+		r.Group(func(r chi.Router) {
+			r.Use(allowPermissionMiddleware(model.PermissionProductWrite))
 
-		r.Post("/v1/products", srv.createProductHandler)
-		r.Put("/v1/products/{id}", srv.updateProductHandler)
-		r.Delete("/v1/products/{id}", srv.deleteProductHandler)
+			r.Post("/v1/products", srv.createProductHandler)
+			r.Put("/v1/products/{id}", srv.updateProductHandler)
+			r.Delete("/v1/products/{id}", srv.deleteProductHandler)
 
-		r.Post("/v1/stores", srv.createStoreHandler)
-		r.Put("/v1/stores/{id}", srv.updateStoreHandler)
-		r.Delete("/v1/stores/{id}", srv.deleteStoreHandler)
+			r.Post("/v1/products/{id}/images", srv.addProductImageHandler)
+			r.Put("/v1/products/{id}/images/order", srv.setProductImageOrderHandler)
+			r.Put("/v1/products/{id}/images/{imageId}/primary", srv.setPrimaryProductImageHandler)
+			r.Delete("/v1/products/{id}/images/{imageId}", srv.deleteProductImageHandler)
 
-		r.Put("/v1/stores/{id}/positions/{productId}", srv.setPositionHandler)
-		r.Delete("/v1/stores/{id}/positions/{productId}", srv.deletePositionHandler)
+			r.Post("/v1/products/{id}/variants", srv.createVariantHandler)
+			r.Put("/v1/products/{id}/variants/{variantId}", srv.updateVariantHandler)
+			r.Delete("/v1/products/{id}/variants/{variantId}", srv.deleteVariantHandler)
+		})
+
+		r.Group(func(r chi.Router) {
+			r.Use(allowPermissionMiddleware(model.PermissionStoreWrite))
+
+			r.Post("/v1/stores", srv.createStoreHandler)
+			r.Delete("/v1/stores/{id}", srv.deleteStoreHandler)
+		})
+
+		r.Group(func(r chi.Router) {
+			r.Use(srv.allowStoreMemberMiddleware(model.PermissionStoreWrite, model.StoreOwner))
+
+			r.Put("/v1/stores/{id}", srv.updateStoreHandler)
+
+			r.Get("/v1/stores/{id}/members", srv.getStoreMembersHandler)
+			r.Put("/v1/stores/{id}/members", srv.setStoreMemberHandler)
+			r.Delete("/v1/stores/{id}/members/{userId}", srv.deleteStoreMemberHandler)
+		})
+
+		r.Group(func(r chi.Router) {
+			r.Use(srv.allowStoreMemberMiddleware(model.PermissionPositionWrite, model.StoreOwner, model.StoreEditor))
+
+			r.Put("/v1/stores/{id}/positions/{variantId}", srv.setPositionHandler)
+			r.Delete("/v1/stores/{id}/positions/{variantId}", srv.deletePositionHandler)
+			r.Post("/v1/stores/{id}/positions/{variantId}/stock/increment", srv.increaseStockHandler)
+			r.Post("/v1/stores/{id}/positions/{variantId}/stock/decrement", srv.decreaseStockHandler)
+		})
+
+		r.With(allowPermissionMiddleware(model.PermissionPriceHistoryRead)).
+			Get("/v1/products/{id}/price-history", srv.getPriceHistoryHandler)
+
+		r.Group(func(r chi.Router) {
+			r.Use(allowPermissionMiddleware(model.PermissionExchangeRateWrite))
+
+			r.Put("/v1/exchange-rates/{currency}", srv.setExchangeRateHandler)
+			r.Delete("/v1/exchange-rates/{currency}", srv.deleteExchangeRateHandler)
+			r.Post("/v1/exchange-rates/import", srv.importExchangeRatesHandler)
+		})
+
+		r.With(allowPermissionMiddleware(model.PermissionOrderWrite)).
+			Post("/v1/orders/{id}/transitions", srv.transitionOrderHandler)
 	})
 
 	decimal.MarshalJSONWithoutQuotes = true
@@ -87,11 +234,52 @@ func extractBearer(r *http.Request) string {
 	return ""
 }
 
+func extractAPIKey(r *http.Request) string {
+	auth := r.Header.Get("Authorization")
+	if len(auth) > 7 && strings.ToUpper(auth[0:7]) == "APIKEY " {
+		return auth[7:]
+	}
+	return ""
+}
+
+// getClaims returns claims of authenticated user.
+func getClaims(r *http.Request) auth.AccessTokenClaims {
+	claims, _ := r.Context().Value(claimsKey{}).(auth.AccessTokenClaims)
+	return claims
+}
+
+// getClient returns description of the client that sent the request.
+func getClient(r *http.Request) auth.Client {
+	return auth.Client{
+		IP:        realip.FromRequest(r),
+		UserAgent: r.UserAgent(),
+	}
+}
+
 func getIDFromURL(r *http.Request, key string) (int64, error) {
 	id := chi.URLParam(r, key)
 	return strconv.ParseInt(id, 10, 64)
 }
 
+// getPageFromURL parses limit and cursor query parameters.
+func getPageFromURL(r *http.Request) (model.Page, error) {
+	q := r.URL.Query()
+	page := model.Page{
+		Limit:  defaultPageLimit,
+		Cursor: q.Get("cursor"),
+	}
+
+	if l := q.Get("limit"); l != "" {
+		limit, err := strconv.Atoi(l)
+		if err != nil || limit < 1 || limit > maxPageLimit {
+			return model.Page{}, fmt.Errorf("limit must be between 1 and %d", maxPageLimit)
+		}
+		page.Limit = limit
+	}
+
+	return page, nil
+}
+
 func writeError(l logrus.FieldLogger, w http.ResponseWriter, code int, message string) {
 	l.Error(message)
 
@@ -16,6 +16,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/vliubezny/gstore/internal/auth"
+	"github.com/vliubezny/gstore/internal/model"
 	"github.com/vliubezny/gstore/internal/service"
 )
 
@@ -24,15 +25,39 @@ const (
 	testPassword = "pass123"
 )
 
+// testSuperadminClaims grant every permission.
+var testSuperadminClaims = auth.AccessTokenClaims{
+	UserID: 1,
+	Roles:  []string{model.RoleSuperadmin},
+	Permissions: []model.Permission{
+		model.PermissionUserWrite, model.PermissionCategoryWrite, model.PermissionProductWrite,
+		model.PermissionStoreWrite, model.PermissionPositionWrite, model.PermissionPriceHistoryRead,
+		model.PermissionExchangeRateWrite, model.PermissionOrderRead, model.PermissionOrderWrite,
+	},
+}
+
 func setupTestRouter(s service.Service) http.Handler {
 	return setupTestRouterWithAuth(s, nil)
 }
 
 func setupTestRouterWithAuth(s service.Service, a auth.Service) http.Handler {
+	return setupTestRouterWithCart(s, a, nil)
+}
+
+func setupTestRouterWithCart(s service.Service, a auth.Service, c service.CartService) http.Handler {
+	return setupTestRouterWithOrders(s, a, c, nil, testSuperadminClaims)
+}
+
+func setupTestRouterWithOrders(s service.Service, a auth.Service, c service.CartService, o service.OrderService,
+	claims auth.AccessTokenClaims) http.Handler {
 	r := chi.NewRouter()
-	SetupRouter(s, a, r, func(_ string) (auth.AccessTokenClaims, error) {
-		return auth.AccessTokenClaims{UserID: 1, IsAdmin: true}, nil
-	})
+	SetupRouter(s, a, c, o, r, func(_ string) (auth.AccessTokenClaims, error) {
+		return claims, nil
+	}, func(_ auth.AccessTokenClaims) bool {
+		return false
+	}, func(_ context.Context, _ string) (auth.AccessTokenClaims, error) {
+		return claims, nil
+	}, auth.VerificationOptional)
 	return r
 }
 
@@ -158,3 +183,57 @@ func Test_extractBearer(t *testing.T) {
 		})
 	}
 }
+
+func Test_getPageFromURL(t *testing.T) {
+	testCases := []struct {
+		desc  string
+		query string
+		page  model.Page
+		err   string
+	}{
+		{
+			desc:  "default",
+			query: "",
+			page:  model.Page{Limit: defaultPageLimit},
+		},
+		{
+			desc:  "limit and cursor",
+			query: "?limit=5&cursor=abc",
+			page:  model.Page{Limit: 5, Cursor: "abc"},
+		},
+		{
+			desc:  "max limit",
+			query: "?limit=100",
+			page:  model.Page{Limit: maxPageLimit},
+		},
+		{
+			desc:  "zero limit",
+			query: "?limit=0",
+			err:   "limit must be between 1 and 100",
+		},
+		{
+			desc:  "too big limit",
+			query: "?limit=101",
+			err:   "limit must be between 1 and 100",
+		},
+		{
+			desc:  "malformed limit",
+			query: "?limit=ten",
+			err:   "limit must be between 1 and 100",
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			r := httptest.NewRequest("", "/"+tC.query, nil)
+
+			page, err := getPageFromURL(r)
+
+			if tC.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tC.err)
+			}
+			assert.Equal(t, tC.page, page)
+		})
+	}
+}
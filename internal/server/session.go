@@ -0,0 +1,65 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+	"github.com/vliubezny/gstore/internal/auth"
+)
+
+func (s *server) getSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	l := getLogger(r)
+	claims := getClaims(r)
+
+	sessions, err := s.a.GetSessions(r.Context(), claims.UserID)
+	if err != nil {
+		writeInternalError(l.WithError(err), w, "fail to get sessions")
+		return
+	}
+
+	resp := make([]session, len(sessions))
+	for i, s := range sessions {
+		resp[i] = fromSessionModel(s, claims.SessionID)
+	}
+
+	writeOK(l, w, resp)
+}
+
+func (s *server) deleteSessionHandler(w http.ResponseWriter, r *http.Request) {
+	l := getLogger(r)
+
+	id := chi.URLParam(r, "id")
+	if _, err := uuid.Parse(id); err != nil {
+		writeError(l.WithError(err), w, http.StatusBadRequest, "invalid session ID")
+		return
+	}
+
+	if err := s.a.RevokeSession(r.Context(), getClaims(r).UserID, id); err != nil {
+		if errors.Is(err, auth.ErrNotFound) {
+			writeError(l.WithError(err), w, http.StatusNotFound, "session not found")
+			return
+		}
+
+		writeInternalError(l.WithError(err), w, "fail to revoke session")
+		return
+	}
+
+	l.WithField("sessionID", id).Info("session revoked")
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *server) deleteSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	l := getLogger(r)
+
+	if err := s.a.RevokeSessions(r.Context(), getClaims(r).UserID); err != nil {
+		writeInternalError(l.WithError(err), w, "fail to revoke sessions")
+		return
+	}
+
+	l.Info("all sessions revoked")
+
+	w.WriteHeader(http.StatusNoContent)
+}
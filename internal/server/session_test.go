@@ -0,0 +1,187 @@
+package server
+
+import (
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/vliubezny/gstore/internal/auth"
+	"github.com/vliubezny/gstore/internal/model"
+)
+
+const testSessionID = "0e37df36-f698-11e6-8dd4-cb9ced3df976"
+
+func Test_getSessionsHandler(t *testing.T) {
+	ts := time.Date(2021, 3, 1, 10, 0, 0, 0, time.UTC)
+	sessions := []model.Session{
+		{ID: testSessionID, UserID: 1, IP: "127.0.0.1", UserAgent: "test", CreatedAt: ts, LastUsedAt: ts, ExpiresAt: ts},
+		{ID: "1e37df36-f698-11e6-8dd4-cb9ced3df976", UserID: 1, IP: "10.0.0.1", UserAgent: "other", CreatedAt: ts, LastUsedAt: ts, ExpiresAt: ts},
+	}
+
+	testCases := []struct {
+		desc      string
+		rSessions []model.Session
+		rErr      error
+		rcode     int
+		rdata     string
+	}{
+		{
+			desc:      "success",
+			rSessions: sessions,
+			rErr:      nil,
+			rcode:     http.StatusOK,
+			rdata: `[
+				{"id":"0e37df36-f698-11e6-8dd4-cb9ced3df976", "ip":"127.0.0.1", "userAgent":"test", "current":true,
+				 "createdAt":"2021-03-01T10:00:00Z", "lastUsedAt":"2021-03-01T10:00:00Z", "expiresAt":"2021-03-01T10:00:00Z"},
+				{"id":"1e37df36-f698-11e6-8dd4-cb9ced3df976", "ip":"10.0.0.1", "userAgent":"other", "current":false,
+				 "createdAt":"2021-03-01T10:00:00Z", "lastUsedAt":"2021-03-01T10:00:00Z", "expiresAt":"2021-03-01T10:00:00Z"}
+			]`,
+		},
+		{
+			desc:      "no sessions",
+			rSessions: []model.Session{},
+			rErr:      nil,
+			rcode:     http.StatusOK,
+			rdata:     `[]`,
+		},
+		{
+			desc:      "internal error",
+			rSessions: nil,
+			rErr:      assert.AnError,
+			rcode:     http.StatusInternalServerError,
+			rdata:     `{"error":"internal error"}`,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			svc := auth.NewMockService(ctrl)
+			svc.EXPECT().GetSessions(gomock.Any(), int64(1)).Return(tC.rSessions, tC.rErr)
+
+			claims := auth.AccessTokenClaims{UserID: 1, SessionID: testSessionID}
+			router := setupTestRouterWithOrders(nil, svc, nil, nil, claims)
+			rec, r := newTestParameters(http.MethodGet, "/v1/sessions", "")
+
+			router.ServeHTTP(rec, r)
+
+			body, _ := ioutil.ReadAll(rec.Result().Body)
+
+			assert.Equal(t, tC.rcode, rec.Result().StatusCode)
+			assert.JSONEq(t, tC.rdata, string(body))
+		})
+	}
+}
+
+func Test_deleteSessionHandler(t *testing.T) {
+	testCases := []struct {
+		desc  string
+		id    string
+		err   error
+		rcode int
+		rdata string
+	}{
+		{
+			desc:  "success",
+			id:    testSessionID,
+			err:   nil,
+			rcode: http.StatusNoContent,
+			rdata: "",
+		},
+		{
+			desc:  "invalid session ID",
+			id:    "test",
+			err:   errSkip,
+			rcode: http.StatusBadRequest,
+			rdata: `{"error":"invalid session ID"}`,
+		},
+		{
+			desc:  "session not found",
+			id:    testSessionID,
+			err:   auth.ErrNotFound,
+			rcode: http.StatusNotFound,
+			rdata: `{"error":"session not found"}`,
+		},
+		{
+			desc:  "internal error",
+			id:    testSessionID,
+			err:   assert.AnError,
+			rcode: http.StatusInternalServerError,
+			rdata: `{"error":"internal error"}`,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			svc := auth.NewMockService(ctrl)
+			if tC.err != errSkip {
+				svc.EXPECT().RevokeSession(gomock.Any(), testSuperadminClaims.UserID, testSessionID).Return(tC.err)
+			}
+
+			router := setupTestRouterWithAuth(nil, svc)
+			rec, r := newTestParameters(http.MethodDelete, "/v1/sessions/"+tC.id, "")
+
+			router.ServeHTTP(rec, r)
+
+			body, _ := ioutil.ReadAll(rec.Result().Body)
+
+			assert.Equal(t, tC.rcode, rec.Result().StatusCode)
+			if tC.rdata == "" {
+				assert.Empty(t, string(body))
+			} else {
+				assert.JSONEq(t, tC.rdata, string(body))
+			}
+		})
+	}
+}
+
+func Test_deleteSessionsHandler(t *testing.T) {
+	testCases := []struct {
+		desc  string
+		err   error
+		rcode int
+		rdata string
+	}{
+		{
+			desc:  "success",
+			err:   nil,
+			rcode: http.StatusNoContent,
+			rdata: "",
+		},
+		{
+			desc:  "internal error",
+			err:   assert.AnError,
+			rcode: http.StatusInternalServerError,
+			rdata: `{"error":"internal error"}`,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			svc := auth.NewMockService(ctrl)
+			svc.EXPECT().RevokeSessions(gomock.Any(), testSuperadminClaims.UserID).Return(tC.err)
+
+			router := setupTestRouterWithAuth(nil, svc)
+			rec, r := newTestParameters(http.MethodDelete, "/v1/sessions", "")
+
+			router.ServeHTTP(rec, r)
+
+			body, _ := ioutil.ReadAll(rec.Result().Body)
+
+			assert.Equal(t, tC.rcode, rec.Result().StatusCode)
+			if tC.rdata == "" {
+				assert.Empty(t, string(body))
+			} else {
+				assert.JSONEq(t, tC.rdata, string(body))
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+
+	"github.com/vliubezny/gstore/internal/model"
+)
+
+const maxAttributeNameLength = 40
+
+var attributeNameRegexp = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// validateSchema checks that category schema is well-formed.
+func validateSchema(schema []model.Attribute) error {
+	names := make(map[string]bool, len(schema))
+
+	for _, a := range schema {
+		if len(a.Name) > maxAttributeNameLength || !attributeNameRegexp.MatchString(a.Name) {
+			return fmt.Errorf("%w: attribute name %q must start with a letter, contain only lowercase letters, digits and underscores and be at maximum %d characters in length",
+				ErrInvalidSchema, a.Name, maxAttributeNameLength)
+		}
+
+		if names[a.Name] {
+			return fmt.Errorf("%w: attribute %q is defined more than once", ErrInvalidSchema, a.Name)
+		}
+		names[a.Name] = true
+
+		switch a.Type {
+		case model.AttributeString, model.AttributeNumber, model.AttributeEnum, model.AttributeBoolean:
+		default:
+			return fmt.Errorf("%w: attribute %q has unknown type %q", ErrInvalidSchema, a.Name, a.Type)
+		}
+
+		if a.Unit != "" && a.Type != model.AttributeNumber {
+			return fmt.Errorf("%w: attribute %q cannot have unit, only number attributes can", ErrInvalidSchema, a.Name)
+		}
+
+		if a.Type != model.AttributeEnum {
+			if len(a.Values) > 0 {
+				return fmt.Errorf("%w: attribute %q cannot have values, only enum attributes can", ErrInvalidSchema, a.Name)
+			}
+			continue
+		}
+
+		if len(a.Values) == 0 {
+			return fmt.Errorf("%w: enum attribute %q must define values", ErrInvalidSchema, a.Name)
+		}
+
+		values := make(map[string]bool, len(a.Values))
+		for _, v := range a.Values {
+			if v == "" || values[v] {
+				return fmt.Errorf("%w: enum attribute %q must define unique non-empty values", ErrInvalidSchema, a.Name)
+			}
+			values[v] = true
+		}
+	}
+
+	return nil
+}
+
+// validateAttributes checks product attributes against category schema.
+func validateAttributes(schema []model.Attribute, attrs map[string]interface{}) error {
+	defined := make(map[string]model.Attribute, len(schema))
+	for _, a := range schema {
+		defined[a.Name] = a
+
+		if _, ok := attrs[a.Name]; a.Required && !ok {
+			return fmt.Errorf("%w: attribute %q is required", ErrInvalidAttributes, a.Name)
+		}
+	}
+
+	for name, v := range attrs {
+		a, ok := defined[name]
+		if !ok {
+			return fmt.Errorf("%w: unknown attribute %q", ErrInvalidAttributes, name)
+		}
+
+		if err := checkAttributeValue(a, v); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkAttributeValue checks that value matches attribute type.
+func checkAttributeValue(a model.Attribute, v interface{}) error {
+	switch a.Type {
+	case model.AttributeString:
+		if _, ok := v.(string); !ok {
+			return fmt.Errorf("%w: attribute %q must be a string", ErrInvalidAttributes, a.Name)
+		}
+	case model.AttributeNumber:
+		if _, ok := v.(float64); !ok {
+			return fmt.Errorf("%w: attribute %q must be a number", ErrInvalidAttributes, a.Name)
+		}
+	case model.AttributeBoolean:
+		if _, ok := v.(bool); !ok {
+			return fmt.Errorf("%w: attribute %q must be a boolean", ErrInvalidAttributes, a.Name)
+		}
+	case model.AttributeEnum:
+		s, _ := v.(string)
+		for _, allowed := range a.Values {
+			if s == allowed {
+				return nil
+			}
+		}
+		return fmt.Errorf("%w: attribute %q must be one of %v", ErrInvalidAttributes, a.Name, a.Values)
+	}
+
+	return nil
+}
+
+// resolveAttributeFilters checks attribute filters against category schema
+// and converts textual filter values into attribute types.
+func resolveAttributeFilters(schema []model.Attribute, filters []model.AttributeFilter) ([]model.AttributeFilter, error) {
+	defined := make(map[string]model.Attribute, len(schema))
+	for _, a := range schema {
+		defined[a.Name] = a
+	}
+
+	resolved := make([]model.AttributeFilter, len(filters))
+	for i, f := range filters {
+		a, ok := defined[f.Name]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown attribute %q", ErrInvalidAttributes, f.Name)
+		}
+
+		if (f.Min.Valid || f.Max.Valid) && a.Type != model.AttributeNumber {
+			return nil, fmt.Errorf("%w: attribute %q does not support range filter", ErrInvalidAttributes, f.Name)
+		}
+
+		if s, ok := f.Value.(string); ok {
+			v, err := parseAttributeValue(a, s)
+			if err != nil {
+				return nil, err
+			}
+			f.Value = v
+		}
+
+		resolved[i] = f
+	}
+
+	return resolved, nil
+}
+
+// parseAttributeValue converts textual value into attribute type.
+func parseAttributeValue(a model.Attribute, s string) (interface{}, error) {
+	var v interface{} = s
+
+	switch a.Type {
+	case model.AttributeNumber:
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+			return nil, fmt.Errorf("%w: attribute %q must be a number", ErrInvalidAttributes, a.Name)
+		}
+		v = f
+	case model.AttributeBoolean:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return nil, fmt.Errorf("%w: attribute %q must be a boolean", ErrInvalidAttributes, a.Name)
+		}
+		v = b
+	}
+
+	if err := checkAttributeValue(a, v); err != nil {
+		return nil, err
+	}
+
+	return v, nil
+}
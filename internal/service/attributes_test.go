@@ -0,0 +1,141 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vliubezny/gstore/internal/model"
+)
+
+func Test_validateSchema(t *testing.T) {
+	testCases := []struct {
+		desc   string
+		schema []model.Attribute
+		err    error
+	}{
+		{
+			desc: "valid",
+			schema: []model.Attribute{
+				{Name: "screen_size", Type: model.AttributeNumber, Unit: "in", Required: true},
+				{Name: "os", Type: model.AttributeEnum, Values: []string{"android", "ios"}},
+				{Name: "model", Type: model.AttributeString},
+				{Name: "nfc", Type: model.AttributeBoolean},
+			},
+			err: nil,
+		},
+		{
+			desc:   "empty",
+			schema: nil,
+			err:    nil,
+		},
+		{
+			desc:   "malformed name",
+			schema: []model.Attribute{{Name: "Screen Size", Type: model.AttributeNumber}},
+			err:    ErrInvalidSchema,
+		},
+		{
+			desc:   "duplicate name",
+			schema: []model.Attribute{{Name: "ram", Type: model.AttributeNumber}, {Name: "ram", Type: model.AttributeString}},
+			err:    ErrInvalidSchema,
+		},
+		{
+			desc:   "unknown type",
+			schema: []model.Attribute{{Name: "ram", Type: "size"}},
+			err:    ErrInvalidSchema,
+		},
+		{
+			desc:   "unit of string attribute",
+			schema: []model.Attribute{{Name: "model", Type: model.AttributeString, Unit: "cm"}},
+			err:    ErrInvalidSchema,
+		},
+		{
+			desc:   "values of number attribute",
+			schema: []model.Attribute{{Name: "ram", Type: model.AttributeNumber, Values: []string{"8"}}},
+			err:    ErrInvalidSchema,
+		},
+		{
+			desc:   "enum without values",
+			schema: []model.Attribute{{Name: "os", Type: model.AttributeEnum}},
+			err:    ErrInvalidSchema,
+		},
+		{
+			desc:   "enum with duplicate values",
+			schema: []model.Attribute{{Name: "os", Type: model.AttributeEnum, Values: []string{"ios", "ios"}}},
+			err:    ErrInvalidSchema,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			err := validateSchema(tC.schema)
+			assert.True(t, errors.Is(err, tC.err), fmt.Sprintf("wanted %s got %s", tC.err, err))
+		})
+	}
+}
+
+func Test_validateAttributes(t *testing.T) {
+	schema := []model.Attribute{
+		{Name: "screen_size", Type: model.AttributeNumber, Unit: "in", Required: true},
+		{Name: "os", Type: model.AttributeEnum, Values: []string{"android", "ios"}},
+		{Name: "model", Type: model.AttributeString},
+		{Name: "nfc", Type: model.AttributeBoolean},
+	}
+
+	testCases := []struct {
+		desc  string
+		attrs map[string]interface{}
+		err   string
+	}{
+		{
+			desc:  "valid",
+			attrs: map[string]interface{}{"screen_size": 6.1, "os": "ios", "model": "A2403", "nfc": true},
+		},
+		{
+			desc:  "required only",
+			attrs: map[string]interface{}{"screen_size": 6.1},
+		},
+		{
+			desc:  "missing required",
+			attrs: map[string]interface{}{"os": "ios"},
+			err:   `invalid attributes: attribute "screen_size" is required`,
+		},
+		{
+			desc:  "unknown attribute",
+			attrs: map[string]interface{}{"screen_size": 6.1, "cpu": "A14"},
+			err:   `invalid attributes: unknown attribute "cpu"`,
+		},
+		{
+			desc:  "not a number",
+			attrs: map[string]interface{}{"screen_size": "6.1"},
+			err:   `invalid attributes: attribute "screen_size" must be a number`,
+		},
+		{
+			desc:  "not a string",
+			attrs: map[string]interface{}{"screen_size": 6.1, "model": 2403.0},
+			err:   `invalid attributes: attribute "model" must be a string`,
+		},
+		{
+			desc:  "not a boolean",
+			attrs: map[string]interface{}{"screen_size": 6.1, "nfc": "yes"},
+			err:   `invalid attributes: attribute "nfc" must be a boolean`,
+		},
+		{
+			desc:  "not allowed enum value",
+			attrs: map[string]interface{}{"screen_size": 6.1, "os": "symbian"},
+			err:   `invalid attributes: attribute "os" must be one of [android ios]`,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			err := validateAttributes(schema, tC.attrs)
+			if tC.err == "" {
+				assert.NoError(t, err)
+				return
+			}
+
+			assert.True(t, errors.Is(err, ErrInvalidAttributes), fmt.Sprintf("wanted %s got %s", ErrInvalidAttributes, err))
+			assert.EqualError(t, err, tC.err)
+		})
+	}
+}
@@ -0,0 +1,166 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/shopspring/decimal"
+	"github.com/vliubezny/gstore/internal/model"
+	"github.com/vliubezny/gstore/internal/storage"
+)
+
+const (
+	// maxCartItemQuantity is a maximal quantity of a position in cart.
+	maxCartItemQuantity = 99
+
+	// maxCartItems is a maximal number of distinct positions in cart.
+	maxCartItems = 50
+)
+
+type cartService struct {
+	s storage.CartStorage
+}
+
+// NewCartService creates cart service instance.
+func NewCartService(s storage.CartStorage) CartService {
+	return &cartService{
+		s: s,
+	}
+}
+
+func (s *cartService) GetCart(ctx context.Context, owner model.CartOwner, currency string) (model.Cart, error) {
+	if currency == "" {
+		currency = model.BaseCurrency
+	}
+
+	var items []model.CartItem
+	if owner != (model.CartOwner{}) {
+		var err error
+		if items, err = s.s.GetCartItems(ctx, owner); err != nil {
+			return model.Cart{}, fmt.Errorf("failed to get cart items: %w", err)
+		}
+	}
+
+	rates, err := getRates(ctx, s.s)
+	if err != nil {
+		return model.Cart{}, err
+	}
+
+	to, ok := rates[currency]
+	if !ok {
+		return model.Cart{}, ErrUnknownCurrency
+	}
+
+	total := decimal.Zero
+	for i, item := range items {
+		pos := item.Position
+		if pos.Currency == "" {
+			continue
+		}
+
+		items[i].PriceChanged = !pos.Price.Equal(item.Price) || pos.Currency != item.Currency
+		items[i].Available = isAvailable(pos, item.Quantity)
+		if !items[i].Available {
+			continue
+		}
+
+		from, ok := rates[pos.Currency]
+		if !ok {
+			return model.Cart{}, fmt.Errorf("no exchange rate of %s", pos.Currency)
+		}
+
+		total = total.Add(convertPrice(pos.Price.Mul(decimal.NewFromInt(item.Quantity)), from, to, currency))
+	}
+
+	return model.Cart{
+		Items:    items,
+		Currency: currency,
+		Total:    total,
+	}, nil
+}
+
+func (s *cartService) SetCartItem(ctx context.Context, owner model.CartOwner, variantID, storeID, quantity int64) error {
+	if quantity < 1 || quantity > maxCartItemQuantity {
+		return fmt.Errorf("%w: quantity must be between 1 and %d", ErrInvalidCartItem, maxCartItemQuantity)
+	}
+
+	pos, err := s.s.GetPosition(ctx, variantID, storeID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return ErrUnknownPosition
+		}
+		return fmt.Errorf("failed to get position: %w", err)
+	}
+
+	if pos.Availability == model.AvailabilityDiscontinued {
+		return fmt.Errorf("%w: position is discontinued", ErrInvalidCartItem)
+	}
+
+	if !isAvailable(pos, quantity) {
+		return ErrInsufficientStock
+	}
+
+	items, err := s.s.GetCartItems(ctx, owner)
+	if err != nil {
+		return fmt.Errorf("failed to get cart items: %w", err)
+	}
+
+	if len(items) >= maxCartItems && !hasCartItem(items, variantID, storeID) {
+		return fmt.Errorf("%w: cart must contain at maximum %d items", ErrInvalidCartItem, maxCartItems)
+	}
+
+	if err := s.s.UpsertCartItem(ctx, owner, variantID, storeID, quantity); err != nil {
+		if errors.Is(err, storage.ErrUnknownPosition) {
+			return ErrUnknownPosition
+		}
+		return fmt.Errorf("failed to set cart item: %w", err)
+	}
+
+	return nil
+}
+
+func (s *cartService) RemoveCartItem(ctx context.Context, owner model.CartOwner, variantID, storeID int64) error {
+	if err := s.s.DeleteCartItem(ctx, owner, variantID, storeID); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return ErrNotFound
+		}
+		return fmt.Errorf("failed to remove cart item: %w", err)
+	}
+	return nil
+}
+
+func (s *cartService) ClearCart(ctx context.Context, owner model.CartOwner) error {
+	if err := s.s.DeleteCart(ctx, owner); err != nil {
+		return fmt.Errorf("failed to clear cart: %w", err)
+	}
+	return nil
+}
+
+func (s *cartService) MergeCart(ctx context.Context, token string, userID int64) error {
+	if err := s.s.MergeCarts(ctx, token, userID, maxCartItemQuantity); err != nil {
+		return fmt.Errorf("failed to merge cart: %w", err)
+	}
+	return nil
+}
+
+// isAvailable checks that quantity of the position can be ordered,
+// backordered positions are available regardless of stock.
+func isAvailable(pos model.Position, quantity int64) bool {
+	switch pos.Availability {
+	case model.AvailabilityInStock:
+		return pos.Quantity >= quantity
+	case model.AvailabilityBackorder:
+		return true
+	}
+	return false
+}
+
+func hasCartItem(items []model.CartItem, variantID, storeID int64) bool {
+	for _, item := range items {
+		if item.VariantID == variantID && item.StoreID == storeID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,416 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vliubezny/gstore/internal/model"
+	"github.com/vliubezny/gstore/internal/storage"
+)
+
+var testOwner = model.CartOwner{UserID: 1}
+
+func TestCartService_GetCart(t *testing.T) {
+	inStock := model.Position{
+		ProductID: 1, VariantID: 1, StoreID: 1, Price: decimal.NewFromInt(10), Currency: "USD",
+		Quantity: 5, Availability: model.AvailabilityInStock,
+	}
+	changed := model.Position{
+		ProductID: 2, VariantID: 2, StoreID: 1, Price: decimal.NewFromInt(20), Currency: "EUR",
+		Availability: model.AvailabilityBackorder,
+	}
+	discontinued := model.Position{
+		ProductID: 3, VariantID: 3, StoreID: 1, Price: decimal.NewFromInt(30), Currency: "USD",
+		Quantity: 5, Availability: model.AvailabilityDiscontinued,
+	}
+
+	items := []model.CartItem{
+		{ProductID: 1, VariantID: 1, StoreID: 1, Quantity: 2, Price: decimal.NewFromInt(10), Currency: "USD", Position: inStock},
+		{ProductID: 2, VariantID: 2, StoreID: 1, Quantity: 1, Price: decimal.NewFromInt(15), Currency: "EUR", Position: changed},
+		{ProductID: 3, VariantID: 3, StoreID: 1, Quantity: 1, Price: decimal.NewFromInt(30), Currency: "USD", Position: discontinued},
+		{ProductID: 4, VariantID: 4, StoreID: 1, Quantity: 1, Price: decimal.NewFromInt(40), Currency: "USD"},
+		{ProductID: 1, VariantID: 1, StoreID: 2, Quantity: 6, Price: decimal.NewFromInt(10), Currency: "USD", Position: inStock},
+	}
+
+	validated := []model.CartItem{
+		{ProductID: 1, VariantID: 1, StoreID: 1, Quantity: 2, Price: decimal.NewFromInt(10), Currency: "USD", Position: inStock, Available: true},
+		{ProductID: 2, VariantID: 2, StoreID: 1, Quantity: 1, Price: decimal.NewFromInt(15), Currency: "EUR", Position: changed, PriceChanged: true, Available: true},
+		{ProductID: 3, VariantID: 3, StoreID: 1, Quantity: 1, Price: decimal.NewFromInt(30), Currency: "USD", Position: discontinued},
+		{ProductID: 4, VariantID: 4, StoreID: 1, Quantity: 1, Price: decimal.NewFromInt(40), Currency: "USD"},
+		{ProductID: 1, VariantID: 1, StoreID: 2, Quantity: 6, Price: decimal.NewFromInt(10), Currency: "USD", Position: inStock},
+	}
+
+	testCases := []struct {
+		desc     string
+		owner    model.CartOwner
+		currency string
+		items    []model.CartItem
+		iErr     error
+		rErr     error
+		cart     model.Cart
+		err      error
+	}{
+		{
+			desc:     "success",
+			owner:    testOwner,
+			currency: "",
+			items:    items,
+			iErr:     nil,
+			rErr:     nil,
+			cart: model.Cart{
+				Items:    validated,
+				Currency: "USD",
+				Total:    decimal.RequireFromString("43.67"),
+			},
+			err: nil,
+		},
+		{
+			desc:     "success in currency",
+			owner:    testOwner,
+			currency: "EUR",
+			items:    items[:2],
+			iErr:     nil,
+			rErr:     nil,
+			cart: model.Cart{
+				Items:    validated[:2],
+				Currency: "EUR",
+				Total:    decimal.RequireFromString("36.90"),
+			},
+			err: nil,
+		},
+		{
+			desc:     "anonymous visitor without cart",
+			owner:    model.CartOwner{},
+			currency: "",
+			iErr:     errSkip,
+			rErr:     nil,
+			cart: model.Cart{
+				Currency: "USD",
+				Total:    decimal.Zero,
+			},
+			err: nil,
+		},
+		{
+			desc:     "ErrUnknownCurrency",
+			owner:    testOwner,
+			currency: "GBP",
+			items:    items,
+			iErr:     nil,
+			rErr:     nil,
+			err:      ErrUnknownCurrency,
+		},
+		{
+			desc:     "unexpected items error",
+			owner:    testOwner,
+			currency: "",
+			iErr:     errTest,
+			rErr:     errSkip,
+			err:      errTest,
+		},
+		{
+			desc:     "unexpected rates error",
+			owner:    testOwner,
+			currency: "",
+			items:    items,
+			iErr:     nil,
+			rErr:     errTest,
+			err:      errTest,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			st := storage.NewMockCartStorage(ctrl)
+			if tC.iErr != errSkip {
+				// items are validated in place, so every case gets its own copy
+				items := append([]model.CartItem(nil), tC.items...)
+				st.EXPECT().GetCartItems(ctx, tC.owner).Return(items, tC.iErr)
+			}
+			if tC.rErr != errSkip {
+				st.EXPECT().GetExchangeRates(ctx).Return(testRates, tC.rErr)
+			}
+
+			s := NewCartService(st)
+
+			cart, err := s.GetCart(ctx, tC.owner, tC.currency)
+			require.True(t, errors.Is(err, tC.err), fmt.Sprintf("wanted %s got %s", tC.err, err))
+			assert.Equal(t, tC.cart, cart)
+		})
+	}
+}
+
+func TestCartService_SetCartItem(t *testing.T) {
+	inStock := model.Position{
+		ProductID: 1, VariantID: 1, StoreID: 1, Price: decimal.NewFromInt(10), Currency: "USD",
+		Quantity: 5, Availability: model.AvailabilityInStock,
+	}
+	backorder := inStock
+	backorder.Availability = model.AvailabilityBackorder
+	discontinued := inStock
+	discontinued.Availability = model.AvailabilityDiscontinued
+
+	full := make([]model.CartItem, maxCartItems)
+	for i := range full {
+		full[i] = model.CartItem{VariantID: int64(i + 1), StoreID: 2, Quantity: 1}
+	}
+
+	testCases := []struct {
+		desc     string
+		quantity int64
+		pos      model.Position
+		pErr     error
+		items    []model.CartItem
+		iErr     error
+		uErr     error
+		err      error
+	}{
+		{
+			desc:     "success",
+			quantity: 5,
+			pos:      inStock,
+			pErr:     nil,
+			iErr:     nil,
+			uErr:     nil,
+			err:      nil,
+		},
+		{
+			desc:     "success backorder",
+			quantity: maxCartItemQuantity,
+			pos:      backorder,
+			pErr:     nil,
+			iErr:     nil,
+			uErr:     nil,
+			err:      nil,
+		},
+		{
+			desc:     "success full cart update",
+			quantity: 1,
+			pos:      inStock,
+			pErr:     nil,
+			items:    append(full[1:], model.CartItem{VariantID: 1, StoreID: 1, Quantity: 2}),
+			iErr:     nil,
+			uErr:     nil,
+			err:      nil,
+		},
+		{
+			desc:     "invalid quantity",
+			quantity: 0,
+			pErr:     errSkip,
+			iErr:     errSkip,
+			uErr:     errSkip,
+			err:      ErrInvalidCartItem,
+		},
+		{
+			desc:     "quantity above limit",
+			quantity: maxCartItemQuantity + 1,
+			pErr:     errSkip,
+			iErr:     errSkip,
+			uErr:     errSkip,
+			err:      ErrInvalidCartItem,
+		},
+		{
+			desc:     "ErrUnknownPosition",
+			quantity: 1,
+			pErr:     storage.ErrNotFound,
+			iErr:     errSkip,
+			uErr:     errSkip,
+			err:      ErrUnknownPosition,
+		},
+		{
+			desc:     "discontinued",
+			quantity: 1,
+			pos:      discontinued,
+			pErr:     nil,
+			iErr:     errSkip,
+			uErr:     errSkip,
+			err:      ErrInvalidCartItem,
+		},
+		{
+			desc:     "ErrInsufficientStock",
+			quantity: 6,
+			pos:      inStock,
+			pErr:     nil,
+			iErr:     errSkip,
+			uErr:     errSkip,
+			err:      ErrInsufficientStock,
+		},
+		{
+			desc:     "full cart",
+			quantity: 1,
+			pos:      inStock,
+			pErr:     nil,
+			items:    full,
+			iErr:     nil,
+			uErr:     errSkip,
+			err:      ErrInvalidCartItem,
+		},
+		{
+			desc:     "ErrUnknownPosition on upsert",
+			quantity: 1,
+			pos:      inStock,
+			pErr:     nil,
+			iErr:     nil,
+			uErr:     storage.ErrUnknownPosition,
+			err:      ErrUnknownPosition,
+		},
+		{
+			desc:     "unexpected position error",
+			quantity: 1,
+			pErr:     errTest,
+			iErr:     errSkip,
+			uErr:     errSkip,
+			err:      errTest,
+		},
+		{
+			desc:     "unexpected items error",
+			quantity: 1,
+			pos:      inStock,
+			pErr:     nil,
+			iErr:     errTest,
+			uErr:     errSkip,
+			err:      errTest,
+		},
+		{
+			desc:     "unexpected upsert error",
+			quantity: 1,
+			pos:      inStock,
+			pErr:     nil,
+			iErr:     nil,
+			uErr:     errTest,
+			err:      errTest,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			st := storage.NewMockCartStorage(ctrl)
+			if tC.pErr != errSkip {
+				st.EXPECT().GetPosition(ctx, int64(1), int64(1)).Return(tC.pos, tC.pErr)
+			}
+			if tC.iErr != errSkip {
+				st.EXPECT().GetCartItems(ctx, testOwner).Return(tC.items, tC.iErr)
+			}
+			if tC.uErr != errSkip {
+				st.EXPECT().UpsertCartItem(ctx, testOwner, int64(1), int64(1), tC.quantity).Return(tC.uErr)
+			}
+
+			s := NewCartService(st)
+
+			err := s.SetCartItem(ctx, testOwner, 1, 1, tC.quantity)
+			assert.True(t, errors.Is(err, tC.err), fmt.Sprintf("wanted %s got %s", tC.err, err))
+		})
+	}
+}
+
+func TestCartService_RemoveCartItem(t *testing.T) {
+	testCases := []struct {
+		desc string
+		rErr error
+		err  error
+	}{
+		{
+			desc: "success",
+			rErr: nil,
+			err:  nil,
+		},
+		{
+			desc: "ErrNotFound",
+			rErr: storage.ErrNotFound,
+			err:  ErrNotFound,
+		},
+		{
+			desc: "unexpected error",
+			rErr: errTest,
+			err:  errTest,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			st := storage.NewMockCartStorage(ctrl)
+			st.EXPECT().DeleteCartItem(ctx, testOwner, int64(1), int64(2)).Return(tC.rErr)
+
+			s := NewCartService(st)
+
+			err := s.RemoveCartItem(ctx, testOwner, 1, 2)
+			assert.True(t, errors.Is(err, tC.err), fmt.Sprintf("wanted %s got %s", tC.err, err))
+		})
+	}
+}
+
+func TestCartService_ClearCart(t *testing.T) {
+	testCases := []struct {
+		desc string
+		rErr error
+		err  error
+	}{
+		{
+			desc: "success",
+			rErr: nil,
+			err:  nil,
+		},
+		{
+			desc: "unexpected error",
+			rErr: errTest,
+			err:  errTest,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			st := storage.NewMockCartStorage(ctrl)
+			st.EXPECT().DeleteCart(ctx, testOwner).Return(tC.rErr)
+
+			s := NewCartService(st)
+
+			err := s.ClearCart(ctx, testOwner)
+			assert.True(t, errors.Is(err, tC.err), fmt.Sprintf("wanted %s got %s", tC.err, err))
+		})
+	}
+}
+
+func TestCartService_MergeCart(t *testing.T) {
+	testCases := []struct {
+		desc string
+		rErr error
+		err  error
+	}{
+		{
+			desc: "success",
+			rErr: nil,
+			err:  nil,
+		},
+		{
+			desc: "unexpected error",
+			rErr: errTest,
+			err:  errTest,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			st := storage.NewMockCartStorage(ctrl)
+			st.EXPECT().MergeCarts(ctx, "token", int64(1), int64(maxCartItemQuantity)).Return(tC.rErr)
+
+			s := NewCartService(st)
+
+			err := s.MergeCart(ctx, "token", 1)
+			assert.True(t, errors.Is(err, tC.err), fmt.Sprintf("wanted %s got %s", tC.err, err))
+		})
+	}
+}
@@ -0,0 +1,199 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/shopspring/decimal"
+	"github.com/vliubezny/gstore/internal/model"
+	"github.com/vliubezny/gstore/internal/storage"
+)
+
+const (
+	// defaultMinorUnits is a number of decimal places of most currencies.
+	defaultMinorUnits = 2
+
+	// conversionPrecision is a number of decimal places kept before rounding to minor units.
+	conversionPrecision = 16
+)
+
+var (
+	currencyCodeRe = regexp.MustCompile(`^[A-Z]{3}$`)
+
+	// minorUnits contains ISO 4217 minor units of currencies which don't use 2 decimal places.
+	minorUnits = map[string]int32{
+		"BHD": 3, "BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "IQD": 3, "ISK": 0, "JOD": 3,
+		"JPY": 0, "KMF": 0, "KRW": 0, "KWD": 3, "LYD": 3, "OMR": 3, "PYG": 0, "RWF": 0,
+		"TND": 3, "UGX": 0, "UYI": 0, "VND": 0, "VUV": 0, "XAF": 0, "XOF": 0, "XPF": 0,
+	}
+
+	baseRate = decimal.NewFromInt(1)
+)
+
+func (s *service) GetExchangeRates(ctx context.Context) ([]model.ExchangeRate, error) {
+	rates, err := s.s.GetExchangeRates(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get exchange rates: %w", err)
+	}
+	return rates, nil
+}
+
+func (s *service) SetExchangeRate(ctx context.Context, rate model.ExchangeRate) error {
+	if err := validateExchangeRate(rate); err != nil {
+		return err
+	}
+
+	if err := s.s.UpsertExchangeRates(ctx, []model.ExchangeRate{rate}); err != nil {
+		return fmt.Errorf("failed to set exchange rate: %w", err)
+	}
+	return nil
+}
+
+func (s *service) DeleteExchangeRate(ctx context.Context, currency string) error {
+	if currency == model.BaseCurrency {
+		return fmt.Errorf("%w: base currency %s cannot be deleted", ErrInvalidExchangeRate, model.BaseCurrency)
+	}
+
+	if err := s.s.DeleteExchangeRate(ctx, currency); err != nil {
+		switch {
+		case errors.Is(err, storage.ErrNotFound):
+			return ErrNotFound
+		case errors.Is(err, storage.ErrCurrencyInUse):
+			return ErrCurrencyInUse
+		}
+		return fmt.Errorf("failed to delete exchange rate: %w", err)
+	}
+	return nil
+}
+
+func (s *service) ImportExchangeRates(ctx context.Context, r io.Reader) (int, error) {
+	var rates []model.ExchangeRate
+	seen := make(map[string]bool)
+
+	sc := bufio.NewScanner(r)
+	for line := 1; sc.Scan(); line++ {
+		text := strings.TrimSpace(sc.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields := strings.Split(text, ",")
+		if len(fields) != 2 {
+			return 0, fmt.Errorf("%w: line %d: expected <currency>,<rate>", ErrInvalidExchangeRate, line)
+		}
+
+		rate := model.ExchangeRate{Currency: strings.TrimSpace(fields[0])}
+
+		var err error
+		if rate.Rate, err = decimal.NewFromString(strings.TrimSpace(fields[1])); err != nil {
+			return 0, fmt.Errorf("%w: line %d: rate must be a number", ErrInvalidExchangeRate, line)
+		}
+
+		if err := validateExchangeRate(rate); err != nil {
+			return 0, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		if seen[rate.Currency] {
+			return 0, fmt.Errorf("%w: line %d: duplicate currency %s", ErrInvalidExchangeRate, line, rate.Currency)
+		}
+		seen[rate.Currency] = true
+
+		rates = append(rates, rate)
+	}
+
+	if err := sc.Err(); err != nil {
+		return 0, fmt.Errorf("failed to read exchange rates: %w", err)
+	}
+
+	if len(rates) == 0 {
+		return 0, nil
+	}
+
+	if err := s.s.UpsertExchangeRates(ctx, rates); err != nil {
+		return 0, fmt.Errorf("failed to import exchange rates: %w", err)
+	}
+
+	return len(rates), nil
+}
+
+func validateExchangeRate(rate model.ExchangeRate) error {
+	if !isCurrencyCode(rate.Currency) {
+		return fmt.Errorf("%w: currency must be an ISO 4217 code", ErrInvalidExchangeRate)
+	}
+
+	if !rate.Rate.IsPositive() {
+		return fmt.Errorf("%w: rate must be greater than 0", ErrInvalidExchangeRate)
+	}
+
+	if rate.Currency == model.BaseCurrency && !rate.Rate.Equal(baseRate) {
+		return fmt.Errorf("%w: rate of base currency %s must be 1", ErrInvalidExchangeRate, model.BaseCurrency)
+	}
+
+	return nil
+}
+
+// rateStorage provides exchange rates.
+type rateStorage interface {
+	GetExchangeRates(ctx context.Context) ([]model.ExchangeRate, error)
+}
+
+// getRates returns exchange rates keyed by currency.
+func getRates(ctx context.Context, s rateStorage) (map[string]decimal.Decimal, error) {
+	rates, err := s.GetExchangeRates(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get exchange rates: %w", err)
+	}
+
+	res := make(map[string]decimal.Decimal, len(rates))
+	for _, r := range rates {
+		res[r.Currency] = r.Rate
+	}
+	return res, nil
+}
+
+// convertPositions converts position prices to the currency.
+func (s *service) convertPositions(ctx context.Context, positions []model.Position, currency string) error {
+	rates, err := getRates(ctx, s.s)
+	if err != nil {
+		return err
+	}
+
+	to, ok := rates[currency]
+	if !ok {
+		return ErrUnknownCurrency
+	}
+
+	for i, p := range positions {
+		from, ok := rates[p.Currency]
+		if !ok {
+			return fmt.Errorf("no exchange rate of %s", p.Currency)
+		}
+
+		positions[i].Price = convertPrice(p.Price, from, to, currency)
+		positions[i].Currency = currency
+	}
+
+	return nil
+}
+
+// convertPrice converts amount using rates of source and target currencies relative to base currency,
+// result is rounded half away from zero to minor units of target currency.
+func convertPrice(amount, from, to decimal.Decimal, currency string) decimal.Decimal {
+	return amount.Mul(to).DivRound(from, conversionPrecision).Round(currencyMinorUnits(currency))
+}
+
+func currencyMinorUnits(currency string) int32 {
+	if units, ok := minorUnits[currency]; ok {
+		return units
+	}
+	return defaultMinorUnits
+}
+
+func isCurrencyCode(s string) bool {
+	return currencyCodeRe.MatchString(s)
+}
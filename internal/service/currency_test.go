@@ -0,0 +1,299 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/vliubezny/gstore/internal/model"
+	"github.com/vliubezny/gstore/internal/storage"
+)
+
+var testRates = []model.ExchangeRate{
+	{Currency: "EUR", Rate: decimal.RequireFromString("0.845")},
+	{Currency: "KWD", Rate: decimal.RequireFromString("0.3053")},
+	{Currency: "USD", Rate: decimal.NewFromInt(1)},
+}
+
+func TestService_SetExchangeRate(t *testing.T) {
+	testCases := []struct {
+		desc string
+		rate model.ExchangeRate
+		rErr error
+		err  error
+	}{
+		{
+			desc: "success",
+			rate: model.ExchangeRate{Currency: "EUR", Rate: decimal.RequireFromString("0.845")},
+			rErr: nil,
+			err:  nil,
+		},
+		{
+			desc: "invalid currency",
+			rate: model.ExchangeRate{Currency: "eur", Rate: decimal.RequireFromString("0.845")},
+			rErr: errSkip,
+			err:  ErrInvalidExchangeRate,
+		},
+		{
+			desc: "invalid rate",
+			rate: model.ExchangeRate{Currency: "EUR", Rate: decimal.Zero},
+			rErr: errSkip,
+			err:  ErrInvalidExchangeRate,
+		},
+		{
+			desc: "base currency rate",
+			rate: model.ExchangeRate{Currency: "USD", Rate: decimal.NewFromInt(2)},
+			rErr: errSkip,
+			err:  ErrInvalidExchangeRate,
+		},
+		{
+			desc: "unexpected error",
+			rate: model.ExchangeRate{Currency: "EUR", Rate: decimal.RequireFromString("0.845")},
+			rErr: errTest,
+			err:  errTest,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			st := storage.NewMockStorage(ctrl)
+			if tC.rErr != errSkip {
+				st.EXPECT().UpsertExchangeRates(ctx, []model.ExchangeRate{tC.rate}).Return(tC.rErr)
+			}
+
+			s := New(st, nil)
+
+			err := s.SetExchangeRate(ctx, tC.rate)
+			assert.True(t, errors.Is(err, tC.err), fmt.Sprintf("wanted %s got %s", tC.err, err))
+		})
+	}
+}
+
+func TestService_DeleteExchangeRate(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		currency string
+		rErr     error
+		err      error
+	}{
+		{
+			desc:     "success",
+			currency: "EUR",
+			rErr:     nil,
+			err:      nil,
+		},
+		{
+			desc:     "base currency",
+			currency: "USD",
+			rErr:     errSkip,
+			err:      ErrInvalidExchangeRate,
+		},
+		{
+			desc:     "ErrNotFound",
+			currency: "EUR",
+			rErr:     storage.ErrNotFound,
+			err:      ErrNotFound,
+		},
+		{
+			desc:     "ErrCurrencyInUse",
+			currency: "EUR",
+			rErr:     storage.ErrCurrencyInUse,
+			err:      ErrCurrencyInUse,
+		},
+		{
+			desc:     "unexpected error",
+			currency: "EUR",
+			rErr:     errTest,
+			err:      errTest,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			st := storage.NewMockStorage(ctrl)
+			if tC.rErr != errSkip {
+				st.EXPECT().DeleteExchangeRate(ctx, tC.currency).Return(tC.rErr)
+			}
+
+			s := New(st, nil)
+
+			err := s.DeleteExchangeRate(ctx, tC.currency)
+			assert.True(t, errors.Is(err, tC.err), fmt.Sprintf("wanted %s got %s", tC.err, err))
+		})
+	}
+}
+
+func TestService_ImportExchangeRates(t *testing.T) {
+	testCases := []struct {
+		desc  string
+		input string
+		rates []model.ExchangeRate
+		rErr  error
+		n     int
+		err   error
+		msg   string
+	}{
+		{
+			desc:  "success",
+			input: "# rates relative to USD\nEUR,0.845\n\n KWD , 0.3053 \nUSD,1\n",
+			rates: testRates,
+			rErr:  nil,
+			n:     3,
+			err:   nil,
+		},
+		{
+			desc:  "empty",
+			input: "# nothing\n",
+			rErr:  errSkip,
+			n:     0,
+			err:   nil,
+		},
+		{
+			desc:  "malformed line",
+			input: "EUR,0.845\nKWD;0.3053\n",
+			rErr:  errSkip,
+			err:   ErrInvalidExchangeRate,
+			msg:   "invalid exchange rate: line 2: expected <currency>,<rate>",
+		},
+		{
+			desc:  "malformed rate",
+			input: "EUR,abc\n",
+			rErr:  errSkip,
+			err:   ErrInvalidExchangeRate,
+			msg:   "invalid exchange rate: line 1: rate must be a number",
+		},
+		{
+			desc:  "invalid rate",
+			input: "EUR,0.845\nKWD,-1\n",
+			rErr:  errSkip,
+			err:   ErrInvalidExchangeRate,
+			msg:   "line 2: invalid exchange rate: rate must be greater than 0",
+		},
+		{
+			desc:  "duplicate currency",
+			input: "EUR,0.845\nEUR,0.85\n",
+			rErr:  errSkip,
+			err:   ErrInvalidExchangeRate,
+			msg:   "invalid exchange rate: line 2: duplicate currency EUR",
+		},
+		{
+			desc:  "unexpected error",
+			input: "EUR,0.845\nKWD,0.3053\nUSD,1",
+			rates: testRates,
+			rErr:  errTest,
+			err:   errTest,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			st := storage.NewMockStorage(ctrl)
+			if tC.rErr != errSkip {
+				st.EXPECT().UpsertExchangeRates(ctx, tC.rates).Return(tC.rErr)
+			}
+
+			s := New(st, nil)
+
+			n, err := s.ImportExchangeRates(ctx, strings.NewReader(tC.input))
+			assert.True(t, errors.Is(err, tC.err), fmt.Sprintf("wanted %s got %s", tC.err, err))
+			assert.Equal(t, tC.n, n)
+			if tC.msg != "" {
+				assert.EqualError(t, err, tC.msg)
+			}
+		})
+	}
+}
+
+func TestService_GetProductPositions_Currency(t *testing.T) {
+	positions := []model.Position{
+		{ProductID: 1, StoreID: 1, Price: decimal.NewFromInt(100), Currency: "USD"},
+		{ProductID: 1, StoreID: 2, Price: decimal.RequireFromString("84.99"), Currency: "EUR"},
+	}
+
+	testCases := []struct {
+		desc      string
+		currency  string
+		rates     []model.ExchangeRate
+		rErr      error
+		positions []model.Position
+		err       error
+	}{
+		{
+			desc:     "success",
+			currency: "KWD",
+			rates:    testRates,
+			positions: []model.Position{
+				{ProductID: 1, StoreID: 1, Price: decimal.RequireFromString("30.530"), Currency: "KWD"},
+				{ProductID: 1, StoreID: 2, Price: decimal.RequireFromString("30.707"), Currency: "KWD"},
+			},
+			err: nil,
+		},
+		{
+			desc:     "ErrUnknownCurrency",
+			currency: "GBP",
+			rates:    testRates,
+			err:      ErrUnknownCurrency,
+		},
+		{
+			desc:     "unexpected error",
+			currency: "KWD",
+			rErr:     errTest,
+			err:      errTest,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			rPositions := make([]model.Position, len(positions))
+			copy(rPositions, positions)
+
+			st := storage.NewMockStorage(ctrl)
+			st.EXPECT().GetProductPositions(ctx, int64(1), model.PositionFilter{}, testPage).Return(rPositions, "", nil)
+			st.EXPECT().GetExchangeRates(ctx).Return(tC.rates, tC.rErr)
+
+			s := New(st, nil)
+
+			res, _, err := s.GetProductPositions(ctx, 1, model.PositionFilter{}, tC.currency, testPage)
+			assert.True(t, errors.Is(err, tC.err), fmt.Sprintf("wanted %s got %s", tC.err, err))
+			if tC.err == nil {
+				assert.Equal(t, tC.positions, res)
+			}
+		})
+	}
+}
+
+func Test_convertPrice(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		amount   string
+		from     string
+		to       string
+		currency string
+		result   string
+	}{
+		{desc: "same currency", amount: "10.5", from: "1", to: "1", currency: "USD", result: "10.50"},
+		{desc: "round half away from zero", amount: "1.005", from: "1", to: "1", currency: "USD", result: "1.01"},
+		{desc: "zero minor units", amount: "10.5", from: "1", to: "104.55", currency: "JPY", result: "1098"},
+		{desc: "three minor units", amount: "84.99", from: "0.845", to: "0.3053", currency: "KWD", result: "30.707"},
+		{desc: "cross rate", amount: "100", from: "104.55", to: "0.845", currency: "EUR", result: "0.81"},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			res := convertPrice(decimal.RequireFromString(tC.amount), decimal.RequireFromString(tC.from),
+				decimal.RequireFromString(tC.to), tC.currency)
+			assert.Equal(t, tC.result, res.StringFixed(currencyMinorUnits(tC.currency)))
+		})
+	}
+}
@@ -0,0 +1,254 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+
+	// register decoders of supported formats
+	_ "image/gif"
+
+	"github.com/google/uuid"
+	"github.com/vliubezny/gstore/internal/media"
+	"github.com/vliubezny/gstore/internal/model"
+	"github.com/vliubezny/gstore/internal/storage"
+)
+
+const (
+	maxImagePixels   = 40000000
+	thumbnailQuality = 85
+)
+
+// imageExtensions maps supported image content types to file extensions.
+var imageExtensions = map[string]string{
+	"image/gif":  ".gif",
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+}
+
+func (s *service) AddProductImage(ctx context.Context, productID int64, data []byte) (model.Image, error) {
+	contentType := http.DetectContentType(data)
+	ext, ok := imageExtensions[contentType]
+	if !ok {
+		return model.Image{}, fmt.Errorf("%w: image must be one of [image/gif image/jpeg image/png]", ErrUnsupportedImage)
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return model.Image{}, fmt.Errorf("%w: malformed image", ErrUnsupportedImage)
+	}
+
+	if cfg.Width*cfg.Height > maxImagePixels {
+		return model.Image{}, fmt.Errorf("%w: image must be at maximum %d pixels", ErrUnsupportedImage, maxImagePixels)
+	}
+
+	if _, err := s.s.GetProduct(ctx, productID); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return model.Image{}, ErrUnknownProduct
+		}
+		return model.Image{}, fmt.Errorf("failed to get product: %w", err)
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return model.Image{}, fmt.Errorf("%w: malformed image", ErrUnsupportedImage)
+	}
+
+	img := model.Image{
+		ProductID:   productID,
+		Key:         fmt.Sprintf("products/%d/%s%s", productID, uuid.New(), ext),
+		ContentType: contentType,
+		Width:       cfg.Width,
+		Height:      cfg.Height,
+	}
+
+	if err := s.saveImageFiles(ctx, img, data, src); err != nil {
+		return model.Image{}, err
+	}
+
+	created, err := s.s.CreateProductImage(ctx, img)
+	if err != nil {
+		s.deleteImageFiles(ctx, img)
+
+		if errors.Is(err, storage.ErrUnknownProduct) {
+			return model.Image{}, ErrUnknownProduct
+		}
+		return model.Image{}, fmt.Errorf("failed to create product image: %w", err)
+	}
+
+	// the image goes last, so it is primary only if product has no other images
+	created.Primary = created.Position == 0
+
+	return s.resolveImageURLs(created), nil
+}
+
+func (s *service) SetProductImageOrder(ctx context.Context, productID int64, imageIDs []int64) error {
+	images, err := s.s.GetProductImages(ctx, []int64{productID})
+	if err != nil {
+		return fmt.Errorf("failed to get product images: %w", err)
+	}
+
+	if len(images) != len(imageIDs) {
+		return fmt.Errorf("%w: all %d product images must be listed", ErrInvalidImageOrder, len(images))
+	}
+
+	listed := make(map[int64]bool, len(imageIDs))
+	for _, id := range imageIDs {
+		listed[id] = true
+	}
+
+	for _, img := range images {
+		if !listed[img.ID] {
+			return fmt.Errorf("%w: all %d product images must be listed", ErrInvalidImageOrder, len(images))
+		}
+	}
+
+	return s.updateImagePositions(ctx, productID, imageIDs)
+}
+
+func (s *service) SetPrimaryProductImage(ctx context.Context, productID, imageID int64) error {
+	images, err := s.s.GetProductImages(ctx, []int64{productID})
+	if err != nil {
+		return fmt.Errorf("failed to get product images: %w", err)
+	}
+
+	imageIDs := []int64{imageID}
+	for _, img := range images {
+		if img.ID != imageID {
+			imageIDs = append(imageIDs, img.ID)
+		}
+	}
+
+	if len(imageIDs) != len(images) {
+		return ErrNotFound
+	}
+
+	return s.updateImagePositions(ctx, productID, imageIDs)
+}
+
+func (s *service) updateImagePositions(ctx context.Context, productID int64, imageIDs []int64) error {
+	if err := s.s.UpdateProductImagePositions(ctx, productID, imageIDs); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return fmt.Errorf("%w: product images were changed concurrently", ErrInvalidImageOrder)
+		}
+		return fmt.Errorf("failed to update product image positions: %w", err)
+	}
+	return nil
+}
+
+func (s *service) DeleteProductImage(ctx context.Context, productID, imageID int64) error {
+	img, err := s.s.DeleteProductImage(ctx, productID, imageID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return ErrNotFound
+		}
+		return fmt.Errorf("failed to delete product image: %w", err)
+	}
+
+	return s.deleteImageFiles(ctx, img)
+}
+
+// loadImages returns images with resolved URLs grouped by product ID.
+func (s *service) loadImages(ctx context.Context, productIDs []int64) (map[int64][]model.Image, error) {
+	res := make(map[int64][]model.Image, len(productIDs))
+	if len(productIDs) == 0 {
+		return res, nil
+	}
+
+	images, err := s.s.GetProductImages(ctx, productIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get product images: %w", err)
+	}
+
+	for _, img := range images {
+		img.Primary = len(res[img.ProductID]) == 0
+		res[img.ProductID] = append(res[img.ProductID], s.resolveImageURLs(img))
+	}
+
+	return res, nil
+}
+
+// resolveImageURLs populates public URLs of the image and its thumbnails.
+func (s *service) resolveImageURLs(img model.Image) model.Image {
+	img.URL = s.m.URL(img.Key)
+	img.Thumbnails = make(map[string]string, len(media.ThumbnailSizes))
+	for _, size := range media.ThumbnailSizes {
+		img.Thumbnails[size.Name] = s.m.URL(thumbnailKey(img, size.Name))
+	}
+	return img
+}
+
+// saveImageFiles saves original image and its thumbnails.
+func (s *service) saveImageFiles(ctx context.Context, img model.Image, data []byte, src image.Image) error {
+	if err := s.m.Save(ctx, img.Key, img.ContentType, bytes.NewReader(data)); err != nil {
+		return fmt.Errorf("failed to save image: %w", err)
+	}
+
+	for _, size := range media.ThumbnailSizes {
+		var buf bytes.Buffer
+		if err := encodeThumbnail(&buf, img, media.Thumbnail(src, size.MaxSide)); err != nil {
+			s.deleteImageFiles(ctx, img)
+			return fmt.Errorf("failed to encode %s thumbnail: %w", size.Name, err)
+		}
+
+		if err := s.m.Save(ctx, thumbnailKey(img, size.Name), thumbnailContentType(img), &buf); err != nil {
+			s.deleteImageFiles(ctx, img)
+			return fmt.Errorf("failed to save %s thumbnail: %w", size.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// deleteImageFiles deletes original image and its thumbnails.
+func (s *service) deleteImageFiles(ctx context.Context, img model.Image) error {
+	keys := []string{img.Key}
+	for _, size := range media.ThumbnailSizes {
+		keys = append(keys, thumbnailKey(img, size.Name))
+	}
+
+	var errs []string
+	for _, key := range keys {
+		if err := s.m.Delete(ctx, key); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to delete image files: %s", strings.Join(errs, "; "))
+	}
+
+	return nil
+}
+
+// thumbnailKey returns media storage key of the image thumbnail,
+// JPEG images have JPEG thumbnails while other formats have PNG ones.
+func thumbnailKey(img model.Image, size string) string {
+	ext := ".png"
+	if img.ContentType == "image/jpeg" {
+		ext = ".jpg"
+	}
+	return strings.TrimSuffix(img.Key, path.Ext(img.Key)) + "_" + size + ext
+}
+
+func thumbnailContentType(img model.Image) string {
+	if img.ContentType == "image/jpeg" {
+		return "image/jpeg"
+	}
+	return "image/png"
+}
+
+func encodeThumbnail(w io.Writer, img model.Image, thumbnail image.Image) error {
+	if img.ContentType == "image/jpeg" {
+		return jpeg.Encode(w, thumbnail, &jpeg.Options{Quality: thumbnailQuality})
+	}
+	return png.Encode(w, thumbnail)
+}
@@ -0,0 +1,336 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vliubezny/gstore/internal/media"
+	"github.com/vliubezny/gstore/internal/model"
+	"github.com/vliubezny/gstore/internal/storage"
+)
+
+func newTestMediaStorage(ctrl *gomock.Controller) *media.MockStorage {
+	m := media.NewMockStorage(ctrl)
+	m.EXPECT().URL(gomock.Any()).DoAndReturn(func(key string) string {
+		return "/media/" + key
+	}).AnyTimes()
+	return m
+}
+
+func newTestPNG(t *testing.T, width, height int) []byte {
+	var buf bytes.Buffer
+	err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, width, height)))
+	require.NoError(t, err)
+	return buf.Bytes()
+}
+
+func TestService_AddProductImage(t *testing.T) {
+	data := newTestPNG(t, 300, 200)
+
+	testCases := []struct {
+		desc     string
+		data     []byte
+		pErr     error
+		saveErr  error
+		position int
+		rErr     error
+		err      error
+	}{
+		{
+			desc:     "success",
+			data:     data,
+			position: 0,
+			err:      nil,
+		},
+		{
+			desc:     "success not primary",
+			data:     data,
+			position: 3,
+			err:      nil,
+		},
+		{
+			desc: "unsupported format",
+			data: []byte("<svg></svg>"),
+			pErr: errSkip,
+			err:  ErrUnsupportedImage,
+		},
+		{
+			desc: "malformed image",
+			data: append([]byte("\x89PNG\r\n\x1a\n"), "garbage"...),
+			pErr: errSkip,
+			err:  ErrUnsupportedImage,
+		},
+		{
+			desc: "ErrUnknownProduct",
+			data: data,
+			pErr: storage.ErrNotFound,
+			err:  ErrUnknownProduct,
+		},
+		{
+			desc:    "save error",
+			data:    data,
+			saveErr: errTest,
+			rErr:    errSkip,
+			err:     errTest,
+		},
+		{
+			desc: "unexpected error",
+			data: data,
+			rErr: errTest,
+			err:  errTest,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			st := storage.NewMockStorage(ctrl)
+			m := newTestMediaStorage(ctrl)
+
+			saved := make(map[string][]byte)
+			if tC.pErr != errSkip {
+				st.EXPECT().GetProduct(ctx, int64(1)).Return(model.Product{ID: 1}, tC.pErr)
+			}
+			if tC.pErr == nil {
+				m.EXPECT().Save(ctx, gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, key, contentType string, r io.Reader) error {
+					saved[key], _ = ioutil.ReadAll(r)
+					return tC.saveErr
+				}).MinTimes(1)
+			}
+			if tC.rErr != errSkip && tC.pErr == nil {
+				st.EXPECT().CreateProductImage(ctx, gomock.Any()).DoAndReturn(func(_ context.Context, img model.Image) (model.Image, error) {
+					img.ID = 1
+					img.Position = tC.position
+					return img, tC.rErr
+				})
+			}
+			if tC.rErr != nil && tC.rErr != errSkip {
+				m.EXPECT().Delete(ctx, gomock.Any()).Return(nil).Times(4)
+			}
+
+			s := New(st, m)
+
+			img, err := s.AddProductImage(ctx, 1, tC.data)
+			assert.True(t, errors.Is(err, tC.err), fmt.Sprintf("wanted %s got %s", tC.err, err))
+			if tC.err != nil {
+				return
+			}
+
+			assert.Equal(t, int64(1), img.ID)
+			assert.Equal(t, int64(1), img.ProductID)
+			assert.True(t, strings.HasPrefix(img.Key, "products/1/"), img.Key)
+			assert.True(t, strings.HasSuffix(img.Key, ".png"), img.Key)
+			assert.Equal(t, "image/png", img.ContentType)
+			assert.Equal(t, 300, img.Width)
+			assert.Equal(t, 200, img.Height)
+			assert.Equal(t, tC.position == 0, img.Primary)
+			assert.Equal(t, "/media/"+img.Key, img.URL)
+			assert.Len(t, img.Thumbnails, len(media.ThumbnailSizes))
+
+			assert.Equal(t, data, saved[img.Key])
+
+			small, err := png.DecodeConfig(bytes.NewReader(saved[thumbnailKey(img, "small")]))
+			require.NoError(t, err)
+			assert.Equal(t, 160, small.Width)
+			assert.Equal(t, 106, small.Height)
+
+			large, err := png.DecodeConfig(bytes.NewReader(saved[thumbnailKey(img, "large")]))
+			require.NoError(t, err)
+			assert.Equal(t, 300, large.Width)
+			assert.Equal(t, 200, large.Height)
+		})
+	}
+}
+
+func TestService_SetProductImageOrder(t *testing.T) {
+	testImages := []model.Image{{ID: 1, ProductID: 1}, {ID: 2, ProductID: 1}, {ID: 3, ProductID: 1}}
+
+	testCases := []struct {
+		desc     string
+		imageIDs []int64
+		iErr     error
+		rErr     error
+		err      error
+	}{
+		{
+			desc:     "success",
+			imageIDs: []int64{3, 1, 2},
+			rErr:     nil,
+			err:      nil,
+		},
+		{
+			desc:     "missing image",
+			imageIDs: []int64{3, 1},
+			rErr:     errSkip,
+			err:      ErrInvalidImageOrder,
+		},
+		{
+			desc:     "unknown image",
+			imageIDs: []int64{3, 1, 4},
+			rErr:     errSkip,
+			err:      ErrInvalidImageOrder,
+		},
+		{
+			desc:     "duplicate image",
+			imageIDs: []int64{3, 1, 1},
+			rErr:     errSkip,
+			err:      ErrInvalidImageOrder,
+		},
+		{
+			desc:     "concurrent change",
+			imageIDs: []int64{3, 1, 2},
+			rErr:     storage.ErrNotFound,
+			err:      ErrInvalidImageOrder,
+		},
+		{
+			desc:     "unexpected images error",
+			imageIDs: []int64{3, 1, 2},
+			iErr:     errTest,
+			rErr:     errSkip,
+			err:      errTest,
+		},
+		{
+			desc:     "unexpected error",
+			imageIDs: []int64{3, 1, 2},
+			rErr:     errTest,
+			err:      errTest,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			st := storage.NewMockStorage(ctrl)
+			st.EXPECT().GetProductImages(ctx, []int64{1}).Return(testImages, tC.iErr)
+			if tC.rErr != errSkip {
+				st.EXPECT().UpdateProductImagePositions(ctx, int64(1), tC.imageIDs).Return(tC.rErr)
+			}
+
+			s := New(st, nil)
+
+			err := s.SetProductImageOrder(ctx, 1, tC.imageIDs)
+			assert.True(t, errors.Is(err, tC.err), fmt.Sprintf("wanted %s got %s", tC.err, err))
+		})
+	}
+}
+
+func TestService_SetPrimaryProductImage(t *testing.T) {
+	testImages := []model.Image{{ID: 1, ProductID: 1}, {ID: 2, ProductID: 1}, {ID: 3, ProductID: 1}}
+
+	testCases := []struct {
+		desc     string
+		imageID  int64
+		imageIDs []int64
+		rErr     error
+		err      error
+	}{
+		{
+			desc:     "success",
+			imageID:  2,
+			imageIDs: []int64{2, 1, 3},
+			rErr:     nil,
+			err:      nil,
+		},
+		{
+			desc:    "ErrNotFound",
+			imageID: 4,
+			rErr:    errSkip,
+			err:     ErrNotFound,
+		},
+		{
+			desc:     "unexpected error",
+			imageID:  2,
+			imageIDs: []int64{2, 1, 3},
+			rErr:     errTest,
+			err:      errTest,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			st := storage.NewMockStorage(ctrl)
+			st.EXPECT().GetProductImages(ctx, []int64{1}).Return(testImages, nil)
+			if tC.rErr != errSkip {
+				st.EXPECT().UpdateProductImagePositions(ctx, int64(1), tC.imageIDs).Return(tC.rErr)
+			}
+
+			s := New(st, nil)
+
+			err := s.SetPrimaryProductImage(ctx, 1, tC.imageID)
+			assert.True(t, errors.Is(err, tC.err), fmt.Sprintf("wanted %s got %s", tC.err, err))
+		})
+	}
+}
+
+func TestService_DeleteProductImage(t *testing.T) {
+	testImage := model.Image{ID: 2, ProductID: 1, Key: "products/1/a.jpg", ContentType: "image/jpeg"}
+
+	testCases := []struct {
+		desc   string
+		rErr   error
+		delErr error
+		err    error
+	}{
+		{
+			desc: "success",
+			rErr: nil,
+			err:  nil,
+		},
+		{
+			desc: "ErrNotFound",
+			rErr: storage.ErrNotFound,
+			err:  ErrNotFound,
+		},
+		{
+			desc: "unexpected error",
+			rErr: errTest,
+			err:  errTest,
+		},
+		{
+			desc:   "file error",
+			rErr:   nil,
+			delErr: errTest,
+			err:    nil,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			st := storage.NewMockStorage(ctrl)
+			st.EXPECT().DeleteProductImage(ctx, int64(1), int64(2)).Return(testImage, tC.rErr)
+
+			m := media.NewMockStorage(ctrl)
+			if tC.rErr == nil {
+				for _, key := range []string{"products/1/a.jpg", "products/1/a_small.jpg", "products/1/a_medium.jpg", "products/1/a_large.jpg"} {
+					m.EXPECT().Delete(ctx, key).Return(tC.delErr)
+				}
+			}
+
+			s := New(st, m)
+
+			err := s.DeleteProductImage(ctx, 1, 2)
+			if tC.delErr != nil {
+				assert.Error(t, err)
+				return
+			}
+			assert.True(t, errors.Is(err, tC.err), fmt.Sprintf("wanted %s got %s", tC.err, err))
+		})
+	}
+}
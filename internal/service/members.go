@@ -0,0 +1,58 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/vliubezny/gstore/internal/model"
+	"github.com/vliubezny/gstore/internal/storage"
+)
+
+func (s *service) GetStoreMembers(ctx context.Context, storeID int64) ([]model.StoreMember, error) {
+	members, err := s.s.GetStoreMembers(ctx, storeID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get store members: %w", err)
+	}
+	return members, nil
+}
+
+func (s *service) GetStoreMember(ctx context.Context, storeID, userID int64) (model.StoreMember, error) {
+	member, err := s.s.GetStoreMember(ctx, storeID, userID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return model.StoreMember{}, ErrNotFound
+		}
+		return model.StoreMember{}, fmt.Errorf("failed to get store member: %w", err)
+	}
+	return member, nil
+}
+
+func (s *service) SetStoreMember(ctx context.Context, storeID int64, email string, role model.StoreRole) (model.StoreMember, error) {
+	member, err := s.s.SetStoreMember(ctx, storeID, email, role)
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrUnknownStore):
+			return model.StoreMember{}, ErrUnknownStore
+		case errors.Is(err, storage.ErrUnknownUser):
+			return model.StoreMember{}, ErrUnknownUser
+		case errors.Is(err, storage.ErrLastOwner):
+			return model.StoreMember{}, ErrLastOwner
+		}
+		return model.StoreMember{}, fmt.Errorf("failed to set store member: %w", err)
+	}
+	return member, nil
+}
+
+func (s *service) RemoveStoreMember(ctx context.Context, storeID, userID int64) error {
+	if err := s.s.DeleteStoreMember(ctx, storeID, userID); err != nil {
+		switch {
+		case errors.Is(err, storage.ErrNotFound):
+			return ErrNotFound
+		case errors.Is(err, storage.ErrLastOwner):
+			return ErrLastOwner
+		}
+		return fmt.Errorf("failed to delete store member: %w", err)
+	}
+	return nil
+}
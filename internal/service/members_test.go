@@ -0,0 +1,185 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/vliubezny/gstore/internal/model"
+	"github.com/vliubezny/gstore/internal/storage"
+)
+
+var testMember = model.StoreMember{StoreID: 1, UserID: 2, Email: "owner@test.com", Role: model.StoreOwner}
+
+func TestService_GetStoreMembers(t *testing.T) {
+	testCases := []struct {
+		desc    string
+		members []model.StoreMember
+		rErr    error
+		err     error
+	}{
+		{
+			desc:    "success",
+			members: []model.StoreMember{testMember},
+			rErr:    nil,
+			err:     nil,
+		},
+		{
+			desc: "unexpected error",
+			rErr: errTest,
+			err:  errTest,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			st := storage.NewMockStorage(ctrl)
+			st.EXPECT().GetStoreMembers(ctx, int64(1)).Return(tC.members, tC.rErr)
+
+			s := New(st, nil)
+
+			members, err := s.GetStoreMembers(ctx, 1)
+			assert.True(t, errors.Is(err, tC.err), fmt.Sprintf("wanted %s got %s", tC.err, err))
+			assert.Equal(t, tC.members, members)
+		})
+	}
+}
+
+func TestService_GetStoreMember(t *testing.T) {
+	testCases := []struct {
+		desc   string
+		member model.StoreMember
+		rErr   error
+		err    error
+	}{
+		{
+			desc:   "success",
+			member: testMember,
+			rErr:   nil,
+			err:    nil,
+		},
+		{
+			desc: "ErrNotFound",
+			rErr: storage.ErrNotFound,
+			err:  ErrNotFound,
+		},
+		{
+			desc: "unexpected error",
+			rErr: errTest,
+			err:  errTest,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			st := storage.NewMockStorage(ctrl)
+			st.EXPECT().GetStoreMember(ctx, int64(1), int64(2)).Return(tC.member, tC.rErr)
+
+			s := New(st, nil)
+
+			member, err := s.GetStoreMember(ctx, 1, 2)
+			assert.True(t, errors.Is(err, tC.err), fmt.Sprintf("wanted %s got %s", tC.err, err))
+			assert.Equal(t, tC.member, member)
+		})
+	}
+}
+
+func TestService_SetStoreMember(t *testing.T) {
+	testCases := []struct {
+		desc   string
+		member model.StoreMember
+		rErr   error
+		err    error
+	}{
+		{
+			desc:   "success",
+			member: testMember,
+			rErr:   nil,
+			err:    nil,
+		},
+		{
+			desc: "ErrUnknownStore",
+			rErr: storage.ErrUnknownStore,
+			err:  ErrUnknownStore,
+		},
+		{
+			desc: "ErrUnknownUser",
+			rErr: storage.ErrUnknownUser,
+			err:  ErrUnknownUser,
+		},
+		{
+			desc: "ErrLastOwner",
+			rErr: storage.ErrLastOwner,
+			err:  ErrLastOwner,
+		},
+		{
+			desc: "unexpected error",
+			rErr: errTest,
+			err:  errTest,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			st := storage.NewMockStorage(ctrl)
+			st.EXPECT().SetStoreMember(ctx, int64(1), "owner@test.com", model.StoreOwner).Return(tC.member, tC.rErr)
+
+			s := New(st, nil)
+
+			member, err := s.SetStoreMember(ctx, 1, "owner@test.com", model.StoreOwner)
+			assert.True(t, errors.Is(err, tC.err), fmt.Sprintf("wanted %s got %s", tC.err, err))
+			assert.Equal(t, tC.member, member)
+		})
+	}
+}
+
+func TestService_RemoveStoreMember(t *testing.T) {
+	testCases := []struct {
+		desc string
+		rErr error
+		err  error
+	}{
+		{
+			desc: "success",
+			rErr: nil,
+			err:  nil,
+		},
+		{
+			desc: "ErrNotFound",
+			rErr: storage.ErrNotFound,
+			err:  ErrNotFound,
+		},
+		{
+			desc: "ErrLastOwner",
+			rErr: storage.ErrLastOwner,
+			err:  ErrLastOwner,
+		},
+		{
+			desc: "unexpected error",
+			rErr: errTest,
+			err:  errTest,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			st := storage.NewMockStorage(ctrl)
+			st.EXPECT().DeleteStoreMember(ctx, int64(1), int64(2)).Return(tC.rErr)
+
+			s := New(st, nil)
+
+			err := s.RemoveStoreMember(ctx, 1, 2)
+			assert.True(t, errors.Is(err, tC.err), fmt.Sprintf("wanted %s got %s", tC.err, err))
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/vliubezny/gstore/internal/model"
+	"github.com/vliubezny/gstore/internal/payment"
+	"github.com/vliubezny/gstore/internal/storage"
+)
+
+// orderTransitions contains statuses reachable from order status.
+var orderTransitions = map[model.OrderStatus][]model.OrderStatus{
+	model.OrderPending:   {model.OrderPaid, model.OrderCancelled},
+	model.OrderPaid:      {model.OrderShipped, model.OrderRefunded},
+	model.OrderShipped:   {model.OrderDelivered},
+	model.OrderDelivered: {model.OrderRefunded},
+}
+
+type orderService struct {
+	s     storage.OrderStorage
+	p     payment.Provider
+	carts CartService
+}
+
+// NewOrderService creates order service instance.
+func NewOrderService(s storage.OrderStorage, p payment.Provider) OrderService {
+	return &orderService{
+		s:     s,
+		p:     p,
+		carts: NewCartService(s),
+	}
+}
+
+func (s *orderService) Checkout(ctx context.Context, userID int64, currency string) (model.Order, error) {
+	cart, err := s.carts.GetCart(ctx, model.CartOwner{UserID: userID}, currency)
+	if err != nil {
+		return model.Order{}, err
+	}
+
+	if len(cart.Items) == 0 {
+		return model.Order{}, ErrEmptyCart
+	}
+
+	items := make([]model.OrderItem, len(cart.Items))
+	for i, item := range cart.Items {
+		if !item.Available {
+			return model.Order{}, fmt.Errorf("%w: variant %d in store %d is unavailable", ErrCartChanged, item.VariantID, item.StoreID)
+		}
+
+		if item.PriceChanged {
+			return model.Order{}, fmt.Errorf("%w: price of variant %d in store %d has changed", ErrCartChanged, item.VariantID, item.StoreID)
+		}
+
+		items[i] = model.OrderItem{
+			ProductID: item.ProductID,
+			VariantID: item.VariantID,
+			StoreID:   item.StoreID,
+			Quantity:  item.Quantity,
+			Price:     item.Position.Price,
+			Currency:  item.Position.Currency,
+		}
+	}
+
+	order, err := s.s.CreateOrder(ctx, model.Order{
+		UserID:   userID,
+		Status:   model.OrderPending,
+		Currency: cart.Currency,
+		Total:    cart.Total,
+		Items:    items,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrPositionChanged):
+			return model.Order{}, ErrCartChanged
+		case errors.Is(err, storage.ErrInsufficientStock):
+			return model.Order{}, ErrInsufficientStock
+		}
+		return model.Order{}, fmt.Errorf("failed to create order: %w", err)
+	}
+
+	return order, nil
+}
+
+func (s *orderService) GetOrders(ctx context.Context, filter model.OrderFilter, page model.Page) ([]model.Order, string, error) {
+	orders, next, err := s.s.GetOrders(ctx, filter, page)
+	if err != nil {
+		if errors.Is(err, storage.ErrInvalidCursor) {
+			return nil, "", ErrInvalidCursor
+		}
+		return nil, "", fmt.Errorf("failed to get orders: %w", err)
+	}
+	return orders, next, nil
+}
+
+func (s *orderService) GetOrder(ctx context.Context, orderID int64) (model.Order, error) {
+	order, err := s.s.GetOrder(ctx, orderID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return model.Order{}, ErrNotFound
+		}
+		return model.Order{}, fmt.Errorf("failed to get order: %w", err)
+	}
+	return order, nil
+}
+
+func (s *orderService) TransitionOrder(ctx context.Context, orderID int64, status model.OrderStatus, actorID int64) (model.Order, error) {
+	order, err := s.GetOrder(ctx, orderID)
+	if err != nil {
+		return model.Order{}, err
+	}
+
+	if !canTransition(order.Status, status) {
+		return model.Order{}, fmt.Errorf("%w: %s order cannot become %s", ErrInvalidTransition, order.Status, status)
+	}
+
+	// shipped goods are not returned to stock automatically
+	release := status == model.OrderCancelled || (status == model.OrderRefunded && order.Status == model.OrderPaid)
+
+	if err := s.s.UpdateOrderStatus(ctx, orderID, order.Status, status, actorID, release); err != nil {
+		if errors.Is(err, storage.ErrOrderStatusChanged) {
+			return model.Order{}, fmt.Errorf("%w: order status has been changed concurrently", ErrInvalidTransition)
+		}
+		return model.Order{}, fmt.Errorf("failed to update order status: %w", err)
+	}
+
+	// money is returned only once the order has become refunded, so concurrent transition can't undo it
+	if status == model.OrderRefunded {
+		if err := s.refundPayments(ctx, orderID); err != nil {
+			return model.Order{}, err
+		}
+	}
+
+	return s.GetOrder(ctx, orderID)
+}
+
+func canTransition(from, to model.OrderStatus) bool {
+	for _, s := range orderTransitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,369 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vliubezny/gstore/internal/model"
+	"github.com/vliubezny/gstore/internal/storage"
+)
+
+var testOrder = model.Order{
+	ID:       1,
+	UserID:   1,
+	Status:   model.OrderPending,
+	Currency: "USD",
+	Total:    decimal.RequireFromString("20.00"),
+	Items: []model.OrderItem{
+		{ProductID: 1, VariantID: 1, StoreID: 1, Name: "iPhone", SKU: "IP", Quantity: 2, Price: decimal.NewFromInt(10), Currency: "USD", Reserved: true},
+	},
+}
+
+func TestOrderService_Checkout(t *testing.T) {
+	pos := model.Position{
+		ProductID: 1, VariantID: 1, StoreID: 1, Price: decimal.NewFromInt(10), Currency: "USD",
+		Quantity: 5, Availability: model.AvailabilityInStock,
+	}
+	item := model.CartItem{ProductID: 1, VariantID: 1, StoreID: 1, Quantity: 2, Price: decimal.NewFromInt(10), Currency: "USD", Position: pos}
+
+	changed := item
+	changed.Price = decimal.NewFromInt(9)
+
+	unavailable := item
+	unavailable.Quantity = 6
+
+	order := model.Order{
+		UserID:   1,
+		Status:   model.OrderPending,
+		Currency: "USD",
+		Total:    decimal.RequireFromString("20.00"),
+		Items: []model.OrderItem{
+			{ProductID: 1, VariantID: 1, StoreID: 1, Quantity: 2, Price: decimal.NewFromInt(10), Currency: "USD"},
+		},
+	}
+
+	testCases := []struct {
+		desc  string
+		items []model.CartItem
+		rErr  error
+		oErr  error
+		order model.Order
+		err   error
+	}{
+		{
+			desc:  "success",
+			items: []model.CartItem{item},
+			rErr:  nil,
+			oErr:  nil,
+			order: testOrder,
+			err:   nil,
+		},
+		{
+			desc:  "ErrEmptyCart",
+			items: nil,
+			rErr:  nil,
+			oErr:  errSkip,
+			err:   ErrEmptyCart,
+		},
+		{
+			desc:  "price changed",
+			items: []model.CartItem{changed},
+			rErr:  nil,
+			oErr:  errSkip,
+			err:   ErrCartChanged,
+		},
+		{
+			desc:  "unavailable",
+			items: []model.CartItem{unavailable},
+			rErr:  nil,
+			oErr:  errSkip,
+			err:   ErrCartChanged,
+		},
+		{
+			desc:  "position changed on order creation",
+			items: []model.CartItem{item},
+			rErr:  nil,
+			oErr:  storage.ErrPositionChanged,
+			err:   ErrCartChanged,
+		},
+		{
+			desc:  "ErrInsufficientStock",
+			items: []model.CartItem{item},
+			rErr:  nil,
+			oErr:  storage.ErrInsufficientStock,
+			err:   ErrInsufficientStock,
+		},
+		{
+			desc:  "unexpected rates error",
+			items: []model.CartItem{item},
+			rErr:  errTest,
+			oErr:  errSkip,
+			err:   errTest,
+		},
+		{
+			desc:  "unexpected order error",
+			items: []model.CartItem{item},
+			rErr:  nil,
+			oErr:  errTest,
+			err:   errTest,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			st := storage.NewMockOrderStorage(ctrl)
+			st.EXPECT().GetCartItems(ctx, model.CartOwner{UserID: 1}).Return(tC.items, nil)
+			st.EXPECT().GetExchangeRates(ctx).Return(testRates, tC.rErr)
+			if tC.oErr != errSkip {
+				st.EXPECT().CreateOrder(ctx, order).Return(tC.order, tC.oErr)
+			}
+
+			s := NewOrderService(st, nil)
+
+			o, err := s.Checkout(ctx, 1, "")
+			require.True(t, errors.Is(err, tC.err), fmt.Sprintf("wanted %s got %s", tC.err, err))
+			assert.Equal(t, tC.order, o)
+		})
+	}
+}
+
+func TestOrderService_GetOrders(t *testing.T) {
+	filter := model.OrderFilter{UserID: 1}
+
+	testCases := []struct {
+		desc   string
+		orders []model.Order
+		next   string
+		rErr   error
+		err    error
+	}{
+		{
+			desc:   "success",
+			orders: []model.Order{testOrder},
+			next:   "next",
+			rErr:   nil,
+			err:    nil,
+		},
+		{
+			desc: "ErrInvalidCursor",
+			rErr: storage.ErrInvalidCursor,
+			err:  ErrInvalidCursor,
+		},
+		{
+			desc: "unexpected error",
+			rErr: errTest,
+			err:  errTest,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			st := storage.NewMockOrderStorage(ctrl)
+			st.EXPECT().GetOrders(ctx, filter, testPage).Return(tC.orders, tC.next, tC.rErr)
+
+			s := NewOrderService(st, nil)
+
+			orders, next, err := s.GetOrders(ctx, filter, testPage)
+			require.True(t, errors.Is(err, tC.err), fmt.Sprintf("wanted %s got %s", tC.err, err))
+			assert.Equal(t, tC.orders, orders)
+			assert.Equal(t, tC.next, next)
+		})
+	}
+}
+
+func TestOrderService_GetOrder(t *testing.T) {
+	testCases := []struct {
+		desc  string
+		order model.Order
+		rErr  error
+		err   error
+	}{
+		{
+			desc:  "success",
+			order: testOrder,
+			rErr:  nil,
+			err:   nil,
+		},
+		{
+			desc: "ErrNotFound",
+			rErr: storage.ErrNotFound,
+			err:  ErrNotFound,
+		},
+		{
+			desc: "unexpected error",
+			rErr: errTest,
+			err:  errTest,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			st := storage.NewMockOrderStorage(ctrl)
+			st.EXPECT().GetOrder(ctx, int64(1)).Return(tC.order, tC.rErr)
+
+			s := NewOrderService(st, nil)
+
+			o, err := s.GetOrder(ctx, 1)
+			require.True(t, errors.Is(err, tC.err), fmt.Sprintf("wanted %s got %s", tC.err, err))
+			assert.Equal(t, tC.order, o)
+		})
+	}
+}
+
+func TestOrderService_TransitionOrder(t *testing.T) {
+	withStatus := func(s model.OrderStatus) model.Order {
+		o := testOrder
+		o.Status = s
+		return o
+	}
+
+	testCases := []struct {
+		desc    string
+		from    model.OrderStatus
+		to      model.OrderStatus
+		gErr    error
+		release bool
+		uErr    error
+		err     error
+	}{
+		{
+			desc: "pay",
+			from: model.OrderPending,
+			to:   model.OrderPaid,
+			uErr: nil,
+			err:  nil,
+		},
+		{
+			desc:    "cancel",
+			from:    model.OrderPending,
+			to:      model.OrderCancelled,
+			release: true,
+			uErr:    nil,
+			err:     nil,
+		},
+		{
+			desc: "ship",
+			from: model.OrderPaid,
+			to:   model.OrderShipped,
+			uErr: nil,
+			err:  nil,
+		},
+		{
+			desc:    "refund unshipped",
+			from:    model.OrderPaid,
+			to:      model.OrderRefunded,
+			release: true,
+			uErr:    nil,
+			err:     nil,
+		},
+		{
+			desc: "deliver",
+			from: model.OrderShipped,
+			to:   model.OrderDelivered,
+			uErr: nil,
+			err:  nil,
+		},
+		{
+			desc: "refund delivered",
+			from: model.OrderDelivered,
+			to:   model.OrderRefunded,
+			uErr: nil,
+			err:  nil,
+		},
+		{
+			desc: "ship unpaid",
+			from: model.OrderPending,
+			to:   model.OrderShipped,
+			uErr: errSkip,
+			err:  ErrInvalidTransition,
+		},
+		{
+			desc: "cancel shipped",
+			from: model.OrderShipped,
+			to:   model.OrderCancelled,
+			uErr: errSkip,
+			err:  ErrInvalidTransition,
+		},
+		{
+			desc: "reopen cancelled",
+			from: model.OrderCancelled,
+			to:   model.OrderPending,
+			uErr: errSkip,
+			err:  ErrInvalidTransition,
+		},
+		{
+			desc: "same status",
+			from: model.OrderPaid,
+			to:   model.OrderPaid,
+			uErr: errSkip,
+			err:  ErrInvalidTransition,
+		},
+		{
+			desc: "ErrNotFound",
+			from: model.OrderPending,
+			to:   model.OrderPaid,
+			gErr: storage.ErrNotFound,
+			uErr: errSkip,
+			err:  ErrNotFound,
+		},
+		{
+			desc: "concurrent change",
+			from: model.OrderPending,
+			to:   model.OrderPaid,
+			uErr: storage.ErrOrderStatusChanged,
+			err:  ErrInvalidTransition,
+		},
+		{
+			desc:    "concurrent change of refunded order",
+			from:    model.OrderPaid,
+			to:      model.OrderRefunded,
+			release: true,
+			uErr:    storage.ErrOrderStatusChanged,
+			err:     ErrInvalidTransition,
+		},
+		{
+			desc: "unexpected error",
+			from: model.OrderPending,
+			to:   model.OrderPaid,
+			uErr: errTest,
+			err:  errTest,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			st := storage.NewMockOrderStorage(ctrl)
+			st.EXPECT().GetOrder(ctx, int64(1)).Return(withStatus(tC.from), tC.gErr)
+			if tC.uErr != errSkip {
+				st.EXPECT().UpdateOrderStatus(ctx, int64(1), tC.from, tC.to, int64(2), tC.release).Return(tC.uErr)
+			}
+			if tC.uErr == nil && tC.to == model.OrderRefunded {
+				st.EXPECT().GetOrderPayments(ctx, int64(1)).Return(nil, nil)
+			}
+			if tC.uErr == nil {
+				st.EXPECT().GetOrder(ctx, int64(1)).Return(withStatus(tC.to), nil)
+			}
+
+			s := NewOrderService(st, nil)
+
+			o, err := s.TransitionOrder(ctx, 1, tC.to, 2)
+			require.True(t, errors.Is(err, tC.err), fmt.Sprintf("wanted %s got %s", tC.err, err))
+			if tC.err == nil {
+				assert.Equal(t, withStatus(tC.to), o)
+			}
+		})
+	}
+}
@@ -0,0 +1,212 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/vliubezny/gstore/internal/model"
+	"github.com/vliubezny/gstore/internal/payment"
+	"github.com/vliubezny/gstore/internal/storage"
+)
+
+func (s *orderService) PayOrder(ctx context.Context, orderID, userID int64, method string) (model.Payment, error) {
+	order, err := s.GetOrder(ctx, orderID)
+	if err != nil {
+		return model.Payment{}, err
+	}
+
+	if order.UserID != userID {
+		return model.Payment{}, ErrNotFound
+	}
+
+	if order.Status != model.OrderPending {
+		return model.Payment{}, fmt.Errorf("%w: %s order cannot be paid", ErrInvalidTransition, order.Status)
+	}
+
+	payments, err := s.s.GetOrderPayments(ctx, orderID)
+	if err != nil {
+		return model.Payment{}, fmt.Errorf("failed to get payments: %w", err)
+	}
+
+	for _, p := range payments {
+		if p.Status != model.PaymentDeclined {
+			return model.Payment{}, fmt.Errorf("%w: order payment is %s", ErrInvalidTransition, p.Status)
+		}
+	}
+
+	p, err := s.p.Authorize(ctx, payment.AuthorizeRequest{
+		Reference: strconv.FormatInt(order.ID, 10),
+		Amount:    order.Total,
+		Currency:  order.Currency,
+		Method:    method,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, payment.ErrDeclined):
+			return model.Payment{}, ErrPaymentDeclined
+		case errors.Is(err, payment.ErrUnavailable):
+			return model.Payment{}, ErrPaymentUnavailable
+		}
+		return model.Payment{}, fmt.Errorf("failed to authorize payment: %w", err)
+	}
+
+	pay := model.Payment{
+		ID:       p.ID,
+		OrderID:  order.ID,
+		Status:   model.PaymentStatus(p.Status),
+		Amount:   p.Amount,
+		Currency: p.Currency,
+	}
+
+	// concurrent request may have paid the order since the check, the payment is not captured then
+	if err := s.s.CreatePayment(ctx, pay); err != nil {
+		if errors.Is(err, storage.ErrPaymentInProgress) {
+			return model.Payment{}, fmt.Errorf("%w: order payment is in progress", ErrInvalidTransition)
+		}
+		return model.Payment{}, fmt.Errorf("failed to create payment: %w", err)
+	}
+
+	if pay.Status == model.PaymentAuthorized {
+		if err := s.capturePayment(ctx, pay, userID); err != nil {
+			return model.Payment{}, err
+		}
+	}
+
+	return s.getPayment(ctx, pay.ID)
+}
+
+func (s *orderService) HandlePaymentWebhook(ctx context.Context, header http.Header, body []byte) error {
+	e, err := s.p.ParseWebhook(header, body)
+	if err != nil {
+		if errors.Is(err, payment.ErrInvalidSignature) {
+			return ErrInvalidSignature
+		}
+		return fmt.Errorf("failed to parse payment event: %w", err)
+	}
+
+	pay, err := s.getPayment(ctx, e.PaymentID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.s.AddPaymentEvent(ctx, e.ID, pay.ID); err != nil {
+		if errors.Is(err, storage.ErrDuplicateEvent) {
+			return nil
+		}
+		return fmt.Errorf("failed to add payment event: %w", err)
+	}
+
+	if err := s.applyPaymentEvent(ctx, pay, e.Status); err != nil {
+		// forget the event so redelivery is processed
+		if derr := s.s.DeletePaymentEvent(ctx, e.ID); derr != nil {
+			return fmt.Errorf("failed to delete payment event after %v: %w", err, derr)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// applyPaymentEvent moves the payment along allowed transitions only,
+// so late, replayed or forged events can't revive declined payments or pay orders without authorization.
+func (s *orderService) applyPaymentEvent(ctx context.Context, pay model.Payment, status payment.Status) error {
+	switch status {
+	case payment.StatusAuthorized:
+		if pay.Status != model.PaymentPending {
+			return nil
+		}
+		return s.capturePayment(ctx, pay, 0)
+
+	case payment.StatusCaptured:
+		if pay.Status != model.PaymentAuthorized {
+			return nil
+		}
+		if err := s.updatePaymentStatus(ctx, pay.ID, model.PaymentCaptured); err != nil {
+			return err
+		}
+		return s.markOrderPaid(ctx, pay.OrderID, 0)
+
+	case payment.StatusDeclined:
+		if pay.Status != model.PaymentPending {
+			return nil
+		}
+		return s.updatePaymentStatus(ctx, pay.ID, model.PaymentDeclined)
+
+	case payment.StatusRefunded:
+		if pay.Status != model.PaymentCaptured {
+			return nil
+		}
+		return s.updatePaymentStatus(ctx, pay.ID, model.PaymentRefunded)
+	}
+
+	return nil
+}
+
+// capturePayment charges authorized payment and marks its order paid on behalf of the actor.
+func (s *orderService) capturePayment(ctx context.Context, pay model.Payment, actorID int64) error {
+	p, err := s.p.Capture(ctx, pay.ID)
+	if err != nil {
+		return fmt.Errorf("failed to capture payment: %w", err)
+	}
+
+	if err := s.updatePaymentStatus(ctx, pay.ID, model.PaymentStatus(p.Status)); err != nil {
+		return err
+	}
+
+	return s.markOrderPaid(ctx, pay.OrderID, actorID)
+}
+
+// markOrderPaid moves pending order to paid, order which is already moved on is left as is.
+func (s *orderService) markOrderPaid(ctx context.Context, orderID, actorID int64) error {
+	err := s.s.UpdateOrderStatus(ctx, orderID, model.OrderPending, model.OrderPaid, actorID, false)
+	if err != nil && !errors.Is(err, storage.ErrOrderStatusChanged) {
+		return fmt.Errorf("failed to update order status: %w", err)
+	}
+	return nil
+}
+
+// refundPayments returns captured payments of the order to customer.
+func (s *orderService) refundPayments(ctx context.Context, orderID int64) error {
+	payments, err := s.s.GetOrderPayments(ctx, orderID)
+	if err != nil {
+		return fmt.Errorf("failed to get payments: %w", err)
+	}
+
+	for _, pay := range payments {
+		if pay.Status != model.PaymentCaptured {
+			continue
+		}
+
+		p, err := s.p.Refund(ctx, pay.ID)
+		if err != nil {
+			return fmt.Errorf("failed to refund payment: %w", err)
+		}
+
+		if err := s.updatePaymentStatus(ctx, pay.ID, model.PaymentStatus(p.Status)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *orderService) getPayment(ctx context.Context, paymentID string) (model.Payment, error) {
+	pay, err := s.s.GetPayment(ctx, paymentID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return model.Payment{}, ErrNotFound
+		}
+		return model.Payment{}, fmt.Errorf("failed to get payment: %w", err)
+	}
+	return pay, nil
+}
+
+func (s *orderService) updatePaymentStatus(ctx context.Context, paymentID string, status model.PaymentStatus) error {
+	if err := s.s.UpdatePaymentStatus(ctx, paymentID, status); err != nil {
+		return fmt.Errorf("failed to update payment status: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,356 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vliubezny/gstore/internal/model"
+	"github.com/vliubezny/gstore/internal/payment"
+	"github.com/vliubezny/gstore/internal/storage"
+)
+
+var testPayment = model.Payment{
+	ID:       "pay1",
+	OrderID:  1,
+	Status:   model.PaymentCaptured,
+	Amount:   testOrder.Total,
+	Currency: "USD",
+}
+
+func TestOrderService_PayOrder(t *testing.T) {
+	paid := testOrder
+	paid.Status = model.OrderPaid
+
+	declined := testPayment
+	declined.Status = model.PaymentDeclined
+
+	pending := testPayment
+	pending.Status = model.PaymentPending
+
+	req := payment.AuthorizeRequest{
+		Reference: "1",
+		Amount:    testOrder.Total,
+		Currency:  "USD",
+		Method:    "card",
+	}
+
+	testCases := []struct {
+		desc     string
+		userID   int64
+		order    model.Order
+		payments []model.Payment
+		status   payment.Status
+		aErr     error
+		crErr    error
+		cErr     error
+		payment  model.Payment
+		err      error
+	}{
+		{
+			desc:     "captured",
+			userID:   1,
+			order:    testOrder,
+			payments: []model.Payment{declined},
+			status:   payment.StatusAuthorized,
+			payment:  testPayment,
+			err:      nil,
+		},
+		{
+			desc:    "pending",
+			userID:  1,
+			order:   testOrder,
+			status:  payment.StatusPending,
+			payment: pending,
+			err:     nil,
+		},
+		{
+			desc:   "declined",
+			userID: 1,
+			order:  testOrder,
+			aErr:   payment.ErrDeclined,
+			err:    ErrPaymentDeclined,
+		},
+		{
+			desc:   "unavailable",
+			userID: 1,
+			order:  testOrder,
+			aErr:   payment.ErrUnavailable,
+			err:    ErrPaymentUnavailable,
+		},
+		{
+			desc:   "another user order",
+			userID: 2,
+			order:  testOrder,
+			aErr:   errSkip,
+			err:    ErrNotFound,
+		},
+		{
+			desc:   "paid order",
+			userID: 1,
+			order:  paid,
+			aErr:   errSkip,
+			err:    ErrInvalidTransition,
+		},
+		{
+			desc:     "payment in progress",
+			userID:   1,
+			order:    testOrder,
+			payments: []model.Payment{pending},
+			aErr:     errSkip,
+			err:      ErrInvalidTransition,
+		},
+		{
+			desc:   "concurrent payment",
+			userID: 1,
+			order:  testOrder,
+			status: payment.StatusAuthorized,
+			crErr:  storage.ErrPaymentInProgress,
+			err:    ErrInvalidTransition,
+		},
+		{
+			desc:   "unexpected create error",
+			userID: 1,
+			order:  testOrder,
+			status: payment.StatusAuthorized,
+			crErr:  errTest,
+			err:    errTest,
+		},
+		{
+			desc:   "unexpected authorize error",
+			userID: 1,
+			order:  testOrder,
+			aErr:   errTest,
+			err:    errTest,
+		},
+		{
+			desc:   "unexpected capture error",
+			userID: 1,
+			order:  testOrder,
+			status: payment.StatusAuthorized,
+			cErr:   errTest,
+			err:    errTest,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			st := storage.NewMockOrderStorage(ctrl)
+			p := payment.NewMockProvider(ctrl)
+
+			st.EXPECT().GetOrder(ctx, int64(1)).Return(tC.order, nil)
+			if tC.userID == tC.order.UserID && tC.order.Status == model.OrderPending {
+				st.EXPECT().GetOrderPayments(ctx, int64(1)).Return(tC.payments, nil)
+			}
+
+			if tC.aErr != errSkip {
+				p.EXPECT().Authorize(ctx, req).Return(payment.Payment{
+					ID: "pay1", Status: tC.status, Amount: req.Amount, Currency: req.Currency,
+				}, tC.aErr)
+			}
+
+			if tC.aErr == nil {
+				st.EXPECT().CreatePayment(ctx, model.Payment{
+					ID: "pay1", OrderID: 1, Status: model.PaymentStatus(tC.status), Amount: req.Amount, Currency: "USD",
+				}).Return(tC.crErr)
+			}
+
+			if tC.status == payment.StatusAuthorized && tC.crErr == nil {
+				p.EXPECT().Capture(ctx, "pay1").Return(payment.Payment{ID: "pay1", Status: payment.StatusCaptured}, tC.cErr)
+				if tC.cErr == nil {
+					st.EXPECT().UpdatePaymentStatus(ctx, "pay1", model.PaymentCaptured).Return(nil)
+					st.EXPECT().UpdateOrderStatus(ctx, int64(1), model.OrderPending, model.OrderPaid, int64(1), false).Return(nil)
+				}
+			}
+
+			if tC.err == nil {
+				st.EXPECT().GetPayment(ctx, "pay1").Return(tC.payment, nil)
+			}
+
+			s := NewOrderService(st, p)
+
+			pay, err := s.PayOrder(ctx, 1, tC.userID, "card")
+			require.True(t, errors.Is(err, tC.err), fmt.Sprintf("wanted %s got %s", tC.err, err))
+			assert.Equal(t, tC.payment, pay)
+		})
+	}
+}
+
+func TestOrderService_HandlePaymentWebhook(t *testing.T) {
+	pending := testPayment
+	pending.Status = model.PaymentPending
+
+	body := []byte("body")
+	header := http.Header{}
+
+	testCases := []struct {
+		desc      string
+		payStatus model.PaymentStatus
+		status    payment.Status
+		pErr      error
+		gErr      error
+		aErr      error
+		captured  bool
+		update    model.PaymentStatus
+		paid      bool
+		uErr      error
+		err       error
+	}{
+		{
+			desc:     "authorized",
+			status:   payment.StatusAuthorized,
+			captured: true,
+			update:   model.PaymentCaptured,
+			paid:     true,
+			err:      nil,
+		},
+		{
+			desc:      "captured",
+			payStatus: model.PaymentAuthorized,
+			status:    payment.StatusCaptured,
+			update:    model.PaymentCaptured,
+			paid:      true,
+			err:       nil,
+		},
+		{
+			desc:      "captured - pending payment ignored",
+			payStatus: model.PaymentPending,
+			status:    payment.StatusCaptured,
+			err:       nil,
+		},
+		{
+			desc:      "captured - declined payment ignored",
+			payStatus: model.PaymentDeclined,
+			status:    payment.StatusCaptured,
+			err:       nil,
+		},
+		{
+			desc:      "declined - captured payment ignored",
+			payStatus: model.PaymentCaptured,
+			status:    payment.StatusDeclined,
+			err:       nil,
+		},
+		{
+			desc:      "refunded",
+			payStatus: model.PaymentCaptured,
+			status:    payment.StatusRefunded,
+			update:    model.PaymentRefunded,
+			err:       nil,
+		},
+		{
+			desc:      "refunded - declined payment ignored",
+			payStatus: model.PaymentDeclined,
+			status:    payment.StatusRefunded,
+			err:       nil,
+		},
+		{
+			desc:   "declined",
+			status: payment.StatusDeclined,
+			update: model.PaymentDeclined,
+			err:    nil,
+		},
+		{
+			desc:   "duplicate",
+			status: payment.StatusAuthorized,
+			aErr:   storage.ErrDuplicateEvent,
+			err:    nil,
+		},
+		{
+			desc: "ErrInvalidSignature",
+			pErr: payment.ErrInvalidSignature,
+			gErr: errSkip,
+			err:  ErrInvalidSignature,
+		},
+		{
+			desc: "ErrNotFound",
+			gErr: storage.ErrNotFound,
+			aErr: errSkip,
+			err:  ErrNotFound,
+		},
+		{
+			desc:      "unexpected update error",
+			payStatus: model.PaymentAuthorized,
+			status:    payment.StatusCaptured,
+			update:    model.PaymentCaptured,
+			uErr:      errTest,
+			err:       errTest,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			st := storage.NewMockOrderStorage(ctrl)
+			p := payment.NewMockProvider(ctrl)
+
+			p.EXPECT().ParseWebhook(header, body).Return(payment.Event{ID: "e1", PaymentID: "pay1", Status: tC.status}, tC.pErr)
+			if tC.gErr != errSkip {
+				pay := pending
+				if tC.payStatus != "" {
+					pay.Status = tC.payStatus
+				}
+				st.EXPECT().GetPayment(ctx, "pay1").Return(pay, tC.gErr)
+			}
+			if tC.aErr != errSkip && tC.gErr == nil {
+				st.EXPECT().AddPaymentEvent(ctx, "e1", "pay1").Return(tC.aErr)
+			}
+			if tC.captured {
+				p.EXPECT().Capture(ctx, "pay1").Return(payment.Payment{ID: "pay1", Status: payment.StatusCaptured}, nil)
+			}
+			if tC.update != "" {
+				st.EXPECT().UpdatePaymentStatus(ctx, "pay1", tC.update).Return(tC.uErr)
+			}
+			if tC.paid {
+				st.EXPECT().UpdateOrderStatus(ctx, int64(1), model.OrderPending, model.OrderPaid, int64(0), false).Return(nil)
+			}
+			if tC.uErr != nil {
+				st.EXPECT().DeletePaymentEvent(ctx, "e1").Return(nil)
+			}
+
+			s := NewOrderService(st, p)
+
+			err := s.HandlePaymentWebhook(ctx, header, body)
+			require.True(t, errors.Is(err, tC.err), fmt.Sprintf("wanted %s got %s", tC.err, err))
+		})
+	}
+}
+
+func TestOrderService_TransitionOrder_refund(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	paid := testOrder
+	paid.Status = model.OrderPaid
+
+	refunded := testOrder
+	refunded.Status = model.OrderRefunded
+
+	declined := testPayment
+	declined.ID = "pay0"
+	declined.Status = model.PaymentDeclined
+
+	st := storage.NewMockOrderStorage(ctrl)
+	p := payment.NewMockProvider(ctrl)
+
+	gomock.InOrder(
+		st.EXPECT().GetOrder(ctx, int64(1)).Return(paid, nil),
+		st.EXPECT().UpdateOrderStatus(ctx, int64(1), model.OrderPaid, model.OrderRefunded, int64(2), true).Return(nil),
+		st.EXPECT().GetOrderPayments(ctx, int64(1)).Return([]model.Payment{declined, testPayment}, nil),
+		p.EXPECT().Refund(ctx, "pay1").Return(payment.Payment{ID: "pay1", Status: payment.StatusRefunded}, nil),
+		st.EXPECT().UpdatePaymentStatus(ctx, "pay1", model.PaymentRefunded).Return(nil),
+		st.EXPECT().GetOrder(ctx, int64(1)).Return(refunded, nil),
+	)
+
+	s := NewOrderService(st, p)
+
+	o, err := s.TransitionOrder(ctx, 1, model.OrderRefunded, 2)
+	require.NoError(t, err)
+	assert.Equal(t, refunded, o)
+}
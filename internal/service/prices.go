@@ -0,0 +1,111 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/vliubezny/gstore/internal/model"
+	"github.com/vliubezny/gstore/internal/storage"
+)
+
+func (s *service) GetPriceHistory(ctx context.Context, productID int64, filter model.PriceHistoryFilter) ([]model.PriceChange, error) {
+	if err := s.checkProduct(ctx, productID); err != nil {
+		return nil, err
+	}
+
+	changes, err := s.s.GetPriceHistory(ctx, productID, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get price history: %w", err)
+	}
+
+	return changes, nil
+}
+
+func (s *service) GetDailyPriceHistory(ctx context.Context, productID int64, filter model.PriceHistoryFilter) ([]model.PriceBucket, error) {
+	if err := s.checkProduct(ctx, productID); err != nil {
+		return nil, err
+	}
+
+	buckets, err := s.s.GetDailyPrices(ctx, productID, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get daily prices: %w", err)
+	}
+
+	return buckets, nil
+}
+
+func (s *service) ComparePrices(ctx context.Context, productIDs []int64, currency string) ([]model.PriceSummary, error) {
+	if currency == "" {
+		currency = model.BaseCurrency
+	}
+
+	summaries, err := s.loadPriceSummaries(ctx, productIDs, currency)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]model.PriceSummary, 0, len(productIDs))
+	seen := make(map[int64]bool, len(productIDs))
+	for _, id := range productIDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		summary, ok := summaries[id]
+		if !ok {
+			summary = model.PriceSummary{ProductID: id, Currency: currency}
+		}
+		res = append(res, summary)
+	}
+
+	return res, nil
+}
+
+// loadPriceSummaries returns price comparison in the currency grouped by product ID.
+func (s *service) loadPriceSummaries(ctx context.Context, productIDs []int64, currency string) (map[int64]model.PriceSummary, error) {
+	res := make(map[int64]model.PriceSummary, len(productIDs))
+	if len(productIDs) == 0 {
+		return res, nil
+	}
+
+	rate := baseRate
+	if currency != model.BaseCurrency {
+		rates, err := getRates(ctx, s.s)
+		if err != nil {
+			return nil, err
+		}
+
+		var ok bool
+		if rate, ok = rates[currency]; !ok {
+			return nil, ErrUnknownCurrency
+		}
+	}
+
+	summaries, err := s.s.GetPriceSummaries(ctx, productIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get price summaries: %w", err)
+	}
+
+	for _, summary := range summaries {
+		summary.MinPrice = convertPrice(summary.MinPrice, baseRate, rate, currency)
+		summary.MaxPrice = convertPrice(summary.MaxPrice, baseRate, rate, currency)
+		summary.MedianPrice = convertPrice(summary.MedianPrice, baseRate, rate, currency)
+		summary.Currency = currency
+		res[summary.ProductID] = summary
+	}
+
+	return res, nil
+}
+
+// checkProduct returns ErrNotFound if product doesn't exist.
+func (s *service) checkProduct(ctx context.Context, productID int64) error {
+	if _, err := s.s.GetProduct(ctx, productID); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return ErrNotFound
+		}
+		return fmt.Errorf("failed to get product: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,223 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/vliubezny/gstore/internal/model"
+	"github.com/vliubezny/gstore/internal/storage"
+)
+
+var testPriceHistoryFilter = model.PriceHistoryFilter{
+	StoreID: 1,
+	From:    time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+	To:      time.Date(2021, 2, 1, 0, 0, 0, 0, time.UTC),
+}
+
+func TestService_GetPriceHistory(t *testing.T) {
+	testCases := []struct {
+		desc    string
+		pErr    error
+		changes []model.PriceChange
+		rErr    error
+		err     error
+	}{
+		{
+			desc: "success",
+			changes: []model.PriceChange{
+				{ProductID: 1, VariantID: 1, StoreID: 1, Price: decimal.NewFromInt(100), UserID: 1, ChangedAt: time.Date(2021, 1, 5, 0, 0, 0, 0, time.UTC)},
+			},
+			err: nil,
+		},
+		{
+			desc: "ErrNotFound",
+			pErr: storage.ErrNotFound,
+			err:  ErrNotFound,
+		},
+		{
+			desc: "unexpected product error",
+			pErr: errTest,
+			err:  errTest,
+		},
+		{
+			desc: "unexpected error",
+			rErr: errTest,
+			err:  errTest,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			st := storage.NewMockStorage(ctrl)
+			st.EXPECT().GetProduct(ctx, int64(1)).Return(model.Product{ID: 1}, tC.pErr)
+			if tC.pErr == nil {
+				st.EXPECT().GetPriceHistory(ctx, int64(1), testPriceHistoryFilter).Return(tC.changes, tC.rErr)
+			}
+
+			s := New(st, nil)
+
+			changes, err := s.GetPriceHistory(ctx, 1, testPriceHistoryFilter)
+			assert.True(t, errors.Is(err, tC.err), fmt.Sprintf("wanted %s got %s", tC.err, err))
+			if tC.err == nil {
+				assert.Equal(t, tC.changes, changes)
+			}
+		})
+	}
+}
+
+func TestService_GetDailyPriceHistory(t *testing.T) {
+	testCases := []struct {
+		desc    string
+		pErr    error
+		buckets []model.PriceBucket
+		rErr    error
+		err     error
+	}{
+		{
+			desc: "success",
+			buckets: []model.PriceBucket{
+				{Date: time.Date(2021, 1, 5, 0, 0, 0, 0, time.UTC), Min: decimal.NewFromInt(90), Avg: decimal.NewFromInt(95), Max: decimal.NewFromInt(100)},
+			},
+			err: nil,
+		},
+		{
+			desc: "ErrNotFound",
+			pErr: storage.ErrNotFound,
+			err:  ErrNotFound,
+		},
+		{
+			desc: "unexpected error",
+			rErr: errTest,
+			err:  errTest,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			st := storage.NewMockStorage(ctrl)
+			st.EXPECT().GetProduct(ctx, int64(1)).Return(model.Product{ID: 1}, tC.pErr)
+			if tC.pErr == nil {
+				st.EXPECT().GetDailyPrices(ctx, int64(1), testPriceHistoryFilter).Return(tC.buckets, tC.rErr)
+			}
+
+			s := New(st, nil)
+
+			buckets, err := s.GetDailyPriceHistory(ctx, 1, testPriceHistoryFilter)
+			assert.True(t, errors.Is(err, tC.err), fmt.Sprintf("wanted %s got %s", tC.err, err))
+			if tC.err == nil {
+				assert.Equal(t, tC.buckets, buckets)
+			}
+		})
+	}
+}
+
+func TestService_ComparePrices(t *testing.T) {
+	summary := model.PriceSummary{
+		ProductID:       2,
+		OfferCount:      3,
+		Currency:        model.BaseCurrency,
+		MinPrice:        decimal.RequireFromString("90.004"),
+		MaxPrice:        decimal.NewFromInt(110),
+		MedianPrice:     decimal.RequireFromString("100.5"),
+		CheapestStoreID: 1,
+	}
+
+	testCases := []struct {
+		desc       string
+		ids        []int64
+		currency   string
+		rates      []model.ExchangeRate
+		ratesErr   error
+		rSummaries []model.PriceSummary
+		rErr       error
+		summaries  []model.PriceSummary
+		err        error
+	}{
+		{
+			desc:       "success",
+			ids:        []int64{3, 2, 3},
+			rSummaries: []model.PriceSummary{summary},
+			summaries: []model.PriceSummary{
+				{ProductID: 3, Currency: "USD"},
+				{
+					ProductID:       2,
+					OfferCount:      3,
+					Currency:        "USD",
+					MinPrice:        decimal.RequireFromString("90.00"),
+					MaxPrice:        decimal.RequireFromString("110.00"),
+					MedianPrice:     decimal.RequireFromString("100.50"),
+					CheapestStoreID: 1,
+				},
+			},
+			err: nil,
+		},
+		{
+			desc:       "success with conversion",
+			ids:        []int64{2},
+			currency:   "JPY",
+			rates:      []model.ExchangeRate{{Currency: "JPY", Rate: decimal.RequireFromString("104.55")}, {Currency: "USD", Rate: decimal.NewFromInt(1)}},
+			rSummaries: []model.PriceSummary{summary},
+			summaries: []model.PriceSummary{
+				{
+					ProductID:       2,
+					OfferCount:      3,
+					Currency:        "JPY",
+					MinPrice:        decimal.NewFromInt(9410),
+					MaxPrice:        decimal.NewFromInt(11501),
+					MedianPrice:     decimal.NewFromInt(10507),
+					CheapestStoreID: 1,
+				},
+			},
+			err: nil,
+		},
+		{
+			desc:     "ErrUnknownCurrency",
+			ids:      []int64{2},
+			currency: "EUR",
+			rates:    []model.ExchangeRate{{Currency: "USD", Rate: decimal.NewFromInt(1)}},
+			err:      ErrUnknownCurrency,
+		},
+		{
+			desc:     "unexpected rates error",
+			ids:      []int64{2},
+			currency: "EUR",
+			ratesErr: errTest,
+			err:      errTest,
+		},
+		{
+			desc: "unexpected error",
+			ids:  []int64{1},
+			rErr: errTest,
+			err:  errTest,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			st := storage.NewMockStorage(ctrl)
+			if tC.currency != "" {
+				st.EXPECT().GetExchangeRates(ctx).Return(tC.rates, tC.ratesErr)
+			}
+			if tC.ratesErr == nil && !errors.Is(tC.err, ErrUnknownCurrency) {
+				st.EXPECT().GetPriceSummaries(ctx, tC.ids).Return(tC.rSummaries, tC.rErr)
+			}
+
+			s := New(st, nil)
+
+			summaries, err := s.ComparePrices(ctx, tC.ids, tC.currency)
+			assert.True(t, errors.Is(err, tC.err), fmt.Sprintf("wanted %s got %s", tC.err, err))
+			assert.Equal(t, tC.summaries, summaries)
+		})
+	}
+}
@@ -4,7 +4,10 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"net/http"
 
+	"github.com/vliubezny/gstore/internal/media"
 	"github.com/vliubezny/gstore/internal/model"
 	"github.com/vliubezny/gstore/internal/storage"
 )
@@ -18,21 +21,99 @@ var (
 	// ErrUnknownCategory states that category is unknown.
 	ErrUnknownCategory = errors.New("category is unknown")
 
+	// ErrCategoryCycle states that category cannot be moved into its own subtree.
+	ErrCategoryCycle = errors.New("category cycle")
+
 	// ErrUnknownStore states that store is unknown.
 	ErrUnknownStore = errors.New("store is unknown")
 
+	// ErrUnknownUser states that user is unknown.
+	ErrUnknownUser = errors.New("user is unknown")
+
 	// ErrUnknownProduct states that product is unknown.
 	ErrUnknownProduct = errors.New("product is unknown")
+
+	// ErrInvalidCursor states that page cursor is malformed.
+	ErrInvalidCursor = errors.New("invalid cursor")
+
+	// ErrInvalidSchema states that category attribute schema is malformed.
+	ErrInvalidSchema = errors.New("invalid attribute schema")
+
+	// ErrInvalidAttributes states that product attributes don't match category schema.
+	ErrInvalidAttributes = errors.New("invalid attributes")
+
+	// ErrUnsupportedImage states that image format or dimensions are not supported.
+	ErrUnsupportedImage = errors.New("unsupported image")
+
+	// ErrInvalidImageOrder states that image order doesn't match product images.
+	ErrInvalidImageOrder = errors.New("invalid image order")
+
+	// ErrUnknownVariant states that product variant is unknown.
+	ErrUnknownVariant = errors.New("variant is unknown")
+
+	// ErrInvalidVariant states that product variant is malformed or duplicates another one.
+	ErrInvalidVariant = errors.New("invalid variant")
+
+	// ErrInsufficientStock states that stock quantity cannot go below zero.
+	ErrInsufficientStock = errors.New("insufficient stock")
+
+	// ErrSKUIsTaken states that SKU is taken by another variant.
+	ErrSKUIsTaken = errors.New("SKU is taken")
+
+	// ErrGTINIsTaken states that GTIN is taken by another variant.
+	ErrGTINIsTaken = errors.New("GTIN is taken")
+
+	// ErrUnknownCurrency states that currency has no exchange rate.
+	ErrUnknownCurrency = errors.New("currency is unknown")
+
+	// ErrInvalidExchangeRate states that exchange rate is malformed.
+	ErrInvalidExchangeRate = errors.New("invalid exchange rate")
+
+	// ErrCurrencyInUse states that currency is used by positions.
+	ErrCurrencyInUse = errors.New("currency is in use")
+
+	// ErrUnknownPosition states that store position is unknown.
+	ErrUnknownPosition = errors.New("position is unknown")
+
+	// ErrInvalidCartItem states that cart item cannot be put into cart.
+	ErrInvalidCartItem = errors.New("invalid cart item")
+
+	// ErrEmptyCart states that cart has no items to order.
+	ErrEmptyCart = errors.New("cart is empty")
+
+	// ErrCartChanged states that cart items are unavailable or their prices have changed.
+	ErrCartChanged = errors.New("cart has changed")
+
+	// ErrInvalidTransition states that order cannot be moved into requested status.
+	ErrInvalidTransition = errors.New("invalid order status transition")
+
+	// ErrPaymentDeclined states that payment was declined by provider.
+	ErrPaymentDeclined = errors.New("payment declined")
+
+	// ErrPaymentUnavailable states that payment provider is not configured.
+	ErrPaymentUnavailable = errors.New("payments are unavailable")
+
+	// ErrInvalidSignature states that payment webhook signature is invalid.
+	ErrInvalidSignature = errors.New("invalid signature")
+
+	// ErrLastOwner states that change would leave store without owner.
+	ErrLastOwner = errors.New("last owner of the store")
 )
 
 // Service provides business logic methods.
 type Service interface {
-	// GetCategories returns slice of product categories.
-	GetCategories(ctx context.Context) ([]model.Category, error)
+	// GetCategories returns page of product categories and cursor of the next page.
+	GetCategories(ctx context.Context, page model.Page) ([]model.Category, string, error)
 
 	// GetCategory returns a product category by ID.
 	GetCategory(ctx context.Context, categoryID int64) (model.Category, error)
 
+	// GetCategoryTree returns all categories arranged into a tree.
+	GetCategoryTree(ctx context.Context) ([]model.CategoryNode, error)
+
+	// GetCategorySubtree returns the category with all its descendants.
+	GetCategorySubtree(ctx context.Context, categoryID int64) (model.CategoryNode, error)
+
 	// CreateCategory creates new category.
 	CreateCategory(ctx context.Context, category model.Category) (model.Category, error)
 
@@ -42,8 +123,8 @@ type Service interface {
 	// DeleteCategory deletes category from storage.
 	DeleteCategory(ctx context.Context, categoryID int64) error
 
-	// GetStores returns slice of stores.
-	GetStores(ctx context.Context) ([]model.Store, error)
+	// GetStores returns page of stores and cursor of the next page.
+	GetStores(ctx context.Context, page model.Page) ([]model.Store, string, error)
 
 	// GetStore returns a product store by ID.
 	GetStore(ctx context.Context, storeID int64) (model.Store, error)
@@ -57,8 +138,24 @@ type Service interface {
 	// DeleteStore deletes store from storage.
 	DeleteStore(ctx context.Context, storeID int64) error
 
-	// GetProducts returns slice of products in category.
-	GetProducts(ctx context.Context, categoryID int64) ([]model.Product, error)
+	// GetStoreMembers returns members of the store.
+	GetStoreMembers(ctx context.Context, storeID int64) ([]model.StoreMember, error)
+
+	// GetStoreMember returns store member by user ID.
+	GetStoreMember(ctx context.Context, storeID, userID int64) (model.StoreMember, error)
+
+	// SetStoreMember adds registered user with the email to store members or changes role of existing member,
+	// the only owner of the store can't be demoted.
+	SetStoreMember(ctx context.Context, storeID int64, email string, role model.StoreRole) (model.StoreMember, error)
+
+	// RemoveStoreMember removes user from store members, the only owner of the store can't be removed.
+	RemoveStoreMember(ctx context.Context, storeID, userID int64) error
+
+	// GetProducts returns page of filtered products in category and cursor of the next page.
+	GetProducts(ctx context.Context, categoryID int64, filter model.ProductFilter, page model.Page) ([]model.Product, string, error)
+
+	// SearchProducts returns page of products ranked by relevance to the search query and cursor of the next page.
+	SearchProducts(ctx context.Context, search model.ProductSearch, page model.Page) ([]model.ProductSearchResult, string, error)
 
 	// GetProduct returns a product by ID.
 	GetProduct(ctx context.Context, productID int64) (model.Product, error)
@@ -72,36 +169,142 @@ type Service interface {
 	// DeleteProduct deletes product.
 	DeleteProduct(ctx context.Context, productID int64) error
 
-	// GetStorePositions returns slice of store positions.
-	GetStorePositions(ctx context.Context, storeID int64) ([]model.Position, error)
+	// AddProductImage saves image with its thumbnails and adds it to the end of product images.
+	AddProductImage(ctx context.Context, productID int64, data []byte) (model.Image, error)
+
+	// SetProductImageOrder orders product images, imageIDs must contain all product images.
+	SetProductImageOrder(ctx context.Context, productID int64, imageIDs []int64) error
+
+	// SetPrimaryProductImage moves image to the first position.
+	SetPrimaryProductImage(ctx context.Context, productID, imageID int64) error
 
-	// GetProductPositions returns slice of product positions.
-	GetProductPositions(ctx context.Context, productID int64) ([]model.Position, error)
+	// DeleteProductImage deletes product image with its thumbnails.
+	DeleteProductImage(ctx context.Context, productID, imageID int64) error
 
-	// SetPosition updates position or creates new one if it doesn't exist.
-	SetPosition(ctx context.Context, position model.Position) error
+	// GetProductVariants returns product variants along with their distinct attribute values.
+	GetProductVariants(ctx context.Context, productID int64) (model.VariantMatrix, error)
+
+	// CreateVariant creates new product variant.
+	CreateVariant(ctx context.Context, variant model.Variant) (model.Variant, error)
+
+	// UpdateVariant updates product variant.
+	UpdateVariant(ctx context.Context, variant model.Variant) error
+
+	// DeleteVariant deletes product variant along with its positions.
+	DeleteVariant(ctx context.Context, productID, variantID int64) error
+
+	// GetStorePositions returns page of store positions and cursor of the next page.
+	GetStorePositions(ctx context.Context, storeID int64, page model.Page) ([]model.Position, string, error)
+
+	// GetProductPositions returns page of filtered product positions and cursor of the next page,
+	// prices are converted to the currency unless it's empty.
+	GetProductPositions(ctx context.Context, productID int64, filter model.PositionFilter, currency string, page model.Page) ([]model.Position, string, error)
+
+	// SetPosition updates position of the variant or creates new one if it doesn't exist,
+	// positions without currency are priced in base currency, price changes are recorded on behalf of the user.
+	// Quantity is initial stock of new position, stock of existing one is changed only with AdjustStock.
+	// Availability and restock date of existing position are kept if availability is empty, new position is in stock.
+	SetPosition(ctx context.Context, position model.Position, userID int64) (model.Position, error)
+
+	// AdjustStock atomically adds delta to position quantity, quantity cannot go below zero.
+	AdjustStock(ctx context.Context, variantID, storeID, delta int64) (model.Position, error)
 
 	// DeletePosition deletes position.
-	DeletePosition(ctx context.Context, productID, storeID int64) error
+	DeletePosition(ctx context.Context, variantID, storeID int64) error
+
+	// GetPriceHistory returns price changes of product positions.
+	GetPriceHistory(ctx context.Context, productID int64, filter model.PriceHistoryFilter) ([]model.PriceChange, error)
+
+	// GetDailyPriceHistory returns daily min, average and max prices of product positions in base currency.
+	GetDailyPriceHistory(ctx context.Context, productID int64, filter model.PriceHistoryFilter) ([]model.PriceBucket, error)
+
+	// ComparePrices returns price comparison in the currency for every distinct product ID in requested order,
+	// products without offers have zero offer count, empty currency means base currency.
+	ComparePrices(ctx context.Context, productIDs []int64, currency string) ([]model.PriceSummary, error)
+
+	// GetExchangeRates returns exchange rates relative to base currency.
+	GetExchangeRates(ctx context.Context) ([]model.ExchangeRate, error)
+
+	// SetExchangeRate updates exchange rate or creates new one if it doesn't exist.
+	SetExchangeRate(ctx context.Context, rate model.ExchangeRate) error
+
+	// DeleteExchangeRate deletes exchange rate of currency which is not used by positions.
+	DeleteExchangeRate(ctx context.Context, currency string) error
+
+	// ImportExchangeRates sets exchange rates listed as <currency>,<rate> lines,
+	// empty lines and lines starting with # are ignored. Returns number of imported rates.
+	ImportExchangeRates(ctx context.Context, r io.Reader) (int, error)
+}
+
+// CartService provides shopping cart methods.
+type CartService interface {
+	// GetCart returns the cart with items re-validated against current positions,
+	// total is calculated in the currency, empty currency means base currency,
+	// zero owner results in empty cart.
+	GetCart(ctx context.Context, owner model.CartOwner, currency string) (model.Cart, error)
+
+	// SetCartItem sets quantity of the position in the cart or puts it into the cart,
+	// the item is priced at current position price.
+	SetCartItem(ctx context.Context, owner model.CartOwner, variantID, storeID, quantity int64) error
+
+	// RemoveCartItem removes the position from the cart.
+	RemoveCartItem(ctx context.Context, owner model.CartOwner, variantID, storeID int64) error
+
+	// ClearCart removes all items from the cart.
+	ClearCart(ctx context.Context, owner model.CartOwner) error
+
+	// MergeCart moves items of anonymous cart identified by token into the user cart.
+	MergeCart(ctx context.Context, token string, userID int64) error
+}
+
+// OrderService provides order placement and lifecycle methods.
+type OrderService interface {
+	// Checkout turns the user cart into pending order with total in the currency,
+	// every cart item must be available at the price it was put into cart.
+	// Empty currency means base currency.
+	Checkout(ctx context.Context, userID int64, currency string) (model.Order, error)
+
+	// GetOrders returns page of filtered orders, newest first, and cursor of the next page.
+	GetOrders(ctx context.Context, filter model.OrderFilter, page model.Page) ([]model.Order, string, error)
+
+	// GetOrder returns order with its status transitions.
+	GetOrder(ctx context.Context, orderID int64) (model.Order, error)
+
+	// TransitionOrder moves order into the status on behalf of the actor,
+	// reserved stock is released when unshipped order is cancelled or refunded,
+	// captured payments are refunded when order is refunded.
+	TransitionOrder(ctx context.Context, orderID int64, status model.OrderStatus, actorID int64) (model.Order, error)
+
+	// PayOrder charges pending order of the user with payment method,
+	// order becomes paid once the payment is captured, asynchronous payments are confirmed with webhook.
+	PayOrder(ctx context.Context, orderID, userID int64, method string) (model.Payment, error)
+
+	// HandlePaymentWebhook verifies and applies payment provider event, repeated events are ignored.
+	HandlePaymentWebhook(ctx context.Context, header http.Header, body []byte) error
 }
 
 type service struct {
 	s storage.Storage
+	m media.Storage
 }
 
 // New creates service instance.
-func New(s storage.Storage) Service {
+func New(s storage.Storage, m media.Storage) Service {
 	return &service{
 		s: s,
+		m: m,
 	}
 }
 
-func (s *service) GetCategories(ctx context.Context) ([]model.Category, error) {
-	categories, err := s.s.GetCategories(ctx)
+func (s *service) GetCategories(ctx context.Context, page model.Page) ([]model.Category, string, error) {
+	categories, next, err := s.s.GetCategories(ctx, page)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get categories: %w", err)
+		if errors.Is(err, storage.ErrInvalidCursor) {
+			return nil, "", ErrInvalidCursor
+		}
+		return nil, "", fmt.Errorf("failed to get categories: %w", err)
 	}
-	return categories, nil
+	return categories, next, nil
 }
 
 func (s *service) GetCategory(ctx context.Context, categoryID int64) (model.Category, error) {
@@ -115,18 +318,83 @@ func (s *service) GetCategory(ctx context.Context, categoryID int64) (model.Cate
 	return category, nil
 }
 
+func (s *service) GetCategoryTree(ctx context.Context) ([]model.CategoryNode, error) {
+	categories, err := s.s.GetCategoryTree(ctx, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get category tree: %w", err)
+	}
+	return buildCategoryTree(categories, 0), nil
+}
+
+func (s *service) GetCategorySubtree(ctx context.Context, categoryID int64) (model.CategoryNode, error) {
+	categories, err := s.s.GetCategoryTree(ctx, categoryID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return model.CategoryNode{}, ErrNotFound
+		}
+		return model.CategoryNode{}, fmt.Errorf("failed to get category subtree: %w", err)
+	}
+
+	for _, c := range categories {
+		if c.ID == categoryID {
+			return model.CategoryNode{Category: c, Children: buildCategoryTree(categories, c.ID)}, nil
+		}
+	}
+
+	return model.CategoryNode{}, ErrNotFound
+}
+
+// buildCategoryTree arranges categories into a tree and returns children of parentID.
+func buildCategoryTree(categories []model.Category, parentID int64) []model.CategoryNode {
+	children := make(map[int64][]model.Category, len(categories))
+	for _, c := range categories {
+		children[c.ParentID] = append(children[c.ParentID], c)
+	}
+
+	var build func(parentID int64) []model.CategoryNode
+	build = func(parentID int64) []model.CategoryNode {
+		nodes := make([]model.CategoryNode, len(children[parentID]))
+		for i, c := range children[parentID] {
+			nodes[i] = model.CategoryNode{Category: c, Children: build(c.ID)}
+		}
+		return nodes
+	}
+
+	return build(parentID)
+}
+
 func (s *service) CreateCategory(ctx context.Context, category model.Category) (model.Category, error) {
+	if err := validateSchema(category.Schema); err != nil {
+		return model.Category{}, err
+	}
+
 	c, err := s.s.CreateCategory(ctx, category)
 	if err != nil {
+		if errors.Is(err, storage.ErrUnknownCategory) {
+			return model.Category{}, ErrUnknownCategory
+		}
 		return model.Category{}, fmt.Errorf("failed to create category: %w", err)
 	}
 	return c, nil
 }
 
 func (s *service) UpdateCategory(ctx context.Context, category model.Category) error {
+	if category.ParentID == category.ID {
+		return ErrCategoryCycle
+	}
+
+	if err := validateSchema(category.Schema); err != nil {
+		return err
+	}
+
 	if err := s.s.UpdateCategory(ctx, category); err != nil {
-		if errors.Is(err, storage.ErrNotFound) {
+		switch {
+		case errors.Is(err, storage.ErrNotFound):
 			return ErrNotFound
+		case errors.Is(err, storage.ErrUnknownCategory):
+			return ErrUnknownCategory
+		case errors.Is(err, storage.ErrCategoryCycle):
+			return ErrCategoryCycle
 		}
 		return fmt.Errorf("failed to update category: %w", err)
 	}
@@ -143,12 +411,15 @@ func (s *service) DeleteCategory(ctx context.Context, categoryID int64) error {
 	return nil
 }
 
-func (s *service) GetStores(ctx context.Context) ([]model.Store, error) {
-	stores, err := s.s.GetStores(ctx)
+func (s *service) GetStores(ctx context.Context, page model.Page) ([]model.Store, string, error) {
+	stores, next, err := s.s.GetStores(ctx, page)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get stores: %w", err)
+		if errors.Is(err, storage.ErrInvalidCursor) {
+			return nil, "", ErrInvalidCursor
+		}
+		return nil, "", fmt.Errorf("failed to get stores: %w", err)
 	}
-	return stores, nil
+	return stores, next, nil
 }
 
 func (s *service) GetStore(ctx context.Context, storeID int64) (model.Store, error) {
@@ -190,12 +461,79 @@ func (s *service) DeleteStore(ctx context.Context, storeID int64) error {
 	return nil
 }
 
-func (s *service) GetProducts(ctx context.Context, categoryID int64) ([]model.Product, error) {
-	products, err := s.s.GetProducts(ctx, categoryID)
+func (s *service) GetProducts(ctx context.Context, categoryID int64, filter model.ProductFilter, page model.Page) ([]model.Product, string, error) {
+	if len(filter.Attributes) > 0 {
+		category, err := s.GetCategory(ctx, categoryID)
+		if err != nil {
+			return nil, "", err
+		}
+
+		if filter.Attributes, err = resolveAttributeFilters(category.Schema, filter.Attributes); err != nil {
+			return nil, "", err
+		}
+	}
+
+	products, next, err := s.s.GetProducts(ctx, categoryID, filter, page)
+	if err != nil {
+		if errors.Is(err, storage.ErrInvalidCursor) {
+			return nil, "", ErrInvalidCursor
+		}
+		return nil, "", fmt.Errorf("failed to get products: %w", err)
+	}
+
+	ids := make([]int64, len(products))
+	for i, p := range products {
+		ids[i] = p.ID
+	}
+
+	images, err := s.loadImages(ctx, ids)
+	if err != nil {
+		return nil, "", err
+	}
+
+	offers, err := s.loadPriceSummaries(ctx, ids, model.BaseCurrency)
+	if err != nil {
+		return nil, "", err
+	}
+
+	for i := range products {
+		products[i].Images = images[products[i].ID]
+		products[i].Offers = offers[products[i].ID]
+	}
+
+	return products, next, nil
+}
+
+func (s *service) SearchProducts(ctx context.Context, search model.ProductSearch, page model.Page) ([]model.ProductSearchResult, string, error) {
+	results, next, err := s.s.SearchProducts(ctx, search, page)
+	if err != nil {
+		if errors.Is(err, storage.ErrInvalidCursor) {
+			return nil, "", ErrInvalidCursor
+		}
+		return nil, "", fmt.Errorf("failed to search products: %w", err)
+	}
+
+	ids := make([]int64, len(results))
+	for i, r := range results {
+		ids[i] = r.Product.ID
+	}
+
+	images, err := s.loadImages(ctx, ids)
+	if err != nil {
+		return nil, "", err
+	}
+
+	offers, err := s.loadPriceSummaries(ctx, ids, model.BaseCurrency)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get products: %w", err)
+		return nil, "", err
 	}
-	return products, nil
+
+	for i := range results {
+		results[i].Product.Images = images[results[i].Product.ID]
+		results[i].Product.Offers = offers[results[i].Product.ID]
+	}
+
+	return results, next, nil
 }
 
 func (s *service) GetProduct(ctx context.Context, productID int64) (model.Product, error) {
@@ -206,10 +544,31 @@ func (s *service) GetProduct(ctx context.Context, productID int64) (model.Produc
 		}
 		return model.Product{}, fmt.Errorf("failed to get product: %w", err)
 	}
+
+	images, err := s.loadImages(ctx, []int64{productID})
+	if err != nil {
+		return model.Product{}, err
+	}
+	product.Images = images[productID]
+
+	offers, err := s.loadPriceSummaries(ctx, []int64{productID}, model.BaseCurrency)
+	if err != nil {
+		return model.Product{}, err
+	}
+	product.Offers = offers[productID]
+
+	if product.Variants, err = s.s.GetProductVariants(ctx, productID); err != nil {
+		return model.Product{}, fmt.Errorf("failed to get product variants: %w", err)
+	}
+
 	return product, nil
 }
 
 func (s *service) CreateProduct(ctx context.Context, product model.Product) (model.Product, error) {
+	if err := s.validateProduct(ctx, product); err != nil {
+		return model.Product{}, err
+	}
+
 	product, err := s.s.CreateProduct(ctx, product)
 	if err != nil {
 		if errors.Is(err, storage.ErrUnknownCategory) {
@@ -221,6 +580,10 @@ func (s *service) CreateProduct(ctx context.Context, product model.Product) (mod
 }
 
 func (s *service) UpdateProduct(ctx context.Context, product model.Product) error {
+	if err := s.validateProduct(ctx, product); err != nil {
+		return err
+	}
+
 	if err := s.s.UpdateProduct(ctx, product); err != nil {
 		switch {
 		case errors.Is(err, storage.ErrUnknownCategory):
@@ -233,48 +596,109 @@ func (s *service) UpdateProduct(ctx context.Context, product model.Product) erro
 	return nil
 }
 
+// validateProduct checks product attributes against schema of its category.
+func (s *service) validateProduct(ctx context.Context, product model.Product) error {
+	category, err := s.s.GetCategory(ctx, product.CategoryID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return ErrUnknownCategory
+		}
+		return fmt.Errorf("failed to get product category: %w", err)
+	}
+
+	return validateAttributes(category.Schema, product.Attributes)
+}
+
 func (s *service) DeleteProduct(ctx context.Context, productID int64) error {
+	// image records are deleted along with product, so files are looked up beforehand
+	images, err := s.s.GetProductImages(ctx, []int64{productID})
+	if err != nil {
+		return fmt.Errorf("failed to get product images: %w", err)
+	}
+
 	if err := s.s.DeleteProduct(ctx, productID); err != nil {
 		if errors.Is(err, storage.ErrNotFound) {
 			return ErrNotFound
 		}
 		return fmt.Errorf("failed to delete product: %w", err)
 	}
+
+	for _, img := range images {
+		if err := s.deleteImageFiles(ctx, img); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
-func (s *service) GetStorePositions(ctx context.Context, storeID int64) ([]model.Position, error) {
-	positions, err := s.s.GetStorePositions(ctx, storeID)
+func (s *service) GetStorePositions(ctx context.Context, storeID int64, page model.Page) ([]model.Position, string, error) {
+	positions, next, err := s.s.GetStorePositions(ctx, storeID, page)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get store positions: %w", err)
+		if errors.Is(err, storage.ErrInvalidCursor) {
+			return nil, "", ErrInvalidCursor
+		}
+		return nil, "", fmt.Errorf("failed to get store positions: %w", err)
 	}
-	return positions, nil
+	return positions, next, nil
 }
 
-func (s *service) GetProductPositions(ctx context.Context, productID int64) ([]model.Position, error) {
-	positions, err := s.s.GetProductPositions(ctx, productID)
+func (s *service) GetProductPositions(ctx context.Context, productID int64, filter model.PositionFilter, currency string, page model.Page) ([]model.Position, string, error) {
+	positions, next, err := s.s.GetProductPositions(ctx, productID, filter, page)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get product positions: %w", err)
+		if errors.Is(err, storage.ErrInvalidCursor) {
+			return nil, "", ErrInvalidCursor
+		}
+		return nil, "", fmt.Errorf("failed to get product positions: %w", err)
 	}
-	return positions, nil
+
+	if currency != "" {
+		if err := s.convertPositions(ctx, positions, currency); err != nil {
+			return nil, "", err
+		}
+	}
+
+	return positions, next, nil
 }
 
-func (s *service) SetPosition(ctx context.Context, position model.Position) error {
-	if err := s.s.UpsertPosition(ctx, position); err != nil {
+func (s *service) SetPosition(ctx context.Context, position model.Position, userID int64) (model.Position, error) {
+	if position.Currency == "" {
+		position.Currency = model.BaseCurrency
+	}
+
+	position, err := s.s.UpsertPosition(ctx, position, userID)
+	if err != nil {
 		switch {
-		case errors.Is(err, storage.ErrUnknownProduct):
-			return ErrUnknownProduct
+		case errors.Is(err, storage.ErrUnknownVariant):
+			return model.Position{}, ErrUnknownVariant
 		case errors.Is(err, storage.ErrUnknownStore):
-			return ErrUnknownStore
+			return model.Position{}, ErrUnknownStore
+		case errors.Is(err, storage.ErrUnknownCurrency):
+			return model.Position{}, ErrUnknownCurrency
 		}
-		return fmt.Errorf("failed to set position: %w", err)
+		return model.Position{}, fmt.Errorf("failed to set position: %w", err)
 	}
 
-	return nil
+	return position, nil
+}
+
+func (s *service) AdjustStock(ctx context.Context, variantID, storeID, delta int64) (model.Position, error) {
+	position, err := s.s.AdjustPositionStock(ctx, variantID, storeID, delta)
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrNotFound):
+			return model.Position{}, ErrNotFound
+		case errors.Is(err, storage.ErrInsufficientStock):
+			return model.Position{}, ErrInsufficientStock
+		}
+		return model.Position{}, fmt.Errorf("failed to adjust stock: %w", err)
+	}
+
+	return position, nil
 }
 
-func (s *service) DeletePosition(ctx context.Context, productID, storeID int64) error {
-	if err := s.s.DeletePosition(ctx, productID, storeID); err != nil {
+func (s *service) DeletePosition(ctx context.Context, variantID, storeID int64) error {
+	if err := s.s.DeletePosition(ctx, variantID, storeID); err != nil {
 		if errors.Is(err, storage.ErrNotFound) {
 			return ErrNotFound
 		}
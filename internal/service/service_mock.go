@@ -8,6 +8,8 @@ import (
 	context "context"
 	gomock "github.com/golang/mock/gomock"
 	model "github.com/vliubezny/gstore/internal/model"
+	io "io"
+	http "net/http"
 	reflect "reflect"
 )
 
@@ -35,18 +37,19 @@ func (m *MockService) EXPECT() *MockServiceMockRecorder {
 }
 
 // GetCategories mocks base method
-func (m *MockService) GetCategories(ctx context.Context) ([]model.Category, error) {
+func (m *MockService) GetCategories(ctx context.Context, page model.Page) ([]model.Category, string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetCategories", ctx)
+	ret := m.ctrl.Call(m, "GetCategories", ctx, page)
 	ret0, _ := ret[0].([]model.Category)
-	ret1, _ := ret[1].(error)
-	return ret0, ret1
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
 }
 
 // GetCategories indicates an expected call of GetCategories
-func (mr *MockServiceMockRecorder) GetCategories(ctx interface{}) *gomock.Call {
+func (mr *MockServiceMockRecorder) GetCategories(ctx, page interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCategories", reflect.TypeOf((*MockService)(nil).GetCategories), ctx)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCategories", reflect.TypeOf((*MockService)(nil).GetCategories), ctx, page)
 }
 
 // GetCategory mocks base method
@@ -64,6 +67,36 @@ func (mr *MockServiceMockRecorder) GetCategory(ctx, categoryID interface{}) *gom
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCategory", reflect.TypeOf((*MockService)(nil).GetCategory), ctx, categoryID)
 }
 
+// GetCategoryTree mocks base method
+func (m *MockService) GetCategoryTree(ctx context.Context) ([]model.CategoryNode, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCategoryTree", ctx)
+	ret0, _ := ret[0].([]model.CategoryNode)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCategoryTree indicates an expected call of GetCategoryTree
+func (mr *MockServiceMockRecorder) GetCategoryTree(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCategoryTree", reflect.TypeOf((*MockService)(nil).GetCategoryTree), ctx)
+}
+
+// GetCategorySubtree mocks base method
+func (m *MockService) GetCategorySubtree(ctx context.Context, categoryID int64) (model.CategoryNode, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCategorySubtree", ctx, categoryID)
+	ret0, _ := ret[0].(model.CategoryNode)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCategorySubtree indicates an expected call of GetCategorySubtree
+func (mr *MockServiceMockRecorder) GetCategorySubtree(ctx, categoryID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCategorySubtree", reflect.TypeOf((*MockService)(nil).GetCategorySubtree), ctx, categoryID)
+}
+
 // CreateCategory mocks base method
 func (m *MockService) CreateCategory(ctx context.Context, category model.Category) (model.Category, error) {
 	m.ctrl.T.Helper()
@@ -108,18 +141,19 @@ func (mr *MockServiceMockRecorder) DeleteCategory(ctx, categoryID interface{}) *
 }
 
 // GetStores mocks base method
-func (m *MockService) GetStores(ctx context.Context) ([]model.Store, error) {
+func (m *MockService) GetStores(ctx context.Context, page model.Page) ([]model.Store, string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetStores", ctx)
+	ret := m.ctrl.Call(m, "GetStores", ctx, page)
 	ret0, _ := ret[0].([]model.Store)
-	ret1, _ := ret[1].(error)
-	return ret0, ret1
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
 }
 
 // GetStores indicates an expected call of GetStores
-func (mr *MockServiceMockRecorder) GetStores(ctx interface{}) *gomock.Call {
+func (mr *MockServiceMockRecorder) GetStores(ctx, page interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetStores", reflect.TypeOf((*MockService)(nil).GetStores), ctx)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetStores", reflect.TypeOf((*MockService)(nil).GetStores), ctx, page)
 }
 
 // GetStore mocks base method
@@ -180,19 +214,95 @@ func (mr *MockServiceMockRecorder) DeleteStore(ctx, storeID interface{}) *gomock
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteStore", reflect.TypeOf((*MockService)(nil).DeleteStore), ctx, storeID)
 }
 
-// GetProducts mocks base method
-func (m *MockService) GetProducts(ctx context.Context, categoryID int64) ([]model.Product, error) {
+// GetStoreMembers mocks base method
+func (m *MockService) GetStoreMembers(ctx context.Context, storeID int64) ([]model.StoreMember, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetProducts", ctx, categoryID)
-	ret0, _ := ret[0].([]model.Product)
+	ret := m.ctrl.Call(m, "GetStoreMembers", ctx, storeID)
+	ret0, _ := ret[0].([]model.StoreMember)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetStoreMembers indicates an expected call of GetStoreMembers
+func (mr *MockServiceMockRecorder) GetStoreMembers(ctx, storeID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetStoreMembers", reflect.TypeOf((*MockService)(nil).GetStoreMembers), ctx, storeID)
+}
+
+// GetStoreMember mocks base method
+func (m *MockService) GetStoreMember(ctx context.Context, storeID, userID int64) (model.StoreMember, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetStoreMember", ctx, storeID, userID)
+	ret0, _ := ret[0].(model.StoreMember)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
+// GetStoreMember indicates an expected call of GetStoreMember
+func (mr *MockServiceMockRecorder) GetStoreMember(ctx, storeID, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetStoreMember", reflect.TypeOf((*MockService)(nil).GetStoreMember), ctx, storeID, userID)
+}
+
+// SetStoreMember mocks base method
+func (m *MockService) SetStoreMember(ctx context.Context, storeID int64, email string, role model.StoreRole) (model.StoreMember, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetStoreMember", ctx, storeID, email, role)
+	ret0, _ := ret[0].(model.StoreMember)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SetStoreMember indicates an expected call of SetStoreMember
+func (mr *MockServiceMockRecorder) SetStoreMember(ctx, storeID, email, role interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetStoreMember", reflect.TypeOf((*MockService)(nil).SetStoreMember), ctx, storeID, email, role)
+}
+
+// RemoveStoreMember mocks base method
+func (m *MockService) RemoveStoreMember(ctx context.Context, storeID, userID int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveStoreMember", ctx, storeID, userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RemoveStoreMember indicates an expected call of RemoveStoreMember
+func (mr *MockServiceMockRecorder) RemoveStoreMember(ctx, storeID, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveStoreMember", reflect.TypeOf((*MockService)(nil).RemoveStoreMember), ctx, storeID, userID)
+}
+
+// GetProducts mocks base method
+func (m *MockService) GetProducts(ctx context.Context, categoryID int64, filter model.ProductFilter, page model.Page) ([]model.Product, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetProducts", ctx, categoryID, filter, page)
+	ret0, _ := ret[0].([]model.Product)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
 // GetProducts indicates an expected call of GetProducts
-func (mr *MockServiceMockRecorder) GetProducts(ctx, categoryID interface{}) *gomock.Call {
+func (mr *MockServiceMockRecorder) GetProducts(ctx, categoryID, filter, page interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetProducts", reflect.TypeOf((*MockService)(nil).GetProducts), ctx, categoryID, filter, page)
+}
+
+// SearchProducts mocks base method
+func (m *MockService) SearchProducts(ctx context.Context, search model.ProductSearch, page model.Page) ([]model.ProductSearchResult, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SearchProducts", ctx, search, page)
+	ret0, _ := ret[0].([]model.ProductSearchResult)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// SearchProducts indicates an expected call of SearchProducts
+func (mr *MockServiceMockRecorder) SearchProducts(ctx, search, page interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetProducts", reflect.TypeOf((*MockService)(nil).GetProducts), ctx, categoryID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SearchProducts", reflect.TypeOf((*MockService)(nil).SearchProducts), ctx, search, page)
 }
 
 // GetProduct mocks base method
@@ -253,60 +363,503 @@ func (mr *MockServiceMockRecorder) DeleteProduct(ctx, productID interface{}) *go
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteProduct", reflect.TypeOf((*MockService)(nil).DeleteProduct), ctx, productID)
 }
 
-// GetStorePositions mocks base method
-func (m *MockService) GetStorePositions(ctx context.Context, storeID int64) ([]model.Position, error) {
+// AddProductImage mocks base method
+func (m *MockService) AddProductImage(ctx context.Context, productID int64, data []byte) (model.Image, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetStorePositions", ctx, storeID)
-	ret0, _ := ret[0].([]model.Position)
+	ret := m.ctrl.Call(m, "AddProductImage", ctx, productID, data)
+	ret0, _ := ret[0].(model.Image)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
+// AddProductImage indicates an expected call of AddProductImage
+func (mr *MockServiceMockRecorder) AddProductImage(ctx, productID, data interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddProductImage", reflect.TypeOf((*MockService)(nil).AddProductImage), ctx, productID, data)
+}
+
+// SetProductImageOrder mocks base method
+func (m *MockService) SetProductImageOrder(ctx context.Context, productID int64, imageIDs []int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetProductImageOrder", ctx, productID, imageIDs)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetProductImageOrder indicates an expected call of SetProductImageOrder
+func (mr *MockServiceMockRecorder) SetProductImageOrder(ctx, productID, imageIDs interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetProductImageOrder", reflect.TypeOf((*MockService)(nil).SetProductImageOrder), ctx, productID, imageIDs)
+}
+
+// SetPrimaryProductImage mocks base method
+func (m *MockService) SetPrimaryProductImage(ctx context.Context, productID, imageID int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetPrimaryProductImage", ctx, productID, imageID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetPrimaryProductImage indicates an expected call of SetPrimaryProductImage
+func (mr *MockServiceMockRecorder) SetPrimaryProductImage(ctx, productID, imageID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetPrimaryProductImage", reflect.TypeOf((*MockService)(nil).SetPrimaryProductImage), ctx, productID, imageID)
+}
+
+// DeleteProductImage mocks base method
+func (m *MockService) DeleteProductImage(ctx context.Context, productID, imageID int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteProductImage", ctx, productID, imageID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteProductImage indicates an expected call of DeleteProductImage
+func (mr *MockServiceMockRecorder) DeleteProductImage(ctx, productID, imageID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteProductImage", reflect.TypeOf((*MockService)(nil).DeleteProductImage), ctx, productID, imageID)
+}
+
+// GetProductVariants mocks base method
+func (m *MockService) GetProductVariants(ctx context.Context, productID int64) (model.VariantMatrix, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetProductVariants", ctx, productID)
+	ret0, _ := ret[0].(model.VariantMatrix)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetProductVariants indicates an expected call of GetProductVariants
+func (mr *MockServiceMockRecorder) GetProductVariants(ctx, productID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetProductVariants", reflect.TypeOf((*MockService)(nil).GetProductVariants), ctx, productID)
+}
+
+// CreateVariant mocks base method
+func (m *MockService) CreateVariant(ctx context.Context, variant model.Variant) (model.Variant, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateVariant", ctx, variant)
+	ret0, _ := ret[0].(model.Variant)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateVariant indicates an expected call of CreateVariant
+func (mr *MockServiceMockRecorder) CreateVariant(ctx, variant interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateVariant", reflect.TypeOf((*MockService)(nil).CreateVariant), ctx, variant)
+}
+
+// UpdateVariant mocks base method
+func (m *MockService) UpdateVariant(ctx context.Context, variant model.Variant) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateVariant", ctx, variant)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateVariant indicates an expected call of UpdateVariant
+func (mr *MockServiceMockRecorder) UpdateVariant(ctx, variant interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateVariant", reflect.TypeOf((*MockService)(nil).UpdateVariant), ctx, variant)
+}
+
+// DeleteVariant mocks base method
+func (m *MockService) DeleteVariant(ctx context.Context, productID, variantID int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteVariant", ctx, productID, variantID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteVariant indicates an expected call of DeleteVariant
+func (mr *MockServiceMockRecorder) DeleteVariant(ctx, productID, variantID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteVariant", reflect.TypeOf((*MockService)(nil).DeleteVariant), ctx, productID, variantID)
+}
+
+// GetStorePositions mocks base method
+func (m *MockService) GetStorePositions(ctx context.Context, storeID int64, page model.Page) ([]model.Position, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetStorePositions", ctx, storeID, page)
+	ret0, _ := ret[0].([]model.Position)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
 // GetStorePositions indicates an expected call of GetStorePositions
-func (mr *MockServiceMockRecorder) GetStorePositions(ctx, storeID interface{}) *gomock.Call {
+func (mr *MockServiceMockRecorder) GetStorePositions(ctx, storeID, page interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetStorePositions", reflect.TypeOf((*MockService)(nil).GetStorePositions), ctx, storeID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetStorePositions", reflect.TypeOf((*MockService)(nil).GetStorePositions), ctx, storeID, page)
 }
 
 // GetProductPositions mocks base method
-func (m *MockService) GetProductPositions(ctx context.Context, productID int64) ([]model.Position, error) {
+func (m *MockService) GetProductPositions(ctx context.Context, productID int64, filter model.PositionFilter, currency string, page model.Page) ([]model.Position, string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetProductPositions", ctx, productID)
+	ret := m.ctrl.Call(m, "GetProductPositions", ctx, productID, filter, currency, page)
 	ret0, _ := ret[0].([]model.Position)
-	ret1, _ := ret[1].(error)
-	return ret0, ret1
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
 }
 
 // GetProductPositions indicates an expected call of GetProductPositions
-func (mr *MockServiceMockRecorder) GetProductPositions(ctx, productID interface{}) *gomock.Call {
+func (mr *MockServiceMockRecorder) GetProductPositions(ctx, productID, filter, currency, page interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetProductPositions", reflect.TypeOf((*MockService)(nil).GetProductPositions), ctx, productID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetProductPositions", reflect.TypeOf((*MockService)(nil).GetProductPositions), ctx, productID, filter, currency, page)
 }
 
 // SetPosition mocks base method
-func (m *MockService) SetPosition(ctx context.Context, position model.Position) error {
+func (m *MockService) SetPosition(ctx context.Context, position model.Position, userID int64) (model.Position, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SetPosition", ctx, position)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret := m.ctrl.Call(m, "SetPosition", ctx, position, userID)
+	ret0, _ := ret[0].(model.Position)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // SetPosition indicates an expected call of SetPosition
-func (mr *MockServiceMockRecorder) SetPosition(ctx, position interface{}) *gomock.Call {
+func (mr *MockServiceMockRecorder) SetPosition(ctx, position, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetPosition", reflect.TypeOf((*MockService)(nil).SetPosition), ctx, position, userID)
+}
+
+// AdjustStock mocks base method
+func (m *MockService) AdjustStock(ctx context.Context, variantID, storeID, delta int64) (model.Position, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AdjustStock", ctx, variantID, storeID, delta)
+	ret0, _ := ret[0].(model.Position)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AdjustStock indicates an expected call of AdjustStock
+func (mr *MockServiceMockRecorder) AdjustStock(ctx, variantID, storeID, delta interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetPosition", reflect.TypeOf((*MockService)(nil).SetPosition), ctx, position)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AdjustStock", reflect.TypeOf((*MockService)(nil).AdjustStock), ctx, variantID, storeID, delta)
 }
 
 // DeletePosition mocks base method
-func (m *MockService) DeletePosition(ctx context.Context, productID, storeID int64) error {
+func (m *MockService) DeletePosition(ctx context.Context, variantID, storeID int64) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeletePosition", ctx, productID, storeID)
+	ret := m.ctrl.Call(m, "DeletePosition", ctx, variantID, storeID)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // DeletePosition indicates an expected call of DeletePosition
-func (mr *MockServiceMockRecorder) DeletePosition(ctx, productID, storeID interface{}) *gomock.Call {
+func (mr *MockServiceMockRecorder) DeletePosition(ctx, variantID, storeID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeletePosition", reflect.TypeOf((*MockService)(nil).DeletePosition), ctx, variantID, storeID)
+}
+
+// GetPriceHistory mocks base method
+func (m *MockService) GetPriceHistory(ctx context.Context, productID int64, filter model.PriceHistoryFilter) ([]model.PriceChange, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPriceHistory", ctx, productID, filter)
+	ret0, _ := ret[0].([]model.PriceChange)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPriceHistory indicates an expected call of GetPriceHistory
+func (mr *MockServiceMockRecorder) GetPriceHistory(ctx, productID, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPriceHistory", reflect.TypeOf((*MockService)(nil).GetPriceHistory), ctx, productID, filter)
+}
+
+// GetDailyPriceHistory mocks base method
+func (m *MockService) GetDailyPriceHistory(ctx context.Context, productID int64, filter model.PriceHistoryFilter) ([]model.PriceBucket, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDailyPriceHistory", ctx, productID, filter)
+	ret0, _ := ret[0].([]model.PriceBucket)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDailyPriceHistory indicates an expected call of GetDailyPriceHistory
+func (mr *MockServiceMockRecorder) GetDailyPriceHistory(ctx, productID, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDailyPriceHistory", reflect.TypeOf((*MockService)(nil).GetDailyPriceHistory), ctx, productID, filter)
+}
+
+// ComparePrices mocks base method
+func (m *MockService) ComparePrices(ctx context.Context, productIDs []int64, currency string) ([]model.PriceSummary, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ComparePrices", ctx, productIDs, currency)
+	ret0, _ := ret[0].([]model.PriceSummary)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ComparePrices indicates an expected call of ComparePrices
+func (mr *MockServiceMockRecorder) ComparePrices(ctx, productIDs, currency interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ComparePrices", reflect.TypeOf((*MockService)(nil).ComparePrices), ctx, productIDs, currency)
+}
+
+// GetExchangeRates mocks base method
+func (m *MockService) GetExchangeRates(ctx context.Context) ([]model.ExchangeRate, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetExchangeRates", ctx)
+	ret0, _ := ret[0].([]model.ExchangeRate)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetExchangeRates indicates an expected call of GetExchangeRates
+func (mr *MockServiceMockRecorder) GetExchangeRates(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetExchangeRates", reflect.TypeOf((*MockService)(nil).GetExchangeRates), ctx)
+}
+
+// SetExchangeRate mocks base method
+func (m *MockService) SetExchangeRate(ctx context.Context, rate model.ExchangeRate) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetExchangeRate", ctx, rate)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetExchangeRate indicates an expected call of SetExchangeRate
+func (mr *MockServiceMockRecorder) SetExchangeRate(ctx, rate interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetExchangeRate", reflect.TypeOf((*MockService)(nil).SetExchangeRate), ctx, rate)
+}
+
+// DeleteExchangeRate mocks base method
+func (m *MockService) DeleteExchangeRate(ctx context.Context, currency string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteExchangeRate", ctx, currency)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteExchangeRate indicates an expected call of DeleteExchangeRate
+func (mr *MockServiceMockRecorder) DeleteExchangeRate(ctx, currency interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteExchangeRate", reflect.TypeOf((*MockService)(nil).DeleteExchangeRate), ctx, currency)
+}
+
+// ImportExchangeRates mocks base method
+func (m *MockService) ImportExchangeRates(ctx context.Context, r io.Reader) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ImportExchangeRates", ctx, r)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ImportExchangeRates indicates an expected call of ImportExchangeRates
+func (mr *MockServiceMockRecorder) ImportExchangeRates(ctx, r interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ImportExchangeRates", reflect.TypeOf((*MockService)(nil).ImportExchangeRates), ctx, r)
+}
+
+// MockCartService is a mock of CartService interface
+type MockCartService struct {
+	ctrl     *gomock.Controller
+	recorder *MockCartServiceMockRecorder
+}
+
+// MockCartServiceMockRecorder is the mock recorder for MockCartService
+type MockCartServiceMockRecorder struct {
+	mock *MockCartService
+}
+
+// NewMockCartService creates a new mock instance
+func NewMockCartService(ctrl *gomock.Controller) *MockCartService {
+	mock := &MockCartService{ctrl: ctrl}
+	mock.recorder = &MockCartServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockCartService) EXPECT() *MockCartServiceMockRecorder {
+	return m.recorder
+}
+
+// GetCart mocks base method
+func (m *MockCartService) GetCart(ctx context.Context, owner model.CartOwner, currency string) (model.Cart, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCart", ctx, owner, currency)
+	ret0, _ := ret[0].(model.Cart)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCart indicates an expected call of GetCart
+func (mr *MockCartServiceMockRecorder) GetCart(ctx, owner, currency interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCart", reflect.TypeOf((*MockCartService)(nil).GetCart), ctx, owner, currency)
+}
+
+// SetCartItem mocks base method
+func (m *MockCartService) SetCartItem(ctx context.Context, owner model.CartOwner, variantID, storeID, quantity int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetCartItem", ctx, owner, variantID, storeID, quantity)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetCartItem indicates an expected call of SetCartItem
+func (mr *MockCartServiceMockRecorder) SetCartItem(ctx, owner, variantID, storeID, quantity interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetCartItem", reflect.TypeOf((*MockCartService)(nil).SetCartItem), ctx, owner, variantID, storeID, quantity)
+}
+
+// RemoveCartItem mocks base method
+func (m *MockCartService) RemoveCartItem(ctx context.Context, owner model.CartOwner, variantID, storeID int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveCartItem", ctx, owner, variantID, storeID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RemoveCartItem indicates an expected call of RemoveCartItem
+func (mr *MockCartServiceMockRecorder) RemoveCartItem(ctx, owner, variantID, storeID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveCartItem", reflect.TypeOf((*MockCartService)(nil).RemoveCartItem), ctx, owner, variantID, storeID)
+}
+
+// ClearCart mocks base method
+func (m *MockCartService) ClearCart(ctx context.Context, owner model.CartOwner) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClearCart", ctx, owner)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ClearCart indicates an expected call of ClearCart
+func (mr *MockCartServiceMockRecorder) ClearCart(ctx, owner interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClearCart", reflect.TypeOf((*MockCartService)(nil).ClearCart), ctx, owner)
+}
+
+// MergeCart mocks base method
+func (m *MockCartService) MergeCart(ctx context.Context, token string, userID int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MergeCart", ctx, token, userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MergeCart indicates an expected call of MergeCart
+func (mr *MockCartServiceMockRecorder) MergeCart(ctx, token, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MergeCart", reflect.TypeOf((*MockCartService)(nil).MergeCart), ctx, token, userID)
+}
+
+// MockOrderService is a mock of OrderService interface
+type MockOrderService struct {
+	ctrl     *gomock.Controller
+	recorder *MockOrderServiceMockRecorder
+}
+
+// MockOrderServiceMockRecorder is the mock recorder for MockOrderService
+type MockOrderServiceMockRecorder struct {
+	mock *MockOrderService
+}
+
+// NewMockOrderService creates a new mock instance
+func NewMockOrderService(ctrl *gomock.Controller) *MockOrderService {
+	mock := &MockOrderService{ctrl: ctrl}
+	mock.recorder = &MockOrderServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockOrderService) EXPECT() *MockOrderServiceMockRecorder {
+	return m.recorder
+}
+
+// Checkout mocks base method
+func (m *MockOrderService) Checkout(ctx context.Context, userID int64, currency string) (model.Order, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Checkout", ctx, userID, currency)
+	ret0, _ := ret[0].(model.Order)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Checkout indicates an expected call of Checkout
+func (mr *MockOrderServiceMockRecorder) Checkout(ctx, userID, currency interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Checkout", reflect.TypeOf((*MockOrderService)(nil).Checkout), ctx, userID, currency)
+}
+
+// GetOrders mocks base method
+func (m *MockOrderService) GetOrders(ctx context.Context, filter model.OrderFilter, page model.Page) ([]model.Order, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOrders", ctx, filter, page)
+	ret0, _ := ret[0].([]model.Order)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// GetOrders indicates an expected call of GetOrders
+func (mr *MockOrderServiceMockRecorder) GetOrders(ctx, filter, page interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrders", reflect.TypeOf((*MockOrderService)(nil).GetOrders), ctx, filter, page)
+}
+
+// GetOrder mocks base method
+func (m *MockOrderService) GetOrder(ctx context.Context, orderID int64) (model.Order, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOrder", ctx, orderID)
+	ret0, _ := ret[0].(model.Order)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOrder indicates an expected call of GetOrder
+func (mr *MockOrderServiceMockRecorder) GetOrder(ctx, orderID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrder", reflect.TypeOf((*MockOrderService)(nil).GetOrder), ctx, orderID)
+}
+
+// TransitionOrder mocks base method
+func (m *MockOrderService) TransitionOrder(ctx context.Context, orderID int64, status model.OrderStatus, actorID int64) (model.Order, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TransitionOrder", ctx, orderID, status, actorID)
+	ret0, _ := ret[0].(model.Order)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TransitionOrder indicates an expected call of TransitionOrder
+func (mr *MockOrderServiceMockRecorder) TransitionOrder(ctx, orderID, status, actorID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TransitionOrder", reflect.TypeOf((*MockOrderService)(nil).TransitionOrder), ctx, orderID, status, actorID)
+}
+
+// PayOrder mocks base method
+func (m *MockOrderService) PayOrder(ctx context.Context, orderID, userID int64, method string) (model.Payment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PayOrder", ctx, orderID, userID, method)
+	ret0, _ := ret[0].(model.Payment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PayOrder indicates an expected call of PayOrder
+func (mr *MockOrderServiceMockRecorder) PayOrder(ctx, orderID, userID, method interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PayOrder", reflect.TypeOf((*MockOrderService)(nil).PayOrder), ctx, orderID, userID, method)
+}
+
+// HandlePaymentWebhook mocks base method
+func (m *MockOrderService) HandlePaymentWebhook(ctx context.Context, header http.Header, body []byte) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HandlePaymentWebhook", ctx, header, body)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// HandlePaymentWebhook indicates an expected call of HandlePaymentWebhook
+func (mr *MockOrderServiceMockRecorder) HandlePaymentWebhook(ctx, header, body interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeletePosition", reflect.TypeOf((*MockService)(nil).DeletePosition), ctx, productID, storeID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HandlePaymentWebhook", reflect.TypeOf((*MockOrderService)(nil).HandlePaymentWebhook), ctx, header, body)
 }
@@ -5,17 +5,32 @@ import (
 	"errors"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
+	"github.com/vliubezny/gstore/internal/media"
 	"github.com/vliubezny/gstore/internal/model"
 	"github.com/vliubezny/gstore/internal/storage"
 )
 
 var (
-	ctx     = context.Background()
-	errTest = errors.New("test")
+	ctx      = context.Background()
+	errTest  = errors.New("test")
+	errSkip  = errors.New("skip")
+	testPage = model.Page{Limit: 10, Cursor: "cursor"}
+
+	testFilter = model.ProductFilter{Name: "phone", StoreID: 1, Sort: model.SortByPrice, Desc: true}
+
+	testSchemaCategory = model.Category{
+		ID:   1,
+		Name: "Laptops",
+		Schema: []model.Attribute{
+			{Name: "ram", Type: model.AttributeNumber, Unit: "GB", Required: true},
+			{Name: "color", Type: model.AttributeEnum, Values: []string{"black", "silver"}},
+		},
+	}
 )
 
 func TestService_GetCategories(t *testing.T) {
@@ -29,13 +44,14 @@ func TestService_GetCategories(t *testing.T) {
 	}
 
 	st := storage.NewMockStorage(ctrl)
-	st.EXPECT().GetCategories(ctx).Return(categories, nil)
+	st.EXPECT().GetCategories(ctx, testPage).Return(categories, "next", nil)
 
-	s := New(st)
+	s := New(st, nil)
 
-	cs, err := s.GetCategories(ctx)
+	cs, next, err := s.GetCategories(ctx, testPage)
 	assert.NoError(t, err)
 	assert.Equal(t, categories, cs)
+	assert.Equal(t, "next", next)
 }
 
 func TestService_GetCategories_Err(t *testing.T) {
@@ -43,16 +59,29 @@ func TestService_GetCategories_Err(t *testing.T) {
 	defer ctrl.Finish()
 
 	st := storage.NewMockStorage(ctrl)
-	st.EXPECT().GetCategories(ctx).Return(nil, errTest)
+	st.EXPECT().GetCategories(ctx, testPage).Return(nil, "", errTest)
 
-	s := New(st)
+	s := New(st, nil)
 
-	cs, err := s.GetCategories(ctx)
+	cs, _, err := s.GetCategories(ctx, testPage)
 	assert.Error(t, err)
 	assert.True(t, errors.Is(err, errTest))
 	assert.Nil(t, cs)
 }
 
+func TestService_GetCategories_ErrInvalidCursor(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	st := storage.NewMockStorage(ctrl)
+	st.EXPECT().GetCategories(ctx, testPage).Return(nil, "", storage.ErrInvalidCursor)
+
+	s := New(st, nil)
+
+	_, _, err := s.GetCategories(ctx, testPage)
+	assert.True(t, errors.Is(err, ErrInvalidCursor), fmt.Sprintf("wanted %s got %s", ErrInvalidCursor, err))
+}
+
 func TestService_GetCategory(t *testing.T) {
 	testCases := []struct {
 		desc      string
@@ -93,7 +122,7 @@ func TestService_GetCategory(t *testing.T) {
 			st := storage.NewMockStorage(ctrl)
 			st.EXPECT().GetCategory(ctx, id).Return(tC.rCategory, tC.rErr)
 
-			s := New(st)
+			s := New(st, nil)
 
 			category, err := s.GetCategory(ctx, id)
 			assert.True(t, errors.Is(err, tC.err), fmt.Sprintf("wanted %s got %s", tC.err, err))
@@ -102,6 +131,115 @@ func TestService_GetCategory(t *testing.T) {
 	}
 }
 
+func TestService_GetCategoryTree(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	st := storage.NewMockStorage(ctrl)
+	st.EXPECT().GetCategoryTree(ctx, int64(0)).Return([]model.Category{
+		{ID: 1, Name: "Electronics"},
+		{ID: 2, Name: "Computers"},
+		{ID: 3, Name: "Phones", ParentID: 1},
+		{ID: 4, Name: "Laptops", ParentID: 2},
+		{ID: 5, Name: "Smartphones", ParentID: 3},
+	}, nil)
+
+	s := New(st, nil)
+
+	tree, err := s.GetCategoryTree(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []model.CategoryNode{
+		{
+			Category: model.Category{ID: 1, Name: "Electronics"},
+			Children: []model.CategoryNode{
+				{
+					Category: model.Category{ID: 3, Name: "Phones", ParentID: 1},
+					Children: []model.CategoryNode{
+						{Category: model.Category{ID: 5, Name: "Smartphones", ParentID: 3}, Children: []model.CategoryNode{}},
+					},
+				},
+			},
+		},
+		{
+			Category: model.Category{ID: 2, Name: "Computers"},
+			Children: []model.CategoryNode{
+				{Category: model.Category{ID: 4, Name: "Laptops", ParentID: 2}, Children: []model.CategoryNode{}},
+			},
+		},
+	}, tree)
+}
+
+func TestService_GetCategoryTree_Err(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	st := storage.NewMockStorage(ctrl)
+	st.EXPECT().GetCategoryTree(ctx, int64(0)).Return(nil, errTest)
+
+	s := New(st, nil)
+
+	tree, err := s.GetCategoryTree(ctx)
+	assert.True(t, errors.Is(err, errTest), fmt.Sprintf("wanted %s got %s", errTest, err))
+	assert.Nil(t, tree)
+}
+
+func TestService_GetCategorySubtree(t *testing.T) {
+	testCases := []struct {
+		desc        string
+		rCategories []model.Category
+		rErr        error
+		node        model.CategoryNode
+		err         error
+	}{
+		{
+			desc: "success",
+			rCategories: []model.Category{
+				{ID: 3, Name: "Phones", ParentID: 1},
+				{ID: 5, Name: "Smartphones", ParentID: 3},
+			},
+			rErr: nil,
+			node: model.CategoryNode{
+				Category: model.Category{ID: 3, Name: "Phones", ParentID: 1},
+				Children: []model.CategoryNode{
+					{Category: model.Category{ID: 5, Name: "Smartphones", ParentID: 3}, Children: []model.CategoryNode{}},
+				},
+			},
+			err: nil,
+		},
+		{
+			desc:        "ErrNotFound",
+			rCategories: nil,
+			rErr:        storage.ErrNotFound,
+			node:        model.CategoryNode{},
+			err:         ErrNotFound,
+		},
+		{
+			desc:        "unexpected error",
+			rCategories: nil,
+			rErr:        errTest,
+			node:        model.CategoryNode{},
+			err:         errTest,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			id := int64(3)
+
+			st := storage.NewMockStorage(ctrl)
+			st.EXPECT().GetCategoryTree(ctx, id).Return(tC.rCategories, tC.rErr)
+
+			s := New(st, nil)
+
+			node, err := s.GetCategorySubtree(ctx, id)
+			assert.True(t, errors.Is(err, tC.err), fmt.Sprintf("wanted %s got %s", tC.err, err))
+			assert.Equal(t, tC.node, node)
+		})
+	}
+}
+
 func TestService_CreateCategory(t *testing.T) {
 	testCases := []struct {
 		desc      string
@@ -117,6 +255,20 @@ func TestService_CreateCategory(t *testing.T) {
 			category:  model.Category{Name: "Test1"},
 			err:       nil,
 		},
+		{
+			desc:      "ErrUnknownCategory",
+			rCategory: model.Category{},
+			rErr:      storage.ErrUnknownCategory,
+			category:  model.Category{Name: "Test1", ParentID: 100},
+			err:       ErrUnknownCategory,
+		},
+		{
+			desc:      "ErrInvalidSchema",
+			rCategory: model.Category{},
+			rErr:      errSkip,
+			category:  model.Category{Name: "Test1", Schema: []model.Attribute{{Name: "ram", Type: "size"}}},
+			err:       ErrInvalidSchema,
+		},
 		{
 			desc:      "unexpected error",
 			rCategory: model.Category{},
@@ -131,9 +283,11 @@ func TestService_CreateCategory(t *testing.T) {
 			defer ctrl.Finish()
 
 			st := storage.NewMockStorage(ctrl)
-			st.EXPECT().CreateCategory(ctx, tC.category).Return(tC.rCategory, tC.rErr)
+			if tC.rErr != errSkip {
+				st.EXPECT().CreateCategory(ctx, tC.category).Return(tC.rCategory, tC.rErr)
+			}
 
-			s := New(st)
+			s := New(st, nil)
 
 			c, err := s.CreateCategory(ctx, tC.category)
 			assert.True(t, errors.Is(err, tC.err), fmt.Sprintf("wanted %s got %s", tC.err, err))
@@ -161,6 +315,24 @@ func TestService_UpdateCategory(t *testing.T) {
 			category: model.Category{ID: 1, Name: "Test1"},
 			err:      ErrNotFound,
 		},
+		{
+			desc:     "ErrUnknownCategory",
+			rErr:     storage.ErrUnknownCategory,
+			category: model.Category{ID: 1, Name: "Test1", ParentID: 100},
+			err:      ErrUnknownCategory,
+		},
+		{
+			desc:     "ErrCategoryCycle",
+			rErr:     storage.ErrCategoryCycle,
+			category: model.Category{ID: 1, Name: "Test1", ParentID: 2},
+			err:      ErrCategoryCycle,
+		},
+		{
+			desc:     "ErrCategoryCycle - self parent",
+			rErr:     errSkip,
+			category: model.Category{ID: 1, Name: "Test1", ParentID: 1},
+			err:      ErrCategoryCycle,
+		},
 		{
 			desc:     "unexpected error",
 			rErr:     errTest,
@@ -174,9 +346,11 @@ func TestService_UpdateCategory(t *testing.T) {
 			defer ctrl.Finish()
 
 			st := storage.NewMockStorage(ctrl)
-			st.EXPECT().UpdateCategory(ctx, tC.category).Return(tC.rErr)
+			if tC.rErr != errSkip {
+				st.EXPECT().UpdateCategory(ctx, tC.category).Return(tC.rErr)
+			}
 
-			s := New(st)
+			s := New(st, nil)
 
 			err := s.UpdateCategory(ctx, tC.category)
 			assert.True(t, errors.Is(err, tC.err), fmt.Sprintf("wanted %s got %s", tC.err, err))
@@ -216,7 +390,7 @@ func TestService_DeleteCategory(t *testing.T) {
 			st := storage.NewMockStorage(ctrl)
 			st.EXPECT().DeleteCategory(ctx, id).Return(tC.rErr)
 
-			s := New(st)
+			s := New(st, nil)
 
 			err := s.DeleteCategory(ctx, id)
 			assert.True(t, errors.Is(err, tC.err), fmt.Sprintf("wanted %s got %s", tC.err, err))
@@ -251,6 +425,13 @@ func TestService_GetStores(t *testing.T) {
 			stores:  nil,
 			err:     errTest,
 		},
+		{
+			desc:    "ErrInvalidCursor",
+			rStores: nil,
+			rErr:    storage.ErrInvalidCursor,
+			stores:  nil,
+			err:     ErrInvalidCursor,
+		},
 	}
 	for _, tC := range testCases {
 		t.Run(tC.desc, func(t *testing.T) {
@@ -258,13 +439,16 @@ func TestService_GetStores(t *testing.T) {
 			defer ctrl.Finish()
 
 			st := storage.NewMockStorage(ctrl)
-			st.EXPECT().GetStores(ctx).Return(tC.rStores, tC.rErr)
+			st.EXPECT().GetStores(ctx, testPage).Return(tC.rStores, "next", tC.rErr)
 
-			s := New(st)
+			s := New(st, nil)
 
-			stores, err := s.GetStores(ctx)
+			stores, next, err := s.GetStores(ctx, testPage)
 			assert.True(t, errors.Is(err, tC.err), fmt.Sprintf("wanted %s got %s", tC.err, err))
 			assert.Equal(t, tC.stores, stores)
+			if err == nil {
+				assert.Equal(t, "next", next)
+			}
 		})
 	}
 }
@@ -309,7 +493,7 @@ func TestService_GetStore(t *testing.T) {
 			st := storage.NewMockStorage(ctrl)
 			st.EXPECT().GetStore(ctx, id).Return(tC.rStore, tC.rErr)
 
-			s := New(st)
+			s := New(st, nil)
 
 			store, err := s.GetStore(ctx, id)
 			assert.True(t, errors.Is(err, tC.err), fmt.Sprintf("wanted %s got %s", tC.err, err))
@@ -349,7 +533,7 @@ func TestService_CreateStore(t *testing.T) {
 			st := storage.NewMockStorage(ctrl)
 			st.EXPECT().CreateStore(ctx, tC.store).Return(tC.rStore, tC.rErr)
 
-			s := New(st)
+			s := New(st, nil)
 
 			str, err := s.CreateStore(ctx, tC.store)
 
@@ -393,7 +577,7 @@ func TestService_UpdateStore(t *testing.T) {
 			st := storage.NewMockStorage(ctrl)
 			st.EXPECT().UpdateStore(ctx, tC.store).Return(tC.rErr)
 
-			s := New(st)
+			s := New(st, nil)
 
 			err := s.UpdateStore(ctx, tC.store)
 			assert.True(t, errors.Is(err, tC.err), fmt.Sprintf("wanted %s got %s", tC.err, err))
@@ -433,7 +617,7 @@ func TestService_DeleteStore(t *testing.T) {
 			st := storage.NewMockStorage(ctrl)
 			st.EXPECT().DeleteStore(ctx, id).Return(tC.rErr)
 
-			s := New(st)
+			s := New(st, nil)
 
 			err := s.DeleteStore(ctx, id)
 			assert.True(t, errors.Is(err, tC.err), fmt.Sprintf("wanted %s got %s", tC.err, err))
@@ -447,6 +631,15 @@ func TestService_GetProducts(t *testing.T) {
 		{ID: 2, CategoryID: 1, Name: "BBB", Description: "D-BBB"},
 	}
 
+	testSummary := model.PriceSummary{
+		ProductID:       2,
+		OfferCount:      2,
+		MinPrice:        decimal.NewFromInt(100),
+		MaxPrice:        decimal.NewFromInt(120),
+		MedianPrice:     decimal.NewFromInt(110),
+		CheapestStoreID: 3,
+	}
+
 	testCases := []struct {
 		desc      string
 		rProducts []model.Product
@@ -456,10 +649,21 @@ func TestService_GetProducts(t *testing.T) {
 	}{
 		{
 			desc:      "success",
-			rProducts: testProducts,
+			rProducts: []model.Product{testProducts[0], testProducts[1]},
 			rErr:      nil,
-			products:  testProducts,
-			err:       nil,
+			products: []model.Product{
+				testProducts[0],
+				{ID: 2, CategoryID: 1, Name: "BBB", Description: "D-BBB", Offers: model.PriceSummary{
+					ProductID:       2,
+					OfferCount:      2,
+					Currency:        "USD",
+					MinPrice:        decimal.RequireFromString("100.00"),
+					MaxPrice:        decimal.RequireFromString("120.00"),
+					MedianPrice:     decimal.RequireFromString("110.00"),
+					CheapestStoreID: 3,
+				}},
+			},
+			err: nil,
 		},
 		{
 			desc:      "unexpected error",
@@ -468,6 +672,13 @@ func TestService_GetProducts(t *testing.T) {
 			products:  nil,
 			err:       errTest,
 		},
+		{
+			desc:      "ErrInvalidCursor",
+			rProducts: nil,
+			rErr:      storage.ErrInvalidCursor,
+			products:  nil,
+			err:       ErrInvalidCursor,
+		},
 	}
 	for _, tC := range testCases {
 		t.Run(tC.desc, func(t *testing.T) {
@@ -475,22 +686,164 @@ func TestService_GetProducts(t *testing.T) {
 			defer ctrl.Finish()
 
 			st := storage.NewMockStorage(ctrl)
-			st.EXPECT().GetProducts(ctx, int64(1)).Return(tC.rProducts, tC.rErr)
+			st.EXPECT().GetProducts(ctx, int64(1), testFilter, testPage).Return(tC.rProducts, "next", tC.rErr)
+			if tC.rErr == nil {
+				st.EXPECT().GetProductImages(ctx, []int64{1, 2}).Return(nil, nil)
+				st.EXPECT().GetPriceSummaries(ctx, []int64{1, 2}).Return([]model.PriceSummary{testSummary}, nil)
+			}
 
-			s := New(st)
+			s := New(st, nil)
 
-			stores, err := s.GetProducts(ctx, 1)
+			stores, next, err := s.GetProducts(ctx, 1, testFilter, testPage)
 			assert.True(t, errors.Is(err, tC.err), fmt.Sprintf("wanted %s got %s", tC.err, err))
 			assert.Equal(t, tC.products, stores)
+			if err == nil {
+				assert.Equal(t, "next", next)
+			}
+		})
+	}
+}
+
+func TestService_GetProducts_AttributeFilters(t *testing.T) {
+	testCases := []struct {
+		desc    string
+		cErr    error
+		filters []model.AttributeFilter
+		rFilter []model.AttributeFilter
+		err     error
+	}{
+		{
+			desc: "success",
+			filters: []model.AttributeFilter{
+				{Name: "color", Value: "black"},
+				{Name: "ram", Value: "16", Min: decimal.NullDecimal{Decimal: decimal.NewFromInt(8), Valid: true}},
+			},
+			rFilter: []model.AttributeFilter{
+				{Name: "color", Value: "black"},
+				{Name: "ram", Value: 16.0, Min: decimal.NullDecimal{Decimal: decimal.NewFromInt(8), Valid: true}},
+			},
+		},
+		{
+			desc:    "unknown attribute",
+			filters: []model.AttributeFilter{{Name: "cpu", Value: "i7"}},
+			err:     ErrInvalidAttributes,
+		},
+		{
+			desc:    "malformed value",
+			filters: []model.AttributeFilter{{Name: "ram", Value: "lots"}},
+			err:     ErrInvalidAttributes,
+		},
+		{
+			desc:    "range filter on enum",
+			filters: []model.AttributeFilter{{Name: "color", Max: decimal.NullDecimal{Decimal: decimal.NewFromInt(8), Valid: true}}},
+			err:     ErrInvalidAttributes,
+		},
+		{
+			desc:    "ErrNotFound",
+			cErr:    storage.ErrNotFound,
+			filters: []model.AttributeFilter{{Name: "ram", Value: "16"}},
+			err:     ErrNotFound,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			st := storage.NewMockStorage(ctrl)
+			st.EXPECT().GetCategory(ctx, int64(1)).Return(testSchemaCategory, tC.cErr)
+			if tC.err == nil {
+				st.EXPECT().GetProducts(ctx, int64(1), model.ProductFilter{Attributes: tC.rFilter}, testPage).Return(nil, "", nil)
+			}
+
+			s := New(st, nil)
+
+			_, _, err := s.GetProducts(ctx, 1, model.ProductFilter{Attributes: tC.filters}, testPage)
+			assert.True(t, errors.Is(err, tC.err), fmt.Sprintf("wanted %s got %s", tC.err, err))
+		})
+	}
+}
+
+func TestService_SearchProducts(t *testing.T) {
+	search := model.ProductSearch{Query: "iphone", CategoryID: 1}
+	testResults := []model.ProductSearchResult{
+		{
+			Product:              model.Product{ID: 1, CategoryID: 1, Name: "iPhone", Description: "Phone"},
+			Rank:                 0.5,
+			NameHighlight:        "<b>iPhone</b>",
+			DescriptionHighlight: "Phone",
+		},
+	}
+
+	testCases := []struct {
+		desc     string
+		rResults []model.ProductSearchResult
+		rErr     error
+		results  []model.ProductSearchResult
+		err      error
+	}{
+		{
+			desc:     "success",
+			rResults: testResults,
+			rErr:     nil,
+			results:  testResults,
+			err:      nil,
+		},
+		{
+			desc:     "unexpected error",
+			rResults: nil,
+			rErr:     errTest,
+			results:  nil,
+			err:      errTest,
+		},
+		{
+			desc:     "ErrInvalidCursor",
+			rResults: nil,
+			rErr:     storage.ErrInvalidCursor,
+			results:  nil,
+			err:      ErrInvalidCursor,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			st := storage.NewMockStorage(ctrl)
+			st.EXPECT().SearchProducts(ctx, search, testPage).Return(tC.rResults, "next", tC.rErr)
+			if tC.rErr == nil {
+				st.EXPECT().GetProductImages(ctx, []int64{1}).Return(nil, nil)
+				st.EXPECT().GetPriceSummaries(ctx, []int64{1}).Return(nil, nil)
+			}
+
+			s := New(st, nil)
+
+			results, next, err := s.SearchProducts(ctx, search, testPage)
+			assert.True(t, errors.Is(err, tC.err), fmt.Sprintf("wanted %s got %s", tC.err, err))
+			assert.Equal(t, tC.results, results)
+			if err == nil {
+				assert.Equal(t, "next", next)
+			}
 		})
 	}
 }
 
 func TestService_GetProduct(t *testing.T) {
+	testImages := []model.Image{
+		{ID: 2, ProductID: 1, Key: "products/1/b.jpg", ContentType: "image/jpeg", Position: 0},
+		{ID: 1, ProductID: 1, Key: "products/1/a.png", ContentType: "image/png", Position: 1},
+	}
+
 	testCases := []struct {
 		desc     string
 		rProduct model.Product
 		rErr     error
+		rImages  []model.Image
+		iErr     error
+		offers   []model.PriceSummary
+		oErr     error
+		variants []model.Variant
+		vErr     error
 		product  model.Product
 		err      error
 	}{
@@ -501,6 +854,80 @@ func TestService_GetProduct(t *testing.T) {
 			product:  model.Product{ID: 1, CategoryID: 1, Name: "Test1", Description: "1 test"},
 			err:      nil,
 		},
+		{
+			desc:     "success with images",
+			rProduct: model.Product{ID: 1, CategoryID: 1, Name: "Test1", Description: "1 test"},
+			rErr:     nil,
+			rImages:  testImages,
+			product: model.Product{ID: 1, CategoryID: 1, Name: "Test1", Description: "1 test", Images: []model.Image{
+				{
+					ID: 2, ProductID: 1, Key: "products/1/b.jpg", ContentType: "image/jpeg", Position: 0, Primary: true,
+					URL: "/media/products/1/b.jpg",
+					Thumbnails: map[string]string{
+						"small":  "/media/products/1/b_small.jpg",
+						"medium": "/media/products/1/b_medium.jpg",
+						"large":  "/media/products/1/b_large.jpg",
+					},
+				},
+				{
+					ID: 1, ProductID: 1, Key: "products/1/a.png", ContentType: "image/png", Position: 1,
+					URL: "/media/products/1/a.png",
+					Thumbnails: map[string]string{
+						"small":  "/media/products/1/a_small.png",
+						"medium": "/media/products/1/a_medium.png",
+						"large":  "/media/products/1/a_large.png",
+					},
+				},
+			}},
+			err: nil,
+		},
+		{
+			desc:     "success with variants",
+			rProduct: model.Product{ID: 1, CategoryID: 1, Name: "Test1", Description: "1 test"},
+			rErr:     nil,
+			variants: []model.Variant{{ID: 1, ProductID: 1, SKU: "SKU-1"}},
+			product: model.Product{ID: 1, CategoryID: 1, Name: "Test1", Description: "1 test", Variants: []model.Variant{
+				{ID: 1, ProductID: 1, SKU: "SKU-1"},
+			}},
+			err: nil,
+		},
+		{
+			desc:     "success with offers",
+			rProduct: model.Product{ID: 1, CategoryID: 1, Name: "Test1", Description: "1 test"},
+			rErr:     nil,
+			offers: []model.PriceSummary{
+				{ProductID: 1, OfferCount: 1, MinPrice: decimal.NewFromInt(10), MaxPrice: decimal.NewFromInt(10), MedianPrice: decimal.NewFromInt(10), CheapestStoreID: 2},
+			},
+			product: model.Product{ID: 1, CategoryID: 1, Name: "Test1", Description: "1 test", Offers: model.PriceSummary{
+				ProductID: 1, OfferCount: 1, Currency: "USD", MinPrice: decimal.RequireFromString("10.00"), MaxPrice: decimal.RequireFromString("10.00"),
+				MedianPrice: decimal.RequireFromString("10.00"), CheapestStoreID: 2,
+			}},
+			err: nil,
+		},
+		{
+			desc:     "unexpected offers error",
+			rProduct: model.Product{ID: 1, CategoryID: 1, Name: "Test1", Description: "1 test"},
+			rErr:     nil,
+			oErr:     errTest,
+			product:  model.Product{},
+			err:      errTest,
+		},
+		{
+			desc:     "unexpected variants error",
+			rProduct: model.Product{ID: 1, CategoryID: 1, Name: "Test1", Description: "1 test"},
+			rErr:     nil,
+			vErr:     errTest,
+			product:  model.Product{},
+			err:      errTest,
+		},
+		{
+			desc:     "unexpected images error",
+			rProduct: model.Product{ID: 1, CategoryID: 1, Name: "Test1", Description: "1 test"},
+			rErr:     nil,
+			iErr:     errTest,
+			product:  model.Product{},
+			err:      errTest,
+		},
 		{
 			desc:     "ErrNotFound",
 			rProduct: model.Product{},
@@ -525,8 +952,17 @@ func TestService_GetProduct(t *testing.T) {
 
 			st := storage.NewMockStorage(ctrl)
 			st.EXPECT().GetProduct(ctx, id).Return(tC.rProduct, tC.rErr)
-
-			s := New(st)
+			if tC.rErr == nil {
+				st.EXPECT().GetProductImages(ctx, []int64{id}).Return(tC.rImages, tC.iErr)
+			}
+			if tC.rErr == nil && tC.iErr == nil {
+				st.EXPECT().GetPriceSummaries(ctx, []int64{id}).Return(tC.offers, tC.oErr)
+			}
+			if tC.rErr == nil && tC.iErr == nil && tC.oErr == nil {
+				st.EXPECT().GetProductVariants(ctx, id).Return(tC.variants, tC.vErr)
+			}
+
+			s := New(st, newTestMediaStorage(ctrl))
 
 			product, err := s.GetProduct(ctx, id)
 			assert.True(t, errors.Is(err, tC.err), fmt.Sprintf("wanted %s got %s", tC.err, err))
@@ -538,6 +974,7 @@ func TestService_GetProduct(t *testing.T) {
 func TestService_CreateProduct(t *testing.T) {
 	testCases := []struct {
 		desc     string
+		cErr     error
 		rProduct model.Product
 		rErr     error
 		product  model.Product
@@ -545,23 +982,38 @@ func TestService_CreateProduct(t *testing.T) {
 	}{
 		{
 			desc:     "success",
-			rProduct: model.Product{ID: 1, CategoryID: 1, Name: "Test1", Description: "1 test"},
+			rProduct: model.Product{ID: 1, CategoryID: 1, Name: "Test1", Description: "1 test", Attributes: map[string]interface{}{"ram": 16.0}},
 			rErr:     nil,
-			product:  model.Product{CategoryID: 1, Name: "Test1", Description: "1 test"},
+			product:  model.Product{CategoryID: 1, Name: "Test1", Description: "1 test", Attributes: map[string]interface{}{"ram": 16.0}},
 			err:      nil,
 		},
+		{
+			desc:     "ErrInvalidAttributes",
+			rProduct: model.Product{},
+			rErr:     errSkip,
+			product:  model.Product{CategoryID: 1, Name: "Test1", Description: "1 test"},
+			err:      ErrInvalidAttributes,
+		},
+		{
+			desc:     "ErrUnknownCategory from category lookup",
+			cErr:     storage.ErrNotFound,
+			rProduct: model.Product{},
+			rErr:     errSkip,
+			product:  model.Product{CategoryID: 1, Name: "Test1", Description: "1 test"},
+			err:      ErrUnknownCategory,
+		},
 		{
 			desc:     "ErrUnknownCategory",
 			rProduct: model.Product{},
 			rErr:     storage.ErrUnknownCategory,
-			product:  model.Product{CategoryID: 1, Name: "Test1", Description: "1 test"},
+			product:  model.Product{CategoryID: 1, Name: "Test1", Description: "1 test", Attributes: map[string]interface{}{"ram": 16.0}},
 			err:      ErrUnknownCategory,
 		},
 		{
 			desc:     "unexpected error",
 			rProduct: model.Product{},
 			rErr:     errTest,
-			product:  model.Product{CategoryID: 1, Name: "Test1", Description: "1 test"},
+			product:  model.Product{CategoryID: 1, Name: "Test1", Description: "1 test", Attributes: map[string]interface{}{"ram": 16.0}},
 			err:      errTest,
 		},
 	}
@@ -571,9 +1023,12 @@ func TestService_CreateProduct(t *testing.T) {
 			defer ctrl.Finish()
 
 			st := storage.NewMockStorage(ctrl)
-			st.EXPECT().CreateProduct(ctx, tC.product).Return(tC.rProduct, tC.rErr)
+			st.EXPECT().GetCategory(ctx, int64(1)).Return(testSchemaCategory, tC.cErr)
+			if tC.rErr != errSkip {
+				st.EXPECT().CreateProduct(ctx, tC.product).Return(tC.rProduct, tC.rErr)
+			}
 
-			s := New(st)
+			s := New(st, nil)
 
 			p, err := s.CreateProduct(ctx, tC.product)
 
@@ -584,8 +1039,11 @@ func TestService_CreateProduct(t *testing.T) {
 }
 
 func TestService_UpdateProduct(t *testing.T) {
+	attrs := map[string]interface{}{"ram": 16.0, "color": "black"}
+
 	testCases := []struct {
 		desc    string
+		cErr    error
 		rErr    error
 		product model.Product
 		err     error
@@ -593,25 +1051,45 @@ func TestService_UpdateProduct(t *testing.T) {
 		{
 			desc:    "success",
 			rErr:    nil,
-			product: model.Product{ID: 1, CategoryID: 1, Name: "Test1", Description: "1 test"},
+			product: model.Product{ID: 1, CategoryID: 1, Name: "Test1", Description: "1 test", Attributes: attrs},
 			err:     nil,
 		},
+		{
+			desc:    "ErrInvalidAttributes",
+			rErr:    errSkip,
+			product: model.Product{ID: 1, CategoryID: 1, Name: "Test1", Description: "1 test", Attributes: map[string]interface{}{"ram": "16"}},
+			err:     ErrInvalidAttributes,
+		},
 		{
 			desc:    "ErrNotFound",
 			rErr:    storage.ErrNotFound,
-			product: model.Product{ID: 1, CategoryID: 1, Name: "Test1", Description: "1 test"},
+			product: model.Product{ID: 1, CategoryID: 1, Name: "Test1", Description: "1 test", Attributes: attrs},
 			err:     ErrNotFound,
 		},
+		{
+			desc:    "ErrUnknownCategory from category lookup",
+			cErr:    storage.ErrNotFound,
+			rErr:    errSkip,
+			product: model.Product{ID: 1, CategoryID: 1, Name: "Test1", Description: "1 test", Attributes: attrs},
+			err:     ErrUnknownCategory,
+		},
 		{
 			desc:    "ErrUnknownCategory",
 			rErr:    storage.ErrUnknownCategory,
-			product: model.Product{ID: 1, CategoryID: 1, Name: "Test1", Description: "1 test"},
+			product: model.Product{ID: 1, CategoryID: 1, Name: "Test1", Description: "1 test", Attributes: attrs},
 			err:     ErrUnknownCategory,
 		},
+		{
+			desc:    "unexpected category error",
+			cErr:    errTest,
+			rErr:    errSkip,
+			product: model.Product{ID: 1, CategoryID: 1, Name: "Test1", Description: "1 test", Attributes: attrs},
+			err:     errTest,
+		},
 		{
 			desc:    "unexpected error",
 			rErr:    errTest,
-			product: model.Product{ID: 1, CategoryID: 1, Name: "Test1", Description: "1 test"},
+			product: model.Product{ID: 1, CategoryID: 1, Name: "Test1", Description: "1 test", Attributes: attrs},
 			err:     errTest,
 		},
 	}
@@ -621,9 +1099,12 @@ func TestService_UpdateProduct(t *testing.T) {
 			defer ctrl.Finish()
 
 			st := storage.NewMockStorage(ctrl)
-			st.EXPECT().UpdateProduct(ctx, tC.product).Return(tC.rErr)
+			st.EXPECT().GetCategory(ctx, int64(1)).Return(testSchemaCategory, tC.cErr)
+			if tC.rErr != errSkip {
+				st.EXPECT().UpdateProduct(ctx, tC.product).Return(tC.rErr)
+			}
 
-			s := New(st)
+			s := New(st, nil)
 
 			err := s.UpdateProduct(ctx, tC.product)
 			assert.True(t, errors.Is(err, tC.err), fmt.Sprintf("wanted %s got %s", tC.err, err))
@@ -632,8 +1113,11 @@ func TestService_UpdateProduct(t *testing.T) {
 }
 
 func TestService_DeleteProduct(t *testing.T) {
+	testImages := []model.Image{{ID: 1, ProductID: 1, Key: "products/1/a.png", ContentType: "image/png"}}
+
 	testCases := []struct {
 		desc string
+		iErr error
 		rErr error
 		err  error
 	}{
@@ -642,6 +1126,12 @@ func TestService_DeleteProduct(t *testing.T) {
 			rErr: nil,
 			err:  nil,
 		},
+		{
+			desc: "unexpected images error",
+			iErr: errTest,
+			rErr: errSkip,
+			err:  errTest,
+		},
 		{
 			desc: "ErrNotFound",
 			rErr: storage.ErrNotFound,
@@ -661,9 +1151,19 @@ func TestService_DeleteProduct(t *testing.T) {
 			id := int64(1)
 
 			st := storage.NewMockStorage(ctrl)
-			st.EXPECT().DeleteProduct(ctx, id).Return(tC.rErr)
+			st.EXPECT().GetProductImages(ctx, []int64{id}).Return(testImages, tC.iErr)
+			if tC.rErr != errSkip {
+				st.EXPECT().DeleteProduct(ctx, id).Return(tC.rErr)
+			}
+
+			m := media.NewMockStorage(ctrl)
+			if tC.rErr == nil {
+				for _, key := range []string{"products/1/a.png", "products/1/a_small.png", "products/1/a_medium.png", "products/1/a_large.png"} {
+					m.EXPECT().Delete(ctx, key).Return(nil)
+				}
+			}
 
-			s := New(st)
+			s := New(st, m)
 
 			err := s.DeleteProduct(ctx, id)
 			assert.True(t, errors.Is(err, tC.err), fmt.Sprintf("wanted %s got %s", tC.err, err))
@@ -698,6 +1198,13 @@ func TestService_GetStorePositions(t *testing.T) {
 			positions:  nil,
 			err:        errTest,
 		},
+		{
+			desc:       "ErrInvalidCursor",
+			rPositions: nil,
+			rErr:       storage.ErrInvalidCursor,
+			positions:  nil,
+			err:        ErrInvalidCursor,
+		},
 	}
 	for _, tC := range testCases {
 		t.Run(tC.desc, func(t *testing.T) {
@@ -705,13 +1212,16 @@ func TestService_GetStorePositions(t *testing.T) {
 			defer ctrl.Finish()
 
 			st := storage.NewMockStorage(ctrl)
-			st.EXPECT().GetStorePositions(ctx, int64(1)).Return(tC.rPositions, tC.rErr)
+			st.EXPECT().GetStorePositions(ctx, int64(1), testPage).Return(tC.rPositions, "next", tC.rErr)
 
-			s := New(st)
+			s := New(st, nil)
 
-			stores, err := s.GetStorePositions(ctx, 1)
+			stores, next, err := s.GetStorePositions(ctx, 1, testPage)
 			assert.True(t, errors.Is(err, tC.err), fmt.Sprintf("wanted %s got %s", tC.err, err))
 			assert.Equal(t, tC.positions, stores)
+			if err == nil {
+				assert.Equal(t, "next", next)
+			}
 		})
 	}
 }
@@ -743,53 +1253,162 @@ func TestService_GetProductPositions(t *testing.T) {
 			positions:  nil,
 			err:        errTest,
 		},
+		{
+			desc:       "ErrInvalidCursor",
+			rPositions: nil,
+			rErr:       storage.ErrInvalidCursor,
+			positions:  nil,
+			err:        ErrInvalidCursor,
+		},
 	}
 	for _, tC := range testCases {
 		t.Run(tC.desc, func(t *testing.T) {
 			ctrl := gomock.NewController(t)
 			defer ctrl.Finish()
 
+			filter := model.PositionFilter{Availability: []model.Availability{model.AvailabilityInStock}}
+
 			st := storage.NewMockStorage(ctrl)
-			st.EXPECT().GetProductPositions(ctx, int64(1)).Return(tC.rPositions, tC.rErr)
+			st.EXPECT().GetProductPositions(ctx, int64(1), filter, testPage).Return(tC.rPositions, "next", tC.rErr)
 
-			s := New(st)
+			s := New(st, nil)
 
-			stores, err := s.GetProductPositions(ctx, 1)
+			stores, next, err := s.GetProductPositions(ctx, 1, filter, "", testPage)
 			assert.True(t, errors.Is(err, tC.err), fmt.Sprintf("wanted %s got %s", tC.err, err))
 			assert.Equal(t, tC.positions, stores)
+			if err == nil {
+				assert.Equal(t, "next", next)
+			}
 		})
 	}
 }
 
 func TestService_SetPosition(t *testing.T) {
+	restockDate := time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)
+
 	testCases := []struct {
-		desc     string
-		rErr     error
-		position model.Position
-		err      error
+		desc      string
+		input     model.Position
+		position  model.Position
+		rPosition model.Position
+		rErr      error
+		err       error
 	}{
 		{
-			desc:     "success",
-			rErr:     nil,
-			position: model.Position{ProductID: 1, StoreID: 1, Price: decimal.NewFromInt(100)},
-			err:      nil,
+			desc:      "success",
+			input:     model.Position{VariantID: 2, StoreID: 1, Price: decimal.NewFromInt(100), Quantity: 5, Availability: model.AvailabilityInStock},
+			position:  model.Position{VariantID: 2, StoreID: 1, Price: decimal.NewFromInt(100), Currency: "USD", Quantity: 5, Availability: model.AvailabilityInStock},
+			rPosition: model.Position{ProductID: 1, VariantID: 2, StoreID: 1, Price: decimal.NewFromInt(100), Currency: "USD", Quantity: 5, Availability: model.AvailabilityInStock},
+			rErr:      nil,
+			err:       nil,
 		},
 		{
-			desc:     "ErrUnknownProduct",
-			rErr:     storage.ErrUnknownProduct,
-			position: model.Position{ProductID: 1, StoreID: 1, Price: decimal.NewFromInt(100)},
-			err:      ErrUnknownProduct,
+			desc:  "success backorder",
+			input: model.Position{VariantID: 2, StoreID: 1, Price: decimal.NewFromInt(100), Availability: model.AvailabilityBackorder, RestockDate: restockDate},
+			position: model.Position{VariantID: 2, StoreID: 1, Price: decimal.NewFromInt(100), Currency: "USD",
+				Availability: model.AvailabilityBackorder, RestockDate: restockDate},
+			rPosition: model.Position{ProductID: 1, VariantID: 2, StoreID: 1, Price: decimal.NewFromInt(100), Currency: "USD",
+				Availability: model.AvailabilityBackorder, RestockDate: restockDate},
+			rErr: nil,
+			err:  nil,
+		},
+		{
+			desc:      "success without availability",
+			input:     model.Position{VariantID: 2, StoreID: 1, Price: decimal.NewFromInt(100)},
+			position:  model.Position{VariantID: 2, StoreID: 1, Price: decimal.NewFromInt(100), Currency: "USD"},
+			rPosition: model.Position{ProductID: 1, VariantID: 2, StoreID: 1, Price: decimal.NewFromInt(100), Currency: "USD", Availability: model.AvailabilityInStock},
+			rErr:      nil,
+			err:       nil,
+		},
+		{
+			desc:      "success with currency",
+			input:     model.Position{VariantID: 2, StoreID: 1, Price: decimal.NewFromInt(100), Currency: "EUR", Availability: model.AvailabilityInStock},
+			position:  model.Position{VariantID: 2, StoreID: 1, Price: decimal.NewFromInt(100), Currency: "EUR", Availability: model.AvailabilityInStock},
+			rPosition: model.Position{ProductID: 1, VariantID: 2, StoreID: 1, Price: decimal.NewFromInt(100), Currency: "EUR", Availability: model.AvailabilityInStock},
+			rErr:      nil,
+			err:       nil,
+		},
+		{
+			desc:     "ErrUnknownCurrency",
+			input:    model.Position{VariantID: 2, StoreID: 1, Price: decimal.NewFromInt(100), Currency: "XYZ", Availability: model.AvailabilityInStock},
+			position: model.Position{VariantID: 2, StoreID: 1, Price: decimal.NewFromInt(100), Currency: "XYZ", Availability: model.AvailabilityInStock},
+			rErr:     storage.ErrUnknownCurrency,
+			err:      ErrUnknownCurrency,
+		},
+		{
+			desc:     "ErrUnknownVariant",
+			input:    model.Position{VariantID: 2, StoreID: 1, Price: decimal.NewFromInt(100), Availability: model.AvailabilityInStock},
+			position: model.Position{VariantID: 2, StoreID: 1, Price: decimal.NewFromInt(100), Currency: "USD", Availability: model.AvailabilityInStock},
+			rErr:     storage.ErrUnknownVariant,
+			err:      ErrUnknownVariant,
 		},
 		{
 			desc:     "ErrUnknownStore",
+			input:    model.Position{VariantID: 2, StoreID: 1, Price: decimal.NewFromInt(100), Availability: model.AvailabilityInStock},
+			position: model.Position{VariantID: 2, StoreID: 1, Price: decimal.NewFromInt(100), Currency: "USD", Availability: model.AvailabilityInStock},
 			rErr:     storage.ErrUnknownStore,
-			position: model.Position{ProductID: 1, StoreID: 1, Price: decimal.NewFromInt(100)},
 			err:      ErrUnknownStore,
 		},
+		{
+			desc:     "unexpected error",
+			input:    model.Position{VariantID: 2, StoreID: 1, Price: decimal.NewFromInt(100), Availability: model.AvailabilityInStock},
+			position: model.Position{VariantID: 2, StoreID: 1, Price: decimal.NewFromInt(100), Currency: "USD", Availability: model.AvailabilityInStock},
+			rErr:     errTest,
+			err:      errTest,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			st := storage.NewMockStorage(ctrl)
+			st.EXPECT().UpsertPosition(ctx, tC.position, int64(1)).Return(tC.rPosition, tC.rErr)
+
+			s := New(st, nil)
+
+			p, err := s.SetPosition(ctx, tC.input, 1)
+			assert.True(t, errors.Is(err, tC.err), fmt.Sprintf("wanted %s got %s", tC.err, err))
+			if tC.err == nil {
+				assert.Equal(t, tC.rPosition, p)
+			} else {
+				assert.Equal(t, model.Position{}, p)
+			}
+		})
+	}
+}
+
+func TestService_AdjustStock(t *testing.T) {
+	testCases := []struct {
+		desc      string
+		rPosition model.Position
+		rErr      error
+		position  model.Position
+		err       error
+	}{
+		{
+			desc:      "success",
+			rPosition: model.Position{ProductID: 1, VariantID: 2, StoreID: 3, Price: decimal.NewFromInt(100), Quantity: 2},
+			rErr:      nil,
+			position:  model.Position{ProductID: 1, VariantID: 2, StoreID: 3, Price: decimal.NewFromInt(100), Quantity: 2},
+			err:       nil,
+		},
+		{
+			desc:     "ErrNotFound",
+			rErr:     storage.ErrNotFound,
+			position: model.Position{},
+			err:      ErrNotFound,
+		},
+		{
+			desc:     "ErrInsufficientStock",
+			rErr:     storage.ErrInsufficientStock,
+			position: model.Position{},
+			err:      ErrInsufficientStock,
+		},
 		{
 			desc:     "unexpected error",
 			rErr:     errTest,
-			position: model.Position{ProductID: 1, StoreID: 1, Price: decimal.NewFromInt(100)},
+			position: model.Position{},
 			err:      errTest,
 		},
 	}
@@ -799,12 +1418,13 @@ func TestService_SetPosition(t *testing.T) {
 			defer ctrl.Finish()
 
 			st := storage.NewMockStorage(ctrl)
-			st.EXPECT().UpsertPosition(ctx, tC.position).Return(tC.rErr)
+			st.EXPECT().AdjustPositionStock(ctx, int64(2), int64(3), int64(-3)).Return(tC.rPosition, tC.rErr)
 
-			s := New(st)
+			s := New(st, nil)
 
-			err := s.SetPosition(ctx, tC.position)
+			p, err := s.AdjustStock(ctx, 2, 3, -3)
 			assert.True(t, errors.Is(err, tC.err), fmt.Sprintf("wanted %s got %s", tC.err, err))
+			assert.Equal(t, tC.position, p)
 		})
 	}
 }
@@ -836,15 +1456,15 @@ func TestService_DeletePosition(t *testing.T) {
 			ctrl := gomock.NewController(t)
 			defer ctrl.Finish()
 
-			productID := int64(1)
+			variantID := int64(1)
 			storeID := int64(2)
 
 			st := storage.NewMockStorage(ctrl)
-			st.EXPECT().DeletePosition(ctx, productID, storeID).Return(tC.rErr)
+			st.EXPECT().DeletePosition(ctx, variantID, storeID).Return(tC.rErr)
 
-			s := New(st)
+			s := New(st, nil)
 
-			err := s.DeletePosition(ctx, productID, storeID)
+			err := s.DeletePosition(ctx, variantID, storeID)
 			assert.True(t, errors.Is(err, tC.err), fmt.Sprintf("wanted %s got %s", tC.err, err))
 		})
 	}
@@ -0,0 +1,220 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/vliubezny/gstore/internal/model"
+	"github.com/vliubezny/gstore/internal/storage"
+)
+
+func (s *service) GetProductVariants(ctx context.Context, productID int64) (model.VariantMatrix, error) {
+	if _, err := s.s.GetProduct(ctx, productID); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return model.VariantMatrix{}, ErrNotFound
+		}
+		return model.VariantMatrix{}, fmt.Errorf("failed to get product: %w", err)
+	}
+
+	variants, err := s.s.GetProductVariants(ctx, productID)
+	if err != nil {
+		return model.VariantMatrix{}, fmt.Errorf("failed to get product variants: %w", err)
+	}
+
+	return buildVariantMatrix(variants), nil
+}
+
+func (s *service) CreateVariant(ctx context.Context, variant model.Variant) (model.Variant, error) {
+	if err := s.validateVariant(ctx, variant); err != nil {
+		return model.Variant{}, err
+	}
+
+	variant, err := s.s.CreateVariant(ctx, variant)
+	if err != nil {
+		return model.Variant{}, mapVariantError("failed to create variant", err)
+	}
+	return variant, nil
+}
+
+func (s *service) UpdateVariant(ctx context.Context, variant model.Variant) error {
+	if err := s.validateVariant(ctx, variant); err != nil {
+		return err
+	}
+
+	if err := s.s.UpdateVariant(ctx, variant); err != nil {
+		return mapVariantError("failed to update variant", err)
+	}
+	return nil
+}
+
+func (s *service) DeleteVariant(ctx context.Context, productID, variantID int64) error {
+	if err := s.s.DeleteVariant(ctx, productID, variantID); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return ErrNotFound
+		}
+		return fmt.Errorf("failed to delete variant: %w", err)
+	}
+	return nil
+}
+
+// validateVariant checks variant GTIN and attributes against product category schema,
+// attributes must be unique among product variants.
+func (s *service) validateVariant(ctx context.Context, variant model.Variant) error {
+	if variant.GTIN != "" && !isValidGTIN(variant.GTIN) {
+		return fmt.Errorf("%w: GTIN must consist of 8, 12, 13 or 14 digits with valid check digit", ErrInvalidVariant)
+	}
+
+	product, err := s.s.GetProduct(ctx, variant.ProductID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return ErrUnknownProduct
+		}
+		return fmt.Errorf("failed to get product: %w", err)
+	}
+
+	category, err := s.s.GetCategory(ctx, product.CategoryID)
+	if err != nil {
+		return fmt.Errorf("failed to get product category: %w", err)
+	}
+
+	if err := validateVariantAttributes(category.Schema, product.Attributes, variant.Attributes); err != nil {
+		return err
+	}
+
+	variants, err := s.s.GetProductVariants(ctx, variant.ProductID)
+	if err != nil {
+		return fmt.Errorf("failed to get product variants: %w", err)
+	}
+
+	for _, v := range variants {
+		if v.ID != variant.ID && sameAttributes(v.Attributes, variant.Attributes) {
+			return fmt.Errorf("%w: variant %s has the same attributes", ErrInvalidVariant, v.SKU)
+		}
+	}
+
+	return nil
+}
+
+func mapVariantError(msg string, err error) error {
+	switch {
+	case errors.Is(err, storage.ErrUnknownProduct):
+		return ErrUnknownProduct
+	case errors.Is(err, storage.ErrNotFound):
+		return ErrNotFound
+	case errors.Is(err, storage.ErrSKUIsTaken):
+		return ErrSKUIsTaken
+	case errors.Is(err, storage.ErrGTINIsTaken):
+		return ErrGTINIsTaken
+	}
+	return fmt.Errorf("%s: %w", msg, err)
+}
+
+// validateVariantAttributes checks variant attributes against category schema,
+// variant cannot override attributes common to the whole product.
+func validateVariantAttributes(schema []model.Attribute, productAttrs, attrs map[string]interface{}) error {
+	defined := make(map[string]model.Attribute, len(schema))
+	for _, a := range schema {
+		defined[a.Name] = a
+	}
+
+	for name, v := range attrs {
+		a, ok := defined[name]
+		if !ok {
+			return fmt.Errorf("%w: unknown attribute %q", ErrInvalidAttributes, name)
+		}
+
+		if _, ok := productAttrs[name]; ok {
+			return fmt.Errorf("%w: attribute %q is already defined by product", ErrInvalidAttributes, name)
+		}
+
+		if err := checkAttributeValue(a, v); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func sameAttributes(a, b map[string]interface{}) bool {
+	if len(a) == 0 || len(b) == 0 {
+		return len(a) == len(b)
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// isValidGTIN checks length and check digit of GTIN-8, GTIN-12 (UPC), GTIN-13 (EAN) and GTIN-14.
+func isValidGTIN(s string) bool {
+	switch len(s) {
+	case 8, 12, 13, 14:
+	default:
+		return false
+	}
+
+	sum := 0
+	for i := len(s) - 1; i >= 0; i-- {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+
+		d := int(s[i] - '0')
+		// digits are weighted 1, 3, 1, 3... from the right, starting with the check digit
+		if (len(s)-1-i)%2 == 1 {
+			d *= 3
+		}
+		sum += d
+	}
+
+	return sum%10 == 0
+}
+
+// buildVariantMatrix collects distinct values of variant attributes.
+func buildVariantMatrix(variants []model.Variant) model.VariantMatrix {
+	values := make(map[string][]interface{})
+	for _, v := range variants {
+		for name, value := range v.Attributes {
+			if !containsValue(values[name], value) {
+				values[name] = append(values[name], value)
+			}
+		}
+	}
+
+	options := make([]model.VariantOption, 0, len(values))
+	for name, vals := range values {
+		sort.Slice(vals, func(i, j int) bool { return lessValue(vals[i], vals[j]) })
+		options = append(options, model.VariantOption{Name: name, Values: vals})
+	}
+	sort.Slice(options, func(i, j int) bool { return options[i].Name < options[j].Name })
+
+	return model.VariantMatrix{
+		Options:  options,
+		Variants: variants,
+	}
+}
+
+func containsValue(values []interface{}, v interface{}) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
+
+// lessValue orders attribute values of the same type.
+func lessValue(a, b interface{}) bool {
+	switch a := a.(type) {
+	case string:
+		b, ok := b.(string)
+		return ok && a < b
+	case float64:
+		b, ok := b.(float64)
+		return ok && a < b
+	case bool:
+		b, ok := b.(bool)
+		return ok && !a && b
+	}
+	return false
+}
@@ -0,0 +1,319 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/vliubezny/gstore/internal/model"
+	"github.com/vliubezny/gstore/internal/storage"
+)
+
+var testVariantProduct = model.Product{
+	ID:          1,
+	CategoryID:  1,
+	Name:        "Laptop",
+	Description: "Test laptop",
+	Attributes:  map[string]interface{}{"ram": 16.0},
+}
+
+func TestService_GetProductVariants(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		pErr     error
+		variants []model.Variant
+		rErr     error
+		matrix   model.VariantMatrix
+		err      error
+	}{
+		{
+			desc: "success",
+			variants: []model.Variant{
+				{ID: 1, ProductID: 1, SKU: "L-B", Attributes: map[string]interface{}{"color": "silver", "ssd": 512.0}},
+				{ID: 2, ProductID: 1, SKU: "L-S", Attributes: map[string]interface{}{"color": "black", "ssd": 256.0}},
+				{ID: 3, ProductID: 1, SKU: "L-S2", Attributes: map[string]interface{}{"color": "black", "ssd": 512.0, "touch": true}},
+				{ID: 4, ProductID: 1, SKU: "L-S3", Attributes: map[string]interface{}{"color": "black", "ssd": 512.0, "touch": false}},
+			},
+			matrix: model.VariantMatrix{
+				Options: []model.VariantOption{
+					{Name: "color", Values: []interface{}{"black", "silver"}},
+					{Name: "ssd", Values: []interface{}{256.0, 512.0}},
+					{Name: "touch", Values: []interface{}{false, true}},
+				},
+				Variants: []model.Variant{
+					{ID: 1, ProductID: 1, SKU: "L-B", Attributes: map[string]interface{}{"color": "silver", "ssd": 512.0}},
+					{ID: 2, ProductID: 1, SKU: "L-S", Attributes: map[string]interface{}{"color": "black", "ssd": 256.0}},
+					{ID: 3, ProductID: 1, SKU: "L-S2", Attributes: map[string]interface{}{"color": "black", "ssd": 512.0, "touch": true}},
+					{ID: 4, ProductID: 1, SKU: "L-S3", Attributes: map[string]interface{}{"color": "black", "ssd": 512.0, "touch": false}},
+				},
+			},
+			err: nil,
+		},
+		{
+			desc:     "success without variants",
+			variants: nil,
+			matrix:   model.VariantMatrix{Options: []model.VariantOption{}},
+			err:      nil,
+		},
+		{
+			desc:   "ErrNotFound",
+			pErr:   storage.ErrNotFound,
+			matrix: model.VariantMatrix{},
+			err:    ErrNotFound,
+		},
+		{
+			desc:   "unexpected product error",
+			pErr:   errTest,
+			matrix: model.VariantMatrix{},
+			err:    errTest,
+		},
+		{
+			desc:   "unexpected error",
+			rErr:   errTest,
+			matrix: model.VariantMatrix{},
+			err:    errTest,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			st := storage.NewMockStorage(ctrl)
+			st.EXPECT().GetProduct(ctx, int64(1)).Return(testVariantProduct, tC.pErr)
+			if tC.pErr == nil {
+				st.EXPECT().GetProductVariants(ctx, int64(1)).Return(tC.variants, tC.rErr)
+			}
+
+			s := New(st, nil)
+
+			matrix, err := s.GetProductVariants(ctx, 1)
+			assert.True(t, errors.Is(err, tC.err), fmt.Sprintf("wanted %s got %s", tC.err, err))
+			assert.Equal(t, tC.matrix, matrix)
+		})
+	}
+}
+
+func TestService_CreateVariant(t *testing.T) {
+	existing := []model.Variant{
+		{ID: 1, ProductID: 1, SKU: "L-B", Attributes: map[string]interface{}{"color": "black"}},
+	}
+
+	testCases := []struct {
+		desc     string
+		variant  model.Variant
+		pErr     error
+		vErr     error
+		rErr     error
+		rVariant model.Variant
+		err      error
+	}{
+		{
+			desc:     "success",
+			variant:  model.Variant{ProductID: 1, SKU: "L-S", GTIN: "4006381333931", Attributes: map[string]interface{}{"color": "silver"}},
+			rVariant: model.Variant{ID: 2, ProductID: 1, SKU: "L-S", GTIN: "4006381333931", Attributes: map[string]interface{}{"color": "silver"}},
+			err:      nil,
+		},
+		{
+			desc:    "invalid GTIN",
+			variant: model.Variant{ProductID: 1, SKU: "L-S", GTIN: "4006381333932"},
+			err:     ErrInvalidVariant,
+		},
+		{
+			desc:    "ErrUnknownProduct",
+			variant: model.Variant{ProductID: 1, SKU: "L-S"},
+			pErr:    storage.ErrNotFound,
+			err:     ErrUnknownProduct,
+		},
+		{
+			desc:    "unknown attribute",
+			variant: model.Variant{ProductID: 1, SKU: "L-S", Attributes: map[string]interface{}{"weight": 2.0}},
+			err:     ErrInvalidAttributes,
+		},
+		{
+			desc:    "attribute of wrong type",
+			variant: model.Variant{ProductID: 1, SKU: "L-S", Attributes: map[string]interface{}{"color": "red"}},
+			err:     ErrInvalidAttributes,
+		},
+		{
+			desc:    "attribute defined by product",
+			variant: model.Variant{ProductID: 1, SKU: "L-S", Attributes: map[string]interface{}{"ram": 32.0}},
+			err:     ErrInvalidAttributes,
+		},
+		{
+			desc:    "duplicate attributes",
+			variant: model.Variant{ProductID: 1, SKU: "L-S", Attributes: map[string]interface{}{"color": "black"}},
+			err:     ErrInvalidVariant,
+		},
+		{
+			desc:    "ErrSKUIsTaken",
+			variant: model.Variant{ProductID: 1, SKU: "L-B", Attributes: map[string]interface{}{"color": "silver"}},
+			rErr:    storage.ErrSKUIsTaken,
+			err:     ErrSKUIsTaken,
+		},
+		{
+			desc:    "ErrGTINIsTaken",
+			variant: model.Variant{ProductID: 1, SKU: "L-S", GTIN: "4006381333931", Attributes: map[string]interface{}{"color": "silver"}},
+			rErr:    storage.ErrGTINIsTaken,
+			err:     ErrGTINIsTaken,
+		},
+		{
+			desc:    "unexpected variants error",
+			variant: model.Variant{ProductID: 1, SKU: "L-S", Attributes: map[string]interface{}{"color": "silver"}},
+			vErr:    errTest,
+			err:     errTest,
+		},
+		{
+			desc:    "unexpected error",
+			variant: model.Variant{ProductID: 1, SKU: "L-S", Attributes: map[string]interface{}{"color": "silver"}},
+			rErr:    errTest,
+			err:     errTest,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			st := storage.NewMockStorage(ctrl)
+			validGTIN := tC.variant.GTIN == "" || isValidGTIN(tC.variant.GTIN)
+			if validGTIN {
+				st.EXPECT().GetProduct(ctx, int64(1)).Return(testVariantProduct, tC.pErr)
+			}
+			if validGTIN && tC.pErr == nil {
+				st.EXPECT().GetCategory(ctx, int64(1)).Return(testSchemaCategory, nil)
+			}
+			validAttrs := validGTIN && tC.pErr == nil &&
+				validateVariantAttributes(testSchemaCategory.Schema, testVariantProduct.Attributes, tC.variant.Attributes) == nil
+			if validAttrs {
+				st.EXPECT().GetProductVariants(ctx, int64(1)).Return(existing, tC.vErr)
+			}
+			if validAttrs && tC.vErr == nil && tC.err != ErrInvalidVariant {
+				st.EXPECT().CreateVariant(ctx, tC.variant).Return(tC.rVariant, tC.rErr)
+			}
+
+			s := New(st, nil)
+
+			variant, err := s.CreateVariant(ctx, tC.variant)
+			assert.True(t, errors.Is(err, tC.err), fmt.Sprintf("wanted %s got %s", tC.err, err))
+			assert.Equal(t, tC.rVariant, variant)
+		})
+	}
+}
+
+func TestService_UpdateVariant(t *testing.T) {
+	existing := []model.Variant{
+		{ID: 1, ProductID: 1, SKU: "L-B", Attributes: map[string]interface{}{"color": "black"}},
+		{ID: 2, ProductID: 1, SKU: "L-S", Attributes: map[string]interface{}{"color": "silver"}},
+	}
+
+	testCases := []struct {
+		desc    string
+		variant model.Variant
+		rErr    error
+		err     error
+	}{
+		{
+			desc:    "success",
+			variant: model.Variant{ID: 1, ProductID: 1, SKU: "L-B2", Attributes: map[string]interface{}{"color": "black"}},
+			err:     nil,
+		},
+		{
+			desc:    "duplicate attributes",
+			variant: model.Variant{ID: 1, ProductID: 1, SKU: "L-B", Attributes: map[string]interface{}{"color": "silver"}},
+			err:     ErrInvalidVariant,
+		},
+		{
+			desc:    "ErrNotFound",
+			variant: model.Variant{ID: 3, ProductID: 1, SKU: "L-B"},
+			rErr:    storage.ErrNotFound,
+			err:     ErrNotFound,
+		},
+		{
+			desc:    "unexpected error",
+			variant: model.Variant{ID: 1, ProductID: 1, SKU: "L-B"},
+			rErr:    errTest,
+			err:     errTest,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			st := storage.NewMockStorage(ctrl)
+			st.EXPECT().GetProduct(ctx, int64(1)).Return(testVariantProduct, nil)
+			st.EXPECT().GetCategory(ctx, int64(1)).Return(testSchemaCategory, nil)
+			st.EXPECT().GetProductVariants(ctx, int64(1)).Return(existing, nil)
+			if tC.err != ErrInvalidVariant {
+				st.EXPECT().UpdateVariant(ctx, tC.variant).Return(tC.rErr)
+			}
+
+			s := New(st, nil)
+
+			err := s.UpdateVariant(ctx, tC.variant)
+			assert.True(t, errors.Is(err, tC.err), fmt.Sprintf("wanted %s got %s", tC.err, err))
+		})
+	}
+}
+
+func TestService_DeleteVariant(t *testing.T) {
+	testCases := []struct {
+		desc string
+		rErr error
+		err  error
+	}{
+		{
+			desc: "success",
+			rErr: nil,
+			err:  nil,
+		},
+		{
+			desc: "ErrNotFound",
+			rErr: storage.ErrNotFound,
+			err:  ErrNotFound,
+		},
+		{
+			desc: "unexpected error",
+			rErr: errTest,
+			err:  errTest,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			st := storage.NewMockStorage(ctrl)
+			st.EXPECT().DeleteVariant(ctx, int64(1), int64(2)).Return(tC.rErr)
+
+			s := New(st, nil)
+
+			err := s.DeleteVariant(ctx, 1, 2)
+			assert.True(t, errors.Is(err, tC.err), fmt.Sprintf("wanted %s got %s", tC.err, err))
+		})
+	}
+}
+
+func Test_isValidGTIN(t *testing.T) {
+	testCases := []struct {
+		gtin  string
+		valid bool
+	}{
+		{gtin: "96385074", valid: true},
+		{gtin: "036000291452", valid: true},
+		{gtin: "4006381333931", valid: true},
+		{gtin: "10012345678902", valid: true},
+		{gtin: "4006381333932", valid: false},
+		{gtin: "400638133393", valid: false},
+		{gtin: "40063813339X1", valid: false},
+		{gtin: "", valid: false},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.gtin, func(t *testing.T) {
+			assert.Equal(t, tC.valid, isValidGTIN(tC.gtin))
+		})
+	}
+}
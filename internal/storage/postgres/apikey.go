@@ -0,0 +1,81 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/vliubezny/gstore/internal/model"
+	"github.com/vliubezny/gstore/internal/storage"
+)
+
+const apiKeyUserIDFKConstraint = "api_key_user_id_fkey"
+
+const apiKeyColumns = "id, user_id, name, prefix, key_hash, scopes, created_at, expires_at, last_used_at"
+
+func (p pg) CreateAPIKey(ctx context.Context, key model.APIKey) (model.APIKey, error) {
+	expiresAt := sql.NullTime{Time: key.ExpiresAt, Valid: !key.ExpiresAt.IsZero()}
+
+	var k apiKey
+	if err := p.ext.GetContext(ctx, &k, `
+		INSERT INTO api_key (id, user_id, name, prefix, key_hash, scopes, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING `+apiKeyColumns,
+		key.ID, key.UserID, key.Name, key.Prefix, key.KeyHash, pq.Array(fromPermissions(key.Scopes)), expiresAt); err != nil {
+		if err, ok := err.(*pq.Error); ok && err.Constraint == apiKeyUserIDFKConstraint {
+			return model.APIKey{}, storage.ErrUnknownUser
+		}
+		return model.APIKey{}, fmt.Errorf("failed to create api key: %w", err)
+	}
+
+	return k.toModel(), nil
+}
+
+func (p pg) GetAPIKeys(ctx context.Context, userID int64) ([]model.APIKey, error) {
+	var keys []apiKey
+	if err := p.ext.SelectContext(ctx, &keys, `
+		SELECT `+apiKeyColumns+` FROM api_key WHERE user_id = $1 ORDER BY created_at DESC, id
+	`, userID); err != nil {
+		return nil, fmt.Errorf("failed to get api keys: %w", err)
+	}
+
+	data := make([]model.APIKey, len(keys))
+	for i, k := range keys {
+		data[i] = k.toModel()
+	}
+
+	return data, nil
+}
+
+func (p pg) DeleteAPIKey(ctx context.Context, userID int64, keyID string) error {
+	res, err := p.ext.ExecContext(ctx, "DELETE FROM api_key WHERE id = $1 AND user_id = $2", keyID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete api key: %w", err)
+	}
+
+	if c, _ := res.RowsAffected(); c == 0 {
+		return storage.ErrNotFound
+	}
+
+	return nil
+}
+
+func (p pg) UseAPIKey(ctx context.Context, keyHash string) (model.APIKey, error) {
+	var k apiKey
+	err := p.ext.GetContext(ctx, &k, `
+		UPDATE api_key SET last_used_at = now()
+		WHERE key_hash = $1 AND (expires_at IS NULL OR expires_at > now())
+		RETURNING `+apiKeyColumns,
+		keyHash)
+
+	if err == sql.ErrNoRows {
+		return model.APIKey{}, storage.ErrNotFound
+	}
+
+	if err != nil {
+		return model.APIKey{}, fmt.Errorf("failed to use api key: %w", err)
+	}
+
+	return k.toModel(), nil
+}
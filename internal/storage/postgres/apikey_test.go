@@ -0,0 +1,107 @@
+//+build integration
+
+package postgres
+
+import (
+	"errors"
+	"time"
+
+	"github.com/vliubezny/gstore/internal/model"
+	"github.com/vliubezny/gstore/internal/storage"
+)
+
+const (
+	testAPIKeyID  = "0e37df36-f698-11e6-8dd4-cb9ced3df976"
+	testAPIKeyID2 = "8ab3c8a2-f698-11e6-8dd4-cb9ced3df976"
+)
+
+func (s *postgresTestSuite) TestPg_CreateAPIKey() {
+	_, err := s.db.Exec(`INSERT INTO store_user (email, password_hash) VALUES ('admin@test.com', '123');`)
+	s.Require().NoError(err)
+
+	key := model.APIKey{
+		ID:      testAPIKeyID,
+		UserID:  1,
+		Name:    "import",
+		Prefix:  "gsk_1234",
+		KeyHash: "hash",
+		Scopes:  []model.Permission{model.PermissionPositionWrite},
+	}
+
+	k, err := s.s.(pg).CreateAPIKey(s.ctx, key)
+	s.Require().NoError(err)
+	s.WithinDuration(time.Now(), k.CreatedAt, time.Minute)
+	s.True(k.ExpiresAt.IsZero())
+	s.True(k.LastUsedAt.IsZero())
+
+	key.CreatedAt = k.CreatedAt
+	s.Equal(key, k)
+
+	key.ID, key.UserID = testAPIKeyID2, 100
+	_, err = s.s.(pg).CreateAPIKey(s.ctx, key)
+	s.True(errors.Is(err, storage.ErrUnknownUser))
+}
+
+func (s *postgresTestSuite) TestPg_GetAPIKeys() {
+	_, err := s.db.Exec(`
+		INSERT INTO store_user (email, password_hash) VALUES ('admin@test.com', '123'), ('user@test.com', '123');
+		INSERT INTO api_key (id, user_id, name, prefix, key_hash, scopes, created_at, expires_at) VALUES
+			('0e37df36-f698-11e6-8dd4-cb9ced3df976', 1, 'old', 'gsk_1', 'hash1', '{}', now() - interval '1 day', now() + interval '1 day'),
+			('8ab3c8a2-f698-11e6-8dd4-cb9ced3df976', 1, 'new', 'gsk_2', 'hash2', '{position:write}', now(), NULL),
+			('9b2cde10-f698-11e6-8dd4-cb9ced3df976', 2, 'other', 'gsk_3', 'hash3', '{}', now(), NULL);
+	`)
+	s.Require().NoError(err)
+
+	keys, err := s.s.(pg).GetAPIKeys(s.ctx, 1)
+	s.Require().NoError(err)
+	s.Require().Len(keys, 2)
+	s.Equal("new", keys[0].Name)
+	s.Equal([]model.Permission{model.PermissionPositionWrite}, keys[0].Scopes)
+	s.True(keys[0].ExpiresAt.IsZero())
+	s.Equal("old", keys[1].Name)
+	s.Empty(keys[1].Scopes)
+	s.False(keys[1].ExpiresAt.IsZero())
+
+	keys, err = s.s.(pg).GetAPIKeys(s.ctx, 3)
+	s.Require().NoError(err)
+	s.Empty(keys)
+}
+
+func (s *postgresTestSuite) TestPg_DeleteAPIKey() {
+	_, err := s.db.Exec(`
+		INSERT INTO store_user (email, password_hash) VALUES ('admin@test.com', '123'), ('user@test.com', '123');
+		INSERT INTO api_key (id, user_id, name, prefix, key_hash) VALUES
+			('0e37df36-f698-11e6-8dd4-cb9ced3df976', 1, 'import', 'gsk_1', 'hash1');
+	`)
+	s.Require().NoError(err)
+
+	err = s.s.(pg).DeleteAPIKey(s.ctx, 2, testAPIKeyID)
+	s.True(errors.Is(err, storage.ErrNotFound), "key of other user must not be deleted")
+
+	s.Require().NoError(s.s.(pg).DeleteAPIKey(s.ctx, 1, testAPIKeyID))
+
+	err = s.s.(pg).DeleteAPIKey(s.ctx, 1, testAPIKeyID)
+	s.True(errors.Is(err, storage.ErrNotFound))
+}
+
+func (s *postgresTestSuite) TestPg_UseAPIKey() {
+	_, err := s.db.Exec(`
+		INSERT INTO store_user (email, password_hash) VALUES ('admin@test.com', '123');
+		INSERT INTO api_key (id, user_id, name, prefix, key_hash, expires_at) VALUES
+			('0e37df36-f698-11e6-8dd4-cb9ced3df976', 1, 'import', 'gsk_1', 'hash1', NULL),
+			('8ab3c8a2-f698-11e6-8dd4-cb9ced3df976', 1, 'expired', 'gsk_2', 'hash2', now() - interval '1 second');
+	`)
+	s.Require().NoError(err)
+
+	k, err := s.s.(pg).UseAPIKey(s.ctx, "hash1")
+	s.Require().NoError(err)
+	s.Equal(testAPIKeyID, k.ID)
+	s.Equal(int64(1), k.UserID)
+	s.WithinDuration(time.Now(), k.LastUsedAt, time.Minute)
+
+	_, err = s.s.(pg).UseAPIKey(s.ctx, "hash2")
+	s.True(errors.Is(err, storage.ErrNotFound), "expired key must not be used")
+
+	_, err = s.s.(pg).UseAPIKey(s.ctx, "unknown")
+	s.True(errors.Is(err, storage.ErrNotFound))
+}
@@ -0,0 +1,124 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/vliubezny/gstore/internal/model"
+	"github.com/vliubezny/gstore/internal/storage"
+)
+
+func (p pg) GetCartItems(ctx context.Context, owner model.CartOwner) ([]model.CartItem, error) {
+	column, value := cartOwnerColumn(owner)
+
+	var items []cartItem
+	if err := p.ext.SelectContext(ctx, &items, fmt.Sprintf(`
+		SELECT i.product_id, i.variant_id, i.store_id, i.quantity, i.price, i.currency,
+			p.price AS position_price, p.currency AS position_currency, p.quantity AS position_quantity,
+			p.availability AS position_availability, p.restock_date AS position_restock_date
+		FROM cart c
+		JOIN cart_item i ON i.cart_id = c.id
+		LEFT JOIN position p ON p.variant_id = i.variant_id AND p.store_id = i.store_id
+		WHERE c.%s = $1
+		ORDER BY i.added_at, i.store_id, i.variant_id
+	`, column), value); err != nil {
+		return nil, fmt.Errorf("failed to get cart items: %w", err)
+	}
+
+	data := make([]model.CartItem, len(items))
+	for i, d := range items {
+		data[i] = d.toModel()
+	}
+
+	return data, nil
+}
+
+func (p pg) UpsertCartItem(ctx context.Context, owner model.CartOwner, variantID, storeID, quantity int64) error {
+	column, value := cartOwnerColumn(owner)
+
+	res, err := p.ext.ExecContext(ctx, fmt.Sprintf(`
+		WITH c AS (
+			INSERT INTO cart (%[1]s) SELECT $1 WHERE EXISTS (
+				SELECT 1 FROM position WHERE variant_id = $2 AND store_id = $3
+			)
+			ON CONFLICT (%[1]s) DO UPDATE SET updated_at = now()
+			RETURNING id
+		)
+		INSERT INTO cart_item (cart_id, product_id, variant_id, store_id, quantity, price, currency)
+			SELECT c.id, p.product_id, p.variant_id, p.store_id, $4, p.price, p.currency
+			FROM c, position p WHERE p.variant_id = $2 AND p.store_id = $3
+			ON CONFLICT (cart_id, variant_id, store_id) DO UPDATE SET
+				quantity = EXCLUDED.quantity,
+				price = EXCLUDED.price,
+				currency = EXCLUDED.currency
+	`, column), value, variantID, storeID, quantity)
+
+	if err != nil {
+		return fmt.Errorf("failed to upsert cart item: %w", err)
+	}
+
+	if c, _ := res.RowsAffected(); c == 0 {
+		return storage.ErrUnknownPosition
+	}
+
+	return nil
+}
+
+func (p pg) DeleteCartItem(ctx context.Context, owner model.CartOwner, variantID, storeID int64) error {
+	column, value := cartOwnerColumn(owner)
+
+	res, err := p.ext.ExecContext(ctx, fmt.Sprintf(`
+		DELETE FROM cart_item i USING cart c
+		WHERE i.cart_id = c.id AND c.%s = $1 AND i.variant_id = $2 AND i.store_id = $3
+	`, column), value, variantID, storeID)
+
+	if err != nil {
+		return fmt.Errorf("failed to delete cart item: %w", err)
+	}
+
+	if c, _ := res.RowsAffected(); c == 0 {
+		return storage.ErrNotFound
+	}
+
+	return nil
+}
+
+func (p pg) DeleteCart(ctx context.Context, owner model.CartOwner) error {
+	column, value := cartOwnerColumn(owner)
+
+	if _, err := p.ext.ExecContext(ctx, fmt.Sprintf("DELETE FROM cart WHERE %s = $1", column), value); err != nil {
+		return fmt.Errorf("failed to delete cart: %w", err)
+	}
+
+	return nil
+}
+
+func (p pg) MergeCarts(ctx context.Context, token string, userID, maxQuantity int64) error {
+	// items are read from the statement snapshot, so they are visible despite cascade deletion of anonymous cart
+	if _, err := p.ext.ExecContext(ctx, `
+		WITH anon AS (
+			DELETE FROM cart WHERE token = $1 RETURNING id
+		), owner AS (
+			INSERT INTO cart (user_id) SELECT $2 FROM anon
+			ON CONFLICT (user_id) DO UPDATE SET updated_at = now()
+			RETURNING id
+		)
+		INSERT INTO cart_item (cart_id, product_id, variant_id, store_id, quantity, price, currency, added_at)
+			SELECT owner.id, i.product_id, i.variant_id, i.store_id, LEAST(i.quantity, $3), i.price, i.currency, i.added_at
+			FROM cart_item i, anon, owner WHERE i.cart_id = anon.id
+			ON CONFLICT (cart_id, variant_id, store_id) DO UPDATE SET
+				quantity = LEAST(cart_item.quantity + EXCLUDED.quantity, $3)
+	`, token, userID, maxQuantity); err != nil {
+		return fmt.Errorf("failed to merge carts: %w", err)
+	}
+
+	return nil
+}
+
+// cartOwnerColumn returns column identifying the cart of the owner along with its value.
+func cartOwnerColumn(owner model.CartOwner) (string, interface{}) {
+	if owner.UserID != 0 {
+		return "user_id", owner.UserID
+	}
+	return "token", owner.Token
+}
@@ -0,0 +1,156 @@
+//+build integration
+
+package postgres
+
+import (
+	"errors"
+
+	"github.com/shopspring/decimal"
+	"github.com/vliubezny/gstore/internal/model"
+	"github.com/vliubezny/gstore/internal/storage"
+)
+
+const testCartToken = "0e37df36-f698-11e6-8dd4-cb9ced3df976"
+
+func (s *postgresTestSuite) setupCartData() {
+	_, err := s.db.Exec(`
+		INSERT INTO product (category_id, name, description) VALUES (1, 'iPhone 11', 'Old iphone');
+		INSERT INTO variant (product_id, sku) VALUES (1, 'IP11-64'), (1, 'IP11-128');
+		INSERT INTO store (name) VALUES ('iStore');
+		INSERT INTO store_user (email, password_hash) VALUES ('user@test.com', '123');
+		INSERT INTO position (product_id, variant_id, store_id, price, quantity) VALUES
+			(1, 1, 1, 100, 5),
+			(1, 2, 1, 150, 5);
+	`)
+	s.Require().NoError(err)
+}
+
+func (s *postgresTestSuite) TestPg_UpsertCartItem() {
+	s.setupCartData()
+	owner := model.CartOwner{UserID: 1}
+
+	err := s.s.(pg).UpsertCartItem(s.ctx, owner, 1, 1, 2)
+	s.Require().NoError(err)
+
+	_, err = s.db.Exec("UPDATE position SET price = 120 WHERE variant_id = 1 AND store_id = 1")
+	s.Require().NoError(err)
+
+	items, err := s.s.(pg).GetCartItems(s.ctx, owner)
+	s.Require().NoError(err)
+
+	s.Equal([]model.CartItem{
+		{
+			ProductID: 1, VariantID: 1, StoreID: 1, Quantity: 2, Price: decimal.NewFromInt(100), Currency: "USD",
+			Position: model.Position{
+				ProductID: 1, VariantID: 1, StoreID: 1, Price: decimal.NewFromInt(120), Currency: "USD",
+				Quantity: 5, Availability: model.AvailabilityInStock,
+			},
+		},
+	}, items)
+
+	// setting quantity again accepts current price
+	err = s.s.(pg).UpsertCartItem(s.ctx, owner, 1, 1, 3)
+	s.Require().NoError(err)
+
+	items, err = s.s.(pg).GetCartItems(s.ctx, owner)
+	s.Require().NoError(err)
+	s.Require().Len(items, 1)
+	s.Equal(int64(3), items[0].Quantity)
+	s.Equal(decimal.NewFromInt(120), items[0].Price)
+
+	err = s.s.(pg).UpsertCartItem(s.ctx, owner, 1, 100500, 1)
+	s.True(errors.Is(err, storage.ErrUnknownPosition), err)
+}
+
+func (s *postgresTestSuite) TestPg_GetCartItems_deletedPosition() {
+	s.setupCartData()
+	owner := model.CartOwner{Token: testCartToken}
+
+	err := s.s.(pg).UpsertCartItem(s.ctx, owner, 1, 1, 1)
+	s.Require().NoError(err)
+
+	err = s.s.(pg).DeletePosition(s.ctx, 1, 1)
+	s.Require().NoError(err)
+
+	items, err := s.s.(pg).GetCartItems(s.ctx, owner)
+	s.Require().NoError(err)
+
+	s.Equal([]model.CartItem{
+		{ProductID: 1, VariantID: 1, StoreID: 1, Quantity: 1, Price: decimal.NewFromInt(100), Currency: "USD"},
+	}, items)
+
+	items, err = s.s.(pg).GetCartItems(s.ctx, model.CartOwner{UserID: 1})
+	s.Require().NoError(err)
+	s.Empty(items)
+}
+
+func (s *postgresTestSuite) TestPg_DeleteCartItem() {
+	s.setupCartData()
+	owner := model.CartOwner{UserID: 1}
+
+	err := s.s.(pg).UpsertCartItem(s.ctx, owner, 1, 1, 1)
+	s.Require().NoError(err)
+
+	err = s.s.(pg).DeleteCartItem(s.ctx, owner, 1, 1)
+	s.Require().NoError(err)
+
+	err = s.s.(pg).DeleteCartItem(s.ctx, owner, 1, 1)
+	s.True(errors.Is(err, storage.ErrNotFound), err)
+}
+
+func (s *postgresTestSuite) TestPg_DeleteCart() {
+	s.setupCartData()
+	owner := model.CartOwner{UserID: 1}
+
+	err := s.s.(pg).UpsertCartItem(s.ctx, owner, 1, 1, 1)
+	s.Require().NoError(err)
+
+	err = s.s.(pg).DeleteCart(s.ctx, owner)
+	s.Require().NoError(err)
+
+	items, err := s.s.(pg).GetCartItems(s.ctx, owner)
+	s.Require().NoError(err)
+	s.Empty(items)
+}
+
+func (s *postgresTestSuite) TestPg_MergeCarts() {
+	s.setupCartData()
+	anon := model.CartOwner{Token: testCartToken}
+	owner := model.CartOwner{UserID: 1}
+
+	s.Require().NoError(s.s.(pg).UpsertCartItem(s.ctx, owner, 1, 1, 3))
+	s.Require().NoError(s.s.(pg).UpsertCartItem(s.ctx, anon, 1, 1, 4))
+	s.Require().NoError(s.s.(pg).UpsertCartItem(s.ctx, anon, 2, 1, 1))
+
+	err := s.s.(pg).MergeCarts(s.ctx, testCartToken, 1, 5)
+	s.Require().NoError(err)
+
+	items, err := s.s.(pg).GetCartItems(s.ctx, owner)
+	s.Require().NoError(err)
+	s.Require().Len(items, 2)
+	s.Equal(int64(5), items[0].Quantity)
+	s.Equal(int64(2), items[1].VariantID)
+	s.Equal(int64(1), items[1].Quantity)
+
+	items, err = s.s.(pg).GetCartItems(s.ctx, anon)
+	s.Require().NoError(err)
+	s.Empty(items)
+
+	// merging of missing anonymous cart changes nothing
+	err = s.s.(pg).MergeCarts(s.ctx, testCartToken, 1, 5)
+	s.Require().NoError(err)
+}
+
+func (s *postgresTestSuite) TestPg_GetPosition() {
+	s.setupCartData()
+
+	pos, err := s.s.(pg).GetPosition(s.ctx, 1, 1)
+	s.Require().NoError(err)
+	s.Equal(model.Position{
+		ProductID: 1, VariantID: 1, StoreID: 1, Price: decimal.NewFromInt(100), Currency: "USD",
+		Quantity: 5, Availability: model.AvailabilityInStock,
+	}, pos)
+
+	_, err = s.s.(pg).GetPosition(s.ctx, 1, 100500)
+	s.True(errors.Is(err, storage.ErrNotFound), err)
+}
@@ -5,14 +5,45 @@ import (
 	"database/sql"
 	"fmt"
 
+	"github.com/lib/pq"
 	"github.com/vliubezny/gstore/internal/model"
 	"github.com/vliubezny/gstore/internal/storage"
 )
 
-func (p pg) GetCategories(ctx context.Context) ([]model.Category, error) {
+const parentIDFKConstraint = "category_parent_id_fkey"
+
+// categoryTreeLockID is a key of advisory lock that serializes changes of category tree.
+const categoryTreeLockID = 0x63617467 // "catg"
+
+// subtreeQuery returns query that selects IDs of the category and all its descendants,
+// categoryID is a placeholder of the category ID argument.
+// UNION drops repeated rows, so the query terminates even if categories form a cycle.
+func subtreeQuery(categoryID string) string {
+	return `
+		WITH RECURSIVE subtree AS (
+			SELECT id FROM category WHERE id = ` + categoryID + `
+			UNION
+			SELECT c.id FROM category c JOIN subtree s ON c.parent_id = s.id
+		) SELECT id FROM subtree`
+}
+
+func (p pg) GetCategories(ctx context.Context, page model.Page) ([]model.Category, string, error) {
+	after, err := decodeCursor(page.Cursor)
+	if err != nil {
+		return nil, "", err
+	}
+
 	var categories []category
-	if err := p.ext.SelectContext(ctx, &categories, "SELECT id, name FROM category"); err != nil {
-		return nil, err
+	if err := p.ext.SelectContext(ctx, &categories, `
+		SELECT id, name, parent_id, attribute_schema FROM category WHERE id > $1 ORDER BY id LIMIT $2
+	`, after.ID, page.Limit+1); err != nil {
+		return nil, "", err
+	}
+
+	var next string
+	if len(categories) > page.Limit {
+		categories = categories[:page.Limit]
+		next = encodeCursor(cursor{ID: categories[page.Limit-1].ID})
 	}
 
 	data := make([]model.Category, len(categories))
@@ -20,12 +51,12 @@ func (p pg) GetCategories(ctx context.Context) ([]model.Category, error) {
 		data[i] = c.toModel()
 	}
 
-	return data, nil
+	return data, next, nil
 }
 
 func (p pg) GetCategory(ctx context.Context, categoryID int64) (model.Category, error) {
 	var c category
-	err := p.ext.GetContext(ctx, &c, "SELECT id, name FROM category WHERE id = $1", categoryID)
+	err := p.ext.GetContext(ctx, &c, "SELECT id, name, parent_id, attribute_schema FROM category WHERE id = $1", categoryID)
 
 	if err == sql.ErrNoRows {
 		return model.Category{}, storage.ErrNotFound
@@ -38,25 +69,76 @@ func (p pg) GetCategory(ctx context.Context, categoryID int64) (model.Category,
 	return c.toModel(), nil
 }
 
-func (p pg) CreateCategory(ctx context.Context, category model.Category) (model.Category, error) {
-	if err := p.ext.GetContext(ctx, &category.ID, "INSERT INTO category (name) VALUES ($1) RETURNING id", category.Name); err != nil {
-		return model.Category{}, fmt.Errorf("failed to create category: %w", err)
-	}
-	return category, nil
-}
+func (p pg) GetCategoryTree(ctx context.Context, categoryID int64) ([]model.Category, error) {
+	var categories []category
 
-func (p pg) UpdateCategory(ctx context.Context, category model.Category) error {
-	res, err := p.ext.ExecContext(ctx, "UPDATE category SET name = $1 WHERE id = $2", category.Name, category.ID)
+	var err error
+	if categoryID == 0 {
+		err = p.ext.SelectContext(ctx, &categories, "SELECT id, name, parent_id, attribute_schema FROM category ORDER BY id")
+	} else {
+		err = p.ext.SelectContext(ctx, &categories, `
+			SELECT id, name, parent_id, attribute_schema FROM category WHERE id IN (`+subtreeQuery("$1")+`) ORDER BY id
+		`, categoryID)
+	}
 
 	if err != nil {
-		return fmt.Errorf("failed to update category: %w", err)
+		return nil, fmt.Errorf("failed to get category tree: %w", err)
 	}
 
-	if c, _ := res.RowsAffected(); c == 0 {
-		return storage.ErrNotFound
+	if categoryID != 0 && len(categories) == 0 {
+		return nil, storage.ErrNotFound
 	}
 
-	return nil
+	data := make([]model.Category, len(categories))
+	for i, c := range categories {
+		data[i] = c.toModel()
+	}
+
+	return data, nil
+}
+
+func (p pg) CreateCategory(ctx context.Context, category model.Category) (model.Category, error) {
+	if err := p.ext.GetContext(ctx, &category.ID, `
+		INSERT INTO category (name, parent_id, attribute_schema) VALUES ($1, $2, $3) RETURNING id
+	`, category.Name, nullID(category.ParentID), newAttributeSchema(category.Schema)); err != nil {
+		if err, ok := err.(*pq.Error); ok && err.Constraint == parentIDFKConstraint {
+			return model.Category{}, storage.ErrUnknownCategory
+		}
+		return model.Category{}, fmt.Errorf("failed to create category: %w", err)
+	}
+	return category, nil
+}
+
+func (p pg) UpdateCategory(ctx context.Context, category model.Category) error {
+	// read committed isolation lets the cycle check see moves committed while waiting for the lock
+	return p.inTxWithIsolation(ctx, sql.LevelReadCommitted, func(tx pg) error {
+		// concurrent moves are serialized, otherwise moving A under B and B under A both pass the check
+		if _, err := tx.ext.ExecContext(ctx, "SELECT pg_advisory_xact_lock($1)", categoryTreeLockID); err != nil {
+			return fmt.Errorf("failed to lock category tree: %w", err)
+		}
+
+		// the category must not become a parent of itself or any of its ancestors
+		res, err := tx.ext.ExecContext(ctx, `
+			UPDATE category SET name = $2, parent_id = $3, attribute_schema = $4
+			WHERE id = $1 AND ($3::integer IS NULL OR $3 NOT IN (`+subtreeQuery("$1")+`))
+		`, category.ID, category.Name, nullID(category.ParentID), newAttributeSchema(category.Schema))
+
+		if err != nil {
+			if err, ok := err.(*pq.Error); ok && err.Constraint == parentIDFKConstraint {
+				return storage.ErrUnknownCategory
+			}
+			return fmt.Errorf("failed to update category: %w", err)
+		}
+
+		if c, _ := res.RowsAffected(); c == 0 {
+			if _, err := tx.GetCategory(ctx, category.ID); err != nil {
+				return err
+			}
+			return storage.ErrCategoryCycle
+		}
+
+		return nil
+	})
 }
 
 func (p pg) DeleteCategory(ctx context.Context, categoryID int64) error {
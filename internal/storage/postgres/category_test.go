@@ -10,7 +10,7 @@ import (
 )
 
 func (s *postgresTestSuite) TestPg_GetCategories() {
-	categories, err := s.s.GetCategories(s.ctx)
+	categories, next, err := s.s.GetCategories(s.ctx, model.Page{Limit: 6})
 	s.Require().NoError(err)
 
 	s.Equal([]model.Category{
@@ -20,11 +20,25 @@ func (s *postgresTestSuite) TestPg_GetCategories() {
 		{ID: 4, Name: "Arts & Crafts"},
 		{ID: 5, Name: "Health & Household"},
 		{ID: 6, Name: "Automotive"},
+	}, categories)
+	s.Require().NotEmpty(next)
+
+	categories, next, err = s.s.GetCategories(s.ctx, model.Page{Limit: 6, Cursor: next})
+	s.Require().NoError(err)
+
+	s.Equal([]model.Category{
 		{ID: 7, Name: "Pet supplies"},
 		{ID: 8, Name: "Software"},
 		{ID: 9, Name: "Sports & Outdoors"},
 		{ID: 10, Name: "Toys and Games"},
 	}, categories)
+	s.Empty(next)
+}
+
+func (s *postgresTestSuite) TestPg_GetCategories_ErrInvalidCursor() {
+	_, _, err := s.s.GetCategories(s.ctx, model.Page{Limit: 6, Cursor: "invalid"})
+
+	s.True(errors.Is(err, storage.ErrInvalidCursor))
 }
 
 func (s *postgresTestSuite) TestPg_GetCategory() {
@@ -40,6 +54,33 @@ func (s *postgresTestSuite) TestPg_GetCategory_ErrNotFound() {
 	s.True(errors.Is(err, storage.ErrNotFound))
 }
 
+func (s *postgresTestSuite) TestPg_GetCategoryTree() {
+	_, err := s.db.Exec(`UPDATE category SET parent_id = 1 WHERE id IN (2, 3);
+		UPDATE category SET parent_id = 2 WHERE id = 8;`)
+	s.Require().NoError(err)
+
+	categories, err := s.s.GetCategoryTree(s.ctx, 1)
+	s.Require().NoError(err)
+
+	s.Equal([]model.Category{
+		{ID: 1, Name: "Electronics"},
+		{ID: 2, Name: "Computers", ParentID: 1},
+		{ID: 3, Name: "Smart Home", ParentID: 1},
+		{ID: 8, Name: "Software", ParentID: 2},
+	}, categories)
+
+	categories, err = s.s.GetCategoryTree(s.ctx, 0)
+	s.Require().NoError(err)
+
+	s.Len(categories, 10)
+}
+
+func (s *postgresTestSuite) TestPg_GetCategoryTree_ErrNotFound() {
+	_, err := s.s.GetCategoryTree(s.ctx, 100500)
+
+	s.True(errors.Is(err, storage.ErrNotFound))
+}
+
 func (s *postgresTestSuite) TestPg_CreateCategory() {
 	c := model.Category{
 		Name: "test category",
@@ -75,6 +116,93 @@ func (s *postgresTestSuite) TestPg_UpdateCategory() {
 	s.Equal(c.Name, name)
 }
 
+func (s *postgresTestSuite) TestPg_CreateCategory_WithParent() {
+	c, err := s.s.CreateCategory(s.ctx, model.Category{Name: "test category", ParentID: 1})
+	s.Require().NoError(err)
+
+	r := s.db.QueryRow("SELECT parent_id FROM category WHERE id = $1", c.ID)
+	var parentID int64
+	err = r.Scan(&parentID)
+	s.Require().NoError(err)
+
+	s.Equal(int64(1), parentID)
+}
+
+func (s *postgresTestSuite) TestPg_CreateCategory_WithSchema() {
+	c := model.Category{
+		Name: "test category",
+		Schema: []model.Attribute{
+			{Name: "ram", Type: model.AttributeNumber, Unit: "GB", Required: true},
+			{Name: "color", Type: model.AttributeEnum, Values: []string{"black", "silver"}},
+		},
+	}
+
+	c, err := s.s.CreateCategory(s.ctx, c)
+	s.Require().NoError(err)
+
+	res, err := s.s.GetCategory(s.ctx, c.ID)
+	s.Require().NoError(err)
+
+	s.Equal(c, res)
+
+	c.Schema = c.Schema[:1]
+	err = s.s.UpdateCategory(s.ctx, c)
+	s.Require().NoError(err)
+
+	res, err = s.s.GetCategory(s.ctx, c.ID)
+	s.Require().NoError(err)
+
+	s.Equal(c, res)
+}
+
+func (s *postgresTestSuite) TestPg_CreateCategory_ErrUnknownCategory() {
+	_, err := s.s.CreateCategory(s.ctx, model.Category{Name: "test category", ParentID: 100500})
+
+	s.True(errors.Is(err, storage.ErrUnknownCategory))
+}
+
+func (s *postgresTestSuite) TestPg_UpdateCategory_ErrCategoryCycle() {
+	_, err := s.db.Exec(`UPDATE category SET parent_id = 1 WHERE id = 2;`)
+	s.Require().NoError(err)
+
+	err = s.s.UpdateCategory(s.ctx, model.Category{ID: 1, Name: "Electronics", ParentID: 2})
+
+	s.True(errors.Is(err, storage.ErrCategoryCycle))
+}
+
+func (s *postgresTestSuite) TestPg_UpdateCategory_ConcurrentMoves() {
+	_, err := s.db.Exec(`UPDATE category SET parent_id = NULL WHERE id IN (1, 2);`)
+	s.Require().NoError(err)
+
+	errs := make(chan error, 2)
+	go func() { errs <- s.s.UpdateCategory(s.ctx, model.Category{ID: 1, Name: "Electronics", ParentID: 2}) }()
+	go func() { errs <- s.s.UpdateCategory(s.ctx, model.Category{ID: 2, Name: "Computers", ParentID: 1}) }()
+
+	var cycles int
+	for i := 0; i < 2; i++ {
+		if err := <-errs; err != nil {
+			s.Require().True(errors.Is(err, storage.ErrCategoryCycle), err)
+			cycles++
+		}
+	}
+	s.Equal(1, cycles, "one of the moves must be rejected")
+}
+
+func (s *postgresTestSuite) TestPg_GetCategoryTree_Cycle() {
+	_, err := s.db.Exec(`UPDATE category SET parent_id = 2 WHERE id = 1; UPDATE category SET parent_id = 1 WHERE id = 2;`)
+	s.Require().NoError(err)
+
+	categories, err := s.s.GetCategoryTree(s.ctx, 1)
+	s.Require().NoError(err, "query must terminate on cycle")
+	s.NotEmpty(categories)
+}
+
+func (s *postgresTestSuite) TestPg_UpdateCategory_ErrUnknownCategory() {
+	err := s.s.UpdateCategory(s.ctx, model.Category{ID: 1, Name: "Electronics", ParentID: 100500})
+
+	s.True(errors.Is(err, storage.ErrUnknownCategory))
+}
+
 func (s *postgresTestSuite) TestPg_UpdateCategory_ErrNotFound() {
 	c := model.Category{
 		ID:   100500,
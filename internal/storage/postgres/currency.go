@@ -0,0 +1,61 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/vliubezny/gstore/internal/model"
+	"github.com/vliubezny/gstore/internal/storage"
+)
+
+func (p pg) GetExchangeRates(ctx context.Context) ([]model.ExchangeRate, error) {
+	var rates []exchangeRate
+	if err := p.ext.SelectContext(ctx, &rates, `
+		SELECT currency, rate, updated_at FROM exchange_rate ORDER BY currency
+	`); err != nil {
+		return nil, fmt.Errorf("failed to get exchange rates: %w", err)
+	}
+
+	data := make([]model.ExchangeRate, len(rates))
+	for i, r := range rates {
+		data[i] = r.toModel()
+	}
+
+	return data, nil
+}
+
+func (p pg) UpsertExchangeRates(ctx context.Context, rates []model.ExchangeRate) error {
+	currencies := make([]string, len(rates))
+	values := make([]string, len(rates))
+	for i, r := range rates {
+		currencies[i] = r.Currency
+		values[i] = r.Rate.String()
+	}
+
+	if _, err := p.ext.ExecContext(ctx, `
+		INSERT INTO exchange_rate (currency, rate)
+			SELECT * FROM unnest($1::text[], $2::numeric[])
+			ON CONFLICT(currency) DO UPDATE SET rate = EXCLUDED.rate, updated_at = now()
+	`, pq.Array(currencies), pq.Array(values)); err != nil {
+		return fmt.Errorf("failed to upsert exchange rates: %w", err)
+	}
+
+	return nil
+}
+
+func (p pg) DeleteExchangeRate(ctx context.Context, currency string) error {
+	res, err := p.ext.ExecContext(ctx, "DELETE FROM exchange_rate WHERE currency = $1", currency)
+	if err != nil {
+		if err, ok := err.(*pq.Error); ok && err.Constraint == positionCurrencyFKConstraint {
+			return storage.ErrCurrencyInUse
+		}
+		return fmt.Errorf("failed to delete exchange rate: %w", err)
+	}
+
+	if c, _ := res.RowsAffected(); c == 0 {
+		return storage.ErrNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,84 @@
+//+build integration
+
+package postgres
+
+import (
+	"errors"
+
+	"github.com/shopspring/decimal"
+	"github.com/vliubezny/gstore/internal/model"
+	"github.com/vliubezny/gstore/internal/storage"
+)
+
+func (s *postgresTestSuite) TestPg_UpsertExchangeRates() {
+	err := s.s.UpsertExchangeRates(s.ctx, []model.ExchangeRate{
+		{Currency: "EUR", Rate: decimal.RequireFromString("0.845")},
+		{Currency: "JPY", Rate: decimal.RequireFromString("104.55")},
+	})
+	s.Require().NoError(err)
+
+	err = s.s.UpsertExchangeRates(s.ctx, []model.ExchangeRate{
+		{Currency: "EUR", Rate: decimal.RequireFromString("0.85")},
+	})
+	s.Require().NoError(err)
+
+	rates, err := s.s.GetExchangeRates(s.ctx)
+	s.Require().NoError(err)
+	s.Require().Len(rates, 3)
+
+	s.Equal("EUR", rates[0].Currency)
+	s.Equal(decimal.RequireFromString("0.85"), rates[0].Rate)
+	s.False(rates[0].UpdatedAt.IsZero())
+	s.Equal("JPY", rates[1].Currency)
+	s.Equal(decimal.RequireFromString("104.55"), rates[1].Rate)
+	s.Equal(model.BaseCurrency, rates[2].Currency)
+	s.Equal(decimal.NewFromInt(1), rates[2].Rate)
+}
+
+func (s *postgresTestSuite) TestPg_DeleteExchangeRate() {
+	_, err := s.db.Exec(`
+		INSERT INTO exchange_rate (currency, rate) VALUES ('EUR', 0.845), ('JPY', 104.55);
+		INSERT INTO product (category_id, name, description) VALUES (1, 'iPhone 11', 'Old iphone');
+		INSERT INTO variant (product_id, sku) VALUES (1, 'IP11');
+		INSERT INTO store (name) VALUES ('iStore');
+		INSERT INTO position (product_id, variant_id, store_id, price, currency) VALUES (1, 1, 1, 100, 'EUR');
+	`)
+	s.Require().NoError(err)
+
+	s.Require().NoError(s.s.DeleteExchangeRate(s.ctx, "JPY"))
+
+	err = s.s.DeleteExchangeRate(s.ctx, "JPY")
+	s.True(errors.Is(err, storage.ErrNotFound))
+
+	err = s.s.DeleteExchangeRate(s.ctx, "EUR")
+	s.True(errors.Is(err, storage.ErrCurrencyInUse))
+}
+
+func (s *postgresTestSuite) TestPg_UpsertPosition_Currency() {
+	_, err := s.db.Exec(`
+		INSERT INTO exchange_rate (currency, rate) VALUES ('EUR', 0.845);
+		INSERT INTO product (category_id, name, description) VALUES (1, 'iPhone 11', 'Old iphone');
+		INSERT INTO variant (product_id, sku) VALUES (1, 'IP11');
+		INSERT INTO store (name) VALUES ('iStore');
+	`)
+	s.Require().NoError(err)
+
+	p := model.Position{VariantID: 1, StoreID: 1, Price: decimal.NewFromInt(100), Currency: "USD", Availability: model.AvailabilityInStock}
+	_, err = s.s.UpsertPosition(s.ctx, p, 0)
+	s.Require().NoError(err)
+
+	// same amount in another currency is a price change
+	p.Currency = "EUR"
+	_, err = s.s.UpsertPosition(s.ctx, p, 0)
+	s.Require().NoError(err)
+
+	changes, err := s.s.GetPriceHistory(s.ctx, 1, model.PriceHistoryFilter{})
+	s.Require().NoError(err)
+	s.Require().Len(changes, 2)
+	s.Equal("USD", changes[0].Currency)
+	s.Equal("EUR", changes[1].Currency)
+
+	p.Currency = "GBP"
+	_, err = s.s.UpsertPosition(s.ctx, p, 0)
+	s.True(errors.Is(err, storage.ErrUnknownCurrency))
+}
@@ -0,0 +1,45 @@
+package postgres
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"github.com/shopspring/decimal"
+	"github.com/vliubezny/gstore/internal/storage"
+)
+
+// cursor represents position of the last item in keyset pagination.
+type cursor struct {
+	ID   int64  `json:"id"`
+	Sort string `json:"sort,omitempty"`
+	// VariantID is a tiebreaker of positions ordered by store.
+	VariantID int64            `json:"variantId,omitempty"`
+	Name      string           `json:"name,omitempty"`
+	Price     *decimal.Decimal `json:"price,omitempty"`
+	Rank      *float64         `json:"rank,omitempty"`
+}
+
+func encodeCursor(c cursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor parses opaque cursor, empty value results in the cursor of the first page.
+func decodeCursor(s string) (cursor, error) {
+	var c cursor
+	if s == "" {
+		return c, nil
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return cursor{}, fmt.Errorf("%w: %v", storage.ErrInvalidCursor, err)
+	}
+
+	if err := json.Unmarshal(data, &c); err != nil {
+		return cursor{}, fmt.Errorf("%w: %v", storage.ErrInvalidCursor, err)
+	}
+
+	return c, nil
+}
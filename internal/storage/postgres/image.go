@@ -0,0 +1,87 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/vliubezny/gstore/internal/model"
+	"github.com/vliubezny/gstore/internal/storage"
+)
+
+const imageProductIDFKConstraint = "product_image_product_id_fkey"
+
+func (p pg) GetProductImages(ctx context.Context, productIDs []int64) ([]model.Image, error) {
+	var images []image
+	if err := p.ext.SelectContext(ctx, &images, `
+		SELECT id, product_id, key, content_type, width, height, position
+		FROM product_image
+		WHERE product_id = ANY($1)
+		ORDER BY product_id, position, id
+	`, pq.Array(productIDs)); err != nil {
+		return nil, fmt.Errorf("failed to get product images: %w", err)
+	}
+
+	data := make([]model.Image, len(images))
+	for i, img := range images {
+		data[i] = img.toModel()
+	}
+
+	return data, nil
+}
+
+func (p pg) CreateProductImage(ctx context.Context, img model.Image) (model.Image, error) {
+	row := p.ext.QueryRowxContext(ctx, `
+		INSERT INTO product_image (product_id, key, content_type, width, height, position)
+		SELECT $1, $2, $3, $4, $5, COALESCE(max(position) + 1, 0) FROM product_image WHERE product_id = $1
+		RETURNING id, position
+	`, img.ProductID, img.Key, img.ContentType, img.Width, img.Height)
+
+	if err := row.Scan(&img.ID, &img.Position); err != nil {
+		if err, ok := err.(*pq.Error); ok && err.Constraint == imageProductIDFKConstraint {
+			return model.Image{}, storage.ErrUnknownProduct
+		}
+		return model.Image{}, fmt.Errorf("failed to create product image: %w", err)
+	}
+
+	return img, nil
+}
+
+func (p pg) UpdateProductImagePositions(ctx context.Context, productID int64, imageIDs []int64) error {
+	// positions are updated only if all images belong to the product
+	res, err := p.ext.ExecContext(ctx, `
+		UPDATE product_image i SET position = o.position - 1
+		FROM unnest($2::integer[]) WITH ORDINALITY o(id, position)
+		WHERE i.id = o.id AND i.product_id = $1
+		AND (SELECT count(*) FROM product_image WHERE product_id = $1 AND id = ANY($2)) = cardinality($2::integer[])
+	`, productID, pq.Array(imageIDs))
+
+	if err != nil {
+		return fmt.Errorf("failed to update product image positions: %w", err)
+	}
+
+	if c, _ := res.RowsAffected(); c != int64(len(imageIDs)) {
+		return storage.ErrNotFound
+	}
+
+	return nil
+}
+
+func (p pg) DeleteProductImage(ctx context.Context, productID, imageID int64) (model.Image, error) {
+	var img image
+	err := p.ext.GetContext(ctx, &img, `
+		DELETE FROM product_image WHERE product_id = $1 AND id = $2
+		RETURNING id, product_id, key, content_type, width, height, position
+	`, productID, imageID)
+
+	if err == sql.ErrNoRows {
+		return model.Image{}, storage.ErrNotFound
+	}
+
+	if err != nil {
+		return model.Image{}, fmt.Errorf("failed to delete product image: %w", err)
+	}
+
+	return img.toModel(), nil
+}
@@ -0,0 +1,99 @@
+//+build integration
+
+package postgres
+
+import (
+	"errors"
+
+	"github.com/vliubezny/gstore/internal/model"
+	"github.com/vliubezny/gstore/internal/storage"
+)
+
+func (s *postgresTestSuite) TestPg_GetProductImages() {
+	_, err := s.db.Exec(`
+		INSERT INTO product (category_id, name, description) VALUES
+			(1, 'iPhone 11', 'Old iphone'),
+			(1, 'iPhone 12', 'New iphone');
+		INSERT INTO product_image (product_id, key, content_type, width, height, position) VALUES
+			(1, 'products/1/a.jpg', 'image/jpeg', 300, 200, 1),
+			(1, 'products/1/b.jpg', 'image/jpeg', 300, 200, 0),
+			(2, 'products/2/c.png', 'image/png', 100, 100, 0);
+	`)
+	s.Require().NoError(err)
+
+	images, err := s.s.GetProductImages(s.ctx, []int64{1, 2})
+	s.Require().NoError(err)
+
+	s.Equal([]model.Image{
+		{ID: 2, ProductID: 1, Key: "products/1/b.jpg", ContentType: "image/jpeg", Width: 300, Height: 200, Position: 0},
+		{ID: 1, ProductID: 1, Key: "products/1/a.jpg", ContentType: "image/jpeg", Width: 300, Height: 200, Position: 1},
+		{ID: 3, ProductID: 2, Key: "products/2/c.png", ContentType: "image/png", Width: 100, Height: 100, Position: 0},
+	}, images)
+}
+
+func (s *postgresTestSuite) TestPg_CreateProductImage() {
+	_, err := s.db.Exec(`INSERT INTO product (category_id, name, description) VALUES (1, 'iPhone 11', 'Old iphone');`)
+	s.Require().NoError(err)
+
+	img, err := s.s.CreateProductImage(s.ctx, model.Image{ProductID: 1, Key: "products/1/a.jpg", ContentType: "image/jpeg", Width: 300, Height: 200})
+	s.Require().NoError(err)
+
+	s.Equal(model.Image{ID: 1, ProductID: 1, Key: "products/1/a.jpg", ContentType: "image/jpeg", Width: 300, Height: 200, Position: 0}, img)
+
+	img, err = s.s.CreateProductImage(s.ctx, model.Image{ProductID: 1, Key: "products/1/b.jpg", ContentType: "image/jpeg", Width: 300, Height: 200})
+	s.Require().NoError(err)
+
+	s.Equal(1, img.Position)
+}
+
+func (s *postgresTestSuite) TestPg_CreateProductImage_ErrUnknownProduct() {
+	_, err := s.s.CreateProductImage(s.ctx, model.Image{ProductID: 100500, Key: "products/1/a.jpg", ContentType: "image/jpeg", Width: 300, Height: 200})
+
+	s.True(errors.Is(err, storage.ErrUnknownProduct))
+}
+
+func (s *postgresTestSuite) TestPg_UpdateProductImagePositions() {
+	_, err := s.db.Exec(`
+		INSERT INTO product (category_id, name, description) VALUES
+			(1, 'iPhone 11', 'Old iphone'),
+			(1, 'iPhone 12', 'New iphone');
+		INSERT INTO product_image (product_id, key, content_type, width, height, position) VALUES
+			(1, 'products/1/a.jpg', 'image/jpeg', 300, 200, 0),
+			(1, 'products/1/b.jpg', 'image/jpeg', 300, 200, 1),
+			(2, 'products/2/c.png', 'image/png', 100, 100, 0);
+	`)
+	s.Require().NoError(err)
+
+	err = s.s.UpdateProductImagePositions(s.ctx, 1, []int64{2, 1})
+	s.Require().NoError(err)
+
+	images, err := s.s.GetProductImages(s.ctx, []int64{1})
+	s.Require().NoError(err)
+
+	s.Equal([]int64{2, 1}, []int64{images[0].ID, images[1].ID})
+
+	err = s.s.UpdateProductImagePositions(s.ctx, 1, []int64{1, 3})
+	s.True(errors.Is(err, storage.ErrNotFound))
+
+	images, err = s.s.GetProductImages(s.ctx, []int64{1})
+	s.Require().NoError(err)
+
+	s.Equal([]int64{2, 1}, []int64{images[0].ID, images[1].ID})
+}
+
+func (s *postgresTestSuite) TestPg_DeleteProductImage() {
+	_, err := s.db.Exec(`
+		INSERT INTO product (category_id, name, description) VALUES (1, 'iPhone 11', 'Old iphone');
+		INSERT INTO product_image (product_id, key, content_type, width, height, position) VALUES
+			(1, 'products/1/a.jpg', 'image/jpeg', 300, 200, 0);
+	`)
+	s.Require().NoError(err)
+
+	img, err := s.s.DeleteProductImage(s.ctx, 1, 1)
+	s.Require().NoError(err)
+
+	s.Equal(model.Image{ID: 1, ProductID: 1, Key: "products/1/a.jpg", ContentType: "image/jpeg", Width: 300, Height: 200}, img)
+
+	_, err = s.s.DeleteProductImage(s.ctx, 1, 1)
+	s.True(errors.Is(err, storage.ErrNotFound))
+}
@@ -0,0 +1,131 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/vliubezny/gstore/internal/model"
+	"github.com/vliubezny/gstore/internal/storage"
+)
+
+const memberStoreIDFKConstraint = "store_member_store_id_fkey"
+
+func (p pg) GetStoreMembers(ctx context.Context, storeID int64) ([]model.StoreMember, error) {
+	var members []storeMember
+	if err := p.ext.SelectContext(ctx, &members, `
+		SELECT m.store_id, m.user_id, u.email, m.role
+		FROM store_member m JOIN store_user u ON u.id = m.user_id
+		WHERE m.store_id = $1 ORDER BY m.user_id
+	`, storeID); err != nil {
+		return nil, fmt.Errorf("failed to get store members: %w", err)
+	}
+
+	data := make([]model.StoreMember, len(members))
+	for i, m := range members {
+		data[i] = m.toModel()
+	}
+
+	return data, nil
+}
+
+func (p pg) GetStoreMember(ctx context.Context, storeID, userID int64) (model.StoreMember, error) {
+	var m storeMember
+	err := p.ext.GetContext(ctx, &m, `
+		SELECT m.store_id, m.user_id, u.email, m.role
+		FROM store_member m JOIN store_user u ON u.id = m.user_id
+		WHERE m.store_id = $1 AND m.user_id = $2
+	`, storeID, userID)
+
+	if err == sql.ErrNoRows {
+		return model.StoreMember{}, storage.ErrNotFound
+	}
+
+	if err != nil {
+		return model.StoreMember{}, fmt.Errorf("failed to get store member: %w", err)
+	}
+
+	return m.toModel(), nil
+}
+
+func (p pg) SetStoreMember(ctx context.Context, storeID int64, email string, role model.StoreRole) (model.StoreMember, error) {
+	var m storeMember
+	err := p.inTx(ctx, func(tx pg) error {
+		owners, err := tx.lockStoreOwners(ctx, storeID)
+		if err != nil {
+			return err
+		}
+
+		err = tx.ext.GetContext(ctx, &m, `
+			INSERT INTO store_member (store_id, user_id, role)
+				SELECT $1, id, $3 FROM store_user WHERE email = $2
+			ON CONFLICT (store_id, user_id) DO UPDATE SET role = EXCLUDED.role
+			RETURNING store_id, user_id, $2 AS email, role
+		`, storeID, email, role)
+
+		if err == sql.ErrNoRows {
+			return storage.ErrUnknownUser
+		}
+
+		if err != nil {
+			if err, ok := err.(*pq.Error); ok && err.Constraint == memberStoreIDFKConstraint {
+				return storage.ErrUnknownStore
+			}
+			return fmt.Errorf("failed to set store member: %w", err)
+		}
+
+		if role != model.StoreOwner && isLastOwner(owners, m.UserID) {
+			return storage.ErrLastOwner
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return model.StoreMember{}, err
+	}
+
+	return m.toModel(), nil
+}
+
+func (p pg) DeleteStoreMember(ctx context.Context, storeID, userID int64) error {
+	return p.inTx(ctx, func(tx pg) error {
+		owners, err := tx.lockStoreOwners(ctx, storeID)
+		if err != nil {
+			return err
+		}
+
+		res, err := tx.ext.ExecContext(ctx, "DELETE FROM store_member WHERE store_id = $1 AND user_id = $2", storeID, userID)
+		if err != nil {
+			return fmt.Errorf("failed to delete store member: %w", err)
+		}
+
+		if c, _ := res.RowsAffected(); c == 0 {
+			return storage.ErrNotFound
+		}
+
+		if isLastOwner(owners, userID) {
+			return storage.ErrLastOwner
+		}
+
+		return nil
+	})
+}
+
+// lockStoreOwners returns IDs of store owners locked until the end of transaction,
+// so concurrent changes can't remove the owners one by one.
+func (p pg) lockStoreOwners(ctx context.Context, storeID int64) ([]int64, error) {
+	var owners []int64
+	if err := p.ext.SelectContext(ctx, &owners, `
+		SELECT user_id FROM store_member WHERE store_id = $1 AND role = $2 FOR UPDATE
+	`, storeID, model.StoreOwner); err != nil {
+		return nil, fmt.Errorf("failed to lock store owners: %w", err)
+	}
+	return owners, nil
+}
+
+// isLastOwner reports whether the user is the only owner of the store.
+func isLastOwner(owners []int64, userID int64) bool {
+	return len(owners) == 1 && owners[0] == userID
+}
@@ -0,0 +1,80 @@
+//+build integration
+
+package postgres
+
+import (
+	"github.com/vliubezny/gstore/internal/model"
+	"github.com/vliubezny/gstore/internal/storage"
+)
+
+func (s *postgresTestSuite) TestPg_StoreMembers() {
+	_, err := s.db.Exec(`
+		INSERT INTO store (name) VALUES ('iStore');
+		INSERT INTO store_user (email, password_hash) VALUES ('owner@test.com', '123'), ('editor@test.com', '123');
+	`)
+	s.Require().NoError(err)
+
+	owner, err := s.s.(pg).SetStoreMember(s.ctx, 1, "owner@test.com", model.StoreEditor)
+	s.Require().NoError(err)
+	s.Equal(model.StoreMember{StoreID: 1, UserID: 1, Email: "owner@test.com", Role: model.StoreEditor}, owner)
+
+	owner, err = s.s.(pg).SetStoreMember(s.ctx, 1, "owner@test.com", model.StoreOwner)
+	s.Require().NoError(err)
+	s.Equal(model.StoreOwner, owner.Role)
+
+	editor, err := s.s.(pg).SetStoreMember(s.ctx, 1, "editor@test.com", model.StoreEditor)
+	s.Require().NoError(err)
+
+	members, err := s.s.(pg).GetStoreMembers(s.ctx, 1)
+	s.Require().NoError(err)
+	s.Equal([]model.StoreMember{owner, editor}, members)
+
+	m, err := s.s.(pg).GetStoreMember(s.ctx, 1, 2)
+	s.Require().NoError(err)
+	s.Equal(editor, m)
+
+	_, err = s.s.(pg).SetStoreMember(s.ctx, 1, "none@test.com", model.StoreEditor)
+	s.Equal(storage.ErrUnknownUser, err)
+
+	_, err = s.s.(pg).SetStoreMember(s.ctx, 100, "editor@test.com", model.StoreEditor)
+	s.Equal(storage.ErrUnknownStore, err)
+
+	s.Require().NoError(s.s.(pg).DeleteStoreMember(s.ctx, 1, 2))
+
+	_, err = s.s.(pg).GetStoreMember(s.ctx, 1, 2)
+	s.Equal(storage.ErrNotFound, err)
+
+	err = s.s.(pg).DeleteStoreMember(s.ctx, 1, 2)
+	s.Equal(storage.ErrNotFound, err)
+}
+
+func (s *postgresTestSuite) TestPg_StoreMembers_LastOwner() {
+	_, err := s.db.Exec(`
+		INSERT INTO store (name) VALUES ('iStore');
+		INSERT INTO store_user (email, password_hash) VALUES ('owner@test.com', '123'), ('another@test.com', '123');
+		INSERT INTO store_member (store_id, user_id, role) VALUES (1, 1, 'owner');
+	`)
+	s.Require().NoError(err)
+
+	_, err = s.s.(pg).SetStoreMember(s.ctx, 1, "owner@test.com", model.StoreEditor)
+	s.Equal(storage.ErrLastOwner, err)
+
+	err = s.s.(pg).DeleteStoreMember(s.ctx, 1, 1)
+	s.Equal(storage.ErrLastOwner, err)
+
+	m, err := s.s.(pg).GetStoreMember(s.ctx, 1, 1)
+	s.Require().NoError(err)
+	s.Equal(model.StoreOwner, m.Role, "rejected change must be rolled back")
+
+	_, err = s.s.(pg).SetStoreMember(s.ctx, 1, "another@test.com", model.StoreOwner)
+	s.Require().NoError(err)
+
+	m, err = s.s.(pg).SetStoreMember(s.ctx, 1, "owner@test.com", model.StoreEditor)
+	s.Require().NoError(err, "owner can step down if another owner exists")
+	s.Equal(model.StoreEditor, m.Role)
+
+	s.Require().NoError(s.s.(pg).DeleteStoreMember(s.ctx, 1, 1))
+
+	err = s.s.(pg).DeleteStoreMember(s.ctx, 1, 2)
+	s.Equal(storage.ErrLastOwner, err)
+}
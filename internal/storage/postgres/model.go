@@ -1,20 +1,112 @@
 package postgres
 
 import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
 	"github.com/shopspring/decimal"
 	"github.com/vliubezny/gstore/internal/model"
 )
 
 type category struct {
-	ID   int64  `db:"id"`
-	Name string `db:"name"`
+	ID       int64           `db:"id"`
+	Name     string          `db:"name"`
+	ParentID sql.NullInt64   `db:"parent_id"`
+	Schema   attributeSchema `db:"attribute_schema"`
 }
 
 func (c category) toModel() model.Category {
 	return model.Category{
-		ID:   c.ID,
-		Name: c.Name,
+		ID:       c.ID,
+		Name:     c.Name,
+		ParentID: c.ParentID.Int64,
+		Schema:   c.Schema.toModel(),
+	}
+}
+
+// attribute represents attribute of category schema stored as JSON.
+type attribute struct {
+	Name     string   `json:"name"`
+	Type     string   `json:"type"`
+	Unit     string   `json:"unit,omitempty"`
+	Values   []string `json:"values,omitempty"`
+	Required bool     `json:"required,omitempty"`
+}
+
+// attributeSchema represents category schema stored as JSONB array.
+type attributeSchema []attribute
+
+func newAttributeSchema(schema []model.Attribute) attributeSchema {
+	s := make(attributeSchema, len(schema))
+	for i, a := range schema {
+		s[i] = attribute{
+			Name:     a.Name,
+			Type:     string(a.Type),
+			Unit:     a.Unit,
+			Values:   a.Values,
+			Required: a.Required,
+		}
+	}
+	return s
+}
+
+// Value implements driver.Valuer interface.
+func (s attributeSchema) Value() (driver.Value, error) {
+	if s == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal(s)
+}
+
+// Scan implements sql.Scanner interface.
+func (s *attributeSchema) Scan(src interface{}) error {
+	data, ok := src.([]byte)
+	if !ok {
+		return fmt.Errorf("unsupported attribute schema type %T", src)
+	}
+	return json.Unmarshal(data, s)
+}
+
+func (s attributeSchema) toModel() []model.Attribute {
+	if len(s) == 0 {
+		return nil
+	}
+
+	schema := make([]model.Attribute, len(s))
+	for i, a := range s {
+		schema[i] = model.Attribute{
+			Name:     a.Name,
+			Type:     model.AttributeType(a.Type),
+			Unit:     a.Unit,
+			Values:   a.Values,
+			Required: a.Required,
+		}
+	}
+	return schema
+}
+
+// attributeValues represents product attributes stored as JSONB object.
+type attributeValues map[string]interface{}
+
+// Value implements driver.Valuer interface.
+func (v attributeValues) Value() (driver.Value, error) {
+	if v == nil {
+		return []byte("{}"), nil
+	}
+	return json.Marshal(v)
+}
+
+// Scan implements sql.Scanner interface.
+func (v *attributeValues) Scan(src interface{}) error {
+	data, ok := src.([]byte)
+	if !ok {
+		return fmt.Errorf("unsupported attributes type %T", src)
 	}
+	return json.Unmarshal(data, v)
 }
 
 type store struct {
@@ -29,48 +121,542 @@ func (s store) toModel() model.Store {
 	}
 }
 
+type storeMember struct {
+	StoreID int64  `db:"store_id"`
+	UserID  int64  `db:"user_id"`
+	Email   string `db:"email"`
+	Role    string `db:"role"`
+}
+
+func (m storeMember) toModel() model.StoreMember {
+	return model.StoreMember{
+		StoreID: m.StoreID,
+		UserID:  m.UserID,
+		Email:   m.Email,
+		Role:    model.StoreRole(m.Role),
+	}
+}
+
 type product struct {
-	ID          int64  `db:"id"`
-	CategoryID  int64  `db:"category_id"`
-	Name        string `db:"name"`
-	Description string `db:"description"`
+	ID          int64           `db:"id"`
+	CategoryID  int64           `db:"category_id"`
+	Name        string          `db:"name"`
+	Description string          `db:"description"`
+	Attributes  attributeValues `db:"attributes"`
 }
 
 func (i product) toModel() model.Product {
+	var attrs map[string]interface{}
+	if len(i.Attributes) > 0 {
+		attrs = i.Attributes
+	}
+
 	return model.Product{
 		ID:          i.ID,
 		CategoryID:  i.CategoryID,
 		Name:        i.Name,
 		Description: i.Description,
+		Attributes:  attrs,
+	}
+}
+
+// productRow represents product with aggregated position data.
+type productRow struct {
+	product
+	BestPrice decimal.NullDecimal `db:"best_price"`
+}
+
+type productSearchResult struct {
+	product
+	Rank                 float64 `db:"rank"`
+	NameHighlight        string  `db:"name_highlight"`
+	DescriptionHighlight string  `db:"description_highlight"`
+}
+
+func (r productSearchResult) toModel() model.ProductSearchResult {
+	return model.ProductSearchResult{
+		Product:              r.product.toModel(),
+		Rank:                 r.Rank,
+		NameHighlight:        r.NameHighlight,
+		DescriptionHighlight: r.DescriptionHighlight,
+	}
+}
+
+type image struct {
+	ID          int64  `db:"id"`
+	ProductID   int64  `db:"product_id"`
+	Key         string `db:"key"`
+	ContentType string `db:"content_type"`
+	Width       int    `db:"width"`
+	Height      int    `db:"height"`
+	Position    int    `db:"position"`
+}
+
+func (i image) toModel() model.Image {
+	return model.Image{
+		ID:          i.ID,
+		ProductID:   i.ProductID,
+		Key:         i.Key,
+		ContentType: i.ContentType,
+		Width:       i.Width,
+		Height:      i.Height,
+		Position:    i.Position,
+	}
+}
+
+type variant struct {
+	ID         int64           `db:"id"`
+	ProductID  int64           `db:"product_id"`
+	SKU        string          `db:"sku"`
+	GTIN       sql.NullString  `db:"gtin"`
+	Attributes attributeValues `db:"attributes"`
+}
+
+func (v variant) toModel() model.Variant {
+	var attrs map[string]interface{}
+	if len(v.Attributes) > 0 {
+		attrs = v.Attributes
+	}
+
+	return model.Variant{
+		ID:         v.ID,
+		ProductID:  v.ProductID,
+		SKU:        v.SKU,
+		GTIN:       v.GTIN.String,
+		Attributes: attrs,
 	}
 }
 
 type position struct {
+	ProductID    int64           `db:"product_id"`
+	VariantID    int64           `db:"variant_id"`
+	StoreID      int64           `db:"store_id"`
+	Price        decimal.Decimal `db:"price"`
+	Currency     string          `db:"currency"`
+	Quantity     int64           `db:"quantity"`
+	Availability string          `db:"availability"`
+	RestockDate  sql.NullTime    `db:"restock_date"`
+}
+
+func (p position) toModel() model.Position {
+	// dates are scanned in zero offset location which differs from UTC
+	var restockDate time.Time
+	if p.RestockDate.Valid {
+		y, m, d := p.RestockDate.Time.Date()
+		restockDate = time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	}
+
+	return model.Position{
+		ProductID:    p.ProductID,
+		VariantID:    p.VariantID,
+		StoreID:      p.StoreID,
+		Price:        p.Price,
+		Currency:     p.Currency,
+		Quantity:     p.Quantity,
+		Availability: model.Availability(p.Availability),
+		RestockDate:  restockDate,
+	}
+}
+
+type cartItem struct {
 	ProductID int64           `db:"product_id"`
+	VariantID int64           `db:"variant_id"`
 	StoreID   int64           `db:"store_id"`
+	Quantity  int64           `db:"quantity"`
 	Price     decimal.Decimal `db:"price"`
+	Currency  string          `db:"currency"`
+	// position columns are null when the position was deleted
+	PositionPrice        decimal.NullDecimal `db:"position_price"`
+	PositionCurrency     sql.NullString      `db:"position_currency"`
+	PositionQuantity     sql.NullInt64       `db:"position_quantity"`
+	PositionAvailability sql.NullString      `db:"position_availability"`
+	PositionRestockDate  sql.NullTime        `db:"position_restock_date"`
 }
 
-func (p position) toModel() model.Position {
-	return model.Position{
-		ProductID: p.ProductID,
-		StoreID:   p.StoreID,
-		Price:     p.Price,
+func (i cartItem) toModel() model.CartItem {
+	item := model.CartItem{
+		ProductID: i.ProductID,
+		VariantID: i.VariantID,
+		StoreID:   i.StoreID,
+		Quantity:  i.Quantity,
+		Price:     i.Price,
+		Currency:  i.Currency,
+	}
+
+	if i.PositionCurrency.Valid {
+		item.Position = position{
+			ProductID:    i.ProductID,
+			VariantID:    i.VariantID,
+			StoreID:      i.StoreID,
+			Price:        i.PositionPrice.Decimal,
+			Currency:     i.PositionCurrency.String,
+			Quantity:     i.PositionQuantity.Int64,
+			Availability: i.PositionAvailability.String,
+			RestockDate:  i.PositionRestockDate,
+		}.toModel()
+	}
+
+	return item
+}
+
+type order struct {
+	ID        int64           `db:"id"`
+	UserID    sql.NullInt64   `db:"user_id"`
+	Status    string          `db:"status"`
+	Currency  string          `db:"currency"`
+	Total     decimal.Decimal `db:"total"`
+	CreatedAt time.Time       `db:"created_at"`
+}
+
+func (o order) toModel() model.Order {
+	return model.Order{
+		ID:        o.ID,
+		UserID:    o.UserID.Int64,
+		Status:    model.OrderStatus(o.Status),
+		Currency:  o.Currency,
+		Total:     o.Total,
+		CreatedAt: o.CreatedAt.UTC(),
+	}
+}
+
+type orderItem struct {
+	OrderID   int64           `db:"order_id"`
+	ProductID int64           `db:"product_id"`
+	VariantID int64           `db:"variant_id"`
+	StoreID   int64           `db:"store_id"`
+	Name      string          `db:"name"`
+	SKU       string          `db:"sku"`
+	Quantity  int64           `db:"quantity"`
+	Price     decimal.Decimal `db:"price"`
+	Currency  string          `db:"currency"`
+	Reserved  bool            `db:"reserved"`
+}
+
+func (i orderItem) toModel() model.OrderItem {
+	return model.OrderItem{
+		ProductID: i.ProductID,
+		VariantID: i.VariantID,
+		StoreID:   i.StoreID,
+		Name:      i.Name,
+		SKU:       i.SKU,
+		Quantity:  i.Quantity,
+		Price:     i.Price,
+		Currency:  i.Currency,
+		Reserved:  i.Reserved,
+	}
+}
+
+type orderTransition struct {
+	FromStatus sql.NullString `db:"from_status"`
+	ToStatus   string         `db:"to_status"`
+	ActorID    sql.NullInt64  `db:"actor_id"`
+	CreatedAt  time.Time      `db:"created_at"`
+}
+
+func (t orderTransition) toModel() model.OrderTransition {
+	return model.OrderTransition{
+		From:      model.OrderStatus(t.FromStatus.String),
+		To:        model.OrderStatus(t.ToStatus),
+		ActorID:   t.ActorID.Int64,
+		CreatedAt: t.CreatedAt.UTC(),
+	}
+}
+
+type priceChange struct {
+	ProductID int64           `db:"product_id"`
+	VariantID int64           `db:"variant_id"`
+	StoreID   int64           `db:"store_id"`
+	Price     decimal.Decimal `db:"price"`
+	Currency  string          `db:"currency"`
+	UserID    sql.NullInt64   `db:"user_id"`
+	ChangedAt time.Time       `db:"changed_at"`
+}
+
+func (c priceChange) toModel() model.PriceChange {
+	return model.PriceChange{
+		ProductID: c.ProductID,
+		VariantID: c.VariantID,
+		StoreID:   c.StoreID,
+		Price:     c.Price,
+		Currency:  c.Currency,
+		UserID:    c.UserID.Int64,
+		ChangedAt: c.ChangedAt.UTC(),
+	}
+}
+
+type priceBucket struct {
+	Date time.Time       `db:"date"`
+	Min  decimal.Decimal `db:"min"`
+	Avg  decimal.Decimal `db:"avg"`
+	Max  decimal.Decimal `db:"max"`
+}
+
+func (b priceBucket) toModel() model.PriceBucket {
+	y, m, d := b.Date.Date()
+	return model.PriceBucket{
+		Date: time.Date(y, m, d, 0, 0, 0, 0, time.UTC),
+		Min:  b.Min,
+		Avg:  b.Avg,
+		Max:  b.Max,
+	}
+}
+
+type priceSummary struct {
+	ProductID       int64           `db:"product_id"`
+	OfferCount      int64           `db:"offer_count"`
+	MinPrice        decimal.Decimal `db:"min_price"`
+	MaxPrice        decimal.Decimal `db:"max_price"`
+	MedianPrice     decimal.Decimal `db:"median_price"`
+	CheapestStoreID int64           `db:"cheapest_store_id"`
+}
+
+func (s priceSummary) toModel() model.PriceSummary {
+	return model.PriceSummary{
+		ProductID:       s.ProductID,
+		OfferCount:      s.OfferCount,
+		Currency:        model.BaseCurrency,
+		MinPrice:        s.MinPrice,
+		MaxPrice:        s.MaxPrice,
+		MedianPrice:     s.MedianPrice,
+		CheapestStoreID: s.CheapestStoreID,
+	}
+}
+
+type exchangeRate struct {
+	Currency  string          `db:"currency"`
+	Rate      decimal.Decimal `db:"rate"`
+	UpdatedAt time.Time       `db:"updated_at"`
+}
+
+func (r exchangeRate) toModel() model.ExchangeRate {
+	return model.ExchangeRate{
+		Currency:  r.Currency,
+		Rate:      r.Rate,
+		UpdatedAt: r.UpdatedAt.UTC(),
+	}
+}
+
+type payment struct {
+	ID        string          `db:"id"`
+	OrderID   int64           `db:"order_id"`
+	Status    string          `db:"status"`
+	Amount    decimal.Decimal `db:"amount"`
+	Currency  string          `db:"currency"`
+	CreatedAt time.Time       `db:"created_at"`
+}
+
+func (p payment) toModel() model.Payment {
+	return model.Payment{
+		ID:        p.ID,
+		OrderID:   p.OrderID,
+		Status:    model.PaymentStatus(p.Status),
+		Amount:    p.Amount,
+		Currency:  p.Currency,
+		CreatedAt: p.CreatedAt.UTC(),
 	}
 }
 
 type user struct {
-	ID           int64  `db:"id"`
-	Email        string `db:"email"`
-	PasswordHash string `db:"password_hash"`
-	IsAdmin      bool   `db:"is_admin"`
+	ID            int64          `db:"id"`
+	Email         string         `db:"email"`
+	PasswordHash  string         `db:"password_hash"`
+	EmailVerified bool           `db:"email_verified"`
+	MFASecret     string         `db:"mfa_secret"`
+	MFAEnabled    bool           `db:"mfa_enabled"`
+	Roles         pq.StringArray `db:"roles"`
+	Permissions   pq.StringArray `db:"permissions"`
 }
 
 func (u user) toModel() model.User {
 	return model.User{
-		ID:           u.ID,
-		Email:        u.Email,
-		PasswordHash: u.PasswordHash,
-		IsAdmin:      u.IsAdmin,
+		ID:            u.ID,
+		Email:         u.Email,
+		PasswordHash:  u.PasswordHash,
+		EmailVerified: u.EmailVerified,
+		MFASecret:     u.MFASecret,
+		MFAEnabled:    u.MFAEnabled,
+		Roles:         []string(u.Roles),
+		Permissions:   toPermissions(u.Permissions),
+	}
+}
+
+type loginFailures struct {
+	Subject       string    `db:"subject"`
+	Failures      int       `db:"failures"`
+	LastFailureAt time.Time `db:"last_failure_at"`
+}
+
+func (f loginFailures) toModel() model.LoginFailures {
+	return model.LoginFailures{
+		Subject: f.Subject,
+		Count:   f.Failures,
+		LastAt:  f.LastFailureAt,
+	}
+}
+
+type session struct {
+	FamilyID  string    `db:"family_id"`
+	UserID    int64     `db:"user_id"`
+	IP        string    `db:"ip"`
+	UserAgent string    `db:"user_agent"`
+	StartedAt time.Time `db:"started_at"`
+	CreatedAt time.Time `db:"created_at"`
+	ExpiresAt time.Time `db:"expires_at"`
+}
+
+func (s session) toModel() model.Session {
+	return model.Session{
+		ID:         s.FamilyID,
+		UserID:     s.UserID,
+		IP:         s.IP,
+		UserAgent:  s.UserAgent,
+		CreatedAt:  s.StartedAt,
+		LastUsedAt: s.CreatedAt,
+		ExpiresAt:  s.ExpiresAt,
 	}
 }
+
+type tokenRevocation struct {
+	UserID    int64     `db:"user_id"`
+	SessionID string    `db:"session_id"`
+	RevokedAt time.Time `db:"revoked_at"`
+}
+
+func (r tokenRevocation) toModel() model.TokenRevocation {
+	return model.TokenRevocation{
+		UserID:       r.UserID,
+		SessionID:    r.SessionID,
+		IssuedBefore: r.RevokedAt,
+	}
+}
+
+type apiKey struct {
+	ID         string         `db:"id"`
+	UserID     int64          `db:"user_id"`
+	Name       string         `db:"name"`
+	Prefix     string         `db:"prefix"`
+	KeyHash    string         `db:"key_hash"`
+	Scopes     pq.StringArray `db:"scopes"`
+	CreatedAt  time.Time      `db:"created_at"`
+	ExpiresAt  sql.NullTime   `db:"expires_at"`
+	LastUsedAt sql.NullTime   `db:"last_used_at"`
+}
+
+func (k apiKey) toModel() model.APIKey {
+	return model.APIKey{
+		ID:         k.ID,
+		UserID:     k.UserID,
+		Name:       k.Name,
+		Prefix:     k.Prefix,
+		KeyHash:    k.KeyHash,
+		Scopes:     toPermissions(k.Scopes),
+		CreatedAt:  k.CreatedAt,
+		ExpiresAt:  k.ExpiresAt.Time,
+		LastUsedAt: k.LastUsedAt.Time,
+	}
+}
+
+type oauthClient struct {
+	ID           string         `db:"id"`
+	UserID       int64          `db:"user_id"`
+	Name         string         `db:"name"`
+	SecretHash   string         `db:"secret_hash"`
+	RedirectURIs pq.StringArray `db:"redirect_uris"`
+	Scopes       pq.StringArray `db:"scopes"`
+	CreatedAt    time.Time      `db:"created_at"`
+}
+
+func (c oauthClient) toModel() model.OAuthClient {
+	return model.OAuthClient{
+		ID:           c.ID,
+		UserID:       c.UserID,
+		Name:         c.Name,
+		SecretHash:   c.SecretHash,
+		RedirectURIs: []string(c.RedirectURIs),
+		Scopes:       toPermissions(c.Scopes),
+		CreatedAt:    c.CreatedAt,
+	}
+}
+
+type authorizationCode struct {
+	CodeHash      string         `db:"code_hash"`
+	ClientID      string         `db:"client_id"`
+	UserID        int64          `db:"user_id"`
+	RedirectURI   string         `db:"redirect_uri"`
+	Scopes        pq.StringArray `db:"scopes"`
+	CodeChallenge string         `db:"code_challenge"`
+	ExpiresAt     time.Time      `db:"expires_at"`
+}
+
+func (c authorizationCode) toModel() model.AuthorizationCode {
+	return model.AuthorizationCode{
+		CodeHash:      c.CodeHash,
+		ClientID:      c.ClientID,
+		UserID:        c.UserID,
+		RedirectURI:   c.RedirectURI,
+		Scopes:        toPermissions(c.Scopes),
+		CodeChallenge: c.CodeChallenge,
+		ExpiresAt:     c.ExpiresAt,
+	}
+}
+
+type role struct {
+	Name        string         `db:"name"`
+	Permissions pq.StringArray `db:"permissions"`
+}
+
+func (r role) toModel() model.Role {
+	return model.Role{
+		Name:        r.Name,
+		Permissions: toPermissions(r.Permissions),
+	}
+}
+
+func fromPermissions(ps []model.Permission) []string {
+	data := make([]string, len(ps))
+	for i, p := range ps {
+		data[i] = string(p)
+	}
+	return data
+}
+
+func toPermissions(ps []string) []model.Permission {
+	data := make([]model.Permission, len(ps))
+	for i, p := range ps {
+		data[i] = model.Permission(p)
+	}
+	return data
+}
+
+type email struct {
+	ID        int64  `db:"id"`
+	Recipient string `db:"recipient"`
+	Subject   string `db:"subject"`
+	Body      string `db:"body"`
+	Attempts  int    `db:"attempts"`
+}
+
+func (e email) toModel() model.Email {
+	return model.Email{
+		ID:       e.ID,
+		To:       e.Recipient,
+		Subject:  e.Subject,
+		Body:     e.Body,
+		Attempts: e.Attempts,
+	}
+}
+
+// nullID converts optional reference where 0 means no reference.
+func nullID(id int64) sql.NullInt64 {
+	return sql.NullInt64{Int64: id, Valid: id != 0}
+}
+
+// nullDate converts optional date where zero value means no date.
+func nullDate(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
+
+// nullString converts optional value where empty string means no value.
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
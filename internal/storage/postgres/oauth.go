@@ -0,0 +1,108 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/vliubezny/gstore/internal/model"
+	"github.com/vliubezny/gstore/internal/storage"
+)
+
+const oauthClientUserIDFKConstraint = "oauth_client_user_id_fkey"
+
+const oauthClientColumns = "id, user_id, name, secret_hash, redirect_uris, scopes, created_at"
+
+func (p pg) CreateOAuthClient(ctx context.Context, client model.OAuthClient) (model.OAuthClient, error) {
+	var c oauthClient
+	if err := p.ext.GetContext(ctx, &c, `
+		INSERT INTO oauth_client (id, user_id, name, secret_hash, redirect_uris, scopes)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING `+oauthClientColumns,
+		client.ID, client.UserID, client.Name, client.SecretHash,
+		pq.Array(client.RedirectURIs), pq.Array(fromPermissions(client.Scopes))); err != nil {
+		if err, ok := err.(*pq.Error); ok && err.Constraint == oauthClientUserIDFKConstraint {
+			return model.OAuthClient{}, storage.ErrUnknownUser
+		}
+		return model.OAuthClient{}, fmt.Errorf("failed to create oauth client: %w", err)
+	}
+
+	return c.toModel(), nil
+}
+
+func (p pg) GetOAuthClient(ctx context.Context, clientID string) (model.OAuthClient, error) {
+	var c oauthClient
+	err := p.ext.GetContext(ctx, &c, "SELECT "+oauthClientColumns+" FROM oauth_client WHERE id = $1", clientID)
+
+	if err == sql.ErrNoRows {
+		return model.OAuthClient{}, storage.ErrNotFound
+	}
+
+	if err != nil {
+		return model.OAuthClient{}, fmt.Errorf("failed to get oauth client: %w", err)
+	}
+
+	return c.toModel(), nil
+}
+
+func (p pg) GetOAuthClients(ctx context.Context, userID int64) ([]model.OAuthClient, error) {
+	var clients []oauthClient
+	if err := p.ext.SelectContext(ctx, &clients, `
+		SELECT `+oauthClientColumns+` FROM oauth_client WHERE user_id = $1 ORDER BY created_at DESC, id
+	`, userID); err != nil {
+		return nil, fmt.Errorf("failed to get oauth clients: %w", err)
+	}
+
+	data := make([]model.OAuthClient, len(clients))
+	for i, c := range clients {
+		data[i] = c.toModel()
+	}
+
+	return data, nil
+}
+
+func (p pg) DeleteOAuthClient(ctx context.Context, userID int64, clientID string) error {
+	res, err := p.ext.ExecContext(ctx, "DELETE FROM oauth_client WHERE id = $1 AND user_id = $2", clientID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete oauth client: %w", err)
+	}
+
+	if c, _ := res.RowsAffected(); c == 0 {
+		return storage.ErrNotFound
+	}
+
+	return nil
+}
+
+func (p pg) SaveAuthorizationCode(ctx context.Context, code model.AuthorizationCode) error {
+	if _, err := p.ext.ExecContext(ctx, `
+		INSERT INTO oauth_code (code_hash, client_id, user_id, redirect_uri, scopes, code_challenge, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`, code.CodeHash, code.ClientID, code.UserID, code.RedirectURI,
+		pq.Array(fromPermissions(code.Scopes)), code.CodeChallenge, code.ExpiresAt); err != nil {
+		return fmt.Errorf("failed to save authorization code: %w", err)
+	}
+	return nil
+}
+
+func (p pg) UseAuthorizationCode(ctx context.Context, codeHash string) (model.AuthorizationCode, error) {
+	var c authorizationCode
+	err := p.ext.GetContext(ctx, &c, `
+		WITH used AS (
+			DELETE FROM oauth_code WHERE code_hash = $1
+			RETURNING code_hash, client_id, user_id, redirect_uri, scopes, code_challenge, expires_at
+		)
+		SELECT * FROM used WHERE expires_at > now()
+	`, codeHash)
+
+	if err == sql.ErrNoRows {
+		return model.AuthorizationCode{}, storage.ErrNotFound
+	}
+
+	if err != nil {
+		return model.AuthorizationCode{}, fmt.Errorf("failed to use authorization code: %w", err)
+	}
+
+	return c.toModel(), nil
+}
@@ -0,0 +1,97 @@
+//+build integration
+
+package postgres
+
+import (
+	"errors"
+	"time"
+
+	"github.com/vliubezny/gstore/internal/model"
+	"github.com/vliubezny/gstore/internal/storage"
+)
+
+const testClientID = "4f1c2a90-f698-11e6-8dd4-cb9ced3df976"
+
+func (s *postgresTestSuite) TestPg_OAuthClients() {
+	_, err := s.db.Exec(`INSERT INTO store_user (email, password_hash) VALUES ('owner@test.com', '123'), ('user@test.com', '123');`)
+	s.Require().NoError(err)
+
+	client := model.OAuthClient{
+		ID:           testClientID,
+		UserID:       1,
+		Name:         "partner",
+		SecretHash:   "hash",
+		RedirectURIs: []string{"https://partner.com/callback"},
+		Scopes:       []model.Permission{model.PermissionPositionWrite},
+	}
+
+	c, err := s.s.(pg).CreateOAuthClient(s.ctx, client)
+	s.Require().NoError(err)
+	s.WithinDuration(time.Now(), c.CreatedAt, time.Minute)
+
+	client.CreatedAt = c.CreatedAt
+	s.Equal(client, c)
+
+	c, err = s.s.(pg).GetOAuthClient(s.ctx, testClientID)
+	s.Require().NoError(err)
+	s.Equal(client, c)
+
+	clients, err := s.s.(pg).GetOAuthClients(s.ctx, 1)
+	s.Require().NoError(err)
+	s.Equal([]model.OAuthClient{client}, clients)
+
+	clients, err = s.s.(pg).GetOAuthClients(s.ctx, 2)
+	s.Require().NoError(err)
+	s.Empty(clients)
+
+	err = s.s.(pg).DeleteOAuthClient(s.ctx, 2, testClientID)
+	s.True(errors.Is(err, storage.ErrNotFound), "client of other user must not be deleted")
+
+	s.Require().NoError(s.s.(pg).DeleteOAuthClient(s.ctx, 1, testClientID))
+
+	_, err = s.s.(pg).GetOAuthClient(s.ctx, testClientID)
+	s.True(errors.Is(err, storage.ErrNotFound))
+
+	client.UserID = 100
+	_, err = s.s.(pg).CreateOAuthClient(s.ctx, client)
+	s.True(errors.Is(err, storage.ErrUnknownUser))
+}
+
+func (s *postgresTestSuite) TestPg_UseAuthorizationCode() {
+	_, err := s.db.Exec(`
+		INSERT INTO store_user (email, password_hash) VALUES ('owner@test.com', '123'), ('user@test.com', '123');
+		INSERT INTO oauth_client (id, user_id, name) VALUES ('4f1c2a90-f698-11e6-8dd4-cb9ced3df976', 1, 'partner');
+	`)
+	s.Require().NoError(err)
+
+	code := model.AuthorizationCode{
+		CodeHash:      "hash",
+		ClientID:      testClientID,
+		UserID:        2,
+		RedirectURI:   "https://partner.com/callback",
+		Scopes:        []model.Permission{model.PermissionOrderRead},
+		CodeChallenge: "challenge",
+		ExpiresAt:     time.Now().Add(time.Minute).UTC().Truncate(time.Millisecond),
+	}
+	s.Require().NoError(s.s.(pg).SaveAuthorizationCode(s.ctx, code))
+
+	expired := code
+	expired.CodeHash, expired.ExpiresAt = "expired", time.Now().Add(-time.Second)
+	s.Require().NoError(s.s.(pg).SaveAuthorizationCode(s.ctx, expired))
+
+	c, err := s.s.(pg).UseAuthorizationCode(s.ctx, "hash")
+	s.Require().NoError(err)
+	s.True(code.ExpiresAt.Equal(c.ExpiresAt))
+	c.ExpiresAt = code.ExpiresAt
+	s.Equal(code, c)
+
+	_, err = s.s.(pg).UseAuthorizationCode(s.ctx, "hash")
+	s.True(errors.Is(err, storage.ErrNotFound), "code must be used once")
+
+	_, err = s.s.(pg).UseAuthorizationCode(s.ctx, "expired")
+	s.True(errors.Is(err, storage.ErrNotFound), "expired code must not be used")
+
+	var count int
+	s.Require().NoError(s.db.QueryRow("SELECT count(*) FROM oauth_code").Scan(&count))
+	s.Equal(0, count, "expired code must be deleted")
+}
@@ -0,0 +1,226 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/vliubezny/gstore/internal/model"
+	"github.com/vliubezny/gstore/internal/storage"
+)
+
+func (p pg) CreateOrder(ctx context.Context, o model.Order) (model.Order, error) {
+	err := p.inTx(ctx, func(tx pg) error {
+		if err := tx.ext.GetContext(ctx, &o.ID, `
+			INSERT INTO store_order (user_id, status, currency, total) VALUES ($1, $2, $3, $4) RETURNING id
+		`, nullID(o.UserID), o.Status, o.Currency, o.Total); err != nil {
+			return fmt.Errorf("failed to create order: %w", err)
+		}
+
+		variantIDs := make([]int64, len(o.Items))
+		storeIDs := make([]int64, len(o.Items))
+		quantities := make([]int64, len(o.Items))
+		prices := make([]string, len(o.Items))
+		currencies := make([]string, len(o.Items))
+		for i, item := range o.Items {
+			variantIDs[i] = item.VariantID
+			storeIDs[i] = item.StoreID
+			quantities[i] = item.Quantity
+			prices[i] = item.Price.String()
+			currencies[i] = item.Currency
+		}
+
+		res, err := tx.ext.ExecContext(ctx, `
+			INSERT INTO order_item (order_id, product_id, variant_id, store_id, name, sku, quantity, price, currency, reserved)
+				SELECT $1, p.product_id, p.variant_id, p.store_id, pr.name, v.sku, i.quantity, p.price, p.currency,
+					p.availability = 'in_stock'
+				FROM unnest($2::integer[], $3::integer[], $4::integer[], $5::numeric[], $6::text[])
+					AS i (variant_id, store_id, quantity, price, currency)
+				JOIN position p ON p.variant_id = i.variant_id AND p.store_id = i.store_id
+				JOIN variant v ON v.id = p.variant_id
+				JOIN product pr ON pr.id = p.product_id
+				WHERE p.price = i.price AND p.currency = i.currency AND p.availability <> 'discontinued'
+		`, o.ID, pq.Array(variantIDs), pq.Array(storeIDs), pq.Array(quantities), pq.Array(prices), pq.Array(currencies))
+		if err != nil {
+			return fmt.Errorf("failed to create order items: %w", err)
+		}
+
+		if c, _ := res.RowsAffected(); c != int64(len(o.Items)) {
+			return storage.ErrPositionChanged
+		}
+
+		// the check constraint rejects reservations exceeding stock
+		if _, err := tx.ext.ExecContext(ctx, `
+			UPDATE position p SET quantity = p.quantity - i.quantity
+			FROM order_item i
+			WHERE i.order_id = $1 AND i.reserved AND p.variant_id = i.variant_id AND p.store_id = i.store_id
+		`, o.ID); err != nil {
+			if err, ok := err.(*pq.Error); ok && err.Constraint == positionQuantityConstraint {
+				return storage.ErrInsufficientStock
+			}
+			return fmt.Errorf("failed to reserve stock: %w", err)
+		}
+
+		if _, err := tx.ext.ExecContext(ctx, `
+			INSERT INTO order_transition (order_id, to_status, actor_id) VALUES ($1, $2, $3)
+		`, o.ID, o.Status, nullID(o.UserID)); err != nil {
+			return fmt.Errorf("failed to record order transition: %w", err)
+		}
+
+		if _, err := tx.ext.ExecContext(ctx, "DELETE FROM cart WHERE user_id = $1", o.UserID); err != nil {
+			return fmt.Errorf("failed to delete cart: %w", err)
+		}
+
+		o, err = tx.GetOrder(ctx, o.ID)
+		return err
+	})
+
+	if err != nil {
+		return model.Order{}, err
+	}
+
+	return o, nil
+}
+
+func (p pg) GetOrder(ctx context.Context, orderID int64) (model.Order, error) {
+	var o order
+	err := p.ext.GetContext(ctx, &o, `
+		SELECT id, user_id, status, currency, total, created_at FROM store_order WHERE id = $1
+	`, orderID)
+
+	if err == sql.ErrNoRows {
+		return model.Order{}, storage.ErrNotFound
+	}
+
+	if err != nil {
+		return model.Order{}, fmt.Errorf("failed to get order: %w", err)
+	}
+
+	items, err := p.getOrderItems(ctx, []int64{orderID})
+	if err != nil {
+		return model.Order{}, err
+	}
+
+	var transitions []orderTransition
+	if err := p.ext.SelectContext(ctx, &transitions, `
+		SELECT from_status, to_status, actor_id, created_at FROM order_transition WHERE order_id = $1 ORDER BY id
+	`, orderID); err != nil {
+		return model.Order{}, fmt.Errorf("failed to get order transitions: %w", err)
+	}
+
+	data := o.toModel()
+	data.Items = items[orderID]
+	data.Transitions = make([]model.OrderTransition, len(transitions))
+	for i, t := range transitions {
+		data.Transitions[i] = t.toModel()
+	}
+
+	return data, nil
+}
+
+func (p pg) GetOrders(ctx context.Context, filter model.OrderFilter, page model.Page) ([]model.Order, string, error) {
+	after, err := decodeCursor(page.Cursor)
+	if err != nil {
+		return nil, "", err
+	}
+
+	status := make([]string, len(filter.Status))
+	for i, s := range filter.Status {
+		status[i] = string(s)
+	}
+
+	var orders []order
+
+	if err := p.ext.SelectContext(ctx, &orders, `
+		SELECT id, user_id, status, currency, total, created_at FROM store_order
+		WHERE ($1 = 0 OR user_id = $1)
+		AND (cardinality($2::text[]) = 0 OR status = ANY($2))
+		AND ($3 = 0 OR id < $3)
+		ORDER BY id DESC LIMIT $4
+	`, filter.UserID, pq.Array(status), after.ID, page.Limit+1); err != nil {
+		return nil, "", fmt.Errorf("failed to get orders: %w", err)
+	}
+
+	var next string
+	if len(orders) > page.Limit {
+		orders = orders[:page.Limit]
+		next = encodeCursor(cursor{ID: orders[page.Limit-1].ID})
+	}
+
+	ids := make([]int64, len(orders))
+	for i, o := range orders {
+		ids[i] = o.ID
+	}
+
+	items, err := p.getOrderItems(ctx, ids)
+	if err != nil {
+		return nil, "", err
+	}
+
+	data := make([]model.Order, len(orders))
+	for i, o := range orders {
+		data[i] = o.toModel()
+		data[i].Items = items[o.ID]
+	}
+
+	return data, next, nil
+}
+
+func (p pg) UpdateOrderStatus(ctx context.Context, orderID int64, from, to model.OrderStatus, actorID int64, releaseStock bool) error {
+	return p.inTx(ctx, func(tx pg) error {
+		res, err := tx.ext.ExecContext(ctx, `
+			UPDATE store_order SET status = $3, updated_at = now() WHERE id = $1 AND status = $2
+		`, orderID, from, to)
+		if err != nil {
+			return fmt.Errorf("failed to update order status: %w", err)
+		}
+
+		if c, _ := res.RowsAffected(); c == 0 {
+			return storage.ErrOrderStatusChanged
+		}
+
+		if _, err := tx.ext.ExecContext(ctx, `
+			INSERT INTO order_transition (order_id, from_status, to_status, actor_id) VALUES ($1, $2, $3, $4)
+		`, orderID, from, to, nullID(actorID)); err != nil {
+			return fmt.Errorf("failed to record order transition: %w", err)
+		}
+
+		if !releaseStock {
+			return nil
+		}
+
+		if _, err := tx.ext.ExecContext(ctx, `
+			WITH released AS (
+				UPDATE order_item SET reserved = FALSE WHERE order_id = $1 AND reserved
+				RETURNING variant_id, store_id, quantity
+			)
+			UPDATE position p SET quantity = p.quantity + r.quantity
+			FROM released r
+			WHERE p.variant_id = r.variant_id AND p.store_id = r.store_id
+		`, orderID); err != nil {
+			return fmt.Errorf("failed to release stock: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// getOrderItems returns items of the orders keyed by order ID.
+func (p pg) getOrderItems(ctx context.Context, orderIDs []int64) (map[int64][]model.OrderItem, error) {
+	var items []orderItem
+	if err := p.ext.SelectContext(ctx, &items, `
+		SELECT order_id, product_id, variant_id, store_id, name, sku, quantity, price, currency, reserved
+		FROM order_item WHERE order_id = ANY($1)
+		ORDER BY order_id, store_id, variant_id
+	`, pq.Array(orderIDs)); err != nil {
+		return nil, fmt.Errorf("failed to get order items: %w", err)
+	}
+
+	data := make(map[int64][]model.OrderItem, len(orderIDs))
+	for _, i := range items {
+		data[i.OrderID] = append(data[i.OrderID], i.toModel())
+	}
+
+	return data, nil
+}
@@ -0,0 +1,153 @@
+//+build integration
+
+package postgres
+
+import (
+	"errors"
+
+	"github.com/shopspring/decimal"
+	"github.com/vliubezny/gstore/internal/model"
+	"github.com/vliubezny/gstore/internal/storage"
+)
+
+func (s *postgresTestSuite) setupOrderData() {
+	_, err := s.db.Exec(`
+		INSERT INTO product (category_id, name, description) VALUES (1, 'iPhone 11', 'Old iphone');
+		INSERT INTO variant (product_id, sku) VALUES (1, 'IP11-64'), (1, 'IP11-128');
+		INSERT INTO store (name) VALUES ('iStore');
+		INSERT INTO store_user (email, password_hash) VALUES ('user@test.com', '123'), ('admin@test.com', '123');
+		INSERT INTO position (product_id, variant_id, store_id, price, quantity, availability) VALUES
+			(1, 1, 1, 100, 5, 'in_stock'),
+			(1, 2, 1, 150, 0, 'backorder');
+	`)
+	s.Require().NoError(err)
+}
+
+func (s *postgresTestSuite) createTestOrder() model.Order {
+	s.Require().NoError(s.s.(pg).UpsertCartItem(s.ctx, model.CartOwner{UserID: 1}, 1, 1, 2))
+
+	o, err := s.s.(pg).CreateOrder(s.ctx, model.Order{
+		UserID:   1,
+		Status:   model.OrderPending,
+		Currency: "USD",
+		Total:    decimal.NewFromInt(350),
+		Items: []model.OrderItem{
+			{VariantID: 1, StoreID: 1, Quantity: 2, Price: decimal.NewFromInt(100), Currency: "USD"},
+			{VariantID: 2, StoreID: 1, Quantity: 1, Price: decimal.NewFromInt(150), Currency: "USD"},
+		},
+	})
+	s.Require().NoError(err)
+	return o
+}
+
+func (s *postgresTestSuite) TestPg_CreateOrder() {
+	s.setupOrderData()
+
+	o := s.createTestOrder()
+
+	s.Equal(int64(1), o.ID)
+	s.Equal(model.OrderPending, o.Status)
+	s.Equal([]model.OrderItem{
+		{ProductID: 1, VariantID: 1, StoreID: 1, Name: "iPhone 11", SKU: "IP11-64", Quantity: 2, Price: decimal.NewFromInt(100), Currency: "USD", Reserved: true},
+		{ProductID: 1, VariantID: 2, StoreID: 1, Name: "iPhone 11", SKU: "IP11-128", Quantity: 1, Price: decimal.NewFromInt(150), Currency: "USD"},
+	}, o.Items)
+	s.Require().Len(o.Transitions, 1)
+	s.Equal(model.OrderTransition{To: model.OrderPending, ActorID: 1, CreatedAt: o.Transitions[0].CreatedAt}, o.Transitions[0])
+
+	pos, err := s.s.(pg).GetPosition(s.ctx, 1, 1)
+	s.Require().NoError(err)
+	s.Equal(int64(3), pos.Quantity)
+
+	items, err := s.s.(pg).GetCartItems(s.ctx, model.CartOwner{UserID: 1})
+	s.Require().NoError(err)
+	s.Empty(items)
+}
+
+func (s *postgresTestSuite) TestPg_CreateOrder_positionChanged() {
+	s.setupOrderData()
+
+	_, err := s.s.(pg).CreateOrder(s.ctx, model.Order{
+		UserID:   1,
+		Status:   model.OrderPending,
+		Currency: "USD",
+		Total:    decimal.NewFromInt(90),
+		Items: []model.OrderItem{
+			{VariantID: 1, StoreID: 1, Quantity: 1, Price: decimal.NewFromInt(90), Currency: "USD"},
+		},
+	})
+	s.True(errors.Is(err, storage.ErrPositionChanged), err)
+
+	_, err = s.s.(pg).CreateOrder(s.ctx, model.Order{
+		UserID:   1,
+		Status:   model.OrderPending,
+		Currency: "USD",
+		Total:    decimal.NewFromInt(600),
+		Items: []model.OrderItem{
+			{VariantID: 1, StoreID: 1, Quantity: 6, Price: decimal.NewFromInt(100), Currency: "USD"},
+		},
+	})
+	s.True(errors.Is(err, storage.ErrInsufficientStock), err)
+
+	orders, _, err := s.s.(pg).GetOrders(s.ctx, model.OrderFilter{}, model.Page{Limit: 10})
+	s.Require().NoError(err)
+	s.Empty(orders)
+}
+
+func (s *postgresTestSuite) TestPg_GetOrders() {
+	s.setupOrderData()
+
+	first := s.createTestOrder()
+	second := s.createTestOrder()
+	s.Require().NoError(s.s.(pg).UpdateOrderStatus(s.ctx, first.ID, model.OrderPending, model.OrderPaid, 2, false))
+
+	orders, next, err := s.s.(pg).GetOrders(s.ctx, model.OrderFilter{UserID: 1}, model.Page{Limit: 1})
+	s.Require().NoError(err)
+	s.Require().Len(orders, 1)
+	s.Equal(second.ID, orders[0].ID)
+	s.Len(orders[0].Items, 2)
+	s.Empty(orders[0].Transitions)
+	s.Require().NotEmpty(next)
+
+	orders, next, err = s.s.(pg).GetOrders(s.ctx, model.OrderFilter{UserID: 1}, model.Page{Limit: 1, Cursor: next})
+	s.Require().NoError(err)
+	s.Require().Len(orders, 1)
+	s.Equal(first.ID, orders[0].ID)
+	s.Empty(next)
+
+	orders, _, err = s.s.(pg).GetOrders(s.ctx, model.OrderFilter{Status: []model.OrderStatus{model.OrderPaid}}, model.Page{Limit: 10})
+	s.Require().NoError(err)
+	s.Require().Len(orders, 1)
+	s.Equal(first.ID, orders[0].ID)
+
+	orders, _, err = s.s.(pg).GetOrders(s.ctx, model.OrderFilter{UserID: 2}, model.Page{Limit: 10})
+	s.Require().NoError(err)
+	s.Empty(orders)
+}
+
+func (s *postgresTestSuite) TestPg_UpdateOrderStatus() {
+	s.setupOrderData()
+
+	o := s.createTestOrder()
+
+	err := s.s.(pg).UpdateOrderStatus(s.ctx, o.ID, model.OrderPending, model.OrderCancelled, 2, true)
+	s.Require().NoError(err)
+
+	o, err = s.s.(pg).GetOrder(s.ctx, o.ID)
+	s.Require().NoError(err)
+	s.Equal(model.OrderCancelled, o.Status)
+	s.False(o.Items[0].Reserved)
+	s.Require().Len(o.Transitions, 2)
+	s.Equal(model.OrderPending, o.Transitions[1].From)
+	s.Equal(model.OrderCancelled, o.Transitions[1].To)
+	s.Equal(int64(2), o.Transitions[1].ActorID)
+
+	pos, err := s.s.(pg).GetPosition(s.ctx, 1, 1)
+	s.Require().NoError(err)
+	s.Equal(int64(5), pos.Quantity)
+
+	err = s.s.(pg).UpdateOrderStatus(s.ctx, o.ID, model.OrderPending, model.OrderPaid, 2, false)
+	s.True(errors.Is(err, storage.ErrOrderStatusChanged), err)
+
+	_, err = s.s.(pg).GetOrder(s.ctx, 100500)
+	s.True(errors.Is(err, storage.ErrNotFound), err)
+}
@@ -0,0 +1,59 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/vliubezny/gstore/internal/model"
+)
+
+func (p pg) EnqueueEmail(ctx context.Context, e model.Email) error {
+	if _, err := p.ext.ExecContext(ctx, `
+		INSERT INTO email_outbox (recipient, subject, body) VALUES ($1, $2, $3)
+	`, e.To, e.Subject, e.Body); err != nil {
+		return fmt.Errorf("failed to enqueue email: %w", err)
+	}
+	return nil
+}
+
+func (p pg) ClaimEmails(ctx context.Context, limit int, lease time.Duration) ([]model.Email, error) {
+	var emails []email
+	if err := p.ext.SelectContext(ctx, &emails, `
+		UPDATE email_outbox SET next_attempt_at = now() + $2 * interval '1 second'
+		WHERE id IN (
+			SELECT id FROM email_outbox
+			WHERE sent_at IS NULL AND next_attempt_at <= now()
+			ORDER BY id LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, recipient, subject, body, attempts
+	`, limit, lease.Seconds()); err != nil {
+		return nil, fmt.Errorf("failed to claim emails: %w", err)
+	}
+
+	data := make([]model.Email, len(emails))
+	for i, e := range emails {
+		data[i] = e.toModel()
+	}
+
+	return data, nil
+}
+
+func (p pg) MarkEmailSent(ctx context.Context, emailID int64) error {
+	if _, err := p.ext.ExecContext(ctx, `
+		UPDATE email_outbox SET sent_at = now(), next_attempt_at = NULL WHERE id = $1
+	`, emailID); err != nil {
+		return fmt.Errorf("failed to mark email sent: %w", err)
+	}
+	return nil
+}
+
+func (p pg) MarkEmailFailed(ctx context.Context, emailID int64, reason string, retryAt time.Time) error {
+	if _, err := p.ext.ExecContext(ctx, `
+		UPDATE email_outbox SET attempts = attempts + 1, last_error = $2, next_attempt_at = $3 WHERE id = $1
+	`, emailID, reason, nullDate(retryAt)); err != nil {
+		return fmt.Errorf("failed to mark email failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+//+build integration
+
+package postgres
+
+import (
+	"time"
+
+	"github.com/vliubezny/gstore/internal/model"
+)
+
+func (s *postgresTestSuite) TestPg_Outbox() {
+	email := model.Email{To: "user@test.com", Subject: "subject", Body: "body"}
+	s.Require().NoError(s.s.(pg).EnqueueEmail(s.ctx, email))
+
+	emails, err := s.s.(pg).ClaimEmails(s.ctx, 10, time.Minute)
+	s.Require().NoError(err)
+	s.Require().Len(emails, 1)
+	email.ID = emails[0].ID
+	s.Equal(email, emails[0])
+
+	emails, err = s.s.(pg).ClaimEmails(s.ctx, 10, time.Minute)
+	s.Require().NoError(err)
+	s.Empty(emails, "claimed email must be leased")
+
+	s.Require().NoError(s.s.(pg).MarkEmailFailed(s.ctx, email.ID, "test error", time.Now().Add(-time.Second)))
+
+	emails, err = s.s.(pg).ClaimEmails(s.ctx, 10, time.Minute)
+	s.Require().NoError(err)
+	s.Require().Len(emails, 1)
+	s.Equal(1, emails[0].Attempts)
+
+	s.Require().NoError(s.s.(pg).MarkEmailSent(s.ctx, email.ID))
+
+	var sent bool
+	s.Require().NoError(s.db.QueryRow("SELECT sent_at IS NOT NULL FROM email_outbox WHERE id = $1", email.ID).Scan(&sent))
+	s.True(sent)
+}
+
+func (s *postgresTestSuite) TestPg_Outbox_giveUp() {
+	s.Require().NoError(s.s.(pg).EnqueueEmail(s.ctx, model.Email{To: "user@test.com", Subject: "subject", Body: "body"}))
+
+	emails, err := s.s.(pg).ClaimEmails(s.ctx, 10, 0)
+	s.Require().NoError(err)
+	s.Require().Len(emails, 1)
+
+	s.Require().NoError(s.s.(pg).MarkEmailFailed(s.ctx, emails[0].ID, "test error", time.Time{}))
+
+	emails, err = s.s.(pg).ClaimEmails(s.ctx, 10, 0)
+	s.Require().NoError(err)
+	s.Empty(emails)
+}
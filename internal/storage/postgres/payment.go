@@ -0,0 +1,98 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/vliubezny/gstore/internal/model"
+	"github.com/vliubezny/gstore/internal/storage"
+)
+
+const paymentOrderIDActiveConstraint = "payment_order_id_active_idx"
+
+func (p pg) CreatePayment(ctx context.Context, pay model.Payment) error {
+	if _, err := p.ext.ExecContext(ctx, `
+		INSERT INTO payment (id, order_id, status, amount, currency) VALUES ($1, $2, $3, $4, $5)
+	`, pay.ID, pay.OrderID, pay.Status, pay.Amount, pay.Currency); err != nil {
+		if err, ok := err.(*pq.Error); ok && err.Constraint == paymentOrderIDActiveConstraint {
+			return storage.ErrPaymentInProgress
+		}
+		return fmt.Errorf("failed to create payment: %w", err)
+	}
+
+	return nil
+}
+
+func (p pg) GetPayment(ctx context.Context, paymentID string) (model.Payment, error) {
+	var pay payment
+	err := p.ext.GetContext(ctx, &pay, `
+		SELECT id, order_id, status, amount, currency, created_at FROM payment WHERE id = $1
+	`, paymentID)
+
+	if err == sql.ErrNoRows {
+		return model.Payment{}, storage.ErrNotFound
+	}
+
+	if err != nil {
+		return model.Payment{}, fmt.Errorf("failed to get payment: %w", err)
+	}
+
+	return pay.toModel(), nil
+}
+
+func (p pg) GetOrderPayments(ctx context.Context, orderID int64) ([]model.Payment, error) {
+	var payments []payment
+	if err := p.ext.SelectContext(ctx, &payments, `
+		SELECT id, order_id, status, amount, currency, created_at FROM payment WHERE order_id = $1
+		ORDER BY created_at, id
+	`, orderID); err != nil {
+		return nil, fmt.Errorf("failed to get payments: %w", err)
+	}
+
+	data := make([]model.Payment, len(payments))
+	for i, pay := range payments {
+		data[i] = pay.toModel()
+	}
+
+	return data, nil
+}
+
+func (p pg) UpdatePaymentStatus(ctx context.Context, paymentID string, status model.PaymentStatus) error {
+	res, err := p.ext.ExecContext(ctx, `
+		UPDATE payment SET status = $2, updated_at = now() WHERE id = $1
+	`, paymentID, status)
+	if err != nil {
+		return fmt.Errorf("failed to update payment status: %w", err)
+	}
+
+	if c, _ := res.RowsAffected(); c == 0 {
+		return storage.ErrNotFound
+	}
+
+	return nil
+}
+
+func (p pg) AddPaymentEvent(ctx context.Context, eventID, paymentID string) error {
+	res, err := p.ext.ExecContext(ctx, `
+		INSERT INTO payment_event (id, payment_id) VALUES ($1, $2) ON CONFLICT (id) DO NOTHING
+	`, eventID, paymentID)
+	if err != nil {
+		return fmt.Errorf("failed to add payment event: %w", err)
+	}
+
+	if c, _ := res.RowsAffected(); c == 0 {
+		return storage.ErrDuplicateEvent
+	}
+
+	return nil
+}
+
+func (p pg) DeletePaymentEvent(ctx context.Context, eventID string) error {
+	if _, err := p.ext.ExecContext(ctx, "DELETE FROM payment_event WHERE id = $1", eventID); err != nil {
+		return fmt.Errorf("failed to delete payment event: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,83 @@
+//+build integration
+
+package postgres
+
+import (
+	"github.com/vliubezny/gstore/internal/model"
+	"github.com/vliubezny/gstore/internal/storage"
+)
+
+func (s *postgresTestSuite) TestPg_Payment() {
+	s.setupOrderData()
+	o := s.createTestOrder()
+
+	pay := model.Payment{
+		ID:       "pay1",
+		OrderID:  o.ID,
+		Status:   model.PaymentPending,
+		Amount:   o.Total,
+		Currency: "USD",
+	}
+	s.Require().NoError(s.s.(pg).CreatePayment(s.ctx, pay))
+
+	s.Require().NoError(s.s.(pg).UpdatePaymentStatus(s.ctx, "pay1", model.PaymentCaptured))
+
+	p, err := s.s.(pg).GetPayment(s.ctx, "pay1")
+	s.Require().NoError(err)
+	pay.Status = model.PaymentCaptured
+	pay.CreatedAt = p.CreatedAt
+	s.Equal(pay.ID, p.ID)
+	s.Equal(pay.Status, p.Status)
+	s.True(pay.Amount.Equal(p.Amount))
+
+	payments, err := s.s.(pg).GetOrderPayments(s.ctx, o.ID)
+	s.Require().NoError(err)
+	s.Len(payments, 1)
+
+	_, err = s.s.(pg).GetPayment(s.ctx, "unknown")
+	s.Equal(storage.ErrNotFound, err)
+
+	err = s.s.(pg).UpdatePaymentStatus(s.ctx, "unknown", model.PaymentCaptured)
+	s.Equal(storage.ErrNotFound, err)
+}
+
+func (s *postgresTestSuite) TestPg_CreatePayment_InProgress() {
+	s.setupOrderData()
+	o := s.createTestOrder()
+
+	s.Require().NoError(s.s.(pg).CreatePayment(s.ctx, model.Payment{
+		ID: "pay1", OrderID: o.ID, Status: model.PaymentDeclined, Amount: o.Total, Currency: "USD",
+	}))
+	s.Require().NoError(s.s.(pg).CreatePayment(s.ctx, model.Payment{
+		ID: "pay2", OrderID: o.ID, Status: model.PaymentAuthorized, Amount: o.Total, Currency: "USD",
+	}), "declined payment must not block another one")
+
+	err := s.s.(pg).CreatePayment(s.ctx, model.Payment{
+		ID: "pay3", OrderID: o.ID, Status: model.PaymentAuthorized, Amount: o.Total, Currency: "USD",
+	})
+	s.Equal(storage.ErrPaymentInProgress, err)
+
+	s.Require().NoError(s.s.(pg).UpdatePaymentStatus(s.ctx, "pay2", model.PaymentDeclined))
+
+	s.NoError(s.s.(pg).CreatePayment(s.ctx, model.Payment{
+		ID: "pay3", OrderID: o.ID, Status: model.PaymentAuthorized, Amount: o.Total, Currency: "USD",
+	}))
+}
+
+func (s *postgresTestSuite) TestPg_AddPaymentEvent() {
+	s.setupOrderData()
+	o := s.createTestOrder()
+
+	s.Require().NoError(s.s.(pg).CreatePayment(s.ctx, model.Payment{
+		ID: "pay1", OrderID: o.ID, Status: model.PaymentPending, Amount: o.Total, Currency: "USD",
+	}))
+
+	s.Require().NoError(s.s.(pg).AddPaymentEvent(s.ctx, "e1", "pay1"))
+
+	err := s.s.(pg).AddPaymentEvent(s.ctx, "e1", "pay1")
+	s.Equal(storage.ErrDuplicateEvent, err)
+
+	s.Require().NoError(s.s.(pg).DeletePaymentEvent(s.ctx, "e1"))
+
+	s.NoError(s.s.(pg).AddPaymentEvent(s.ctx, "e1", "pay1"))
+}
@@ -2,6 +2,7 @@ package postgres
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 
 	"github.com/lib/pq"
@@ -10,15 +11,31 @@ import (
 )
 
 const (
-	productIDFKConstraint = "position_product_id_fkey"
-	storeIDFKConstraint   = "position_store_id_fkey"
+	storeIDFKConstraint          = "position_store_id_fkey"
+	positionQuantityConstraint   = "position_quantity_check"
+	positionCurrencyFKConstraint = "position_currency_fkey"
 )
 
-func (p pg) GetStorePositions(ctx context.Context, storeID int64) ([]model.Position, error) {
+func (p pg) GetStorePositions(ctx context.Context, storeID int64, page model.Page) ([]model.Position, string, error) {
+	after, err := decodeCursor(page.Cursor)
+	if err != nil {
+		return nil, "", err
+	}
+
 	var positions []position
 
-	if err := p.ext.SelectContext(ctx, &positions, "SELECT product_id, store_id, price FROM position WHERE store_id=$1", storeID); err != nil {
-		return nil, fmt.Errorf("failed to get positions: %w", err)
+	if err := p.ext.SelectContext(ctx, &positions, `
+		SELECT product_id, variant_id, store_id, price, currency, quantity, availability, restock_date FROM position
+		WHERE store_id = $1 AND variant_id > $2
+		ORDER BY variant_id LIMIT $3
+	`, storeID, after.ID, page.Limit+1); err != nil {
+		return nil, "", fmt.Errorf("failed to get positions: %w", err)
+	}
+
+	var next string
+	if len(positions) > page.Limit {
+		positions = positions[:page.Limit]
+		next = encodeCursor(cursor{ID: positions[page.Limit-1].VariantID})
 	}
 
 	data := make([]model.Position, len(positions))
@@ -26,14 +43,36 @@ func (p pg) GetStorePositions(ctx context.Context, storeID int64) ([]model.Posit
 		data[i] = d.toModel()
 	}
 
-	return data, nil
+	return data, next, nil
 }
 
-func (p pg) GetProductPositions(ctx context.Context, productID int64) ([]model.Position, error) {
+func (p pg) GetProductPositions(ctx context.Context, productID int64, filter model.PositionFilter, page model.Page) ([]model.Position, string, error) {
+	after, err := decodeCursor(page.Cursor)
+	if err != nil {
+		return nil, "", err
+	}
+
+	availability := make([]string, len(filter.Availability))
+	for i, a := range filter.Availability {
+		availability[i] = string(a)
+	}
+
 	var positions []position
 
-	if err := p.ext.SelectContext(ctx, &positions, "SELECT product_id, store_id, price FROM position WHERE product_id=$1", productID); err != nil {
-		return nil, fmt.Errorf("failed to get positions: %w", err)
+	if err := p.ext.SelectContext(ctx, &positions, `
+		SELECT product_id, variant_id, store_id, price, currency, quantity, availability, restock_date FROM position
+		WHERE product_id = $1 AND (store_id, variant_id) > ($2, $3)
+		AND (cardinality($4::text[]) = 0 OR availability = ANY($4))
+		ORDER BY store_id, variant_id LIMIT $5
+	`, productID, after.ID, after.VariantID, pq.Array(availability), page.Limit+1); err != nil {
+		return nil, "", fmt.Errorf("failed to get positions: %w", err)
+	}
+
+	var next string
+	if len(positions) > page.Limit {
+		positions = positions[:page.Limit]
+		last := positions[page.Limit-1]
+		next = encodeCursor(cursor{ID: last.StoreID, VariantID: last.VariantID})
 	}
 
 	data := make([]model.Position, len(positions))
@@ -41,33 +80,98 @@ func (p pg) GetProductPositions(ctx context.Context, productID int64) ([]model.P
 		data[i] = d.toModel()
 	}
 
-	return data, nil
+	return data, next, nil
 }
 
-func (p pg) UpsertPosition(ctx context.Context, position model.Position) error {
-	if _, err := p.ext.ExecContext(ctx, `
-		INSERT INTO position (product_id, store_id, price) VALUES($1, $2, $3)
-			ON CONFLICT(product_id, store_id) DO UPDATE SET price = EXCLUDED.price;
-	`, position.ProductID, position.StoreID, position.Price); err != nil {
+func (p pg) GetPosition(ctx context.Context, variantID, storeID int64) (model.Position, error) {
+	var pos position
+	err := p.ext.GetContext(ctx, &pos, `
+		SELECT product_id, variant_id, store_id, price, currency, quantity, availability, restock_date FROM position
+		WHERE variant_id = $1 AND store_id = $2
+	`, variantID, storeID)
+
+	if err == sql.ErrNoRows {
+		return model.Position{}, storage.ErrNotFound
+	}
+
+	if err != nil {
+		return model.Position{}, fmt.Errorf("failed to get position: %w", err)
+	}
+
+	return pos.toModel(), nil
+}
+
+func (p pg) UpsertPosition(ctx context.Context, pos model.Position, userID int64) (model.Position, error) {
+	// previous price is locked to record every change exactly once under concurrent updates,
+	// quantity of existing position is changed only by AdjustPositionStock to not lose concurrent adjustments
+	var res position
+	err := p.ext.GetContext(ctx, &res, `
+		WITH old AS (
+			SELECT price, currency FROM position WHERE variant_id = $1 AND store_id = $2 FOR UPDATE
+		), up AS (
+			INSERT INTO position (product_id, variant_id, store_id, price, currency, quantity, availability, restock_date)
+				SELECT product_id, id, $2, $3, $4, $5, COALESCE(NULLIF($6::text, ''), $9), $7 FROM variant WHERE id = $1
+				ON CONFLICT(variant_id, store_id) DO UPDATE SET
+					price = EXCLUDED.price,
+					currency = EXCLUDED.currency,
+					availability = CASE WHEN $6::text = '' THEN position.availability ELSE EXCLUDED.availability END,
+					restock_date = CASE WHEN $6::text = '' THEN position.restock_date ELSE EXCLUDED.restock_date END
+			RETURNING product_id, variant_id, store_id, price, currency, quantity, availability, restock_date
+		), history AS (
+			INSERT INTO position_price_history (product_id, variant_id, store_id, price, currency, user_id)
+				SELECT product_id, variant_id, store_id, price, currency, $8 FROM up
+				WHERE NOT EXISTS (SELECT 1 FROM old WHERE old.price = up.price AND old.currency = up.currency)
+		)
+		SELECT product_id, variant_id, store_id, price, currency, quantity, availability, restock_date FROM up
+	`, pos.VariantID, pos.StoreID, pos.Price, pos.Currency, pos.Quantity, pos.Availability, nullDate(pos.RestockDate),
+		nullID(userID), model.AvailabilityInStock)
+
+	if err == sql.ErrNoRows {
+		return model.Position{}, storage.ErrUnknownVariant
+	}
+
+	if err != nil {
 		if err, ok := err.(*pq.Error); ok {
 			switch err.Constraint {
-			case productIDFKConstraint:
-				return storage.ErrUnknownProduct
 			case storeIDFKConstraint:
-				return storage.ErrUnknownStore
+				return model.Position{}, storage.ErrUnknownStore
+			case positionCurrencyFKConstraint:
+				return model.Position{}, storage.ErrUnknownCurrency
 			}
 		}
+		return model.Position{}, fmt.Errorf("failed to upsert position: %w", err)
+	}
 
-		return fmt.Errorf("failed to upsert position: %w", err)
+	return res.toModel(), nil
+}
+
+func (p pg) AdjustPositionStock(ctx context.Context, variantID, storeID, delta int64) (model.Position, error) {
+	// the check constraint guarantees that concurrent decrements never make quantity negative
+	var pos position
+	err := p.ext.GetContext(ctx, &pos, `
+		UPDATE position SET quantity = quantity + $3
+		WHERE variant_id = $1 AND store_id = $2
+		RETURNING product_id, variant_id, store_id, price, currency, quantity, availability, restock_date
+	`, variantID, storeID, delta)
+
+	if err == sql.ErrNoRows {
+		return model.Position{}, storage.ErrNotFound
 	}
 
-	return nil
+	if err != nil {
+		if err, ok := err.(*pq.Error); ok && err.Constraint == positionQuantityConstraint {
+			return model.Position{}, storage.ErrInsufficientStock
+		}
+		return model.Position{}, fmt.Errorf("failed to adjust position stock: %w", err)
+	}
+
+	return pos.toModel(), nil
 }
 
-func (p pg) DeletePosition(ctx context.Context, productID, storeID int64) error {
+func (p pg) DeletePosition(ctx context.Context, variantID, storeID int64) error {
 	res, err := p.ext.ExecContext(ctx, `
-		DELETE FROM position WHERE product_id = $1 AND store_id = $2
-	`, productID, storeID)
+		DELETE FROM position WHERE variant_id = $1 AND store_id = $2
+	`, variantID, storeID)
 
 	if err != nil {
 		return fmt.Errorf("failed to delete position: %w", err)
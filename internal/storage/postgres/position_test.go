@@ -4,6 +4,8 @@ package postgres
 
 import (
 	"errors"
+	"sync"
+	"time"
 
 	"github.com/shopspring/decimal"
 	"github.com/vliubezny/gstore/internal/model"
@@ -15,105 +17,139 @@ func (s *postgresTestSuite) TestPg_GetStorePositions() {
 		INSERT INTO product (category_id, name, description) VALUES
 			(1, 'iPhone 11', 'Old iphone'),
 			(1, 'iPhone 12', 'New iphone');
+		INSERT INTO variant (product_id, sku) VALUES (1, 'IP11'), (2, 'IP12');
 		INSERT INTO store (name) VALUES ('iStore');
-		INSERT INTO position (product_id, store_id, price) VALUES
-			(1, 1, 100),
-			(2, 1, 200);
+		INSERT INTO position (product_id, variant_id, store_id, price) VALUES
+			(1, 1, 1, 100),
+			(2, 2, 1, 200);
 	`)
 	s.Require().NoError(err)
 	storeID := int64(1)
 
-	positions, err := s.s.GetStorePositions(s.ctx, storeID)
+	positions, next, err := s.s.GetStorePositions(s.ctx, storeID, model.Page{Limit: 1})
 	s.Require().NoError(err)
 
 	s.Equal([]model.Position{
-		{ProductID: 1, StoreID: storeID, Price: decimal.NewFromInt(100)},
-		{ProductID: 2, StoreID: storeID, Price: decimal.NewFromInt(200)},
+		{ProductID: 1, VariantID: 1, StoreID: storeID, Price: decimal.NewFromInt(100), Currency: "USD", Availability: model.AvailabilityInStock},
 	}, positions)
+	s.Require().NotEmpty(next)
+
+	positions, next, err = s.s.GetStorePositions(s.ctx, storeID, model.Page{Limit: 1, Cursor: next})
+	s.Require().NoError(err)
+
+	s.Equal([]model.Position{
+		{ProductID: 2, VariantID: 2, StoreID: storeID, Price: decimal.NewFromInt(200), Currency: "USD", Availability: model.AvailabilityInStock},
+	}, positions)
+	s.Empty(next)
 }
 
 func (s *postgresTestSuite) TestPg_GetProductPositions() {
 	_, err := s.db.Exec(`
 		INSERT INTO product (category_id, name, description) VALUES (1, 'iPhone 11', 'Old iphone');
+		INSERT INTO variant (product_id, sku) VALUES (1, 'IP11-64'), (1, 'IP11-128');
 		INSERT INTO store (name) VALUES ('iStore'), ('Amazon');
-		INSERT INTO position (product_id, store_id, price) VALUES
-			(1, 1, 100),
-			(1, 2, 200);
+		INSERT INTO position (product_id, variant_id, store_id, price) VALUES
+			(1, 1, 1, 100),
+			(1, 2, 1, 150),
+			(1, 1, 2, 200);
 	`)
 	s.Require().NoError(err)
 	productID := int64(1)
 
-	positions, err := s.s.GetProductPositions(s.ctx, productID)
+	positions, next, err := s.s.GetProductPositions(s.ctx, productID, model.PositionFilter{}, model.Page{Limit: 2})
 	s.Require().NoError(err)
 
 	s.Equal([]model.Position{
-		{ProductID: productID, StoreID: 1, Price: decimal.NewFromInt(100)},
-		{ProductID: productID, StoreID: 2, Price: decimal.NewFromInt(200)},
+		{ProductID: productID, VariantID: 1, StoreID: 1, Price: decimal.NewFromInt(100), Currency: "USD", Availability: model.AvailabilityInStock},
+		{ProductID: productID, VariantID: 2, StoreID: 1, Price: decimal.NewFromInt(150), Currency: "USD", Availability: model.AvailabilityInStock},
 	}, positions)
+	s.Require().NotEmpty(next)
+
+	positions, next, err = s.s.GetProductPositions(s.ctx, productID, model.PositionFilter{}, model.Page{Limit: 2, Cursor: next})
+	s.Require().NoError(err)
+
+	s.Equal([]model.Position{
+		{ProductID: productID, VariantID: 1, StoreID: 2, Price: decimal.NewFromInt(200), Currency: "USD", Availability: model.AvailabilityInStock},
+	}, positions)
+	s.Empty(next)
 }
 
 func (s *postgresTestSuite) TestPg_UpsertPosition() {
 	_, err := s.db.Exec(`
 		INSERT INTO product (category_id, name, description) VALUES (1, 'iPhone 11', 'Old iphone');
+		INSERT INTO variant (product_id, sku) VALUES (1, 'IP11');
 		INSERT INTO store (name) VALUES ('iStore');
 	`)
 	s.Require().NoError(err)
 
 	p := model.Position{
-		ProductID: 1,
-		StoreID:   1,
-		Price:     decimal.NewFromInt(100),
+		VariantID:    1,
+		StoreID:      1,
+		Price:        decimal.NewFromInt(100),
+		Currency:     "USD",
+		Quantity:     5,
+		Availability: model.AvailabilityInStock,
 	}
 
-	s.Require().NoError(s.s.UpsertPosition(s.ctx, p))
-
-	r := s.db.QueryRow(`
-		SELECT product_id, store_id, price FROM position WHERE product_id = $1 AND store_id = $2
-	`, p.ProductID, p.StoreID)
-	res := model.Position{}
-	err = r.Scan(&res.ProductID, &res.StoreID, &res.Price)
+	res, err := s.s.UpsertPosition(s.ctx, p, 0)
 	s.Require().NoError(err)
 
+	p.ProductID = 1
 	s.Equal(p, res)
 
+	positions, _, err := s.s.GetStorePositions(s.ctx, p.StoreID, model.Page{Limit: 10})
+	s.Require().NoError(err)
+
+	s.Equal([]model.Position{p}, positions)
+
 	p.Price = decimal.NewFromInt(200)
+	p.Quantity = 0
+	p.Availability = model.AvailabilityBackorder
+	p.RestockDate = time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	res, err = s.s.UpsertPosition(s.ctx, p, 0)
+	s.Require().NoError(err)
+
+	p.Quantity = 5 // stock of existing position is changed only by adjustments
+	s.Equal(p, res)
 
-	s.Require().NoError(s.s.UpsertPosition(s.ctx, p))
+	positions, _, err = s.s.GetStorePositions(s.ctx, p.StoreID, model.Page{Limit: 10})
+	s.Require().NoError(err)
+
+	s.Equal([]model.Position{p}, positions)
 
-	r = s.db.QueryRow(`
-		SELECT product_id, store_id, price FROM position WHERE product_id = $1 AND store_id = $2;
-	`, p.ProductID, p.StoreID)
-	err = r.Scan(&res.ProductID, &res.StoreID, &res.Price)
+	// price only update keeps availability and restock date
+	res, err = s.s.UpsertPosition(s.ctx, model.Position{VariantID: 1, StoreID: 1, Price: decimal.NewFromInt(300), Currency: "USD"}, 0)
 	s.Require().NoError(err)
 
+	p.Price = decimal.NewFromInt(300)
 	s.Equal(p, res)
 
-	s.True(errors.Is(storage.ErrUnknownProduct, s.s.UpsertPosition(s.ctx,
-		model.Position{ProductID: 100, StoreID: 1, Price: decimal.NewFromInt(100)},
-	)))
+	_, err = s.s.UpsertPosition(s.ctx, model.Position{VariantID: 100, StoreID: 1, Price: decimal.NewFromInt(100), Availability: model.AvailabilityInStock}, 0)
+	s.True(errors.Is(err, storage.ErrUnknownVariant))
 
-	s.True(errors.Is(storage.ErrUnknownStore, s.s.UpsertPosition(s.ctx,
-		model.Position{ProductID: 1, StoreID: 100, Price: decimal.NewFromInt(100)},
-	)))
+	_, err = s.s.UpsertPosition(s.ctx, model.Position{VariantID: 1, StoreID: 100, Price: decimal.NewFromInt(100), Availability: model.AvailabilityInStock}, 0)
+	s.True(errors.Is(err, storage.ErrUnknownStore))
 }
 
 func (s *postgresTestSuite) TestPg_DeletePosition() {
 	_, err := s.db.Exec(`
 		INSERT INTO product (category_id, name, description) VALUES (1, 'iPhone 11', 'Old iphone');
+		INSERT INTO variant (product_id, sku) VALUES (1, 'IP11');
 		INSERT INTO store (name) VALUES ('iStore');
-		INSERT INTO position (product_id, store_id, price) VALUES (1, 1, 100);
+		INSERT INTO position (product_id, variant_id, store_id, price) VALUES (1, 1, 1, 100);
 	`)
 	s.Require().NoError(err)
 
-	productID := int64(1)
+	variantID := int64(1)
 	storeID := int64(1)
 
-	err = s.s.DeletePosition(s.ctx, productID, storeID)
+	err = s.s.DeletePosition(s.ctx, variantID, storeID)
 	s.Require().NoError(err)
 
 	r := s.db.QueryRow(`
-		SELECT count(*) FROM position WHERE product_id = $1 AND store_id = $2;
-	`, productID, storeID)
+		SELECT count(*) FROM position WHERE variant_id = $1 AND store_id = $2;
+	`, variantID, storeID)
 	var c int
 	err = r.Scan(&c)
 	s.Require().NoError(err)
@@ -124,3 +160,81 @@ func (s *postgresTestSuite) TestPg_DeletePosition() {
 
 	s.True(errors.Is(err, storage.ErrNotFound))
 }
+
+func (s *postgresTestSuite) TestPg_GetProductPositions_Filter() {
+	_, err := s.db.Exec(`
+		INSERT INTO product (category_id, name, description) VALUES (1, 'iPhone 11', 'Old iphone');
+		INSERT INTO variant (product_id, sku) VALUES (1, 'IP11');
+		INSERT INTO store (name) VALUES ('iStore'), ('Amazon'), ('eBay');
+		INSERT INTO position (product_id, variant_id, store_id, price, quantity, availability) VALUES
+			(1, 1, 1, 100, 5, 'in_stock'),
+			(1, 1, 2, 200, 0, 'backorder'),
+			(1, 1, 3, 300, 0, 'discontinued');
+	`)
+	s.Require().NoError(err)
+
+	stores := func(availability ...model.Availability) []int64 {
+		positions, _, err := s.s.GetProductPositions(s.ctx, 1, model.PositionFilter{Availability: availability}, model.Page{Limit: 10})
+		s.Require().NoError(err)
+
+		var res []int64
+		for _, p := range positions {
+			res = append(res, p.StoreID)
+		}
+		return res
+	}
+
+	s.Equal([]int64{1, 2, 3}, stores())
+	s.Equal([]int64{1}, stores(model.AvailabilityInStock))
+	s.Equal([]int64{1, 2}, stores(model.AvailabilityInStock, model.AvailabilityBackorder))
+	s.Equal([]int64{3}, stores(model.AvailabilityDiscontinued))
+}
+
+func (s *postgresTestSuite) TestPg_AdjustPositionStock() {
+	_, err := s.db.Exec(`
+		INSERT INTO product (category_id, name, description) VALUES (1, 'iPhone 11', 'Old iphone');
+		INSERT INTO variant (product_id, sku) VALUES (1, 'IP11');
+		INSERT INTO store (name) VALUES ('iStore');
+		INSERT INTO position (product_id, variant_id, store_id, price, quantity) VALUES (1, 1, 1, 100, 5);
+	`)
+	s.Require().NoError(err)
+
+	p, err := s.s.AdjustPositionStock(s.ctx, 1, 1, 3)
+	s.Require().NoError(err)
+
+	s.Equal(model.Position{ProductID: 1, VariantID: 1, StoreID: 1, Price: decimal.NewFromInt(100), Currency: "USD",
+		Quantity: 8, Availability: model.AvailabilityInStock}, p)
+
+	_, err = s.s.AdjustPositionStock(s.ctx, 1, 1, -9)
+	s.True(errors.Is(err, storage.ErrInsufficientStock))
+
+	_, err = s.s.AdjustPositionStock(s.ctx, 100, 1, 1)
+	s.True(errors.Is(err, storage.ErrNotFound))
+
+	// concurrent decrements succeed only while stock lasts
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := s.s.AdjustPositionStock(s.ctx, 1, 1, -1)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	failed := 0
+	for err := range errs {
+		if err != nil {
+			s.True(errors.Is(err, storage.ErrInsufficientStock))
+			failed++
+		}
+	}
+	s.Equal(2, failed)
+
+	var quantity int64
+	s.Require().NoError(s.db.QueryRow("SELECT quantity FROM position WHERE variant_id = 1 AND store_id = 1").Scan(&quantity))
+	s.Equal(int64(0), quantity)
+}
@@ -3,6 +3,7 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"fmt"
 
 	"github.com/jmoiron/sqlx"
 
@@ -28,3 +29,33 @@ func New(db *sql.DB) storage.Storage {
 		ext: dbx,
 	}
 }
+
+// inTx executes action with storage bound to transaction.
+func (p pg) inTx(ctx context.Context, action func(tx pg) error) error {
+	return p.inTxWithIsolation(ctx, sql.LevelRepeatableRead, action)
+}
+
+// inTxWithIsolation executes action with storage bound to transaction of the isolation level.
+func (p pg) inTxWithIsolation(ctx context.Context, level sql.IsolationLevel, action func(tx pg) error) error {
+	tx, err := p.dbx.BeginTxx(ctx, &sql.TxOptions{Isolation: level})
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	err = action(pg{dbx: p.dbx, ext: tx})
+
+	if err != nil {
+		rbErr := tx.Rollback()
+		if rbErr != nil {
+			return fmt.Errorf("failed to rollback transaction: %v root: %w", rbErr, err)
+		}
+
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
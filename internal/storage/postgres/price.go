@@ -0,0 +1,106 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/vliubezny/gstore/internal/model"
+)
+
+func (p pg) GetPriceHistory(ctx context.Context, productID int64, filter model.PriceHistoryFilter) ([]model.PriceChange, error) {
+	var q queryBuilder
+	priceHistoryConditions(&q, productID, filter)
+
+	var changes []priceChange
+	if err := p.ext.SelectContext(ctx, &changes, `
+		SELECT product_id, variant_id, store_id, price, currency, user_id, changed_at
+		FROM position_price_history
+		WHERE `+q.where()+`
+		ORDER BY changed_at, id
+	`, q.args...); err != nil {
+		return nil, fmt.Errorf("failed to get price history: %w", err)
+	}
+
+	data := make([]model.PriceChange, len(changes))
+	for i, c := range changes {
+		data[i] = c.toModel()
+	}
+
+	return data, nil
+}
+
+func (p pg) GetDailyPrices(ctx context.Context, productID int64, filter model.PriceHistoryFilter) ([]model.PriceBucket, error) {
+	var q queryBuilder
+	priceHistoryConditions(&q, productID, filter)
+
+	var buckets []priceBucket
+	// prices are compared in base currency, prices in currencies without exchange rate are skipped
+	if err := p.ext.SelectContext(ctx, &buckets, `
+		SELECT date_trunc('day', changed_at AT TIME ZONE 'UTC') AS date,
+			round(min(base_price), 2) AS min, round(avg(base_price), 2) AS avg, round(max(base_price), 2) AS max
+		FROM (
+			SELECT h.changed_at, h.price / r.rate AS base_price
+			FROM position_price_history h
+			JOIN exchange_rate r ON r.currency = h.currency
+			WHERE `+q.where()+`
+		) prices
+		GROUP BY 1
+		ORDER BY 1
+	`, q.args...); err != nil {
+		return nil, fmt.Errorf("failed to get daily prices: %w", err)
+	}
+
+	data := make([]model.PriceBucket, len(buckets))
+	for i, b := range buckets {
+		data[i] = b.toModel()
+	}
+
+	return data, nil
+}
+
+func (p pg) GetPriceSummaries(ctx context.Context, productIDs []int64) ([]model.PriceSummary, error) {
+	var summaries []priceSummary
+	// prices are compared in base currency
+	if err := p.ext.SelectContext(ctx, &summaries, `
+		SELECT product_id,
+			count(*) AS offer_count,
+			min(base_price) AS min_price,
+			max(base_price) AS max_price,
+			percentile_cont(0.5) WITHIN GROUP (ORDER BY base_price)::numeric AS median_price,
+			(array_agg(store_id ORDER BY base_price, store_id))[1] AS cheapest_store_id
+		FROM (
+			SELECT pos.product_id, pos.store_id, pos.price / r.rate AS base_price
+			FROM position pos
+			JOIN exchange_rate r ON r.currency = pos.currency
+			WHERE pos.product_id = ANY($1) AND pos.availability <> $2
+		) offers
+		GROUP BY product_id
+		ORDER BY product_id
+	`, pq.Array(productIDs), model.AvailabilityDiscontinued); err != nil {
+		return nil, fmt.Errorf("failed to get price summaries: %w", err)
+	}
+
+	data := make([]model.PriceSummary, len(summaries))
+	for i, s := range summaries {
+		data[i] = s.toModel()
+	}
+
+	return data, nil
+}
+
+func priceHistoryConditions(q *queryBuilder, productID int64, f model.PriceHistoryFilter) {
+	q.and("product_id = " + q.arg(productID))
+
+	if f.StoreID != 0 {
+		q.and("store_id = " + q.arg(f.StoreID))
+	}
+
+	if !f.From.IsZero() {
+		q.and("changed_at >= " + q.arg(f.From))
+	}
+
+	if !f.To.IsZero() {
+		q.and("changed_at < " + q.arg(f.To))
+	}
+}
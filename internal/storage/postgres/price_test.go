@@ -0,0 +1,161 @@
+//+build integration
+
+package postgres
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/vliubezny/gstore/internal/model"
+)
+
+func (s *postgresTestSuite) TestPg_UpsertPosition_PriceHistory() {
+	_, err := s.db.Exec(`
+		INSERT INTO product (category_id, name, description) VALUES (1, 'iPhone 11', 'Old iphone');
+		INSERT INTO variant (product_id, sku) VALUES (1, 'IP11');
+		INSERT INTO store (name) VALUES ('iStore');
+		INSERT INTO store_user (email, password_hash) VALUES ('admin@test.com', '123');
+	`)
+	s.Require().NoError(err)
+
+	p := model.Position{VariantID: 1, StoreID: 1, Price: decimal.NewFromInt(100), Currency: "USD", Availability: model.AvailabilityInStock}
+
+	_, err = s.s.UpsertPosition(s.ctx, p, 1)
+	s.Require().NoError(err)
+
+	p.Quantity = 10
+	_, err = s.s.UpsertPosition(s.ctx, p, 1)
+	s.Require().NoError(err)
+
+	p.Price = decimal.NewFromInt(90)
+	_, err = s.s.UpsertPosition(s.ctx, p, 0)
+	s.Require().NoError(err)
+
+	changes, err := s.s.GetPriceHistory(s.ctx, 1, model.PriceHistoryFilter{})
+	s.Require().NoError(err)
+	s.Require().Len(changes, 2)
+
+	s.Equal(decimal.NewFromInt(100), changes[0].Price)
+	s.Equal(int64(1), changes[0].UserID)
+	s.Equal(decimal.NewFromInt(90), changes[1].Price)
+	s.Equal(int64(0), changes[1].UserID)
+	s.False(changes[1].ChangedAt.Before(changes[0].ChangedAt))
+}
+
+func (s *postgresTestSuite) TestPg_GetPriceHistory() {
+	_, err := s.db.Exec(`
+		INSERT INTO product (category_id, name, description) VALUES (1, 'iPhone 11', 'Old iphone');
+		INSERT INTO variant (product_id, sku) VALUES (1, 'IP11');
+		INSERT INTO store (name) VALUES ('iStore'), ('Amazon');
+		INSERT INTO position_price_history (product_id, variant_id, store_id, price, changed_at) VALUES
+			(1, 1, 1, 100, '2021-01-01T10:00:00Z'),
+			(1, 1, 2, 110, '2021-01-01T12:00:00Z'),
+			(1, 1, 1, 90, '2021-01-02T10:00:00Z'),
+			(1, 1, 1, 80, '2021-01-03T10:00:00Z');
+	`)
+	s.Require().NoError(err)
+
+	changes, err := s.s.GetPriceHistory(s.ctx, 1, model.PriceHistoryFilter{
+		StoreID: 1,
+		From:    time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+		To:      time.Date(2021, 1, 3, 0, 0, 0, 0, time.UTC),
+	})
+	s.Require().NoError(err)
+
+	s.Equal([]model.PriceChange{
+		{ProductID: 1, VariantID: 1, StoreID: 1, Price: decimal.NewFromInt(100), Currency: "USD", ChangedAt: time.Date(2021, 1, 1, 10, 0, 0, 0, time.UTC)},
+		{ProductID: 1, VariantID: 1, StoreID: 1, Price: decimal.NewFromInt(90), Currency: "USD", ChangedAt: time.Date(2021, 1, 2, 10, 0, 0, 0, time.UTC)},
+	}, changes)
+
+	changes, err = s.s.GetPriceHistory(s.ctx, 2, model.PriceHistoryFilter{})
+	s.Require().NoError(err)
+	s.Empty(changes)
+}
+
+func (s *postgresTestSuite) TestPg_GetDailyPrices() {
+	_, err := s.db.Exec(`
+		INSERT INTO product (category_id, name, description) VALUES (1, 'iPhone 11', 'Old iphone');
+		INSERT INTO variant (product_id, sku) VALUES (1, 'IP11');
+		INSERT INTO store (name) VALUES ('iStore'), ('Amazon');
+		INSERT INTO position_price_history (product_id, variant_id, store_id, price, changed_at) VALUES
+			(1, 1, 1, 100, '2021-01-01T10:00:00Z'),
+			(1, 1, 2, 110, '2021-01-01T12:00:00Z'),
+			(1, 1, 1, 90, '2021-01-02T10:00:00Z'),
+			(1, 1, 1, 80, '2021-01-03T10:00:00Z');
+	`)
+	s.Require().NoError(err)
+
+	buckets, err := s.s.GetDailyPrices(s.ctx, 1, model.PriceHistoryFilter{
+		From: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+		To:   time.Date(2021, 1, 3, 0, 0, 0, 0, time.UTC),
+	})
+	s.Require().NoError(err)
+
+	s.Equal([]model.PriceBucket{
+		{Date: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC), Min: decimal.RequireFromString("100.00"), Avg: decimal.RequireFromString("105.00"), Max: decimal.RequireFromString("110.00")},
+		{Date: time.Date(2021, 1, 2, 0, 0, 0, 0, time.UTC), Min: decimal.RequireFromString("90.00"), Avg: decimal.RequireFromString("90.00"), Max: decimal.RequireFromString("90.00")},
+	}, buckets)
+}
+
+func (s *postgresTestSuite) TestPg_GetDailyPrices_Currencies() {
+	_, err := s.db.Exec(`
+		INSERT INTO exchange_rate (currency, rate) VALUES ('EUR', 0.8);
+		INSERT INTO product (category_id, name, description) VALUES (1, 'iPhone 11', 'Old iphone');
+		INSERT INTO variant (product_id, sku) VALUES (1, 'IP11');
+		INSERT INTO store (name) VALUES ('iStore'), ('Otto');
+		INSERT INTO position_price_history (product_id, variant_id, store_id, price, currency, changed_at) VALUES
+			(1, 1, 1, 100, 'USD', '2021-01-01T10:00:00Z'),
+			(1, 1, 2, 96, 'EUR', '2021-01-01T12:00:00Z');
+	`)
+	s.Require().NoError(err)
+
+	buckets, err := s.s.GetDailyPrices(s.ctx, 1, model.PriceHistoryFilter{})
+	s.Require().NoError(err)
+
+	// 96 EUR is 120 USD
+	s.Equal([]model.PriceBucket{
+		{Date: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC), Min: decimal.RequireFromString("100.00"), Avg: decimal.RequireFromString("110.00"), Max: decimal.RequireFromString("120.00")},
+	}, buckets)
+}
+
+func (s *postgresTestSuite) TestPg_GetPriceSummaries() {
+	_, err := s.db.Exec(`
+		INSERT INTO exchange_rate (currency, rate) VALUES ('EUR', 0.8);
+		INSERT INTO product (category_id, name, description) VALUES
+			(1, 'iPhone 11', 'Old iphone'),
+			(1, 'iPhone 12', 'New iphone'),
+			(1, 'iPhone 13', 'Future iphone');
+		INSERT INTO variant (product_id, sku) VALUES (1, 'IP11'), (2, 'IP12'), (3, 'IP13');
+		INSERT INTO store (name) VALUES ('iStore'), ('Amazon'), ('eBay'), ('Walmart'), ('Otto');
+		INSERT INTO position (product_id, variant_id, store_id, price, currency, availability) VALUES
+			(1, 1, 1, 120, 'USD', 'in_stock'),
+			(1, 1, 2, 100, 'USD', 'backorder'),
+			(1, 1, 3, 100, 'USD', 'in_stock'),
+			(1, 1, 4, 50, 'USD', 'discontinued'),
+			(1, 1, 5, 76, 'EUR', 'in_stock'),
+			(2, 2, 1, 200, 'USD', 'in_stock'),
+			(2, 2, 2, 300, 'USD', 'in_stock'),
+			(3, 3, 1, 10, 'USD', 'discontinued');
+	`)
+	s.Require().NoError(err)
+
+	summaries, err := s.s.GetPriceSummaries(s.ctx, []int64{3, 2, 1})
+	s.Require().NoError(err)
+	s.Require().Len(summaries, 2)
+
+	// prices are compared as numbers since division changes their scale
+	s.Equal(int64(1), summaries[0].ProductID)
+	s.Equal(int64(4), summaries[0].OfferCount)
+	s.Equal(model.BaseCurrency, summaries[0].Currency)
+	s.Equal("95", summaries[0].MinPrice.String())
+	s.Equal("120", summaries[0].MaxPrice.String())
+	s.Equal("100", summaries[0].MedianPrice.String())
+	s.Equal(int64(5), summaries[0].CheapestStoreID)
+
+	s.Equal(int64(2), summaries[1].ProductID)
+	s.Equal(int64(2), summaries[1].OfferCount)
+	s.Equal("200", summaries[1].MinPrice.String())
+	s.Equal("300", summaries[1].MaxPrice.String())
+	s.Equal("250", summaries[1].MedianPrice.String())
+	s.Equal(int64(1), summaries[1].CheapestStoreID)
+}
@@ -3,7 +3,9 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"strings"
 
 	"github.com/lib/pq"
 	"github.com/vliubezny/gstore/internal/model"
@@ -12,11 +14,86 @@ import (
 
 const categoryIDFKConstraint = "product_category_id_fkey"
 
-func (p pg) GetProducts(ctx context.Context, categoryID int64) ([]model.Product, error) {
-	var products []product
+func (p pg) GetProducts(ctx context.Context, categoryID int64, filter model.ProductFilter, page model.Page) ([]model.Product, string, error) {
+	sortKey := productSortKey(filter)
 
-	if err := p.ext.SelectContext(ctx, &products, "SELECT id, category_id, name, description FROM product WHERE category_id=$1", categoryID); err != nil {
-		return nil, fmt.Errorf("failed to get products: %w", err)
+	after, err := decodeCursor(page.Cursor)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if page.Cursor != "" && after.Sort != sortKey {
+		return nil, "", fmt.Errorf("%w: cursor is sorted by %q", storage.ErrInvalidCursor, after.Sort)
+	}
+
+	var q queryBuilder
+	if filter.IncludeSubcategories {
+		q.and("p.category_id IN (" + subtreeQuery(q.arg(categoryID)) + ")")
+	} else {
+		q.and("p.category_id = " + q.arg(categoryID))
+	}
+
+	if filter.Name != "" {
+		q.and("p.name ILIKE " + q.arg(containsPattern(filter.Name)))
+	}
+
+	if filter.MinPrice.Valid {
+		q.and("bp.price >= " + q.arg(filter.MinPrice.Decimal))
+	}
+
+	if filter.MaxPrice.Valid {
+		q.and("bp.price <= " + q.arg(filter.MaxPrice.Decimal))
+	}
+
+	if filter.StoreID != 0 {
+		q.and("EXISTS (SELECT 1 FROM position WHERE product_id = p.id AND store_id = " + q.arg(filter.StoreID) + ")")
+	}
+
+	for _, a := range filter.Attributes {
+		cond, err := attributeCondition(&q, a)
+		if err != nil {
+			return nil, "", err
+		}
+		q.and(cond)
+	}
+
+	if page.Cursor != "" {
+		q.and(productKeyset(&q, filter, after))
+	}
+
+	query := `
+		SELECT p.id, p.category_id, p.name, p.description, p.attributes, bp.price AS best_price
+		FROM product p
+		LEFT JOIN LATERAL (
+			SELECT min(pos.price / r.rate) AS price FROM position pos
+			JOIN exchange_rate r ON r.currency = pos.currency
+			WHERE pos.product_id = p.id
+		) bp ON TRUE
+		WHERE ` + q.where() + `
+		ORDER BY ` + productOrder(filter) + `
+		LIMIT ` + q.arg(page.Limit+1)
+
+	var products []productRow
+
+	if err := p.ext.SelectContext(ctx, &products, query, q.args...); err != nil {
+		return nil, "", fmt.Errorf("failed to get products: %w", err)
+	}
+
+	var next string
+	if len(products) > page.Limit {
+		products = products[:page.Limit]
+		last := products[page.Limit-1]
+
+		c := cursor{ID: last.ID, Sort: sortKey}
+		switch filter.Sort {
+		case model.SortByName:
+			c.Name = last.Name
+		case model.SortByPrice:
+			if last.BestPrice.Valid {
+				c.Price = &last.BestPrice.Decimal
+			}
+		}
+		next = encodeCursor(c)
 	}
 
 	data := make([]model.Product, len(products))
@@ -24,12 +101,95 @@ func (p pg) GetProducts(ctx context.Context, categoryID int64) ([]model.Product,
 		data[i] = d.toModel()
 	}
 
-	return data, nil
+	return data, next, nil
+}
+
+// productSortKey returns textual representation of product ordering.
+func productSortKey(f model.ProductFilter) string {
+	key := string(f.Sort)
+	if key == "" {
+		key = string(model.SortByID)
+	}
+	if f.Desc {
+		key = "-" + key
+	}
+	return key
+}
+
+// productOrder returns ORDER BY clause, product ID is a tiebreaker for non-unique keys.
+func productOrder(f model.ProductFilter) string {
+	dir := "ASC"
+	if f.Desc {
+		dir = "DESC"
+	}
+
+	switch f.Sort {
+	case model.SortByName:
+		return "p.name " + dir + ", p.id"
+	case model.SortByPrice:
+		return "bp.price " + dir + " NULLS LAST, p.id"
+	default:
+		return "p.id " + dir
+	}
+}
+
+// productKeyset returns condition that selects products following the cursor.
+func productKeyset(q *queryBuilder, f model.ProductFilter, after cursor) string {
+	cmp := ">"
+	if f.Desc {
+		cmp = "<"
+	}
+
+	switch f.Sort {
+	case model.SortByName:
+		name := q.arg(after.Name)
+		return fmt.Sprintf("(p.name %s %s OR (p.name = %s AND p.id > %s))", cmp, name, name, q.arg(after.ID))
+	case model.SortByPrice:
+		if after.Price == nil { // products without positions go last
+			return fmt.Sprintf("(bp.price IS NULL AND p.id > %s)", q.arg(after.ID))
+		}
+		price := q.arg(*after.Price)
+		return fmt.Sprintf("(bp.price %s %s OR (bp.price = %s AND p.id > %s) OR bp.price IS NULL)", cmp, price, price, q.arg(after.ID))
+	default:
+		return fmt.Sprintf("p.id %s %s", cmp, q.arg(after.ID))
+	}
+}
+
+// attributeCondition returns condition that selects products with matching attribute.
+func attributeCondition(q *queryBuilder, f model.AttributeFilter) (string, error) {
+	var conds []string
+
+	if f.Value != nil {
+		v, err := json.Marshal(map[string]interface{}{f.Name: f.Value})
+		if err != nil {
+			return "", fmt.Errorf("failed to encode attribute filter: %w", err)
+		}
+		conds = append(conds, "p.attributes @> "+q.arg(string(v))+"::jsonb")
+	}
+
+	if f.Min.Valid || f.Max.Valid {
+		// attributes of other types are skipped instead of failing numeric cast
+		name := q.arg(f.Name) + "::text"
+		num := "(CASE WHEN jsonb_typeof(p.attributes->" + name + ") = 'number' THEN (p.attributes->>" + name + ")::numeric END)"
+
+		if f.Min.Valid {
+			conds = append(conds, num+" >= "+q.arg(f.Min.Decimal))
+		}
+		if f.Max.Valid {
+			conds = append(conds, num+" <= "+q.arg(f.Max.Decimal))
+		}
+	}
+
+	if len(conds) == 0 {
+		return "p.attributes ? " + q.arg(f.Name) + "::text", nil
+	}
+
+	return strings.Join(conds, " AND "), nil
 }
 
 func (p pg) GetProduct(ctx context.Context, productID int64) (model.Product, error) {
 	var prod product
-	err := p.ext.GetContext(ctx, &prod, "SELECT id, category_id, name, description FROM product WHERE id = $1", productID)
+	err := p.ext.GetContext(ctx, &prod, "SELECT id, category_id, name, description, attributes FROM product WHERE id = $1", productID)
 
 	if err == sql.ErrNoRows {
 		return model.Product{}, storage.ErrNotFound
@@ -44,8 +204,8 @@ func (p pg) GetProduct(ctx context.Context, productID int64) (model.Product, err
 
 func (p pg) CreateProduct(ctx context.Context, product model.Product) (model.Product, error) {
 	if err := p.ext.GetContext(ctx, &product.ID, `
-			INSERT INTO product (category_id, name, description) VALUES ($1, $2, $3) RETURNING id
-		`, product.CategoryID, product.Name, product.Description); err != nil {
+			INSERT INTO product (category_id, name, description, attributes) VALUES ($1, $2, $3, $4) RETURNING id
+		`, product.CategoryID, product.Name, product.Description, attributeValues(product.Attributes)); err != nil {
 
 		if err, ok := err.(*pq.Error); ok && err.Constraint == categoryIDFKConstraint {
 			return model.Product{}, storage.ErrUnknownCategory
@@ -60,9 +220,10 @@ func (p pg) UpdateProduct(ctx context.Context, product model.Product) error {
 		UPDATE product SET
 		category_id =$2,
 		name = $3,
-		description = $4
+		description = $4,
+		attributes = $5
 		WHERE id = $1
-	`, product.ID, product.CategoryID, product.Name, product.Description)
+	`, product.ID, product.CategoryID, product.Name, product.Description, attributeValues(product.Attributes))
 
 	if err != nil {
 		if err, ok := err.(*pq.Error); ok && err.Constraint == categoryIDFKConstraint {
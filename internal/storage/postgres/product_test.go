@@ -5,6 +5,7 @@ package postgres
 import (
 	"errors"
 
+	"github.com/shopspring/decimal"
 	"github.com/vliubezny/gstore/internal/model"
 	"github.com/vliubezny/gstore/internal/storage"
 )
@@ -17,13 +18,127 @@ func (s *postgresTestSuite) TestPg_GetProducts() {
 	($1, 'iPhone 12', 'New iphone');`, categoryID)
 	s.Require().NoError(err)
 
-	products, err := s.s.GetProducts(s.ctx, categoryID)
+	products, next, err := s.s.GetProducts(s.ctx, categoryID, model.ProductFilter{}, model.Page{Limit: 10})
 	s.Require().NoError(err)
 
 	s.Equal([]model.Product{
 		{ID: 1, CategoryID: categoryID, Name: "iPhone 11", Description: "Old iphone"},
 		{ID: 2, CategoryID: categoryID, Name: "iPhone 12", Description: "New iphone"},
 	}, products)
+	s.Empty(next)
+
+	products, next, err = s.s.GetProducts(s.ctx, categoryID, model.ProductFilter{}, model.Page{Limit: 1})
+	s.Require().NoError(err)
+
+	s.Equal([]model.Product{
+		{ID: 1, CategoryID: categoryID, Name: "iPhone 11", Description: "Old iphone"},
+	}, products)
+	s.Equal(encodeCursor(cursor{ID: 1, Sort: "id"}), next)
+}
+
+func (s *postgresTestSuite) TestPg_GetProducts_Filter() {
+	_, err := s.db.Exec(`
+		INSERT INTO product (category_id, name, description) VALUES
+			(1, 'iPhone 11', 'Old iphone'),
+			(1, 'iPhone 12', 'New iphone'),
+			(1, 'Pixel 5', 'Google phone'),
+			(1, 'Galaxy 100%', 'Samsung phone');
+		INSERT INTO variant (product_id, sku) VALUES (1, 'IP11'), (2, 'IP12'), (3, 'PX5');
+		INSERT INTO store (name) VALUES ('iStore'), ('Amazon');
+		INSERT INTO position (product_id, variant_id, store_id, price) VALUES
+			(1, 1, 1, 500),
+			(1, 1, 2, 450),
+			(2, 2, 1, 900),
+			(3, 3, 2, 600);
+	`)
+	s.Require().NoError(err)
+
+	ids := func(filter model.ProductFilter) []int64 {
+		var res []int64
+		page := model.Page{Limit: 1}
+		for {
+			products, next, err := s.s.GetProducts(s.ctx, 1, filter, page)
+			s.Require().NoError(err)
+
+			for _, p := range products {
+				res = append(res, p.ID)
+			}
+
+			if next == "" {
+				return res
+			}
+			page.Cursor = next
+		}
+	}
+
+	s.Equal([]int64{1, 2}, ids(model.ProductFilter{Name: "iphone"}))
+	s.Equal([]int64{4}, ids(model.ProductFilter{Name: "100%"}))
+	s.Equal([]int64{1, 3}, ids(model.ProductFilter{
+		MinPrice: decimal.NullDecimal{Decimal: decimal.NewFromInt(450), Valid: true},
+		MaxPrice: decimal.NullDecimal{Decimal: decimal.NewFromInt(600), Valid: true},
+	}))
+	s.Equal([]int64{1, 2}, ids(model.ProductFilter{StoreID: 1}))
+	s.Equal([]int64{4, 1, 2, 3}, ids(model.ProductFilter{Sort: model.SortByName}))
+	s.Equal([]int64{3, 2, 1, 4}, ids(model.ProductFilter{Sort: model.SortByName, Desc: true}))
+	s.Equal([]int64{1, 3, 2, 4}, ids(model.ProductFilter{Sort: model.SortByPrice}))
+	s.Equal([]int64{2, 3, 1, 4}, ids(model.ProductFilter{Sort: model.SortByPrice, Desc: true}))
+	s.Equal([]int64{4, 3, 2, 1}, ids(model.ProductFilter{Sort: model.SortByID, Desc: true}))
+
+	_, err = s.db.Exec(`
+		UPDATE category SET parent_id = 1 WHERE id = 2;
+		INSERT INTO product (category_id, name, description) VALUES (2, 'MacBook', 'Laptop');
+	`)
+	s.Require().NoError(err)
+
+	s.Equal([]int64{1, 2, 3, 4}, ids(model.ProductFilter{}))
+	s.Equal([]int64{1, 2, 3, 4, 5}, ids(model.ProductFilter{IncludeSubcategories: true}))
+
+	_, next, err := s.s.GetProducts(s.ctx, 1, model.ProductFilter{}, model.Page{Limit: 1})
+	s.Require().NoError(err)
+
+	_, _, err = s.s.GetProducts(s.ctx, 1, model.ProductFilter{Sort: model.SortByName}, model.Page{Limit: 1, Cursor: next})
+	s.True(errors.Is(err, storage.ErrInvalidCursor))
+}
+
+func (s *postgresTestSuite) TestPg_GetProducts_AttributeFilter() {
+	_, err := s.db.Exec(`
+		INSERT INTO product (category_id, name, description, attributes) VALUES
+			(1, 'Laptop 1', 'Laptop', '{"ram": 8, "color": "black", "touch": false}'),
+			(1, 'Laptop 2', 'Laptop', '{"ram": 16, "color": "silver", "touch": true}'),
+			(1, 'Laptop 3', 'Laptop', '{"ram": 32.0, "color": "black"}'),
+			(1, 'Laptop 4', 'Laptop', '{"ram": "unknown"}');
+	`)
+	s.Require().NoError(err)
+
+	ids := func(filters ...model.AttributeFilter) []int64 {
+		products, _, err := s.s.GetProducts(s.ctx, 1, model.ProductFilter{Attributes: filters}, model.Page{Limit: 10})
+		s.Require().NoError(err)
+
+		var res []int64
+		for _, p := range products {
+			res = append(res, p.ID)
+		}
+		return res
+	}
+
+	s.Equal([]int64{1, 3}, ids(model.AttributeFilter{Name: "color", Value: "black"}))
+	s.Equal([]int64{2}, ids(model.AttributeFilter{Name: "touch", Value: true}))
+	s.Equal([]int64{3}, ids(model.AttributeFilter{Name: "ram", Value: 32.0}))
+	s.Equal([]int64{2, 3}, ids(model.AttributeFilter{Name: "ram", Min: decimal.NullDecimal{Decimal: decimal.NewFromInt(16), Valid: true}}))
+	s.Equal([]int64{1, 2}, ids(model.AttributeFilter{
+		Name: "ram",
+		Min:  decimal.NullDecimal{Decimal: decimal.NewFromInt(8), Valid: true},
+		Max:  decimal.NullDecimal{Decimal: decimal.NewFromInt(16), Valid: true},
+	}))
+	s.Equal([]int64{1}, ids(
+		model.AttributeFilter{Name: "color", Value: "black"},
+		model.AttributeFilter{Name: "ram", Max: decimal.NullDecimal{Decimal: decimal.NewFromInt(16), Valid: true}},
+	))
+
+	product, err := s.s.GetProduct(s.ctx, 2)
+	s.Require().NoError(err)
+
+	s.Equal(map[string]interface{}{"ram": 16.0, "color": "silver", "touch": true}, product.Attributes)
 }
 
 func (s *postgresTestSuite) TestPg_GetProduct() {
@@ -65,6 +180,23 @@ func (s *postgresTestSuite) TestPg_CreateProduct() {
 	s.True(errors.Is(storage.ErrUnknownCategory, err))
 }
 
+func (s *postgresTestSuite) TestPg_CreateProduct_Attributes() {
+	prod := model.Product{
+		CategoryID:  1,
+		Name:        "Laptop",
+		Description: "Laptop",
+		Attributes:  map[string]interface{}{"ram": 16.0, "color": "black", "touch": true},
+	}
+
+	prod, err := s.s.CreateProduct(s.ctx, prod)
+	s.Require().NoError(err)
+
+	res, err := s.s.GetProduct(s.ctx, prod.ID)
+	s.Require().NoError(err)
+
+	s.Equal(prod, res)
+}
+
 func (s *postgresTestSuite) TestPg_UpdateProduct() {
 	_, err := s.db.Exec(`INSERT INTO product (category_id, name, description) VALUES (1, 'iPhone 11', 'Old iphone');`)
 	s.Require().NoError(err)
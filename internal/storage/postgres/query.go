@@ -0,0 +1,38 @@
+package postgres
+
+import (
+	"strconv"
+	"strings"
+)
+
+// queryBuilder collects conditions and positional arguments of a parameterized query.
+type queryBuilder struct {
+	conds []string
+	args  []interface{}
+}
+
+// arg registers argument and returns its placeholder.
+func (q *queryBuilder) arg(v interface{}) string {
+	q.args = append(q.args, v)
+	return "$" + strconv.Itoa(len(q.args))
+}
+
+// and adds condition to WHERE clause.
+func (q *queryBuilder) and(cond string) {
+	q.conds = append(q.conds, cond)
+}
+
+// where returns WHERE clause conditions joined with AND.
+func (q *queryBuilder) where() string {
+	if len(q.conds) == 0 {
+		return "TRUE"
+	}
+	return strings.Join(q.conds, " AND ")
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// containsPattern returns LIKE pattern that matches strings containing s.
+func containsPattern(s string) string {
+	return "%" + likeEscaper.Replace(s) + "%"
+}
@@ -0,0 +1,92 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/vliubezny/gstore/internal/model"
+	"github.com/vliubezny/gstore/internal/storage"
+)
+
+const (
+	searchConfig = "english"
+	searchSort   = "rank"
+)
+
+func (p pg) SearchProducts(ctx context.Context, search model.ProductSearch, page model.Page) ([]model.ProductSearchResult, string, error) {
+	after, err := decodeCursor(page.Cursor)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if page.Cursor != "" && (after.Sort != searchSort || after.Rank == nil) {
+		return nil, "", fmt.Errorf("%w: cursor is sorted by %q", storage.ErrInvalidCursor, after.Sort)
+	}
+
+	tsquery := prefixTSQuery(search.Query)
+	if tsquery == "" {
+		return []model.ProductSearchResult{}, "", nil
+	}
+
+	var q queryBuilder
+	tsq := q.arg(tsquery)
+	q.and("p.search_vector @@ ts.query")
+
+	if search.CategoryID != 0 {
+		q.and("p.category_id = " + q.arg(search.CategoryID))
+	}
+
+	keyset := "TRUE"
+	if page.Cursor != "" {
+		rank := q.arg(*after.Rank)
+		keyset = fmt.Sprintf("(r.rank < %s OR (r.rank = %s AND r.id > %s))", rank, rank, q.arg(after.ID))
+	}
+
+	var results []productSearchResult
+
+	if err := p.ext.SelectContext(ctx, &results, `
+		SELECT r.id, r.category_id, r.name, r.description, r.attributes, r.rank,
+			ts_headline('`+searchConfig+`', r.name, r.query, 'HighlightAll=TRUE') AS name_highlight,
+			ts_headline('`+searchConfig+`', r.description, r.query) AS description_highlight
+		FROM (
+			SELECT p.id, p.category_id, p.name, p.description, p.attributes, ts.query,
+				ts_rank(p.search_vector, ts.query)::float8 AS rank
+			FROM product p, to_tsquery('`+searchConfig+`', `+tsq+`) ts(query)
+			WHERE `+q.where()+`
+		) r
+		WHERE `+keyset+`
+		ORDER BY r.rank DESC, r.id
+		LIMIT `+q.arg(page.Limit+1), q.args...); err != nil {
+		return nil, "", fmt.Errorf("failed to search products: %w", err)
+	}
+
+	var next string
+	if len(results) > page.Limit {
+		results = results[:page.Limit]
+		last := results[page.Limit-1]
+		next = encodeCursor(cursor{ID: last.ID, Sort: searchSort, Rank: &last.Rank})
+	}
+
+	data := make([]model.ProductSearchResult, len(results))
+	for i, r := range results {
+		data[i] = r.toModel()
+	}
+
+	return data, next, nil
+}
+
+// prefixTSQuery converts text into tsquery that matches all words and treats the last word as a prefix.
+// Any tsquery operators in the text are ignored.
+func prefixTSQuery(text string) string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	if len(words) == 0 {
+		return ""
+	}
+
+	return strings.Join(words, " & ") + ":*"
+}
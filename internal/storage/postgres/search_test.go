@@ -0,0 +1,62 @@
+//+build integration
+
+package postgres
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vliubezny/gstore/internal/model"
+	"github.com/vliubezny/gstore/internal/storage"
+)
+
+func (s *postgresTestSuite) TestPg_SearchProducts() {
+	_, err := s.db.Exec(`
+		INSERT INTO product (category_id, name, description) VALUES
+			(1, 'iPhone 12', 'Apple smartphone'),
+			(1, 'Phone case', 'Case for iPhone 12'),
+			(2, 'MacBook', 'Apple laptop'),
+			(1, 'Pixel 5', 'Google smartphone');
+	`)
+	s.Require().NoError(err)
+
+	results, next, err := s.s.SearchProducts(s.ctx, model.ProductSearch{Query: "iphone"}, model.Page{Limit: 1})
+	s.Require().NoError(err)
+
+	s.Require().Len(results, 1)
+	s.Equal(model.Product{ID: 1, CategoryID: 1, Name: "iPhone 12", Description: "Apple smartphone"}, results[0].Product)
+	s.Equal("<b>iPhone</b> 12", results[0].NameHighlight)
+	s.True(results[0].Rank > 0)
+	s.Require().NotEmpty(next)
+
+	results, next, err = s.s.SearchProducts(s.ctx, model.ProductSearch{Query: "iphone"}, model.Page{Limit: 1, Cursor: next})
+	s.Require().NoError(err)
+
+	s.Require().Len(results, 1)
+	s.Equal(int64(2), results[0].Product.ID)
+	s.Equal("Case for <b>iPhone</b> 12", results[0].DescriptionHighlight)
+	s.Empty(next)
+
+	results, _, err = s.s.SearchProducts(s.ctx, model.ProductSearch{Query: "smartph"}, model.Page{Limit: 10})
+	s.Require().NoError(err)
+	s.Len(results, 2, "prefix match")
+
+	results, _, err = s.s.SearchProducts(s.ctx, model.ProductSearch{Query: "apple", CategoryID: 2}, model.Page{Limit: 10})
+	s.Require().NoError(err)
+	s.Require().Len(results, 1)
+	s.Equal(int64(3), results[0].Product.ID)
+
+	results, _, err = s.s.SearchProducts(s.ctx, model.ProductSearch{Query: "!&|"}, model.Page{Limit: 10})
+	s.Require().NoError(err)
+	s.Empty(results)
+
+	_, _, err = s.s.SearchProducts(s.ctx, model.ProductSearch{Query: "iphone"}, model.Page{Limit: 1, Cursor: encodeCursor(cursor{ID: 1})})
+	s.True(errors.Is(err, storage.ErrInvalidCursor))
+}
+
+func Test_prefixTSQuery(t *testing.T) {
+	assert.Equal(t, "iphone & 12:*", prefixTSQuery("iPhone 12"))
+	assert.Equal(t, "smart & ph:*", prefixTSQuery(" smart: & !ph| "))
+	assert.Equal(t, "", prefixTSQuery("!&|"))
+}
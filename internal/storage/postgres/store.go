@@ -9,10 +9,23 @@ import (
 	"github.com/vliubezny/gstore/internal/storage"
 )
 
-func (p pg) GetStores(ctx context.Context) ([]model.Store, error) {
+func (p pg) GetStores(ctx context.Context, page model.Page) ([]model.Store, string, error) {
+	after, err := decodeCursor(page.Cursor)
+	if err != nil {
+		return nil, "", err
+	}
+
 	var stores []store
-	if err := p.ext.SelectContext(ctx, &stores, "SELECT id, name FROM store"); err != nil {
-		return nil, err
+	if err := p.ext.SelectContext(ctx, &stores, `
+		SELECT id, name FROM store WHERE id > $1 ORDER BY id LIMIT $2
+	`, after.ID, page.Limit+1); err != nil {
+		return nil, "", err
+	}
+
+	var next string
+	if len(stores) > page.Limit {
+		stores = stores[:page.Limit]
+		next = encodeCursor(cursor{ID: stores[page.Limit-1].ID})
 	}
 
 	data := make([]model.Store, len(stores))
@@ -20,7 +33,7 @@ func (p pg) GetStores(ctx context.Context) ([]model.Store, error) {
 		data[i] = c.toModel()
 	}
 
-	return data, nil
+	return data, next, nil
 }
 
 func (p pg) GetStore(ctx context.Context, storeID int64) (model.Store, error) {
@@ -15,13 +15,21 @@ func (s *postgresTestSuite) TestPg_GetStores() {
 	('Amazon');`)
 	s.Require().NoError(err)
 
-	stores, err := s.s.GetStores(s.ctx)
+	stores, next, err := s.s.GetStores(s.ctx, model.Page{Limit: 1})
 	s.Require().NoError(err)
 
 	s.Equal([]model.Store{
 		{ID: 1, Name: "iStore"},
+	}, stores)
+	s.Require().NotEmpty(next)
+
+	stores, next, err = s.s.GetStores(s.ctx, model.Page{Limit: 1, Cursor: next})
+	s.Require().NoError(err)
+
+	s.Equal([]model.Store{
 		{ID: 2, Name: "Amazon"},
 	}, stores)
+	s.Empty(next)
 }
 
 func (s *postgresTestSuite) TestPg_GetStore() {
@@ -11,12 +11,25 @@ import (
 	"github.com/vliubezny/gstore/internal/storage"
 )
 
-const emailUniqueConstraint = "store_user_email_key"
+const (
+	emailUniqueConstraint            = "store_user_email_key"
+	userRoleFKConstraint             = "user_role_role_fkey"
+	revokedSessionUserIDFKConstraint = "revoked_session_user_id_fkey"
+)
+
+// userColumns selects user along with roles and permissions granted by them.
+const userColumns = `
+	u.id, u.email, u.password_hash, u.email_verified, COALESCE(u.mfa_secret, '') AS mfa_secret, u.mfa_enabled,
+	ARRAY(SELECT role FROM user_role WHERE user_id = u.id ORDER BY role) AS roles,
+	ARRAY(
+		SELECT DISTINCT rp.permission FROM user_role ur JOIN role_permission rp ON rp.role = ur.role
+		WHERE ur.user_id = u.id ORDER BY rp.permission
+	) AS permissions`
 
 func (p pg) CreateUser(ctx context.Context, user model.User) (model.User, error) {
 	if err := p.ext.GetContext(ctx, &user.ID, `
-			INSERT INTO store_user (email, password_hash, is_admin) VALUES ($1, $2, $3) RETURNING id
-		`, user.Email, user.PasswordHash, user.IsAdmin); err != nil {
+			INSERT INTO store_user (email, password_hash) VALUES ($1, $2) RETURNING id
+		`, user.Email, user.PasswordHash); err != nil {
 
 		if err, ok := err.(*pq.Error); ok && err.Constraint == emailUniqueConstraint {
 			return model.User{}, storage.ErrEmailIsTaken
@@ -28,7 +41,7 @@ func (p pg) CreateUser(ctx context.Context, user model.User) (model.User, error)
 
 func (p pg) GetUserByEmail(ctx context.Context, email string) (model.User, error) {
 	var u user
-	err := p.ext.GetContext(ctx, &u, "SELECT id, email, password_hash, is_admin FROM store_user WHERE email = $1", email)
+	err := p.ext.GetContext(ctx, &u, "SELECT "+userColumns+" FROM store_user u WHERE u.email = $1", email)
 
 	if err == sql.ErrNoRows {
 		return model.User{}, storage.ErrNotFound
@@ -43,7 +56,7 @@ func (p pg) GetUserByEmail(ctx context.Context, email string) (model.User, error
 
 func (p pg) GetUserByID(ctx context.Context, id int64) (model.User, error) {
 	var u user
-	err := p.ext.GetContext(ctx, &u, "SELECT id, email, password_hash, is_admin FROM store_user WHERE id = $1", id)
+	err := p.ext.GetContext(ctx, &u, "SELECT "+userColumns+" FROM store_user u WHERE u.id = $1", id)
 
 	if err == sql.ErrNoRows {
 		return model.User{}, storage.ErrNotFound
@@ -56,21 +69,77 @@ func (p pg) GetUserByID(ctx context.Context, id int64) (model.User, error) {
 	return u.toModel(), nil
 }
 
-func (p pg) SaveToken(ctx context.Context, tokenID string, userID int64, expiresAt time.Time) error {
+func (p pg) SaveToken(ctx context.Context, token model.RefreshToken) error {
 	if _, err := p.ext.ExecContext(ctx, `
-			INSERT INTO token (id, user_id, expires_at) VALUES ($1, $2, $3)
-		`, tokenID, userID, expiresAt); err != nil {
-
+		INSERT INTO token (id, family_id, user_id, expires_at, ip, user_agent, started_at)
+		SELECT $1, $2, $3, $4, $5, $6, COALESCE(MIN(started_at), now()) FROM token WHERE family_id = $2
+	`, token.ID, token.FamilyID, token.UserID, token.ExpiresAt, token.IP, token.UserAgent); err != nil {
 		return fmt.Errorf("failed to save token: %w", err)
 	}
 	return nil
 }
 
-func (p pg) DeleteToken(ctx context.Context, tokenID string) error {
-	res, err := p.ext.ExecContext(ctx, "DELETE FROM token WHERE id = $1", tokenID)
+func (p pg) UseToken(ctx context.Context, tokenID string) error {
+	var reused bool
+	err := p.ext.GetContext(ctx, &reused, `
+		WITH t AS (
+			SELECT id, used_at FROM token WHERE id = $1 FOR UPDATE
+		), used AS (
+			UPDATE token SET used_at = now() FROM t WHERE token.id = t.id AND t.used_at IS NULL
+		)
+		SELECT used_at IS NOT NULL FROM t
+	`, tokenID)
+
+	if err == sql.ErrNoRows {
+		return storage.ErrNotFound
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to use token: %w", err)
+	}
+
+	if reused {
+		return storage.ErrTokenReused
+	}
+
+	return nil
+}
+
+func (p pg) DeleteTokenFamily(ctx context.Context, userID int64, familyID string) error {
+	res, err := p.ext.ExecContext(ctx, "DELETE FROM token WHERE user_id = $1 AND family_id = $2", userID, familyID)
+	if err != nil {
+		return fmt.Errorf("failed to delete token family: %w", err)
+	}
+
+	if c, _ := res.RowsAffected(); c == 0 {
+		return storage.ErrNotFound
+	}
+
+	return nil
+}
+
+func (p pg) GetSessions(ctx context.Context, userID int64) ([]model.Session, error) {
+	var sessions []session
+	if err := p.ext.SelectContext(ctx, &sessions, `
+		SELECT family_id, user_id, ip, user_agent, started_at, created_at, expires_at FROM token
+		WHERE user_id = $1 AND used_at IS NULL AND expires_at > now()
+		ORDER BY created_at DESC
+	`, userID); err != nil {
+		return nil, fmt.Errorf("failed to get sessions: %w", err)
+	}
+
+	data := make([]model.Session, len(sessions))
+	for i, s := range sessions {
+		data[i] = s.toModel()
+	}
+
+	return data, nil
+}
 
+func (p pg) RevokeUserTokens(ctx context.Context, userID int64) error {
+	res, err := p.ext.ExecContext(ctx, "UPDATE store_user SET tokens_valid_after = now() WHERE id = $1", userID)
 	if err != nil {
-		return fmt.Errorf("failed to delete token: %w", err)
+		return fmt.Errorf("failed to revoke tokens: %w", err)
 	}
 
 	if c, _ := res.RowsAffected(); c == 0 {
@@ -80,13 +149,170 @@ func (p pg) DeleteToken(ctx context.Context, tokenID string) error {
 	return nil
 }
 
-func (p pg) UpdateUserPermissions(ctx context.Context, user model.User) error {
+func (p pg) RevokeSessionTokens(ctx context.Context, userID int64, sessionID string) error {
+	if _, err := p.ext.ExecContext(ctx, `
+		INSERT INTO revoked_session (session_id, user_id) VALUES ($1, $2)
+		ON CONFLICT (session_id) DO UPDATE SET revoked_at = now()
+	`, sessionID, userID); err != nil {
+		if err, ok := err.(*pq.Error); ok && err.Constraint == revokedSessionUserIDFKConstraint {
+			return storage.ErrNotFound
+		}
+		return fmt.Errorf("failed to revoke session tokens: %w", err)
+	}
+
+	return nil
+}
+
+func (p pg) GetTokenRevocations(ctx context.Context, since time.Time) ([]model.TokenRevocation, error) {
+	var revocations []tokenRevocation
+	if err := p.ext.SelectContext(ctx, &revocations, `
+		SELECT id AS user_id, '' AS session_id, tokens_valid_after AS revoked_at FROM store_user
+		WHERE tokens_valid_after > $1
+		UNION ALL
+		SELECT user_id, session_id::text, revoked_at FROM revoked_session
+		WHERE revoked_at > $1
+	`, since); err != nil {
+		return nil, fmt.Errorf("failed to get token revocations: %w", err)
+	}
+
+	data := make([]model.TokenRevocation, len(revocations))
+	for i, r := range revocations {
+		data[i] = r.toModel()
+	}
+
+	return data, nil
+}
+
+func (p pg) DeleteUserTokens(ctx context.Context, userID int64) error {
+	if _, err := p.ext.ExecContext(ctx, "DELETE FROM token WHERE user_id = $1", userID); err != nil {
+		return fmt.Errorf("failed to delete tokens: %w", err)
+	}
+	return nil
+}
+
+func (p pg) UpdateUserPassword(ctx context.Context, userID int64, passwordHash string) error {
+	res, err := p.ext.ExecContext(ctx, "UPDATE store_user SET password_hash = $2 WHERE id = $1", userID, passwordHash)
+	if err != nil {
+		return fmt.Errorf("failed to update password: %w", err)
+	}
+
+	if c, _ := res.RowsAffected(); c == 0 {
+		return storage.ErrNotFound
+	}
+
+	return nil
+}
+
+func (p pg) SaveResetToken(ctx context.Context, tokenHash string, userID int64, expiresAt time.Time) error {
+	if _, err := p.ext.ExecContext(ctx, `
+		WITH replaced AS (
+			DELETE FROM password_reset_token WHERE user_id = $2
+		)
+		INSERT INTO password_reset_token (token_hash, user_id, expires_at) VALUES ($1, $2, $3)
+	`, tokenHash, userID, expiresAt); err != nil {
+		return fmt.Errorf("failed to save reset token: %w", err)
+	}
+	return nil
+}
+
+func (p pg) UseResetToken(ctx context.Context, tokenHash string) (int64, error) {
+	var userID int64
+	err := p.ext.GetContext(ctx, &userID, `
+		WITH used AS (
+			DELETE FROM password_reset_token WHERE token_hash = $1 RETURNING user_id, expires_at
+		)
+		SELECT user_id FROM used WHERE expires_at > now()
+	`, tokenHash)
+
+	if err == sql.ErrNoRows {
+		return 0, storage.ErrNotFound
+	}
+
+	if err != nil {
+		return 0, fmt.Errorf("failed to use reset token: %w", err)
+	}
+
+	return userID, nil
+}
+
+func (p pg) VerifyUserEmail(ctx context.Context, userID int64, email string) error {
 	res, err := p.ext.ExecContext(ctx, `
-		UPDATE store_user SET is_admin = $2 WHERE id = $1
-	`, user.ID, user.IsAdmin)
+		UPDATE store_user SET email_verified = true WHERE id = $1 AND email = $2
+	`, userID, email)
+	if err != nil {
+		return fmt.Errorf("failed to verify email: %w", err)
+	}
 
+	if c, _ := res.RowsAffected(); c == 0 {
+		return storage.ErrNotFound
+	}
+
+	return nil
+}
+
+func (p pg) MarkVerificationSent(ctx context.Context, userID int64, interval time.Duration) error {
+	res, err := p.ext.ExecContext(ctx, `
+		UPDATE store_user SET verification_sent_at = now()
+		WHERE id = $1 AND (verification_sent_at IS NULL OR verification_sent_at <= now() - $2 * interval '1 second')
+	`, userID, interval.Seconds())
 	if err != nil {
-		return fmt.Errorf("failed to update user permissions: %w", err)
+		return fmt.Errorf("failed to mark verification sent: %w", err)
+	}
+
+	if c, _ := res.RowsAffected(); c == 0 {
+		return storage.ErrRateLimited
+	}
+
+	return nil
+}
+
+func (p pg) GetLoginFailures(ctx context.Context, subjects []string) ([]model.LoginFailures, error) {
+	var failures []loginFailures
+	if err := p.ext.SelectContext(ctx, &failures, `
+		SELECT subject, failures, last_failure_at FROM login_failure WHERE subject = ANY($1)
+	`, pq.StringArray(subjects)); err != nil {
+		return nil, fmt.Errorf("failed to get login failures: %w", err)
+	}
+
+	data := make([]model.LoginFailures, len(failures))
+	for i, f := range failures {
+		data[i] = f.toModel()
+	}
+
+	return data, nil
+}
+
+func (p pg) AddLoginFailure(ctx context.Context, subject string, window time.Duration) (model.LoginFailures, error) {
+	var f loginFailures
+	if err := p.ext.GetContext(ctx, &f, `
+		INSERT INTO login_failure (subject, failures, last_failure_at) VALUES ($1, 1, now())
+		ON CONFLICT (subject) DO UPDATE SET
+			failures = CASE
+				WHEN login_failure.last_failure_at < now() - $2 * interval '1 second' THEN 1
+				ELSE login_failure.failures + 1
+			END,
+			last_failure_at = now()
+		RETURNING subject, failures, last_failure_at
+	`, subject, window.Seconds()); err != nil {
+		return model.LoginFailures{}, fmt.Errorf("failed to add login failure: %w", err)
+	}
+
+	return f.toModel(), nil
+}
+
+func (p pg) DeleteLoginFailures(ctx context.Context, subject string) error {
+	if _, err := p.ext.ExecContext(ctx, "DELETE FROM login_failure WHERE subject = $1", subject); err != nil {
+		return fmt.Errorf("failed to delete login failures: %w", err)
+	}
+	return nil
+}
+
+func (p pg) SetMFASecret(ctx context.Context, userID int64, secret string) error {
+	res, err := p.ext.ExecContext(ctx, `
+		UPDATE store_user SET mfa_secret = $2, mfa_enabled = false, mfa_last_step = 0 WHERE id = $1
+	`, userID, secret)
+	if err != nil {
+		return fmt.Errorf("failed to set mfa secret: %w", err)
 	}
 
 	if c, _ := res.RowsAffected(); c == 0 {
@@ -96,26 +322,121 @@ func (p pg) UpdateUserPermissions(ctx context.Context, user model.User) error {
 	return nil
 }
 
-func (p pg) InTx(ctx context.Context, action func(s storage.UserStorage) error) error {
-	tx, err := p.dbx.BeginTxx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead})
+func (p pg) EnableMFA(ctx context.Context, userID int64, recoveryCodeHashes []string) error {
+	res, err := p.ext.ExecContext(ctx, `
+		WITH deleted AS (
+			DELETE FROM mfa_recovery_code WHERE user_id = $1
+		), inserted AS (
+			INSERT INTO mfa_recovery_code (user_id, code_hash) SELECT $1, unnest($2::text[])
+		)
+		UPDATE store_user SET mfa_enabled = true WHERE id = $1 AND mfa_secret IS NOT NULL
+	`, userID, pq.StringArray(recoveryCodeHashes))
 	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
+		return fmt.Errorf("failed to enable mfa: %w", err)
 	}
 
-	err = action(pg{dbx: p.dbx, ext: tx})
+	if c, _ := res.RowsAffected(); c == 0 {
+		return storage.ErrNotFound
+	}
+
+	return nil
+}
 
+func (p pg) DisableMFA(ctx context.Context, userID int64) error {
+	res, err := p.ext.ExecContext(ctx, `
+		WITH deleted AS (
+			DELETE FROM mfa_recovery_code WHERE user_id = $1
+		)
+		UPDATE store_user SET mfa_secret = NULL, mfa_enabled = false, mfa_last_step = 0 WHERE id = $1
+	`, userID)
 	if err != nil {
-		rbErr := tx.Rollback()
-		if rbErr != nil {
-			return fmt.Errorf("failed to rollback transaction: %v root: %w", rbErr, err)
-		}
+		return fmt.Errorf("failed to disable mfa: %w", err)
+	}
+
+	if c, _ := res.RowsAffected(); c == 0 {
+		return storage.ErrNotFound
+	}
+
+	return nil
+}
 
-		return err
+func (p pg) UseMFAStep(ctx context.Context, userID int64, step int64) error {
+	res, err := p.ext.ExecContext(ctx, `
+		UPDATE store_user SET mfa_last_step = $2 WHERE id = $1 AND mfa_last_step < $2
+	`, userID, step)
+	if err != nil {
+		return fmt.Errorf("failed to use mfa step: %w", err)
 	}
 
-	if err = tx.Commit(); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
+	if c, _ := res.RowsAffected(); c == 0 {
+		return storage.ErrNotFound
 	}
 
 	return nil
 }
+
+func (p pg) UseRecoveryCode(ctx context.Context, userID int64, codeHash string) error {
+	res, err := p.ext.ExecContext(ctx, `
+		DELETE FROM mfa_recovery_code WHERE user_id = $1 AND code_hash = $2
+	`, userID, codeHash)
+	if err != nil {
+		return fmt.Errorf("failed to use recovery code: %w", err)
+	}
+
+	if c, _ := res.RowsAffected(); c == 0 {
+		return storage.ErrNotFound
+	}
+
+	return nil
+}
+
+func (p pg) GetRoles(ctx context.Context) ([]model.Role, error) {
+	var roles []role
+	if err := p.ext.SelectContext(ctx, &roles, `
+		SELECT r.name, ARRAY(SELECT permission FROM role_permission WHERE role = r.name ORDER BY permission) AS permissions
+		FROM role r ORDER BY r.name
+	`); err != nil {
+		return nil, fmt.Errorf("failed to get roles: %w", err)
+	}
+
+	data := make([]model.Role, len(roles))
+	for i, r := range roles {
+		data[i] = r.toModel()
+	}
+
+	return data, nil
+}
+
+func (p pg) SetUserRoles(ctx context.Context, userID int64, roles []string) error {
+	return p.inTx(ctx, func(tx pg) error {
+		var id int64
+		err := tx.ext.GetContext(ctx, &id, "SELECT id FROM store_user WHERE id = $1 FOR UPDATE", userID)
+		if err == sql.ErrNoRows {
+			return storage.ErrNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get user: %w", err)
+		}
+
+		if _, err := tx.ext.ExecContext(ctx, "DELETE FROM user_role WHERE user_id = $1", userID); err != nil {
+			return fmt.Errorf("failed to delete user roles: %w", err)
+		}
+
+		if _, err := tx.ext.ExecContext(ctx, `
+			INSERT INTO user_role (user_id, role) SELECT $1, unnest($2::text[])
+		`, userID, pq.Array(roles)); err != nil {
+			if err, ok := err.(*pq.Error); ok && err.Constraint == userRoleFKConstraint {
+				return storage.ErrUnknownRole
+			}
+			return fmt.Errorf("failed to add user roles: %w", err)
+		}
+
+		return nil
+	})
+}
+
+func (p pg) InTx(ctx context.Context, action func(s storage.UserStorage) error) error {
+	return p.inTx(ctx, func(tx pg) error {
+		return action(tx)
+	})
+}
@@ -5,6 +5,7 @@ package postgres
 import (
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -13,11 +14,22 @@ import (
 	"github.com/vliubezny/gstore/internal/storage"
 )
 
+// testRoleUser is a catalog editor and store manager.
+var testRoleUser = model.User{
+	ID:           1,
+	Email:        "admin@test.com",
+	PasswordHash: "123",
+	Roles:        []string{model.RoleCatalogEditor, model.RoleStoreManager},
+	Permissions: []model.Permission{
+		model.PermissionCategoryWrite, model.PermissionPositionWrite, model.PermissionPriceHistoryRead,
+		model.PermissionProductWrite, model.PermissionStoreWrite,
+	},
+}
+
 func (s *postgresTestSuite) TestPg_CreateUser() {
 	user := model.User{
 		Email:        "admin@test.com",
 		PasswordHash: "1234",
-		IsAdmin:      true,
 	}
 
 	user, err := s.s.(pg).CreateUser(s.ctx, user)
@@ -25,9 +37,9 @@ func (s *postgresTestSuite) TestPg_CreateUser() {
 
 	s.Require().True(user.ID > 0, "ID is not populated")
 
-	r := s.db.QueryRow("SELECT id, email, password_hash, is_admin FROM store_user WHERE id = $1", user.ID)
+	r := s.db.QueryRow("SELECT id, email, password_hash FROM store_user WHERE id = $1", user.ID)
 	res := model.User{}
-	err = r.Scan(&res.ID, &res.Email, &res.PasswordHash, &res.IsAdmin)
+	err = r.Scan(&res.ID, &res.Email, &res.PasswordHash)
 	s.Require().NoError(err)
 
 	s.Equal(user, res)
@@ -37,88 +49,404 @@ func (s *postgresTestSuite) TestPg_CreateUser() {
 }
 
 func (s *postgresTestSuite) TestPg_GetUserByEmail() {
-	_, err := s.db.Exec(`INSERT INTO store_user (email, password_hash, is_admin) VALUES ('admin@test.com', '123', TRUE);`)
+	_, err := s.db.Exec(`
+		INSERT INTO store_user (email, password_hash) VALUES ('admin@test.com', '123');
+		INSERT INTO user_role (user_id, role) VALUES (1, 'catalog-editor'), (1, 'store-manager');
+	`)
 	s.Require().NoError(err)
 
 	u, err := s.s.(pg).GetUserByEmail(s.ctx, "admin@test.com")
 	s.Require().NoError(err)
 
-	s.Equal(model.User{ID: 1, Email: "admin@test.com", PasswordHash: "123", IsAdmin: true}, u)
+	s.Equal(testRoleUser, u)
 
 	_, err = s.s.(pg).GetUserByEmail(s.ctx, "none@test.com")
 	s.True(errors.Is(storage.ErrNotFound, err))
 }
 
 func (s *postgresTestSuite) TestPg_GetUserByID() {
-	_, err := s.db.Exec(`INSERT INTO store_user (email, password_hash, is_admin) VALUES ('admin@test.com', '123', TRUE);`)
+	_, err := s.db.Exec(`
+		INSERT INTO store_user (email, password_hash) VALUES ('admin@test.com', '123');
+		INSERT INTO user_role (user_id, role) VALUES (1, 'catalog-editor'), (1, 'store-manager');
+	`)
 	s.Require().NoError(err)
 
 	u, err := s.s.(pg).GetUserByID(s.ctx, 1)
 	s.Require().NoError(err)
 
-	s.Equal(model.User{ID: 1, Email: "admin@test.com", PasswordHash: "123", IsAdmin: true}, u)
+	s.Equal(testRoleUser, u)
 
 	_, err = s.s.(pg).GetUserByID(s.ctx, 100500)
 	s.True(errors.Is(storage.ErrNotFound, err))
 }
 
 func (s *postgresTestSuite) TestPg_SaveToken() {
-	_, err := s.db.Exec(`INSERT INTO store_user (email, password_hash, is_admin) VALUES ('admin@test.com', '123', TRUE);`)
+	_, err := s.db.Exec(`INSERT INTO store_user (email, password_hash) VALUES ('admin@test.com', '123');`)
+	s.Require().NoError(err)
+
+	token := model.RefreshToken{
+		ID:        uuid.NewString(),
+		FamilyID:  uuid.NewString(),
+		UserID:    1,
+		ExpiresAt: time.Now().Add(10 * time.Hour).UTC().Truncate(time.Microsecond),
+		IP:        "127.0.0.1",
+		UserAgent: "test",
+	}
+
+	err = s.s.(pg).SaveToken(s.ctx, token)
+	s.Require().NoError(err)
+
+	r := s.db.QueryRow("SELECT user_id, family_id, expires_at, ip, user_agent FROM token WHERE id = $1", token.ID)
+	var res model.RefreshToken
+	err = r.Scan(&res.UserID, &res.FamilyID, &res.ExpiresAt, &res.IP, &res.UserAgent)
+	s.Require().NoError(err)
+
+	res.ID = token.ID
+	res.ExpiresAt = res.ExpiresAt.UTC()
+	s.Equal(token, res)
+}
+
+func (s *postgresTestSuite) TestPg_UseToken() {
+	_, err := s.db.Exec(`INSERT INTO store_user (email, password_hash) VALUES ('admin@test.com', '123');`)
+	s.Require().NoError(err)
+
+	familyID := uuid.NewString()
+	first := model.RefreshToken{ID: uuid.NewString(), FamilyID: familyID, UserID: 1, ExpiresAt: time.Now().Add(time.Hour)}
+	s.Require().NoError(s.s.(pg).SaveToken(s.ctx, first))
+
+	_, err = s.db.Exec(`UPDATE token SET started_at = now() - interval '1 day'`)
 	s.Require().NoError(err)
 
-	tokenID := uuid.NewString()
-	userID := int64(1)
-	expiredAt := time.Now().Add(10 * time.Hour).UTC()
+	s.Require().NoError(s.s.(pg).UseToken(s.ctx, first.ID))
 
-	err = s.s.(pg).SaveToken(s.ctx, tokenID, userID, expiredAt)
+	second := model.RefreshToken{ID: uuid.NewString(), FamilyID: familyID, UserID: 1, ExpiresAt: time.Now().Add(time.Hour)}
+	s.Require().NoError(s.s.(pg).SaveToken(s.ctx, second))
+
+	err = s.s.(pg).UseToken(s.ctx, first.ID)
+	s.True(errors.Is(err, storage.ErrTokenReused))
+
+	err = s.s.(pg).UseToken(s.ctx, uuid.NewString())
+	s.True(errors.Is(err, storage.ErrNotFound))
+
+	sessions, err := s.s.(pg).GetSessions(s.ctx, 1)
 	s.Require().NoError(err)
+	s.Require().Len(sessions, 1, "rotated out tokens must not be listed")
+	s.Equal(familyID, sessions[0].ID)
+	s.True(sessions[0].CreatedAt.Before(time.Now().Add(-23*time.Hour)), "family must keep login time")
+}
 
-	r := s.db.QueryRow("SELECT user_id, expires_at FROM token WHERE id = $1", tokenID)
-	var resUserID int64
-	var resExpiresAt time.Time
-	err = r.Scan(&resUserID, &resExpiresAt)
+func (s *postgresTestSuite) TestPg_DeleteTokenFamily() {
+	_, err := s.db.Exec(`
+		INSERT INTO store_user (email, password_hash) VALUES ('admin@test.com', '123'), ('user@test.com', '123');
+		INSERT INTO token (id, family_id, user_id, expires_at) VALUES
+			('0e37df36-f698-11e6-8dd4-cb9ced3df976', '0e37df36-f698-11e6-8dd4-cb9ced3df976', 1, '2025-10-19 10:23:54'),
+			('1e37df36-f698-11e6-8dd4-cb9ced3df976', '0e37df36-f698-11e6-8dd4-cb9ced3df976', 1, '2025-10-19 10:23:54'),
+			('2e37df36-f698-11e6-8dd4-cb9ced3df976', '2e37df36-f698-11e6-8dd4-cb9ced3df976', 1, '2025-10-19 10:23:54')
+	`)
 	s.Require().NoError(err)
 
-	s.Equal(userID, resUserID)
-	s.Equal(expiredAt, resExpiresAt.UTC())
+	err = s.s.(pg).DeleteTokenFamily(s.ctx, 2, "0e37df36-f698-11e6-8dd4-cb9ced3df976")
+	s.True(errors.Is(err, storage.ErrNotFound), "family of another user must not be deleted")
+
+	s.Require().NoError(s.s.(pg).DeleteTokenFamily(s.ctx, 1, "0e37df36-f698-11e6-8dd4-cb9ced3df976"))
+
+	var n int
+	s.Require().NoError(s.db.QueryRow("SELECT count(*) FROM token").Scan(&n))
+	s.Equal(1, n)
+
+	err = s.s.(pg).DeleteTokenFamily(s.ctx, 1, "0e37df36-f698-11e6-8dd4-cb9ced3df976")
+	s.True(errors.Is(err, storage.ErrNotFound))
 }
 
-func (s *postgresTestSuite) TestPg_DeleteToken() {
+func (s *postgresTestSuite) TestPg_GetSessions() {
 	_, err := s.db.Exec(`
-		INSERT INTO store_user (email, password_hash, is_admin) VALUES ('admin@test.com', '123', TRUE);
-		INSERT INTO token (id, user_id, expires_at) VALUES ('0e37df36-f698-11e6-8dd4-cb9ced3df976', 1, '2025-10-19 10:23:54')
+		INSERT INTO store_user (email, password_hash) VALUES ('admin@test.com', '123'), ('user@test.com', '123');
+		INSERT INTO token (id, family_id, user_id, expires_at, ip, user_agent, created_at) VALUES
+			('0e37df36-f698-11e6-8dd4-cb9ced3df976', '0e37df36-f698-11e6-8dd4-cb9ced3df976', 1, now() + interval '1 day', '10.0.0.1', 'first', now() - interval '1 hour'),
+			('1e37df36-f698-11e6-8dd4-cb9ced3df976', '1e37df36-f698-11e6-8dd4-cb9ced3df976', 1, now() + interval '1 day', '10.0.0.2', 'second', now()),
+			('2e37df36-f698-11e6-8dd4-cb9ced3df976', '2e37df36-f698-11e6-8dd4-cb9ced3df976', 1, now() - interval '1 day', '10.0.0.3', 'expired', now()),
+			('3e37df36-f698-11e6-8dd4-cb9ced3df976', '3e37df36-f698-11e6-8dd4-cb9ced3df976', 2, now() + interval '1 day', '10.0.0.4', 'another', now())
 	`)
 	s.Require().NoError(err)
 
-	err = s.s.(pg).DeleteToken(s.ctx, "0e37df36-f698-11e6-8dd4-cb9ced3df976")
+	sessions, err := s.s.(pg).GetSessions(s.ctx, 1)
+	s.Require().NoError(err)
+	s.Require().Len(sessions, 2)
+
+	s.Equal("1e37df36-f698-11e6-8dd4-cb9ced3df976", sessions[0].ID)
+	s.Equal("10.0.0.2", sessions[0].IP)
+	s.Equal("second", sessions[0].UserAgent)
+	s.Equal("0e37df36-f698-11e6-8dd4-cb9ced3df976", sessions[1].ID)
+}
+
+func (s *postgresTestSuite) TestPg_RevokeUserTokens() {
+	_, err := s.db.Exec(`INSERT INTO store_user (email, password_hash) VALUES ('admin@test.com', '123'), ('user@test.com', '123');`)
 	s.Require().NoError(err)
 
-	err = s.s.(pg).DeleteToken(s.ctx, "0e37df36-f698-11e6-8dd4-cb9ced3df976")
+	since := time.Now().Add(-time.Minute)
+
+	revocations, err := s.s.(pg).GetTokenRevocations(s.ctx, since)
+	s.Require().NoError(err)
+	s.Empty(revocations)
+
+	s.Require().NoError(s.s.(pg).RevokeUserTokens(s.ctx, 2))
+
+	revocations, err = s.s.(pg).GetTokenRevocations(s.ctx, since)
+	s.Require().NoError(err)
+	s.Require().Len(revocations, 1)
+	s.Equal(int64(2), revocations[0].UserID)
+	s.WithinDuration(time.Now(), revocations[0].IssuedBefore, time.Minute)
+
+	revocations, err = s.s.(pg).GetTokenRevocations(s.ctx, time.Now().Add(time.Minute))
+	s.Require().NoError(err)
+	s.Empty(revocations, "old revocations must be skipped")
+
+	err = s.s.(pg).RevokeUserTokens(s.ctx, 100)
 	s.True(errors.Is(err, storage.ErrNotFound))
 }
 
-func (s *postgresTestSuite) TestPg_UpdateUserPermissions() {
+func (s *postgresTestSuite) TestPg_RevokeSessionTokens() {
+	_, err := s.db.Exec(`INSERT INTO store_user (email, password_hash) VALUES ('admin@test.com', '123'), ('user@test.com', '123');`)
+	s.Require().NoError(err)
+
+	since := time.Now().Add(-time.Minute)
+
+	s.Require().NoError(s.s.(pg).RevokeSessionTokens(s.ctx, 2, "0e37df36-f698-11e6-8dd4-cb9ced3df976"))
+	s.Require().NoError(s.s.(pg).RevokeSessionTokens(s.ctx, 2, "0e37df36-f698-11e6-8dd4-cb9ced3df976"), "revocation must be idempotent")
+
+	revocations, err := s.s.(pg).GetTokenRevocations(s.ctx, since)
+	s.Require().NoError(err)
+	s.Require().Len(revocations, 1)
+	s.Equal(int64(2), revocations[0].UserID)
+	s.Equal("0e37df36-f698-11e6-8dd4-cb9ced3df976", revocations[0].SessionID)
+	s.WithinDuration(time.Now(), revocations[0].IssuedBefore, time.Minute)
+
+	revocations, err = s.s.(pg).GetTokenRevocations(s.ctx, time.Now().Add(time.Minute))
+	s.Require().NoError(err)
+	s.Empty(revocations, "old revocations must be skipped")
+
+	err = s.s.(pg).RevokeSessionTokens(s.ctx, 100, "1e37df36-f698-11e6-8dd4-cb9ced3df976")
+	s.True(errors.Is(err, storage.ErrNotFound))
+}
+
+func (s *postgresTestSuite) TestPg_DeleteUserTokens() {
 	_, err := s.db.Exec(`
-		INSERT INTO store_user (email, password_hash, is_admin) VALUES ('admin@test.com', '123', FALSE);
+		INSERT INTO store_user (email, password_hash) VALUES ('admin@test.com', '123'), ('user@test.com', '123');
+		INSERT INTO token (id, family_id, user_id, expires_at) VALUES
+			('0e37df36-f698-11e6-8dd4-cb9ced3df976', '0e37df36-f698-11e6-8dd4-cb9ced3df976', 1, '2025-10-19 10:23:54'),
+			('1e37df36-f698-11e6-8dd4-cb9ced3df976', '1e37df36-f698-11e6-8dd4-cb9ced3df976', 1, '2025-10-19 10:23:54'),
+			('2e37df36-f698-11e6-8dd4-cb9ced3df976', '2e37df36-f698-11e6-8dd4-cb9ced3df976', 2, '2025-10-19 10:23:54')
 	`)
 	s.Require().NoError(err)
 
-	u := model.User{ID: 1, IsAdmin: true}
+	s.Require().NoError(s.s.(pg).DeleteUserTokens(s.ctx, 1))
 
-	err = s.s.(pg).UpdateUserPermissions(s.ctx, u)
+	var n int
+	s.Require().NoError(s.db.QueryRow("SELECT count(*) FROM token").Scan(&n))
+	s.Equal(1, n)
+}
+
+func (s *postgresTestSuite) TestPg_UpdateUserPassword() {
+	_, err := s.db.Exec(`INSERT INTO store_user (email, password_hash) VALUES ('admin@test.com', '123');`)
 	s.Require().NoError(err)
 
-	res := model.User{}
-	s.Require().NoError(s.db.QueryRow(`
-		SELECT email, password_hash, is_admin FROM store_user WHERE id = $1
-	`, 1).Scan(&res.Email, &res.PasswordHash, &res.IsAdmin))
+	s.Require().NoError(s.s.(pg).UpdateUserPassword(s.ctx, 1, "456"))
+
+	u, err := s.s.(pg).GetUserByID(s.ctx, 1)
+	s.Require().NoError(err)
+	s.Equal("456", u.PasswordHash)
+
+	err = s.s.(pg).UpdateUserPassword(s.ctx, 100, "456")
+	s.True(errors.Is(err, storage.ErrNotFound))
+}
+
+func (s *postgresTestSuite) TestPg_ResetToken() {
+	_, err := s.db.Exec(`INSERT INTO store_user (email, password_hash) VALUES ('admin@test.com', '123');`)
+	s.Require().NoError(err)
+
+	s.Require().NoError(s.s.(pg).SaveResetToken(s.ctx, "hash1", 1, time.Now().Add(time.Hour)))
+	s.Require().NoError(s.s.(pg).SaveResetToken(s.ctx, "hash2", 1, time.Now().Add(time.Hour)))
+
+	_, err = s.s.(pg).UseResetToken(s.ctx, "hash1")
+	s.True(errors.Is(err, storage.ErrNotFound), "previous token must be replaced")
+
+	userID, err := s.s.(pg).UseResetToken(s.ctx, "hash2")
+	s.Require().NoError(err)
+	s.Equal(int64(1), userID)
+
+	_, err = s.s.(pg).UseResetToken(s.ctx, "hash2")
+	s.True(errors.Is(err, storage.ErrNotFound), "token must be single-use")
+
+	s.Require().NoError(s.s.(pg).SaveResetToken(s.ctx, "hash3", 1, time.Now().Add(-time.Minute)))
+
+	_, err = s.s.(pg).UseResetToken(s.ctx, "hash3")
+	s.True(errors.Is(err, storage.ErrNotFound), "expired token must be rejected")
+}
+
+func (s *postgresTestSuite) TestPg_VerifyUserEmail() {
+	_, err := s.db.Exec(`INSERT INTO store_user (email, password_hash) VALUES ('admin@test.com', '123');`)
+	s.Require().NoError(err)
+
+	err = s.s.(pg).VerifyUserEmail(s.ctx, 1, "old@test.com")
+	s.True(errors.Is(err, storage.ErrNotFound))
+
+	s.Require().NoError(s.s.(pg).VerifyUserEmail(s.ctx, 1, "admin@test.com"))
+
+	u, err := s.s.(pg).GetUserByID(s.ctx, 1)
+	s.Require().NoError(err)
+	s.True(u.EmailVerified)
+}
+
+func (s *postgresTestSuite) TestPg_MarkVerificationSent() {
+	_, err := s.db.Exec(`INSERT INTO store_user (email, password_hash) VALUES ('admin@test.com', '123');`)
+	s.Require().NoError(err)
+
+	// registration counts as sent verification
+	err = s.s.(pg).MarkVerificationSent(s.ctx, 1, time.Minute)
+	s.True(errors.Is(err, storage.ErrRateLimited))
+
+	_, err = s.db.Exec(`UPDATE store_user SET verification_sent_at = now() - interval '2 minutes'`)
+	s.Require().NoError(err)
+
+	s.Require().NoError(s.s.(pg).MarkVerificationSent(s.ctx, 1, time.Minute))
+
+	err = s.s.(pg).MarkVerificationSent(s.ctx, 1, time.Minute)
+	s.True(errors.Is(err, storage.ErrRateLimited))
+}
+
+func (s *postgresTestSuite) TestPg_MFA() {
+	_, err := s.db.Exec(`INSERT INTO store_user (email, password_hash) VALUES ('admin@test.com', '123');`)
+	s.Require().NoError(err)
+
+	err = s.s.(pg).EnableMFA(s.ctx, 1, []string{"code1"})
+	s.True(errors.Is(err, storage.ErrNotFound), "mfa must be enrolled before enabling")
+
+	s.Require().NoError(s.s.(pg).SetMFASecret(s.ctx, 1, "SECRET"))
+
+	u, err := s.s.(pg).GetUserByID(s.ctx, 1)
+	s.Require().NoError(err)
+	s.Equal("SECRET", u.MFASecret)
+	s.False(u.MFAEnabled)
+
+	s.Require().NoError(s.s.(pg).EnableMFA(s.ctx, 1, []string{"code1", "code2"}))
+
+	u, err = s.s.(pg).GetUserByID(s.ctx, 1)
+	s.Require().NoError(err)
+	s.True(u.MFAEnabled)
+
+	s.Require().NoError(s.s.(pg).UseMFAStep(s.ctx, 1, 100))
+
+	err = s.s.(pg).UseMFAStep(s.ctx, 1, 100)
+	s.True(errors.Is(err, storage.ErrNotFound), "step must be single-use")
+
+	err = s.s.(pg).UseMFAStep(s.ctx, 1, 99)
+	s.True(errors.Is(err, storage.ErrNotFound), "previous step must be rejected")
+
+	s.Require().NoError(s.s.(pg).UseRecoveryCode(s.ctx, 1, "code1"))
+
+	err = s.s.(pg).UseRecoveryCode(s.ctx, 1, "code1")
+	s.True(errors.Is(err, storage.ErrNotFound), "recovery code must be single-use")
 
-	s.Equal(model.User{Email: "admin@test.com", PasswordHash: "123", IsAdmin: true}, res)
+	s.Require().NoError(s.s.(pg).DisableMFA(s.ctx, 1))
 
-	err = s.s.(pg).UpdateUserPermissions(s.ctx, model.User{ID: 1000})
+	u, err = s.s.(pg).GetUserByID(s.ctx, 1)
+	s.Require().NoError(err)
+	s.Empty(u.MFASecret)
+	s.False(u.MFAEnabled)
+
+	err = s.s.(pg).UseRecoveryCode(s.ctx, 1, "code2")
+	s.True(errors.Is(err, storage.ErrNotFound), "recovery codes must be deleted")
+
+	err = s.s.(pg).SetMFASecret(s.ctx, 100, "SECRET")
 	s.True(errors.Is(err, storage.ErrNotFound))
 }
 
+func (s *postgresTestSuite) TestPg_LoginFailures() {
+	f, err := s.s.(pg).AddLoginFailure(s.ctx, "account:admin@test.com", time.Hour)
+	s.Require().NoError(err)
+	s.Equal(1, f.Count)
+
+	f, err = s.s.(pg).AddLoginFailure(s.ctx, "account:admin@test.com", time.Hour)
+	s.Require().NoError(err)
+	s.Equal(2, f.Count)
+
+	_, err = s.s.(pg).AddLoginFailure(s.ctx, "ip:127.0.0.1", time.Hour)
+	s.Require().NoError(err)
+
+	failures, err := s.s.(pg).GetLoginFailures(s.ctx, []string{"account:admin@test.com", "ip:10.0.0.1"})
+	s.Require().NoError(err)
+	s.Require().Len(failures, 1)
+	s.Equal("account:admin@test.com", failures[0].Subject)
+	s.Equal(2, failures[0].Count)
+
+	_, err = s.db.Exec(`UPDATE login_failure SET last_failure_at = now() - interval '2 hours'`)
+	s.Require().NoError(err)
+
+	f, err = s.s.(pg).AddLoginFailure(s.ctx, "account:admin@test.com", time.Hour)
+	s.Require().NoError(err)
+	s.Equal(1, f.Count, "failures before window must be forgotten")
+
+	s.Require().NoError(s.s.(pg).DeleteLoginFailures(s.ctx, "account:admin@test.com"))
+
+	failures, err = s.s.(pg).GetLoginFailures(s.ctx, []string{"account:admin@test.com"})
+	s.Require().NoError(err)
+	s.Empty(failures)
+}
+
+func (s *postgresTestSuite) TestPg_LoginFailures_LongSubject() {
+	subject := "account:" + strings.Repeat("a", 300) + "@test.com"
+
+	f, err := s.s.(pg).AddLoginFailure(s.ctx, subject, time.Hour)
+	s.Require().NoError(err)
+	s.Equal(1, f.Count)
+
+	failures, err := s.s.(pg).GetLoginFailures(s.ctx, []string{subject})
+	s.Require().NoError(err)
+	s.Require().Len(failures, 1)
+	s.Equal(subject, failures[0].Subject)
+}
+
+func (s *postgresTestSuite) TestPg_GetRoles() {
+	roles, err := s.s.(pg).GetRoles(s.ctx)
+	s.Require().NoError(err)
+
+	s.Require().Len(roles, 4)
+	s.Equal(model.Role{
+		Name:        model.RoleCatalogEditor,
+		Permissions: []model.Permission{model.PermissionCategoryWrite, model.PermissionProductWrite},
+	}, roles[0])
+	s.Equal(model.RoleSuperadmin, roles[2].Name)
+}
+
+func (s *postgresTestSuite) TestPg_SetUserRoles() {
+	_, err := s.db.Exec(`
+		INSERT INTO store_user (email, password_hash) VALUES ('admin@test.com', '123');
+		INSERT INTO user_role (user_id, role) VALUES (1, 'support');
+	`)
+	s.Require().NoError(err)
+
+	err = s.s.(pg).SetUserRoles(s.ctx, 1, []string{model.RoleStoreManager, model.RoleCatalogEditor})
+	s.Require().NoError(err)
+
+	u, err := s.s.(pg).GetUserByID(s.ctx, 1)
+	s.Require().NoError(err)
+	s.Equal(testRoleUser, u)
+
+	err = s.s.(pg).SetUserRoles(s.ctx, 1, []string{"unknown"})
+	s.True(errors.Is(err, storage.ErrUnknownRole))
+
+	err = s.s.(pg).SetUserRoles(s.ctx, 1000, nil)
+	s.True(errors.Is(err, storage.ErrNotFound))
+
+	err = s.s.(pg).SetUserRoles(s.ctx, 1, nil)
+	s.Require().NoError(err)
+
+	u, err = s.s.(pg).GetUserByID(s.ctx, 1)
+	s.Require().NoError(err)
+	s.Empty(u.Roles)
+	s.Empty(u.Permissions)
+}
+
 func (s *postgresTestSuite) TestPg_InTx() {
 	userCount := func() int {
 		var c int
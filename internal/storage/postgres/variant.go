@@ -0,0 +1,92 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/vliubezny/gstore/internal/model"
+	"github.com/vliubezny/gstore/internal/storage"
+)
+
+const (
+	variantProductIDFKConstraint = "variant_product_id_fkey"
+	variantSKUConstraint         = "variant_sku_key"
+	variantGTINConstraint        = "variant_gtin_key"
+)
+
+func (p pg) GetProductVariants(ctx context.Context, productID int64) ([]model.Variant, error) {
+	var variants []variant
+	if err := p.ext.SelectContext(ctx, &variants, `
+		SELECT id, product_id, sku, gtin, attributes FROM variant WHERE product_id = $1 ORDER BY id
+	`, productID); err != nil {
+		return nil, fmt.Errorf("failed to get product variants: %w", err)
+	}
+
+	data := make([]model.Variant, len(variants))
+	for i, v := range variants {
+		data[i] = v.toModel()
+	}
+
+	return data, nil
+}
+
+func (p pg) CreateVariant(ctx context.Context, v model.Variant) (model.Variant, error) {
+	if err := p.ext.GetContext(ctx, &v.ID, `
+		INSERT INTO variant (product_id, sku, gtin, attributes) VALUES ($1, $2, $3, $4) RETURNING id
+	`, v.ProductID, v.SKU, nullString(v.GTIN), attributeValues(v.Attributes)); err != nil {
+		return model.Variant{}, variantError("failed to create variant", err)
+	}
+
+	return v, nil
+}
+
+func (p pg) UpdateVariant(ctx context.Context, v model.Variant) error {
+	res, err := p.ext.ExecContext(ctx, `
+		UPDATE variant SET
+		sku = $3,
+		gtin = $4,
+		attributes = $5
+		WHERE id = $1 AND product_id = $2
+	`, v.ID, v.ProductID, v.SKU, nullString(v.GTIN), attributeValues(v.Attributes))
+
+	if err != nil {
+		return variantError("failed to update variant", err)
+	}
+
+	if c, _ := res.RowsAffected(); c == 0 {
+		return storage.ErrNotFound
+	}
+
+	return nil
+}
+
+func (p pg) DeleteVariant(ctx context.Context, productID, variantID int64) error {
+	res, err := p.ext.ExecContext(ctx, "DELETE FROM variant WHERE id = $1 AND product_id = $2", variantID, productID)
+
+	if err != nil {
+		return fmt.Errorf("failed to delete variant: %w", err)
+	}
+
+	if c, _ := res.RowsAffected(); c == 0 {
+		return storage.ErrNotFound
+	}
+
+	return nil
+}
+
+// variantError maps constraint violations of variant table to storage errors.
+func variantError(msg string, err error) error {
+	if err, ok := err.(*pq.Error); ok {
+		switch err.Constraint {
+		case variantProductIDFKConstraint:
+			return storage.ErrUnknownProduct
+		case variantSKUConstraint:
+			return storage.ErrSKUIsTaken
+		case variantGTINConstraint:
+			return storage.ErrGTINIsTaken
+		}
+	}
+
+	return fmt.Errorf("%s: %w", msg, err)
+}
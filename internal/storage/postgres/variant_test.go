@@ -0,0 +1,98 @@
+//+build integration
+
+package postgres
+
+import (
+	"errors"
+
+	"github.com/vliubezny/gstore/internal/model"
+	"github.com/vliubezny/gstore/internal/storage"
+)
+
+func (s *postgresTestSuite) TestPg_GetProductVariants() {
+	_, err := s.db.Exec(`
+		INSERT INTO product (category_id, name, description) VALUES
+			(1, 'iPhone 11', 'Old iphone'),
+			(1, 'iPhone 12', 'New iphone');
+		INSERT INTO variant (product_id, sku, gtin, attributes) VALUES
+			(1, 'IP11-64', '4006381333931', '{"capacity": 64}'),
+			(2, 'IP12-64', NULL, '{}'),
+			(1, 'IP11-128', NULL, '{"capacity": 128}');
+	`)
+	s.Require().NoError(err)
+
+	variants, err := s.s.GetProductVariants(s.ctx, 1)
+	s.Require().NoError(err)
+
+	s.Equal([]model.Variant{
+		{ID: 1, ProductID: 1, SKU: "IP11-64", GTIN: "4006381333931", Attributes: map[string]interface{}{"capacity": 64.0}},
+		{ID: 3, ProductID: 1, SKU: "IP11-128", Attributes: map[string]interface{}{"capacity": 128.0}},
+	}, variants)
+}
+
+func (s *postgresTestSuite) TestPg_CreateVariant() {
+	_, err := s.db.Exec(`INSERT INTO product (category_id, name, description) VALUES (1, 'iPhone 11', 'Old iphone');`)
+	s.Require().NoError(err)
+
+	v, err := s.s.CreateVariant(s.ctx, model.Variant{ProductID: 1, SKU: "IP11-64", GTIN: "4006381333931"})
+	s.Require().NoError(err)
+
+	s.Equal(model.Variant{ID: 1, ProductID: 1, SKU: "IP11-64", GTIN: "4006381333931"}, v)
+
+	_, err = s.s.CreateVariant(s.ctx, model.Variant{ProductID: 1, SKU: "IP11-64"})
+	s.True(errors.Is(err, storage.ErrSKUIsTaken))
+
+	_, err = s.s.CreateVariant(s.ctx, model.Variant{ProductID: 1, SKU: "IP11-128", GTIN: "4006381333931"})
+	s.True(errors.Is(err, storage.ErrGTINIsTaken))
+
+	_, err = s.s.CreateVariant(s.ctx, model.Variant{ProductID: 100, SKU: "IP11-256"})
+	s.True(errors.Is(err, storage.ErrUnknownProduct))
+
+	// variants without GTIN don't conflict
+	_, err = s.s.CreateVariant(s.ctx, model.Variant{ProductID: 1, SKU: "IP11-128"})
+	s.Require().NoError(err)
+	_, err = s.s.CreateVariant(s.ctx, model.Variant{ProductID: 1, SKU: "IP11-256"})
+	s.Require().NoError(err)
+}
+
+func (s *postgresTestSuite) TestPg_UpdateVariant() {
+	_, err := s.db.Exec(`
+		INSERT INTO product (category_id, name, description) VALUES
+			(1, 'iPhone 11', 'Old iphone'),
+			(1, 'iPhone 12', 'New iphone');
+		INSERT INTO variant (product_id, sku) VALUES (1, 'IP11-64'), (1, 'IP11-128');
+	`)
+	s.Require().NoError(err)
+
+	v := model.Variant{ID: 1, ProductID: 1, SKU: "IP11-64-B", Attributes: map[string]interface{}{"color": "black"}}
+	s.Require().NoError(s.s.UpdateVariant(s.ctx, v))
+
+	variants, err := s.s.GetProductVariants(s.ctx, 1)
+	s.Require().NoError(err)
+	s.Equal(v, variants[0])
+
+	err = s.s.UpdateVariant(s.ctx, model.Variant{ID: 2, ProductID: 1, SKU: "IP11-64-B"})
+	s.True(errors.Is(err, storage.ErrSKUIsTaken))
+
+	err = s.s.UpdateVariant(s.ctx, model.Variant{ID: 1, ProductID: 2, SKU: "IP11-64-B"})
+	s.True(errors.Is(err, storage.ErrNotFound))
+}
+
+func (s *postgresTestSuite) TestPg_DeleteVariant() {
+	_, err := s.db.Exec(`
+		INSERT INTO product (category_id, name, description) VALUES (1, 'iPhone 11', 'Old iphone');
+		INSERT INTO variant (product_id, sku) VALUES (1, 'IP11');
+		INSERT INTO store (name) VALUES ('iStore');
+		INSERT INTO position (product_id, variant_id, store_id, price) VALUES (1, 1, 1, 100);
+	`)
+	s.Require().NoError(err)
+
+	s.Require().NoError(s.s.DeleteVariant(s.ctx, 1, 1))
+
+	var c int
+	s.Require().NoError(s.db.QueryRow("SELECT count(*) FROM position WHERE variant_id = 1").Scan(&c))
+	s.Equal(0, c)
+
+	err = s.s.DeleteVariant(s.ctx, 1, 1)
+	s.True(errors.Is(err, storage.ErrNotFound))
+}
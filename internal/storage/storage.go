@@ -17,24 +17,82 @@ var (
 	// ErrUnknownCategory states that category is unknown.
 	ErrUnknownCategory = errors.New("category is unknown")
 
+	// ErrCategoryCycle states that category cannot be moved into its own subtree.
+	ErrCategoryCycle = errors.New("category cycle")
+
 	// ErrUnknownStore states that store is unknown.
 	ErrUnknownStore = errors.New("store is unknown")
 
 	// ErrUnknownProduct states that product is unknown.
 	ErrUnknownProduct = errors.New("product is unknown")
 
+	// ErrUnknownVariant states that product variant is unknown.
+	ErrUnknownVariant = errors.New("variant is unknown")
+
+	// ErrSKUIsTaken states that SKU is taken by another variant.
+	ErrSKUIsTaken = errors.New("SKU is taken")
+
+	// ErrGTINIsTaken states that GTIN is taken by another variant.
+	ErrGTINIsTaken = errors.New("GTIN is taken")
+
+	// ErrInsufficientStock states that stock quantity cannot go below zero.
+	ErrInsufficientStock = errors.New("insufficient stock")
+
+	// ErrUnknownCurrency states that currency has no exchange rate.
+	ErrUnknownCurrency = errors.New("currency is unknown")
+
+	// ErrCurrencyInUse states that currency is used by positions.
+	ErrCurrencyInUse = errors.New("currency is in use")
+
+	// ErrUnknownPosition states that store position is unknown.
+	ErrUnknownPosition = errors.New("position is unknown")
+
+	// ErrLastOwner states that change would leave store without owner.
+	ErrLastOwner = errors.New("last owner of the store")
+
+	// ErrPositionChanged states that store position price or availability has changed.
+	ErrPositionChanged = errors.New("position has changed")
+
+	// ErrOrderStatusChanged states that order status has been changed concurrently.
+	ErrOrderStatusChanged = errors.New("order status has changed")
+
+	// ErrPaymentInProgress states that order has payment which is not declined.
+	ErrPaymentInProgress = errors.New("payment is in progress")
+
+	// ErrDuplicateEvent states that payment event has been already recorded.
+	ErrDuplicateEvent = errors.New("event is duplicate")
+
 	// ErrEmailIsTaken states that email address is taken.
 	ErrEmailIsTaken = errors.New("email is taken")
+
+	// ErrUnknownUser states that user is unknown.
+	ErrUnknownUser = errors.New("user is unknown")
+
+	// ErrUnknownRole states that role is unknown.
+	ErrUnknownRole = errors.New("role is unknown")
+
+	// ErrInvalidCursor states that page cursor is malformed.
+	ErrInvalidCursor = errors.New("invalid cursor")
+
+	// ErrRateLimited states that action is repeated too often.
+	ErrRateLimited = errors.New("rate limited")
+
+	// ErrTokenReused states that rotated out token is presented again.
+	ErrTokenReused = errors.New("token is reused")
 )
 
 // Storage provides methods to interact with data storage.
 type Storage interface {
-	// GetCategories returns slice of product categories.
-	GetCategories(ctx context.Context) ([]model.Category, error)
+	// GetCategories returns page of product categories and cursor of the next page.
+	GetCategories(ctx context.Context, page model.Page) ([]model.Category, string, error)
 
 	// GetCategory returns a product category by ID.
 	GetCategory(ctx context.Context, categoryID int64) (model.Category, error)
 
+	// GetCategoryTree returns flat list of the category and all its descendants,
+	// zero categoryID results in all categories.
+	GetCategoryTree(ctx context.Context, categoryID int64) ([]model.Category, error)
+
 	// CreateCategory creates new category.
 	CreateCategory(ctx context.Context, category model.Category) (model.Category, error)
 
@@ -44,8 +102,8 @@ type Storage interface {
 	// DeleteCategory deletes category from storage.
 	DeleteCategory(ctx context.Context, categoryID int64) error
 
-	// GetStores returns slice of stores.
-	GetStores(ctx context.Context) ([]model.Store, error)
+	// GetStores returns page of stores and cursor of the next page.
+	GetStores(ctx context.Context, page model.Page) ([]model.Store, string, error)
 
 	// GetStore returns a product store by ID.
 	GetStore(ctx context.Context, storeID int64) (model.Store, error)
@@ -59,8 +117,24 @@ type Storage interface {
 	// DeleteStore deletes store from storage.
 	DeleteStore(ctx context.Context, storeID int64) error
 
-	// GetProducts returns slice of products in category.
-	GetProducts(ctx context.Context, categoryID int64) ([]model.Product, error)
+	// GetStoreMembers returns members of the store.
+	GetStoreMembers(ctx context.Context, storeID int64) ([]model.StoreMember, error)
+
+	// GetStoreMember returns store member by user ID.
+	GetStoreMember(ctx context.Context, storeID, userID int64) (model.StoreMember, error)
+
+	// SetStoreMember adds user with the email to store members or changes role of existing member,
+	// returns ErrLastOwner if the member is the only owner of the store and the role is not owner.
+	SetStoreMember(ctx context.Context, storeID int64, email string, role model.StoreRole) (model.StoreMember, error)
+
+	// DeleteStoreMember removes user from store members, returns ErrLastOwner if the member is the only owner of the store.
+	DeleteStoreMember(ctx context.Context, storeID, userID int64) error
+
+	// GetProducts returns page of filtered products in category and cursor of the next page.
+	GetProducts(ctx context.Context, categoryID int64, filter model.ProductFilter, page model.Page) ([]model.Product, string, error)
+
+	// SearchProducts returns page of products ranked by relevance to the search query and cursor of the next page.
+	SearchProducts(ctx context.Context, search model.ProductSearch, page model.Page) ([]model.ProductSearchResult, string, error)
 
 	// GetProduct returns a product by ID.
 	GetProduct(ctx context.Context, productID int64) (model.Product, error)
@@ -74,21 +148,155 @@ type Storage interface {
 	// DeleteProduct deletes product.
 	DeleteProduct(ctx context.Context, productID int64) error
 
-	// GetStorePositions returns slice of store positions.
-	GetStorePositions(ctx context.Context, storeID int64) ([]model.Position, error)
+	// GetProductImages returns images of the products ordered by product ID and position.
+	GetProductImages(ctx context.Context, productIDs []int64) ([]model.Image, error)
 
-	// GetProductPositions returns slice of product positions.
-	GetProductPositions(ctx context.Context, productID int64) ([]model.Position, error)
+	// CreateProductImage adds image to the end of product images.
+	CreateProductImage(ctx context.Context, image model.Image) (model.Image, error)
 
-	// UpsertPosition updates position or creates new one if it doesn't exist.
-	UpsertPosition(ctx context.Context, position model.Position) error
+	// UpdateProductImagePositions orders product images according to imageIDs.
+	UpdateProductImagePositions(ctx context.Context, productID int64, imageIDs []int64) error
+
+	// DeleteProductImage deletes product image.
+	DeleteProductImage(ctx context.Context, productID, imageID int64) (model.Image, error)
+
+	// GetProductVariants returns variants of the product ordered by ID.
+	GetProductVariants(ctx context.Context, productID int64) ([]model.Variant, error)
+
+	// CreateVariant creates new product variant.
+	CreateVariant(ctx context.Context, variant model.Variant) (model.Variant, error)
+
+	// UpdateVariant updates product variant.
+	UpdateVariant(ctx context.Context, variant model.Variant) error
+
+	// DeleteVariant deletes product variant along with its positions.
+	DeleteVariant(ctx context.Context, productID, variantID int64) error
+
+	// GetStorePositions returns page of store positions and cursor of the next page.
+	GetStorePositions(ctx context.Context, storeID int64, page model.Page) ([]model.Position, string, error)
+
+	// GetProductPositions returns page of filtered product positions and cursor of the next page.
+	GetProductPositions(ctx context.Context, productID int64, filter model.PositionFilter, page model.Page) ([]model.Position, string, error)
+
+	// GetExchangeRates returns exchange rates ordered by currency.
+	GetExchangeRates(ctx context.Context) ([]model.ExchangeRate, error)
+
+	// UpsertExchangeRates updates exchange rates or creates new ones if they don't exist.
+	UpsertExchangeRates(ctx context.Context, rates []model.ExchangeRate) error
+
+	// DeleteExchangeRate deletes exchange rate of currency which is not used by positions.
+	DeleteExchangeRate(ctx context.Context, currency string) error
+
+	// GetPriceSummaries returns price comparison of the products in base currency ordered by product ID,
+	// products without offers are omitted.
+	GetPriceSummaries(ctx context.Context, productIDs []int64) ([]model.PriceSummary, error)
+
+	// UpsertPosition updates position of the variant or creates new one if it doesn't exist,
+	// product ID of the position is taken from the variant, price changes are recorded into history
+	// on behalf of the user. Quantity is set only for new position, empty availability keeps
+	// availability and restock date of existing position or means in stock for new one.
+	UpsertPosition(ctx context.Context, position model.Position, userID int64) (model.Position, error)
+
+	// AdjustPositionStock atomically adds delta to position quantity,
+	// quantity cannot go below zero.
+	AdjustPositionStock(ctx context.Context, variantID, storeID, delta int64) (model.Position, error)
 
 	// DeletePosition deletes position.
-	DeletePosition(ctx context.Context, productID, storeID int64) error
+	DeletePosition(ctx context.Context, variantID, storeID int64) error
+
+	// GetPriceHistory returns recorded price changes of product positions ordered by time.
+	GetPriceHistory(ctx context.Context, productID int64, filter model.PriceHistoryFilter) ([]model.PriceChange, error)
+
+	// GetDailyPrices returns daily statistics of recorded product prices in base currency ordered by date.
+	GetDailyPrices(ctx context.Context, productID int64, filter model.PriceHistoryFilter) ([]model.PriceBucket, error)
+}
+
+// CartStorage provides methods to interact with shopping cart storage.
+type CartStorage interface {
+	// GetCartItems returns items of the cart along with current state of their positions ordered by time of adding.
+	GetCartItems(ctx context.Context, owner model.CartOwner) ([]model.CartItem, error)
+
+	// UpsertCartItem sets quantity of the position in the cart or puts it into the cart priced at current position price,
+	// the cart is created if it doesn't exist.
+	UpsertCartItem(ctx context.Context, owner model.CartOwner, variantID, storeID, quantity int64) error
+
+	// DeleteCartItem deletes position from the cart.
+	DeleteCartItem(ctx context.Context, owner model.CartOwner, variantID, storeID int64) error
+
+	// DeleteCart deletes the cart along with its items.
+	DeleteCart(ctx context.Context, owner model.CartOwner) error
+
+	// MergeCarts moves items of anonymous cart into the user cart and deletes anonymous cart,
+	// quantities of positions present in both carts are summed up to maxQuantity.
+	MergeCarts(ctx context.Context, token string, userID, maxQuantity int64) error
+
+	// GetPosition returns position of the variant in the store.
+	GetPosition(ctx context.Context, variantID, storeID int64) (model.Position, error)
+
+	// GetExchangeRates returns exchange rates ordered by currency.
+	GetExchangeRates(ctx context.Context) ([]model.ExchangeRate, error)
+}
+
+// OrderStorage provides methods to interact with order storage.
+type OrderStorage interface {
+	CartStorage
+
+	// CreateOrder creates pending order along with its placement transition in a single transaction,
+	// items are snapshotted from positions which must still have expected prices and must not be discontinued,
+	// stock of in-stock positions is reserved and the cart of order user is deleted.
+	CreateOrder(ctx context.Context, order model.Order) (model.Order, error)
+
+	// GetOrder returns order along with its items and transitions.
+	GetOrder(ctx context.Context, orderID int64) (model.Order, error)
+
+	// GetOrders returns page of filtered orders with their items, newest first, and cursor of the next page.
+	GetOrders(ctx context.Context, filter model.OrderFilter, page model.Page) ([]model.Order, string, error)
+
+	// UpdateOrderStatus changes status of the order which is still in from status and records the transition
+	// on behalf of the actor, reserved stock is returned to positions if releaseStock is set.
+	UpdateOrderStatus(ctx context.Context, orderID int64, from, to model.OrderStatus, actorID int64, releaseStock bool) error
+
+	// CreatePayment creates order payment, returns ErrPaymentInProgress if order has payment which is not declined.
+	CreatePayment(ctx context.Context, payment model.Payment) error
+
+	// GetPayment returns payment by provider ID.
+	GetPayment(ctx context.Context, paymentID string) (model.Payment, error)
+
+	// GetOrderPayments returns payments of the order, oldest first.
+	GetOrderPayments(ctx context.Context, orderID int64) ([]model.Payment, error)
+
+	// UpdatePaymentStatus changes status of the payment.
+	UpdatePaymentStatus(ctx context.Context, paymentID string, status model.PaymentStatus) error
+
+	// AddPaymentEvent records provider event of the payment,
+	// ErrDuplicateEvent is returned if the event is already recorded.
+	AddPaymentEvent(ctx context.Context, eventID, paymentID string) error
+
+	// DeletePaymentEvent deletes record of provider event so it can be processed again.
+	DeletePaymentEvent(ctx context.Context, eventID string) error
+}
+
+// OutboxStorage provides methods to interact with outgoing email storage.
+type OutboxStorage interface {
+	// EnqueueEmail adds email to outbox for delivery.
+	EnqueueEmail(ctx context.Context, email model.Email) error
+
+	// ClaimEmails returns unsent emails due for delivery and postpones their next attempt by lease,
+	// so concurrent workers don't pick them up.
+	ClaimEmails(ctx context.Context, limit int, lease time.Duration) ([]model.Email, error)
+
+	// MarkEmailSent marks email as delivered.
+	MarkEmailSent(ctx context.Context, emailID int64) error
+
+	// MarkEmailFailed records failed delivery attempt and schedules the next one at retryAt,
+	// zero retryAt stops delivery attempts.
+	MarkEmailFailed(ctx context.Context, emailID int64, reason string, retryAt time.Time) error
 }
 
 // UserStorage provides methods to interact with user storage.
 type UserStorage interface {
+	OutboxStorage
+
 	// InTx executes action in transaction.
 	InTx(ctx context.Context, action func(s UserStorage) error) error
 
@@ -101,12 +309,108 @@ type UserStorage interface {
 	// GetUserByID returns user from storage by ID.
 	GetUserByID(ctx context.Context, id int64) (model.User, error)
 
-	// SaveToken saves token reference.
-	SaveToken(ctx context.Context, tokenID string, userID int64, expiresAt time.Time) error
+	// SaveToken saves refresh token reference, the token starts new family unless the family exists.
+	SaveToken(ctx context.Context, token model.RefreshToken) error
+
+	// UseToken marks refresh token as rotated out,
+	// ErrNotFound is returned if token is unknown and ErrTokenReused if it has been already used.
+	UseToken(ctx context.Context, tokenID string) error
+
+	// DeleteTokenFamily deletes all tokens of the family that belongs to the user.
+	DeleteTokenFamily(ctx context.Context, userID int64, familyID string) error
+
+	// GetSessions returns active token families of the user starting from the recently used.
+	GetSessions(ctx context.Context, userID int64) ([]model.Session, error)
+
+	// RevokeUserTokens revokes access tokens of the user issued before now.
+	RevokeUserTokens(ctx context.Context, userID int64) error
+
+	// RevokeSessionTokens revokes access tokens issued within the session of the user.
+	RevokeSessionTokens(ctx context.Context, userID int64, sessionID string) error
+
+	// GetTokenRevocations returns revocations of access tokens made after since.
+	GetTokenRevocations(ctx context.Context, since time.Time) ([]model.TokenRevocation, error)
+
+	// CreateAPIKey creates API key, returns ErrUnknownUser if user doesn't exist.
+	CreateAPIKey(ctx context.Context, key model.APIKey) (model.APIKey, error)
+
+	// GetAPIKeys returns API keys of the user starting from the recently created.
+	GetAPIKeys(ctx context.Context, userID int64) ([]model.APIKey, error)
+
+	// DeleteAPIKey deletes API key of the user.
+	DeleteAPIKey(ctx context.Context, userID int64, keyID string) error
+
+	// UseAPIKey returns unexpired API key by hash and records its usage.
+	UseAPIKey(ctx context.Context, keyHash string) (model.APIKey, error)
+
+	// CreateOAuthClient creates OAuth client, returns ErrUnknownUser if owner doesn't exist.
+	CreateOAuthClient(ctx context.Context, client model.OAuthClient) (model.OAuthClient, error)
+
+	// GetOAuthClient returns OAuth client by ID.
+	GetOAuthClient(ctx context.Context, clientID string) (model.OAuthClient, error)
+
+	// GetOAuthClients returns OAuth clients owned by the user starting from the recently created.
+	GetOAuthClients(ctx context.Context, userID int64) ([]model.OAuthClient, error)
+
+	// DeleteOAuthClient deletes OAuth client owned by the user.
+	DeleteOAuthClient(ctx context.Context, userID int64, clientID string) error
+
+	// SaveAuthorizationCode saves authorization code.
+	SaveAuthorizationCode(ctx context.Context, code model.AuthorizationCode) error
+
+	// UseAuthorizationCode deletes authorization code by hash and returns it unless expired.
+	UseAuthorizationCode(ctx context.Context, codeHash string) (model.AuthorizationCode, error)
+
+	// DeleteUserTokens deletes all tokens of the user.
+	DeleteUserTokens(ctx context.Context, userID int64) error
+
+	// UpdateUserPassword changes password hash of the user.
+	UpdateUserPassword(ctx context.Context, userID int64, passwordHash string) error
+
+	// SaveResetToken saves hash of password reset token replacing previous tokens of the user.
+	SaveResetToken(ctx context.Context, tokenHash string, userID int64, expiresAt time.Time) error
+
+	// UseResetToken deletes password reset token by hash and returns ID of its user,
+	// ErrNotFound is returned if token is unknown or expired.
+	UseResetToken(ctx context.Context, tokenHash string) (int64, error)
+
+	// VerifyUserEmail marks email of the user as verified if it's still the user email.
+	VerifyUserEmail(ctx context.Context, userID int64, email string) error
+
+	// MarkVerificationSent records that verification email is sent to the user,
+	// ErrRateLimited is returned if the previous one was sent less than interval ago.
+	MarkVerificationSent(ctx context.Context, userID int64, interval time.Duration) error
+
+	// SetMFASecret starts MFA enrollment of the user with TOTP secret, MFA stays disabled until EnableMFA.
+	SetMFASecret(ctx context.Context, userID int64, secret string) error
+
+	// EnableMFA enables MFA of the enrolled user and replaces recovery codes,
+	// ErrNotFound is returned if user is unknown or not enrolled.
+	EnableMFA(ctx context.Context, userID int64, recoveryCodeHashes []string) error
+
+	// DisableMFA disables MFA of the user and deletes TOTP secret and recovery codes.
+	DisableMFA(ctx context.Context, userID int64) error
+
+	// UseMFAStep records TOTP time step used by the user,
+	// ErrNotFound is returned if the step or later one has been already used.
+	UseMFAStep(ctx context.Context, userID int64, step int64) error
+
+	// UseRecoveryCode deletes recovery code of the user by hash, ErrNotFound is returned if code is unknown.
+	UseRecoveryCode(ctx context.Context, userID int64, codeHash string) error
+
+	// GetLoginFailures returns failed login attempts of subjects, subjects without failures are omitted.
+	GetLoginFailures(ctx context.Context, subjects []string) ([]model.LoginFailures, error)
+
+	// AddLoginFailure records failed login attempt of the subject and returns its failures,
+	// failures recorded before window are forgotten.
+	AddLoginFailure(ctx context.Context, subject string, window time.Duration) (model.LoginFailures, error)
+
+	// DeleteLoginFailures forgets failed login attempts of the subject.
+	DeleteLoginFailures(ctx context.Context, subject string) error
 
-	// DeleteToken deletes token.
-	DeleteToken(ctx context.Context, tokenID string) error
+	// GetRoles returns roles with their permissions.
+	GetRoles(ctx context.Context) ([]model.Role, error)
 
-	// UpdateUserPermissions updates user permissions.
-	UpdateUserPermissions(ctx context.Context, user model.User) error
+	// SetUserRoles replaces roles of the user.
+	SetUserRoles(ctx context.Context, userID int64, roles []string) error
 }
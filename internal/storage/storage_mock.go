@@ -36,18 +36,19 @@ func (m *MockStorage) EXPECT() *MockStorageMockRecorder {
 }
 
 // GetCategories mocks base method
-func (m *MockStorage) GetCategories(ctx context.Context) ([]model.Category, error) {
+func (m *MockStorage) GetCategories(ctx context.Context, page model.Page) ([]model.Category, string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetCategories", ctx)
+	ret := m.ctrl.Call(m, "GetCategories", ctx, page)
 	ret0, _ := ret[0].([]model.Category)
-	ret1, _ := ret[1].(error)
-	return ret0, ret1
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
 }
 
 // GetCategories indicates an expected call of GetCategories
-func (mr *MockStorageMockRecorder) GetCategories(ctx interface{}) *gomock.Call {
+func (mr *MockStorageMockRecorder) GetCategories(ctx, page interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCategories", reflect.TypeOf((*MockStorage)(nil).GetCategories), ctx)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCategories", reflect.TypeOf((*MockStorage)(nil).GetCategories), ctx, page)
 }
 
 // GetCategory mocks base method
@@ -65,6 +66,21 @@ func (mr *MockStorageMockRecorder) GetCategory(ctx, categoryID interface{}) *gom
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCategory", reflect.TypeOf((*MockStorage)(nil).GetCategory), ctx, categoryID)
 }
 
+// GetCategoryTree mocks base method
+func (m *MockStorage) GetCategoryTree(ctx context.Context, categoryID int64) ([]model.Category, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCategoryTree", ctx, categoryID)
+	ret0, _ := ret[0].([]model.Category)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCategoryTree indicates an expected call of GetCategoryTree
+func (mr *MockStorageMockRecorder) GetCategoryTree(ctx, categoryID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCategoryTree", reflect.TypeOf((*MockStorage)(nil).GetCategoryTree), ctx, categoryID)
+}
+
 // CreateCategory mocks base method
 func (m *MockStorage) CreateCategory(ctx context.Context, category model.Category) (model.Category, error) {
 	m.ctrl.T.Helper()
@@ -109,18 +125,19 @@ func (mr *MockStorageMockRecorder) DeleteCategory(ctx, categoryID interface{}) *
 }
 
 // GetStores mocks base method
-func (m *MockStorage) GetStores(ctx context.Context) ([]model.Store, error) {
+func (m *MockStorage) GetStores(ctx context.Context, page model.Page) ([]model.Store, string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetStores", ctx)
+	ret := m.ctrl.Call(m, "GetStores", ctx, page)
 	ret0, _ := ret[0].([]model.Store)
-	ret1, _ := ret[1].(error)
-	return ret0, ret1
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
 }
 
 // GetStores indicates an expected call of GetStores
-func (mr *MockStorageMockRecorder) GetStores(ctx interface{}) *gomock.Call {
+func (mr *MockStorageMockRecorder) GetStores(ctx, page interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetStores", reflect.TypeOf((*MockStorage)(nil).GetStores), ctx)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetStores", reflect.TypeOf((*MockStorage)(nil).GetStores), ctx, page)
 }
 
 // GetStore mocks base method
@@ -181,19 +198,95 @@ func (mr *MockStorageMockRecorder) DeleteStore(ctx, storeID interface{}) *gomock
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteStore", reflect.TypeOf((*MockStorage)(nil).DeleteStore), ctx, storeID)
 }
 
-// GetProducts mocks base method
-func (m *MockStorage) GetProducts(ctx context.Context, categoryID int64) ([]model.Product, error) {
+// GetStoreMembers mocks base method
+func (m *MockStorage) GetStoreMembers(ctx context.Context, storeID int64) ([]model.StoreMember, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetProducts", ctx, categoryID)
-	ret0, _ := ret[0].([]model.Product)
+	ret := m.ctrl.Call(m, "GetStoreMembers", ctx, storeID)
+	ret0, _ := ret[0].([]model.StoreMember)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetStoreMembers indicates an expected call of GetStoreMembers
+func (mr *MockStorageMockRecorder) GetStoreMembers(ctx, storeID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetStoreMembers", reflect.TypeOf((*MockStorage)(nil).GetStoreMembers), ctx, storeID)
+}
+
+// GetStoreMember mocks base method
+func (m *MockStorage) GetStoreMember(ctx context.Context, storeID, userID int64) (model.StoreMember, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetStoreMember", ctx, storeID, userID)
+	ret0, _ := ret[0].(model.StoreMember)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetStoreMember indicates an expected call of GetStoreMember
+func (mr *MockStorageMockRecorder) GetStoreMember(ctx, storeID, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetStoreMember", reflect.TypeOf((*MockStorage)(nil).GetStoreMember), ctx, storeID, userID)
+}
+
+// SetStoreMember mocks base method
+func (m *MockStorage) SetStoreMember(ctx context.Context, storeID int64, email string, role model.StoreRole) (model.StoreMember, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetStoreMember", ctx, storeID, email, role)
+	ret0, _ := ret[0].(model.StoreMember)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
+// SetStoreMember indicates an expected call of SetStoreMember
+func (mr *MockStorageMockRecorder) SetStoreMember(ctx, storeID, email, role interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetStoreMember", reflect.TypeOf((*MockStorage)(nil).SetStoreMember), ctx, storeID, email, role)
+}
+
+// DeleteStoreMember mocks base method
+func (m *MockStorage) DeleteStoreMember(ctx context.Context, storeID, userID int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteStoreMember", ctx, storeID, userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteStoreMember indicates an expected call of DeleteStoreMember
+func (mr *MockStorageMockRecorder) DeleteStoreMember(ctx, storeID, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteStoreMember", reflect.TypeOf((*MockStorage)(nil).DeleteStoreMember), ctx, storeID, userID)
+}
+
+// GetProducts mocks base method
+func (m *MockStorage) GetProducts(ctx context.Context, categoryID int64, filter model.ProductFilter, page model.Page) ([]model.Product, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetProducts", ctx, categoryID, filter, page)
+	ret0, _ := ret[0].([]model.Product)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
 // GetProducts indicates an expected call of GetProducts
-func (mr *MockStorageMockRecorder) GetProducts(ctx, categoryID interface{}) *gomock.Call {
+func (mr *MockStorageMockRecorder) GetProducts(ctx, categoryID, filter, page interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetProducts", reflect.TypeOf((*MockStorage)(nil).GetProducts), ctx, categoryID, filter, page)
+}
+
+// SearchProducts mocks base method
+func (m *MockStorage) SearchProducts(ctx context.Context, search model.ProductSearch, page model.Page) ([]model.ProductSearchResult, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SearchProducts", ctx, search, page)
+	ret0, _ := ret[0].([]model.ProductSearchResult)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// SearchProducts indicates an expected call of SearchProducts
+func (mr *MockStorageMockRecorder) SearchProducts(ctx, search, page interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetProducts", reflect.TypeOf((*MockStorage)(nil).GetProducts), ctx, categoryID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SearchProducts", reflect.TypeOf((*MockStorage)(nil).SearchProducts), ctx, search, page)
 }
 
 // GetProduct mocks base method
@@ -254,184 +347,1371 @@ func (mr *MockStorageMockRecorder) DeleteProduct(ctx, productID interface{}) *go
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteProduct", reflect.TypeOf((*MockStorage)(nil).DeleteProduct), ctx, productID)
 }
 
-// GetStorePositions mocks base method
-func (m *MockStorage) GetStorePositions(ctx context.Context, storeID int64) ([]model.Position, error) {
+// GetProductImages mocks base method
+func (m *MockStorage) GetProductImages(ctx context.Context, productIDs []int64) ([]model.Image, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetStorePositions", ctx, storeID)
-	ret0, _ := ret[0].([]model.Position)
+	ret := m.ctrl.Call(m, "GetProductImages", ctx, productIDs)
+	ret0, _ := ret[0].([]model.Image)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetProductImages indicates an expected call of GetProductImages
+func (mr *MockStorageMockRecorder) GetProductImages(ctx, productIDs interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetProductImages", reflect.TypeOf((*MockStorage)(nil).GetProductImages), ctx, productIDs)
+}
+
+// CreateProductImage mocks base method
+func (m *MockStorage) CreateProductImage(ctx context.Context, image model.Image) (model.Image, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateProductImage", ctx, image)
+	ret0, _ := ret[0].(model.Image)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateProductImage indicates an expected call of CreateProductImage
+func (mr *MockStorageMockRecorder) CreateProductImage(ctx, image interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateProductImage", reflect.TypeOf((*MockStorage)(nil).CreateProductImage), ctx, image)
+}
+
+// UpdateProductImagePositions mocks base method
+func (m *MockStorage) UpdateProductImagePositions(ctx context.Context, productID int64, imageIDs []int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateProductImagePositions", ctx, productID, imageIDs)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateProductImagePositions indicates an expected call of UpdateProductImagePositions
+func (mr *MockStorageMockRecorder) UpdateProductImagePositions(ctx, productID, imageIDs interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateProductImagePositions", reflect.TypeOf((*MockStorage)(nil).UpdateProductImagePositions), ctx, productID, imageIDs)
+}
+
+// DeleteProductImage mocks base method
+func (m *MockStorage) DeleteProductImage(ctx context.Context, productID, imageID int64) (model.Image, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteProductImage", ctx, productID, imageID)
+	ret0, _ := ret[0].(model.Image)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteProductImage indicates an expected call of DeleteProductImage
+func (mr *MockStorageMockRecorder) DeleteProductImage(ctx, productID, imageID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteProductImage", reflect.TypeOf((*MockStorage)(nil).DeleteProductImage), ctx, productID, imageID)
+}
+
+// GetProductVariants mocks base method
+func (m *MockStorage) GetProductVariants(ctx context.Context, productID int64) ([]model.Variant, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetProductVariants", ctx, productID)
+	ret0, _ := ret[0].([]model.Variant)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetProductVariants indicates an expected call of GetProductVariants
+func (mr *MockStorageMockRecorder) GetProductVariants(ctx, productID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetProductVariants", reflect.TypeOf((*MockStorage)(nil).GetProductVariants), ctx, productID)
+}
+
+// CreateVariant mocks base method
+func (m *MockStorage) CreateVariant(ctx context.Context, variant model.Variant) (model.Variant, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateVariant", ctx, variant)
+	ret0, _ := ret[0].(model.Variant)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
+// CreateVariant indicates an expected call of CreateVariant
+func (mr *MockStorageMockRecorder) CreateVariant(ctx, variant interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateVariant", reflect.TypeOf((*MockStorage)(nil).CreateVariant), ctx, variant)
+}
+
+// UpdateVariant mocks base method
+func (m *MockStorage) UpdateVariant(ctx context.Context, variant model.Variant) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateVariant", ctx, variant)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateVariant indicates an expected call of UpdateVariant
+func (mr *MockStorageMockRecorder) UpdateVariant(ctx, variant interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateVariant", reflect.TypeOf((*MockStorage)(nil).UpdateVariant), ctx, variant)
+}
+
+// DeleteVariant mocks base method
+func (m *MockStorage) DeleteVariant(ctx context.Context, productID, variantID int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteVariant", ctx, productID, variantID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteVariant indicates an expected call of DeleteVariant
+func (mr *MockStorageMockRecorder) DeleteVariant(ctx, productID, variantID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteVariant", reflect.TypeOf((*MockStorage)(nil).DeleteVariant), ctx, productID, variantID)
+}
+
+// GetStorePositions mocks base method
+func (m *MockStorage) GetStorePositions(ctx context.Context, storeID int64, page model.Page) ([]model.Position, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetStorePositions", ctx, storeID, page)
+	ret0, _ := ret[0].([]model.Position)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
 // GetStorePositions indicates an expected call of GetStorePositions
-func (mr *MockStorageMockRecorder) GetStorePositions(ctx, storeID interface{}) *gomock.Call {
+func (mr *MockStorageMockRecorder) GetStorePositions(ctx, storeID, page interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetStorePositions", reflect.TypeOf((*MockStorage)(nil).GetStorePositions), ctx, storeID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetStorePositions", reflect.TypeOf((*MockStorage)(nil).GetStorePositions), ctx, storeID, page)
 }
 
 // GetProductPositions mocks base method
-func (m *MockStorage) GetProductPositions(ctx context.Context, productID int64) ([]model.Position, error) {
+func (m *MockStorage) GetProductPositions(ctx context.Context, productID int64, filter model.PositionFilter, page model.Page) ([]model.Position, string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetProductPositions", ctx, productID)
+	ret := m.ctrl.Call(m, "GetProductPositions", ctx, productID, filter, page)
 	ret0, _ := ret[0].([]model.Position)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// GetProductPositions indicates an expected call of GetProductPositions
+func (mr *MockStorageMockRecorder) GetProductPositions(ctx, productID, filter, page interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetProductPositions", reflect.TypeOf((*MockStorage)(nil).GetProductPositions), ctx, productID, filter, page)
+}
+
+// GetExchangeRates mocks base method
+func (m *MockStorage) GetExchangeRates(ctx context.Context) ([]model.ExchangeRate, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetExchangeRates", ctx)
+	ret0, _ := ret[0].([]model.ExchangeRate)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetProductPositions indicates an expected call of GetProductPositions
-func (mr *MockStorageMockRecorder) GetProductPositions(ctx, productID interface{}) *gomock.Call {
+// GetExchangeRates indicates an expected call of GetExchangeRates
+func (mr *MockStorageMockRecorder) GetExchangeRates(ctx interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetProductPositions", reflect.TypeOf((*MockStorage)(nil).GetProductPositions), ctx, productID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetExchangeRates", reflect.TypeOf((*MockStorage)(nil).GetExchangeRates), ctx)
 }
 
-// UpsertPosition mocks base method
-func (m *MockStorage) UpsertPosition(ctx context.Context, position model.Position) error {
+// UpsertExchangeRates mocks base method
+func (m *MockStorage) UpsertExchangeRates(ctx context.Context, rates []model.ExchangeRate) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "UpsertPosition", ctx, position)
+	ret := m.ctrl.Call(m, "UpsertExchangeRates", ctx, rates)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// UpsertPosition indicates an expected call of UpsertPosition
-func (mr *MockStorageMockRecorder) UpsertPosition(ctx, position interface{}) *gomock.Call {
+// UpsertExchangeRates indicates an expected call of UpsertExchangeRates
+func (mr *MockStorageMockRecorder) UpsertExchangeRates(ctx, rates interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpsertPosition", reflect.TypeOf((*MockStorage)(nil).UpsertPosition), ctx, position)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpsertExchangeRates", reflect.TypeOf((*MockStorage)(nil).UpsertExchangeRates), ctx, rates)
 }
 
-// DeletePosition mocks base method
-func (m *MockStorage) DeletePosition(ctx context.Context, productID, storeID int64) error {
+// DeleteExchangeRate mocks base method
+func (m *MockStorage) DeleteExchangeRate(ctx context.Context, currency string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeletePosition", ctx, productID, storeID)
+	ret := m.ctrl.Call(m, "DeleteExchangeRate", ctx, currency)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// DeletePosition indicates an expected call of DeletePosition
-func (mr *MockStorageMockRecorder) DeletePosition(ctx, productID, storeID interface{}) *gomock.Call {
+// DeleteExchangeRate indicates an expected call of DeleteExchangeRate
+func (mr *MockStorageMockRecorder) DeleteExchangeRate(ctx, currency interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeletePosition", reflect.TypeOf((*MockStorage)(nil).DeletePosition), ctx, productID, storeID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteExchangeRate", reflect.TypeOf((*MockStorage)(nil).DeleteExchangeRate), ctx, currency)
 }
 
-// MockUserStorage is a mock of UserStorage interface
-type MockUserStorage struct {
-	ctrl     *gomock.Controller
-	recorder *MockUserStorageMockRecorder
+// GetPriceSummaries mocks base method
+func (m *MockStorage) GetPriceSummaries(ctx context.Context, productIDs []int64) ([]model.PriceSummary, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPriceSummaries", ctx, productIDs)
+	ret0, _ := ret[0].([]model.PriceSummary)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
-// MockUserStorageMockRecorder is the mock recorder for MockUserStorage
-type MockUserStorageMockRecorder struct {
-	mock *MockUserStorage
+// GetPriceSummaries indicates an expected call of GetPriceSummaries
+func (mr *MockStorageMockRecorder) GetPriceSummaries(ctx, productIDs interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPriceSummaries", reflect.TypeOf((*MockStorage)(nil).GetPriceSummaries), ctx, productIDs)
 }
 
-// NewMockUserStorage creates a new mock instance
-func NewMockUserStorage(ctrl *gomock.Controller) *MockUserStorage {
-	mock := &MockUserStorage{ctrl: ctrl}
-	mock.recorder = &MockUserStorageMockRecorder{mock}
-	return mock
+// UpsertPosition mocks base method
+func (m *MockStorage) UpsertPosition(ctx context.Context, position model.Position, userID int64) (model.Position, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpsertPosition", ctx, position, userID)
+	ret0, _ := ret[0].(model.Position)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
-// EXPECT returns an object that allows the caller to indicate expected use
-func (m *MockUserStorage) EXPECT() *MockUserStorageMockRecorder {
-	return m.recorder
+// UpsertPosition indicates an expected call of UpsertPosition
+func (mr *MockStorageMockRecorder) UpsertPosition(ctx, position, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpsertPosition", reflect.TypeOf((*MockStorage)(nil).UpsertPosition), ctx, position, userID)
 }
 
-// InTx mocks base method
-func (m *MockUserStorage) InTx(ctx context.Context, action func(UserStorage) error) error {
+// AdjustPositionStock mocks base method
+func (m *MockStorage) AdjustPositionStock(ctx context.Context, variantID, storeID, delta int64) (model.Position, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "InTx", ctx, action)
+	ret := m.ctrl.Call(m, "AdjustPositionStock", ctx, variantID, storeID, delta)
+	ret0, _ := ret[0].(model.Position)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AdjustPositionStock indicates an expected call of AdjustPositionStock
+func (mr *MockStorageMockRecorder) AdjustPositionStock(ctx, variantID, storeID, delta interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AdjustPositionStock", reflect.TypeOf((*MockStorage)(nil).AdjustPositionStock), ctx, variantID, storeID, delta)
+}
+
+// DeletePosition mocks base method
+func (m *MockStorage) DeletePosition(ctx context.Context, variantID, storeID int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeletePosition", ctx, variantID, storeID)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// InTx indicates an expected call of InTx
-func (mr *MockUserStorageMockRecorder) InTx(ctx, action interface{}) *gomock.Call {
+// DeletePosition indicates an expected call of DeletePosition
+func (mr *MockStorageMockRecorder) DeletePosition(ctx, variantID, storeID interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InTx", reflect.TypeOf((*MockUserStorage)(nil).InTx), ctx, action)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeletePosition", reflect.TypeOf((*MockStorage)(nil).DeletePosition), ctx, variantID, storeID)
 }
 
-// CreateUser mocks base method
-func (m *MockUserStorage) CreateUser(ctx context.Context, user model.User) (model.User, error) {
+// GetPriceHistory mocks base method
+func (m *MockStorage) GetPriceHistory(ctx context.Context, productID int64, filter model.PriceHistoryFilter) ([]model.PriceChange, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateUser", ctx, user)
-	ret0, _ := ret[0].(model.User)
+	ret := m.ctrl.Call(m, "GetPriceHistory", ctx, productID, filter)
+	ret0, _ := ret[0].([]model.PriceChange)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// CreateUser indicates an expected call of CreateUser
-func (mr *MockUserStorageMockRecorder) CreateUser(ctx, user interface{}) *gomock.Call {
+// GetPriceHistory indicates an expected call of GetPriceHistory
+func (mr *MockStorageMockRecorder) GetPriceHistory(ctx, productID, filter interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUser", reflect.TypeOf((*MockUserStorage)(nil).CreateUser), ctx, user)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPriceHistory", reflect.TypeOf((*MockStorage)(nil).GetPriceHistory), ctx, productID, filter)
 }
 
-// GetUserByEmail mocks base method
-func (m *MockUserStorage) GetUserByEmail(ctx context.Context, email string) (model.User, error) {
+// GetDailyPrices mocks base method
+func (m *MockStorage) GetDailyPrices(ctx context.Context, productID int64, filter model.PriceHistoryFilter) ([]model.PriceBucket, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetUserByEmail", ctx, email)
-	ret0, _ := ret[0].(model.User)
+	ret := m.ctrl.Call(m, "GetDailyPrices", ctx, productID, filter)
+	ret0, _ := ret[0].([]model.PriceBucket)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetUserByEmail indicates an expected call of GetUserByEmail
-func (mr *MockUserStorageMockRecorder) GetUserByEmail(ctx, email interface{}) *gomock.Call {
+// GetDailyPrices indicates an expected call of GetDailyPrices
+func (mr *MockStorageMockRecorder) GetDailyPrices(ctx, productID, filter interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserByEmail", reflect.TypeOf((*MockUserStorage)(nil).GetUserByEmail), ctx, email)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDailyPrices", reflect.TypeOf((*MockStorage)(nil).GetDailyPrices), ctx, productID, filter)
 }
 
-// GetUserByID mocks base method
-func (m *MockUserStorage) GetUserByID(ctx context.Context, id int64) (model.User, error) {
+// MockCartStorage is a mock of CartStorage interface
+type MockCartStorage struct {
+	ctrl     *gomock.Controller
+	recorder *MockCartStorageMockRecorder
+}
+
+// MockCartStorageMockRecorder is the mock recorder for MockCartStorage
+type MockCartStorageMockRecorder struct {
+	mock *MockCartStorage
+}
+
+// NewMockCartStorage creates a new mock instance
+func NewMockCartStorage(ctrl *gomock.Controller) *MockCartStorage {
+	mock := &MockCartStorage{ctrl: ctrl}
+	mock.recorder = &MockCartStorageMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockCartStorage) EXPECT() *MockCartStorageMockRecorder {
+	return m.recorder
+}
+
+// GetCartItems mocks base method
+func (m *MockCartStorage) GetCartItems(ctx context.Context, owner model.CartOwner) ([]model.CartItem, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetUserByID", ctx, id)
-	ret0, _ := ret[0].(model.User)
+	ret := m.ctrl.Call(m, "GetCartItems", ctx, owner)
+	ret0, _ := ret[0].([]model.CartItem)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetUserByID indicates an expected call of GetUserByID
-func (mr *MockUserStorageMockRecorder) GetUserByID(ctx, id interface{}) *gomock.Call {
+// GetCartItems indicates an expected call of GetCartItems
+func (mr *MockCartStorageMockRecorder) GetCartItems(ctx, owner interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserByID", reflect.TypeOf((*MockUserStorage)(nil).GetUserByID), ctx, id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCartItems", reflect.TypeOf((*MockCartStorage)(nil).GetCartItems), ctx, owner)
 }
 
-// SaveToken mocks base method
-func (m *MockUserStorage) SaveToken(ctx context.Context, tokenID string, userID int64, expiresAt time.Time) error {
+// UpsertCartItem mocks base method
+func (m *MockCartStorage) UpsertCartItem(ctx context.Context, owner model.CartOwner, variantID, storeID, quantity int64) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SaveToken", ctx, tokenID, userID, expiresAt)
+	ret := m.ctrl.Call(m, "UpsertCartItem", ctx, owner, variantID, storeID, quantity)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// SaveToken indicates an expected call of SaveToken
-func (mr *MockUserStorageMockRecorder) SaveToken(ctx, tokenID, userID, expiresAt interface{}) *gomock.Call {
+// UpsertCartItem indicates an expected call of UpsertCartItem
+func (mr *MockCartStorageMockRecorder) UpsertCartItem(ctx, owner, variantID, storeID, quantity interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpsertCartItem", reflect.TypeOf((*MockCartStorage)(nil).UpsertCartItem), ctx, owner, variantID, storeID, quantity)
+}
+
+// DeleteCartItem mocks base method
+func (m *MockCartStorage) DeleteCartItem(ctx context.Context, owner model.CartOwner, variantID, storeID int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteCartItem", ctx, owner, variantID, storeID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteCartItem indicates an expected call of DeleteCartItem
+func (mr *MockCartStorageMockRecorder) DeleteCartItem(ctx, owner, variantID, storeID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteCartItem", reflect.TypeOf((*MockCartStorage)(nil).DeleteCartItem), ctx, owner, variantID, storeID)
+}
+
+// DeleteCart mocks base method
+func (m *MockCartStorage) DeleteCart(ctx context.Context, owner model.CartOwner) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteCart", ctx, owner)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteCart indicates an expected call of DeleteCart
+func (mr *MockCartStorageMockRecorder) DeleteCart(ctx, owner interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveToken", reflect.TypeOf((*MockUserStorage)(nil).SaveToken), ctx, tokenID, userID, expiresAt)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteCart", reflect.TypeOf((*MockCartStorage)(nil).DeleteCart), ctx, owner)
 }
 
-// DeleteToken mocks base method
-func (m *MockUserStorage) DeleteToken(ctx context.Context, tokenID string) error {
+// MergeCarts mocks base method
+func (m *MockCartStorage) MergeCarts(ctx context.Context, token string, userID, maxQuantity int64) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeleteToken", ctx, tokenID)
+	ret := m.ctrl.Call(m, "MergeCarts", ctx, token, userID, maxQuantity)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// DeleteToken indicates an expected call of DeleteToken
-func (mr *MockUserStorageMockRecorder) DeleteToken(ctx, tokenID interface{}) *gomock.Call {
+// MergeCarts indicates an expected call of MergeCarts
+func (mr *MockCartStorageMockRecorder) MergeCarts(ctx, token, userID, maxQuantity interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MergeCarts", reflect.TypeOf((*MockCartStorage)(nil).MergeCarts), ctx, token, userID, maxQuantity)
+}
+
+// GetPosition mocks base method
+func (m *MockCartStorage) GetPosition(ctx context.Context, variantID, storeID int64) (model.Position, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPosition", ctx, variantID, storeID)
+	ret0, _ := ret[0].(model.Position)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPosition indicates an expected call of GetPosition
+func (mr *MockCartStorageMockRecorder) GetPosition(ctx, variantID, storeID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPosition", reflect.TypeOf((*MockCartStorage)(nil).GetPosition), ctx, variantID, storeID)
+}
+
+// GetExchangeRates mocks base method
+func (m *MockCartStorage) GetExchangeRates(ctx context.Context) ([]model.ExchangeRate, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetExchangeRates", ctx)
+	ret0, _ := ret[0].([]model.ExchangeRate)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetExchangeRates indicates an expected call of GetExchangeRates
+func (mr *MockCartStorageMockRecorder) GetExchangeRates(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetExchangeRates", reflect.TypeOf((*MockCartStorage)(nil).GetExchangeRates), ctx)
+}
+
+// MockOrderStorage is a mock of OrderStorage interface
+type MockOrderStorage struct {
+	ctrl     *gomock.Controller
+	recorder *MockOrderStorageMockRecorder
+}
+
+// MockOrderStorageMockRecorder is the mock recorder for MockOrderStorage
+type MockOrderStorageMockRecorder struct {
+	mock *MockOrderStorage
+}
+
+// NewMockOrderStorage creates a new mock instance
+func NewMockOrderStorage(ctrl *gomock.Controller) *MockOrderStorage {
+	mock := &MockOrderStorage{ctrl: ctrl}
+	mock.recorder = &MockOrderStorageMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockOrderStorage) EXPECT() *MockOrderStorageMockRecorder {
+	return m.recorder
+}
+
+// GetCartItems mocks base method
+func (m *MockOrderStorage) GetCartItems(ctx context.Context, owner model.CartOwner) ([]model.CartItem, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCartItems", ctx, owner)
+	ret0, _ := ret[0].([]model.CartItem)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCartItems indicates an expected call of GetCartItems
+func (mr *MockOrderStorageMockRecorder) GetCartItems(ctx, owner interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCartItems", reflect.TypeOf((*MockOrderStorage)(nil).GetCartItems), ctx, owner)
+}
+
+// UpsertCartItem mocks base method
+func (m *MockOrderStorage) UpsertCartItem(ctx context.Context, owner model.CartOwner, variantID, storeID, quantity int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpsertCartItem", ctx, owner, variantID, storeID, quantity)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpsertCartItem indicates an expected call of UpsertCartItem
+func (mr *MockOrderStorageMockRecorder) UpsertCartItem(ctx, owner, variantID, storeID, quantity interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpsertCartItem", reflect.TypeOf((*MockOrderStorage)(nil).UpsertCartItem), ctx, owner, variantID, storeID, quantity)
+}
+
+// DeleteCartItem mocks base method
+func (m *MockOrderStorage) DeleteCartItem(ctx context.Context, owner model.CartOwner, variantID, storeID int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteCartItem", ctx, owner, variantID, storeID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteCartItem indicates an expected call of DeleteCartItem
+func (mr *MockOrderStorageMockRecorder) DeleteCartItem(ctx, owner, variantID, storeID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteCartItem", reflect.TypeOf((*MockOrderStorage)(nil).DeleteCartItem), ctx, owner, variantID, storeID)
+}
+
+// DeleteCart mocks base method
+func (m *MockOrderStorage) DeleteCart(ctx context.Context, owner model.CartOwner) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteCart", ctx, owner)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteCart indicates an expected call of DeleteCart
+func (mr *MockOrderStorageMockRecorder) DeleteCart(ctx, owner interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteCart", reflect.TypeOf((*MockOrderStorage)(nil).DeleteCart), ctx, owner)
+}
+
+// MergeCarts mocks base method
+func (m *MockOrderStorage) MergeCarts(ctx context.Context, token string, userID, maxQuantity int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MergeCarts", ctx, token, userID, maxQuantity)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MergeCarts indicates an expected call of MergeCarts
+func (mr *MockOrderStorageMockRecorder) MergeCarts(ctx, token, userID, maxQuantity interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MergeCarts", reflect.TypeOf((*MockOrderStorage)(nil).MergeCarts), ctx, token, userID, maxQuantity)
+}
+
+// GetPosition mocks base method
+func (m *MockOrderStorage) GetPosition(ctx context.Context, variantID, storeID int64) (model.Position, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPosition", ctx, variantID, storeID)
+	ret0, _ := ret[0].(model.Position)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPosition indicates an expected call of GetPosition
+func (mr *MockOrderStorageMockRecorder) GetPosition(ctx, variantID, storeID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPosition", reflect.TypeOf((*MockOrderStorage)(nil).GetPosition), ctx, variantID, storeID)
+}
+
+// GetExchangeRates mocks base method
+func (m *MockOrderStorage) GetExchangeRates(ctx context.Context) ([]model.ExchangeRate, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetExchangeRates", ctx)
+	ret0, _ := ret[0].([]model.ExchangeRate)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetExchangeRates indicates an expected call of GetExchangeRates
+func (mr *MockOrderStorageMockRecorder) GetExchangeRates(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetExchangeRates", reflect.TypeOf((*MockOrderStorage)(nil).GetExchangeRates), ctx)
+}
+
+// CreateOrder mocks base method
+func (m *MockOrderStorage) CreateOrder(ctx context.Context, order model.Order) (model.Order, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateOrder", ctx, order)
+	ret0, _ := ret[0].(model.Order)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateOrder indicates an expected call of CreateOrder
+func (mr *MockOrderStorageMockRecorder) CreateOrder(ctx, order interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateOrder", reflect.TypeOf((*MockOrderStorage)(nil).CreateOrder), ctx, order)
+}
+
+// GetOrder mocks base method
+func (m *MockOrderStorage) GetOrder(ctx context.Context, orderID int64) (model.Order, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOrder", ctx, orderID)
+	ret0, _ := ret[0].(model.Order)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOrder indicates an expected call of GetOrder
+func (mr *MockOrderStorageMockRecorder) GetOrder(ctx, orderID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrder", reflect.TypeOf((*MockOrderStorage)(nil).GetOrder), ctx, orderID)
+}
+
+// GetOrders mocks base method
+func (m *MockOrderStorage) GetOrders(ctx context.Context, filter model.OrderFilter, page model.Page) ([]model.Order, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOrders", ctx, filter, page)
+	ret0, _ := ret[0].([]model.Order)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// GetOrders indicates an expected call of GetOrders
+func (mr *MockOrderStorageMockRecorder) GetOrders(ctx, filter, page interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrders", reflect.TypeOf((*MockOrderStorage)(nil).GetOrders), ctx, filter, page)
+}
+
+// UpdateOrderStatus mocks base method
+func (m *MockOrderStorage) UpdateOrderStatus(ctx context.Context, orderID int64, from, to model.OrderStatus, actorID int64, releaseStock bool) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateOrderStatus", ctx, orderID, from, to, actorID, releaseStock)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateOrderStatus indicates an expected call of UpdateOrderStatus
+func (mr *MockOrderStorageMockRecorder) UpdateOrderStatus(ctx, orderID, from, to, actorID, releaseStock interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateOrderStatus", reflect.TypeOf((*MockOrderStorage)(nil).UpdateOrderStatus), ctx, orderID, from, to, actorID, releaseStock)
+}
+
+// CreatePayment mocks base method
+func (m *MockOrderStorage) CreatePayment(ctx context.Context, payment model.Payment) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreatePayment", ctx, payment)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreatePayment indicates an expected call of CreatePayment
+func (mr *MockOrderStorageMockRecorder) CreatePayment(ctx, payment interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreatePayment", reflect.TypeOf((*MockOrderStorage)(nil).CreatePayment), ctx, payment)
+}
+
+// GetPayment mocks base method
+func (m *MockOrderStorage) GetPayment(ctx context.Context, paymentID string) (model.Payment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPayment", ctx, paymentID)
+	ret0, _ := ret[0].(model.Payment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPayment indicates an expected call of GetPayment
+func (mr *MockOrderStorageMockRecorder) GetPayment(ctx, paymentID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPayment", reflect.TypeOf((*MockOrderStorage)(nil).GetPayment), ctx, paymentID)
+}
+
+// GetOrderPayments mocks base method
+func (m *MockOrderStorage) GetOrderPayments(ctx context.Context, orderID int64) ([]model.Payment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOrderPayments", ctx, orderID)
+	ret0, _ := ret[0].([]model.Payment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOrderPayments indicates an expected call of GetOrderPayments
+func (mr *MockOrderStorageMockRecorder) GetOrderPayments(ctx, orderID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrderPayments", reflect.TypeOf((*MockOrderStorage)(nil).GetOrderPayments), ctx, orderID)
+}
+
+// UpdatePaymentStatus mocks base method
+func (m *MockOrderStorage) UpdatePaymentStatus(ctx context.Context, paymentID string, status model.PaymentStatus) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdatePaymentStatus", ctx, paymentID, status)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdatePaymentStatus indicates an expected call of UpdatePaymentStatus
+func (mr *MockOrderStorageMockRecorder) UpdatePaymentStatus(ctx, paymentID, status interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatePaymentStatus", reflect.TypeOf((*MockOrderStorage)(nil).UpdatePaymentStatus), ctx, paymentID, status)
+}
+
+// AddPaymentEvent mocks base method
+func (m *MockOrderStorage) AddPaymentEvent(ctx context.Context, eventID, paymentID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddPaymentEvent", ctx, eventID, paymentID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddPaymentEvent indicates an expected call of AddPaymentEvent
+func (mr *MockOrderStorageMockRecorder) AddPaymentEvent(ctx, eventID, paymentID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddPaymentEvent", reflect.TypeOf((*MockOrderStorage)(nil).AddPaymentEvent), ctx, eventID, paymentID)
+}
+
+// DeletePaymentEvent mocks base method
+func (m *MockOrderStorage) DeletePaymentEvent(ctx context.Context, eventID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeletePaymentEvent", ctx, eventID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeletePaymentEvent indicates an expected call of DeletePaymentEvent
+func (mr *MockOrderStorageMockRecorder) DeletePaymentEvent(ctx, eventID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeletePaymentEvent", reflect.TypeOf((*MockOrderStorage)(nil).DeletePaymentEvent), ctx, eventID)
+}
+
+// MockOutboxStorage is a mock of OutboxStorage interface
+type MockOutboxStorage struct {
+	ctrl     *gomock.Controller
+	recorder *MockOutboxStorageMockRecorder
+}
+
+// MockOutboxStorageMockRecorder is the mock recorder for MockOutboxStorage
+type MockOutboxStorageMockRecorder struct {
+	mock *MockOutboxStorage
+}
+
+// NewMockOutboxStorage creates a new mock instance
+func NewMockOutboxStorage(ctrl *gomock.Controller) *MockOutboxStorage {
+	mock := &MockOutboxStorage{ctrl: ctrl}
+	mock.recorder = &MockOutboxStorageMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockOutboxStorage) EXPECT() *MockOutboxStorageMockRecorder {
+	return m.recorder
+}
+
+// EnqueueEmail mocks base method
+func (m *MockOutboxStorage) EnqueueEmail(ctx context.Context, email model.Email) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnqueueEmail", ctx, email)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// EnqueueEmail indicates an expected call of EnqueueEmail
+func (mr *MockOutboxStorageMockRecorder) EnqueueEmail(ctx, email interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnqueueEmail", reflect.TypeOf((*MockOutboxStorage)(nil).EnqueueEmail), ctx, email)
+}
+
+// ClaimEmails mocks base method
+func (m *MockOutboxStorage) ClaimEmails(ctx context.Context, limit int, lease time.Duration) ([]model.Email, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClaimEmails", ctx, limit, lease)
+	ret0, _ := ret[0].([]model.Email)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ClaimEmails indicates an expected call of ClaimEmails
+func (mr *MockOutboxStorageMockRecorder) ClaimEmails(ctx, limit, lease interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClaimEmails", reflect.TypeOf((*MockOutboxStorage)(nil).ClaimEmails), ctx, limit, lease)
+}
+
+// MarkEmailSent mocks base method
+func (m *MockOutboxStorage) MarkEmailSent(ctx context.Context, emailID int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkEmailSent", ctx, emailID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MarkEmailSent indicates an expected call of MarkEmailSent
+func (mr *MockOutboxStorageMockRecorder) MarkEmailSent(ctx, emailID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkEmailSent", reflect.TypeOf((*MockOutboxStorage)(nil).MarkEmailSent), ctx, emailID)
+}
+
+// MarkEmailFailed mocks base method
+func (m *MockOutboxStorage) MarkEmailFailed(ctx context.Context, emailID int64, reason string, retryAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkEmailFailed", ctx, emailID, reason, retryAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MarkEmailFailed indicates an expected call of MarkEmailFailed
+func (mr *MockOutboxStorageMockRecorder) MarkEmailFailed(ctx, emailID, reason, retryAt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkEmailFailed", reflect.TypeOf((*MockOutboxStorage)(nil).MarkEmailFailed), ctx, emailID, reason, retryAt)
+}
+
+// MockUserStorage is a mock of UserStorage interface
+type MockUserStorage struct {
+	ctrl     *gomock.Controller
+	recorder *MockUserStorageMockRecorder
+}
+
+// MockUserStorageMockRecorder is the mock recorder for MockUserStorage
+type MockUserStorageMockRecorder struct {
+	mock *MockUserStorage
+}
+
+// NewMockUserStorage creates a new mock instance
+func NewMockUserStorage(ctrl *gomock.Controller) *MockUserStorage {
+	mock := &MockUserStorage{ctrl: ctrl}
+	mock.recorder = &MockUserStorageMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockUserStorage) EXPECT() *MockUserStorageMockRecorder {
+	return m.recorder
+}
+
+// EnqueueEmail mocks base method
+func (m *MockUserStorage) EnqueueEmail(ctx context.Context, email model.Email) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnqueueEmail", ctx, email)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// EnqueueEmail indicates an expected call of EnqueueEmail
+func (mr *MockUserStorageMockRecorder) EnqueueEmail(ctx, email interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnqueueEmail", reflect.TypeOf((*MockUserStorage)(nil).EnqueueEmail), ctx, email)
+}
+
+// ClaimEmails mocks base method
+func (m *MockUserStorage) ClaimEmails(ctx context.Context, limit int, lease time.Duration) ([]model.Email, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClaimEmails", ctx, limit, lease)
+	ret0, _ := ret[0].([]model.Email)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ClaimEmails indicates an expected call of ClaimEmails
+func (mr *MockUserStorageMockRecorder) ClaimEmails(ctx, limit, lease interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClaimEmails", reflect.TypeOf((*MockUserStorage)(nil).ClaimEmails), ctx, limit, lease)
+}
+
+// MarkEmailSent mocks base method
+func (m *MockUserStorage) MarkEmailSent(ctx context.Context, emailID int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkEmailSent", ctx, emailID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MarkEmailSent indicates an expected call of MarkEmailSent
+func (mr *MockUserStorageMockRecorder) MarkEmailSent(ctx, emailID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkEmailSent", reflect.TypeOf((*MockUserStorage)(nil).MarkEmailSent), ctx, emailID)
+}
+
+// MarkEmailFailed mocks base method
+func (m *MockUserStorage) MarkEmailFailed(ctx context.Context, emailID int64, reason string, retryAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkEmailFailed", ctx, emailID, reason, retryAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MarkEmailFailed indicates an expected call of MarkEmailFailed
+func (mr *MockUserStorageMockRecorder) MarkEmailFailed(ctx, emailID, reason, retryAt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkEmailFailed", reflect.TypeOf((*MockUserStorage)(nil).MarkEmailFailed), ctx, emailID, reason, retryAt)
+}
+
+// InTx mocks base method
+func (m *MockUserStorage) InTx(ctx context.Context, action func(UserStorage) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InTx", ctx, action)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// InTx indicates an expected call of InTx
+func (mr *MockUserStorageMockRecorder) InTx(ctx, action interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InTx", reflect.TypeOf((*MockUserStorage)(nil).InTx), ctx, action)
+}
+
+// CreateUser mocks base method
+func (m *MockUserStorage) CreateUser(ctx context.Context, user model.User) (model.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateUser", ctx, user)
+	ret0, _ := ret[0].(model.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateUser indicates an expected call of CreateUser
+func (mr *MockUserStorageMockRecorder) CreateUser(ctx, user interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUser", reflect.TypeOf((*MockUserStorage)(nil).CreateUser), ctx, user)
+}
+
+// GetUserByEmail mocks base method
+func (m *MockUserStorage) GetUserByEmail(ctx context.Context, email string) (model.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserByEmail", ctx, email)
+	ret0, _ := ret[0].(model.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserByEmail indicates an expected call of GetUserByEmail
+func (mr *MockUserStorageMockRecorder) GetUserByEmail(ctx, email interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserByEmail", reflect.TypeOf((*MockUserStorage)(nil).GetUserByEmail), ctx, email)
+}
+
+// GetUserByID mocks base method
+func (m *MockUserStorage) GetUserByID(ctx context.Context, id int64) (model.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserByID", ctx, id)
+	ret0, _ := ret[0].(model.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserByID indicates an expected call of GetUserByID
+func (mr *MockUserStorageMockRecorder) GetUserByID(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserByID", reflect.TypeOf((*MockUserStorage)(nil).GetUserByID), ctx, id)
+}
+
+// SaveToken mocks base method
+func (m *MockUserStorage) SaveToken(ctx context.Context, token model.RefreshToken) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveToken", ctx, token)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveToken indicates an expected call of SaveToken
+func (mr *MockUserStorageMockRecorder) SaveToken(ctx, token interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveToken", reflect.TypeOf((*MockUserStorage)(nil).SaveToken), ctx, token)
+}
+
+// UseToken mocks base method
+func (m *MockUserStorage) UseToken(ctx context.Context, tokenID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseToken", ctx, tokenID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UseToken indicates an expected call of UseToken
+func (mr *MockUserStorageMockRecorder) UseToken(ctx, tokenID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseToken", reflect.TypeOf((*MockUserStorage)(nil).UseToken), ctx, tokenID)
+}
+
+// DeleteTokenFamily mocks base method
+func (m *MockUserStorage) DeleteTokenFamily(ctx context.Context, userID int64, familyID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteTokenFamily", ctx, userID, familyID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteTokenFamily indicates an expected call of DeleteTokenFamily
+func (mr *MockUserStorageMockRecorder) DeleteTokenFamily(ctx, userID, familyID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteTokenFamily", reflect.TypeOf((*MockUserStorage)(nil).DeleteTokenFamily), ctx, userID, familyID)
+}
+
+// GetSessions mocks base method
+func (m *MockUserStorage) GetSessions(ctx context.Context, userID int64) ([]model.Session, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSessions", ctx, userID)
+	ret0, _ := ret[0].([]model.Session)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSessions indicates an expected call of GetSessions
+func (mr *MockUserStorageMockRecorder) GetSessions(ctx, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSessions", reflect.TypeOf((*MockUserStorage)(nil).GetSessions), ctx, userID)
+}
+
+// RevokeUserTokens mocks base method
+func (m *MockUserStorage) RevokeUserTokens(ctx context.Context, userID int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeUserTokens", ctx, userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeUserTokens indicates an expected call of RevokeUserTokens
+func (mr *MockUserStorageMockRecorder) RevokeUserTokens(ctx, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeUserTokens", reflect.TypeOf((*MockUserStorage)(nil).RevokeUserTokens), ctx, userID)
+}
+
+// RevokeSessionTokens mocks base method
+func (m *MockUserStorage) RevokeSessionTokens(ctx context.Context, userID int64, sessionID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeSessionTokens", ctx, userID, sessionID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeSessionTokens indicates an expected call of RevokeSessionTokens
+func (mr *MockUserStorageMockRecorder) RevokeSessionTokens(ctx, userID, sessionID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeSessionTokens", reflect.TypeOf((*MockUserStorage)(nil).RevokeSessionTokens), ctx, userID, sessionID)
+}
+
+// GetTokenRevocations mocks base method
+func (m *MockUserStorage) GetTokenRevocations(ctx context.Context, since time.Time) ([]model.TokenRevocation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTokenRevocations", ctx, since)
+	ret0, _ := ret[0].([]model.TokenRevocation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTokenRevocations indicates an expected call of GetTokenRevocations
+func (mr *MockUserStorageMockRecorder) GetTokenRevocations(ctx, since interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTokenRevocations", reflect.TypeOf((*MockUserStorage)(nil).GetTokenRevocations), ctx, since)
+}
+
+// CreateAPIKey mocks base method
+func (m *MockUserStorage) CreateAPIKey(ctx context.Context, key model.APIKey) (model.APIKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateAPIKey", ctx, key)
+	ret0, _ := ret[0].(model.APIKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateAPIKey indicates an expected call of CreateAPIKey
+func (mr *MockUserStorageMockRecorder) CreateAPIKey(ctx, key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAPIKey", reflect.TypeOf((*MockUserStorage)(nil).CreateAPIKey), ctx, key)
+}
+
+// GetAPIKeys mocks base method
+func (m *MockUserStorage) GetAPIKeys(ctx context.Context, userID int64) ([]model.APIKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAPIKeys", ctx, userID)
+	ret0, _ := ret[0].([]model.APIKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAPIKeys indicates an expected call of GetAPIKeys
+func (mr *MockUserStorageMockRecorder) GetAPIKeys(ctx, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAPIKeys", reflect.TypeOf((*MockUserStorage)(nil).GetAPIKeys), ctx, userID)
+}
+
+// DeleteAPIKey mocks base method
+func (m *MockUserStorage) DeleteAPIKey(ctx context.Context, userID int64, keyID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteAPIKey", ctx, userID, keyID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteAPIKey indicates an expected call of DeleteAPIKey
+func (mr *MockUserStorageMockRecorder) DeleteAPIKey(ctx, userID, keyID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAPIKey", reflect.TypeOf((*MockUserStorage)(nil).DeleteAPIKey), ctx, userID, keyID)
+}
+
+// UseAPIKey mocks base method
+func (m *MockUserStorage) UseAPIKey(ctx context.Context, keyHash string) (model.APIKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseAPIKey", ctx, keyHash)
+	ret0, _ := ret[0].(model.APIKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UseAPIKey indicates an expected call of UseAPIKey
+func (mr *MockUserStorageMockRecorder) UseAPIKey(ctx, keyHash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseAPIKey", reflect.TypeOf((*MockUserStorage)(nil).UseAPIKey), ctx, keyHash)
+}
+
+// CreateOAuthClient mocks base method
+func (m *MockUserStorage) CreateOAuthClient(ctx context.Context, client model.OAuthClient) (model.OAuthClient, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateOAuthClient", ctx, client)
+	ret0, _ := ret[0].(model.OAuthClient)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateOAuthClient indicates an expected call of CreateOAuthClient
+func (mr *MockUserStorageMockRecorder) CreateOAuthClient(ctx, client interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateOAuthClient", reflect.TypeOf((*MockUserStorage)(nil).CreateOAuthClient), ctx, client)
+}
+
+// GetOAuthClient mocks base method
+func (m *MockUserStorage) GetOAuthClient(ctx context.Context, clientID string) (model.OAuthClient, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOAuthClient", ctx, clientID)
+	ret0, _ := ret[0].(model.OAuthClient)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOAuthClient indicates an expected call of GetOAuthClient
+func (mr *MockUserStorageMockRecorder) GetOAuthClient(ctx, clientID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOAuthClient", reflect.TypeOf((*MockUserStorage)(nil).GetOAuthClient), ctx, clientID)
+}
+
+// GetOAuthClients mocks base method
+func (m *MockUserStorage) GetOAuthClients(ctx context.Context, userID int64) ([]model.OAuthClient, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOAuthClients", ctx, userID)
+	ret0, _ := ret[0].([]model.OAuthClient)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOAuthClients indicates an expected call of GetOAuthClients
+func (mr *MockUserStorageMockRecorder) GetOAuthClients(ctx, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOAuthClients", reflect.TypeOf((*MockUserStorage)(nil).GetOAuthClients), ctx, userID)
+}
+
+// DeleteOAuthClient mocks base method
+func (m *MockUserStorage) DeleteOAuthClient(ctx context.Context, userID int64, clientID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteOAuthClient", ctx, userID, clientID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteOAuthClient indicates an expected call of DeleteOAuthClient
+func (mr *MockUserStorageMockRecorder) DeleteOAuthClient(ctx, userID, clientID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteOAuthClient", reflect.TypeOf((*MockUserStorage)(nil).DeleteOAuthClient), ctx, userID, clientID)
+}
+
+// SaveAuthorizationCode mocks base method
+func (m *MockUserStorage) SaveAuthorizationCode(ctx context.Context, code model.AuthorizationCode) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveAuthorizationCode", ctx, code)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveAuthorizationCode indicates an expected call of SaveAuthorizationCode
+func (mr *MockUserStorageMockRecorder) SaveAuthorizationCode(ctx, code interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveAuthorizationCode", reflect.TypeOf((*MockUserStorage)(nil).SaveAuthorizationCode), ctx, code)
+}
+
+// UseAuthorizationCode mocks base method
+func (m *MockUserStorage) UseAuthorizationCode(ctx context.Context, codeHash string) (model.AuthorizationCode, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseAuthorizationCode", ctx, codeHash)
+	ret0, _ := ret[0].(model.AuthorizationCode)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UseAuthorizationCode indicates an expected call of UseAuthorizationCode
+func (mr *MockUserStorageMockRecorder) UseAuthorizationCode(ctx, codeHash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseAuthorizationCode", reflect.TypeOf((*MockUserStorage)(nil).UseAuthorizationCode), ctx, codeHash)
+}
+
+// DeleteUserTokens mocks base method
+func (m *MockUserStorage) DeleteUserTokens(ctx context.Context, userID int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteUserTokens", ctx, userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteUserTokens indicates an expected call of DeleteUserTokens
+func (mr *MockUserStorageMockRecorder) DeleteUserTokens(ctx, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteUserTokens", reflect.TypeOf((*MockUserStorage)(nil).DeleteUserTokens), ctx, userID)
+}
+
+// UpdateUserPassword mocks base method
+func (m *MockUserStorage) UpdateUserPassword(ctx context.Context, userID int64, passwordHash string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateUserPassword", ctx, userID, passwordHash)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateUserPassword indicates an expected call of UpdateUserPassword
+func (mr *MockUserStorageMockRecorder) UpdateUserPassword(ctx, userID, passwordHash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUserPassword", reflect.TypeOf((*MockUserStorage)(nil).UpdateUserPassword), ctx, userID, passwordHash)
+}
+
+// SaveResetToken mocks base method
+func (m *MockUserStorage) SaveResetToken(ctx context.Context, tokenHash string, userID int64, expiresAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveResetToken", ctx, tokenHash, userID, expiresAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveResetToken indicates an expected call of SaveResetToken
+func (mr *MockUserStorageMockRecorder) SaveResetToken(ctx, tokenHash, userID, expiresAt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveResetToken", reflect.TypeOf((*MockUserStorage)(nil).SaveResetToken), ctx, tokenHash, userID, expiresAt)
+}
+
+// UseResetToken mocks base method
+func (m *MockUserStorage) UseResetToken(ctx context.Context, tokenHash string) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseResetToken", ctx, tokenHash)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UseResetToken indicates an expected call of UseResetToken
+func (mr *MockUserStorageMockRecorder) UseResetToken(ctx, tokenHash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseResetToken", reflect.TypeOf((*MockUserStorage)(nil).UseResetToken), ctx, tokenHash)
+}
+
+// VerifyUserEmail mocks base method
+func (m *MockUserStorage) VerifyUserEmail(ctx context.Context, userID int64, email string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VerifyUserEmail", ctx, userID, email)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// VerifyUserEmail indicates an expected call of VerifyUserEmail
+func (mr *MockUserStorageMockRecorder) VerifyUserEmail(ctx, userID, email interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyUserEmail", reflect.TypeOf((*MockUserStorage)(nil).VerifyUserEmail), ctx, userID, email)
+}
+
+// MarkVerificationSent mocks base method
+func (m *MockUserStorage) MarkVerificationSent(ctx context.Context, userID int64, interval time.Duration) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkVerificationSent", ctx, userID, interval)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MarkVerificationSent indicates an expected call of MarkVerificationSent
+func (mr *MockUserStorageMockRecorder) MarkVerificationSent(ctx, userID, interval interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkVerificationSent", reflect.TypeOf((*MockUserStorage)(nil).MarkVerificationSent), ctx, userID, interval)
+}
+
+// SetMFASecret mocks base method
+func (m *MockUserStorage) SetMFASecret(ctx context.Context, userID int64, secret string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetMFASecret", ctx, userID, secret)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetMFASecret indicates an expected call of SetMFASecret
+func (mr *MockUserStorageMockRecorder) SetMFASecret(ctx, userID, secret interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetMFASecret", reflect.TypeOf((*MockUserStorage)(nil).SetMFASecret), ctx, userID, secret)
+}
+
+// EnableMFA mocks base method
+func (m *MockUserStorage) EnableMFA(ctx context.Context, userID int64, recoveryCodeHashes []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnableMFA", ctx, userID, recoveryCodeHashes)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// EnableMFA indicates an expected call of EnableMFA
+func (mr *MockUserStorageMockRecorder) EnableMFA(ctx, userID, recoveryCodeHashes interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnableMFA", reflect.TypeOf((*MockUserStorage)(nil).EnableMFA), ctx, userID, recoveryCodeHashes)
+}
+
+// DisableMFA mocks base method
+func (m *MockUserStorage) DisableMFA(ctx context.Context, userID int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DisableMFA", ctx, userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DisableMFA indicates an expected call of DisableMFA
+func (mr *MockUserStorageMockRecorder) DisableMFA(ctx, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DisableMFA", reflect.TypeOf((*MockUserStorage)(nil).DisableMFA), ctx, userID)
+}
+
+// UseMFAStep mocks base method
+func (m *MockUserStorage) UseMFAStep(ctx context.Context, userID, step int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseMFAStep", ctx, userID, step)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UseMFAStep indicates an expected call of UseMFAStep
+func (mr *MockUserStorageMockRecorder) UseMFAStep(ctx, userID, step interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseMFAStep", reflect.TypeOf((*MockUserStorage)(nil).UseMFAStep), ctx, userID, step)
+}
+
+// UseRecoveryCode mocks base method
+func (m *MockUserStorage) UseRecoveryCode(ctx context.Context, userID int64, codeHash string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseRecoveryCode", ctx, userID, codeHash)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UseRecoveryCode indicates an expected call of UseRecoveryCode
+func (mr *MockUserStorageMockRecorder) UseRecoveryCode(ctx, userID, codeHash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseRecoveryCode", reflect.TypeOf((*MockUserStorage)(nil).UseRecoveryCode), ctx, userID, codeHash)
+}
+
+// GetLoginFailures mocks base method
+func (m *MockUserStorage) GetLoginFailures(ctx context.Context, subjects []string) ([]model.LoginFailures, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLoginFailures", ctx, subjects)
+	ret0, _ := ret[0].([]model.LoginFailures)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLoginFailures indicates an expected call of GetLoginFailures
+func (mr *MockUserStorageMockRecorder) GetLoginFailures(ctx, subjects interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLoginFailures", reflect.TypeOf((*MockUserStorage)(nil).GetLoginFailures), ctx, subjects)
+}
+
+// AddLoginFailure mocks base method
+func (m *MockUserStorage) AddLoginFailure(ctx context.Context, subject string, window time.Duration) (model.LoginFailures, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddLoginFailure", ctx, subject, window)
+	ret0, _ := ret[0].(model.LoginFailures)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AddLoginFailure indicates an expected call of AddLoginFailure
+func (mr *MockUserStorageMockRecorder) AddLoginFailure(ctx, subject, window interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddLoginFailure", reflect.TypeOf((*MockUserStorage)(nil).AddLoginFailure), ctx, subject, window)
+}
+
+// DeleteLoginFailures mocks base method
+func (m *MockUserStorage) DeleteLoginFailures(ctx context.Context, subject string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteLoginFailures", ctx, subject)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteLoginFailures indicates an expected call of DeleteLoginFailures
+func (mr *MockUserStorageMockRecorder) DeleteLoginFailures(ctx, subject interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteLoginFailures", reflect.TypeOf((*MockUserStorage)(nil).DeleteLoginFailures), ctx, subject)
+}
+
+// GetRoles mocks base method
+func (m *MockUserStorage) GetRoles(ctx context.Context) ([]model.Role, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRoles", ctx)
+	ret0, _ := ret[0].([]model.Role)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRoles indicates an expected call of GetRoles
+func (mr *MockUserStorageMockRecorder) GetRoles(ctx interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteToken", reflect.TypeOf((*MockUserStorage)(nil).DeleteToken), ctx, tokenID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRoles", reflect.TypeOf((*MockUserStorage)(nil).GetRoles), ctx)
 }
 
-// UpdateUserPermissions mocks base method
-func (m *MockUserStorage) UpdateUserPermissions(ctx context.Context, user model.User) error {
+// SetUserRoles mocks base method
+func (m *MockUserStorage) SetUserRoles(ctx context.Context, userID int64, roles []string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "UpdateUserPermissions", ctx, user)
+	ret := m.ctrl.Call(m, "SetUserRoles", ctx, userID, roles)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// UpdateUserPermissions indicates an expected call of UpdateUserPermissions
-func (mr *MockUserStorageMockRecorder) UpdateUserPermissions(ctx, user interface{}) *gomock.Call {
+// SetUserRoles indicates an expected call of SetUserRoles
+func (mr *MockUserStorageMockRecorder) SetUserRoles(ctx, userID, roles interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUserPermissions", reflect.TypeOf((*MockUserStorage)(nil).UpdateUserPermissions), ctx, user)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetUserRoles", reflect.TypeOf((*MockUserStorage)(nil).SetUserRoles), ctx, userID, roles)
 }
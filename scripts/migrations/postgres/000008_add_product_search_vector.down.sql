@@ -0,0 +1,6 @@
+BEGIN TRANSACTION;
+
+DROP INDEX IF EXISTS product_search_vector_idx;
+ALTER TABLE product DROP COLUMN IF EXISTS search_vector;
+
+COMMIT TRANSACTION;
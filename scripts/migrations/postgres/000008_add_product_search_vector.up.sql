@@ -0,0 +1,10 @@
+BEGIN TRANSACTION;
+
+ALTER TABLE product ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('english', name), 'A') ||
+    setweight(to_tsvector('english', description), 'B')
+) STORED;
+
+CREATE INDEX IF NOT EXISTS product_search_vector_idx ON product USING GIN (search_vector);
+
+COMMIT TRANSACTION;
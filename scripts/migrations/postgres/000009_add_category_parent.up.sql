@@ -0,0 +1,7 @@
+BEGIN TRANSACTION;
+
+ALTER TABLE category ADD COLUMN IF NOT EXISTS parent_id integer REFERENCES category (id);
+
+CREATE INDEX IF NOT EXISTS category_parent_id_idx ON category (parent_id);
+
+COMMIT TRANSACTION;
@@ -0,0 +1,8 @@
+BEGIN TRANSACTION;
+
+DROP INDEX IF EXISTS product_attributes_idx;
+ALTER TABLE product DROP COLUMN IF EXISTS attributes;
+
+ALTER TABLE category DROP COLUMN IF EXISTS attribute_schema;
+
+COMMIT TRANSACTION;
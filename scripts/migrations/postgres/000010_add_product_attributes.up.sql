@@ -0,0 +1,9 @@
+BEGIN TRANSACTION;
+
+ALTER TABLE category ADD COLUMN IF NOT EXISTS attribute_schema jsonb NOT NULL DEFAULT '[]';
+
+ALTER TABLE product ADD COLUMN IF NOT EXISTS attributes jsonb NOT NULL DEFAULT '{}';
+
+CREATE INDEX IF NOT EXISTS product_attributes_idx ON product USING GIN (attributes jsonb_path_ops);
+
+COMMIT TRANSACTION;
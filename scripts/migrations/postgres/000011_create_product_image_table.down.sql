@@ -0,0 +1,5 @@
+BEGIN TRANSACTION;
+
+DROP TABLE IF EXISTS product_image;
+
+COMMIT TRANSACTION;
@@ -0,0 +1,15 @@
+BEGIN TRANSACTION;
+
+CREATE TABLE IF NOT EXISTS product_image (
+    id serial PRIMARY KEY,
+    product_id integer NOT NULL REFERENCES product (id) ON DELETE CASCADE,
+    key VARCHAR(255) NOT NULL UNIQUE,
+    content_type VARCHAR(50) NOT NULL,
+    width integer NOT NULL,
+    height integer NOT NULL,
+    position integer NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS product_image_product_id_position_idx ON product_image (product_id, position);
+
+COMMIT TRANSACTION;
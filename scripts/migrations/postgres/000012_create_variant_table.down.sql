@@ -0,0 +1,18 @@
+BEGIN TRANSACTION;
+
+-- only the cheapest variant of a product is kept in a store
+DELETE FROM position p USING position o
+    WHERE o.product_id = p.product_id AND o.store_id = p.store_id
+    AND (o.price < p.price OR (o.price = p.price AND o.variant_id < p.variant_id));
+
+DROP INDEX IF EXISTS position_product_id_store_id_idx;
+
+ALTER TABLE position
+    DROP CONSTRAINT IF EXISTS position_variant_id_fkey,
+    DROP CONSTRAINT IF EXISTS position_pkey,
+    DROP COLUMN IF EXISTS variant_id,
+    ADD PRIMARY KEY (product_id, store_id);
+
+DROP TABLE IF EXISTS variant;
+
+COMMIT TRANSACTION;
@@ -0,0 +1,30 @@
+BEGIN TRANSACTION;
+
+CREATE TABLE IF NOT EXISTS variant (
+    id serial PRIMARY KEY,
+    product_id integer NOT NULL REFERENCES product (id) ON DELETE CASCADE,
+    sku VARCHAR(64) NOT NULL UNIQUE,
+    gtin VARCHAR(14) UNIQUE,
+    attributes jsonb NOT NULL DEFAULT '{}',
+    UNIQUE (id, product_id)
+);
+
+CREATE INDEX IF NOT EXISTS variant_product_id_idx ON variant (product_id);
+
+-- every existing product becomes a parent of a single variant
+INSERT INTO variant (product_id, sku) SELECT id, 'SKU-' || id FROM product ORDER BY id;
+
+ALTER TABLE position ADD COLUMN IF NOT EXISTS variant_id integer;
+
+UPDATE position p SET variant_id = v.id FROM variant v WHERE v.product_id = p.product_id;
+
+ALTER TABLE position
+    ALTER COLUMN variant_id SET NOT NULL,
+    DROP CONSTRAINT IF EXISTS position_pkey,
+    ADD PRIMARY KEY (variant_id, store_id),
+    ADD CONSTRAINT position_variant_id_fkey FOREIGN KEY (variant_id, product_id)
+        REFERENCES variant (id, product_id) ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS position_product_id_store_id_idx ON position (product_id, store_id);
+
+COMMIT TRANSACTION;
@@ -0,0 +1,8 @@
+BEGIN TRANSACTION;
+
+ALTER TABLE position
+    DROP COLUMN IF EXISTS quantity,
+    DROP COLUMN IF EXISTS availability,
+    DROP COLUMN IF EXISTS restock_date;
+
+COMMIT TRANSACTION;
@@ -0,0 +1,9 @@
+BEGIN TRANSACTION;
+
+ALTER TABLE position
+    ADD COLUMN IF NOT EXISTS quantity bigint NOT NULL DEFAULT 0 CHECK (quantity >= 0),
+    ADD COLUMN IF NOT EXISTS availability VARCHAR(20) NOT NULL DEFAULT 'in_stock'
+        CHECK (availability IN ('in_stock', 'backorder', 'discontinued')),
+    ADD COLUMN IF NOT EXISTS restock_date date;
+
+COMMIT TRANSACTION;
@@ -0,0 +1,5 @@
+BEGIN TRANSACTION;
+
+DROP TABLE IF EXISTS position_price_history;
+
+COMMIT TRANSACTION;
@@ -0,0 +1,20 @@
+BEGIN TRANSACTION;
+
+CREATE TABLE IF NOT EXISTS position_price_history (
+    id bigserial PRIMARY KEY,
+    product_id integer NOT NULL REFERENCES product (id) ON DELETE CASCADE,
+    variant_id integer NOT NULL REFERENCES variant (id) ON DELETE CASCADE,
+    store_id integer NOT NULL REFERENCES store (id) ON DELETE CASCADE,
+    price numeric NOT NULL,
+    user_id integer REFERENCES store_user (id) ON DELETE SET NULL,
+    changed_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS position_price_history_product_id_changed_at_idx
+    ON position_price_history (product_id, changed_at);
+
+-- current prices are the starting points of the history
+INSERT INTO position_price_history (product_id, variant_id, store_id, price)
+    SELECT product_id, variant_id, store_id, price FROM position;
+
+COMMIT TRANSACTION;
@@ -0,0 +1,9 @@
+BEGIN TRANSACTION;
+
+ALTER TABLE position_price_history DROP COLUMN IF EXISTS currency;
+
+ALTER TABLE position DROP COLUMN IF EXISTS currency;
+
+DROP TABLE IF EXISTS exchange_rate;
+
+COMMIT TRANSACTION;
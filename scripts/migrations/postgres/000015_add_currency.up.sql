@@ -0,0 +1,17 @@
+BEGIN TRANSACTION;
+
+CREATE TABLE IF NOT EXISTS exchange_rate (
+    currency VARCHAR(3) PRIMARY KEY CHECK (currency ~ '^[A-Z]{3}$'),
+    rate numeric NOT NULL CHECK (rate > 0),
+    updated_at timestamptz NOT NULL DEFAULT now()
+);
+
+-- rates are relative to USD, existing prices are considered to be in USD
+INSERT INTO exchange_rate (currency, rate) VALUES ('USD', 1);
+
+ALTER TABLE position ADD COLUMN IF NOT EXISTS currency VARCHAR(3) NOT NULL DEFAULT 'USD'
+    CONSTRAINT position_currency_fkey REFERENCES exchange_rate (currency);
+
+ALTER TABLE position_price_history ADD COLUMN IF NOT EXISTS currency VARCHAR(3) NOT NULL DEFAULT 'USD';
+
+COMMIT TRANSACTION;
@@ -0,0 +1,7 @@
+BEGIN TRANSACTION;
+
+DROP TABLE IF EXISTS cart_item;
+
+DROP TABLE IF EXISTS cart;
+
+COMMIT TRANSACTION;
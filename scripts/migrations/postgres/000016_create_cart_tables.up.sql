@@ -0,0 +1,26 @@
+BEGIN TRANSACTION;
+
+-- cart belongs either to a user or to an anonymous visitor identified by token
+CREATE TABLE IF NOT EXISTS cart (
+    id bigserial PRIMARY KEY,
+    user_id integer UNIQUE REFERENCES store_user (id) ON DELETE CASCADE,
+    token UUID UNIQUE,
+    updated_at timestamptz NOT NULL DEFAULT now(),
+    CHECK ((user_id IS NULL) <> (token IS NULL))
+);
+
+-- items outlive positions to report them as unavailable
+CREATE TABLE IF NOT EXISTS cart_item (
+    cart_id bigint NOT NULL REFERENCES cart (id) ON DELETE CASCADE,
+    product_id integer NOT NULL,
+    variant_id integer NOT NULL,
+    store_id integer NOT NULL REFERENCES store (id) ON DELETE CASCADE,
+    quantity integer NOT NULL CHECK (quantity > 0),
+    price numeric NOT NULL,
+    currency VARCHAR(3) NOT NULL,
+    added_at timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY (cart_id, variant_id, store_id),
+    FOREIGN KEY (variant_id, product_id) REFERENCES variant (id, product_id) ON DELETE CASCADE
+);
+
+COMMIT TRANSACTION;
@@ -0,0 +1,9 @@
+BEGIN TRANSACTION;
+
+DROP TABLE IF EXISTS order_transition;
+
+DROP TABLE IF EXISTS order_item;
+
+DROP TABLE IF EXISTS store_order;
+
+COMMIT TRANSACTION;
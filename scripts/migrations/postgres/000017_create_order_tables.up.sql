@@ -0,0 +1,42 @@
+BEGIN TRANSACTION;
+
+CREATE TABLE IF NOT EXISTS store_order (
+    id bigserial PRIMARY KEY,
+    user_id integer REFERENCES store_user (id) ON DELETE SET NULL,
+    status VARCHAR(16) NOT NULL
+        CHECK (status IN ('pending', 'paid', 'shipped', 'delivered', 'cancelled', 'refunded')),
+    currency VARCHAR(3) NOT NULL,
+    total numeric NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS store_order_user_id_idx ON store_order (user_id);
+
+-- items are snapshots, so they don't reference products and positions
+CREATE TABLE IF NOT EXISTS order_item (
+    order_id bigint NOT NULL REFERENCES store_order (id) ON DELETE CASCADE,
+    product_id integer NOT NULL,
+    variant_id integer NOT NULL,
+    store_id integer NOT NULL,
+    name VARCHAR(160) NOT NULL,
+    sku VARCHAR(64) NOT NULL,
+    quantity integer NOT NULL CHECK (quantity > 0),
+    price numeric NOT NULL,
+    currency VARCHAR(3) NOT NULL,
+    reserved boolean NOT NULL DEFAULT FALSE,
+    PRIMARY KEY (order_id, variant_id, store_id)
+);
+
+CREATE TABLE IF NOT EXISTS order_transition (
+    id bigserial PRIMARY KEY,
+    order_id bigint NOT NULL REFERENCES store_order (id) ON DELETE CASCADE,
+    from_status VARCHAR(16),
+    to_status VARCHAR(16) NOT NULL,
+    actor_id integer REFERENCES store_user (id) ON DELETE SET NULL,
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS order_transition_order_id_idx ON order_transition (order_id);
+
+COMMIT TRANSACTION;
@@ -0,0 +1,7 @@
+BEGIN TRANSACTION;
+
+DROP TABLE IF EXISTS payment_event;
+
+DROP TABLE IF EXISTS payment;
+
+COMMIT TRANSACTION;
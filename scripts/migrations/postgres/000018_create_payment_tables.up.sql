@@ -0,0 +1,26 @@
+BEGIN TRANSACTION;
+
+CREATE TABLE IF NOT EXISTS payment (
+    id VARCHAR(64) PRIMARY KEY,
+    order_id bigint NOT NULL REFERENCES store_order (id) ON DELETE CASCADE,
+    status VARCHAR(16) NOT NULL
+        CHECK (status IN ('pending', 'authorized', 'captured', 'declined', 'refunded')),
+    amount numeric NOT NULL,
+    currency VARCHAR(3) NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS payment_order_id_idx ON payment (order_id);
+
+-- order has at most one payment that is not declined, so concurrent requests can't charge customer twice
+CREATE UNIQUE INDEX IF NOT EXISTS payment_order_id_active_idx ON payment (order_id) WHERE status <> 'declined';
+
+-- provider callbacks are recorded to ignore repeated deliveries
+CREATE TABLE IF NOT EXISTS payment_event (
+    id VARCHAR(64) PRIMARY KEY,
+    payment_id VARCHAR(64) NOT NULL REFERENCES payment (id) ON DELETE CASCADE,
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+COMMIT TRANSACTION;
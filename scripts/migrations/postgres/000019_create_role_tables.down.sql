@@ -0,0 +1,15 @@
+BEGIN TRANSACTION;
+
+ALTER TABLE store_user ADD COLUMN IF NOT EXISTS is_admin BOOLEAN NOT NULL DEFAULT FALSE;
+
+UPDATE store_user SET is_admin = TRUE WHERE id IN (SELECT user_id FROM user_role WHERE role = 'superadmin');
+
+ALTER TABLE store_user ALTER COLUMN is_admin DROP DEFAULT;
+
+DROP TABLE IF EXISTS user_role;
+
+DROP TABLE IF EXISTS role_permission;
+
+DROP TABLE IF EXISTS role;
+
+COMMIT TRANSACTION;
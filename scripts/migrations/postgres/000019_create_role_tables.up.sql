@@ -0,0 +1,44 @@
+BEGIN TRANSACTION;
+
+CREATE TABLE IF NOT EXISTS role (
+    name VARCHAR(32) PRIMARY KEY
+);
+
+CREATE TABLE IF NOT EXISTS role_permission (
+    role VARCHAR(32) NOT NULL REFERENCES role (name) ON DELETE CASCADE,
+    permission VARCHAR(64) NOT NULL,
+    PRIMARY KEY (role, permission)
+);
+
+CREATE TABLE IF NOT EXISTS user_role (
+    user_id integer NOT NULL REFERENCES store_user (id) ON DELETE CASCADE,
+    role VARCHAR(32) NOT NULL REFERENCES role (name) ON DELETE CASCADE,
+    PRIMARY KEY (user_id, role)
+);
+
+INSERT INTO role (name) VALUES ('superadmin'), ('catalog-editor'), ('store-manager'), ('support');
+
+INSERT INTO role_permission (role, permission) VALUES
+    ('superadmin', 'user:write'),
+    ('superadmin', 'category:write'),
+    ('superadmin', 'product:write'),
+    ('superadmin', 'store:write'),
+    ('superadmin', 'position:write'),
+    ('superadmin', 'price_history:read'),
+    ('superadmin', 'exchange_rate:write'),
+    ('superadmin', 'order:read'),
+    ('superadmin', 'order:write'),
+    ('catalog-editor', 'category:write'),
+    ('catalog-editor', 'product:write'),
+    ('store-manager', 'store:write'),
+    ('store-manager', 'position:write'),
+    ('store-manager', 'price_history:read'),
+    ('support', 'order:read'),
+    ('support', 'order:write');
+
+-- admins keep full access
+INSERT INTO user_role (user_id, role) SELECT id, 'superadmin' FROM store_user WHERE is_admin;
+
+ALTER TABLE store_user DROP COLUMN IF EXISTS is_admin;
+
+COMMIT TRANSACTION;
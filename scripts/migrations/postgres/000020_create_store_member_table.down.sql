@@ -0,0 +1,5 @@
+BEGIN TRANSACTION;
+
+DROP TABLE IF EXISTS store_member;
+
+COMMIT TRANSACTION;
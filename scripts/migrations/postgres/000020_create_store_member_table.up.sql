@@ -0,0 +1,13 @@
+BEGIN TRANSACTION;
+
+CREATE TABLE IF NOT EXISTS store_member (
+    store_id integer NOT NULL REFERENCES store (id) ON DELETE CASCADE,
+    user_id integer NOT NULL REFERENCES store_user (id) ON DELETE CASCADE,
+    role VARCHAR(16) NOT NULL CHECK (role IN ('owner', 'editor')),
+    created_at timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY (store_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS store_member_user_id_idx ON store_member (user_id);
+
+COMMIT TRANSACTION;
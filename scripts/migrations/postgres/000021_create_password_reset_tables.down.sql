@@ -0,0 +1,7 @@
+BEGIN TRANSACTION;
+
+DROP TABLE IF EXISTS email_outbox;
+
+DROP TABLE IF EXISTS password_reset_token;
+
+COMMIT TRANSACTION;
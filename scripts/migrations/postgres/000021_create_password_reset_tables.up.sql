@@ -0,0 +1,26 @@
+BEGIN TRANSACTION;
+
+CREATE TABLE IF NOT EXISTS password_reset_token (
+    token_hash VARCHAR(64) PRIMARY KEY,
+    user_id integer NOT NULL REFERENCES store_user (id) ON DELETE CASCADE,
+    expires_at timestamptz NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS password_reset_token_user_id_idx ON password_reset_token (user_id);
+
+CREATE TABLE IF NOT EXISTS email_outbox (
+    id bigserial PRIMARY KEY,
+    recipient VARCHAR(120) NOT NULL,
+    subject VARCHAR(200) NOT NULL,
+    body text NOT NULL,
+    attempts integer NOT NULL DEFAULT 0,
+    last_error text,
+    -- NULL means that delivery is not attempted anymore
+    next_attempt_at timestamptz DEFAULT now(),
+    sent_at timestamptz,
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS email_outbox_next_attempt_at_idx ON email_outbox (next_attempt_at) WHERE sent_at IS NULL;
+
+COMMIT TRANSACTION;
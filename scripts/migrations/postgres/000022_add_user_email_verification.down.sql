@@ -0,0 +1,6 @@
+BEGIN TRANSACTION;
+
+ALTER TABLE store_user DROP COLUMN IF EXISTS verification_sent_at;
+ALTER TABLE store_user DROP COLUMN IF EXISTS email_verified;
+
+COMMIT TRANSACTION;
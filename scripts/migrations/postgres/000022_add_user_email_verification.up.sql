@@ -0,0 +1,13 @@
+BEGIN TRANSACTION;
+
+ALTER TABLE store_user ADD COLUMN IF NOT EXISTS email_verified BOOLEAN NOT NULL DEFAULT false;
+-- verification_sent_at is used to rate limit verification emails
+ALTER TABLE store_user ADD COLUMN IF NOT EXISTS verification_sent_at timestamptz;
+
+-- users registered before verification was introduced are trusted
+UPDATE store_user SET email_verified = true;
+
+-- new users get verification email on registration
+ALTER TABLE store_user ALTER COLUMN verification_sent_at SET DEFAULT now();
+
+COMMIT TRANSACTION;
@@ -0,0 +1,10 @@
+BEGIN TRANSACTION;
+
+-- login_failure tracks failed login attempts per account (email) and per client IP
+CREATE TABLE IF NOT EXISTS login_failure (
+    subject TEXT PRIMARY KEY,
+    failures integer NOT NULL,
+    last_failure_at timestamptz NOT NULL
+);
+
+COMMIT TRANSACTION;
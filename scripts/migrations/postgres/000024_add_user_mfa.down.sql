@@ -0,0 +1,9 @@
+BEGIN TRANSACTION;
+
+DROP TABLE IF EXISTS mfa_recovery_code;
+
+ALTER TABLE store_user DROP COLUMN IF EXISTS mfa_last_step;
+ALTER TABLE store_user DROP COLUMN IF EXISTS mfa_enabled;
+ALTER TABLE store_user DROP COLUMN IF EXISTS mfa_secret;
+
+COMMIT TRANSACTION;
@@ -0,0 +1,14 @@
+BEGIN TRANSACTION;
+
+ALTER TABLE store_user ADD COLUMN IF NOT EXISTS mfa_secret VARCHAR(64);
+ALTER TABLE store_user ADD COLUMN IF NOT EXISTS mfa_enabled BOOLEAN NOT NULL DEFAULT false;
+-- mfa_last_step is the last used TOTP time step, codes of earlier steps can't be reused
+ALTER TABLE store_user ADD COLUMN IF NOT EXISTS mfa_last_step bigint NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS mfa_recovery_code (
+    user_id integer NOT NULL REFERENCES store_user (id) ON DELETE CASCADE,
+    code_hash VARCHAR(64) NOT NULL,
+    PRIMARY KEY (user_id, code_hash)
+);
+
+COMMIT TRANSACTION;
@@ -0,0 +1,16 @@
+BEGIN TRANSACTION;
+
+DROP INDEX IF EXISTS token_user_id_idx;
+DROP INDEX IF EXISTS token_family_id_idx;
+
+-- rotated out tokens must not become valid again
+DELETE FROM token WHERE used_at IS NOT NULL;
+
+ALTER TABLE token DROP COLUMN IF EXISTS user_agent;
+ALTER TABLE token DROP COLUMN IF EXISTS ip;
+ALTER TABLE token DROP COLUMN IF EXISTS used_at;
+ALTER TABLE token DROP COLUMN IF EXISTS created_at;
+ALTER TABLE token DROP COLUMN IF EXISTS started_at;
+ALTER TABLE token DROP COLUMN IF EXISTS family_id;
+
+COMMIT TRANSACTION;
@@ -0,0 +1,19 @@
+BEGIN TRANSACTION;
+
+-- refresh tokens rotated from one login form a family that represents user session
+ALTER TABLE token ADD COLUMN IF NOT EXISTS family_id UUID;
+UPDATE token SET family_id = id WHERE family_id IS NULL;
+ALTER TABLE token ALTER COLUMN family_id SET NOT NULL;
+
+-- started_at is a login time of the family
+ALTER TABLE token ADD COLUMN IF NOT EXISTS started_at timestamptz NOT NULL DEFAULT now();
+ALTER TABLE token ADD COLUMN IF NOT EXISTS created_at timestamptz NOT NULL DEFAULT now();
+-- used_at is set when token is rotated out, presenting it again revokes the whole family
+ALTER TABLE token ADD COLUMN IF NOT EXISTS used_at timestamptz;
+ALTER TABLE token ADD COLUMN IF NOT EXISTS ip VARCHAR(45) NOT NULL DEFAULT '';
+ALTER TABLE token ADD COLUMN IF NOT EXISTS user_agent VARCHAR(256) NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS token_family_id_idx ON token (family_id);
+CREATE INDEX IF NOT EXISTS token_user_id_idx ON token (user_id);
+
+COMMIT TRANSACTION;
@@ -0,0 +1,7 @@
+BEGIN TRANSACTION;
+
+DROP INDEX IF EXISTS store_user_tokens_valid_after_idx;
+
+ALTER TABLE store_user DROP COLUMN IF EXISTS tokens_valid_after;
+
+COMMIT TRANSACTION;
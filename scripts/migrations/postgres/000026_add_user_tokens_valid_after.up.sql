@@ -0,0 +1,8 @@
+BEGIN TRANSACTION;
+
+-- access tokens of the user issued before tokens_valid_after are revoked
+ALTER TABLE store_user ADD COLUMN IF NOT EXISTS tokens_valid_after timestamptz;
+
+CREATE INDEX IF NOT EXISTS store_user_tokens_valid_after_idx ON store_user (tokens_valid_after);
+
+COMMIT TRANSACTION;
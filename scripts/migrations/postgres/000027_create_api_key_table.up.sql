@@ -0,0 +1,19 @@
+BEGIN TRANSACTION;
+
+-- personal API keys of machine clients, only hash of the key is stored
+CREATE TABLE IF NOT EXISTS api_key (
+    id UUID PRIMARY KEY,
+    user_id integer NOT NULL REFERENCES store_user (id) ON DELETE CASCADE,
+    name VARCHAR(64) NOT NULL,
+    -- prefix is a beginning of the key to tell keys apart
+    prefix VARCHAR(16) NOT NULL,
+    key_hash VARCHAR(64) NOT NULL UNIQUE,
+    scopes VARCHAR(64)[] NOT NULL DEFAULT '{}',
+    created_at timestamptz NOT NULL DEFAULT now(),
+    expires_at timestamptz,
+    last_used_at timestamptz
+);
+
+CREATE INDEX IF NOT EXISTS api_key_user_id_idx ON api_key (user_id);
+
+COMMIT TRANSACTION;
@@ -0,0 +1,6 @@
+BEGIN TRANSACTION;
+
+DROP TABLE IF EXISTS oauth_code;
+DROP TABLE IF EXISTS oauth_client;
+
+COMMIT TRANSACTION;
@@ -0,0 +1,28 @@
+BEGIN TRANSACTION;
+
+-- OAuth 2.0 clients registered by users, e.g. integrations of partner stores
+CREATE TABLE IF NOT EXISTS oauth_client (
+    id UUID PRIMARY KEY,
+    user_id integer NOT NULL REFERENCES store_user (id) ON DELETE CASCADE,
+    name VARCHAR(64) NOT NULL,
+    -- secret_hash is empty for public clients that can't keep secret, e.g. mobile apps
+    secret_hash VARCHAR(64) NOT NULL DEFAULT '',
+    redirect_uris VARCHAR(256)[] NOT NULL DEFAULT '{}',
+    scopes VARCHAR(64)[] NOT NULL DEFAULT '{}',
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS oauth_client_user_id_idx ON oauth_client (user_id);
+
+-- authorization codes are single use, they are deleted on exchange for tokens
+CREATE TABLE IF NOT EXISTS oauth_code (
+    code_hash VARCHAR(64) PRIMARY KEY,
+    client_id UUID NOT NULL REFERENCES oauth_client (id) ON DELETE CASCADE,
+    user_id integer NOT NULL REFERENCES store_user (id) ON DELETE CASCADE,
+    redirect_uri VARCHAR(256) NOT NULL,
+    scopes VARCHAR(64)[] NOT NULL DEFAULT '{}',
+    code_challenge VARCHAR(128) NOT NULL,
+    expires_at timestamptz NOT NULL
+);
+
+COMMIT TRANSACTION;
@@ -0,0 +1,12 @@
+BEGIN TRANSACTION;
+
+-- access tokens issued within revoked session are rejected until they expire
+CREATE TABLE IF NOT EXISTS revoked_session (
+    session_id UUID PRIMARY KEY,
+    user_id integer NOT NULL REFERENCES store_user (id) ON DELETE CASCADE,
+    revoked_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS revoked_session_revoked_at_idx ON revoked_session (revoked_at);
+
+COMMIT TRANSACTION;